@@ -11,6 +11,6 @@ import (
 // with a non-zero status code if an error occurs.
 func main() {
 	if err := cli.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(cli.ExitCode(err))
 	}
 }
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+// addCmd represents the add command.
+//
+//nolint:gochecknoglobals // this is command definition
+var addCmd = &cobra.Command{
+	Use:   "add <component_path>",
+	Short: "Adds a component template to the project in the current directory",
+	Long: `Applies a component template, such as a database or CI add-on, into the
+existing project in the current directory, generated by mold.
+
+The component is rendered with the data recorded in the project's .mold.yaml,
+with the data file and --set values on top. The values of secret prompts are
+masked in it, so they must be given again. Its files are recorded in the
+project's provenance, with the component as a layer, so 'mold info', 'verify'
+and later runs track them like the rest of the project. A component declaring
+'requires' in its template.yaml is only added to projects generated from that
+template, in a version the constraint accepts.
+
+Files the project doesn't have are written, and tracked files the user hasn't
+changed are updated, so adding a component twice changes nothing. When a file
+the user changed or created differs from what the component generates,
+nothing is written and the conflicting files are listed.`,
+	Args: cobra.ExactArgs(1), // Requires exactly one argument: the path to the component.
+	RunE: func(cmd *cobra.Command, args []string) error {
+		componentPath := workPath(args[0])
+		if _, err := os.Stat(componentPath); os.IsNotExist(err) {
+			return fmt.Errorf("template path '%s' not found", componentPath)
+		}
+
+		data, err := loadData(dataFile, dataFormat, setValues, fetchOptions(), cmd.InOrStdin(), cmd.ErrOrStderr())
+		if err != nil {
+			return err
+		}
+
+		log := cmd.OutOrStdout()
+		fmt.Fprintf(log, "🧩 Adding component from: %s\n", componentPath)
+		err = core.Add(core.Options{
+			TemplatePath:    componentPath,
+			OutputDir:       workPath("."),
+			Data:            data,
+			Strict:          strict,
+			NoFormat:        noFormat,
+			Out:             log,
+			MaxIncludeDepth: maxIncludeDepth,
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(log, "\n✅ Successfully added component: %s\n", componentPath)
+		return nil
+	},
+}
+
+//nolint:gochecknoinits // The command 'init' is acceptable.
+func init() {
+	// Add flags to the 'add' command.
+	addCmd.Flags().StringVarP(&dataFile, "data-file", "d", "",
+		"Path to a JSON or YAML file with data on top of the recorded data ('-' for stdin)")
+	addCmd.Flags().BoolVar(&noFormat, "no-format", false, "Skip the post-render formatter stage")
+	addRenderFlags(addCmd)
+	markPathFlags(addCmd.Flags(), "data-file")
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executeAdd runs the add command in projectDir.
+func executeAdd(t *testing.T, projectDir string, args ...string) (string, error) {
+	t.Helper()
+	t.Chdir(projectDir)
+	out, _, err := execute(t, addCmd, "", append([]string{"add"}, args...)...)
+	return out, err
+}
+
+func TestAddCmd(t *testing.T) {
+	templateDir := filepath.Join(t.TempDir(), "service")
+	require.NoError(t, os.MkdirAll(templateDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "main.go"), []byte("package main"), 0644))
+	componentDir := filepath.Join(t.TempDir(), "with-postgres")
+	require.NoError(t, os.MkdirAll(componentDir, 0755))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(componentDir, "db.yaml.tmpl"), []byte("name: {{.db_name}}"), 0644,
+	))
+	projectDir := t.TempDir()
+	require.NoError(t, core.Apply(core.Options{
+		TemplatePath: templateDir, OutputDir: projectDir, Data: map[string]any{"db_name": "app"}, Out: &bytes.Buffer{},
+	}))
+
+	out, err := executeAdd(t, projectDir, componentDir)
+	require.NoError(t, err)
+	assert.Contains(t, out, "➕ Adding: db.yaml")
+	content, err := os.ReadFile(filepath.Join(projectDir, "db.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "name: app", string(content))
+
+	out, err = executeAdd(t, projectDir, componentDir)
+	require.NoError(t, err)
+	assert.Contains(t, out, "Nothing to add")
+
+	out, err = executeAdd(t, projectDir, componentDir, "--set", "db_name=other")
+	require.NoError(t, err)
+	assert.Contains(t, out, "🔄 Updating: db.yaml")
+
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "db.yaml"), []byte("name: mine"), 0644))
+	_, err = executeAdd(t, projectDir, componentDir, "--set", "db_name=third")
+	require.ErrorContains(t, err, "refusing to overwrite files changed since the project was generated: db.yaml")
+
+	_, err = executeAdd(t, projectDir, filepath.Join(t.TempDir(), "missing"))
+	require.ErrorContains(t, err, "not found")
+}
@@ -1,41 +1,168 @@
 package cli
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"io/fs"
+	"io"
 	"os"
 	"path/filepath"
-	"strings"
+	"time"
 
 	"github.com/0m3kk/mold/internal/core"
-	"github.com/0m3kk/mold/internal/utils"
 
 	"github.com/spf13/cobra"
 )
 
 //nolint:gochecknoglobals // this is cmd flag
 var (
-	outputDir string
-	dataFile  string
+	outputDir       string
+	dataFile        string
+	strict          bool
+	noFormat        bool
+	setValues       []core.Override
+	force           bool
+	clock           string
+	fuzzyKeys       bool
+	subdir          string
+	keepPrefix      bool
+	noProvenance    bool
+	prune           bool
+	dryRun          bool
+	mergeMode       string
+	backup          bool
+	backupDir       string
+	profile         bool
+	profileTop      int
+	profileOut      string
+	maxTemplate     string
+	maxFiles        int
+	maxBytes        string
+	linkMode        string
+	keepSymlinks    bool
+	reportPath      string
+	templateVersion string
+	refreshVersions bool
+	quiet           bool
+	promptMissing   bool
+	noInput         bool
+	noWarnUnused    bool
+	noWarnMissing   bool
+	nameRule        string
+	sanitizeNames   bool
 )
 
 // applyCmd represents the apply command, renamed from createCmd.
 //
 //nolint:gochecknoglobals // this is command definition
 var applyCmd = &cobra.Command{
-	Use:   "apply <template_path>",
+	Use:   "apply <template_path> [layer_path...]",
 	Short: "Applies a template directory to generate a project using a data file",
 	Long: `Generates a project structure from a template directory.
 This command requires a data file (JSON or YAML) to render templates.
 It processes files ending in '.tmpl' by filling in placeholders from the data file
-and saves the result to the output directory. All other files are copied as-is.`,
-	Args: cobra.ExactArgs(1), // Requires exactly one argument: the path to the template.
-	RunE: func(_ *cobra.Command, args []string) error {
-		var err error
-		templatePath := args[0]
-
-		// 1. Validate the --data-file flag. It is now mandatory.
-		if dataFile == "" {
+and saves the result to the output directory. All other files are copied as-is.
+A '.tmpl' component elsewhere in a file name marks it too, so main.tmpl.go keeps
+its extension for editors and renders to main.go.
+When the output path ends in .tar, .tar.gz, .tgz or .zip, the files are written
+into that archive instead of a directory. An output of '-' writes an uncompressed
+tar stream to stdout and the progress messages to stderr.
+Files under '_partials' are not copied; templates include them with
+{{template "name" .}}.
+Generated files matching a glob in the template's 'formatters' section are then
+formatted in place.
+Further template paths are applied as layers on top of the first one, in order,
+with the same data. A file of a later layer replaces the file an earlier layer
+generates at the same path.
+A template argument that is not an existing path is looked up as a template
+name, such as 'go/service', in the templates directory, then in the registries
+of the config file, fetching the template it publishes into the cache.
+A name of the form name@version, or --version for the first template, pins the
+template to a version: a directory of its 'versions' directory, a version of
+its registry entry or a tag of its git repository. A version constraint such as
+^1.4, ~1.4.2, '>=1.2 <2' or 1.x resolves to the highest matching version, the
+one registry templates resolved to before unless --refresh is given. A template
+holding only a 'versions' directory resolves to its latest version without one.
+A path or name holding a literal '@' is used as it is; --version never splits
+the argument.
+With --subdir, only that subdirectory of the template is generated, relative to
+the output root unless --keep-prefix is given.
+A .mold.yaml file recording the template, its version, the data and the
+generated files is written at the output root, unless --no-provenance is given.
+With --prune, the files the previous run recorded there that this run no longer
+generates are deleted, unless they were modified since. With --merge, the files
+changed since the previous run get the template changes merged into them, using
+the content it generated, kept under .mold/base, as the base. --dry-run prints
+what would be generated, merged and pruned without writing anything.
+With --backup or --backup-dir, each existing file is copied into a backup
+directory before it is overwritten or pruned.
+With --profile, the time spent reading, parsing, executing, formatting, writing
+and copying each file is measured, and the slowest files and the phase totals
+are printed at the end. --profile-out writes the whole profile as JSON.
+Template files are read into memory to be rendered, so a '.tmpl' file over
+--max-template-size fails the run before anything is written, and one over half
+of it is warned about. --max-files and --max-bytes limit the files and bytes
+generated, checked on the plan before anything is written and again while
+writing, and extracted from the archive of a fetched template.
+File and directory names can hold __name__ tokens instead of {{.name}}, such
+as __project_name__ or __snake_service__, and write a literal '__' as '____'.
+The names rendered from placeholders are checked against --name-rule: the
+default windows-safe rejects control characters, <>:"\|?*, a leading '-', a
+trailing space or dot and the names Windows reserves such as CON, posix only
+accepts letters, digits, '.', '_' and '-', and permissive only rejects control
+characters. --sanitize-names replaces what the rule rejects with '_' instead
+of failing. A path rendered outside the output directory always fails.
+Generated paths only differing by case, which are one path on macOS and
+Windows, are warned about, or fail the run with --strict or when a probe file
+shows the output directory ignores case.
+The output path can hold placeholders, such as -o './{{.project_name}}', which
+are filled from the data file and --set values.
+With --link hard or --link symlink, the copied files are linked to the template
+files instead of duplicated, so editing one edits the other. Rendered files are
+always written.
+With --preserve-symlinks, the symlinks of the template are recreated as
+symlinks, with placeholders in their targets replaced, such as
+'current -> releases/{{.version}}'.
+Named pipes, sockets and devices in the template, which would block the run if
+read, are skipped with a warning, or fail the run with --strict.
+With --prompt-missing, the data file is optional: the values of the prompts and
+placeholders it doesn't provide are asked for on stderr and read from stdin,
+following the types, rules and when conditions of the prompts. The answers are
+recorded in the provenance like the rest of the data. --no-input never asks,
+leaving the missing values to fail as usual.
+The notices of the templates are printed after the summary, and the message of a
+deprecated template as a warning. --quiet prints the warnings and errors only.
+Once the run succeeded, the data keys no template or path uses, which are often
+misspelled, are warned about, and so are the references to missing keys that
+rendered as <no value> outside --strict, with the file and line of each. Both
+lists are part of the report; --no-warn-unused and --no-warn-missing turn them
+off.
+With --report, a JSON document describing the run is written to the given file,
+even when it fails: the template, the effective options, what was done with each
+file and its hash, the warnings, the timing and the error, if any.`,
+	Args: cobra.MinimumNArgs(1), // Requires the path to the template, optionally followed by layers.
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		templatePath := workPath(args[0])
+		log, errLog := cmd.OutOrStdout(), cmd.ErrOrStderr()
+		if quiet {
+			log, errLog = &warningsOnly{w: log}, &warningsOnly{w: errLog}
+		}
+		// The observer keeps the result, holding the notices printed at the
+		// end, and writes it to --report once the run is over.
+		report := &reportObserver{log: log}
+		if reportPath != "" {
+			// Automation reads the report to diagnose failures too, those
+			// before the run included.
+			defer func() { err = report.finish(err) }()
+		}
+
+		// 1. Validate the --data-file flag. It is mandatory unless the missing
+		// values are asked for.
+		ask := promptMissing && !noInput
+		if ask && dataFile == stdinPath {
+			return errors.New("--prompt-missing reads the answers from stdin, so the data can't be read from it")
+		}
+		if dataFile == "" && !ask {
 			// Check if an example data file exists to provide a helpful hint.
 			exampleHint := ""
 			exampleYAML := filepath.Join(templatePath, "tmpl.yaml")
@@ -52,80 +179,326 @@ and saves the result to the output directory. All other files are copied as-is.`
 			return fmt.Errorf("the --data-file flag is required for rendering templates.%s", exampleHint)
 		}
 
-		// 2. Validate Template Path
-		if _, err = os.Stat(templatePath); os.IsNotExist(err) {
-			return fmt.Errorf("template path '%s' not found", templatePath)
+		// The limits apply to the archives of fetched templates too.
+		var limitBytes int64
+		if limitBytes, err = core.ParseSize(maxBytes); err != nil {
+			return fmt.Errorf("invalid --max-bytes value '%s': expected a size such as 500MB or 10GB", maxBytes)
+		}
+
+		// 2. Resolve Template Paths, or names in the templates directory or
+		// the registries. Fetching reports to stderr, stdout may be the output.
+		origins := make(map[string]core.TemplateOrigin)
+		for i, arg := range args {
+			version := ""
+			if i == 0 {
+				version = templateVersion
+			}
+			var origin *core.TemplateOrigin
+			if args[i], origin, err = resolveApplyTemplate(arg, version, refreshVersions, errLog); err != nil {
+				return err
+			}
+			if origin != nil {
+				origins[args[i]] = *origin
+			}
+		}
+		templatePath = args[0]
+		var modTime time.Time
+		if modTime, err = parseClock(clock); err != nil {
+			return err
+		}
+		var merge core.MergeMode
+		if merge, err = core.ParseMergeMode(mergeMode); err != nil {
+			return err
+		}
+		var link core.LinkMode
+		if link, err = core.ParseLinkMode(linkMode); err != nil {
+			return err
+		}
+		var names core.NameRule
+		if names, err = core.ParseNameRule(nameRule); err != nil {
+			return err
+		}
+		var maxSize int64
+		if maxSize, err = core.ParseSize(maxTemplate); err != nil {
+			return fmt.Errorf("invalid --max-template-size value '%s': expected a size such as 512KB or 10MB",
+				maxTemplate)
+		}
+		var sink core.Sink
+		if outputDir == stdinPath {
+			// Keep stdout for the tar stream.
+			if sink, err = streamSink(cmd.OutOrStdout(), modTime); err != nil {
+				return err
+			}
+			log = errLog
+		}
+		fmt.Fprintf(log, "🚀 Applying template from: %s\n", templatePath)
+		for _, layer := range args[1:] {
+			fmt.Fprintf(log, "🧩 Layering template from: %s\n", layer)
 		}
-		fmt.Printf("🚀 Applying template from: %s\n", templatePath)
 
 		// 3. Load data from the specified file.
-		fmt.Printf("📖 Loading data from: %s\n", dataFile)
+		if dataFile != "" {
+			fmt.Fprintf(log, "📖 Loading data from: %s\n", dataFile)
+		}
 		var data map[string]any
-		data, err = core.LoadDataFile(dataFile)
+		data, err = loadData(dataFile, dataFormat, setValues, fetchOptions(), cmd.InOrStdin(), cmd.ErrOrStderr())
 		if err != nil {
 			return err // Error is already descriptive.
 		}
-
-		// 4. Create output directory if it doesn't exist.
-		if err = os.MkdirAll(outputDir, 0750); err != nil {
-			return fmt.Errorf("failed to create output directory '%s': %w", outputDir, err)
+		var asker *core.Asker
+		if ask {
+			// The questions stay visible with --quiet and a tar stream on stdout.
+			asker = core.NewAsker(cmd.InOrStdin(), cmd.ErrOrStderr())
+			// The output path is rendered first, so its keys are asked first.
+			// Rendering it reports a path that doesn't parse.
+			keys, _ := core.IdentifyPlaceholders("output", outputDir)
+			if _, err = asker.AskMissing(nil, keys, nil, data, nil); err != nil {
+				return err
+			}
+		}
+		// The data the output path uses isn't unused.
+		outputRefs, _ := core.IdentifyReferences("output", outputDir)
+		var output string
+		if output, err = core.ReplacePlaceholdersInOutput(outputDir, data); err != nil {
+			return err
+		}
+		if output != outputDir {
+			fmt.Fprintf(log, "📂 Generating into: %s\n", output)
 		}
 
-		// 5. Walk the template directory to render/copy files.
-		err = filepath.WalkDir(templatePath, func(path string, d fs.DirEntry, walkErr error) error {
-			if walkErr != nil {
-				return walkErr
+		// 4. Render and copy the template into the output directory.
+		var profiler core.Profiler
+		prof := newProfile()
+		if prof != nil {
+			profiler = prof
+		}
+		err = core.Apply(core.Options{
+			TemplatePath:     templatePath,
+			Layers:           args[1:],
+			OutputDir:        output,
+			Data:             data,
+			Strict:           strict,
+			NoFormat:         noFormat,
+			FuzzyKeys:        fuzzyKeys,
+			Out:              log,
+			Sink:             sink,
+			Clock:            modTime,
+			Subdir:           subdir,
+			KeepPrefix:       keepPrefix,
+			NoProvenance:     noProvenance,
+			Prune:            prune,
+			DryRun:           dryRun,
+			Merge:            merge,
+			BackupDir:        backupPath(output, modTime),
+			Profiler:         profiler,
+			MaxTemplateSize:  maxSize,
+			MaxIncludeDepth:  maxIncludeDepth,
+			MaxFiles:         maxFiles,
+			MaxBytes:         limitBytes,
+			Link:             link,
+			PreserveSymlinks: keepSymlinks,
+			Observer:         report,
+			Origins:          origins,
+			Ask:              asker,
+			NoWarnUnused:     noWarnUnused,
+			NoWarnMissing:    noWarnMissing,
+			Referenced:       outputRefs,
+			NameRule:         names,
+			SanitizeNames:    sanitizeNames,
+		})
+		if err != nil {
+			return err
+		}
+		if prof != nil {
+			if err = reportProfile(log, prof); err != nil {
+				return err
 			}
+		}
 
-			// Skip hit files
-			if d.Name() == "tmpl.json" || d.Name() == "tmpl.yaml" {
-				return nil
-			}
+		// 5. Success Message
+		destination := output
+		if outputDir == stdinPath {
+			destination = "stdout"
+		}
+		if dryRun {
+			fmt.Fprintf(log, "\n✅ Dry run finished, nothing was written to: %s\n", destination)
+		} else {
+			fmt.Fprintf(log, "\n✅ Successfully applied template to: %s\n", destination)
+		}
+		for _, notice := range report.result.Notices {
+			fmt.Fprintf(log, "📣 %s\n", notice)
+		}
+		return nil
+	},
+}
 
-			// Determine the destination path for the file or directory.
-			relPath, innerErr := filepath.Rel(templatePath, path)
-			if innerErr != nil {
-				return fmt.Errorf("failed to get relative path for '%s': %w", path, innerErr)
-			}
-			// Replace placeholders in relative path
-			relPath, innerErr = core.ReplacePlaceholdersInPath(relPath, data)
-			if innerErr != nil {
-				return fmt.Errorf("failed to replace placeholders in path '%s': %w", relPath, innerErr)
-			}
-			destPath := filepath.Join(outputDir, relPath)
+// warningsOnly passes the warnings written to it through to w, the lines
+// starting with "⚠️", and drops the progress messages.
+type warningsOnly struct {
+	w    io.Writer
+	line []byte
+}
 
-			if d.IsDir() {
-				// Create the corresponding directory in the destination.
-				return os.MkdirAll(destPath, 0750)
+func (q *warningsOnly) Write(p []byte) (int, error) {
+	q.line = append(q.line, p...)
+	for {
+		i := bytes.IndexByte(q.line, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if bytes.HasPrefix(q.line, []byte("⚠️")) {
+			if _, err := q.w.Write(q.line[:i+1]); err != nil {
+				return 0, err
 			}
+		}
+		q.line = q.line[i+1:]
+	}
+}
 
-			// Decide whether to render or copy the file.
-			if strings.HasSuffix(d.Name(), ".tmpl") {
-				// This is a template file that needs to be rendered.
-				finalDestPath := strings.TrimSuffix(destPath, ".tmpl")
-				fmt.Printf("✨ Rendering: %s -> %s\n", relPath, strings.TrimSuffix(relPath, ".tmpl"))
-				return core.RenderTemplateFile(path, finalDestPath, data)
-			}
+// streamSink returns a sink writing a tar stream to w. Binary output is not
+// written to a terminal unless --force is given.
+func streamSink(w io.Writer, modTime time.Time) (core.Sink, error) {
+	if file, ok := w.(*os.File); ok && !force {
+		if info, err := file.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			return nil, errors.New("refusing to write a tar stream to a terminal, redirect stdout or use --force")
+		}
+	}
+	return core.NewTarStreamSink(w, modTime), nil
+}
 
-			// This is a regular file, so just copy it.
-			fmt.Printf("📄 Copying: %s\n", relPath)
-			return utils.CopyFile(path, destPath)
-		})
+// reportObserver keeps the result of the run and writes it to --report
+// once the run is over.
+type reportObserver struct {
+	core.NopObserver
+	log    io.Writer
+	result *core.Result
+	// err is the failure to write the report.
+	err error
+}
 
-		if err != nil {
-			return fmt.Errorf("error during template processing: %w", err)
-		}
+func (o *reportObserver) Done(result *core.Result) {
+	o.result = result
+	if reportPath != "" {
+		o.err = writeReport(o.log, result)
+	}
+}
 
-		// 6. Success Message
-		fmt.Printf("\n✅ Successfully applied template to: %s\n", outputDir)
-		return nil
-	},
+// finish writes the report of a command that failed before the run, and
+// returns the error it failed with, joined with any failure to write the
+// report.
+func (o *reportObserver) finish(runErr error) error {
+	if o.result == nil {
+		o.result = core.NewResult()
+		o.result.Finish(runErr)
+		o.err = writeReport(o.log, o.result)
+	}
+	if o.err != nil {
+		return errors.Join(runErr, o.err)
+	}
+	return runErr
+}
+
+// writeReport writes a result to --report.
+func writeReport(log io.Writer, result *core.Result) error {
+	content, err := result.Marshal()
+	if err == nil {
+		err = os.WriteFile(reportPath, content, 0600)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write report '%s': %w", reportPath, err)
+	}
+	fmt.Fprintf(log, "🧾 Report written to: %s\n", reportPath)
+	return nil
+}
+
+// backupPath returns the backup directory of the run: --backup-dir, or a new
+// directory under the .mold/backup of the output with --backup.
+func backupPath(output string, modTime time.Time) string {
+	if backupDir != "" || !backup {
+		return backupDir
+	}
+	if modTime.IsZero() {
+		modTime = time.Now()
+	}
+	return core.DefaultBackupDir(output, modTime)
+}
+
+// parseClock parses the --clock flag. An empty value means the current time.
+func parseClock(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --clock value '%s': expected an RFC 3339 timestamp", value)
+	}
+	return t, nil
 }
 
 //nolint:gochecknoinits // The command 'init' is acceptable.
 func init() {
 	// Add flags to the 'apply' command.
-	applyCmd.Flags().StringVarP(&outputDir, "output", "o", ".", "Output directory for the new project")
-	applyCmd.Flags().
-		StringVarP(&dataFile, "data-file", "d", "", "Path to a JSON or YAML file with placeholder data (required)")
+	applyCmd.Flags().StringVarP(&outputDir, "output", "o", ".",
+		"Output directory for the new project, an archive path (.tar, .tar.gz, .tgz, .zip) or '-' for a tar stream on stdout")
+	applyCmd.Flags().StringVarP(&dataFile, "data-file", "d", "",
+		"Path to a JSON or YAML file with placeholder data, '-' for stdin (required without --prompt-missing)")
+	applyCmd.Flags().BoolVar(&noFormat, "no-format", false, "Skip the post-render formatter stage")
+	applyCmd.Flags().BoolVar(&fuzzyKeys, "fuzzy-keys", false,
+		"Match data keys spelled in another case style, such as projectName for project_name (ignored with --strict)")
+	applyCmd.Flags().BoolVar(&force, "force", false, "Write a tar stream to stdout even when it is a terminal")
+	applyCmd.Flags().StringVar(&clock, "clock", "",
+		"RFC 3339 timestamp for archive entries and the provenance, for reproducible archives (default now)")
+	applyCmd.Flags().StringVar(&subdir, "subdir", "",
+		"Only generate this subdirectory of the template, such as 'deploy', relative to the output root")
+	applyCmd.Flags().BoolVar(&keepPrefix, "keep-prefix", false,
+		"Keep the --subdir path in the generated paths")
+	applyCmd.Flags().BoolVar(&noProvenance, "no-provenance", false,
+		"Do not record the template and data in a .mold.yaml file at the output root")
+	applyCmd.Flags().BoolVar(&prune, "prune", false,
+		"Delete the unmodified files of the previous run that the template no longer generates")
+	applyCmd.Flags().BoolVar(&dryRun, "dry-run", false,
+		"Print what would be generated, merged and pruned without writing anything")
+	applyCmd.Flags().StringVar(&mergeMode, "merge", string(core.MergeOff),
+		"Merge the template changes into files changed since the previous run: off, clean-only or always")
+	applyCmd.Flags().BoolVar(&backup, "backup", false,
+		"Copy the existing files into .mold/backup/<timestamp> in the output before overwriting or pruning them")
+	applyCmd.Flags().StringVar(&backupDir, "backup-dir", "",
+		"Copy the existing files into this directory before overwriting or pruning them (implies --backup)")
+	applyCmd.Flags().BoolVar(&profile, "profile", false,
+		"Time the phases of generating each file and print the slowest files and the phase totals")
+	applyCmd.Flags().IntVar(&profileTop, "profile-top", 10, "Number of slowest files printed by --profile")
+	applyCmd.Flags().StringVar(&profileOut, "profile-out", "",
+		"Write the whole profile as JSON to this file (implies profiling)")
+	applyCmd.Flags().StringVar(&maxTemplate, "max-template-size", "10MB",
+		"Fail on '.tmpl' files larger than this, such as 512KB or 10MB, and warn above half of it (0 for no limit)")
+	applyCmd.Flags().IntVar(&maxFiles, "max-files", core.DefaultMaxFiles,
+		"Fail when the run generates, or a fetched template archive holds, more files than this (0 for no limit)")
+	applyCmd.Flags().StringVar(&maxBytes, "max-bytes", "10GB",
+		"Fail when the run generates, or a fetched template archive holds, more bytes than this (0 for no limit)")
+	applyCmd.Flags().StringVar(&linkMode, "link", string(core.LinkCopy),
+		"Put the copied files into the output as copies, hard links or symlinks to the template: copy, hard or symlink")
+	applyCmd.Flags().BoolVar(&keepSymlinks, "preserve-symlinks", false,
+		"Recreate the symlinks of the template as symlinks, with placeholders in their targets replaced")
+	applyCmd.Flags().StringVar(&reportPath, "report", "",
+		"Write a JSON report of the run, its files, warnings and error, to this file, even when it fails")
+	applyCmd.Flags().StringVar(&templateVersion, "version", "",
+		"Apply this version of the template, taking the template argument literally")
+	applyCmd.Flags().BoolVarP(&quiet, "quiet", "q", false,
+		"Only print warnings, such as deprecations, and errors: no progress messages or template notices")
+	applyCmd.Flags().BoolVar(&promptMissing, "prompt-missing", false,
+		"Ask for the values of the prompts and placeholders missing from the data, reading the answers from stdin")
+	applyCmd.Flags().BoolVar(&noInput, "no-input", false,
+		"Never ask for input, even with --prompt-missing, failing on missing values as usual")
+	applyCmd.Flags().BoolVar(&noWarnUnused, "no-warn-unused", false,
+		"Don't warn about the data keys no template or path uses")
+	applyCmd.Flags().BoolVar(&noWarnMissing, "no-warn-missing", false,
+		"Don't warn about the references to missing keys rendered as <no value>")
+	applyCmd.Flags().BoolVar(&refreshVersions, "refresh", false,
+		"Resolve version constraints such as ^1.4 against the current tags instead of the cached resolutions")
+	applyCmd.Flags().StringVar(&nameRule, "name-rule", string(core.NameWindowsSafe),
+		"Names the rendered file and directory names can have: posix, windows-safe or permissive")
+	applyCmd.Flags().BoolVar(&sanitizeNames, "sanitize-names", false,
+		"Replace the characters --name-rule rejects in rendered names with '_' instead of failing")
+	addRenderFlags(applyCmd)
+	markPathFlags(applyCmd.Flags(), "output", "data-file", "backup-dir", "profile-out", "report")
 }
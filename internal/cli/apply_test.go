@@ -1,13 +1,20 @@
 package cli
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"encoding/json"
+	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/0m3kk/mold/internal/core"
 
-	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
@@ -197,6 +204,71 @@ func TestApplyCmd(t *testing.T) {
 				return tempDir, templateDir, dataFile, "", func() {}
 			},
 		},
+		{
+			name: "format_generated_go_files",
+			args: []string{"template"},
+			setupFunc: func(t *testing.T) (string, string, string, string, func()) {
+				tempDir := t.TempDir()
+				templateDir := filepath.Join(tempDir, "template")
+				dataFile := filepath.Join(tempDir, "data.yaml")
+				outputDir := filepath.Join(tempDir, "output")
+
+				require.NoError(t, os.MkdirAll(templateDir, 0755))
+				require.NoError(t, os.WriteFile(
+					filepath.Join(templateDir, "template.yaml"),
+					[]byte("formatters:\n  \"**/*.go\": [\"gofmt\", \"-w\"]\n"),
+					0644,
+				))
+				require.NoError(t, os.WriteFile(
+					filepath.Join(templateDir, "main.go.tmpl"),
+					[]byte("package {{.package_name}}\nfunc main() {\n      println(1)\n}\n"),
+					0644,
+				))
+				require.NoError(t, os.WriteFile(dataFile, []byte("package_name: main"), 0644))
+
+				return tempDir, templateDir, dataFile, outputDir, func() {}
+			},
+			validateOutput: func(t *testing.T, outputDir string) {
+				mainContent, err := os.ReadFile(filepath.Join(outputDir, "main.go"))
+				require.NoError(t, err)
+				assert.Equal(t, "package main\n\nfunc main() {\n\tprintln(1)\n}\n", string(mainContent))
+
+				_, err = os.Stat(filepath.Join(outputDir, "template.yaml"))
+				assert.True(t, os.IsNotExist(err))
+			},
+		},
+		{
+			name: "apply_to_zip_archive",
+			args: []string{"template"},
+			setupFunc: func(t *testing.T) (string, string, string, string, func()) {
+				tempDir := t.TempDir()
+				templateDir := filepath.Join(tempDir, "template")
+				dataFile := filepath.Join(tempDir, "data.yaml")
+				outputDir := filepath.Join(tempDir, "project.zip")
+
+				require.NoError(t, os.MkdirAll(filepath.Join(templateDir, "{{.name}}"), 0755))
+				require.NoError(t, os.WriteFile(
+					filepath.Join(templateDir, "{{.name}}", "README.md.tmpl"),
+					[]byte("# {{.name}}"),
+					0644,
+				))
+				require.NoError(t, os.WriteFile(dataFile, []byte("name: demo"), 0644))
+
+				return tempDir, templateDir, dataFile, outputDir, func() {}
+			},
+			validateOutput: func(t *testing.T, outputDir string) {
+				zr, err := zip.OpenReader(outputDir)
+				require.NoError(t, err)
+				defer zr.Close()
+
+				rc, err := zr.Open("demo/README.md")
+				require.NoError(t, err)
+				defer rc.Close()
+				content, err := io.ReadAll(rc)
+				require.NoError(t, err)
+				assert.Equal(t, "# demo", string(content))
+			},
+		},
 		{
 			name: "skip_tmpl_files",
 			args: []string{"template"},
@@ -243,17 +315,9 @@ func TestApplyCmd(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Reset global variables
-			outputDir = "."
-			dataFile = ""
-
 			tempDir, templateDir, dataFileVar, outputDirVar, cleanup := tt.setupFunc(t)
 			defer cleanup()
 
-			// Set up command with flags
-			cmd := &cobra.Command{}
-			cmd.AddCommand(applyCmd)
-
 			// Build command line args
 			args := []string{"apply"}
 			if templateDir != "" {
@@ -282,8 +346,7 @@ func TestApplyCmd(t *testing.T) {
 				defer func() { t.Chdir(originalWd) }()
 			}
 
-			cmd.SetArgs(args)
-			err := cmd.Execute()
+			_, _, err := execute(t, applyCmd, "", args...)
 
 			if tt.expectedError != "" {
 				require.Error(t, err)
@@ -298,6 +361,54 @@ func TestApplyCmd(t *testing.T) {
 	}
 }
 
+func TestApplyCmdStdout(t *testing.T) {
+	tempDir := t.TempDir()
+	templateDir := filepath.Join(tempDir, "template")
+	require.NoError(t, os.MkdirAll(templateDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "README.md.tmpl"), []byte("# {{.name}}"), 0644))
+	dataPath := filepath.Join(tempDir, "data.yaml")
+	require.NoError(t, os.WriteFile(dataPath, []byte("name: demo"), 0644))
+
+	stdout, stderr, err := execute(t, applyCmd, "",
+		"apply", templateDir, "-d", dataPath, "-o", "-", "--clock", "2024-01-02T03:04:05Z")
+	require.NoError(t, err)
+
+	tr := tar.NewReader(strings.NewReader(stdout))
+	header, err := tr.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "README.md", header.Name)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), header.ModTime.UTC())
+	content, err := io.ReadAll(tr)
+	require.NoError(t, err)
+	assert.Equal(t, "# demo", string(content))
+
+	// The provenance comes last.
+	header, err = tr.Next()
+	require.NoError(t, err)
+	assert.Equal(t, core.ProvenanceFile, header.Name)
+	content, err = io.ReadAll(tr)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "generated_at: 2024-01-02T03:04:05Z")
+	_, err = tr.Next()
+	assert.Equal(t, io.EOF, err)
+
+	assert.Contains(t, stderr, "✨ Rendering: README.md.tmpl -> README.md")
+	assert.Contains(t, stderr, "Successfully applied template to: stdout")
+}
+
+func TestParseClock(t *testing.T) {
+	got, err := parseClock("")
+	require.NoError(t, err)
+	assert.True(t, got.IsZero())
+
+	got, err = parseClock("2024-01-02T03:04:05+02:00")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 2, 1, 4, 5, 0, time.UTC), got.UTC())
+
+	_, err = parseClock("yesterday")
+	require.ErrorContains(t, err, "invalid --clock value 'yesterday'")
+}
+
 func TestApplyCmdFlags(t *testing.T) {
 	// Test that flags are properly registered
 	assert.True(t, applyCmd.Flags().HasFlags())
@@ -311,10 +422,30 @@ func TestApplyCmdFlags(t *testing.T) {
 	require.NotNil(t, dataFileFlag)
 	assert.Equal(t, "d", dataFileFlag.Shorthand)
 	assert.Empty(t, dataFileFlag.DefValue)
+
+	strictFlag := applyCmd.Flags().Lookup("strict")
+	require.NotNil(t, strictFlag)
+	assert.Equal(t, "false", strictFlag.DefValue)
+
+	noFormatFlag := applyCmd.Flags().Lookup("no-format")
+	require.NotNil(t, noFormatFlag)
+	assert.Equal(t, "false", noFormatFlag.DefValue)
+
+	fuzzyKeysFlag := applyCmd.Flags().Lookup("fuzzy-keys")
+	require.NotNil(t, fuzzyKeysFlag)
+	assert.Equal(t, "false", fuzzyKeysFlag.DefValue)
+
+	forceFlag := applyCmd.Flags().Lookup("force")
+	require.NotNil(t, forceFlag)
+	assert.Equal(t, "false", forceFlag.DefValue)
+
+	clockFlag := applyCmd.Flags().Lookup("clock")
+	require.NotNil(t, clockFlag)
+	assert.Empty(t, clockFlag.DefValue)
 }
 
 func TestApplyCmdBasicProperties(t *testing.T) {
-	assert.Equal(t, "apply <template_path>", applyCmd.Use)
+	assert.Equal(t, "apply <template_path> [layer_path...]", applyCmd.Use)
 	assert.Equal(t, "Applies a template directory to generate a project using a data file", applyCmd.Short)
 	assert.Contains(t, applyCmd.Long, "Generates a project structure from a template directory")
 }
@@ -344,17 +475,7 @@ func TestApplyCmdArgumentValidation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Reset global variables
-			outputDir = "."
-			dataFile = ""
-
-			cmd := &cobra.Command{}
-			cmd.AddCommand(applyCmd)
-
-			args := append([]string{"apply"}, tt.args...)
-			cmd.SetArgs(args)
-
-			err := cmd.Execute()
+			_, _, err := execute(t, applyCmd, "", append([]string{"apply"}, tt.args...)...)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -384,32 +505,501 @@ func TestApplyCmdErrorHandling(t *testing.T) {
 		invalidOutputDir := filepath.Join(tempDir, "existing_file")
 		require.NoError(t, os.WriteFile(invalidOutputDir, []byte("block"), 0644))
 
-		// Reset global variables
-		outputDir = "."
-		dataFile = ""
-
-		cmd := &cobra.Command{}
-		cmd.AddCommand(applyCmd)
-
 		originalWd, _ := os.Getwd()
 		// require.NoError(t, os.Chdir(tempDir))
 		t.Chdir(tempDir)
 		defer func() { t.Chdir(originalWd) }()
 
 		relTemplatePath, _ := filepath.Rel(tempDir, templateDir)
-		args := []string{"apply", relTemplatePath, "--data-file", dataFileVar, "--output", invalidOutputDir}
-		cmd.SetArgs(args)
-
-		err := cmd.Execute()
+		_, _, err := execute(t, applyCmd, "",
+			"apply", relTemplatePath, "--data-file", dataFileVar, "--output", invalidOutputDir)
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to create output directory")
 	})
 }
 
+func TestApplyCmdSubdir(t *testing.T) {
+	tempDir := t.TempDir()
+	templateDir := filepath.Join(tempDir, "template")
+	require.NoError(t, os.MkdirAll(filepath.Join(templateDir, "deploy", "k8s"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "deploy", "k8s", "x.yaml.tmpl"),
+		[]byte("name: {{.name}}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "README.md"), []byte("# readme"), 0644))
+	dataPath := filepath.Join(tempDir, "data.yaml")
+	require.NoError(t, os.WriteFile(dataPath, []byte("name: demo"), 0644))
+
+	run := func(args ...string) error {
+		_, _, err := execute(t, applyCmd, "", append([]string{"apply", templateDir, "-d", dataPath}, args...)...)
+		return err
+	}
+
+	out := filepath.Join(tempDir, "out")
+	require.NoError(t, run("-o", out, "--subdir", "deploy"))
+	content, err := os.ReadFile(filepath.Join(out, "k8s", "x.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "name: demo", string(content))
+	assert.NoFileExists(t, filepath.Join(out, "README.md"))
+
+	prefixed := filepath.Join(tempDir, "prefixed")
+	require.NoError(t, run("-o", prefixed, "--subdir", "deploy", "--keep-prefix"))
+	assert.FileExists(t, filepath.Join(prefixed, "deploy", "k8s", "x.yaml"))
+
+	err = run("-o", filepath.Join(tempDir, "missing"), "--subdir", "nope")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "subdirectory 'nope' not found in template")
+}
+
+func TestApplyCmdPrune(t *testing.T) {
+	tempDir := t.TempDir()
+	templateDir := filepath.Join(tempDir, "template")
+	require.NoError(t, os.MkdirAll(filepath.Join(templateDir, "old"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "README.md"), []byte("# readme"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "old", "x.txt"), []byte("x"), 0644))
+	dataPath := filepath.Join(tempDir, "data.yaml")
+	require.NoError(t, os.WriteFile(dataPath, []byte("name: demo"), 0644))
+	out := filepath.Join(tempDir, "out")
+
+	run := func(args ...string) (string, error) {
+		stdout, _, err := execute(t, applyCmd, "",
+			append([]string{"apply", templateDir, "-d", dataPath, "-o", out}, args...)...)
+		return stdout, err
+	}
+
+	_, err := run()
+	require.NoError(t, err)
+	require.NoError(t, os.RemoveAll(filepath.Join(templateDir, "old")))
+
+	log, err := run("--prune", "--dry-run")
+	require.NoError(t, err)
+	assert.Contains(t, log, "🧹 Would prune: old/x.txt")
+	assert.Contains(t, log, "✅ Dry run finished, nothing was written to: "+out)
+	assert.FileExists(t, filepath.Join(out, "old", "x.txt"))
+
+	log, err = run("--prune")
+	require.NoError(t, err)
+	assert.Contains(t, log, "🧹 Pruning: old/x.txt")
+	assert.NoDirExists(t, filepath.Join(out, "old"))
+	assert.FileExists(t, filepath.Join(out, "README.md"))
+
+	// A change to an unchanged template file survives with --merge.
+	require.NoError(t, os.WriteFile(filepath.Join(out, "README.md"), []byte("# readme\nmine\n"), 0644))
+	log, err = run("--merge", "clean-only")
+	require.NoError(t, err)
+	assert.Contains(t, log, "🔀 Merged your changes to: README.md")
+	content, err := os.ReadFile(filepath.Join(out, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# readme\nmine\n", string(content))
+
+	_, err = run("--merge", "sometimes")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid merge mode 'sometimes'")
+
+	// --backup keeps the overwritten files under .mold/backup.
+	log, err = run("--backup", "--clock", "2024-05-01T12:30:00Z")
+	require.NoError(t, err)
+	backupDir := filepath.Join(out, ".mold", "backup", "20240501T123000Z")
+	assert.Contains(t, log, "💾 Backed up 2 files to: "+backupDir)
+	content, err = os.ReadFile(filepath.Join(backupDir, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# readme\nmine\n", string(content))
+}
+
 // TestInit verifies the init function runs without panicking.
 func TestInit(t *testing.T) {
 	// The init function should have already run when the package was loaded
 	// We just verify the command has the expected flags
 	assert.NotNil(t, applyCmd.Flags().Lookup("output"))
 	assert.NotNil(t, applyCmd.Flags().Lookup("data-file"))
+	assert.NotNil(t, applyCmd.Flags().Lookup("subdir"))
+	assert.NotNil(t, applyCmd.Flags().Lookup("keep-prefix"))
+}
+
+func TestApplyCmdProfile(t *testing.T) {
+	tempDir := t.TempDir()
+	templateDir := filepath.Join(tempDir, "template")
+	require.NoError(t, os.MkdirAll(templateDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "README.md.tmpl"), []byte("# {{.name}}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "LICENSE"), []byte("MIT"), 0644))
+	dataPath := filepath.Join(tempDir, "data.yaml")
+	require.NoError(t, os.WriteFile(dataPath, []byte("name: demo"), 0644))
+
+	run := func(args ...string) (string, error) {
+		stdout, _, err := execute(t, applyCmd, "",
+			append([]string{"apply", templateDir, "-d", dataPath, "-o", filepath.Join(tempDir, "out")}, args...)...)
+		return stdout, err
+	}
+
+	log, err := run()
+	require.NoError(t, err)
+	assert.NotContains(t, log, "Profiled")
+
+	log, err = run("--profile", "--profile-top", "1")
+	require.NoError(t, err)
+	assert.Contains(t, log, "⏱️  Profiled 2 files in ")
+	assert.Regexp(t, `\n   execute +\S+\n`, log)
+	assert.Contains(t, log, "🐢 Slowest files:")
+	assert.Equal(t, 1, strings.Count(log, " (read ")+strings.Count(log, " (copy "), "only the slowest file is listed")
+
+	profilePath := filepath.Join(tempDir, "profile.json")
+	log, err = run("--profile-out", profilePath)
+	require.NoError(t, err)
+	assert.NotContains(t, log, "Profiled", "--profile-out alone doesn't print the profile")
+	assert.Contains(t, log, "⏱️  Profile written to: "+profilePath)
+	content, err := os.ReadFile(profilePath)
+	require.NoError(t, err)
+	var report core.ProfileReport
+	require.NoError(t, json.Unmarshal(content, &report))
+	phases := make(map[string][]core.Phase)
+	for _, file := range report.Files {
+		phases[file.Path] = slices.Sorted(maps.Keys(file.Phases))
+	}
+	assert.Equal(t, map[string][]core.Phase{
+		"LICENSE":   {core.PhaseCopy},
+		"README.md": {core.PhaseExecute, core.PhaseParse, core.PhaseRead, core.PhaseWrite},
+	}, phases)
+}
+
+func TestApplyCmdReport(t *testing.T) {
+	tempDir := t.TempDir()
+	templateDir := filepath.Join(tempDir, "template")
+	require.NoError(t, os.MkdirAll(templateDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, core.MetadataFile),
+		[]byte("name: demo\nversion: 1.0.0\nprompts:\n  port: {type: int}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "README.md.tmpl"), []byte("# {{.name}}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "LICENSE"), []byte("MIT"), 0644))
+	reportFile := filepath.Join(tempDir, "result.json")
+
+	run := func(data string) (core.Result, string, error) {
+		dataPath := filepath.Join(tempDir, "data.yaml")
+		require.NoError(t, os.WriteFile(dataPath, []byte(data), 0644))
+		require.NoError(t, os.RemoveAll(reportFile))
+		stdout, _, err := execute(t, applyCmd, "",
+			"apply", templateDir, "-d", dataPath, "-o", filepath.Join(tempDir, "out"), "--report", reportFile)
+		var result core.Result
+		content, readErr := os.ReadFile(reportFile)
+		require.NoError(t, readErr)
+		require.NoError(t, json.Unmarshal(content, &result))
+		return result, stdout, err
+	}
+
+	result, log, err := run("name: demo\nport: 8080")
+	require.NoError(t, err)
+	assert.Contains(t, log, "🧾 Report written to: "+reportFile)
+	assert.Equal(t, core.ResultSchema, result.Schema)
+	assert.True(t, result.Success)
+	assert.Equal(t, "demo", result.Template.Name)
+	assert.Equal(t, "1.0.0", result.Template.Version)
+	assert.Equal(t, core.LinkCopy, result.Options.Link)
+	require.Len(t, result.Files, 2)
+	assert.Equal(t, "LICENSE", result.Files[0].Path)
+	assert.Equal(t, core.StatusCopied, result.Files[0].Status)
+	assert.Equal(t, core.StatusRendered, result.Files[1].Status)
+	assert.Len(t, result.Files[1].SHA256, 64)
+
+	// A failed run is reported too.
+	result, _, err = run("name: demo\nport: http")
+	require.ErrorContains(t, err, "invalid value for 'port'")
+	assert.False(t, result.Success)
+	assert.Equal(t, err.Error(), result.Error)
+	assert.Empty(t, result.Files)
+
+	// So is a run failing before the template is applied.
+	_, _, err = execute(t, applyCmd, "",
+		"apply", templateDir, "-d", filepath.Join(tempDir, "missing.yaml"), "--report", reportFile)
+	require.Error(t, err)
+	content, readErr := os.ReadFile(reportFile)
+	require.NoError(t, readErr)
+	assert.Contains(t, string(content), `"success": false`)
+}
+
+func TestApplyCmdMaxTemplateSize(t *testing.T) {
+	tempDir := t.TempDir()
+	templateDir := filepath.Join(tempDir, "template")
+	require.NoError(t, os.MkdirAll(templateDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "README.md.tmpl"),
+		[]byte(strings.Repeat("# {{.name}}\n", 200)), 0644))
+	dataPath := filepath.Join(tempDir, "data.yaml")
+	require.NoError(t, os.WriteFile(dataPath, []byte("name: demo"), 0644))
+
+	run := func(args ...string) error {
+		_, _, err := execute(t, applyCmd, "",
+			append([]string{"apply", templateDir, "-d", dataPath, "-o", filepath.Join(tempDir, "out")}, args...)...)
+		return err
+	}
+
+	require.NoError(t, run())
+	require.NoError(t, run("--max-template-size", "0"))
+	err := run("--max-template-size", "1KB")
+	require.ErrorContains(t, err, "is 2.3 KB, over the limit of 1.0 KB")
+	err = run("--max-template-size", "1XB")
+	require.ErrorContains(t, err, "invalid --max-template-size value '1XB'")
+	err = run("--link", "soft")
+	require.ErrorContains(t, err, "invalid link mode 'soft'")
+
+	require.NoError(t, run("--max-files", "1", "--max-bytes", "0"))
+	err = run("--max-files", "0", "--max-bytes", "2KB")
+	require.ErrorContains(t, err, "the templates generate 2.3 KB, over the limit of 2.0 KB")
+	err = run("--max-bytes", "lots")
+	require.ErrorContains(t, err, "invalid --max-bytes value 'lots'")
+}
+
+func TestApplyCmdOutputPlaceholders(t *testing.T) {
+	tempDir := t.TempDir()
+	templateDir := filepath.Join(tempDir, "template")
+	require.NoError(t, os.MkdirAll(templateDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "README.md.tmpl"), []byte("# {{.name}}"), 0644))
+	dataPath := filepath.Join(tempDir, "data.yaml")
+	require.NoError(t, os.WriteFile(dataPath, []byte("name: Demo App\nempty: ''"), 0644))
+
+	run := func(output string) (string, error) {
+		stdout, _, err := execute(t, applyCmd, "", "apply", templateDir, "-d", dataPath, "-o", output)
+		return stdout, err
+	}
+
+	out, err := run(filepath.Join(tempDir, "out", "{{snake .name}}"))
+	require.NoError(t, err)
+	rendered := filepath.Join(tempDir, "out", "demo_app")
+	assert.Contains(t, out, "📂 Generating into: "+rendered+"\n")
+	assert.Contains(t, out, "✅ Successfully applied template to: "+rendered+"\n")
+	assert.FileExists(t, filepath.Join(rendered, "README.md"))
+
+	_, err = run("{{.empty}}")
+	require.ErrorContains(t, err, "output path '{{.empty}}' renders to an empty path")
+	_, err = run(filepath.Join(tempDir, "{{.missing}}"))
+	require.ErrorContains(t, err, "map has no entry for key \"missing\"")
+	_, err = run(filepath.Join(tempDir, "{{.name"))
+	require.ErrorContains(t, err, "invalid output path")
+	_, err = os.Stat(filepath.Join(tempDir, "<no value>"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestApplyCmdRegistry(t *testing.T) {
+	dir := setupRegistry(t)
+	dataPath := filepath.Join(dir, "data.yaml")
+	require.NoError(t, os.WriteFile(dataPath, []byte("name: site"), 0644))
+	output := filepath.Join(dir, "out")
+
+	_, stderr, err := execute(t, applyCmd, "", "apply", "web", "-d", dataPath, "-o", output)
+	require.NoError(t, err)
+	assert.Contains(t, stderr, "🌐 Fetching template 'web' from registry 'acme': file://")
+
+	content, err := os.ReadFile(filepath.Join(output, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# site\n", string(content))
+	p, err := core.LoadProvenance(output)
+	require.NoError(t, err)
+	assert.Contains(t, p.Template.URL, "web.tar")
+
+	// A name no registry publishes keeps the templates directory error.
+	_, _, err = execute(t, applyCmd, "", "apply", "nope", "-d", dataPath, "-o", output)
+	require.ErrorContains(t, err, "template path 'nope' not found, nor as a template name")
+}
+
+func TestApplyCmdVersion(t *testing.T) {
+	dir := t.TempDir()
+	templates := filepath.Join(dir, "templates")
+	for path, content := range map[string]string{
+		"go-service/versions/v1.4.2/VERSION":  "1.4.2",
+		"go-service/versions/v1.10.0/VERSION": "1.10.0",
+		"mail@home/VERSION":                   "literal",
+	} {
+		path = filepath.Join(templates, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	dataPath := filepath.Join(dir, "data.yaml")
+	require.NoError(t, os.WriteFile(dataPath, []byte("name: demo"), 0644))
+
+	run := func(args ...string) (string, string, error) {
+		output := filepath.Join(dir, "out")
+		require.NoError(t, os.RemoveAll(output))
+		_, stderr, err := execute(t, applyCmd, "",
+			append([]string{"apply", "--templates-dir", templates, "-d", dataPath, "-o", output}, args...)...)
+		content, _ := os.ReadFile(filepath.Join(output, "VERSION"))
+		return string(content), stderr, err
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		want     string
+		resolved string
+	}{
+		{name: "latest", args: []string{"go-service"}, want: "1.10.0", resolved: "v1.10.0"},
+		{name: "pinned", args: []string{"go-service@v1.4.2"}, want: "1.4.2", resolved: "v1.4.2"},
+		{name: "flag", args: []string{"go-service", "--version", "v1.4.2"}, want: "1.4.2", resolved: "v1.4.2"},
+		{name: "literal @", args: []string{"mail@home"}, want: "literal"},
+		{name: "caret", args: []string{"go-service@^1.4"}, want: "1.10.0", resolved: "v1.10.0"},
+		{name: "tilde", args: []string{"go-service", "--version", "~1.4"}, want: "1.4.2", resolved: "v1.4.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, stderr, err := run(tt.args...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, content)
+			p, err := core.LoadProvenance(filepath.Join(dir, "out"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.resolved, p.Template.Resolved)
+			if tt.resolved != "" {
+				assert.Regexp(t, `📌 Resolved template 'go-service' (\S+ )?to version `+tt.resolved, stderr)
+			}
+		})
+	}
+
+	_, stderr, err := run("go-service@^1.4")
+	require.NoError(t, err)
+	assert.Contains(t, stderr, "📌 Resolved template 'go-service' ^1.4 to version v1.10.0")
+	_, _, err = run("go-service@v2.0.0")
+	require.ErrorContains(t, err, "has no version 'v2.0.0', available versions: v1.4.2, v1.10.0")
+	_, _, err = run("go-service@^2")
+	require.ErrorContains(t, err, "has no version matching '^2', available versions: v1.4.2, v1.10.0")
+	_, _, err = run("mail@home", "--version", "v1")
+	require.ErrorContains(t, err, "it has no 'versions' directory")
+}
+
+func TestApplyCmdNotices(t *testing.T) {
+	dir := t.TempDir()
+	templateDir := filepath.Join(dir, "template")
+	require.NoError(t, os.MkdirAll(templateDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, core.MetadataFile), []byte(
+		"deprecated: use go-service-v2 instead\nnotices:\n  - Run 'make bootstrap' in {{kebab .name}} next.\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "README.md.tmpl"), []byte("# {{.name}}"), 0644))
+	dataPath := filepath.Join(dir, "data.yaml")
+	require.NoError(t, os.WriteFile(dataPath, []byte("name: MyApp"), 0644))
+
+	run := func(args ...string) string {
+		stdout, _, err := execute(t, applyCmd, "",
+			append([]string{"apply", templateDir, "-d", dataPath, "-o", filepath.Join(dir, "out")}, args...)...)
+		require.NoError(t, err)
+		return stdout
+	}
+
+	out := run()
+	deprecation := "⚠️  Template '" + templateDir + "' is deprecated: use go-service-v2 instead\n"
+	assert.Contains(t, out, deprecation)
+	assert.Less(t, strings.Index(out, deprecation), strings.Index(out, "✨ Rendering"))
+	notice := "📣 Run 'make bootstrap' in my-app next.\n"
+	assert.Contains(t, out, notice)
+	assert.Greater(t, strings.Index(out, notice), strings.Index(out, "✅ Successfully applied template"))
+
+	// --quiet keeps the deprecation only.
+	assert.Equal(t, deprecation, run("--quiet"))
+
+	reportFile := filepath.Join(dir, "report.json")
+	run("--report", reportFile)
+	content, err := os.ReadFile(reportFile)
+	require.NoError(t, err)
+	var result core.Result
+	require.NoError(t, json.Unmarshal(content, &result))
+	assert.Equal(t, []core.Deprecation{{Template: templateDir, Message: "use go-service-v2 instead"}}, result.Deprecations)
+	assert.Equal(t, []string{"Run 'make bootstrap' in my-app next."}, result.Notices)
+}
+
+func TestApplyCmdPromptMissing(t *testing.T) {
+	dir := t.TempDir()
+	templateDir := filepath.Join(dir, "template")
+	require.NoError(t, os.MkdirAll(templateDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, core.MetadataFile), []byte(
+		"prompts:\n  name: {}\n  port: {type: int}\n  database: {type: enum, choices: [postgres, sqlite]}\n"+
+			"  db_user: {when: '{{eq .database \"postgres\"}}'}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "app.yaml.tmpl"),
+		[]byte("name: {{.name}}\nport: {{.port}}\ndatabase: {{.database}}\nuser: {{.db_user}}\n"), 0644))
+	dataPath := filepath.Join(dir, "data.yaml")
+	require.NoError(t, os.WriteFile(dataPath, []byte("name: app\n"), 0644))
+
+	run := func(stdin string, args ...string) (string, string, error) {
+		return execute(t, applyCmd, stdin, append([]string{"apply", templateDir}, args...)...)
+	}
+
+	output := filepath.Join(dir, "out")
+	_, questions, err := run("not a port\n8080\npostgres\nadmin\n", "-d", dataPath, "-o", output, "--prompt-missing")
+	require.NoError(t, err)
+	assert.Equal(t, "❓ port: ⚠️  invalid value for 'port': expected int, got string \"not a port\"\n"+
+		"❓ port: ❓ database [one of [postgres sqlite]]: ❓ db_user: ", questions)
+	content, err := os.ReadFile(filepath.Join(output, "app.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "name: app\nport: 8080\ndatabase: postgres\nuser: admin\n", string(content))
+	provenance, err := core.LoadProvenance(output)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "app", "port": 8080, "database": "postgres", "db_user": "admin"},
+		provenance.Data)
+
+	// The data file is optional, and the keys of the output path are asked
+	// first.
+	_, questions, err = run("web\n9090\nsqlite\n", "-o", filepath.Join(dir, "{{.name}}"), "--prompt-missing")
+	require.NoError(t, err)
+	assert.Equal(t, "❓ name: ❓ port: ❓ database [one of [postgres sqlite]]: ", questions)
+	assert.FileExists(t, filepath.Join(dir, "web", "app.yaml"))
+
+	// --no-input fails on the missing keys as usual.
+	_, questions, err = run("8080\n", "-d", dataPath, "-o", filepath.Join(dir, "none"), "--prompt-missing",
+		"--no-input", "--strict")
+	require.ErrorContains(t, err, `map has no entry for key "port"`)
+	assert.NotContains(t, questions, "❓")
+	_, _, err = run("", "-o", filepath.Join(dir, "none"), "--prompt-missing", "--no-input")
+	require.ErrorContains(t, err, "the --data-file flag is required")
+	_, _, err = run("", "-d", "-", "--data-format", "yaml", "--prompt-missing")
+	require.ErrorContains(t, err, "--prompt-missing reads the answers from stdin")
+}
+
+func TestApplyCmdUsageWarnings(t *testing.T) {
+	dir := t.TempDir()
+	templateDir := filepath.Join(dir, "template")
+	require.NoError(t, os.MkdirAll(templateDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "README.md.tmpl"),
+		[]byte("# {{.project_name}}\n{{.owner}}\n"), 0644))
+	dataPath := filepath.Join(dir, "data.yaml")
+	require.NoError(t, os.WriteFile(dataPath, []byte("porject_name: app\nslug: app\n"), 0644))
+
+	run := func(args ...string) (string, error) {
+		stdout, stderr, err := execute(t, applyCmd, "", append([]string{"apply", templateDir, "-d", dataPath}, args...)...)
+		return stdout + stderr, err
+	}
+
+	// The key of the output path is used.
+	out, err := run("-o", filepath.Join(dir, "{{.slug}}"), "--report", filepath.Join(dir, "report.json"))
+	require.NoError(t, err)
+	assert.Contains(t, out, "⚠️  Data keys no template or path uses, check their spelling: porject_name\n")
+	assert.Contains(t, out, "⚠️  2 references to missing keys rendered as <no value>: README.md:1, README.md:2\n")
+	content, err := os.ReadFile(filepath.Join(dir, "report.json"))
+	require.NoError(t, err)
+	var report core.Result
+	require.NoError(t, json.Unmarshal(content, &report))
+	assert.Equal(t, []string{"porject_name"}, report.UnusedKeys)
+	assert.Equal(t, []core.MissingValue{{Path: "README.md", Line: 1}, {Path: "README.md", Line: 2}},
+		report.MissingValues)
+
+	out, err = run("-o", filepath.Join(dir, "quiet"), "--no-warn-unused", "--no-warn-missing")
+	require.NoError(t, err)
+	assert.NotContains(t, out, "⚠️")
+}
+
+func TestApplyCmdNameRule(t *testing.T) {
+	dir := t.TempDir()
+	templateDir := filepath.Join(dir, "template")
+	require.NoError(t, os.MkdirAll(templateDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "{{.name}}.txt"), []byte("notes"), 0644))
+	dataPath := filepath.Join(dir, "data.yaml")
+	require.NoError(t, os.WriteFile(dataPath, []byte("name: 'a: b'\n"), 0644))
+
+	run := func(output string, args ...string) (string, error) {
+		stdout, stderr, err := execute(t, applyCmd, "",
+			append([]string{"apply", templateDir, "-d", dataPath, "-o", filepath.Join(dir, output)}, args...)...)
+		return stdout + stderr, err
+	}
+
+	_, err := run("default")
+	require.ErrorContains(t, err, "invalid name 'a: b.txt' rendered from '{{.name}}.txt': character ':' (U+003A)")
+	out, err := run("sanitized", "--sanitize-names")
+	require.NoError(t, err)
+	assert.Contains(t, out, "🧼 Sanitizing name 'a: b.txt' rendered from '{{.name}}.txt' to 'a_ b.txt'\n")
+	assert.FileExists(t, filepath.Join(dir, "sanitized", "a_ b.txt"))
+	_, err = run("posix", "--name-rule", "posix", "--sanitize-names")
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "posix", "a__b.txt"))
+	_, err = run("permissive", "--name-rule", "permissive")
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "permissive", "a: b.txt"))
+	_, err = run("unknown", "--name-rule", "dos")
+	require.ErrorContains(t, err, "invalid name rule 'dos'")
 }
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/0m3kk/mold/internal/core"
+	"github.com/0m3kk/mold/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// preserveOwner keeps the user and group of the copied files.
+//
+//nolint:gochecknoglobals // this is cmd flag
+var preserveOwner bool
+
+// copyCmd represents the copy command.
+//
+//nolint:gochecknoglobals // this is command definition
+var copyCmd = &cobra.Command{
+	Use:   "copy <src> <dst>",
+	Short: "Copies a template to a new name in the templates directory",
+	Long: `Copies the template src to the new template dst in the templates directory,
+keeping file permissions and times. Nested names, such as 'go/service', create their
+grouping directories as needed. The 'name' field of the copied template.yaml is
+set to dst. An existing template is never overwritten.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := resolveTemplatesDir()
+		if err != nil {
+			return err
+		}
+		preserve := utils.PreserveOptions{
+			Times: true,
+			Owner: preserveOwner,
+			Warn: func(path string, err error) {
+				fmt.Fprintf(cmd.ErrOrStderr(), "⚠️  Could not preserve the owner of '%s': %v\n", path, err)
+			},
+		}
+		path, err := core.CopyTemplate(dir, args[0], args[1], utils.WithPreserveDir(preserve))
+		if err != nil {
+			return err
+		}
+		count, err := utils.CountFiles(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "✅ Copied template '%s' to '%s': %s (%d files)\n", args[0], args[1], path, count)
+		return nil
+	},
+}
+
+//nolint:gochecknoinits // The command 'init' is acceptable.
+func init() {
+	copyCmd.Flags().BoolVar(&preserveOwner, "preserve-owner", false,
+		"Keep the user and group of the copied files, which usually takes root")
+}
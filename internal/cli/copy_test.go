@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executeManage runs the copy or rename command on the templates in dir.
+func executeManage(t *testing.T, dir string, sub *cobra.Command, args ...string) (string, error) {
+	t.Helper()
+	out, _, err := execute(t, sub, "", append([]string{sub.Name(), "--templates-dir", dir}, args...)...)
+	return out, err
+}
+
+func TestCopyAndRenameCmd(t *testing.T) {
+	dir := t.TempDir()
+	servicePath := filepath.Join(dir, "go", "service")
+	require.NoError(t, os.MkdirAll(servicePath, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(servicePath, "template.yaml"), []byte("name: go/service\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(servicePath, "run.sh"), []byte("#!/bin/sh\n"), 0755))
+	mtime := time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(servicePath, "run.sh"), mtime, mtime))
+
+	out, err := executeManage(t, dir, copyCmd, "go/service", "go/api", "--preserve-owner")
+	require.NoError(t, err)
+	assert.Contains(t, out, "✅ Copied template 'go/service' to 'go/api'")
+	assert.Contains(t, out, "(2 files)")
+	content, err := os.ReadFile(filepath.Join(dir, "go", "api", "template.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "name: go/api\n", string(content))
+	info, err := os.Stat(filepath.Join(dir, "go", "api", "run.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	assert.Equal(t, mtime, info.ModTime().UTC())
+
+	_, err = executeManage(t, dir, copyCmd, "go/service", "go/api")
+	require.ErrorContains(t, err, "template 'go/api' already exists")
+
+	out, err = executeManage(t, dir, renameCmd, "go/api", "web/api")
+	require.NoError(t, err)
+	assert.Contains(t, out, "✅ Renamed template 'go/api' to 'web/api'")
+	assert.NoDirExists(t, filepath.Join(dir, "go", "api"))
+	content, err = os.ReadFile(filepath.Join(dir, "web", "api", "template.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "name: web/api\n", string(content))
+
+	_, err = executeManage(t, dir, renameCmd, "go/missing", "x")
+	require.ErrorContains(t, err, "template 'go/missing' not found")
+}
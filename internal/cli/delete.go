@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/0m3kk/mold/internal/core"
+	"github.com/0m3kk/mold/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+//nolint:gochecknoglobals // this is cmd flag
+var deleteYes bool
+
+// deleteCmd represents the delete command.
+//
+//nolint:gochecknoglobals // this is command definition
+var deleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Deletes a template from the templates directory",
+	Long: `Deletes the template with the given name, such as 'go/service', from the
+templates directory. The path and the number of files are shown and the deletion
+has to be confirmed, unless --yes is given. Templates that resolve outside the
+templates directory, for example through a symlink, are never deleted.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		out := cmd.OutOrStdout()
+		dir, err := resolveTemplatesDir()
+		if err != nil {
+			return err
+		}
+		path, err := core.ResolveTemplate(dir, name)
+		if err != nil {
+			return err
+		}
+		count, err := utils.CountFiles(path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "🗑️  Deleting template '%s': %s (%d files)\n", name, path, count)
+		if !deleteYes && !confirm(cmd.InOrStdin(), out, "Delete it?") {
+			return errors.New("delete cancelled, template left untouched")
+		}
+		if err = core.DeleteTemplate(dir, name); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "✅ Deleted template '%s'\n", name)
+		return nil
+	},
+}
+
+//nolint:gochecknoinits // The command 'init' is acceptable.
+func init() {
+	deleteCmd.Flags().BoolVarP(&deleteYes, "yes", "y", false, "Delete without asking for confirmation")
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executeDelete runs the delete command on the templates in dir.
+func executeDelete(t *testing.T, dir, stdin string, args ...string) (string, error) {
+	t.Helper()
+	out, _, err := execute(t, deleteCmd, stdin, append([]string{"delete", "--templates-dir", dir}, args...)...)
+	return out, err
+}
+
+func TestDeleteCmd(t *testing.T) {
+	dir := t.TempDir()
+	servicePath := filepath.Join(dir, "go", "service")
+	for _, name := range []string{"main.go.tmpl", "template.yaml", "cmd/run.go.tmpl"} {
+		path := filepath.Join(servicePath, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(name), 0644))
+	}
+
+	t.Run("declined", func(t *testing.T) {
+		out, err := executeDelete(t, dir, "n\n", "go/service")
+		require.ErrorContains(t, err, "delete cancelled")
+		assert.Contains(t, out, "Deleting template 'go/service': "+servicePath+" (3 files)")
+		assert.Contains(t, out, "Delete it? [y/N]")
+		assert.DirExists(t, servicePath)
+	})
+
+	t.Run("no answer", func(t *testing.T) {
+		_, err := executeDelete(t, dir, "", "go/service")
+		require.ErrorContains(t, err, "delete cancelled")
+		assert.DirExists(t, servicePath)
+	})
+
+	t.Run("confirmed", func(t *testing.T) {
+		out, err := executeDelete(t, dir, "yes\n", "go/service")
+		require.NoError(t, err)
+		assert.Contains(t, out, "✅ Deleted template 'go/service'")
+		assert.NoDirExists(t, servicePath)
+		assert.DirExists(t, filepath.Join(dir, "go"))
+	})
+
+	t.Run("missing template", func(t *testing.T) {
+		_, err := executeDelete(t, dir, "y\n", "go/service")
+		require.ErrorContains(t, err, "template 'go/service' not found in")
+	})
+
+	t.Run("yes flag skips the question", func(t *testing.T) {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "web"), 0755))
+		out, err := executeDelete(t, dir, "", "web", "--yes")
+		require.NoError(t, err)
+		assert.NotContains(t, out, "[y/N]")
+		assert.NoDirExists(t, filepath.Join(dir, "web"))
+	})
+
+	t.Run("symlink outside the templates directory", func(t *testing.T) {
+		outside := t.TempDir()
+		require.NoError(t, os.Symlink(outside, filepath.Join(dir, "linked")))
+		_, err := executeDelete(t, dir, "", "linked", "--yes")
+		require.ErrorContains(t, err, "outside the templates directory")
+		assert.DirExists(t, outside)
+	})
+}
@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/cpuguy83/go-md2man/v2/md2man"
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+// The source and manual of the man pages.
+const (
+	manSource = "mold"
+	manManual = "Mold Manual"
+)
+
+//nolint:gochecknoglobals // this is cmd flag
+var (
+	docsFormat string
+	docsDir    string
+	docsClock  string
+)
+
+// docsCmd represents the docs command.
+//
+//nolint:gochecknoglobals // this is command definition
+var docsCmd = &cobra.Command{
+	Use:    "docs",
+	Short:  "Generates the reference documentation of mold",
+	Hidden: true,
+	Long: `Writes a man page or a markdown page per command into the output directory,
+followed by a page on the template.yaml keys and a page on the functions
+templates can call, listed from the ones the renderer registers.
+
+The pages hold no timestamp, so regenerating them only changes them when the
+documentation changes. With --clock, man pages are dated with it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if docsFormat != "man" && docsFormat != "markdown" {
+			return fmt.Errorf("invalid --format value '%s': expected man or markdown", docsFormat)
+		}
+		date, err := parseClock(docsClock)
+		if err != nil {
+			return err
+		}
+		if err = os.MkdirAll(docsDir, 0750); err != nil {
+			return fmt.Errorf("failed to create output directory '%s': %w", docsDir, err)
+		}
+
+		pages := make(map[string][]byte)
+		if err = commandPages(cmd.Root(), date, pages); err != nil {
+			return err
+		}
+		templatePage, functionsPage := templateReference(), functionReference()
+		if docsFormat == "man" {
+			pages["mold-template-yaml.5"] = manPage("MOLD-TEMPLATE-YAML", "5", date, templatePage)
+			pages["mold-template-functions.7"] = manPage("MOLD-TEMPLATE-FUNCTIONS", "7", date, functionsPage)
+		} else {
+			pages["mold_template_yaml.md"] = []byte(templatePage)
+			pages["mold_template_functions.md"] = []byte(functionsPage)
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(pages)) {
+			path := filepath.Join(docsDir, name)
+			//nolint:gosec // documentation is meant to be shared
+			if err = os.WriteFile(path, pages[name], 0644); err != nil {
+				return fmt.Errorf("failed to write '%s': %w", path, err)
+			}
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "📚 Wrote %d %s pages to: %s\n", len(pages), docsFormat, docsDir)
+		return nil
+	},
+}
+
+// commandPages adds the page of cmd and of each of its available
+// subcommands to pages, by file name.
+func commandPages(cmd *cobra.Command, date time.Time, pages map[string][]byte) error {
+	// The tag holds the time of the run.
+	cmd.DisableAutoGenTag = true
+	var buf bytes.Buffer
+	var name string
+	if docsFormat == "man" {
+		name = strings.ReplaceAll(cmd.CommandPath(), " ", "-") + ".1"
+		header := &doc.GenManHeader{
+			Title:   strings.ToUpper(strings.TrimSuffix(name, ".1")),
+			Section: "1",
+			Date:    &date,
+			Source:  manSource,
+			Manual:  manManual,
+		}
+		if err := doc.GenMan(cmd, header, &buf); err != nil {
+			return fmt.Errorf("failed to generate the man page of '%s': %w", cmd.CommandPath(), err)
+		}
+		pages[name] = undated(buf.Bytes(), date)
+	} else {
+		name = strings.ReplaceAll(cmd.CommandPath(), " ", "_") + ".md"
+		if err := doc.GenMarkdown(cmd, &buf); err != nil {
+			return fmt.Errorf("failed to generate the markdown page of '%s': %w", cmd.CommandPath(), err)
+		}
+		pages[name] = buf.Bytes()
+	}
+	for _, child := range cmd.Commands() {
+		if !child.IsAvailableCommand() || child.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		if err := commandPages(child, date, pages); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// manPage converts a markdown page to a man page.
+func manPage(title, section string, date time.Time, markdown string) []byte {
+	preamble := fmt.Sprintf("%% %q %q %q %q %q\n", title, section, date.Format("Jan 2006"), manSource, manManual)
+	return undated(md2man.Render([]byte(preamble+markdown)), date)
+}
+
+// undated blanks the date of a man page header when no --clock is given,
+// since cobra always writes one.
+func undated(page []byte, date time.Time) []byte {
+	if !date.IsZero() {
+		return page
+	}
+	return bytes.Replace(page, []byte(`"Jan 0001"`), []byte(`""`), 1)
+}
+
+// templateReference returns the markdown reference of the template.yaml
+// keys.
+func templateReference() string {
+	var b strings.Builder
+	b.WriteString("# NAME\n\ntemplate.yaml - the metadata file at the root of a mold template\n\n")
+	b.WriteString("# DESCRIPTION\n\nEvery key is optional. A template without the file gets empty metadata.\n\n")
+	b.WriteString("# KEYS\n\n")
+	for _, field := range core.MetadataSchema() {
+		fmt.Fprintf(&b, "**%s** (%s)\n: %s\n\n", field.Key, field.Type, field.Description)
+	}
+	return b.String()
+}
+
+// functionReference returns the markdown reference of the functions
+// templates can call.
+func functionReference() string {
+	var b strings.Builder
+	b.WriteString("# NAME\n\nmold template functions - the functions mold templates can call\n\n")
+	b.WriteString("# DESCRIPTION\n\nTemplates are Go text/template files. Besides the builtin functions of " +
+		"text/template, they can call:\n\n")
+	b.WriteString("# FUNCTIONS\n\n")
+	for _, f := range core.HelperFuncs() {
+		fmt.Fprintf(&b, "**%s**\n: %s\n\n", f.Signature, f.Description)
+	}
+	return b.String()
+}
+
+//nolint:gochecknoinits // The command 'init' is acceptable.
+func init() {
+	docsCmd.Flags().StringVar(&docsFormat, "format", "markdown", "Page format: man or markdown")
+	docsCmd.Flags().StringVarP(&docsDir, "output", "o", "docs", "Output directory of the pages")
+	docsCmd.Flags().StringVar(&docsClock, "clock", "",
+		"RFC 3339 timestamp dating the man pages (default no date)")
+	markPathFlags(docsCmd.Flags(), "output")
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executeDocs runs the docs command and returns the pages it generated, by
+// file name.
+func executeDocs(t *testing.T, args ...string) (map[string]string, error) {
+	t.Helper()
+	dir := t.TempDir()
+	if _, _, err := execute(t, docsCmd, "", append([]string{"docs", "-o", dir}, args...)...); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	pages := make(map[string]string)
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		require.NoError(t, err)
+		pages[entry.Name()] = string(content)
+	}
+	return pages, nil
+}
+
+func TestDocsCmd(t *testing.T) {
+	t.Run("markdown", func(t *testing.T) {
+		pages, err := executeDocs(t)
+		require.NoError(t, err)
+		assert.Contains(t, pages, "mold.md")
+		assert.Contains(t, pages["mold_info.md"], "mold info [dir]")
+		assert.NotContains(t, pages, "mold_docs.md", "the docs command is hidden")
+		assert.Contains(t, pages["mold_template_functions.md"], "**snake(string) string**")
+		assert.Contains(t, pages["mold_template_yaml.md"], "**prompts.<name>.secret** (bool)")
+
+		again, err := executeDocs(t)
+		require.NoError(t, err)
+		assert.Equal(t, pages, again, "regenerated pages differ")
+	})
+
+	t.Run("man", func(t *testing.T) {
+		pages, err := executeDocs(t, "--format", "man")
+		require.NoError(t, err)
+		assert.Contains(t, pages["mold-info.1"], `.TH "MOLD-INFO" "1" "" "mold" "Mold Manual"`)
+		assert.Contains(t, pages["mold-template-functions.7"], `\fBsnake(string) string\fP`)
+		assert.Contains(t, pages, "mold-template-yaml.5")
+		assert.NotContains(t, pages["mold-info.1"], "HISTORY")
+
+		pages, err = executeDocs(t, "--format", "man", "--clock", "2024-05-01T00:00:00Z")
+		require.NoError(t, err)
+		assert.Contains(t, pages["mold-info.1"], `.TH "MOLD-INFO" "1" "May 2024"`)
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		_, err := executeDocs(t, "--format", "html")
+		require.ErrorContains(t, err, "invalid --format value 'html'")
+	})
+}
@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+//nolint:gochecknoglobals // this is cmd flag
+var doctorOutput string
+
+// doctorReport is the JSON output of the doctor command.
+type doctorReport struct {
+	Checks []core.Check `json:"checks"`
+}
+
+// doctorCmd represents the doctor command.
+//
+//nolint:gochecknoglobals // this is command definition
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Checks the environment mold runs in",
+	Long: `Checks that the templates directory exists and can be read, and for each template
+in it that its template.yaml parses, that its templates and paths compile and
+that the commands of its formatters are installed.
+
+Each check is reported as OK, WARN or FAIL, with a hint on how to fix it. With
+--output json, the checks are printed as JSON. The command only fails when a
+check fails.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if doctorOutput != "text" && doctorOutput != "json" {
+			return fmt.Errorf("invalid --output value '%s': expected text or json", doctorOutput)
+		}
+
+		checks := runDoctor()
+		out := cmd.OutOrStdout()
+		if doctorOutput == "json" {
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(doctorReport{Checks: checks}); err != nil {
+				return err
+			}
+		} else {
+			printChecks(out, checks)
+		}
+
+		failures := 0
+		for _, check := range checks {
+			if check.Status == core.CheckFail {
+				failures++
+			}
+		}
+		if failures > 0 {
+			return fmt.Errorf("doctor found %d failures", failures)
+		}
+		return nil
+	},
+}
+
+// runDoctor runs the checks of the templates directory and, when it can be
+// read, of every template in it.
+func runDoctor() []core.Check {
+	dir, err := resolveTemplatesDir()
+	if err != nil {
+		return []core.Check{{
+			Name:    "templates directory",
+			Status:  core.CheckFail,
+			Message: err.Error(),
+			Hint:    "Set " + core.TemplatesDirEnv + " or --templates-dir",
+		}}
+	}
+	checks := []core.Check{core.CheckTemplatesDir(dir)}
+	if checks[0].Status != core.CheckOK {
+		return checks
+	}
+	names, err := core.ListTemplates(dir)
+	if err != nil {
+		return append(checks, core.Check{
+			Name:    "templates",
+			Status:  core.CheckFail,
+			Message: err.Error(),
+			Hint:    fmt.Sprintf("Check the permissions of the directories in '%s'", dir),
+		})
+	}
+	for _, name := range names {
+		path, err := core.ResolveTemplate(dir, name)
+		if err != nil {
+			checks = append(checks, core.Check{
+				Name:    "template " + name,
+				Status:  core.CheckFail,
+				Message: err.Error(),
+				Hint:    "Replace the symlink with a copy of the template",
+			})
+			continue
+		}
+		checks = append(checks, core.CheckTemplate(name, path))
+	}
+	return checks
+}
+
+// printChecks prints the checks with their hints, followed by a summary.
+func printChecks(out io.Writer, checks []core.Check) {
+	counts := make(map[core.CheckStatus]int)
+	for _, check := range checks {
+		counts[check.Status]++
+		icon := "✅"
+		switch check.Status {
+		case core.CheckWarn:
+			icon = "⚠️ "
+		case core.CheckFail:
+			icon = "❌"
+		case core.CheckOK:
+		}
+		fmt.Fprintf(out, "%s %-4s %s: %s\n", icon, check.Status, check.Name, check.Message)
+		if check.Hint != "" {
+			fmt.Fprintf(out, "     💡 %s\n", check.Hint)
+		}
+	}
+	fmt.Fprintf(out, "\n🩺 %d checks: %d OK, %d warnings, %d failures\n",
+		len(checks), counts[core.CheckOK], counts[core.CheckWarn], counts[core.CheckFail])
+}
+
+//nolint:gochecknoinits // The command 'init' is acceptable.
+func init() {
+	doctorCmd.Flags().StringVarP(&doctorOutput, "output", "o", "text", "Output format: text or json")
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executeDoctor runs the doctor command on the templates in dir.
+func executeDoctor(t *testing.T, dir string, args ...string) (string, error) {
+	t.Helper()
+	out, _, err := execute(t, doctorCmd, "", append([]string{"doctor", "--templates-dir", dir}, args...)...)
+	return out, err
+}
+
+func TestDoctorCmd(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go/service/main.go.tmpl":         "package {{.name}}",
+		"go/service/" + core.MetadataFile: "formatters:\n  '*.md': [mold-no-such-formatter]\n",
+		"plain/README.md":                 "readme",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	t.Run("warnings only", func(t *testing.T) {
+		out, err := executeDoctor(t, dir)
+		require.NoError(t, err)
+		assert.Contains(t, out, "✅ OK   templates directory: '"+dir+"' is readable")
+		assert.Contains(t, out, "⚠️  WARN template go/service: formatters not found on the PATH: mold-no-such-formatter")
+		assert.Contains(t, out, "💡 Install them, or apply with --no-format")
+		assert.Contains(t, out, "✅ OK   template plain: ")
+		assert.Contains(t, out, "🩺 3 checks: 2 OK, 1 warnings, 0 failures")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		out, err := executeDoctor(t, dir, "-o", "json")
+		require.NoError(t, err)
+		var report doctorReport
+		require.NoError(t, json.Unmarshal([]byte(out), &report))
+		require.Len(t, report.Checks, 3)
+		assert.Equal(t, "template go/service", report.Checks[1].Name)
+		assert.Equal(t, core.CheckWarn, report.Checks[1].Status)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "plain", "broken.txt.tmpl"), []byte("{{.x"), 0644))
+		out, err := executeDoctor(t, dir)
+		require.ErrorContains(t, err, "doctor found 1 failures")
+		assert.Contains(t, out, "❌ FAIL template plain: 1 files or paths don't compile: broken.txt.tmpl")
+	})
+
+	t.Run("missing templates directory", func(t *testing.T) {
+		out, err := executeDoctor(t, filepath.Join(dir, "missing"))
+		require.NoError(t, err)
+		assert.Contains(t, out, "💡 Run 'mold init' to create it")
+	})
+
+	t.Run("invalid output", func(t *testing.T) {
+		_, err := executeDoctor(t, dir, "-o", "yaml")
+		require.ErrorContains(t, err, "invalid --output value 'yaml'")
+	})
+}
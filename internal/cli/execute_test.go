@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+// execute runs mold with args, the command line after "mold", reading stdin,
+// and returns what it wrote to stdout and stderr. The global flags and the
+// flags of sub, the subcommand run, are reset to their defaults before and
+// after the run, with the package variables they are bound to, so runs don't
+// leak into each other or into the other tests.
+func execute(t *testing.T, sub *cobra.Command, stdin string, args ...string) (string, string, error) {
+	t.Helper()
+	reset := func() {
+		resetFlags(t, rootCmd.PersistentFlags())
+		resetCommandFlags(t, sub)
+	}
+	reset()
+	t.Cleanup(reset)
+
+	var stdout, stderr bytes.Buffer
+	root := &cobra.Command{Use: "mold", PersistentPreRunE: resolveWorkDir}
+	root.PersistentFlags().AddFlagSet(rootCmd.PersistentFlags())
+	root.AddCommand(rootCmd.Commands()...)
+	root.SetOut(&stdout)
+	root.SetErr(&stderr)
+	root.SetIn(strings.NewReader(stdin))
+	root.SetArgs(args)
+	err := root.Execute()
+	return stdout.String(), stderr.String(), err
+}
+
+// resetCommandFlags resets the flags of cmd and of its subcommands.
+func resetCommandFlags(t *testing.T, cmd *cobra.Command) {
+	t.Helper()
+	resetFlags(t, cmd.Flags())
+	resetFlags(t, cmd.PersistentFlags())
+	for _, child := range cmd.Commands() {
+		resetCommandFlags(t, child)
+	}
+}
+
+// resetFlags sets every flag of flags back to its default value, as given by
+// its DefValue, and marks it as not given.
+func resetFlags(t *testing.T, flags *pflag.FlagSet) {
+	t.Helper()
+	flags.VisitAll(func(f *pflag.Flag) {
+		var err error
+		switch value := f.Value.(type) {
+		case *overrideFlag:
+			// The --set flags share one list, empty by default.
+			*value.overrides = nil
+		case pflag.SliceValue:
+			// Setting a repeatable flag appends, its values are replaced.
+			var defaults []string
+			if def := strings.Trim(f.DefValue, "[]"); def != "" {
+				defaults = strings.Split(def, ",")
+			}
+			err = value.Replace(defaults)
+		default:
+			err = value.Set(f.DefValue)
+		}
+		require.NoError(t, err, "failed to reset --%s", f.Name)
+		f.Changed = false
+	})
+}
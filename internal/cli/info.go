@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+//nolint:gochecknoglobals // this is cmd flag
+var infoOutput string
+
+// infoReport is the JSON output of the info command.
+type infoReport struct {
+	Provenance *core.Provenance `json:"provenance"`
+	Files      []core.FileCheck `json:"files"`
+}
+
+// infoCmd represents the info command.
+//
+//nolint:gochecknoglobals // this is command definition
+var infoCmd = &cobra.Command{
+	Use:   "info [dir]",
+	Short: "Shows how a generated project was generated",
+	Long: `Reads the .mold.yaml file that apply writes at the root of a generated project,
+in the current directory by default, and shows the template and version that
+generated it, when, with which data, and how many files it tracks. The tracked
+files are hashed to report the ones modified or missing since then. Secret
+values are masked.
+
+With --output json, the recorded provenance and the state of every tracked file
+are printed as JSON. A directory without a .mold.yaml file exits with code 2.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		dir = workPath(dir)
+		if infoOutput != "text" && infoOutput != "json" {
+			return fmt.Errorf("invalid --output value '%s': expected text or json", infoOutput)
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return fmt.Errorf("project directory '%s' not found", dir)
+		}
+
+		p, err := core.LoadProvenance(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			return &ExitError{
+				Code: ExitNotGenerated,
+				Err:  fmt.Errorf("'%s' is not a mold-generated project: it has no %s file", dir, core.ProvenanceFile),
+			}
+		}
+		if err != nil {
+			return err
+		}
+		checks, err := core.CheckFiles(dir, p.Files)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		if infoOutput == "json" {
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(infoReport{Provenance: p, Files: checks})
+		}
+		printInfo(out, p, checks)
+		return nil
+	},
+}
+
+// printInfo prints the provenance and the state of the tracked files.
+func printInfo(out io.Writer, p *core.Provenance, checks []core.FileCheck) {
+	fmt.Fprintf(out, "📦 Template: %s\n", describeTemplate(p.Template))
+	for _, layer := range p.Layers {
+		fmt.Fprintf(out, "🧩 Layer: %s\n", describeTemplate(layer))
+	}
+	fmt.Fprintf(out, "🕒 Generated: %s by mold %s\n", p.GeneratedAt.Format(time.RFC3339), p.MoldVersion)
+
+	values := make(map[string]any)
+	flattenData("", p.Data, values)
+	fmt.Fprintf(out, "📖 Data (%d keys):\n", len(values))
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		fmt.Fprintf(out, "  %s: %v\n", key, values[key])
+	}
+
+	var modified, missing []string
+	linked := 0
+	for _, check := range checks {
+		if check.Link != "" {
+			linked++
+		}
+		switch check.State {
+		case core.FileModified:
+			if check.Link != "" {
+				modified = append(modified, check.Path+" (linked, the template file changed too)")
+				continue
+			}
+			modified = append(modified, check.Path)
+		case core.FileMissing:
+			missing = append(missing, check.Path)
+		case core.FileUnchanged:
+		}
+	}
+	fmt.Fprintf(out, "📄 Files: %d tracked, %d modified, %d missing\n", len(checks), len(modified), len(missing))
+	for _, path := range modified {
+		fmt.Fprintf(out, "  ✏️  modified: %s\n", path)
+	}
+	for _, path := range missing {
+		fmt.Fprintf(out, "  ❌ missing: %s\n", path)
+	}
+	if linked > 0 {
+		fmt.Fprintf(out, "🔗 %d files are linked to the template, editing them edits the template\n", linked)
+	}
+	if len(modified) == 0 && len(missing) == 0 {
+		fmt.Fprintln(out, "✅ No tracked file changed since the project was generated")
+	}
+}
+
+// describeTemplate returns the name and version of a template followed by
+// where it was applied from.
+func describeTemplate(t core.ProvenanceTemplate) string {
+	source := t.Path
+	if t.URL != "" {
+		source = t.URL
+		if t.Ref != "" {
+			source += "@" + t.Ref
+		}
+	}
+	switch {
+	case t.Name == "":
+		return source
+	case t.Version == "":
+		return fmt.Sprintf("%s (%s)", t.Name, source)
+	default:
+		return fmt.Sprintf("%s %s (%s)", t.Name, t.Version, source)
+	}
+}
+
+// flattenData adds the values of nested data to values under their dotted
+// keys.
+func flattenData(prefix string, data map[string]any, values map[string]any) {
+	for key, value := range data {
+		if nested, ok := value.(map[string]any); ok && len(nested) > 0 {
+			flattenData(prefix+key+".", nested, values)
+			continue
+		}
+		values[prefix+key] = value
+	}
+}
+
+//nolint:gochecknoinits // The command 'init' is acceptable.
+func init() {
+	infoCmd.Flags().StringVarP(&infoOutput, "output", "o", "text", "Output format: text or json")
+}
@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executeInfo runs the info command.
+func executeInfo(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	out, _, err := execute(t, infoCmd, "", append([]string{"info"}, args...)...)
+	return out, err
+}
+
+func TestInfoCmd(t *testing.T) {
+	templateDir := t.TempDir()
+	files := map[string]string{
+		core.MetadataFile: "name: go/service\nversion: 1.2.0\nprompts:\n  api_key: {secret: true}\n",
+		"README.md.tmpl":  "# {{.name}}",
+		"main.go":         "package main\n",
+		"LICENSE":         "MIT\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(templateDir, name), []byte(content), 0644))
+	}
+	projectDir := filepath.Join(t.TempDir(), "project")
+	err := core.Apply(core.Options{
+		TemplatePath: templateDir,
+		OutputDir:    projectDir,
+		Data:         map[string]any{"name": "demo", "api_key": "s3cret", "db": map[string]any{"port": 5432}},
+		Clock:        time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Out:          &bytes.Buffer{},
+	})
+	require.NoError(t, err)
+
+	t.Run("clean", func(t *testing.T) {
+		out, err := executeInfo(t, projectDir)
+		require.NoError(t, err)
+		assert.Contains(t, out, "📦 Template: go/service 1.2.0 ("+templateDir+")")
+		assert.Contains(t, out, "🕒 Generated: 2024-01-02T03:04:05Z by mold ")
+		assert.Contains(t, out, "📖 Data (3 keys):\n  api_key: ******\n  db.port: 5432\n  name: demo\n")
+		assert.Contains(t, out, "📄 Files: 3 tracked, 0 modified, 0 missing")
+		assert.Contains(t, out, "✅ No tracked file changed")
+		assert.NotContains(t, out, "s3cret")
+	})
+
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "README.md"), []byte("# edited"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(projectDir, "LICENSE")))
+
+	t.Run("modified", func(t *testing.T) {
+		out, err := executeInfo(t, projectDir)
+		require.NoError(t, err)
+		assert.Contains(t, out, "📄 Files: 3 tracked, 1 modified, 1 missing")
+		assert.Contains(t, out, "✏️  modified: README.md")
+		assert.Contains(t, out, "❌ missing: LICENSE")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		out, err := executeInfo(t, projectDir, "--output", "json")
+		require.NoError(t, err)
+		var report struct {
+			Provenance core.Provenance  `json:"provenance"`
+			Files      []core.FileCheck `json:"files"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(out), &report))
+		assert.Equal(t, "1.2.0", report.Provenance.Template.Version)
+		assert.Equal(t, core.SecretMask, report.Provenance.Data["api_key"])
+		assert.Equal(t, []core.FileCheck{
+			{Path: "LICENSE", State: core.FileMissing},
+			{Path: "README.md", State: core.FileModified},
+			{Path: "main.go", State: core.FileUnchanged},
+		}, report.Files)
+	})
+
+	t.Run("linked", func(t *testing.T) {
+		linkedDir := filepath.Join(t.TempDir(), "linked")
+		err := core.Apply(core.Options{TemplatePath: templateDir, OutputDir: linkedDir, Link: core.LinkHard,
+			Data: map[string]any{"name": "demo", "api_key": "s3cret"}, Out: &bytes.Buffer{}})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(linkedDir, "main.go"), []byte("package edited\n"), 0644))
+		t.Cleanup(func() {
+			_ = os.WriteFile(filepath.Join(templateDir, "main.go"), []byte(files["main.go"]), 0644)
+		})
+
+		out, err := executeInfo(t, linkedDir)
+		require.NoError(t, err)
+		assert.Contains(t, out, "✏️  modified: main.go (linked, the template file changed too)")
+		assert.Contains(t, out, "🔗 2 files are linked to the template, editing them edits the template")
+	})
+
+	t.Run("not generated", func(t *testing.T) {
+		_, err := executeInfo(t, templateDir)
+		require.ErrorContains(t, err, "is not a mold-generated project")
+		assert.Equal(t, ExitNotGenerated, ExitCode(err))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := executeInfo(t, filepath.Join(projectDir, "missing"))
+		require.ErrorContains(t, err, "not found")
+		assert.Equal(t, 1, ExitCode(err))
+
+		_, err = executeInfo(t, projectDir, "--output", "xml")
+		require.ErrorContains(t, err, "invalid --output value 'xml'")
+	})
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+//nolint:gochecknoglobals // this is cmd flag
+var withExample bool
+
+// initCmd represents the init command.
+//
+//nolint:gochecknoglobals // this is command definition
+var initCmd = &cobra.Command{
+	Use:   "init [dir]",
+	Short: "Creates a templates directory",
+	Long: `Creates the templates directory, or dir when given, with a '.gitkeep' file so it
+can be committed while empty. With --with-example, a starter template named
+'example' is added that 'mold apply example -d <templates>/example/tmpl.yaml' can
+apply right away. An existing 'example' directory is left untouched.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+		dir, err := resolveTemplatesDir()
+		if err != nil {
+			return err
+		}
+		if len(args) == 1 {
+			dir = workPath(args[0])
+		}
+
+		if err = os.MkdirAll(dir, 0750); err != nil {
+			return fmt.Errorf("failed to create templates directory '%s': %w", dir, err)
+		}
+		gitkeep := filepath.Join(dir, ".gitkeep")
+		if _, err = os.Stat(gitkeep); errors.Is(err, os.ErrNotExist) {
+			if err = os.WriteFile(gitkeep, nil, 0600); err != nil {
+				return fmt.Errorf("failed to create '%s': %w", gitkeep, err)
+			}
+		}
+		fmt.Fprintf(out, "📁 Templates directory: %s\n", dir)
+
+		if withExample {
+			examplePath := filepath.Join(dir, core.ExampleTemplateName)
+			if _, err = os.Lstat(examplePath); err == nil {
+				fmt.Fprintf(out, "⏭️  Skipping the example template, '%s' already exists\n", examplePath)
+				return nil
+			}
+			if err = core.WriteExampleTemplate(examplePath); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "✨ Created the example template: %s\n", examplePath)
+			fmt.Fprintf(out, "   Try it: mold apply %s -d %s -o my-project\n",
+				examplePath, filepath.Join(examplePath, "tmpl.yaml"))
+		}
+		return nil
+	},
+}
+
+//nolint:gochecknoinits // The command 'init' is acceptable.
+func init() {
+	initCmd.Flags().BoolVar(&withExample, "with-example", false,
+		"Also create a starter template named 'example' with example data")
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executeInit runs the init command.
+func executeInit(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	out, _, err := execute(t, initCmd, "", append([]string{"init"}, args...)...)
+	return out, err
+}
+
+func TestInitCmd(t *testing.T) {
+	t.Run("empty templates directory", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "templates")
+		out, err := executeInit(t, dir)
+		require.NoError(t, err)
+		assert.Contains(t, out, "📁 Templates directory: "+dir)
+		assert.FileExists(t, filepath.Join(dir, ".gitkeep"))
+		assert.NoDirExists(t, filepath.Join(dir, core.ExampleTemplateName))
+	})
+
+	t.Run("default templates directory", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "default")
+		t.Setenv(core.TemplatesDirEnv, dir)
+		_, err := executeInit(t)
+		require.NoError(t, err)
+		assert.FileExists(t, filepath.Join(dir, ".gitkeep"))
+	})
+
+	t.Run("example applies cleanly", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "templates")
+		out, err := executeInit(t, dir, "--with-example")
+		require.NoError(t, err)
+		assert.Contains(t, out, "✨ Created the example template")
+
+		// Apply the example by name, with its own example data.
+		project := filepath.Join(t.TempDir(), "project")
+		_, _, err = execute(t, applyCmd, "", "apply", "--templates-dir", dir, core.ExampleTemplateName, "--strict",
+			"-d", filepath.Join(dir, core.ExampleTemplateName, "tmpl.yaml"), "-o", project)
+		require.NoError(t, err)
+
+		readme, err := os.ReadFile(filepath.Join(project, "README.md"))
+		require.NoError(t, err)
+		assert.Contains(t, string(readme), "# hello")
+		main, err := os.ReadFile(filepath.Join(project, "hello", "main.go"))
+		require.NoError(t, err)
+		assert.Contains(t, string(main), `fmt.Println("Hello from hello!")`)
+	})
+
+	t.Run("existing example is skipped", func(t *testing.T) {
+		dir := t.TempDir()
+		examplePath := filepath.Join(dir, core.ExampleTemplateName)
+		require.NoError(t, os.MkdirAll(examplePath, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(examplePath, "mine.txt"), []byte("mine"), 0644))
+
+		out, err := executeInit(t, dir, "--with-example")
+		require.NoError(t, err)
+		assert.Contains(t, out, "Skipping the example template")
+		entries, err := os.ReadDir(examplePath)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+}
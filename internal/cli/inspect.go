@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+//nolint:gochecknoglobals // this is cmd flag
+var (
+	inspectJSON          bool
+	inspectFailOnMissing bool
+)
+
+// inspectCmd represents the inspect command.
+//
+//nolint:gochecknoglobals // this is command definition
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <template_path>",
+	Short: "Reports the keys a template needs that the data is missing, and the data it never uses",
+	Long: `Cross-references the placeholders of a template, and of the templates it
+extends, with a data file before applying it. The files, paths, partials and
+template.yaml expressions are read the way apply reads them to ask for missing
+values with --prompt-missing, so both find the same keys.
+
+Three sections are printed: the keys referenced but missing from the data, with
+the files referencing them, the values of the data no file references, and the
+keys that have a value, from the data or from the defaults, computed values or
+derived cases of the template. A nested reference such as {{.db.port}} is
+looked up in the nested data as deep as the data goes.
+
+With --json, the same report is printed as JSON. With --fail-on-missing, the
+command fails when a key is missing, for use in CI.`,
+	Args: cobra.ExactArgs(1), // Requires exactly one argument: the path to the template.
+	RunE: func(cmd *cobra.Command, args []string) error {
+		templatePath, _, err := resolveApplyTemplate(args[0], "", false, cmd.ErrOrStderr())
+		if err != nil {
+			return err
+		}
+		data, err := loadData(dataFile, dataFormat, setValues, fetchOptions(), cmd.InOrStdin(), cmd.ErrOrStderr())
+		if err != nil {
+			return err
+		}
+		report, err := core.Inspect(templatePath, data, core.DefaultMaxTemplateSize)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		if inspectJSON {
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			if err = encoder.Encode(report); err != nil {
+				return err
+			}
+		} else {
+			printInspection(out, report)
+		}
+		if inspectFailOnMissing && len(report.Missing) > 0 {
+			// The data is incomplete, the command was used right.
+			cmd.SilenceUsage = true
+			return fmt.Errorf("the data is missing %d keys the template references", len(report.Missing))
+		}
+		return nil
+	},
+}
+
+// printInspection prints the missing, unused and satisfied keys.
+func printInspection(out io.Writer, report *core.InspectReport) {
+	fmt.Fprintf(out, "❌ Missing (%d):\n", len(report.Missing))
+	for _, usage := range report.Missing {
+		fmt.Fprintf(out, "  %s: %s\n", usage.Key, strings.Join(usage.Files, ", "))
+	}
+	fmt.Fprintf(out, "💤 Unused (%d):\n", len(report.Unused))
+	for _, key := range report.Unused {
+		fmt.Fprintf(out, "  %s\n", key)
+	}
+	fmt.Fprintf(out, "✅ Satisfied (%d):\n", len(report.Satisfied))
+	for _, usage := range report.Satisfied {
+		fmt.Fprintf(out, "  %s (%s)\n", usage.Key, usage.Source)
+	}
+}
+
+//nolint:gochecknoinits // The command 'init' is acceptable.
+func init() {
+	inspectCmd.Flags().StringVarP(&dataFile, "data-file", "d", "",
+		"Path to a JSON or YAML file with placeholder data, '-' for stdin")
+	inspectCmd.Flags().Var(&overrideFlag{overrides: &setValues}, "set",
+		"Set a data value, overriding the data file (key=value, nested keys use dots: a.b=c)")
+	inspectCmd.Flags().StringVar(&dataFormat, "data-format", "",
+		"Format of the data file (json, jsonc or yaml), required when reading data from stdin with '-d -'")
+	inspectCmd.Flags().BoolVar(&inspectJSON, "json", false, "Print the report as JSON")
+	inspectCmd.Flags().BoolVar(&inspectFailOnMissing, "fail-on-missing", false,
+		"Fail when the data is missing a key the template references")
+	markPathFlags(inspectCmd.Flags(), "data-file")
+}
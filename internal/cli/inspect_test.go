@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executeInspect runs the inspect command.
+func executeInspect(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	out, _, err := execute(t, inspectCmd, "", append([]string{"inspect"}, args...)...)
+	return out, err
+}
+
+func TestInspectCmd(t *testing.T) {
+	dir := t.TempDir()
+	templateDir := filepath.Join(dir, "template")
+	require.NoError(t, os.MkdirAll(filepath.Join(templateDir, "{{.name}}"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, core.MetadataFile),
+		[]byte("defaults:\n  port: 8080\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "{{.name}}", "main.go.tmpl"),
+		[]byte("{{.port}} {{.db.host}} {{.owner}}"), 0644))
+	dataPath := filepath.Join(dir, "data.yaml")
+	require.NoError(t, os.WriteFile(dataPath, []byte("name: app\ndb: {user: admin}\nextra: 1\n"), 0644))
+
+	out, err := executeInspect(t, templateDir, "-d", dataPath)
+	require.NoError(t, err)
+	assert.Equal(t, "❌ Missing (2):\n"+
+		"  db.host: {{.name}}/main.go.tmpl\n"+
+		"  owner: {{.name}}/main.go.tmpl\n"+
+		"💤 Unused (2):\n"+
+		"  db.user\n"+
+		"  extra\n"+
+		"✅ Satisfied (2):\n"+
+		"  name (data)\n"+
+		"  port (default)\n", out)
+
+	out, err = executeInspect(t, templateDir, "-d", dataPath, "--set", "owner=ann", "--json")
+	require.NoError(t, err)
+	var report core.InspectReport
+	require.NoError(t, json.Unmarshal([]byte(out), &report))
+	assert.Equal(t, []core.KeyUsage{{Key: "db.host", Files: []string{"{{.name}}/main.go.tmpl"}}}, report.Missing)
+	assert.Equal(t, []string{"db.user", "extra"}, report.Unused)
+	assert.Len(t, report.Satisfied, 3)
+
+	_, err = executeInspect(t, templateDir, "-d", dataPath, "--fail-on-missing")
+	require.ErrorContains(t, err, "the data is missing 2 keys the template references")
+	_, err = executeInspect(t, templateDir, "-d", dataPath, "--set", "owner=ann", "--set", "db.host=db",
+		"--fail-on-missing")
+	require.NoError(t, err)
+}
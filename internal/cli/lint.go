@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+//nolint:gochecknoglobals // this is cmd flag
+var lintDisable []string
+
+// lintCmd represents the lint command.
+//
+//nolint:gochecknoglobals // this is command definition
+var lintCmd = &cobra.Command{
+	Use:   "lint <template_path>",
+	Short: "Checks a template for common mistakes",
+	Long: `Checks the files and paths of a template, and of the templates it extends,
+against the prompts and defaults declared in its template.yaml.
+
+Rules:
+  undeclared-key     (error)   a key is referenced but not declared
+  unused-input       (warning) a prompt or default is never referenced
+  parse-error        (error)   a template or path doesn't parse
+  unknown-func       (error)   a template calls a function no helper defines
+  copied-delimiters  (warning) a copied file contains template delimiters
+  min-mold-version   (warning) a setting needs a newer mold than minMoldVersion
+
+The command fails when there are errors, or warnings with --strict.`,
+	Args: cobra.ExactArgs(1), // Requires exactly one argument: the path to the template.
+	RunE: func(cmd *cobra.Command, args []string) error {
+		templatePath := workPath(args[0])
+		if _, err := os.Stat(templatePath); os.IsNotExist(err) {
+			return fmt.Errorf("template path '%s' not found", templatePath)
+		}
+		for _, rule := range lintDisable {
+			if !slices.Contains(core.LintRules, rule) {
+				return fmt.Errorf("unknown lint rule '%s'", rule)
+			}
+		}
+
+		findings, err := core.Lint(templatePath)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		var errorCount, warningCount int
+		for _, finding := range findings {
+			if slices.Contains(lintDisable, finding.Rule) {
+				continue
+			}
+			icon := "❌"
+			if finding.Level == core.LevelError {
+				errorCount++
+			} else {
+				icon = "⚠️ "
+				warningCount++
+			}
+			fmt.Fprintf(out, "%s %s [%s] %s: %s\n", icon, finding.Level, finding.Rule, finding.Path, finding.Message)
+		}
+
+		if errorCount > 0 || (strict && warningCount > 0) {
+			return fmt.Errorf("lint found %d errors and %d warnings", errorCount, warningCount)
+		}
+		fmt.Fprintf(out, "✅ Lint passed with %d warnings\n", warningCount)
+		return nil
+	},
+}
+
+//nolint:gochecknoinits // The command 'init' is acceptable.
+func init() {
+	// Add flags to the 'lint' command.
+	lintCmd.Flags().StringSliceVar(&lintDisable, "disable", nil, "Comma-separated lint rule IDs to skip")
+	lintCmd.Flags().BoolVar(&strict, "strict", false, "Treat warnings as errors")
+}
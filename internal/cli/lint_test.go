@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executeLint runs the lint command.
+func executeLint(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	out, _, err := execute(t, lintCmd, "", append([]string{"lint"}, args...)...)
+	return out, err
+}
+
+func TestLintCmd(t *testing.T) {
+	templateDir := t.TempDir()
+	files := map[string]string{
+		"template.yaml": "prompts:\n  name: {}\n  unused: {}\n",
+		"main.go.tmpl":  "{{.name}} {{.missing}}",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(templateDir, name), []byte(content), 0644))
+	}
+
+	tests := []struct {
+		name          string
+		args          []string
+		expectedError string
+		contains      []string
+		notContains   []string
+	}{
+		{
+			name:          "errors fail",
+			expectedError: "lint found 1 errors and 1 warnings",
+			contains: []string{
+				"❌ error [undeclared-key] main.go.tmpl: 'missing' is not declared in prompts or defaults",
+				"⚠️  warning [unused-input] template.yaml: 'unused' is declared but never used",
+			},
+		},
+		{
+			name:        "disabled errors pass",
+			args:        []string{"--disable", "undeclared-key"},
+			contains:    []string{"Lint passed with 1 warnings"},
+			notContains: []string{"missing"},
+		},
+		{
+			name:          "strict promotes warnings",
+			args:          []string{"--disable", "undeclared-key", "--strict"},
+			expectedError: "lint found 0 errors and 1 warnings",
+		},
+		{
+			name:          "unknown rule",
+			args:          []string{"--disable", "nope"},
+			expectedError: "unknown lint rule 'nope'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := executeLint(t, append([]string{templateDir}, tt.args...)...)
+			if tt.expectedError != "" {
+				require.ErrorContains(t, err, tt.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+			for _, s := range tt.contains {
+				assert.Contains(t, out, s)
+			}
+			for _, s := range tt.notContains {
+				assert.NotContains(t, out, s)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+//nolint:gochecknoglobals // this is cmd flag
+var (
+	listRemote bool
+	listAll    bool
+	listOutput string
+)
+
+const (
+	// originProject is the origin of the templates of the project templates
+	// directory.
+	originProject = "project"
+	// originUser is the origin of the templates of the other search paths.
+	originUser = "user"
+)
+
+// listEntry is a template listed by the list command.
+type listEntry struct {
+	Name string `json:"name"`
+	// Origin is "project", "user" or "registry:<name>".
+	Origin      string `json:"origin"`
+	Description string `json:"description,omitempty"`
+	// Path is the directory of a local template.
+	Path string `json:"path,omitempty"`
+	// URL and Ref locate a template published in a registry.
+	URL string `json:"url,omitempty"`
+	Ref string `json:"ref,omitempty"`
+	// ShadowedBy is the path or URL of the template of the same name in an
+	// earlier origin, applied instead of this one.
+	ShadowedBy string `json:"shadowed_by,omitempty"`
+}
+
+// listReport is the JSON output of the list command.
+type listReport struct {
+	Templates []listEntry `json:"templates"`
+	// Warnings are the search paths that couldn't be listed.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// listCmd represents the list command.
+//
+//nolint:gochecknoglobals // this is command definition
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the templates that can be applied by name",
+	Long: `Lists the templates of the templates directory, the first search path, with their
+descriptions.
+
+With --remote, the indexes of the registries of the config file are fetched and
+their templates listed too. A registry that can't be fetched is used from the
+cache, or skipped, with a warning, so listing works offline. With --all, the
+templates of every search path are listed along with the registries.
+
+Each template is labelled with its origin: [project] for the templates directory
+of the project, [user] for the other search paths and [registry:<name>]. They are
+listed in the order names resolve in, the search paths then the registries,
+sorted by name within each. A name published by several origins is applied from
+the first one, the others are marked as shadowed by it. A search path that is
+missing or can't be read is reported below the list rather than failing it.
+
+With --output json, the templates are printed as JSON.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if listOutput != "text" && listOutput != "json" {
+			return fmt.Errorf("invalid --output value '%s': expected text or json", listOutput)
+		}
+		local, warnings, err := listLocal(listAll)
+		if err != nil {
+			return err
+		}
+		groups := local
+		if listRemote || listAll {
+			registries, _, err := loadRegistries(cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+			groups = append(groups, listRegistries(registries)...)
+		}
+		// The shadowing is found over every origin, even the ones not listed.
+		markShadowed(groups)
+		if !listAll {
+			groups = slices.Delete(groups, 1, len(local))
+		}
+		entries := slices.Concat(groups...)
+
+		out := cmd.OutOrStdout()
+		if listOutput == "json" {
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(listReport{Templates: entries, Warnings: warnings})
+		}
+		return printList(out, entries, warnings)
+	},
+}
+
+// listLocal returns the templates of each search path, in order, sorted by
+// name. A search path that is missing or can't be read holds none, with a
+// warning when all of them are listed or it is the templates directory.
+func listLocal(all bool) ([][]listEntry, []string, error) {
+	paths, err := resolveTemplatePaths()
+	if err != nil {
+		return nil, nil, err
+	}
+	project := core.ProjectTemplatesDir(workPath("."))
+	groups := make([][]listEntry, len(paths))
+	var warnings []string
+	for i, dir := range paths {
+		groups[i] = []listEntry{}
+		warning := ""
+		if _, err = os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+			warning = fmt.Sprintf("Search path '%s' doesn't exist", dir)
+		} else if groups[i], err = listTemplatesDir(dir, project); err != nil {
+			warning = fmt.Sprintf("Could not list search path '%s': %v", dir, err)
+		}
+		if warning != "" && (all || i == 0) {
+			warnings = append(warnings, warning)
+		}
+	}
+	return groups, warnings, nil
+}
+
+// listTemplatesDir returns the templates of a search path, labelled as the
+// project's when it is the project templates directory.
+func listTemplatesDir(dir, project string) ([]listEntry, error) {
+	origin := originUser
+	if abs, err := filepath.Abs(dir); err == nil && abs == project {
+		origin = originProject
+	}
+	names, err := core.ListTemplates(dir)
+	if err != nil {
+		return []listEntry{}, err
+	}
+	entries := make([]listEntry, 0, len(names))
+	for _, name := range names {
+		path, err := core.TemplatePath(dir, name)
+		if err != nil {
+			return []listEntry{}, err
+		}
+		entry := listEntry{Name: name, Origin: origin, Path: path}
+		// A broken template is still listed, doctor tells what is wrong.
+		if meta, err := core.LoadMetadata(path); err == nil {
+			entry.Description = meta.Description
+		}
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b listEntry) int { return cmp.Compare(a.Name, b.Name) })
+	return entries, nil
+}
+
+// listRegistries returns the templates of each registry, in order, sorted by
+// name.
+func listRegistries(registries []core.Registry) [][]listEntry {
+	groups := make([][]listEntry, 0, len(registries))
+	for _, registry := range registries {
+		var entries []listEntry
+		for _, template := range registry.Index.Entries() {
+			entries = append(entries, listEntry{
+				Name:        template.Name,
+				Origin:      "registry:" + registry.Source.Name,
+				Description: template.Description,
+				URL:         template.URL,
+				Ref:         template.Ref,
+			})
+		}
+		groups = append(groups, entries)
+	}
+	return groups
+}
+
+// markShadowed marks the templates shadowed by a template of the same name
+// in an earlier group, with the path or URL of the first one.
+func markShadowed(groups [][]listEntry) {
+	found := make(map[string]string)
+	for _, entries := range groups {
+		for i := range entries {
+			entry := &entries[i]
+			if first, ok := found[entry.Name]; ok {
+				entry.ShadowedBy = first
+			} else {
+				found[entry.Name] = cmp.Or(entry.Path, entry.URL)
+			}
+		}
+	}
+}
+
+// printList prints the templates as a table, followed by the warnings.
+func printList(out io.Writer, entries []listEntry, warnings []string) error {
+	if len(entries) == 0 {
+		fmt.Fprintln(out, "No templates found.")
+	} else {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, entry := range entries {
+			description := entry.Description
+			if entry.ShadowedBy != "" {
+				description = strings.TrimSpace(description + " (shadowed by " + entry.ShadowedBy + ")")
+			}
+			fmt.Fprintf(w, "%s\t[%s]\t%s\n", entry.Name, entry.Origin, description)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(warnings) > 0 {
+		fmt.Fprintln(out)
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(out, "⚠️  %s\n", warning)
+	}
+	return nil
+}
+
+//nolint:gochecknoinits // The command 'init' is acceptable.
+func init() {
+	listCmd.Flags().BoolVar(&listRemote, "remote", false,
+		"Also list the templates of the registries of the config file")
+	listCmd.Flags().BoolVar(&listAll, "all", false,
+		"List the templates of every search path and of the registries")
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", "text", "Output format: text or json")
+}
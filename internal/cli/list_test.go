@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupRegistry writes a templates directory, a registry index publishing a
+// template from a tar archive and a config file registering it, and points
+// mold at them.
+func setupRegistry(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	local := filepath.Join(dir, "templates", "go", "service")
+	require.NoError(t, os.MkdirAll(local, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(local, core.MetadataFile),
+		[]byte("description: A local Go service\n"), 0644))
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	content := "# {{.name}}\n"
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "web/README.md.tmpl", Mode: 0644, Size: int64(len(content))}))
+	_, err := tw.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	archive := filepath.Join(dir, "web.tar")
+	require.NoError(t, os.WriteFile(archive, buf.Bytes(), 0644))
+
+	index := "schema: 1\ntemplates:\n" +
+		"  web:\n    url: file://" + filepath.ToSlash(archive) + "\n    path: web\n    description: A website\n" +
+		"  go/service:\n    url: https://example.com/acme/templates.git\n    path: go/service\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "registry.yaml"), []byte(index), 0644))
+	config := "registries:\n" +
+		"  - name: acme\n    url: " + filepath.Join(dir, "registry.yaml") + "\n" +
+		"  - name: gone\n    url: " + filepath.Join(dir, "gone.yaml") + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(config), 0644))
+
+	templates := filepath.Join(dir, "templates")
+	t.Setenv(core.TemplatePathsEnv, "")
+	t.Setenv(core.TemplatesDirEnv, templates)
+	t.Setenv(core.ConfigEnv, filepath.Join(dir, "config.yaml"))
+	t.Setenv(core.CacheDirEnv, filepath.Join(dir, "cache"))
+	return dir
+}
+
+// executeList runs the list command.
+func executeList(t *testing.T, args ...string) (string, string, error) {
+	t.Helper()
+	return execute(t, listCmd, "", append([]string{"list"}, args...)...)
+}
+
+func TestListCmd(t *testing.T) {
+	dir := setupRegistry(t)
+	local := filepath.Join(dir, "templates", "go", "service")
+
+	t.Run("local", func(t *testing.T) {
+		out, _, err := executeList(t)
+		require.NoError(t, err)
+		assert.Equal(t, "go/service  [user]  A local Go service\n", out)
+	})
+
+	t.Run("remote", func(t *testing.T) {
+		out, stderr, err := executeList(t, "--remote")
+		require.NoError(t, err)
+		assert.Equal(t, "go/service  [user]           A local Go service\n"+
+			"go/service  [registry:acme]  (shadowed by "+local+")\n"+
+			"web         [registry:acme]  A website\n", out)
+		assert.Contains(t, stderr, "⚠️  Skipping registry 'gone'")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		out, _, err := executeList(t, "--remote", "-o", "json")
+		require.NoError(t, err)
+		var report listReport
+		require.NoError(t, json.Unmarshal([]byte(out), &report))
+		require.Len(t, report.Templates, 3)
+		assert.Equal(t, "user", report.Templates[0].Origin)
+		assert.Equal(t, local, report.Templates[1].ShadowedBy)
+		assert.Equal(t, "registry:acme", report.Templates[2].Origin)
+		assert.Contains(t, report.Templates[2].URL, "web.tar")
+	})
+
+	t.Run("invalid output", func(t *testing.T) {
+		_, _, err := executeList(t, "-o", "yaml")
+		require.ErrorContains(t, err, "invalid --output value 'yaml'")
+	})
+}
+
+func TestListAll(t *testing.T) {
+	dir := setupRegistry(t)
+	t.Setenv(core.TemplatePathsEnv, "")
+	t.Setenv(core.TemplatesDirEnv, "")
+	writeFile(t, dir, ".git/HEAD", "ref: refs/heads/main\n")
+	writeFile(t, dir, "user/web/template.yaml", "description: A personal website\n")
+	writeFile(t, dir, "user/cli/template.yaml", "description: A personal CLI\n")
+	config, err := os.ReadFile(filepath.Join(dir, "config.yaml"))
+	require.NoError(t, err)
+	writeFile(t, dir, "config.yaml", string(config)+"templatePaths:\n  - user\n  - missing\n")
+	project, user := filepath.Join(dir, "templates", "go", "service"), filepath.Join(dir, "user", "web")
+
+	t.Run("first path", func(t *testing.T) {
+		out, err := executeIn(t, listCmd, "-C", dir, "list")
+		require.NoError(t, err)
+		assert.Equal(t, "go/service  [project]  A local Go service\n", out)
+	})
+
+	t.Run("remote", func(t *testing.T) {
+		// The registries are shadowed by the search paths not listed too.
+		out, err := executeIn(t, listCmd, "-C", dir, "list", "--remote")
+		require.NoError(t, err)
+		assert.Contains(t, out, "go/service  [project]        A local Go service\n"+
+			"go/service  [registry:acme]  (shadowed by "+project+")\n"+
+			"web         [registry:acme]  A website (shadowed by "+user+")\n")
+		assert.NotContains(t, out, "[user]")
+	})
+
+	t.Run("all", func(t *testing.T) {
+		out, err := executeIn(t, listCmd, "-C", dir, "list", "--all")
+		require.NoError(t, err)
+		assert.Contains(t, out, "⚠️  Skipping registry 'gone'")
+		assert.Contains(t, out, "go/service  [project]        A local Go service\n"+
+			"cli         [user]           A personal CLI\n"+
+			"web         [user]           A personal website\n"+
+			"go/service  [registry:acme]  (shadowed by "+project+")\n"+
+			"web         [registry:acme]  A website (shadowed by "+user+")\n"+
+			"\n⚠️  Search path '"+filepath.Join(dir, "missing")+"' doesn't exist\n")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		out, _, err := execute(t, listCmd, "", "-C", dir, "list", "--all", "-o", "json")
+		require.NoError(t, err)
+		var report listReport
+		require.NoError(t, json.Unmarshal([]byte(out), &report))
+		origins := make([]string, 0, len(report.Templates))
+		for _, entry := range report.Templates {
+			origins = append(origins, entry.Origin+" "+entry.Name+" "+entry.ShadowedBy)
+		}
+		assert.Equal(t, []string{
+			"project go/service ", "user cli ", "user web ",
+			"registry:acme go/service " + project, "registry:acme web " + user,
+		}, origins)
+		assert.Equal(t, []string{"Search path '" + filepath.Join(dir, "missing") + "' doesn't exist"}, report.Warnings)
+	})
+}
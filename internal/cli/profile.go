@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/0m3kk/mold/internal/core"
+)
+
+// newProfile returns the profile of an apply run, or nil when neither
+// --profile nor --profile-out is given.
+func newProfile() *core.Profile {
+	if !profile && profileOut == "" {
+		return nil
+	}
+	return core.NewProfile()
+}
+
+// reportProfile stops the profile, prints the slowest files and the phase
+// totals with --profile, and writes the whole report with --profile-out.
+func reportProfile(w io.Writer, p *core.Profile) error {
+	p.Stop()
+	report := p.Report()
+	if profile {
+		printProfile(w, report, profileTop)
+	}
+	if profileOut == "" {
+		return nil
+	}
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(profileOut, append(content, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write profile '%s': %w", profileOut, err)
+	}
+	fmt.Fprintf(w, "⏱️  Profile written to: %s\n", profileOut)
+	return nil
+}
+
+// printProfile prints the phase totals and the top slowest files of a
+// report.
+func printProfile(w io.Writer, report core.ProfileReport, top int) {
+	fmt.Fprintf(w, "\n⏱️  Profiled %d files in %s\n", len(report.Files), round(report.Elapsed))
+	for _, phase := range core.Phases {
+		fmt.Fprintf(w, "   %-8s %12s\n", phase, round(report.Totals[phase]))
+	}
+	if top <= 0 || len(report.Files) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "🐢 Slowest files:\n")
+	for _, file := range report.Files[:min(top, len(report.Files))] {
+		var phases []string
+		for _, phase := range core.Phases {
+			if d, ok := file.Phases[phase]; ok {
+				phases = append(phases, fmt.Sprintf("%s %s", phase, round(d)))
+			}
+		}
+		fmt.Fprintf(w, "   %12s  %s (%s)\n", round(file.Total), file.Path, strings.Join(phases, ", "))
+	}
+}
+
+// round rounds a duration to the microsecond for display.
+func round(d time.Duration) time.Duration {
+	return d.Round(time.Microsecond)
+}
@@ -0,0 +1,213 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"text/tabwriter"
+	"time"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+//nolint:gochecknoglobals // this is cmd flag
+var (
+	skipVerify     bool
+	registryOutput string
+)
+
+// registryInfo is a registry listed by the registry list command. Templates
+// and FetchedAt come from the cached index, and are empty for a registry
+// never fetched.
+type registryInfo struct {
+	Name      string     `json:"name"`
+	URL       string     `json:"url"`
+	Templates *int       `json:"templates"`
+	FetchedAt *time.Time `json:"fetched_at"`
+}
+
+// registryReport is the JSON output of the registry list command.
+type registryReport struct {
+	Registries []registryInfo `json:"registries"`
+}
+
+// registryCmd groups the commands managing the registries of the config file.
+//
+//nolint:gochecknoglobals // this is command definition
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "Manages the registries templates are published in",
+	Long: `Adds, removes and lists the registries of the config file, the registry.yaml
+indexes that 'mold list --remote' lists and 'mold apply' looks template names up
+in. The config file is ~/.mold/config.yaml, or the path in $` + core.ConfigEnv + `.`,
+}
+
+// registryAddCmd represents the registry add command.
+//
+//nolint:gochecknoglobals // this is command definition
+var registryAddCmd = &cobra.Command{
+	Use:   "add <name> <url>",
+	Short: "Registers a registry in the config file",
+	Long: `Registers the registry index at url under name, after the registries already
+registered. The url is an https URL of a registry.yaml file, a git repository
+holding one at its root, or a local path. Names use lowercase letters, digits,
+'.', '_' and '-', and must be unique.
+
+The index is fetched, checked and cached before the registry is saved, so a
+mistyped url or an invalid index is refused. --skip-verify saves it without
+fetching, such as when the registry can't be reached yet.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+		path, config, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		source := core.RegistrySource{Name: args[0], URL: args[1]}
+		if err = config.AddRegistry(source); err != nil {
+			return err
+		}
+		if !skipVerify {
+			cacheDir, err := defaultCacheDir()
+			if err != nil {
+				return err
+			}
+			index, err := core.FetchRegistryIndex(source, cacheDir, fetchOptions())
+			if err != nil {
+				return fmt.Errorf("failed to verify registry '%s', use --skip-verify to add it anyway: %w",
+					source.Name, err)
+			}
+			fmt.Fprintf(out, "🔎 Registry '%s' publishes %d templates\n", source.Name, len(index.Templates))
+		}
+		if err = core.SaveConfig(path, config); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "✅ Added registry '%s': %s\n", source.Name, source.URL)
+		return nil
+	},
+}
+
+// registryRemoveCmd represents the registry remove command.
+//
+//nolint:gochecknoglobals // this is command definition
+var registryRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Removes a registry from the config file",
+	Long: `Removes the registry with the given name from the config file, and deletes its
+cached index. The templates already fetched from it stay in the cache.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, config, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if err = config.RemoveRegistry(args[0]); err != nil {
+			return err
+		}
+		if err = core.SaveConfig(path, config); err != nil {
+			return err
+		}
+		cacheDir, err := defaultCacheDir()
+		if err != nil {
+			return err
+		}
+		if err = core.RemoveRegistryCache(cacheDir, args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "✅ Removed registry '%s'\n", args[0])
+		return nil
+	},
+}
+
+// registryListCmd represents the registry list command.
+//
+//nolint:gochecknoglobals // this is command definition
+var registryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the registries of the config file",
+	Long: `Lists the registries of the config file in the order template names are looked
+up in them, with their url, and the number of templates and the time of their
+cached index. Nothing is fetched: 'mold list --remote' refreshes the indexes.
+
+With --output json, the registries are printed as JSON.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if registryOutput != "text" && registryOutput != "json" {
+			return fmt.Errorf("invalid --output value '%s': expected text or json", registryOutput)
+		}
+		_, config, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		cacheDir, err := defaultCacheDir()
+		if err != nil {
+			return err
+		}
+		registries := make([]registryInfo, 0, len(config.Registries))
+		for _, source := range config.Registries {
+			info := registryInfo{Name: source.Name, URL: source.URL}
+			index, fetched, err := core.CachedRegistryIndex(cacheDir, source.Name)
+			switch {
+			case err == nil:
+				count := len(index.Templates)
+				info.Templates, info.FetchedAt = &count, &fetched
+			case !errors.Is(err, fs.ErrNotExist):
+				fmt.Fprintf(cmd.ErrOrStderr(), "⚠️  Ignoring the cached index of registry '%s': %v\n", source.Name, err)
+			}
+			registries = append(registries, info)
+		}
+
+		out := cmd.OutOrStdout()
+		if registryOutput == "json" {
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(registryReport{Registries: registries})
+		}
+		return printRegistries(out, registries)
+	},
+}
+
+// loadConfig reads the config file and returns it with its path.
+func loadConfig() (string, *core.Config, error) {
+	path, err := core.DefaultConfigPath()
+	if err != nil {
+		return "", nil, err
+	}
+	path = workPath(path)
+	config, err := core.LoadConfig(path)
+	if err != nil {
+		return "", nil, err
+	}
+	return path, config, nil
+}
+
+// printRegistries prints the registries as a table.
+func printRegistries(out io.Writer, registries []registryInfo) error {
+	if len(registries) == 0 {
+		fmt.Fprintln(out, "No registries configured, add one with 'mold registry add <name> <url>'.")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tURL\tTEMPLATES\tFETCHED")
+	for _, info := range registries {
+		templates, fetched := "-", "never"
+		if info.Templates != nil {
+			templates = fmt.Sprint(*info.Templates)
+			fetched = info.FetchedAt.Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", info.Name, info.URL, templates, fetched)
+	}
+	return w.Flush()
+}
+
+//nolint:gochecknoinits // The command 'init' is acceptable.
+func init() {
+	registryAddCmd.Flags().BoolVar(&skipVerify, "skip-verify", false,
+		"Add the registry without fetching and checking its index")
+	registryListCmd.Flags().StringVarP(&registryOutput, "output", "o", "text", "Output format: text or json")
+	registryCmd.AddCommand(registryAddCmd, registryRemoveCmd, registryListCmd)
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executeRegistry runs the registry command, returning its stdout and stderr.
+func executeRegistry(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	stdout, stderr, err := execute(t, registryCmd, "", append([]string{"registry"}, args...)...)
+	return stdout + stderr, err
+}
+
+func TestRegistryCmd(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	cacheDir := filepath.Join(dir, "cache")
+	t.Setenv(core.ConfigEnv, configPath)
+	t.Setenv(core.CacheDirEnv, cacheDir)
+	index := filepath.Join(dir, "registry.yaml")
+	require.NoError(t, os.WriteFile(index,
+		[]byte("schema: 1\ntemplates:\n  web:\n    url: https://example.com/web.git\n"), 0644))
+
+	out, err := executeRegistry(t, "list")
+	require.NoError(t, err)
+	assert.Contains(t, out, "No registries configured")
+
+	out, err = executeRegistry(t, "add", "acme", index)
+	require.NoError(t, err)
+	assert.Contains(t, out, "🔎 Registry 'acme' publishes 1 templates")
+	assert.Contains(t, out, "✅ Added registry 'acme': "+index)
+	assert.FileExists(t, core.RegistryCachePath(cacheDir, "acme"))
+
+	_, err = executeRegistry(t, "add", "acme", index)
+	require.ErrorContains(t, err, "registry 'acme' already exists")
+	_, err = executeRegistry(t, "add", "plain", "http://example.com/registry.yaml")
+	require.ErrorContains(t, err, "over plain http")
+	_, err = executeRegistry(t, "add", "gone", filepath.Join(dir, "missing.yaml"))
+	require.ErrorContains(t, err, "failed to verify registry 'gone', use --skip-verify")
+
+	out, err = executeRegistry(t, "add", "gone", filepath.Join(dir, "missing.yaml"), "--skip-verify")
+	require.NoError(t, err)
+	assert.NotContains(t, out, "🔎")
+
+	config, err := core.LoadConfig(configPath)
+	require.NoError(t, err)
+	require.Len(t, config.Registries, 2)
+	assert.Equal(t, core.RegistrySource{Name: "acme", URL: index}, config.Registries[0])
+	assert.Equal(t, "gone", config.Registries[1].Name)
+
+	out, err = executeRegistry(t, "list")
+	require.NoError(t, err)
+	lines := bytes.Split(bytes.TrimSpace([]byte(out)), []byte("\n"))
+	require.Len(t, lines, 3)
+	assert.Regexp(t, `^NAME\s+URL\s+TEMPLATES\s+FETCHED$`, string(lines[0]))
+	assert.Regexp(t, `^acme\s+\S+registry\.yaml\s+1\s+\d{4}-\d{2}-\d{2} \d{2}:\d{2}$`, string(lines[1]))
+	assert.Regexp(t, `^gone\s+\S+missing\.yaml\s+-\s+never$`, string(lines[2]))
+
+	out, err = executeRegistry(t, "list", "-o", "json")
+	require.NoError(t, err)
+	var report registryReport
+	require.NoError(t, json.Unmarshal([]byte(out), &report))
+	require.Len(t, report.Registries, 2)
+	require.NotNil(t, report.Registries[0].Templates)
+	assert.Equal(t, 1, *report.Registries[0].Templates)
+	assert.Nil(t, report.Registries[1].FetchedAt)
+
+	out, err = executeRegistry(t, "remove", "acme")
+	require.NoError(t, err)
+	assert.Contains(t, out, "✅ Removed registry 'acme'")
+	assert.NoFileExists(t, core.RegistryCachePath(cacheDir, "acme"))
+	_, err = executeRegistry(t, "remove", "acme")
+	require.ErrorContains(t, err, "registry 'acme' not found")
+
+	config, err = core.LoadConfig(configPath)
+	require.NoError(t, err)
+	require.Len(t, config.Registries, 1)
+	assert.Equal(t, "gone", config.Registries[0].Name)
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+// renameCmd represents the rename command.
+//
+//nolint:gochecknoglobals // this is command definition
+var renameCmd = &cobra.Command{
+	Use:   "rename <src> <dst>",
+	Short: "Renames a template in the templates directory",
+	Long: `Moves the template src to dst in the templates directory. Nested names, such
+as 'go/service', create their grouping directories as needed. The 'name' field of
+its template.yaml is set to dst. An existing template is never overwritten.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := resolveTemplatesDir()
+		if err != nil {
+			return err
+		}
+		path, err := core.RenameTemplate(dir, args[0], args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "✅ Renamed template '%s' to '%s': %s\n", args[0], args[1], path)
+		return nil
+	},
+}
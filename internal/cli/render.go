@@ -0,0 +1,252 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+//nolint:gochecknoglobals // this is cmd flag
+var (
+	renderOutput string
+	templateRoot string
+	dataFormat   string
+	dataTimeout  = core.DefaultDataTimeout
+	insecureData bool
+	strictData   bool
+	yaml11Bools  bool
+	// maxIncludeDepth limits how deep {{template}} calls nest.
+	maxIncludeDepth = core.DefaultMaxIncludeDepth
+)
+
+const (
+	// stdinPath is the path argument that stands for stdin.
+	stdinPath = "-"
+	// stdinName names stdin in error messages.
+	stdinName = "<stdin>"
+	// stdinSeparator is the line between the data and the template when both
+	// are read from stdin.
+	stdinSeparator = "---"
+)
+
+// renderCmd represents the render command.
+//
+//nolint:gochecknoglobals // this is command definition
+var renderCmd = &cobra.Command{
+	Use:   "render <template_file|->",
+	Short: "Renders a single template file to stdout",
+	Long: `Renders one template file with the given data and writes the result to stdout,
+or to the file given by --output. Use it to preview a template without
+generating a whole project.
+
+Pass '-' as the template file to read the template from stdin. Data can also
+be read from stdin with '--data-file -' and '--data-format'. When both come from
+stdin, it holds the data first, then a '---' line, then the template.
+
+When the file belongs to a template that has a '_partials' directory, pass the
+template directory with --template-root so the partials can be included.`,
+	Args: cobra.ExactArgs(1), // Requires exactly one argument: the template file.
+	RunE: func(cmd *cobra.Command, args []string) error {
+		templateFile := workPath(args[0])
+		dataIn, templateIn := cmd.InOrStdin(), cmd.InOrStdin()
+		if templateFile == stdinPath && dataFile == stdinPath {
+			if dataFormat == "" {
+				return errors.New("cannot read both the template and the data file from stdin without --data-format")
+			}
+			var err error
+			if dataIn, templateIn, err = splitStdin(cmd.InOrStdin()); err != nil {
+				return err
+			}
+		}
+
+		data, err := loadData(dataFile, dataFormat, setValues, fetchOptions(), dataIn, cmd.ErrOrStderr())
+		if err != nil {
+			return err
+		}
+
+		partialsDir := ""
+		if templateRoot != "" {
+			partialsDir = filepath.Join(templateRoot, core.PartialsDir)
+		}
+		renderer, err := core.NewRenderer(partialsDir, strict)
+		if err != nil {
+			return err
+		}
+		renderer.MaxIncludeDepth = maxIncludeDepth
+
+		name, content, perm, err := readTemplate(templateFile, templateIn)
+		if err != nil {
+			return err
+		}
+		// Render into a buffer so a failing template doesn't write partial output.
+		var result bytes.Buffer
+		if err = renderer.Render(&result, name, content, data); err != nil {
+			return err
+		}
+
+		if renderOutput != "" {
+			if err = os.WriteFile(renderOutput, result.Bytes(), perm); err != nil {
+				return fmt.Errorf("failed to write output file '%s': %w", renderOutput, err)
+			}
+			return nil
+		}
+		_, err = cmd.OutOrStdout().Write(result.Bytes())
+		return err
+	},
+}
+
+// splitStdin splits stdin holding both the data and the template at the
+// first '---' line, and returns readers for the data and the template.
+func splitStdin(stdin io.Reader) (io.Reader, io.Reader, error) {
+	content, err := io.ReadAll(stdin)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read stdin: %w", err)
+	}
+	for offset := 0; offset < len(content); {
+		line, _, _ := bytes.Cut(content[offset:], []byte("\n"))
+		next := offset + len(line) + 1
+		if string(bytes.TrimSuffix(line, []byte("\r"))) == stdinSeparator {
+			return bytes.NewReader(content[:offset]), bytes.NewReader(content[min(next, len(content)):]), nil
+		}
+		offset = next
+	}
+	return nil, nil, fmt.Errorf("no '%s' line between the data and the template read from stdin", stdinSeparator)
+}
+
+// readTemplate returns the name used in error messages, the content and the
+// permissions of the template file, which is read from stdin for "-".
+func readTemplate(path string, stdin io.Reader) (string, []byte, os.FileMode, error) {
+	if path == stdinPath {
+		content, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", nil, 0, fmt.Errorf("could not read template from stdin: %w", err)
+		}
+		return stdinName, content, 0644, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("could not read template file '%s': %w", path, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("failed to stat template file '%s': %w", path, err)
+	}
+	return filepath.Base(path), content, info.Mode().Perm(), nil
+}
+
+// loadData loads the data file, if any, and applies the --set and --set-file
+// overrides on top. A path of "-" reads the data from stdin, which requires a
+// format, and an http or https URL is fetched with the fetch options.
+// Warnings about the data and the overrides are written to stderr.
+func loadData(
+	path, format string, overrides []core.Override, fetch core.FetchOptions, stdin io.Reader, stderr io.Writer,
+) (map[string]any, error) {
+	data := make(map[string]any)
+	dataOpts := core.DataOptions{Strict: strictData, YAML11Bools: yaml11Bools, Warn: stderr}
+	var err error
+	switch {
+	case path == stdinPath:
+		if format == "" {
+			return nil, errors.New("the --data-format flag is required when reading data from stdin")
+		}
+		var content []byte
+		if content, err = io.ReadAll(stdin); err != nil {
+			return nil, fmt.Errorf("failed to read data from stdin: %w", err)
+		}
+		data, err = core.ParseData(content, format, stdinName, dataOpts)
+	case core.IsDataURL(path):
+		data, err = core.LoadDataURL(path, format, fetch, dataOpts)
+	case path != "" && format != "":
+		data, err = core.LoadDataFileAs(path, format, dataOpts)
+	case path != "":
+		data, err = core.LoadDataFile(path, dataOpts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = core.ApplyOverrides(data, overrides, stderr); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// fetchOptions returns the options for fetching data URLs and templates
+// from the flags. --max-bytes is checked by apply, the other commands keep
+// its default.
+func fetchOptions() core.FetchOptions {
+	limit, _ := core.ParseSize(maxBytes)
+	return core.FetchOptions{Timeout: dataTimeout, Insecure: insecureData, MaxFiles: maxFiles, MaxBytes: limit}
+}
+
+// overrideFlag collects --set, --set-string and --set-file values into one
+// list, so the last flag wins whichever of them it is.
+type overrideFlag struct {
+	overrides *[]core.Override
+	kind      core.OverrideKind
+}
+
+// String implements pflag.Value. It is always the empty default: pflag
+// prints it in the usage, which must not show the values of a run.
+func (f *overrideFlag) String() string {
+	return ""
+}
+
+// Set implements pflag.Value.
+func (f *overrideFlag) Set(expr string) error {
+	*f.overrides = append(*f.overrides, core.Override{Expr: expr, Kind: f.kind})
+	return nil
+}
+
+// Type implements pflag.Value.
+func (f *overrideFlag) Type() string {
+	if f.kind == core.OverrideFile {
+		return "key=path"
+	}
+	return "key=value"
+}
+
+// addRenderFlags registers the rendering flags shared by apply and render.
+func addRenderFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&strict, "strict", false,
+		"Fail on missing keys and treat warnings, such as formatter failures, as errors")
+	cmd.Flags().Var(&overrideFlag{overrides: &setValues}, "set",
+		"Set a data value, overriding the data file (key=value, nested keys use dots: a.b=c)")
+	cmd.Flags().Var(&overrideFlag{overrides: &setValues, kind: core.OverrideString}, "set-string",
+		"Set a data value as a string, never as a number, boolean or null (--set version=1.10 is the number 1.1, "+
+			"--set-string version=1.10 is the string \"1.10\")")
+	cmd.Flags().Var(&overrideFlag{overrides: &setValues, kind: core.OverrideFile}, "set-file",
+		"Set a data value to the content of a file, overriding the data file (key=path)")
+	cmd.Flags().StringVar(&dataFormat, "data-format", "",
+		"Format of the data file (json, jsonc or yaml), required when reading data from stdin with '-d -'")
+	cmd.Flags().DurationVar(&dataTimeout, "data-timeout", core.DefaultDataTimeout,
+		"Timeout for fetching the data file when it is an http(s) URL")
+	cmd.Flags().BoolVar(&insecureData, "insecure-data", false, "Allow fetching the data file over plain http")
+	cmd.Flags().BoolVar(&strictData, "strict-data", false,
+		"Fail when a key is defined twice in the same mapping of the data file instead of warning")
+	cmd.Flags().BoolVar(&yaml11Bools, "yaml11-bools", false,
+		"Read the unquoted YAML 1.1 words yes, no, on, off, y and n in the data file as booleans "+
+			"(with --strict-data, warn about them instead)")
+	cmd.Flags().IntVar(&maxIncludeDepth, "max-include-depth", core.DefaultMaxIncludeDepth,
+		"Fail when {{template}} calls nest deeper than this")
+}
+
+//nolint:gochecknoinits // The command 'init' is acceptable.
+func init() {
+	// Add flags to the 'render' command.
+	renderCmd.Flags().StringVarP(&renderOutput, "output", "o", "", "Write the result to this file instead of stdout")
+	renderCmd.Flags().StringVarP(&dataFile, "data-file", "d", "",
+		"Path to a JSON or YAML file with placeholder data ('-' for stdin, "+
+			"followed by a '---' line and the template when the template is '-' too)")
+	renderCmd.Flags().StringVar(&templateRoot, "template-root", "",
+		"Template directory whose '_partials' can be included by the file")
+	addRenderFlags(renderCmd)
+	markPathFlags(renderCmd.Flags(), "output", "data-file", "template-root")
+}
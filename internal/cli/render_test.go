@@ -0,0 +1,309 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executeRender runs the render command, reading stdin.
+func executeRender(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+	out, _, err := execute(t, renderCmd, stdin, append([]string{"render"}, args...)...)
+	return out, err
+}
+
+func TestRenderCmd(t *testing.T) {
+	tempDir := t.TempDir()
+	templateDir := filepath.Join(tempDir, "template")
+	require.NoError(t, os.MkdirAll(filepath.Join(templateDir, "_partials"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(templateDir, "cmd"), 0755))
+
+	templateFile := filepath.Join(templateDir, "cmd", "main.go.tmpl")
+	require.NoError(t, os.WriteFile(templateFile, []byte("package {{snake .name}} // {{.version}}"), 0644))
+
+	partialFile := filepath.Join(templateDir, "cmd", "with_partial.tmpl")
+	require.NoError(t, os.WriteFile(partialFile, []byte(`{{template "header.tmpl" .}}`), 0644))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(templateDir, "_partials", "header.tmpl"),
+		[]byte("// Generated for {{.name}}"),
+		0644,
+	))
+
+	brokenFile := filepath.Join(templateDir, "broken.tmpl")
+	require.NoError(t, os.WriteFile(brokenFile, []byte("line one\n{{.name"), 0644))
+
+	dataPath := filepath.Join(tempDir, "data.yaml")
+	require.NoError(t, os.WriteFile(dataPath, []byte("name: MyService\nversion: v1"), 0644))
+
+	t.Run("render_to_stdout", func(t *testing.T) {
+		out, err := executeRender(t, "", templateFile, "-d", dataPath)
+		require.NoError(t, err)
+		assert.Equal(t, "package my_service // v1", out)
+	})
+
+	t.Run("set_overrides_data_file", func(t *testing.T) {
+		out, err := executeRender(t, "", templateFile, "-d", dataPath, "--set", "version=v2")
+		require.NoError(t, err)
+		assert.Equal(t, "package my_service // v2", out)
+	})
+
+	t.Run("set_without_data_file", func(t *testing.T) {
+		out, err := executeRender(t, "", templateFile, "--set", "name=other", "--set", "version=1")
+		require.NoError(t, err)
+		assert.Equal(t, "package other // 1", out)
+	})
+
+	t.Run("render_to_file", func(t *testing.T) {
+		outputFile := filepath.Join(tempDir, "main.go")
+		out, err := executeRender(t, "", templateFile, "-d", dataPath, "-o", outputFile)
+		require.NoError(t, err)
+		assert.Empty(t, out)
+
+		content, err := os.ReadFile(outputFile)
+		require.NoError(t, err)
+		assert.Equal(t, "package my_service // v1", string(content))
+	})
+
+	t.Run("partials_with_template_root", func(t *testing.T) {
+		out, err := executeRender(t, "", partialFile, "-d", dataPath, "--template-root", templateDir)
+		require.NoError(t, err)
+		assert.Equal(t, "// Generated for MyService", out)
+	})
+
+	t.Run("max_include_depth", func(t *testing.T) {
+		_, err := executeRender(t, "", partialFile, "-d", dataPath, "--template-root", templateDir,
+			"--max-include-depth", "0")
+		require.NoError(t, err, "0 means the default depth")
+
+		deepFile := filepath.Join(templateDir, "deep.tmpl")
+		require.NoError(t, os.WriteFile(deepFile, []byte(`{{define "a"}}{{template "header.tmpl" .}}{{end}}`+
+			`{{template "a" .}}`), 0644))
+		out, err := executeRender(t, "", deepFile, "-d", dataPath, "--template-root", templateDir)
+		require.NoError(t, err)
+		assert.Equal(t, "// Generated for MyService", out)
+		_, err = executeRender(t, "", deepFile, "-d", dataPath, "--template-root", templateDir,
+			"--max-include-depth", "1")
+		require.ErrorContains(t, err, "templates nested deeper than 1: deep.tmpl -> a -> header.tmpl")
+	})
+
+	t.Run("partials_without_template_root", func(t *testing.T) {
+		_, err := executeRender(t, "", partialFile, "-d", dataPath)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `template "header.tmpl" not defined`)
+	})
+
+	t.Run("strict_missing_key", func(t *testing.T) {
+		out, err := executeRender(t, "", templateFile, "--set", "name=x", "--strict")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `map has no entry for key "version"`)
+		assert.NotContains(t, out, "package")
+	})
+
+	t.Run("parse_error_has_line", func(t *testing.T) {
+		_, err := executeRender(t, "", brokenFile, "-d", dataPath)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "broken.tmpl:2:")
+	})
+
+	t.Run("missing_template_file", func(t *testing.T) {
+		_, err := executeRender(t, "", filepath.Join(tempDir, "nope.tmpl"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "could not read template file")
+	})
+
+	t.Run("data_from_url", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"name": "remote"}`))
+		}))
+		defer server.Close()
+
+		_, err := executeRender(t, "Hello {{.name}}", "-", "-d", server.URL+"/services/foo")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "--insecure-data")
+
+		out, err := executeRender(t, "Hello {{.name}}", "-", "-d", server.URL+"/services/foo", "--insecure-data",
+			"--data-timeout", "5s")
+		require.NoError(t, err)
+		assert.Equal(t, "Hello remote", out)
+	})
+
+	t.Run("strict_data_rejects_duplicate_keys", func(t *testing.T) {
+		dupPath := filepath.Join(tempDir, "dup.yaml")
+		require.NoError(t, os.WriteFile(dupPath, []byte("name: first\nname: second\n"), 0644))
+
+		out, err := executeRender(t, "Hello {{.name}}", "-", "-d", dupPath)
+		require.NoError(t, err)
+		assert.Equal(t, "Hello second", out)
+
+		_, err = executeRender(t, "Hello {{.name}}", "-", "-d", dupPath, "--strict-data")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "duplicate key 'name' at line 2, first defined at line 1")
+	})
+
+	t.Run("yaml11_bools", func(t *testing.T) {
+		boolsPath := filepath.Join(tempDir, "bools.yaml")
+		require.NoError(t, os.WriteFile(boolsPath, []byte("enabled: yes\nanswer: \"no\"\n"), 0644))
+
+		out, err := executeRender(t, "{{.enabled}} {{.answer}}", "-", "-d", boolsPath)
+		require.NoError(t, err)
+		assert.Equal(t, "yes no", out)
+
+		out, err = executeRender(t, "{{.enabled}} {{.answer}}", "-", "-d", boolsPath, "--yaml11-bools")
+		require.NoError(t, err)
+		assert.Equal(t, "true no", out)
+
+		out, err = executeRender(t, "{{.enabled}}", "-", "-d", boolsPath, "--yaml11-bools", "--strict-data")
+		require.NoError(t, err)
+		assert.Equal(t, "yes", out)
+	})
+
+	t.Run("set_file_and_set_last_wins", func(t *testing.T) {
+		namePath := filepath.Join(tempDir, "name.txt")
+		require.NoError(t, os.WriteFile(namePath, []byte("from file\n"), 0644))
+
+		out, err := executeRender(t, "Hello {{.name}}", "-", "--set", "name=flag", "--set-file", "name="+namePath)
+		require.NoError(t, err)
+		assert.Equal(t, "Hello from file", out)
+
+		out, err = executeRender(t, "Hello {{.name}}", "-", "--set-file", "name="+namePath, "--set", "name=flag")
+		require.NoError(t, err)
+		assert.Equal(t, "Hello flag", out)
+	})
+
+	t.Run("set_string_keeps_raw_value", func(t *testing.T) {
+		out, err := executeRender(t, "{{.version}} {{.enabled}} {{.none}}", "-",
+			"--set-string", "version=1.10", "--set-string", "enabled=yes", "--set-string", "none=null")
+		require.NoError(t, err)
+		assert.Equal(t, "1.10 yes null", out)
+
+		out, err = executeRender(t, "{{.version}}", "-", "--set-string", "version=1.10", "--set", "version=1.10")
+		require.NoError(t, err)
+		assert.Equal(t, "1.1", out)
+	})
+
+	t.Run("missing_set_file", func(t *testing.T) {
+		outputFile := filepath.Join(tempDir, "never.txt")
+		_, err := executeRender(t, "Hello {{.name}}", "-", "--set-file", "name=missing.txt", "-o", outputFile)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to read --set-file 'name=missing.txt'")
+		assert.NoFileExists(t, outputFile)
+	})
+
+	t.Run("invalid_set", func(t *testing.T) {
+		_, err := executeRender(t, "", templateFile, "--set", "novalue")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "expected key=value")
+	})
+
+	t.Run("usage_shows_no_set_values", func(t *testing.T) {
+		_, err := executeRender(t, "", templateFile, "--set", "name=x", "--set-string", "id=1", "--set-file", "k=v")
+		require.Error(t, err)
+		for _, line := range strings.Split(renderCmd.UsageString(), "\n") {
+			if strings.Contains(line, "--set") {
+				assert.NotContains(t, line, "(default", "usage line %q", line)
+			}
+		}
+	})
+}
+
+func TestRenderCmdStdin(t *testing.T) {
+	tempDir := t.TempDir()
+	dataPath := filepath.Join(tempDir, "data.yaml")
+	require.NoError(t, os.WriteFile(dataPath, []byte("name: file"), 0644))
+
+	t.Run("template_from_stdin", func(t *testing.T) {
+		out, err := executeRender(t, "Hello {{.name}}", "-", "-d", dataPath, "--set", "name=world")
+		require.NoError(t, err)
+		assert.Equal(t, "Hello world", out)
+	})
+
+	t.Run("template_from_stdin_without_data", func(t *testing.T) {
+		out, err := executeRender(t, "Hello {{camel .name}}", "-", "--set", "name=my_app")
+		require.NoError(t, err)
+		assert.Equal(t, "Hello MyApp", out)
+	})
+
+	t.Run("stdin_errors_reference_stdin", func(t *testing.T) {
+		_, err := executeRender(t, "line one\nHello {{.name", "-")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "<stdin>:2:")
+	})
+
+	t.Run("stdin_execution_errors_have_position", func(t *testing.T) {
+		_, err := executeRender(t, "Hello {{.name.first}}", "-", "--set", "name=x")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "<stdin>:1:")
+	})
+
+	t.Run("stdin_template_to_file", func(t *testing.T) {
+		outputFile := filepath.Join(tempDir, "out.txt")
+		out, err := executeRender(t, "Hello {{.name}}", "-", "--set", "name=file", "-o", outputFile)
+		require.NoError(t, err)
+		assert.Empty(t, out)
+
+		content, err := os.ReadFile(outputFile)
+		require.NoError(t, err)
+		assert.Equal(t, "Hello file", string(content))
+	})
+
+	t.Run("data_from_stdin", func(t *testing.T) {
+		templateFile := filepath.Join(tempDir, "hello.tmpl")
+		require.NoError(t, os.WriteFile(templateFile, []byte("Hello {{.name}}"), 0644))
+
+		out, err := executeRender(t, `{"name": "json"}`, templateFile, "-d", "-", "--data-format", "json")
+		require.NoError(t, err)
+		assert.Equal(t, "Hello json", out)
+	})
+
+	t.Run("data_from_stdin_requires_format", func(t *testing.T) {
+		templateFile := filepath.Join(tempDir, "hello.tmpl")
+		_, err := executeRender(t, "name: yaml", templateFile, "-d", "-")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "--data-format flag is required")
+	})
+
+	t.Run("template_and_data_both_from_stdin", func(t *testing.T) {
+		out, err := executeRender(t, "name: yaml\r\n---\r\nHello {{.name}}\n---\n", "-", "-d", "-", "--data-format", "yaml")
+		require.NoError(t, err)
+		assert.Equal(t, "Hello yaml\n---\n", out)
+
+		out, err = executeRender(t, "---\n{{.name}}", "-", "-d", "-", "--data-format", "yaml", "--set", "name=set")
+		require.NoError(t, err)
+		assert.Equal(t, "set", out)
+
+		_, err = executeRender(t, "{\"name\": \"json\"}\nHello {{.name}}", "-", "-d", "-", "--data-format", "json")
+		require.ErrorContains(t, err, "no '---' line between the data and the template read from stdin")
+
+		_, err = executeRender(t, "name: yaml\n---\nHello", "-", "-d", "-")
+		require.ErrorContains(t, err, "cannot read both the template and the data file from stdin without --data-format")
+	})
+
+	t.Run("data_format_overrides_extension", func(t *testing.T) {
+		dataTxt := filepath.Join(tempDir, "data.txt")
+		require.NoError(t, os.WriteFile(dataTxt, []byte("name: txt"), 0644))
+
+		out, err := executeRender(t, "Hello {{.name}}", "-", "-d", dataTxt, "--data-format", "yaml")
+		require.NoError(t, err)
+		assert.Equal(t, "Hello txt", out)
+	})
+}
+
+func TestRenderCmdFlags(t *testing.T) {
+	flags := []string{
+		"output", "data-file", "template-root", "strict", "set", "set-string", "set-file",
+		"data-format", "data-timeout", "insecure-data", "strict-data", "yaml11-bools",
+	}
+	for _, name := range flags {
+		assert.NotNil(t, renderCmd.Flags().Lookup(name), "missing flag %q", name)
+	}
+	assert.Equal(t, "o", renderCmd.Flags().Lookup("output").Shorthand)
+	assert.Equal(t, "d", renderCmd.Flags().Lookup("data-file").Shorthand)
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+//nolint:gochecknoglobals // this is cmd flag
+var (
+	reverseVars         []string
+	reverseOutput       string
+	reverseWordBoundary bool
+)
+
+// reverseCmd represents the reverse command.
+//
+//nolint:gochecknoglobals // this is command definition
+var reverseCmd = &cobra.Command{
+	Use:   "reverse <project_dir>",
+	Short: "Turns an existing project into a template",
+	Long: `Copies an existing project into a new template directory and replaces each
+value given with --var by its placeholder, in file contents and in paths. Files
+with replacements get the '.tmpl' suffix. Version control directories are
+skipped. A template.yaml declaring the variables as prompts and a tmpl.yaml with
+the original values are written, so applying the template with that data
+generates the project again.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+		if reverseOutput == "" {
+			return errors.New("the --output flag is required")
+		}
+		vars := make([]core.ReverseVar, 0, len(reverseVars))
+		for _, expr := range reverseVars {
+			name, value, ok := strings.Cut(expr, "=")
+			if !ok {
+				return fmt.Errorf("invalid --var value '%s': expected name=value", expr)
+			}
+			vars = append(vars, core.ReverseVar{Name: name, Value: value})
+		}
+
+		projectDir := workPath(args[0])
+		fmt.Fprintf(out, "🔄 Reversing project: %s\n", projectDir)
+		counts, err := core.Reverse(core.ReverseOptions{
+			ProjectDir:   projectDir,
+			OutputDir:    reverseOutput,
+			Vars:         vars,
+			WordBoundary: reverseWordBoundary,
+			Out:          out,
+		})
+		if err != nil {
+			return err
+		}
+		for _, v := range vars {
+			fmt.Fprintf(out, "🔁 %s: %d replacements of '%s'\n", v.Name, counts[v.Name], v.Value)
+		}
+		fmt.Fprintf(out, "\n✅ Created template: %s\n", reverseOutput)
+		return nil
+	},
+}
+
+//nolint:gochecknoinits // The command 'init' is acceptable.
+func init() {
+	reverseCmd.Flags().StringArrayVar(&reverseVars, "var", nil,
+		"Replace a literal value with a placeholder, such as project_name=acme (can be repeated)")
+	reverseCmd.Flags().StringVarP(&reverseOutput, "output", "o", "", "Template directory to create (required)")
+	reverseCmd.Flags().BoolVar(&reverseWordBoundary, "word-boundary", false,
+		"Only replace values that are not part of a longer word")
+	markPathFlags(reverseCmd.Flags(), "output")
+}
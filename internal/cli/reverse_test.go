@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executeReverse runs the reverse command.
+func executeReverse(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	out, _, err := execute(t, reverseCmd, "", append([]string{"reverse"}, args...)...)
+	return out, err
+}
+
+func TestReverseCmd(t *testing.T) {
+	project := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(project, ".git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(project, ".git", "HEAD"), []byte("acme"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(project, "go.mod"), []byte("module github.com/acme/svc\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(project, "acme.txt"), []byte("acme and go"), 0644))
+	templateDir := filepath.Join(t.TempDir(), "new-template")
+
+	out, err := executeReverse(t, project, "-o", templateDir,
+		"--var", "project_name=acme", "--var", "module=github.com/acme/svc", "--var", "lang=go")
+	require.NoError(t, err)
+	assert.Contains(t, out, "🔁 project_name: 2 replacements of 'acme'")
+	assert.Contains(t, out, "🔁 module: 1 replacements of 'github.com/acme/svc'")
+	assert.Contains(t, out, "Value 'go' of 'lang' is short")
+	assert.Contains(t, out, "✅ Created template: "+templateDir)
+
+	content, err := os.ReadFile(filepath.Join(templateDir, "{{.project_name}}.txt.tmpl"))
+	require.NoError(t, err)
+	assert.Equal(t, "{{.project_name}} and {{.lang}}", string(content))
+	assert.NoDirExists(t, filepath.Join(templateDir, ".git"))
+	assert.FileExists(t, filepath.Join(templateDir, "template.yaml"))
+
+	_, err = executeReverse(t, project, "--var", "project_name=acme")
+	require.ErrorContains(t, err, "the --output flag is required")
+
+	_, err = executeReverse(t, project, "-o", filepath.Join(t.TempDir(), "x"), "--var", "acme")
+	require.ErrorContains(t, err, "invalid --var value 'acme'")
+}
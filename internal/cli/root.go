@@ -1,7 +1,27 @@
 package cli
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/0m3kk/mold/internal/core"
+
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+//nolint:gochecknoglobals // this is cmd flag
+var (
+	// templatesDirs are the directories holding the named templates, in the
+	// order names are looked up in.
+	templatesDirs []string
+	// workDir is the directory mold runs in, like after cd, rather than the
+	// current one.
+	workDir string
 )
 
 // rootCmd represents the base command when called without any subcommands.
@@ -13,8 +33,19 @@ var rootCmd = &cobra.Command{
 	Long: `Mold is a powerful and simple command-line tool that helps you
 generate project structures, files, and configurations from predefined templates.
 
-Use 'mold init' to create a templates directory, 'mold list' to see
-available templates, and 'mold create' to generate a new project.`,
+Use 'mold init' to create a templates directory and 'mold apply' to generate
+a new project from a template path or a template name in that directory.
+
+Template names are looked up in the search paths, in order, the first one
+holding a name winning: the --templates-dir flags, or the paths of
+$MOLD_TEMPLATE_PATHS separated like $PATH, or $MOLD_TEMPLATES_DIR, or else the
+nearest templates directory up to the git root followed by the templatePaths of
+the config file, or ~/.mold/templates without any. The commands creating or
+changing templates use the first path.
+
+With -C <dir>, mold runs as if started in dir: relative paths given as
+arguments or flags, and in the MOLD_* environment variables, are relative to it.`,
+	PersistentPreRunE: resolveWorkDir,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -23,10 +54,274 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
+// ExitNotGenerated is the exit code of commands run on a directory that mold
+// didn't generate.
+const ExitNotGenerated = 2
+
+// ExitVerifyError is the exit code of verify when the project can't be
+// checked, such as without a manifest. A project that changed exits with 1.
+const ExitVerifyError = 2
+
+// ExitError is an error that exits mold with a specific code.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code for an error returned by Execute: the code
+// of an ExitError, 1 for any other error and 0 for none.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return 1
+}
+
+// resolveWorkDir checks the --chdir directory and resolves the relative
+// values of the path flags of cmd against it. The process never changes
+// directory, so the library keeps working on the paths it is given.
+func resolveWorkDir(cmd *cobra.Command, _ []string) error {
+	if workDir == "" {
+		return nil
+	}
+	info, err := os.Stat(workDir)
+	if err != nil {
+		return fmt.Errorf("cannot change to directory '%s': %w", workDir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("cannot change to directory '%s': it is not a directory", workDir)
+	}
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if _, ok := f.Annotations[cobra.BashCompFilenameExt]; !ok || err != nil {
+			return
+		}
+		// Setting a repeatable flag appends, its values are replaced instead.
+		if values, ok := f.Value.(pflag.SliceValue); ok {
+			paths := values.GetSlice()
+			for i, path := range paths {
+				paths[i] = workPath(path)
+			}
+			err = values.Replace(paths)
+			return
+		}
+		err = f.Value.Set(workPath(f.Value.String()))
+	})
+	for i, override := range setValues {
+		if key, path, ok := strings.Cut(override.Expr, "="); ok && override.Kind == core.OverrideFile {
+			setValues[i].Expr = key + "=" + workPath(path)
+		}
+	}
+	return err
+}
+
+// workPath returns path as seen from the --chdir directory, joined to it
+// when relative. Empty paths, '-' for stdin or stdout and URLs are kept.
+func workPath(path string) string {
+	if workDir == "" || path == "" || path == stdinPath || filepath.IsAbs(path) || strings.Contains(path, "://") {
+		return path
+	}
+	return filepath.Join(workDir, path)
+}
+
+// markPathFlags marks the flags holding paths, which --chdir resolves and
+// shells complete as file names.
+func markPathFlags(flags *pflag.FlagSet, names ...string) {
+	for _, name := range names {
+		if err := cobra.MarkFlagFilename(flags, name); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// resolveTemplatePaths returns the --templates-dir flags, falling back to
+// the search paths found from the working directory with the templatePaths
+// of the config file. The flags are taken as they are, without searching.
+func resolveTemplatePaths() ([]string, error) {
+	if len(templatesDirs) > 0 {
+		return templatesDirs, nil
+	}
+	path, config, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	configured, err := config.SearchPaths(path)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := core.FindTemplatePaths(workPath("."), configured)
+	if err != nil {
+		return nil, err
+	}
+	for i, dir := range paths {
+		paths[i] = workPath(dir)
+	}
+	return paths, nil
+}
+
+// resolveTemplatesDir returns the first search path, the templates directory
+// the commands creating or changing templates work in.
+func resolveTemplatesDir() (string, error) {
+	paths, err := resolveTemplatePaths()
+	if err != nil {
+		return "", err
+	}
+	return paths[0], nil
+}
+
+// defaultCacheDir returns the default cache directory, seen from the
+// --chdir directory.
+func defaultCacheDir() (string, error) {
+	dir, err := core.DefaultCacheDir()
+	return workPath(dir), err
+}
+
+// resolveTemplateArg returns the template path given on the command line. A
+// path that doesn't exist is looked up as a template name in the search
+// paths, the templates it shadows in later paths being noted to log.
+func resolveTemplateArg(arg string, log io.Writer) (string, error) {
+	path := workPath(arg)
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		return path, nil
+	}
+	paths, err := resolveTemplatePaths()
+	if err != nil {
+		return "", fmt.Errorf("template path '%s' not found", arg)
+	}
+	path, shadowed, err := core.FindTemplate(paths, arg)
+	if err != nil {
+		return "", fmt.Errorf("template path '%s' not found, nor as a template name: %w", arg, err)
+	}
+	if len(shadowed) > 0 {
+		fmt.Fprintf(log, "📚 Using template '%s' from '%s', shadowing '%s'\n", arg, path,
+			strings.Join(shadowed, "', '"))
+	}
+	return path, nil
+}
+
+// resolveApplyTemplate resolves a template argument like resolveTemplateArg,
+// then as a template published in the registries of the config file, fetched
+// into the cache directory. A version, given or from an argument of the form
+// name@version, pins the template to a version of its versions layout, its
+// registry entry or its git tags, or the highest matching a constraint such
+// as ^1.4. Registry constraints resolve as cached unless refresh is set. The
+// origin of a fetched or versioned template is returned with its path.
+func resolveApplyTemplate(arg, version string, refresh bool, log io.Writer) (string, *core.TemplateOrigin, error) {
+	name := arg
+	if version == "" {
+		// A path or name holding a literal '@' wins over a version.
+		if path, err := resolveTemplateArg(arg, log); err == nil {
+			return resolveLocalVersion(arg, path, "", log)
+		}
+		if n, v, ok := core.SplitVersion(arg); ok {
+			name, version = n, v
+		}
+	}
+	path, err := resolveTemplateArg(name, log)
+	if err == nil {
+		return resolveLocalVersion(name, path, version, log)
+	}
+	if _, nameErr := core.TemplatePath("", name); nameErr != nil {
+		return "", nil, err
+	}
+	registries, cacheDir, regErr := loadRegistries(log)
+	if regErr != nil {
+		return "", nil, errors.Join(err, regErr)
+	}
+	entry, ok := core.FindRegistryTemplate(registries, name)
+	if !ok {
+		return "", nil, err
+	}
+	if version != "" {
+		if entry, err = entry.Pin(version, core.PinOptions{CacheDir: cacheDir, Refresh: refresh}); err != nil {
+			return "", nil, err
+		}
+		printResolved(log, name, version, entry.Version)
+	}
+	fmt.Fprintf(log, "🌐 Fetching template '%s' from registry '%s': %s\n", entry.Name, entry.Registry, entry.URL)
+	if path, err = core.FetchTemplate(entry, cacheDir, fetchOptions(), log); err != nil {
+		return "", nil, err
+	}
+	return path, &core.TemplateOrigin{URL: entry.URL, Ref: entry.Ref, Version: entry.Version}, nil
+}
+
+// resolveLocalVersion resolves a version of a local template, its latest one
+// without a version.
+func resolveLocalVersion(name, path, version string, log io.Writer) (string, *core.TemplateOrigin, error) {
+	path, resolved, err := core.ResolveVersion(path, version)
+	if err != nil {
+		return "", nil, err
+	}
+	if resolved == "" {
+		return path, nil, nil
+	}
+	printResolved(log, name, version, resolved)
+	return path, &core.TemplateOrigin{Version: resolved}, nil
+}
+
+// printResolved prints the version a template resolved to, with the
+// constraint it resolved from.
+func printResolved(log io.Writer, name, version, resolved string) {
+	if version == "" || version == resolved {
+		fmt.Fprintf(log, "📌 Resolved template '%s' to version %s\n", name, resolved)
+		return
+	}
+	fmt.Fprintf(log, "📌 Resolved template '%s' %s to version %s\n", name, version, resolved)
+}
+
+// loadRegistries loads the registries of the config file, with their
+// failures reported to warn, and returns them with the cache directory.
+func loadRegistries(warn io.Writer) ([]core.Registry, string, error) {
+	_, config, err := loadConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	cacheDir, err := defaultCacheDir()
+	if err != nil {
+		return nil, "", err
+	}
+	return core.LoadRegistries(config.Registries, cacheDir, fetchOptions(), warn), cacheDir, nil
+}
+
 // init function is called by Go when the package is initialized.
 //
 //nolint:gochecknoinits // The command 'init' is acceptable.
 func init() {
+	rootCmd.PersistentFlags().StringArrayVar(&templatesDirs, "templates-dir", nil,
+		"Directory holding the named templates, repeatable to search several in order (default $"+
+			core.TemplatePathsEnv+", $"+core.TemplatesDirEnv+
+			", or the nearest templates directory up to the git root and the configured templatePaths)")
+	rootCmd.PersistentFlags().StringVarP(&workDir, "chdir", "C", "",
+		"Run as if mold was started in this directory, resolving relative paths against it")
+	markPathFlags(rootCmd.PersistentFlags(), "templates-dir")
+
 	// Add subcommands to the root command.
+	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(renderCmd)
+	rootCmd.AddCommand(addCmd)
+	rootCmd.AddCommand(testCmd)
+	rootCmd.AddCommand(lintCmd)
+	rootCmd.AddCommand(inspectCmd)
+	rootCmd.AddCommand(deleteCmd)
+	rootCmd.AddCommand(copyCmd)
+	rootCmd.AddCommand(renameCmd)
+	rootCmd.AddCommand(reverseCmd)
+	rootCmd.AddCommand(infoCmd)
+	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(docsCmd)
+	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(registryCmd)
 }
@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executeIn runs mold with args, sub being the subcommand they run, and
+// returns its stdout and stderr.
+func executeIn(t *testing.T, sub *cobra.Command, args ...string) (string, error) {
+	t.Helper()
+	stdout, stderr, err := execute(t, sub, "", args...)
+	return stdout + stderr, err
+}
+
+// writeFile writes content to the slash-separated path under dir, creating
+// its parent directories.
+func writeFile(t *testing.T, dir, path, content string) {
+	t.Helper()
+	path = filepath.Join(dir, filepath.FromSlash(path))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestChdir(t *testing.T) {
+	repo := t.TempDir()
+	writeFile(t, repo, "templates/svc/README.md.tmpl", "# {{.name}}\n{{.license}}\n")
+	writeFile(t, repo, "templates/svc/_partials/header.tmpl", "// {{.name}}")
+	writeFile(t, repo, "named/web/README.md.tmpl", "<h1>{{.name}}</h1>\n")
+	writeFile(t, repo, "data.yaml", "name: demo\n")
+	writeFile(t, repo, "LICENSE", "MIT")
+	writeFile(t, repo, "main.go.tmpl", "{{template \"header.tmpl\" .}}\npackage {{.name}}\n")
+
+	t.Run("apply", func(t *testing.T) {
+		_, err := executeIn(t, applyCmd, "-C", repo, "apply", "templates/svc", "-d", "data.yaml",
+			"--set-file", "license=LICENSE", "-o", "out", "--report", "report.json", "--profile-out", "profile.json")
+		require.NoError(t, err)
+		content, err := os.ReadFile(filepath.Join(repo, "out", "README.md"))
+		require.NoError(t, err)
+		assert.Equal(t, "# demo\nMIT\n", string(content))
+		assert.FileExists(t, filepath.Join(repo, "report.json"))
+		assert.FileExists(t, filepath.Join(repo, "profile.json"))
+
+		// A template named in the templates directory replaces the file,
+		// backed up into the backup directory.
+		writeFile(t, repo, "out/README.md", "edited\n")
+		_, err = executeIn(t, applyCmd, "-C", repo, "--templates-dir", "named", "apply", "web", "-d", "data.yaml",
+			"-o", "out", "--backup-dir", "saved", "--no-provenance")
+		require.NoError(t, err)
+		content, err = os.ReadFile(filepath.Join(repo, "out", "README.md"))
+		require.NoError(t, err)
+		assert.Equal(t, "<h1>demo</h1>\n", string(content))
+		content, err = os.ReadFile(filepath.Join(repo, "saved", "README.md"))
+		require.NoError(t, err)
+		assert.Equal(t, "edited\n", string(content))
+	})
+
+	t.Run("render", func(t *testing.T) {
+		_, err := executeIn(t, renderCmd, "-C", repo, "render", "main.go.tmpl", "-d", "data.yaml",
+			"--template-root", "templates/svc", "-o", "main.go")
+		require.NoError(t, err)
+		content, err := os.ReadFile(filepath.Join(repo, "main.go"))
+		require.NoError(t, err)
+		assert.Equal(t, "// demo\npackage demo\n", string(content))
+	})
+
+	t.Run("reverse", func(t *testing.T) {
+		_, err := executeIn(t, reverseCmd, "-C", repo, "reverse", "out", "-o", "reversed", "--var", "name=demo")
+		require.NoError(t, err)
+		assert.FileExists(t, filepath.Join(repo, "reversed", "README.md.tmpl"))
+	})
+
+	t.Run("inspect", func(t *testing.T) {
+		_, err := executeIn(t, inspectCmd, "-C", repo, "inspect", "templates/svc", "-d", "data.yaml",
+			"--set", "license=MIT", "--fail-on-missing")
+		require.NoError(t, err)
+	})
+
+	t.Run("add", func(t *testing.T) {
+		writeFile(t, repo, "component/NOTICE.tmpl", "{{.name}}\n")
+		_, err := executeIn(t, addCmd, "-C", filepath.Join(repo, "out"), "add", "../component", "-d", "../data.yaml")
+		require.NoError(t, err)
+		assert.FileExists(t, filepath.Join(repo, "out", "NOTICE"))
+	})
+
+	t.Run("docs", func(t *testing.T) {
+		_, err := executeIn(t, docsCmd, "-C", repo, "docs", "-o", "pages")
+		require.NoError(t, err)
+		assert.DirExists(t, filepath.Join(repo, "pages"))
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := executeIn(t, verifyCmd, "-C", filepath.Join(repo, "nope"), "verify")
+		require.ErrorContains(t, err, "cannot change to directory")
+		_, err = executeIn(t, verifyCmd, "-C", filepath.Join(repo, "LICENSE"), "verify")
+		require.ErrorContains(t, err, "it is not a directory")
+	})
+}
+
+func TestTemplatesDirDiscovery(t *testing.T) {
+	repo := t.TempDir()
+	writeFile(t, repo, ".git/HEAD", "ref: refs/heads/main\n")
+	writeFile(t, repo, "templates/svc/template.yaml", "description: A service\n")
+	writeFile(t, repo, "services/api/cmd/main.go", "package main\n")
+	t.Setenv(core.TemplatePathsEnv, "")
+	t.Setenv(core.TemplatesDirEnv, "")
+	t.Setenv("HOME", t.TempDir())
+
+	for _, dir := range []string{".", "services", "services/api/cmd"} {
+		out, err := executeIn(t, listCmd, "-C", filepath.Join(repo, dir), "list")
+		require.NoError(t, err)
+		assert.Contains(t, out, "svc  [project]  A service", "from %s", dir)
+	}
+
+	// An explicit templates directory is used without searching.
+	out, err := executeIn(t, listCmd, "-C", filepath.Join(repo, "services"), "--templates-dir", "api", "list")
+	require.NoError(t, err)
+	assert.Contains(t, out, "cmd  [user]")
+	assert.NotContains(t, out, "svc")
+}
+
+func TestTemplateSearchPaths(t *testing.T) {
+	repo, home := t.TempDir(), t.TempDir()
+	writeFile(t, repo, ".git/HEAD", "ref: refs/heads/main\n")
+	writeFile(t, repo, "templates/svc/template.yaml", "description: A team service\n")
+	writeFile(t, home, "personal/svc/template.yaml", "description: A personal service\n")
+	writeFile(t, home, "personal/cli/template.yaml", "description: A personal CLI\n")
+	writeFile(t, home, ".mold/config.yaml", "templatePaths:\n  - ~/personal\n  - ../missing\n")
+	t.Setenv(core.TemplatePathsEnv, "")
+	t.Setenv(core.TemplatesDirEnv, "")
+	t.Setenv(core.ConfigEnv, "")
+	t.Setenv("HOME", home)
+	project, personal := filepath.Join(repo, "templates", "svc"), filepath.Join(home, "personal", "svc")
+
+	// The project templates come first, the configured paths after them.
+	out, err := executeIn(t, listCmd, "-C", repo, "list", "--all")
+	require.NoError(t, err)
+	assert.Equal(t, "svc  [project]  A team service\n"+
+		"cli  [user]     A personal CLI\n"+
+		"svc  [user]     A personal service (shadowed by "+project+")\n"+
+		"\n⚠️  Search path '"+filepath.Join(home, "missing")+"' doesn't exist\n", out)
+
+	// Names resolve to the first path holding them, noting the shadowed ones.
+	out, err = executeIn(t, inspectCmd, "-C", repo, "inspect", "svc")
+	require.NoError(t, err)
+	assert.Contains(t, out, "📚 Using template 'svc' from '"+project+"', shadowing '"+personal+"'\n")
+	out, err = executeIn(t, inspectCmd, "-C", repo, "inspect", "cli")
+	require.NoError(t, err)
+	assert.NotContains(t, out, "📚")
+
+	// The flags replace the search, in the order given, relative to -C.
+	out, err = executeIn(t, listCmd, "-C", home,
+		"--templates-dir", "personal", "--templates-dir", filepath.Join(repo, "templates"), "list", "--all", "-o", "json")
+	require.NoError(t, err)
+	var report listReport
+	require.NoError(t, json.Unmarshal([]byte(out), &report))
+	assert.Equal(t, []listEntry{
+		{Name: "cli", Origin: originUser, Description: "A personal CLI", Path: filepath.Join(home, "personal", "cli")},
+		{Name: "svc", Origin: originUser, Description: "A personal service", Path: personal},
+		{Name: "svc", Origin: originUser, Description: "A team service", Path: project, ShadowedBy: personal},
+	}, report.Templates)
+}
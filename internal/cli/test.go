@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+//nolint:gochecknoglobals // this is cmd flag
+var (
+	testRun    string
+	testUpdate bool
+	testYes    bool
+)
+
+// testCmd represents the test command.
+//
+//nolint:gochecknoglobals // this is command definition
+var testCmd = &cobra.Command{
+	Use:   "test <template_path>",
+	Short: "Runs the golden tests of a template",
+	Long: `Runs the golden test cases found in the 'tests' directory of a template.
+
+Each case is a directory holding a 'data.yaml' file and an 'expected' directory.
+The template is applied with the case's data into a temporary directory and the
+result is compared with 'expected'. Mismatching files are reported with unified
+diffs. The command fails when any case fails.
+
+With --update, the 'expected' directory of each selected case is replaced with
+the freshly generated output instead. Nothing is updated when any case fails
+to render.`,
+	Args: cobra.ExactArgs(1), // Requires exactly one argument: the path to the template.
+	RunE: func(cmd *cobra.Command, args []string) error {
+		templatePath := workPath(args[0])
+		if _, err := os.Stat(templatePath); os.IsNotExist(err) {
+			return fmt.Errorf("template path '%s' not found", templatePath)
+		}
+
+		cases, err := core.FindTestCases(templatePath, testRun)
+		if err != nil {
+			return err
+		}
+		if len(cases) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "⚠️  No test cases to run")
+			return nil
+		}
+
+		if testUpdate {
+			return updateTestCases(cmd, templatePath, cases)
+		}
+
+		failed := 0
+		for _, tc := range cases {
+			result := core.RunTestCase(templatePath, tc)
+			if !result.Passed() {
+				failed++
+			}
+			printTestResult(cmd.OutOrStdout(), result)
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d test cases failed", failed, len(cases))
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "\n✅ All %d test cases passed\n", len(cases))
+		return nil
+	},
+}
+
+// updateTestCases regenerates the expected output of the cases after the
+// user confirms it.
+func updateTestCases(cmd *cobra.Command, templatePath string, cases []core.TestCase) error {
+	out := cmd.OutOrStdout()
+	updates := make([]*core.TestUpdate, 0, len(cases))
+	defer func() {
+		for _, update := range updates {
+			_ = update.Discard()
+		}
+	}()
+
+	// Render every case before touching any expected output.
+	for _, tc := range cases {
+		update, err := core.PrepareTestUpdate(templatePath, tc)
+		if err != nil {
+			return fmt.Errorf("refusing to update, test case '%s' failed to render: %w", tc.Name, err)
+		}
+		if update.Empty() {
+			fmt.Fprintf(out, "--- OK: %s (up to date)\n", tc.Name)
+			_ = update.Discard()
+			continue
+		}
+		updates = append(updates, update)
+		fmt.Fprintf(out, "--- UPDATE: %s (%d added, %d changed, %d removed)\n",
+			tc.Name, len(update.Added), len(update.Changed), len(update.Removed))
+		printPaths(out, "+", update.Added)
+		printPaths(out, "~", update.Changed)
+		printPaths(out, "-", update.Removed)
+	}
+	if len(updates) == 0 {
+		fmt.Fprintln(out, "\n✅ All expected output is up to date")
+		return nil
+	}
+
+	question := fmt.Sprintf("Replace the expected output of %d test cases?", len(updates))
+	if !testYes && !confirm(cmd.InOrStdin(), out, question) {
+		return errors.New("update cancelled, expected output left untouched")
+	}
+	for _, update := range updates {
+		if err := update.Commit(); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "\n✅ Updated the expected output of %d test cases\n", len(updates))
+	return nil
+}
+
+// printPaths lists paths with a marker in front of each one.
+func printPaths(w io.Writer, marker string, paths []string) {
+	for _, path := range paths {
+		fmt.Fprintf(w, "    %s %s\n", marker, path)
+	}
+}
+
+// confirm asks a yes/no question and reports whether the answer is yes. No
+// answer, such as the end of a non-interactive stdin, means no, and so does
+// a question never answered with yes or no.
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	yes, err := core.AskConfirm(in, out, question, false)
+	return err == nil && yes
+}
+
+// printTestResult reports the outcome of a test case and its mismatches.
+func printTestResult(w io.Writer, result core.TestResult) {
+	if result.Passed() {
+		fmt.Fprintf(w, "--- PASS: %s\n", result.Case.Name)
+		return
+	}
+	fmt.Fprintf(w, "--- FAIL: %s\n", result.Case.Name)
+	if result.Err != nil {
+		fmt.Fprintf(w, "    %v\n", result.Err)
+		return
+	}
+	for _, mismatch := range result.Mismatches {
+		fmt.Fprintf(w, "    %s: ", mismatch.Path)
+		if !strings.Contains(mismatch.Diff, "\n") {
+			fmt.Fprintln(w, mismatch.Diff)
+			continue
+		}
+		fmt.Fprintln(w, "content differs")
+		for _, line := range strings.SplitAfter(strings.TrimSuffix(mismatch.Diff, "\n"), "\n") {
+			fmt.Fprintf(w, "        %s", line)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+//nolint:gochecknoinits // The command 'init' is acceptable.
+func init() {
+	// Add flags to the 'test' command.
+	testCmd.Flags().StringVar(&testRun, "run", "", "Run only the test cases whose name matches the regular expression")
+	testCmd.Flags().BoolVar(&testUpdate, "update", false, "Replace the expected output with the generated output")
+	testCmd.Flags().BoolVarP(&testYes, "yes", "y", false, "Update without asking for confirmation")
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executeTest runs the test command, reading stdin.
+func executeTest(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+	out, _, err := execute(t, testCmd, stdin, append([]string{"test"}, args...)...)
+	return out, err
+}
+
+func TestTestCmd(t *testing.T) {
+	templateDir := t.TempDir()
+	files := map[string]string{
+		"main.go.tmpl":                "package {{.pkg}}\n",
+		"tests/main/data.yaml":        "pkg: main",
+		"tests/main/expected/main.go": "package main\n",
+		"tests/lib/data.yaml":         "pkg: lib",
+		"tests/lib/expected/main.go":  "package main\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(templateDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	t.Run("failing case", func(t *testing.T) {
+		out, err := executeTest(t, "", templateDir)
+		require.ErrorContains(t, err, "1 of 2 test cases failed")
+		assert.Contains(t, out, "--- FAIL: lib")
+		assert.Contains(t, out, "-package main")
+		assert.Contains(t, out, "+package lib")
+		assert.Contains(t, out, "--- PASS: main")
+	})
+
+	t.Run("run filter", func(t *testing.T) {
+		out, err := executeTest(t, "", templateDir, "--run", "^main$")
+		require.NoError(t, err)
+		assert.Contains(t, out, "--- PASS: main")
+		assert.NotContains(t, out, "lib")
+		assert.Contains(t, out, "All 1 test cases passed")
+	})
+
+	t.Run("no matching case", func(t *testing.T) {
+		out, err := executeTest(t, "", templateDir, "--run", "nothing")
+		require.NoError(t, err)
+		assert.Contains(t, out, "No test cases to run")
+	})
+}
+
+func TestTestCmdUpdate(t *testing.T) {
+	templateDir := t.TempDir()
+	files := map[string]string{
+		"main.go.tmpl":                "package {{.pkg}}\n",
+		"tests/lib/data.yaml":         "pkg: lib",
+		"tests/lib/expected/main.go":  "package main\n",
+		"tests/lib/expected/old.go":   "package old\n",
+		"tests/main/data.yaml":        "pkg: main",
+		"tests/main/expected/main.go": "package stale\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(templateDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	readExpected := func(name string) string {
+		content, _ := os.ReadFile(filepath.Join(templateDir, "tests", name, "expected", "main.go"))
+		return string(content)
+	}
+
+	t.Run("declined", func(t *testing.T) {
+		out, err := executeTest(t, "n\n", templateDir, "--update")
+		require.ErrorContains(t, err, "update cancelled")
+		assert.Contains(t, out, "--- UPDATE: lib (0 added, 1 changed, 1 removed)")
+		assert.Contains(t, out, "    - old.go")
+		assert.Equal(t, "package main\n", readExpected("lib"))
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		out, err := executeTest(t, "", templateDir, "--update", "--yes", "--run", "lib")
+		require.NoError(t, err)
+		assert.Contains(t, out, "Updated the expected output of 1 test cases")
+		assert.Equal(t, "package lib\n", readExpected("lib"))
+		assert.Equal(t, "package stale\n", readExpected("main"))
+		_, err = os.Stat(filepath.Join(templateDir, "tests", "lib", "expected", "old.go"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("confirmed", func(t *testing.T) {
+		out, err := executeTest(t, "yes\n", templateDir, "--update")
+		require.NoError(t, err)
+		assert.Contains(t, out, "--- OK: lib (up to date)")
+		assert.Equal(t, "package main\n", readExpected("main"))
+
+		out, err = executeTest(t, "", templateDir)
+		require.NoError(t, err)
+		assert.Contains(t, out, "All 2 test cases passed")
+	})
+
+	t.Run("render error", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(templateDir, "tests", "main", "data.yaml"), []byte("pkg: ["), 0644))
+		_, err := executeTest(t, "", templateDir, "--update", "--yes")
+		require.ErrorContains(t, err, "refusing to update, test case 'main' failed to render")
+		assert.Equal(t, "package main\n", readExpected("main"))
+	})
+}
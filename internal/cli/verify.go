@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+//nolint:gochecknoglobals // this is cmd flag
+var (
+	verifyOutput    string
+	verifyUntracked bool
+)
+
+// verifyCmd represents the verify command.
+//
+//nolint:gochecknoglobals // this is command definition
+var verifyCmd = &cobra.Command{
+	Use:   "verify [dir]",
+	Short: "Checks that a generated project matches its manifest",
+	Long: `Reads the manifest of the .mold.yaml file at the root of a generated project, in
+the current directory by default, and re-hashes every tracked file with the same
+check apply uses to find the files changed since it generated them. Files whose
+content was modified, that are missing, or whose permissions changed are listed.
+With --untracked, the files added to the directories mold created are listed
+too. The output root itself is left out, it usually holds files of its own.
+
+It exits with 0 when the project matches its manifest, 1 when it doesn't, and 2
+when it can't be checked, such as for a directory without a .mold.yaml file.
+With --output json, the state of every tracked file and the untracked files are
+printed as JSON.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		dir = workPath(dir)
+		v, err := verifyProject(dir)
+		if err != nil {
+			return &ExitError{Code: ExitVerifyError, Err: err}
+		}
+
+		out := cmd.OutOrStdout()
+		if verifyOutput == "json" {
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			if err = encoder.Encode(v); err != nil {
+				return &ExitError{Code: ExitVerifyError, Err: err}
+			}
+		} else {
+			printVerification(out, v)
+		}
+		if !v.Clean() {
+			// The project changed, the command was used right.
+			cmd.SilenceUsage = true
+			return errors.New("the project doesn't match its manifest")
+		}
+		return nil
+	},
+}
+
+// verifyProject checks the project at dir against its manifest.
+func verifyProject(dir string) (*core.Verification, error) {
+	if verifyOutput != "text" && verifyOutput != "json" {
+		return nil, fmt.Errorf("invalid --output value '%s': expected text or json", verifyOutput)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("project directory '%s' not found", dir)
+	}
+	p, err := core.LoadProvenance(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("'%s' is not a mold-generated project: it has no %s file", dir, core.ProvenanceFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return core.Verify(dir, p, verifyUntracked)
+}
+
+// printVerification lists the files that don't match the manifest.
+func printVerification(out io.Writer, v *core.Verification) {
+	changed := 0
+	for _, check := range v.Files {
+		switch check.State {
+		case core.FileModified:
+			fmt.Fprintf(out, "✏️  modified: %s\n", check.Path)
+		case core.FileMissing:
+			fmt.Fprintf(out, "❌ missing: %s\n", check.Path)
+		case core.FileUnchanged:
+		}
+		if check.Mode != "" {
+			fmt.Fprintf(out, "🔐 mode changed: %s (now %s)\n", check.Path, check.Mode)
+		}
+		if check.State != core.FileUnchanged || check.Mode != "" {
+			changed++
+		}
+	}
+	for _, path := range v.Untracked {
+		fmt.Fprintf(out, "❓ untracked: %s\n", path)
+	}
+	if v.Clean() {
+		fmt.Fprintf(out, "✅ All %d tracked files match the manifest\n", len(v.Files))
+		return
+	}
+	fmt.Fprintf(out, "📄 Files: %d tracked, %d changed, %d untracked\n", len(v.Files), changed, len(v.Untracked))
+}
+
+//nolint:gochecknoinits // The command 'init' is acceptable.
+func init() {
+	verifyCmd.Flags().StringVarP(&verifyOutput, "output", "o", "text", "Output format: text or json")
+	verifyCmd.Flags().BoolVar(&verifyUntracked, "untracked", false,
+		"Also list the files added to the directories mold created")
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0m3kk/mold/internal/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executeVerify runs the verify command.
+func executeVerify(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	out, _, err := execute(t, verifyCmd, "", append([]string{"verify"}, args...)...)
+	return out, err
+}
+
+func TestVerifyCmd(t *testing.T) {
+	templateDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(templateDir, "cmd"), 0755))
+	files := map[string]string{
+		"README.md.tmpl": "# {{.name}}",
+		"cmd/main.go":    "package main\n",
+		"run.sh":         "#!/bin/sh\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(templateDir, name), []byte(content), 0644))
+	}
+	projectDir := filepath.Join(t.TempDir(), "project")
+	err := core.Apply(core.Options{
+		TemplatePath: templateDir,
+		OutputDir:    projectDir,
+		Data:         map[string]any{"name": "demo"},
+		Out:          &bytes.Buffer{},
+	})
+	require.NoError(t, err)
+
+	t.Run("clean", func(t *testing.T) {
+		out, err := executeVerify(t, projectDir, "--untracked")
+		require.NoError(t, err)
+		assert.Equal(t, "✅ All 3 tracked files match the manifest\n", out)
+	})
+
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "cmd", "main.go"), []byte("package edited\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "cmd", "extra.go"), []byte("package main\n"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(projectDir, "README.md")))
+	require.NoError(t, os.Chmod(filepath.Join(projectDir, "run.sh"), 0755))
+
+	t.Run("dirty", func(t *testing.T) {
+		out, err := executeVerify(t, projectDir)
+		require.ErrorContains(t, err, "the project doesn't match its manifest")
+		assert.Equal(t, 1, ExitCode(err))
+		assert.Equal(t, "❌ missing: README.md\n✏️  modified: cmd/main.go\n🔐 mode changed: run.sh (now 0755)\n"+
+			"📄 Files: 3 tracked, 3 changed, 0 untracked\n", out)
+
+		out, err = executeVerify(t, projectDir, "--untracked")
+		require.Error(t, err)
+		assert.Contains(t, out, "❓ untracked: cmd/extra.go\n")
+		assert.Contains(t, out, "📄 Files: 3 tracked, 3 changed, 1 untracked\n")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		out, err := executeVerify(t, projectDir, "--output", "json", "--untracked")
+		assert.Equal(t, 1, ExitCode(err))
+		var v core.Verification
+		require.NoError(t, json.Unmarshal([]byte(out), &v))
+		assert.Equal(t, []core.FileCheck{
+			{Path: "README.md", State: core.FileMissing},
+			{Path: "cmd/main.go", State: core.FileModified},
+			{Path: "run.sh", State: core.FileUnchanged, Mode: "0755"},
+		}, v.Files)
+		assert.Equal(t, []string{"cmd/extra.go"}, v.Untracked)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := executeVerify(t, templateDir)
+		require.ErrorContains(t, err, "is not a mold-generated project")
+		assert.Equal(t, ExitVerifyError, ExitCode(err))
+
+		_, err = executeVerify(t, filepath.Join(projectDir, "missing"))
+		require.ErrorContains(t, err, "not found")
+		assert.Equal(t, ExitVerifyError, ExitCode(err))
+
+		_, err = executeVerify(t, projectDir, "--output", "xml")
+		require.ErrorContains(t, err, "invalid --output value 'xml'")
+		assert.Equal(t, ExitVerifyError, ExitCode(err))
+	})
+}
@@ -0,0 +1,296 @@
+package core
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/0m3kk/mold/internal/utils"
+)
+
+// Add applies a component template into the existing project at
+// opts.OutputDir, generated by mold. The component is rendered with the data
+// recorded in the project's provenance, opts.Data on top, into a staging
+// directory first; its files are then copied into the project, which records
+// them in its provenance like the files it generated, with the component as
+// a layer.
+//
+// A project file the component generates differently is only replaced when
+// the project tracks it and the user hasn't changed it since. A file the user
+// changed or created is a conflict, and no file is written when there is
+// any, so adding a component twice is a no-op and local edits are never
+// overwritten.
+//
+// The values of secret prompts are masked in the provenance, so they aren't
+// reused: a component using one gets it from opts.Data, or misses it.
+func Add(opts Options) error {
+	out := opts.Out
+	if out == nil {
+		out = os.Stdout
+	}
+
+	project, err := LoadProvenance(opts.OutputDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("no mold project in '%s': components are added to projects generated by mold: %w",
+			opts.OutputDir, err)
+	}
+	if err != nil {
+		return err
+	}
+	_, meta, err := ResolveChain(opts.TemplatePath)
+	if err != nil {
+		return err
+	}
+	if err = meta.Requires.checkProject(project); err != nil {
+		return fmt.Errorf("cannot add component '%s': %w", opts.TemplatePath, err)
+	}
+
+	staging, err := os.MkdirTemp("", "mold-add-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	staged := opts
+	staged.OutputDir = staging
+	staged.Sink = nil
+	staged.NoProvenance = false
+	staged.Data = make(map[string]any)
+	MergeData(staged.Data, unmasked(project.Data))
+	MergeData(staged.Data, opts.Data)
+	if err = Apply(staged); err != nil {
+		return err
+	}
+	component, err := LoadProvenance(staging)
+	if err != nil {
+		return err
+	}
+
+	changes, err := compareStaged(staging, opts.OutputDir, project.Files)
+	if err != nil {
+		return err
+	}
+	if len(changes.conflicts) > 0 {
+		return fmt.Errorf("refusing to overwrite files changed since the project was generated: %s",
+			strings.Join(changes.conflicts, ", "))
+	}
+
+	for _, relPath := range changes.added {
+		fmt.Fprintf(out, "➕ Adding: %s\n", relPath)
+	}
+	for _, relPath := range changes.updated {
+		fmt.Fprintf(out, "🔄 Updating: %s\n", relPath)
+	}
+	for _, relPath := range slices.Concat(changes.added, changes.updated) {
+		if err = copyStaged(staging, opts.OutputDir, relPath); err != nil {
+			return err
+		}
+	}
+	if len(changes.added)+len(changes.updated) == 0 {
+		fmt.Fprintln(out, "👌 Nothing to add, the project already contains the component")
+	}
+	return recordComponent(opts.OutputDir, project, component)
+}
+
+// stagedChanges sorts the files of a staged component by what adding it
+// does to the project, each list holding slash-separated paths.
+type stagedChanges struct {
+	// added are missing from the project, updated are tracked files the user
+	// didn't change and the component generates differently, and conflicts
+	// are files the user changed or created that differ from the component.
+	added, updated, conflicts []string
+}
+
+// compareStaged compares the staged files with the project's, using the
+// files the project tracks to tell which ones the user changed.
+func compareStaged(staging, projectDir string, tracked []ManifestFile) (stagedChanges, error) {
+	var changes stagedChanges
+	err := filepath.WalkDir(staging, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil || d.IsDir() {
+			return walkErr
+		}
+		relPath, err := filepath.Rel(staging, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(relPath)
+		if key == ProvenanceFile || isStatePath(key) {
+			return nil
+		}
+
+		existing, err := os.ReadFile(filepath.Join(projectDir, relPath))
+		if errors.Is(err, fs.ErrNotExist) {
+			changes.added = append(changes.added, key)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read project file '%s': %w", relPath, err)
+		}
+		generated, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(existing, generated) {
+			return nil
+		}
+		i := slices.IndexFunc(tracked, func(f ManifestFile) bool { return f.Path == key })
+		if i >= 0 && tracked[i].SHA256 == hashContent(existing) {
+			changes.updated = append(changes.updated, key)
+		} else {
+			changes.conflicts = append(changes.conflicts, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return stagedChanges{}, fmt.Errorf("failed to compare component with project: %w", err)
+	}
+	return changes, nil
+}
+
+// copyStaged copies a staged file into the project, with its base copy for
+// the three-way merges of later runs when it has one.
+func copyStaged(staging, projectDir, key string) error {
+	relPath := filepath.FromSlash(key)
+	if err := utils.CopyFile(filepath.Join(staging, relPath), filepath.Join(projectDir, relPath),
+		utils.WithParentDirs(utils.DirMode)); err != nil {
+		return err
+	}
+	base := filepath.Join(filepath.FromSlash(BaseDir), relPath)
+	if _, err := os.Stat(filepath.Join(staging, base)); err != nil {
+		return nil
+	}
+	return utils.CopyFile(filepath.Join(staging, base), filepath.Join(projectDir, base),
+		utils.WithParentDirs(utils.DirMode))
+}
+
+// recordComponent records the component in the provenance of the project:
+// the component becomes a layer, its data and files are merged into the
+// project's, and the files it generates take their recorded hash from it.
+func recordComponent(projectDir string, project, component *Provenance) error {
+	if !slices.ContainsFunc(project.Layers, func(l ProvenanceTemplate) bool { return l.Path == component.Template.Path }) {
+		project.Layers = append(project.Layers, component.Template)
+	}
+	// The secrets of the project stay masked, given again or not.
+	secrets := maskedKeys(project.Data, "")
+	project.Data = component.Data
+	for _, key := range secrets {
+		if err := SetValue(project.Data, key, SecretMask); err != nil {
+			return err
+		}
+	}
+
+	files := make(map[string]ManifestFile, len(project.Files)+len(component.Files))
+	for _, file := range slices.Concat(project.Files, component.Files) {
+		files[file.Path] = file
+	}
+	project.Files = slices.SortedFunc(maps.Values(files), func(a, b ManifestFile) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	project.Dirs = slices.Compact(slices.Sorted(slices.Values(slices.Concat(project.Dirs, component.Dirs))))
+
+	content, err := project.Marshal()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(projectDir, ProvenanceFile)
+	//nolint:gosec // generated files are meant to be shared
+	if err = os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write '%s': %w", path, err)
+	}
+	return nil
+}
+
+// unmasked returns a copy of provenance data without the values masked as
+// secrets.
+func unmasked(data map[string]any) map[string]any {
+	copied := make(map[string]any, len(data))
+	for key, value := range data {
+		switch v := value.(type) {
+		case map[string]any:
+			copied[key] = unmasked(v)
+		case string:
+			if v != SecretMask {
+				copied[key] = v
+			}
+		default:
+			copied[key] = value
+		}
+	}
+	return copied
+}
+
+// maskedKeys returns the dotted keys of the values masked as secrets in
+// provenance data, below prefix.
+func maskedKeys(data map[string]any, prefix string) []string {
+	var keys []string
+	for key, value := range data {
+		switch v := value.(type) {
+		case map[string]any:
+			keys = append(keys, maskedKeys(v, prefix+key+".")...)
+		case string:
+			if v == SecretMask {
+				keys = append(keys, prefix+key)
+			}
+		}
+	}
+	return keys
+}
+
+// check checks the version constraint of the requirement.
+func (r Requirement) check() error {
+	if r == (Requirement{}) {
+		return nil
+	}
+	if r.Template == "" {
+		return errors.New("the template name is missing")
+	}
+	if r.Version != "" {
+		if _, err := ParseConstraint(r.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkProject checks that the project was generated from the required
+// template, by its name in the provenance, in a version the requirement
+// accepts: the version it was resolved to, or the one of its template.yaml.
+// The templates the applied ones extend count too.
+func (r Requirement) checkProject(project *Provenance) error {
+	if r.Template == "" {
+		return nil
+	}
+	var templates []ProvenanceTemplate
+	for _, applied := range slices.Concat([]ProvenanceTemplate{project.Template}, project.Layers) {
+		templates = append(append(templates, applied), applied.Extends...)
+	}
+	i := slices.IndexFunc(templates, func(t ProvenanceTemplate) bool { return t.Name == r.Template })
+	if i < 0 {
+		return fmt.Errorf("it requires template '%s', which the project wasn't generated from", r.Template)
+	}
+	if r.Version == "" {
+		return nil
+	}
+	constraint, err := ParseConstraint(r.Version)
+	if err != nil {
+		return err
+	}
+	recorded := cmp.Or(templates[i].Resolved, templates[i].Version)
+	if recorded == "" {
+		return fmt.Errorf("it requires template '%s' %s, but the project records no version of it",
+			r.Template, r.Version)
+	}
+	v, err := ParseSemver(recorded)
+	if err != nil || !constraint.Check(v) {
+		return fmt.Errorf("it requires template '%s' %s, but the project was generated from version %s",
+			r.Template, r.Version, recorded)
+	}
+	return nil
+}
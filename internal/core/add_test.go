@@ -0,0 +1,287 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// generateProject applies a go/service 1.2.0 template with data into a new
+// project directory.
+func generateProject(t *testing.T, data map[string]any) string {
+	t.Helper()
+	base := writeTemplate(t, map[string]string{
+		MetadataFile: "name: go/service\nversion: 1.2.0\n",
+		"main.go":    "package main",
+	})
+	projectDir := t.TempDir()
+	if err := Apply(Options{TemplatePath: base, OutputDir: projectDir, Data: data, Out: &bytes.Buffer{}}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	return projectDir
+}
+
+func TestAdd(t *testing.T) {
+	component := writeTemplate(t, map[string]string{
+		"docker-compose.yaml.tmpl": "image: postgres:{{.version}}",
+		"db/schema.sql":            "create table t();",
+	})
+
+	t.Run("add twice is idempotent", func(t *testing.T) {
+		projectDir := generateProject(t, map[string]any{"version": "16"})
+
+		var provenance []byte
+		for i := range 2 {
+			var out bytes.Buffer
+			if err := Add(Options{TemplatePath: component, OutputDir: projectDir, Out: &out}); err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+			content, err := os.ReadFile(filepath.Join(projectDir, ProvenanceFile))
+			if err != nil {
+				t.Fatalf("Failed to read provenance: %v", err)
+			}
+			if i == 1 {
+				if !contains(out.String(), "Nothing to add") {
+					t.Errorf("Expected nothing to add the second time, got %q", out.String())
+				}
+				if !bytes.Equal(content, provenance) {
+					t.Errorf("Expected the provenance to be unchanged:\n%s\nthen:\n%s", provenance, content)
+				}
+			}
+			provenance = content
+		}
+
+		content, err := os.ReadFile(filepath.Join(projectDir, "docker-compose.yaml"))
+		if err != nil || string(content) != "image: postgres:16" {
+			t.Errorf("Expected the recorded data to be used, got %q: %v", content, err)
+		}
+		content, err = os.ReadFile(filepath.Join(projectDir, "db", "schema.sql"))
+		if err != nil || string(content) != "create table t();" {
+			t.Errorf("Unexpected copied file %q: %v", content, err)
+		}
+		content, err = os.ReadFile(filepath.Join(projectDir, "main.go"))
+		if err != nil || string(content) != "package main" {
+			t.Errorf("Expected untouched project file, got %q: %v", content, err)
+		}
+	})
+
+	t.Run("added files are recorded in the provenance", func(t *testing.T) {
+		projectDir := generateProject(t, map[string]any{"name": "app"})
+
+		err := Add(Options{
+			TemplatePath: component, OutputDir: projectDir, Data: map[string]any{"version": "16"}, Out: &bytes.Buffer{},
+		})
+		if err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		p, err := LoadProvenance(projectDir)
+		if err != nil {
+			t.Fatalf("LoadProvenance failed: %v", err)
+		}
+		if p.Template.Name != "go/service" || len(p.Layers) != 1 || p.Layers[0].Path != component {
+			t.Errorf("Expected the component as a layer of go/service, got %+v and %+v", p.Template, p.Layers)
+		}
+		if p.Data["name"] != "app" || p.Data["version"] != "16" {
+			t.Errorf("Expected the data of the component merged into the project's, got %v", p.Data)
+		}
+		var paths []string
+		for _, file := range p.Files {
+			paths = append(paths, file.Path)
+		}
+		if want := []string{"db/schema.sql", "docker-compose.yaml", "main.go"}; !slices.Equal(paths, want) {
+			t.Errorf("Expected the tracked files %v, got %v", want, paths)
+		}
+		if !slices.Contains(p.Dirs, "db") {
+			t.Errorf("Expected the db directory to be recorded, got %v", p.Dirs)
+		}
+
+		checks, err := CheckFiles(projectDir, p.Files)
+		if err != nil {
+			t.Fatalf("CheckFiles failed: %v", err)
+		}
+		for _, check := range checks {
+			if check.State != FileUnchanged {
+				t.Errorf("Expected %s to be unchanged, got %s", check.Path, check.State)
+			}
+		}
+	})
+
+	t.Run("unchanged tracked files are updated", func(t *testing.T) {
+		projectDir := generateProject(t, map[string]any{"version": "16"})
+		if err := Add(Options{TemplatePath: component, OutputDir: projectDir, Out: &bytes.Buffer{}}); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+
+		var out bytes.Buffer
+		err := Add(Options{
+			TemplatePath: component, OutputDir: projectDir, Data: map[string]any{"version": "17"}, Out: &out,
+		})
+		if err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		if !contains(out.String(), "🔄 Updating: docker-compose.yaml") {
+			t.Errorf("Expected the tracked file to be updated, got %q", out.String())
+		}
+		content, _ := os.ReadFile(filepath.Join(projectDir, "docker-compose.yaml"))
+		if string(content) != "image: postgres:17" {
+			t.Errorf("Expected the new content, got %q", content)
+		}
+	})
+
+	t.Run("conflict with user edits", func(t *testing.T) {
+		projectDir := generateProject(t, map[string]any{"version": "16"})
+		if err := Add(Options{TemplatePath: component, OutputDir: projectDir, Out: &bytes.Buffer{}}); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		edited := filepath.Join(projectDir, "docker-compose.yaml")
+		if err := os.WriteFile(edited, []byte("image: postgres:15 # pinned"), 0644); err != nil {
+			t.Fatalf("Failed to write project file: %v", err)
+		}
+		if err := os.Remove(filepath.Join(projectDir, "db", "schema.sql")); err != nil {
+			t.Fatalf("Failed to remove project file: %v", err)
+		}
+
+		err := Add(Options{
+			TemplatePath: component, OutputDir: projectDir, Data: map[string]any{"version": "17"}, Out: &bytes.Buffer{},
+		})
+		if err == nil || !contains(err.Error(), "changed since the project was generated: docker-compose.yaml") {
+			t.Fatalf("Expected conflict error naming the file, got: %v", err)
+		}
+
+		content, _ := os.ReadFile(edited)
+		if string(content) != "image: postgres:15 # pinned" {
+			t.Errorf("Expected user edit to be kept, got %q", content)
+		}
+		if _, err = os.Stat(filepath.Join(projectDir, "db", "schema.sql")); !os.IsNotExist(err) {
+			t.Errorf("Expected nothing to be added on conflict, got: %v", err)
+		}
+	})
+
+	t.Run("conflict with untracked files", func(t *testing.T) {
+		projectDir := generateProject(t, map[string]any{"version": "16"})
+		created := filepath.Join(projectDir, "docker-compose.yaml")
+		if err := os.WriteFile(created, []byte("image: mysql"), 0644); err != nil {
+			t.Fatalf("Failed to write project file: %v", err)
+		}
+
+		err := Add(Options{TemplatePath: component, OutputDir: projectDir, Out: &bytes.Buffer{}})
+		if err == nil || !contains(err.Error(), "docker-compose.yaml") {
+			t.Fatalf("Expected conflict error naming the file, got: %v", err)
+		}
+		content, _ := os.ReadFile(created)
+		if string(content) != "image: mysql" {
+			t.Errorf("Expected the user's file to be kept, got %q", content)
+		}
+	})
+
+	t.Run("secrets are not reused", func(t *testing.T) {
+		base := writeTemplate(t, map[string]string{
+			MetadataFile: "prompts:\n  db.password: {type: string, secret: true}\n",
+			"main.go":    "package main",
+		})
+		projectDir := t.TempDir()
+		data := map[string]any{"name": "app", "db": map[string]any{"password": "s3cret"}}
+		if err := Apply(Options{TemplatePath: base, OutputDir: projectDir, Data: data, Out: &bytes.Buffer{}}); err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		secretComponent := writeTemplate(t, map[string]string{"db.env.tmpl": "{{.name}}:{{.db.password}}"})
+
+		err := Add(Options{TemplatePath: secretComponent, OutputDir: projectDir, Strict: true, Out: &bytes.Buffer{}})
+		if err == nil || !contains(err.Error(), `no entry for key "password"`) {
+			t.Fatalf("Expected the masked secret to be missing, got: %v", err)
+		}
+
+		err = Add(Options{
+			TemplatePath: secretComponent, OutputDir: projectDir, Strict: true, Out: &bytes.Buffer{},
+			Data: map[string]any{"db": map[string]any{"password": "s3cret"}},
+		})
+		if err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		content, err := os.ReadFile(filepath.Join(projectDir, "db.env"))
+		if err != nil || string(content) != "app:s3cret" {
+			t.Errorf("Expected the secret given again, got %q: %v", content, err)
+		}
+		p, err := LoadProvenance(projectDir)
+		if err != nil {
+			t.Fatalf("LoadProvenance failed: %v", err)
+		}
+		if db, _ := p.Data["db"].(map[string]any); db["password"] != SecretMask {
+			t.Errorf("Expected the secret to stay masked, got %v", p.Data)
+		}
+	})
+
+	t.Run("project without provenance", func(t *testing.T) {
+		err := Add(Options{TemplatePath: component, OutputDir: t.TempDir(), Out: &bytes.Buffer{}})
+		if err == nil || !contains(err.Error(), "no mold project in") {
+			t.Errorf("Expected a missing project error, got: %v", err)
+		}
+	})
+}
+
+func TestAddRequires(t *testing.T) {
+	projectDir := generateProject(t, nil)
+
+	tests := []struct {
+		name     string
+		metadata string
+		wantErr  string
+	}{
+		{name: "matching template and version", metadata: "requires:\n  template: go/service\n  version: ^1.2\n"},
+		{name: "any version", metadata: "requires:\n  template: go/service\n"},
+		{
+			name:     "other version",
+			metadata: "requires:\n  template: go/service\n  version: ^2\n",
+			wantErr:  "it requires template 'go/service' ^2, but the project was generated from version 1.2.0",
+		},
+		{
+			name:     "other template",
+			metadata: "requires:\n  template: node/app\n",
+			wantErr:  "it requires template 'node/app', which the project wasn't generated from",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			component := writeTemplate(t, map[string]string{
+				MetadataFile: tt.metadata,
+				"ci.yaml":    "steps: []",
+			})
+			err := Add(Options{TemplatePath: component, OutputDir: projectDir, Out: &bytes.Buffer{}})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Add failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("template the project's template extends", func(t *testing.T) {
+		base := writeTemplate(t, map[string]string{MetadataFile: "name: go/base\nversion: 1.0.0\n"})
+		child := writeTemplate(t, map[string]string{MetadataFile: "name: go/api\nextends: " + base + "\n"})
+		project := t.TempDir()
+		if err := Apply(Options{TemplatePath: child, OutputDir: project, Out: &bytes.Buffer{}}); err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		component := writeTemplate(t, map[string]string{
+			MetadataFile: "requires:\n  template: go/base\n  version: ^1\n",
+			"ci.yaml":    "steps: []",
+		})
+		if err := Add(Options{TemplatePath: component, OutputDir: project, Out: &bytes.Buffer{}}); err != nil {
+			t.Errorf("Add failed: %v", err)
+		}
+	})
+
+	t.Run("invalid requirement", func(t *testing.T) {
+		component := writeTemplate(t, map[string]string{MetadataFile: "requires:\n  version: ^1\n"})
+		err := Add(Options{TemplatePath: component, OutputDir: projectDir, Out: &bytes.Buffer{}})
+		if err == nil || !contains(err.Error(), "invalid requires") {
+			t.Errorf("Expected an invalid requires error, got: %v", err)
+		}
+	})
+}
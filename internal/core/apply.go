@@ -0,0 +1,1039 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/0m3kk/mold/internal/utils"
+	"github.com/0m3kk/mold/internal/version"
+)
+
+// Options configures a single Apply run.
+type Options struct {
+	// TemplatePath is the template directory to apply.
+	TemplatePath string
+	// Layers are further template directories applied on top of TemplatePath,
+	// in order. A file of a later layer replaces the file an earlier one
+	// generates at the same path.
+	Layers []string
+	// OutputDir is the directory where the project is generated. A path
+	// ending in .tar, .tar.gz, .tgz or .zip produces an archive instead.
+	OutputDir string
+	// Data holds the values used to render templates and path placeholders.
+	Data map[string]any
+	// Strict makes missing keys and warnings, such as formatter failures,
+	// errors.
+	Strict bool
+	// NoFormat disables the post-render formatter stage.
+	NoFormat bool
+	// NoProvenance disables writing the provenance file, ProvenanceFile, at
+	// the output root.
+	NoProvenance bool
+	// FuzzyKeys lets data keys match referenced keys spelled in another case
+	// style, such as "projectName" for "project_name". Strict disables it.
+	FuzzyKeys bool
+	// Out receives progress messages. Defaults to os.Stdout.
+	Out io.Writer
+	// Sink receives the generated files instead of OutputDir when set.
+	Sink Sink
+	// Clock is the timestamp of archive entries and of the recorded
+	// provenance. Defaults to the current time.
+	Clock time.Time
+	// Subdir restricts the generated files to this subdirectory of the
+	// templates. Its content lands at the output root unless KeepPrefix is
+	// set. Metadata, partials and data validation still cover the whole
+	// template, and formatter globs match the paths of a full run.
+	Subdir     string
+	KeepPrefix bool
+	// Prune deletes the files the previous run recorded in the manifest of
+	// the output directory that this run doesn't generate, unless they were
+	// modified since, and the directories it left empty.
+	Prune bool
+	// Merge says what to do with the generated files the user changed since
+	// the previous run recorded in the manifest of the output directory.
+	// Empty means MergeOff.
+	Merge MergeMode
+	// DryRun goes through the whole run, printing what would be generated,
+	// pruned and merged, without writing anything.
+	DryRun bool
+	// BackupDir receives a copy of each existing output file, at the same
+	// relative path and with the same mode, before it is overwritten or
+	// pruned. Empty disables backups. It needs an output directory.
+	BackupDir string
+	// Profiler times the phases of generating each file. Nil disables
+	// profiling.
+	Profiler Profiler
+	// MaxTemplateSize is the size limit in bytes of the files ending in
+	// '.tmpl', which are read into memory to be rendered. A larger one fails
+	// the run before anything is written, and one larger than half of it is
+	// warned about. Zero disables the limit. Copied files are streamed and
+	// have no limit.
+	MaxTemplateSize int64
+	// MaxIncludeDepth limits how deep {{template}} calls nest. Zero means
+	// DefaultMaxIncludeDepth.
+	MaxIncludeDepth int
+	// Link says how the copied files are put into the output directory.
+	// Rendered files are always written. Empty means LinkCopy.
+	Link LinkMode
+	// MaxFiles and MaxBytes limit the files and bytes the run generates, to
+	// stop a runaway path placeholder or template. The planned files are
+	// counted before anything is written, rendered ones by the size of their
+	// template, and the generated ones again as they are written, raw
+	// directories included. Zero disables a limit.
+	MaxFiles int
+	MaxBytes int64
+	// PreserveSymlinks recreates the symlinks of the templates as symlinks,
+	// with the placeholders of their targets replaced, instead of copying the
+	// files they point to. It needs an output directory.
+	PreserveSymlinks bool
+	// Origins maps the paths of TemplatePath and Layers fetched by
+	// FetchTemplate, or resolved to a version, to where they come from,
+	// recorded in the provenance.
+	Origins map[string]TemplateOrigin
+	// Ask, when set, asks for the values of the prompts and placeholders
+	// missing from the data before rendering, following the when of the
+	// prompts. The answers are part of the data, and of the provenance.
+	// Nil leaves them missing.
+	Ask *Asker
+	// NoWarnUnused leaves out the warning about the data keys no template or
+	// path uses, and NoWarnMissing the one about the references to missing
+	// keys rendered as "<no value>", along with their lists in the Result.
+	NoWarnUnused  bool
+	NoWarnMissing bool
+	// Referenced are the data keys used outside the templates, such as by
+	// the output path, never reported as unused.
+	Referenced []string
+	// NameRule says which names the generated files and directories can
+	// have once their placeholders are replaced. A name it rejects fails the
+	// run, naming the template path and the character at fault, unless
+	// SanitizeNames replaces what it rejects with '_'. A path leaving the
+	// output directory always fails. Empty means NameWindowsSafe.
+	NameRule      NameRule
+	SanitizeNames bool
+	// Result, when set, is filled in with what the run did, the warnings it
+	// printed and the error it failed with. Start it with NewResult.
+	Result *Result
+	// Observer receives the events of the run as they happen: the walk of
+	// the templates, each file generated or pruned, the warnings and the
+	// Result, even without Result set. Nil disables them.
+	Observer Observer
+}
+
+// entryKind says what Apply does with a planned entry.
+type entryKind int
+
+const (
+	entryDir entryKind = iota
+	entryRender
+	entryCopy
+	// entryRaw is a raw directory, copied as a whole.
+	entryRaw
+	// entrySymlink is a symlink recreated with its target rendered.
+	entrySymlink
+)
+
+// layer is one template applied by Apply.
+type layer struct {
+	path string
+	// source is the index in sources of the template the layer belongs to:
+	// itself, or the template extending it.
+	source   int
+	meta     *Metadata
+	renderer *Renderer
+	// funcs are the helper functions of path placeholders, knowing the
+	// acronyms of the template.
+	funcs template.FuncMap
+	// paths caches the rendered paths of the layer, by template path.
+	paths map[string]string
+	// rendered and copied count the files the layer produced.
+	rendered int
+	copied   int
+}
+
+// entry is one directory or file Apply is going to generate.
+type entry struct {
+	// src is the path of the source in the template directory.
+	src string
+	// rel is the destination path relative to the output root, without the
+	// '.tmpl' marker of rendered files, and marked the path with it.
+	rel    string
+	marked string
+	kind   entryKind
+	info   fs.FileInfo
+	layer  *layer
+}
+
+// source returns the path of the source relative to its template.
+func (e entry) source() string {
+	if rel, err := filepath.Rel(e.layer.path, e.src); err == nil {
+		return rel
+	}
+	return e.src
+}
+
+// applier carries the state of one Apply run.
+type applier struct {
+	opts    Options
+	sink    Sink
+	out     io.Writer
+	layers  []*layer
+	entries map[string]entry
+	// sources are the applied templates and prompts their prompts, recorded
+	// in the provenance.
+	sources []ProvenanceTemplate
+	prompts Prompts
+	// files and dirs record the generated files and directories for the
+	// manifest.
+	files manifest
+	dirs  []string
+	// previous is the provenance of the previous run when pruning or
+	// merging, and tracked its files by path.
+	previous *Provenance
+	tracked  map[string]ManifestFile
+	// theirs holds the generated content of the files replaced by a merge.
+	theirs map[string][]byte
+	// raw records the files copied from raw directories, which are never
+	// merged.
+	raw map[string]bool
+	// backedUp counts the files copied to the backup directory.
+	backedUp int
+	profiler Profiler
+	observer Observer
+	// derived are the case variants added to the data, left out of the
+	// provenance.
+	derived []string
+	// status records what was done with each file, by slash-separated path,
+	// for the result.
+	status map[string]FileStatus
+	// prefix is the destination of the applied subdirectory in a full run,
+	// dropped from the output paths unless KeepPrefix is set.
+	prefix string
+	// notices are the notices of the templates, rendered once the run
+	// succeeded, and deprecations their deprecation messages.
+	notices      []notice
+	rendered     []string
+	deprecations []Deprecation
+	// collisions are the planned paths only differing by case.
+	collisions []Collision
+	// headers caches the rendered headers, by layer path and glob.
+	headers map[string]string
+	// refs records the data the layers use, missing the references to
+	// missing keys rendered as "<no value>" and unused the data keys no
+	// template uses.
+	refs    keyTracker
+	missing []MissingValue
+	unused  []string
+	// generated counts the files written against MaxFiles and MaxBytes.
+	generated fileLimit
+}
+
+// Apply renders '.tmpl' files and copies all other files from the template
+// directory, followed by its layers, into the output directory or archive.
+// A template extending a parent is applied on top of it. Every template is
+// loaded and planned before anything is written. The defaults of the
+// templates fill in the values missing from the data. Entries are
+// generated in sorted destination path order so the output is reproducible,
+// followed by the provenance file recording the run and the generated files.
+// The values of secret prompts are masked in messages and errors.
+func Apply(opts Options) error {
+	a := &applier{
+		opts:      opts,
+		out:       opts.Out,
+		entries:   make(map[string]entry),
+		files:     make(manifest),
+		theirs:    make(map[string][]byte),
+		raw:       make(map[string]bool),
+		status:    make(map[string]FileStatus),
+		profiler:  opts.Profiler,
+		observer:  opts.Observer,
+		headers:   make(map[string]string),
+		generated: fileLimit{maxFiles: opts.MaxFiles, maxBytes: opts.MaxBytes},
+	}
+	if a.out == nil {
+		a.out = os.Stdout
+	}
+	if a.observer == nil {
+		a.observer = NopObserver{}
+	}
+	if a.profiler == nil {
+		a.profiler = nopProfiler{}
+	}
+	// An observer is handed the result even when the caller keeps none.
+	result := opts.Result
+	if result == nil && opts.Observer != nil {
+		result = NewResult()
+	}
+	if result == nil {
+		return a.apply()
+	}
+	if result.StartedAt.IsZero() {
+		*result = *NewResult()
+	}
+	recorder := &warningRecorder{w: a.out, observer: a.observer}
+	a.out = recorder
+	err := a.apply()
+	a.record(result, recorder.warnings, err)
+	a.observer.Done(result)
+	return err
+}
+
+// apply resolves the templates and the data of the run, then generates them.
+func (a *applier) apply() error {
+	opts := a.opts
+
+	// Resolve the inheritance chain of every template before planning, since
+	// all of their defaults apply to the paths.
+	data := make(map[string]any)
+	defaults := make(map[string]any)
+	var prompts Prompts
+	var deriveCases bool
+	var acronyms []string
+	computed := make(map[string]string)
+	for _, templatePath := range append([]string{opts.TemplatePath}, opts.Layers...) {
+		chain, meta, err := ResolveChain(templatePath)
+		if err != nil {
+			return err
+		}
+		if meta.Deprecated != "" {
+			fmt.Fprintf(a.out, "⚠️  Template '%s' is deprecated: %s\n", templatePath, meta.Deprecated)
+			a.deprecations = append(a.deprecations, Deprecation{Template: templatePath, Message: meta.Deprecated})
+		}
+		if len(chain) > 1 {
+			fmt.Fprintf(a.out, "🧬 Template chain: %s\n", strings.Join(chain, " -> "))
+		}
+		if meta.MinMoldVersion != "" {
+			if checked, _ := checkMoldVersion(meta.MinMoldVersion, version.String()); !checked {
+				fmt.Fprintf(a.out, "⚠️  Template '%s' needs mold %s or later, which can't be checked on mold %s\n",
+					templatePath, meta.MinMoldVersion, version.String())
+			}
+		}
+		for _, path := range chain {
+			if err = a.addLayer(path, meta); err != nil {
+				return err
+			}
+		}
+		for i, text := range meta.Notices {
+			a.notices = append(a.notices, notice{
+				template: templatePath, name: fmt.Sprintf("notice %d", i+1), text: text, layer: a.layers[len(a.layers)-1],
+			})
+		}
+		MergeData(data, meta.Defaults)
+		MergeData(defaults, meta.Defaults)
+		prompts = append(prompts, meta.Prompts...)
+		deriveCases = deriveCases || meta.DeriveCases
+		acronyms = append(acronyms, meta.Acronyms...)
+		maps.Copy(computed, meta.Computed)
+		source, err := provenanceTemplate(templatePath, chain, meta)
+		if err != nil {
+			return err
+		}
+		if origin, ok := opts.Origins[templatePath]; ok {
+			source.URL, source.Ref, source.Resolved = origin.URL, origin.Ref, origin.Version
+		}
+		a.sources = append(a.sources, source)
+	}
+	MergeData(data, opts.Data)
+	if opts.FuzzyKeys && !opts.Strict {
+		if err := a.matchFuzzyKeys(data); err != nil {
+			return err
+		}
+	}
+	casing := NewCasing(acronyms)
+	if opts.Ask != nil {
+		if err := a.askMissing(data, prompts, computed, deriveCases, casing.funcs()); err != nil {
+			return err
+		}
+	}
+	if _, err := prompts.Gate(data, defaults, opts.Data, casing.funcs(), a.out); err != nil {
+		return err
+	}
+	values, err := Compute(computed, data, opts.Data, casing.funcs(), a.out)
+	if err != nil {
+		return err
+	}
+	// Mask secret values both as given and as coerced by the validation.
+	var secrets secrets
+	secrets = secrets.collect(prompts, data)
+	err = prompts.Validate(data)
+	secrets = secrets.collect(prompts, data)
+	if err != nil {
+		return secrets.maskError(fmt.Errorf("invalid data: %w", err))
+	}
+	if deriveCases {
+		a.derived = DeriveCases(data, casing, prompts, a.out)
+	}
+	a.opts.Data = data
+	a.prompts = prompts
+	if len(secrets) > 0 {
+		a.out = &maskWriter{w: a.out, secrets: secrets}
+	}
+	for _, value := range values {
+		fmt.Fprintf(a.out, "🧮 Computed: %s = %s\n", value.Key, value.Value)
+	}
+	if err = a.run(); err != nil {
+		return secrets.maskError(err)
+	}
+	if err = a.warnUsage(opts.Data); err != nil {
+		return err
+	}
+	a.rendered = a.renderNotices(secrets)
+	return nil
+}
+
+// run plans the entries of every layer and generates them.
+func (a *applier) run() error {
+	if err := a.checkSubdir(); err != nil {
+		return err
+	}
+	if err := a.loadPrevious(); err != nil {
+		return err
+	}
+	if err := a.checkBackup(); err != nil {
+		return err
+	}
+	if err := a.checkLink(); err != nil {
+		return err
+	}
+	if err := a.checkSymlinks(); err != nil {
+		return err
+	}
+	templates := make([]string, 0, len(a.layers))
+	for _, l := range a.layers {
+		templates = append(templates, l.path)
+	}
+	a.observer.WalkStart(templates)
+	for _, l := range a.layers {
+		if err := a.planLayer(l); err != nil {
+			return err
+		}
+	}
+	// The provenance file and the state directory are reserved at the output
+	// root, a template's own copies are never generated.
+	delete(a.entries, ProvenanceFile)
+	maps.DeleteFunc(a.entries, func(key string, _ entry) bool { return isStatePath(key) })
+	entries := slices.SortedFunc(maps.Values(a.entries), func(x, y entry) int {
+		return strings.Compare(filepath.ToSlash(x.rel), filepath.ToSlash(y.rel))
+	})
+	if err := a.checkLimits(entries); err != nil {
+		return err
+	}
+
+	// Create the output directory or archive.
+	a.sink = a.opts.Sink
+	if a.opts.DryRun {
+		fmt.Fprintln(a.out, "🔍 Dry run, nothing is written")
+		a.sink = discardSink{}
+	}
+	if a.sink == nil {
+		var err error
+		if a.sink, err = NewSink(a.opts.OutputDir, a.opts.Clock); err != nil {
+			return err
+		}
+		if dirSink, onDisk := a.sink.(*DirSink); onDisk {
+			// A path placeholder can expand to directories the template
+			// doesn't have, such as {{.package}}.go giving pkg/api/api.go.
+			dirSink.ParentMode = utils.DirMode
+		}
+	}
+	if err := a.checkCollisions(entries); err != nil {
+		_ = a.sink.Abort()
+		return err
+	}
+
+	for _, e := range entries {
+		if err := a.observe(e); err != nil {
+			_ = a.sink.Abort()
+			return fmt.Errorf("error during template processing: %w", err)
+		}
+	}
+	if err := a.prune(); err != nil {
+		_ = a.sink.Abort()
+		return err
+	}
+	// The provenance comes last, it holds the hashes of the generated files.
+	if !a.opts.NoProvenance && !a.opts.DryRun {
+		if _, onDisk := a.sink.(*DirSink); onDisk {
+			if err := a.storeBases(); err != nil {
+				return err
+			}
+		}
+		if err := a.writeProvenance(); err != nil {
+			_ = a.sink.Abort()
+			return fmt.Errorf("error during template processing: %w", err)
+		}
+	} else if !a.opts.NoProvenance {
+		// A dry run records nothing, but counts the provenance it would back up.
+		if err := a.backup(ProvenanceFile); err != nil {
+			return err
+		}
+	}
+	if err := a.sink.Commit(); err != nil {
+		return err
+	}
+	a.reportBackup()
+
+	if len(a.layers) > 1 {
+		for _, l := range a.layers {
+			fmt.Fprintf(a.out, "📦 Layer %s: %d rendered, %d copied\n", l.path, l.rendered, l.copied)
+		}
+	}
+	return nil
+}
+
+// writeProvenance records the applied templates and data at the output
+// root. The derived cases are left out, the next run derives them again.
+func (a *applier) writeProvenance() error {
+	generatedAt := a.opts.Clock
+	if generatedAt.IsZero() {
+		generatedAt = time.Now()
+	}
+	data := maps.Clone(a.opts.Data)
+	for _, key := range a.derived {
+		delete(data, key)
+	}
+	p := &Provenance{
+		Schema:      ProvenanceSchema,
+		Template:    a.sources[0],
+		Layers:      a.sources[1:],
+		MoldVersion: version.String(),
+		GeneratedAt: generatedAt.UTC().Truncate(time.Second),
+		Data:        maskedData(a.prompts, data),
+		Files:       a.producedFiles(),
+		Dirs:        a.dirs,
+	}
+	content, err := p.Marshal()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "🧾 Recording provenance: %s\n", ProvenanceFile)
+	if err = a.backup(ProvenanceFile); err != nil {
+		return err
+	}
+	w, err := a.sink.Create(ProvenanceFile, 0644, int64(len(content)))
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(content); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("failed to write '%s': %w", ProvenanceFile, err)
+	}
+	return w.Close()
+}
+
+// templateMarker is the component of a file name marking it as a template
+// to render with the data.
+const templateMarker = ".tmpl"
+
+// templateName returns the destination name of a template file and true,
+// or false for a file to copy. A name ending in '.tmpl' loses that suffix,
+// and other names lose a '.tmpl' component anywhere after their first, so
+// main.tmpl.go renders to main.go and keeps its extension for editors. A
+// name holding the component more than once, without ending in it, is
+// ambiguous.
+func templateName(name string) (string, bool, error) {
+	if strings.HasSuffix(name, templateMarker) {
+		return strings.TrimSuffix(name, templateMarker), true, nil
+	}
+	parts := strings.Split(name, ".")
+	at := -1
+	for i, part := range parts[1:] {
+		if "."+part != templateMarker {
+			continue
+		}
+		if at >= 0 {
+			return "", false, fmt.Errorf("ambiguous template file name '%s': it holds '%s' more than once",
+				name, templateMarker)
+		}
+		at = i + 1
+	}
+	if at < 0 {
+		return name, false, nil
+	}
+	return strings.Join(slices.Delete(parts, at, at+1), "."), true, nil
+}
+
+// IsHintFile reports whether the file holds example data for the template
+// rather than template content.
+func IsHintFile(name string) bool {
+	return name == "tmpl.json" || name == "tmpl.yaml"
+}
+
+// matchFuzzyKeys makes the data available under the spelling of the keys
+// the layers reference.
+func (a *applier) matchFuzzyKeys(data map[string]any) error {
+	refs, err := a.references()
+	if err != nil {
+		return err
+	}
+	matches, err := MatchFuzzyKeys(data, refs.keys())
+	if err != nil {
+		return fmt.Errorf("invalid data: %w", err)
+	}
+	for _, match := range matches {
+		fmt.Fprintf(a.out, "🔤 Matched data key: %s\n", match)
+		// The data key is used under its referenced spelling.
+		key, _, _ := strings.Cut(match, " -> ")
+		refs.use(key, "fuzzy keys")
+	}
+	return nil
+}
+
+// addLayer adds a template whose chain has the given merged metadata.
+func (a *applier) addLayer(templatePath string, meta *Metadata) error {
+	renderer, err := NewRenderer(filepath.Join(templatePath, PartialsDir), a.opts.Strict)
+	if err != nil {
+		return err
+	}
+	// The files and paths of the layer read the files of their own template.
+	files := newTemplateFiles(templatePath)
+	renderer.MaxIncludeDepth = a.opts.MaxIncludeDepth
+	renderer.Acronyms = meta.Acronyms
+	renderer.Funcs = files.funcs()
+	casing := defaultCasing
+	if len(meta.Acronyms) > 0 {
+		casing = NewCasing(meta.Acronyms)
+	}
+	funcs := helperFuncs(casing, files)
+	a.layers = append(a.layers, &layer{
+		path: templatePath, source: len(a.sources), meta: meta, renderer: renderer, funcs: funcs,
+		paths: make(map[string]string),
+	})
+	return nil
+}
+
+// checkSubdir makes sure the subdirectory to apply is inside the templates
+// and exists in at least one layer.
+func (a *applier) checkSubdir() error {
+	subdir := a.opts.Subdir
+	if subdir == "" {
+		return nil
+	}
+	clean := filepath.Clean(subdir)
+	if filepath.IsAbs(clean) || !filepath.IsLocal(clean) {
+		return fmt.Errorf("invalid subdirectory '%s': expected a relative path inside the template", subdir)
+	}
+	for _, l := range a.layers {
+		info, err := os.Stat(filepath.Join(l.path, clean))
+		if err == nil && info.IsDir() {
+			a.opts.Subdir = clean
+			if !a.opts.KeepPrefix {
+				if a.prefix, err = replacePlaceholders(clean, a.opts.Data, l.funcs, a.opts.Strict); err != nil {
+					return fmt.Errorf("failed to replace placeholders in path '%s': %w", clean, err)
+				}
+			}
+			return nil
+		}
+		if err == nil {
+			return fmt.Errorf("subdirectory '%s' of template '%s' is not a directory", subdir, l.path)
+		}
+	}
+	return fmt.Errorf("subdirectory '%s' not found in template '%s'", subdir, a.opts.TemplatePath)
+}
+
+// planLayer plans the entries of a layer on top of the entries of the
+// previous layers.
+func (a *applier) planLayer(l *layer) error {
+	root := l.path
+	if a.opts.Subdir != "" {
+		// Layers without the subdirectory contribute nothing.
+		root = filepath.Join(l.path, a.opts.Subdir)
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			return nil
+		}
+	}
+	// Walk the template directory to plan what to render/copy.
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, walkErr error) error {
+		return a.plan(l, path, d, walkErr)
+	})
+	if err != nil {
+		return fmt.Errorf("error during template processing: %w", err)
+	}
+	return nil
+}
+
+// plan records what to do with a single entry of a template directory. A
+// file replaces the file planned by a previous layer at the same path.
+func (a *applier) plan(l *layer, path string, d fs.DirEntry, walkErr error) error {
+	if walkErr != nil {
+		return walkErr
+	}
+
+	// Skip hint files
+	if IsHintFile(d.Name()) {
+		return nil
+	}
+
+	// Determine the destination path for the file or directory.
+	relPath, err := filepath.Rel(l.path, path)
+	if err != nil {
+		return fmt.Errorf("failed to get relative path for '%s': %w", path, err)
+	}
+	if relPath == MetadataFile {
+		return nil
+	}
+	// Partials are only included by other templates and tests only run
+	// against the template.
+	if (relPath == PartialsDir || relPath == TestsDir) && d.IsDir() {
+		return filepath.SkipDir
+	}
+	if l.meta.Ignored(filepath.ToSlash(relPath)) {
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+	raw := d.IsDir() && l.meta.IsRaw(filepath.ToSlash(relPath))
+	source := filepath.ToSlash(relPath)
+	// Drop the subdirectory from the destination path.
+	if a.opts.Subdir != "" && !a.opts.KeepPrefix {
+		if relPath, err = filepath.Rel(a.opts.Subdir, relPath); err != nil {
+			return fmt.Errorf("failed to get relative path for '%s': %w", path, err)
+		}
+	}
+	// Replace placeholders in relative path
+	authored := relPath
+	relPath, err = a.renderPath(l, relPath)
+	if err != nil {
+		return fmt.Errorf("failed to replace placeholders in path '%s': %w", source, err)
+	}
+
+	// Follow symlinks so linked files are copied with their target's content,
+	// unless they are preserved.
+	symlink := a.opts.PreserveSymlinks && d.Type()&fs.ModeSymlink != 0
+	stat := os.Stat
+	if symlink {
+		stat = os.Lstat
+	}
+	info, err := stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat '%s': %w", path, err)
+	}
+	if kind := irregularType(info.Mode()); kind != "" {
+		return a.skipIrregular(path, relPath, kind)
+	}
+
+	e := entry{src: path, rel: relPath, kind: entryCopy, info: info, layer: l}
+	switch {
+	case symlink:
+		e.kind = entrySymlink
+	case raw:
+		e.kind = entryRaw
+	case d.IsDir():
+		e.kind = entryDir
+	default:
+		name, render, nameErr := templateName(filepath.Base(relPath))
+		if nameErr != nil {
+			return fmt.Errorf("failed to plan '%s': %w", path, nameErr)
+		}
+		if !render {
+			break
+		}
+		e.kind = entryRender
+		e.rel, e.marked = filepath.Join(filepath.Dir(relPath), name), relPath
+		if err = a.checkTemplateSize(path, info.Size()); err != nil {
+			return err
+		}
+	}
+	// The names the template spells out are the author's to choose.
+	if relPath != authored {
+		if e.rel, err = a.checkNames(source, e.rel); err != nil {
+			return err
+		}
+	}
+	key := filepath.ToSlash(e.rel)
+	prev, ok := a.entries[key]
+	if ok && (prev.kind == entryDir || prev.kind == entryRaw) && e.kind == entryDir {
+		return nil
+	}
+	if ok && prev.layer == l && prev.kind != entryDir && e.kind != entryDir {
+		// Such as main.go.tmpl and main.tmpl.go, unlike a later layer
+		// replacing a file.
+		return fmt.Errorf("'%s' and '%s' both generate '%s'", prev.source(), e.source(), key)
+	}
+	a.entries[key] = e
+	if raw {
+		// The files of a raw directory are copied with it.
+		return filepath.SkipDir
+	}
+	return nil
+}
+
+// renderPath replaces the placeholders of a path of the layer, rendering
+// each path once. A path whose last name is plain text, like most files of
+// a templated directory, reuses the rendering of its directory.
+func (a *applier) renderPath(l *layer, path string) (string, error) {
+	if rendered, ok := l.paths[path]; ok {
+		return rendered, nil
+	}
+	var rendered string
+	var err error
+	i := strings.LastIndexByte(path, filepath.Separator)
+	name := path[i+1:]
+	// A name without braces can't end an action started in the directory.
+	plain := !strings.Contains(name, "{{") && !strings.Contains(name, "}}") && pathTokens(name, l.funcs) == name
+	switch {
+	case plain && i < 0:
+		rendered = path
+	case plain:
+		if rendered, err = a.renderPath(l, path[:i]); err != nil {
+			return "", err
+		}
+		rendered += path[i:]
+	default:
+		if rendered, err = replacePlaceholders(path, a.opts.Data, l.funcs, a.opts.Strict); err != nil {
+			return "", err
+		}
+	}
+	l.paths[path] = rendered
+	return rendered, nil
+}
+
+// checkTemplateSize rejects a template file over MaxTemplateSize, and warns
+// about one over half of it, or rejects it in strict mode.
+func (a *applier) checkTemplateSize(path string, size int64) error {
+	limit := a.opts.MaxTemplateSize
+	if err := checkTemplateSize(path, size, limit); err != nil || limit <= 0 || size <= limit/2 {
+		return err
+	}
+	if a.opts.Strict {
+		return fmt.Errorf("template file '%s' is %s, close to the limit of %s", path, formatSize(size), formatSize(limit))
+	}
+	fmt.Fprintf(a.out, "⚠️  Template file '%s' is %s, close to the limit of %s\n",
+		path, formatSize(size), formatSize(limit))
+	return nil
+}
+
+// generate creates a planned entry in the sink. A file the user changed
+// since the previous run is merged with the generated content.
+func (a *applier) generate(e entry) error {
+	if e.kind == entryDir {
+		// Create the corresponding directory in the destination.
+		if e.rel != "." {
+			a.dirs = append(a.dirs, filepath.ToSlash(e.rel))
+		}
+		return a.sink.Mkdir(filepath.ToSlash(e.rel), e.info.Mode())
+	}
+	if e.kind == entryRaw {
+		return a.copyRaw(e)
+	}
+	if e.kind == entrySymlink {
+		return a.symlink(e)
+	}
+
+	change, err := a.userChange(e.rel)
+	if err != nil {
+		return err
+	}
+	if e.kind == entryRender {
+		// This is a template file that needs to be rendered.
+		fmt.Fprintf(a.out, "✨ Rendering: %s -> %s\n", e.marked, e.rel)
+		e.layer.rendered++
+		a.status[filepath.ToSlash(e.rel)] = StatusRendered
+		err = a.render(e.layer, e.src, e.rel, e.info.Mode())
+	} else {
+		// This is a regular file, so just copy it, or link it.
+		e.layer.copied++
+		a.status[filepath.ToSlash(e.rel)] = StatusCopied
+		var linked bool
+		if linked, err = a.link(e); err == nil && !linked {
+			fmt.Fprintf(a.out, "📄 Copying: %s\n", e.rel)
+			err = a.copy(e.layer, e.src, e.rel, e.info)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return a.merge(e.rel, change)
+}
+
+// render executes a template file and writes the result to the sink. It
+// parses and executes the template itself, rather than through Render, to
+// time both phases.
+func (a *applier) render(l *layer, path, relPath string, mode fs.FileMode) error {
+	start := a.profiler.Now()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read template file '%s': %w", path, err)
+	}
+	a.profiler.Record(relPath, PhaseRead, start)
+
+	start = a.profiler.Now()
+	name := filepath.Base(path)
+	tmpl, err := l.renderer.parse(name, content)
+	if err != nil {
+		return err
+	}
+	a.profiler.Record(relPath, PhaseParse, start)
+
+	start = a.profiler.Now()
+	var rendered bytes.Buffer
+	if err = tmpl.Execute(&rendered, a.opts.Data); err != nil {
+		return fmt.Errorf("failed to render template '%s': %w", name, err)
+	}
+	a.profiler.Record(relPath, PhaseExecute, start)
+	result, err := a.addHeader(l, relPath, rendered.Bytes(), false)
+	if err != nil {
+		return err
+	}
+	a.trackMissing(filepath.ToSlash(relPath), content, rendered.Bytes())
+	return a.write(l, relPath, mode, result)
+}
+
+// copy copies a regular file to the sink. Files that get a header, or have
+// to be formatted before they reach an archive, are copied through memory.
+func (a *applier) copy(l *layer, path, relPath string, info fs.FileInfo) error {
+	start := a.profiler.Now()
+	_, onDisk := a.sink.(*DirSink)
+	if header := a.copiedHeader(l, relPath); header || !onDisk && len(a.formatters(l, relPath)) > 0 {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to open source file '%s': %w", path, err)
+		}
+		a.profiler.Record(relPath, PhaseRead, start)
+		if header {
+			if content, err = a.addHeader(l, relPath, content, true); err != nil {
+				return err
+			}
+		}
+		return a.write(l, relPath, info.Mode(), content)
+	}
+
+	w, err := a.create(relPath, info.Mode(), info.Size())
+	if err != nil {
+		return err
+	}
+	if err = utils.CopyFileTo(w, path); err != nil {
+		_ = w.Close()
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	a.profiler.Record(relPath, PhaseCopy, start)
+	return a.formatOnDisk(l, relPath)
+}
+
+// write formats the content and writes it to the sink.
+func (a *applier) write(l *layer, relPath string, mode fs.FileMode, content []byte) error {
+	if _, onDisk := a.sink.(*DirSink); !onDisk {
+		var err error
+		if content, err = a.formatContent(l, relPath, content); err != nil {
+			return err
+		}
+	}
+
+	start := a.profiler.Now()
+	w, err := a.create(relPath, mode, int64(len(content)))
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(content); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("failed to write '%s': %w", relPath, err)
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	a.profiler.Record(relPath, PhaseWrite, start)
+	return a.formatOnDisk(l, relPath)
+}
+
+// create opens a generated file in the sink, after backing up the file it
+// replaces, and records it in the manifest once it is written.
+func (a *applier) create(relPath string, mode fs.FileMode, size int64) (io.WriteCloser, error) {
+	if err := a.count(relPath, size); err != nil {
+		return nil, err
+	}
+	if err := a.backup(relPath); err != nil {
+		return nil, err
+	}
+	w, err := a.sink.Create(filepath.ToSlash(relPath), mode, size)
+	if err != nil {
+		return nil, err
+	}
+	return a.files.track(w, relPath, mode), nil
+}
+
+// formatters returns the formatter commands the layer declares for the output
+// path. The globs match the path the file has when the whole template is
+// applied.
+func (a *applier) formatters(l *layer, relPath string) [][]string {
+	if a.opts.NoFormat {
+		return nil
+	}
+	return l.meta.FormattersFor(filepath.ToSlash(filepath.Join(a.prefix, relPath)))
+}
+
+// formatOnDisk runs the formatters matching a file written to a directory
+// sink in place.
+func (a *applier) formatOnDisk(l *layer, relPath string) error {
+	dirSink, onDisk := a.sink.(*DirSink)
+	if !onDisk {
+		return nil
+	}
+	commands := a.formatters(l, relPath)
+	if len(commands) == 0 {
+		return nil
+	}
+	defer a.profiler.Record(relPath, PhaseFormat, a.profiler.Now())
+	for _, command := range commands {
+		name := strings.Join(command, " ")
+		fmt.Fprintf(a.out, "🎨 Formatting: %s (%s)\n", relPath, name)
+		if err := FormatFile(command, dirSink.Path(relPath)); err != nil {
+			if err = a.formatFailed(name, relPath, err); err != nil {
+				return err
+			}
+		}
+	}
+	return a.files.rehash(relPath, dirSink.Path(relPath))
+}
+
+// formatContent runs the formatters matching a file that is not on disk. A
+// dry run runs none of them, it names the ones that would format the file.
+func (a *applier) formatContent(l *layer, relPath string, content []byte) ([]byte, error) {
+	commands := a.formatters(l, relPath)
+	if len(commands) == 0 {
+		return content, nil
+	}
+	if a.opts.DryRun {
+		names := make([]string, 0, len(commands))
+		for _, command := range commands {
+			names = append(names, strings.Join(command, " "))
+		}
+		fmt.Fprintf(a.out, "🎨 Would format: %s (%s)\n", relPath, strings.Join(names, ", "))
+		return content, nil
+	}
+	defer a.profiler.Record(relPath, PhaseFormat, a.profiler.Now())
+	for _, command := range commands {
+		name := strings.Join(command, " ")
+		fmt.Fprintf(a.out, "🎨 Formatting: %s (%s)\n", relPath, name)
+		formatted, err := FormatContent(command, relPath, content)
+		if err != nil {
+			if err = a.formatFailed(name, relPath, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		content = formatted
+	}
+	return content, nil
+}
+
+// formatFailed reports a formatter failure as a warning, or as an error in
+// strict mode.
+func (a *applier) formatFailed(name, relPath string, err error) error {
+	if a.opts.Strict {
+		return fmt.Errorf("formatter '%s' failed on '%s': %w", name, relPath, err)
+	}
+	fmt.Fprintf(a.out, "⚠️  Formatter '%s' failed on '%s': %v\n", name, relPath, err)
+	return nil
+}
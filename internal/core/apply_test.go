@@ -0,0 +1,787 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTemplate creates the files of a template directory from a map of
+// relative paths to contents.
+func writeTemplate(t testing.TB, files map[string]string) string {
+	t.Helper()
+	templateDir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(templateDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create template directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write template file: %v", err)
+		}
+	}
+	return templateDir
+}
+
+func TestApply(t *testing.T) {
+	crookedGo := "package {{.pkg}}\nfunc Hello() string {\n{{- if .loud}}\n        return \"HELLO\"\n{{- end}}\n}\n"
+	formattedGo := "package main\n\nfunc Hello() string {\n\treturn \"HELLO\"\n}\n"
+	data := map[string]any{"pkg": "main", "loud": true}
+
+	t.Run("formatters run on matching files", func(t *testing.T) {
+		templateDir := writeTemplate(t, map[string]string{
+			MetadataFile:                "formatters:\n  \"**/*.go\": [\"gofmt\", \"-w\"]\n",
+			"cmd/main.go.tmpl":          crookedGo,
+			"notes.txt":                 "static",
+			"tmpl.yaml":                 "pkg: main",
+			"{{.pkg}}/nested/deep.tmpl": "{{.pkg}}",
+		})
+		outputDir := t.TempDir()
+		var out bytes.Buffer
+
+		err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: &out})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+
+		output, err := os.ReadFile(filepath.Join(outputDir, "cmd", "main.go"))
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		if string(output) != formattedGo {
+			t.Errorf("Output mismatch:\nGot:\n%s\nWant:\n%s", string(output), formattedGo)
+		}
+		if _, err = os.Stat(filepath.Join(outputDir, MetadataFile)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s not to be copied, got: %v", MetadataFile, err)
+		}
+		if _, err = os.Stat(filepath.Join(outputDir, "main", "nested", "deep")); err != nil {
+			t.Errorf("Expected nested rendered file: %v", err)
+		}
+		if !contains(out.String(), "Formatting: "+filepath.Join("cmd", "main.go")) {
+			t.Errorf("Expected formatter log line, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("no format skips the formatter stage", func(t *testing.T) {
+		templateDir := writeTemplate(t, map[string]string{
+			MetadataFile:   "formatters:\n  \"**/*.go\": [\"gofmt\"]\n",
+			"main.go.tmpl": crookedGo,
+		})
+		outputDir := t.TempDir()
+
+		err := Apply(Options{
+			TemplatePath: templateDir,
+			OutputDir:    outputDir,
+			Data:         data,
+			NoFormat:     true,
+			Out:          &bytes.Buffer{},
+		})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+
+		output, err := os.ReadFile(filepath.Join(outputDir, "main.go"))
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		if string(output) == formattedGo {
+			t.Error("Expected output to remain unformatted")
+		}
+	})
+
+	t.Run("formatter failure is a warning", func(t *testing.T) {
+		templateDir := writeTemplate(t, map[string]string{
+			MetadataFile:    "formatters:\n  \"*.go\": [\"gofmt\"]\n  \"*.txt\": [\"false\"]\n",
+			"broken.go":     "package main\nfunc {",
+			"notes.txt":     "static",
+			"after.go.tmpl": "package {{.pkg}}\n",
+		})
+		outputDir := t.TempDir()
+		var out bytes.Buffer
+
+		err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: &out})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		if !contains(out.String(), "Formatter 'gofmt' failed on 'broken.go'") {
+			t.Errorf("Expected gofmt warning, got:\n%s", out.String())
+		}
+		if !contains(out.String(), "Formatter 'false' failed on 'notes.txt'") {
+			t.Errorf("Expected external formatter warning, got:\n%s", out.String())
+		}
+		if _, err = os.Stat(filepath.Join(outputDir, "after.go")); err != nil {
+			t.Errorf("Expected apply to continue after formatter failure: %v", err)
+		}
+	})
+
+	t.Run("formatter failure is an error in strict mode", func(t *testing.T) {
+		templateDir := writeTemplate(t, map[string]string{
+			MetadataFile: "formatters:\n  \"*.go\": [\"gofmt\"]\n",
+			"broken.go":  "package main\nfunc {",
+		})
+
+		err := Apply(Options{
+			TemplatePath: templateDir,
+			OutputDir:    t.TempDir(),
+			Data:         data,
+			Strict:       true,
+			Out:          &bytes.Buffer{},
+		})
+		if err == nil || !contains(err.Error(), "formatter 'gofmt' failed on 'broken.go'") {
+			t.Errorf("Expected strict formatter error, got: %v", err)
+		}
+	})
+
+	t.Run("dry run runs no formatter", func(t *testing.T) {
+		marker := filepath.Join(t.TempDir(), "RAN")
+		templateDir := writeTemplate(t, map[string]string{
+			MetadataFile: "formatters:\n  \"**/*.go\": [\"gofmt\"]\n  \"cmd/*\": [\"touch\", \"" + marker + "\"]\n" +
+				"  \"*.txt\": [\"sh\", \"-c\", \"touch " + marker + "\"]\n",
+			"cmd/main.go.tmpl": crookedGo,
+			"notes.txt":        "static",
+		})
+		var out bytes.Buffer
+
+		err := Apply(Options{TemplatePath: templateDir, OutputDir: t.TempDir(), Data: data, DryRun: true, Out: &out})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		if _, err = os.Stat(marker); !os.IsNotExist(err) {
+			t.Errorf("Expected no formatter command to run in a dry run, got: %v", err)
+		}
+		for _, line := range []string{
+			"🎨 Would format: cmd/main.go (gofmt, touch " + marker + ")\n",
+			"🎨 Would format: notes.txt (sh -c touch " + marker + ")\n",
+		} {
+			if !contains(out.String(), line) {
+				t.Errorf("Expected %q in the dry run, got:\n%s", line, out.String())
+			}
+		}
+		if contains(out.String(), "🎨 Formatting") {
+			t.Errorf("Expected no file to be formatted, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("partials are included and not copied", func(t *testing.T) {
+		templateDir := writeTemplate(t, map[string]string{
+			PartialsDir + "/license.tmpl": "// Copyright {{.owner}}",
+			"main.go.tmpl":                `{{template "license.tmpl" .}}` + "\npackage {{.pkg}}\n",
+		})
+		outputDir := t.TempDir()
+
+		err := Apply(Options{
+			TemplatePath: templateDir,
+			OutputDir:    outputDir,
+			Data:         map[string]any{"owner": "ACME", "pkg": "main"},
+			Out:          &bytes.Buffer{},
+		})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+
+		output, err := os.ReadFile(filepath.Join(outputDir, "main.go"))
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		if string(output) != "// Copyright ACME\npackage main\n" {
+			t.Errorf("Unexpected output: %q", string(output))
+		}
+		if _, err = os.Stat(filepath.Join(outputDir, PartialsDir)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s not to be copied, got: %v", PartialsDir, err)
+		}
+	})
+
+	t.Run("strict mode fails on missing keys", func(t *testing.T) {
+		templateDir := writeTemplate(t, map[string]string{"main.go.tmpl": "package {{.pkg}}\n"})
+
+		err := Apply(Options{
+			TemplatePath: templateDir,
+			OutputDir:    t.TempDir(),
+			Data:         map[string]any{},
+			Strict:       true,
+			Out:          &bytes.Buffer{},
+		})
+		if err == nil || !contains(err.Error(), "map has no entry for key \"pkg\"") {
+			t.Errorf("Expected missing key error, got: %v", err)
+		}
+	})
+
+	t.Run("archive output", func(t *testing.T) {
+		templateDir := writeTemplate(t, map[string]string{
+			MetadataFile:            "formatters:\n  \"**/*.go\": [\"gofmt\"]\n",
+			"{{.pkg}}/main.go.tmpl": crookedGo,
+			"static/notes.txt":      "static",
+		})
+		if err := os.Chmod(filepath.Join(templateDir, "static", "notes.txt"), 0600); err != nil {
+			t.Fatalf("Failed to chmod: %v", err)
+		}
+		archivePath := filepath.Join(t.TempDir(), "project.tar.gz")
+
+		err := Apply(Options{TemplatePath: templateDir, OutputDir: archivePath, Data: data, Out: &bytes.Buffer{}})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+
+		entries := readTarEntries(t, archivePath, true)
+		if got := entries["main/main.go"].content; got != formattedGo {
+			t.Errorf("Output mismatch:\nGot:\n%s\nWant:\n%s", got, formattedGo)
+		}
+		if entry := entries["static/notes.txt"]; entry.content != "static" || entry.mode.Perm() != 0600 {
+			t.Errorf("Unexpected copied entry: %+v", entry)
+		}
+		if _, ok := entries["main/"]; !ok {
+			t.Errorf("Expected directory entry for rendered path, got %v", entries)
+		}
+	})
+
+	t.Run("tar stream is sorted and reproducible", func(t *testing.T) {
+		templateDir := writeTemplate(t, map[string]string{
+			"b.txt":               "b",
+			"{{.pkg}}/x.txt.tmpl": "{{.pkg}}",
+		})
+		clock := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		var streams [2]bytes.Buffer
+		for i := range streams {
+			err := Apply(Options{
+				TemplatePath: templateDir,
+				Data:         map[string]any{"pkg": "a"},
+				Out:          &bytes.Buffer{},
+				Clock:        clock,
+				Sink:         NewTarStreamSink(&streams[i], clock),
+			})
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+		}
+		if !bytes.Equal(streams[0].Bytes(), streams[1].Bytes()) {
+			t.Error("Expected identical tar streams")
+		}
+
+		var names []string
+		tr := tar.NewReader(&streams[0])
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Failed to read tar entry: %v", err)
+			}
+			if !header.ModTime.Equal(clock) {
+				t.Errorf("Expected mod time %v for '%s', got %v", clock, header.Name, header.ModTime)
+			}
+			names = append(names, header.Name)
+		}
+		if want := []string{"a/", "a/x.txt", "b.txt", ProvenanceFile}; !slices.Equal(names, want) {
+			t.Errorf("Expected entries %v, got %v", want, names)
+		}
+	})
+
+	t.Run("logs and manifest are reproducible", func(t *testing.T) {
+		files := map[string]string{MetadataFile: "defaults:\n  zone: eu\n  db: {port: 5432, host: local}\n"}
+		for _, dir := range []string{"a", "a-b", "a/b", "z", "{{.pkg}}", "m/n/o"} {
+			for _, name := range []string{"y.txt", "b.txt.tmpl", "a.txt", "B.txt"} {
+				files[dir+"/"+name] = "{{.pkg}} {{.zone}}"
+			}
+		}
+		base := writeTemplate(t, files)
+		layer := writeTemplate(t, map[string]string{"a/a.txt": "layer", "new/x.txt": "x"})
+		clock := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		var logs [2]bytes.Buffer
+		var manifests [2][]byte
+		for i := range logs {
+			outputDir := t.TempDir()
+			err := Apply(Options{
+				TemplatePath: base,
+				Layers:       []string{layer},
+				OutputDir:    outputDir,
+				Data:         map[string]any{"pkg": "p", "extra": map[string]any{"k2": 2, "k1": 1}},
+				Out:          &logs[i],
+				Clock:        clock,
+			})
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if manifests[i], err = os.ReadFile(filepath.Join(outputDir, ProvenanceFile)); err != nil {
+				t.Fatalf("Failed to read the manifest: %v", err)
+			}
+		}
+		if logs[0].String() != logs[1].String() {
+			t.Errorf("Expected identical logs:\n%s\n---\n%s", logs[0].String(), logs[1].String())
+		}
+		if !bytes.Equal(manifests[0], manifests[1]) {
+			t.Errorf("Expected identical manifests:\n%s\n---\n%s", manifests[0], manifests[1])
+		}
+
+		// Files are generated, and recorded, in sorted path order.
+		var logged []string
+		for _, line := range strings.Split(logs[0].String(), "\n") {
+			if _, path, ok := strings.Cut(line, " -> "); ok {
+				logged = append(logged, path)
+			} else if path, ok := strings.CutPrefix(line, "📄 Copying: "); ok {
+				logged = append(logged, path)
+			}
+		}
+		if len(logged) != 25 || !slices.IsSorted(logged) {
+			t.Errorf("Expected the 25 files logged in sorted order, got %v", logged)
+		}
+		p, err := ParseProvenance(manifests[0], ProvenanceFile)
+		if err != nil {
+			t.Fatalf("ParseProvenance failed: %v", err)
+		}
+		paths := make([]string, 0, len(p.Files))
+		for _, file := range p.Files {
+			paths = append(paths, file.Path)
+		}
+		if !slices.Equal(paths, logged) || !slices.IsSorted(p.Dirs) {
+			t.Errorf("Expected the manifest in sorted order, got %v and %v", paths, p.Dirs)
+		}
+	})
+
+	t.Run("layers", func(t *testing.T) {
+		parent := writeTemplate(t, map[string]string{"LICENSE": "MIT"})
+		base := writeTemplate(t, map[string]string{
+			MetadataFile:            "extends: " + parent + "\nformatters:\n  \"**/*.go\": [\"gofmt\"]\n",
+			"{{.pkg}}/main.go.tmpl": crookedGo,
+			"config.yaml.tmpl":      "db: none",
+			"README.md":             "base",
+		})
+		postgres := writeTemplate(t, map[string]string{
+			"config.yaml.tmpl":   "db: postgres",
+			"{{.pkg}}/db.go":     "package main\n",
+			"_partials/x.tmpl":   "unused",
+			"migrations/001.sql": "create table t();",
+		})
+		grpc := writeTemplate(t, map[string]string{
+			"README.md.tmpl": "{{.pkg}} with grpc",
+		})
+		var out bytes.Buffer
+		archivePath := filepath.Join(t.TempDir(), "project.tar")
+
+		err := Apply(Options{
+			TemplatePath: base,
+			Layers:       []string{postgres, grpc},
+			OutputDir:    archivePath,
+			Data:         data,
+			Out:          &out,
+		})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+
+		entries := readTarEntries(t, archivePath, false)
+		want := map[string]string{
+			"main/":              "",
+			"main/main.go":       formattedGo,
+			"main/db.go":         "package main\n",
+			"migrations/":        "",
+			"migrations/001.sql": "create table t();",
+			"config.yaml":        "db: postgres",
+			"README.md":          "main with grpc",
+			"LICENSE":            "MIT",
+		}
+		if len(entries) != len(want)+1 {
+			t.Errorf("Expected %d entries, got %v", len(want), entries)
+		}
+		for name, content := range want {
+			if entry, ok := entries[name]; !ok || entry.content != content {
+				t.Errorf("Entry '%s' = %q, want %q", name, entry.content, content)
+			}
+		}
+		for _, summary := range []string{
+			"📦 Layer " + base + ": 1 rendered, 0 copied",
+			"📦 Layer " + postgres + ": 1 rendered, 2 copied",
+			"📦 Layer " + grpc + ": 1 rendered, 0 copied",
+		} {
+			if !contains(out.String(), summary) {
+				t.Errorf("Expected summary %q in output:\n%s", summary, out.String())
+			}
+		}
+
+		// The manifest records the layer producing each file, the parent's
+		// files belonging to the template extending it.
+		p, err := ParseProvenance([]byte(entries[ProvenanceFile].content), ProvenanceFile)
+		if err != nil {
+			t.Fatalf("ParseProvenance failed: %v", err)
+		}
+		templates := make(map[string]string)
+		for _, file := range p.Files {
+			templates[file.Path] = file.Template
+		}
+		wantTemplates := map[string]string{
+			"LICENSE":            base,
+			"README.md":          grpc,
+			"config.yaml":        postgres,
+			"main/db.go":         postgres,
+			"main/main.go":       base,
+			"migrations/001.sql": postgres,
+		}
+		if !reflect.DeepEqual(templates, wantTemplates) {
+			t.Errorf("Unexpected templates of the manifest files:\ngot:  %v\nwant: %v", templates, wantTemplates)
+		}
+		layers := []string{p.Template.Path}
+		for _, l := range p.Layers {
+			layers = append(layers, l.Path)
+		}
+		if want := []string{base, postgres, grpc}; !slices.Equal(layers, want) {
+			t.Errorf("Expected the templates %v in the provenance, got %v", want, layers)
+		}
+	})
+
+	t.Run("subdir", func(t *testing.T) {
+		templateDir := writeTemplate(t, map[string]string{
+			MetadataFile: "prompts:\n  port: {type: int}\n" +
+				"ignore: [\"deploy/**/*.bak\"]\nformatters:\n  \"deploy/**/*.go\": [\"gofmt\"]\n",
+			"_partials/labels.tmpl":             "app: {{.pkg}}",
+			"deploy/k8s/x.yaml.tmpl":            "{{template \"labels.tmpl\" .}}\nport: {{.port}}",
+			"deploy/k8s/old.bak":                "ignored",
+			"deploy/{{.pkg}}/main.go.tmpl":      crookedGo,
+			"cmd/main.go.tmpl":                  "not applied",
+			"README.md":                         "not applied",
+			"deploy/_partials/unrelated.txt":    "copied, only the root partials are special",
+			"deploy/nested/subdir/keep/file.md": "nested",
+		})
+		deployData := map[string]any{"pkg": "main", "loud": true, "port": 8080}
+
+		tests := []struct {
+			name       string
+			subdir     string
+			keepPrefix bool
+			want       map[string]string
+		}{
+			{
+				name:   "relative to the subdir",
+				subdir: "deploy",
+				want: map[string]string{
+					"k8s/x.yaml":                 "app: main\nport: 8080",
+					"main/main.go":               formattedGo,
+					"_partials/unrelated.txt":    "copied, only the root partials are special",
+					"nested/subdir/keep/file.md": "nested",
+				},
+			},
+			{
+				name:       "keep prefix",
+				subdir:     "deploy/k8s/",
+				keepPrefix: true,
+				want:       map[string]string{"deploy/k8s/x.yaml": "app: main\nport: 8080"},
+			},
+		}
+		for _, tt := range tests {
+			outputDir := t.TempDir()
+			err := Apply(Options{
+				TemplatePath: templateDir,
+				OutputDir:    outputDir,
+				Data:         deployData,
+				Out:          &bytes.Buffer{},
+				Subdir:       tt.subdir,
+				KeepPrefix:   tt.keepPrefix,
+				NoProvenance: true,
+			})
+			if err != nil {
+				t.Fatalf("%s: Apply failed: %v", tt.name, err)
+			}
+			var files []string
+			_ = filepath.WalkDir(outputDir, func(path string, d os.DirEntry, _ error) error {
+				if !d.IsDir() {
+					rel, _ := filepath.Rel(outputDir, path)
+					files = append(files, filepath.ToSlash(rel))
+				}
+				return nil
+			})
+			if len(files) != len(tt.want) {
+				t.Errorf("%s: expected %d files, got %v", tt.name, len(tt.want), files)
+			}
+			for name, content := range tt.want {
+				output, err := os.ReadFile(filepath.Join(outputDir, filepath.FromSlash(name)))
+				if err != nil || string(output) != content {
+					t.Errorf("%s: file '%s' = %q, want %q (%v)", tt.name, name, output, content, err)
+				}
+			}
+		}
+
+		errTests := map[string]string{
+			"missing":        "subdirectory 'missing' not found in template",
+			"README.md":      "subdirectory 'README.md' of template",
+			"../outside":     "invalid subdirectory '../outside'",
+			"deploy/../../x": "invalid subdirectory",
+		}
+		for subdir, wantErr := range errTests {
+			outputDir := filepath.Join(t.TempDir(), "out")
+			err := Apply(Options{
+				TemplatePath: templateDir,
+				OutputDir:    outputDir,
+				Data:         deployData,
+				Out:          &bytes.Buffer{},
+				Subdir:       subdir,
+			})
+			if err == nil || !contains(err.Error(), wantErr) {
+				t.Errorf("Subdir %q: expected error containing %q, got: %v", subdir, wantErr, err)
+			}
+			if _, err = os.Stat(outputDir); !os.IsNotExist(err) {
+				t.Errorf("Subdir %q: expected no output directory, got: %v", subdir, err)
+			}
+		}
+
+		// Validation still covers the prompts of the whole template.
+		err := Apply(Options{
+			TemplatePath: templateDir,
+			OutputDir:    t.TempDir(),
+			Data:         map[string]any{"pkg": "main", "port": "http"},
+			Out:          &bytes.Buffer{},
+			Subdir:       "deploy",
+		})
+		if err == nil || !contains(err.Error(), "invalid value for 'port'") {
+			t.Errorf("Expected a validation error, got: %v", err)
+		}
+	})
+
+	t.Run("invalid layer fails before writing", func(t *testing.T) {
+		base := writeTemplate(t, map[string]string{"a.txt": "a"})
+		broken := writeTemplate(t, map[string]string{"{{.name": "x"})
+		outDir := filepath.Join(t.TempDir(), "out")
+
+		err := Apply(Options{TemplatePath: base, Layers: []string{broken}, OutputDir: outDir, Out: &bytes.Buffer{}})
+		if err == nil || !contains(err.Error(), "failed to replace placeholders") {
+			t.Errorf("Expected placeholder error, got: %v", err)
+		}
+		if _, err = os.Stat(outDir); !os.IsNotExist(err) {
+			t.Errorf("Expected no output directory, got: %v", err)
+		}
+	})
+
+	t.Run("typed prompts", func(t *testing.T) {
+		templateDir := writeTemplate(t, map[string]string{
+			MetadataFile:   "prompts:\n  port: {type: int}\n  debug: {type: bool}\n",
+			"main.go.tmpl": "port := {{printf \"%#v\" .port}} // {{if .debug}}debug{{end}}",
+		})
+		outDir := t.TempDir()
+
+		err := Apply(Options{
+			TemplatePath: templateDir,
+			OutputDir:    outDir,
+			Data:         map[string]any{"port": "8080", "debug": "false"},
+			Out:          &bytes.Buffer{},
+		})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		content, _ := os.ReadFile(filepath.Join(outDir, "main.go"))
+		if string(content) != "port := 8080 // " {
+			t.Errorf("Expected coerced values, got %q", content)
+		}
+
+		err = Apply(Options{
+			TemplatePath: templateDir,
+			OutputDir:    t.TempDir(),
+			Data:         map[string]any{"port": "http"},
+			Out:          &bytes.Buffer{},
+		})
+		if err == nil || !contains(err.Error(), `invalid data: invalid value for 'port': expected int, got string "http"`) {
+			t.Errorf("Expected type error, got: %v", err)
+		}
+	})
+
+	t.Run("secret values are masked", func(t *testing.T) {
+		const secret = "s3cr3tV4lue"
+		templateDir := writeTemplate(t, map[string]string{
+			MetadataFile: "formatters:\n  \"*.go\": [\"gofmt\"]\n" +
+				"prompts:\n  api_key: {type: string, secret: true}\n  pin: {type: int, secret: true}\n",
+			"{{.api_key}}.go.tmpl": "package main\n{{.api_key}}\n",
+		})
+
+		var out bytes.Buffer
+		err := Apply(Options{
+			TemplatePath: templateDir,
+			OutputDir:    t.TempDir(),
+			Data:         map[string]any{"api_key": secret},
+			Strict:       true,
+			Out:          &out,
+		})
+		if err == nil || !contains(err.Error(), "formatter 'gofmt' failed on '******.go'") {
+			t.Errorf("Expected masked formatter error, got: %v", err)
+		}
+		if !contains(out.String(), "-> ******.go") {
+			t.Errorf("Expected masked rendering log, got:\n%s", out.String())
+		}
+		if contains(err.Error(), secret) || contains(out.String(), secret) {
+			t.Errorf("Secret value leaked:\n%v\n%s", err, out.String())
+		}
+
+		err = Apply(Options{
+			TemplatePath: templateDir,
+			OutputDir:    t.TempDir(),
+			Data:         map[string]any{"api_key": secret, "pin": "12ab"},
+			Out:          &bytes.Buffer{},
+		})
+		if err == nil || !contains(err.Error(), "invalid value for 'pin': expected int, got ******") ||
+			contains(err.Error(), "12ab") {
+			t.Errorf("Expected masked validation error, got: %v", err)
+		}
+	})
+
+	t.Run("failed archive output leaves nothing behind", func(t *testing.T) {
+		templateDir := writeTemplate(t, map[string]string{
+			"a.txt":      "first",
+			"b.txt.tmpl": "{{.missing.key}}",
+			"c.txt":      "last",
+		})
+		outDir := t.TempDir()
+
+		err := Apply(Options{
+			TemplatePath: templateDir,
+			OutputDir:    filepath.Join(outDir, "project.zip"),
+			Data:         map[string]any{"missing": "scalar"},
+			Out:          &bytes.Buffer{},
+		})
+		if err == nil {
+			t.Fatal("Expected render error")
+		}
+
+		leftovers, err := os.ReadDir(outDir)
+		if err != nil {
+			t.Fatalf("Failed to read output directory: %v", err)
+		}
+		if len(leftovers) != 0 {
+			t.Errorf("Expected no partial archive, got %v", leftovers)
+		}
+	})
+
+	t.Run("placeholders expand to nested directories", func(t *testing.T) {
+		templateDir := writeTemplate(t, map[string]string{
+			"{{.pkg}}.go.tmpl": "package {{.name}}",
+			"{{.pkg}}_test.go": "test",
+		})
+		data := map[string]any{"pkg": "cmd/app/main", "name": "main"}
+
+		outDir := filepath.Join(t.TempDir(), "out")
+		err := Apply(Options{TemplatePath: templateDir, OutputDir: outDir, Data: data, Out: io.Discard})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		files := readFiles(t, outDir)
+		if files["cmd/app/main.go"] != "package main" || files["cmd/app/main_test.go"] != "test" {
+			t.Errorf("Expected the files in nested directories, got %v", files)
+		}
+		info, err := os.Stat(filepath.Join(outDir, "cmd", "app"))
+		if err != nil || info.Mode().Perm() != 0750 {
+			t.Errorf("Expected the directories created with mode 0750, got %v (%v)", info, err)
+		}
+
+		dryDir := filepath.Join(t.TempDir(), "dry")
+		var out bytes.Buffer
+		err = Apply(Options{TemplatePath: templateDir, OutputDir: dryDir, Data: data, Out: &out, DryRun: true})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		if !contains(out.String(), "cmd/app/main.go") {
+			t.Errorf("Expected the dry run to list the nested file, got:\n%s", out.String())
+		}
+		if _, err = os.Stat(dryDir); !os.IsNotExist(err) {
+			t.Errorf("Expected a dry run to create no directories, got %v", err)
+		}
+	})
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		templateDir := writeTemplate(t, map[string]string{MetadataFile: "formatters: ["})
+
+		err := Apply(Options{TemplatePath: templateDir, OutputDir: t.TempDir(), Out: &bytes.Buffer{}})
+		if err == nil || !contains(err.Error(), "failed to parse template metadata") {
+			t.Errorf("Expected metadata error, got: %v", err)
+		}
+	})
+}
+
+func TestTemplateName(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   string
+		render bool
+		err    string
+	}{
+		{name: "main.go.tmpl", want: "main.go", render: true},
+		{name: "main.tmpl.go", want: "main.go", render: true},
+		{name: "docker-compose.tmpl.prod.yaml", want: "docker-compose.prod.yaml", render: true},
+		{name: "page.html.tmpl.tmpl", want: "page.html.tmpl", render: true},
+		{name: "main.go", want: "main.go"},
+		{name: "tmpl.go", want: "tmpl.go"},
+		{name: "main.tmplx.go", want: "main.tmplx.go"},
+		{name: "a.tmpl.b.tmpl.go", err: "ambiguous template file name 'a.tmpl.b.tmpl.go'"},
+	}
+	for _, tt := range tests {
+		got, render, err := templateName(tt.name)
+		if tt.err != "" {
+			if err == nil || !contains(err.Error(), tt.err) {
+				t.Errorf("templateName(%q): expected %q, got %v", tt.name, tt.err, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want || render != tt.render {
+			t.Errorf("templateName(%q) = %q, %v, %v, want %q, %v", tt.name, got, render, err, tt.want, tt.render)
+		}
+	}
+}
+
+func TestApplyTemplateMarker(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		"main.tmpl.go":          "package {{.name}}\n",
+		"cmd/{{.name}}.go.tmpl": "// {{.name}}\n",
+		"notes.txt":             "{{.name}}\n",
+	})
+	data := map[string]any{"name": "app"}
+	outputDir := t.TempDir()
+	var out bytes.Buffer
+	err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, NoProvenance: true, Out: &out})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	want := map[string]string{"main.go": "package app\n", "cmd/app.go": "// app\n", "notes.txt": "{{.name}}\n"}
+	if files := readFiles(t, outputDir); !reflect.DeepEqual(files, want) {
+		t.Errorf("Expected %q, got %q", want, files)
+	}
+
+	out.Reset()
+	err = Apply(Options{TemplatePath: templateDir, OutputDir: t.TempDir(), Data: data, DryRun: true, Out: &out})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	for _, line := range []string{
+		"✨ Rendering: main.tmpl.go -> main.go\n", "✨ Rendering: cmd/app.go.tmpl -> cmd/app.go\n",
+	} {
+		if !contains(out.String(), line) {
+			t.Errorf("Expected %q in the dry run, got:\n%s", line, out.String())
+		}
+	}
+
+	errorTests := map[string]map[string]string{
+		"ambiguous template file name 'a.tmpl.b.tmpl.go'":           {"a.tmpl.b.tmpl.go": ""},
+		"'main.go.tmpl' and 'main.tmpl.go' both generate 'main.go'": {"main.go.tmpl": "", "main.tmpl.go": ""},
+		"'main.go' and 'main.go.tmpl' both generate 'main.go'":      {"main.go": "", "main.go.tmpl": ""},
+	}
+	for want, files := range errorTests {
+		err = Apply(Options{TemplatePath: writeTemplate(t, files), OutputDir: t.TempDir(), Out: io.Discard})
+		if err == nil || !contains(err.Error(), want) {
+			t.Errorf("Expected %q, got: %v", want, err)
+		}
+	}
+
+	// A later layer still replaces the file of an earlier one.
+	layer := writeTemplate(t, map[string]string{"main.go": "package layer\n"})
+	outputDir = t.TempDir()
+	err = Apply(Options{TemplatePath: templateDir, Layers: []string{layer}, OutputDir: outputDir, Data: data,
+		Out: io.Discard})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(outputDir, "main.go")); string(content) != "package layer\n" {
+		t.Errorf("Expected the layer to replace main.go, got %q", content)
+	}
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// MaxAskAttempts is how many answers Asker reads for a value before giving
+// up on one its prompt keeps rejecting.
+const MaxAskAttempts = 3
+
+// Asker asks the user for the values missing from the data, writing the
+// questions to an output and reading the answers from an input, one line
+// each.
+type Asker struct {
+	out    io.Writer
+	reader *bufio.Reader
+}
+
+// NewAsker returns an asker writing its questions to out and reading the
+// answers from in.
+func NewAsker(in io.Reader, out io.Writer) *Asker {
+	return &Asker{out: out, reader: bufio.NewReader(in)}
+}
+
+// AskMissing asks, in order, for the value of every prompt missing from
+// data whose when expression holds, then for the referenced keys missing
+// from data that no prompt declares, such as the placeholders of a template
+// without prompts. Keys listed in skip, such as computed values, are never
+// asked. The answers are coerced and checked like the data, and stored in
+// data. It returns the keys it asked for.
+func (a *Asker) AskMissing(prompts Prompts, referenced, skip []string, data map[string]any,
+	funcs template.FuncMap) ([]string, error) {
+	var asked []string
+	declared := make(map[string]bool)
+	for _, prompt := range prompts {
+		name, _, _ := strings.Cut(prompt.Name, ".")
+		declared[name] = true
+		if _, ok := LookupValue(data, prompt.Name); ok || slices.Contains(skip, prompt.Name) {
+			continue
+		}
+		on, err := prompt.enabled(data, funcs)
+		if err != nil {
+			return nil, err
+		}
+		if !on {
+			continue
+		}
+		if err = a.ask(prompt, data); err != nil {
+			return nil, err
+		}
+		asked = append(asked, prompt.Name)
+	}
+	for _, key := range referenced {
+		if _, ok := data[key]; ok || declared[key] || slices.Contains(skip, key) {
+			continue
+		}
+		if err := a.ask(Prompt{Name: key}, data); err != nil {
+			return nil, err
+		}
+		asked = append(asked, key)
+	}
+	return asked, nil
+}
+
+// ask asks for the value of one prompt and stores it in data. An answer the
+// prompt rejects asks again, up to MaxAskAttempts times.
+func (a *Asker) ask(prompt Prompt, data map[string]any) error {
+	if prompt.Type == TypeMap {
+		return fmt.Errorf("'%s' is a map, which can't be asked for: give it in the data", prompt.Name)
+	}
+	question := prompt.Name
+	if prompt.Description != "" {
+		question += " (" + prompt.Description + ")"
+	}
+	if prompt.hasChoices() || prompt.Type == TypeConfirm {
+		question += " [" + prompt.expected() + "]"
+	}
+	for range MaxAskAttempts {
+		fmt.Fprintf(a.out, "❓ %s: ", question)
+		line, err := a.reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read the value of '%s': %w", prompt.Name, err)
+		}
+		if errors.Is(err, io.EOF) && line == "" {
+			fmt.Fprintln(a.out)
+			break
+		}
+		value, err := prompt.answer(strings.TrimSpace(line))
+		if err == nil {
+			if errs := prompt.checkRules(value); len(errs) > 0 {
+				err = errors.Join(errs...)
+			}
+		}
+		if err == nil {
+			return SetValue(data, prompt.Name, value)
+		}
+		fmt.Fprintf(a.out, "⚠️  %v\n", err)
+	}
+	return fmt.Errorf("no valid value given for '%s'", prompt.Name)
+}
+
+// answer converts a typed answer to a value of the prompt. Lists are given
+// as comma-separated values.
+func (p Prompt) answer(text string) (any, error) {
+	switch p.Type {
+	case "", TypeString:
+		return text, nil
+	case TypeList:
+		values := []any{}
+		for item := range strings.SplitSeq(text, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		return values, nil
+	}
+	value, err := p.coerce(text)
+	if err != nil {
+		if p.Secret {
+			return nil, fmt.Errorf("invalid value for '%s': expected %s", p.Name, p.expected())
+		}
+		return nil, fmt.Errorf("invalid value for '%s': %w", p.Name, err)
+	}
+	return value, nil
+}
+
+// askMissing asks for the values of the prompts and the keys referenced by
+// the layers that the data doesn't have. Computed values and derived cases
+// aren't asked, they come from the rest of the data.
+func (a *applier) askMissing(data map[string]any, prompts Prompts, computed map[string]string, deriveCases bool,
+	funcs template.FuncMap) error {
+	refs, err := a.references()
+	if err != nil {
+		return err
+	}
+	referenced := refs.keys()
+	skip := slices.Collect(maps.Keys(computed))
+	if deriveCases {
+		known := make(map[string]bool)
+		for _, key := range slices.Concat(referenced, slices.Collect(maps.Keys(data))) {
+			known[key] = true
+		}
+		for _, prompt := range prompts {
+			known[prompt.Name] = true
+		}
+		for _, key := range referenced {
+			if _, ok := IsDerivedCase(key, known); ok {
+				skip = append(skip, key)
+			}
+		}
+	}
+	asked, err := a.opts.Ask.AskMissing(prompts, referenced, skip, data, funcs)
+	if err != nil {
+		return err
+	}
+	if len(asked) > 0 {
+		fmt.Fprintf(a.out, "🙋 Asked for: %s\n", strings.Join(asked, ", "))
+	}
+	return nil
+}
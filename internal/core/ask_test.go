@@ -0,0 +1,113 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestAskMissing(t *testing.T) {
+	var prompts Prompts
+	err := yaml.Unmarshal([]byte(`
+name: {description: Project name, pattern: '^[a-z-]+$'}
+port: {type: int, max: 65535}
+database: {type: enum, choices: [postgres, sqlite]}
+db_user: {when: '{{eq .database "postgres"}}'}
+db_file: {when: '{{eq .database "sqlite"}}'}
+tags: {type: list}
+ci: {type: confirm}
+`), &prompts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]any{"ci": true, "author": "Ann"}
+	// The name and the port are rejected once each.
+	answers := "My App\nmy-app\n99999\n8080\nsqlite\nmain.db\ngo, cli\nversion\n"
+	var out bytes.Buffer
+	asker := NewAsker(strings.NewReader(answers), &out)
+	asked, err := asker.AskMissing(prompts, []string{"author", "name", "service", "version"}, []string{"service"},
+		data, helperFunc)
+	if err != nil {
+		t.Fatalf("AskMissing failed: %v\n%s", err, out.String())
+	}
+	want := map[string]any{
+		"name": "my-app", "port": 8080, "database": "sqlite", "db_file": "main.db", "tags": []any{"go", "cli"},
+		"ci": true, "author": "Ann", "version": "version",
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("Expected data %v, got %v", want, data)
+	}
+	wantAsked := []string{"name", "port", "database", "db_file", "tags", "version"}
+	if !reflect.DeepEqual(asked, wantAsked) {
+		t.Errorf("Expected to ask for %q, got %q", wantAsked, asked)
+	}
+	for _, line := range []string{
+		"❓ name (Project name): ",
+		"⚠️  invalid value for 'name': must match '^[a-z-]+$' (pattern), got \"My App\"\n",
+		"⚠️  invalid value for 'port': must be at most 65535 (max), got 99999\n",
+		"❓ database [one of [postgres sqlite]]: ",
+	} {
+		if !contains(out.String(), line) {
+			t.Errorf("Expected %q in the output:\n%s", line, out.String())
+		}
+	}
+
+	// Running out of answers fails, and so do answers the prompt keeps
+	// rejecting.
+	for _, answers := range []string{"", "x\ny\nz\n1\n"} {
+		asker = NewAsker(strings.NewReader(answers), &out)
+		_, err = asker.AskMissing(Prompts{{Name: "port", Type: TypeInt}}, nil, nil, map[string]any{}, helperFunc)
+		if err == nil || !contains(err.Error(), "no valid value given for 'port'") {
+			t.Errorf("Expected %q to fail, got: %v", answers, err)
+		}
+	}
+	asker = NewAsker(strings.NewReader("x\n"), &out)
+	_, err = asker.AskMissing(Prompts{{Name: "labels", Type: TypeMap}}, nil, nil, map[string]any{}, helperFunc)
+	if err == nil || !contains(err.Error(), "'labels' is a map, which can't be asked for") {
+		t.Errorf("Expected a map prompt to fail, got: %v", err)
+	}
+}
+
+func TestApplyAsk(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile: "prompts:\n  name: {}\n  token: {secret: true}\n" +
+			"computed:\n  module: 'example.com/{{.name}}'\n" +
+			"defaults:\n  license: MIT\n",
+		"README.md.tmpl": "{{.name}} {{.module}} {{.license}} {{.token}} {{.owner}}",
+	})
+	outputDir := t.TempDir()
+	var out, questions bytes.Buffer
+	err := Apply(Options{
+		TemplatePath: templateDir,
+		OutputDir:    outputDir,
+		Data:         map[string]any{"name": "app"},
+		Out:          &out,
+		Ask:          NewAsker(strings.NewReader("s3cr3t\nann\n"), &questions),
+	})
+	if err != nil {
+		t.Fatalf("Apply failed: %v\n%s", err, out.String())
+	}
+	content, err := os.ReadFile(filepath.Join(outputDir, "README.md"))
+	if err != nil || string(content) != "app example.com/app MIT s3cr3t ann" {
+		t.Errorf("Expected the answers to be rendered, got %q, %v", content, err)
+	}
+	if questions.String() != "❓ token: ❓ owner: " {
+		t.Errorf("Expected the missing values only to be asked, got %q", questions.String())
+	}
+	if !contains(out.String(), "🙋 Asked for: token, owner\n") {
+		t.Errorf("Expected the asked keys in the output:\n%s", out.String())
+	}
+	// The answers are recorded, so a later run has them.
+	provenance, err := LoadProvenance(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provenance.Data["owner"] != "ann" || provenance.Data["token"] != SecretMask {
+		t.Errorf("Expected the answers in the provenance, got %v", provenance.Data)
+	}
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/0m3kk/mold/internal/utils"
+)
+
+// BackupDir holds the backups of the runs that keep one by default, each in
+// a directory named after the time of the run.
+const BackupDir = StateDir + "/backup"
+
+// DefaultBackupDir returns the backup directory of a run at t in the output
+// directory.
+func DefaultBackupDir(outputDir string, t time.Time) string {
+	return filepath.Join(outputDir, filepath.FromSlash(BackupDir), t.UTC().Format("20060102T150405Z"))
+}
+
+// checkBackup validates the options of a run keeping a backup.
+func (a *applier) checkBackup() error {
+	if a.opts.BackupDir != "" && (a.opts.Sink != nil || ArchiveFormat(a.opts.OutputDir) != "") {
+		return errors.New("backing up needs an output directory, not an archive or a stream")
+	}
+	return nil
+}
+
+// backup copies the file at the output path, if there is one, into the
+// backup directory before it is overwritten or pruned. In a dry run, it is
+// only counted.
+func (a *applier) backup(relPath string) error {
+	if a.opts.BackupDir == "" {
+		return nil
+	}
+	src := filepath.Join(a.opts.OutputDir, relPath)
+	info, err := os.Lstat(src)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to back up '%s': %w", relPath, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	a.backedUp++
+	if a.opts.DryRun {
+		return nil
+	}
+	dst := filepath.Join(a.opts.BackupDir, relPath)
+	if err = os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return fmt.Errorf("failed to create directory for '%s': %w", dst, err)
+	}
+	if err = utils.CopyFileAtomic(src, dst); err != nil {
+		return fmt.Errorf("failed to back up '%s': %w", relPath, err)
+	}
+	return nil
+}
+
+// reportBackup says how many files were backed up, and where.
+func (a *applier) reportBackup() {
+	switch {
+	case a.opts.BackupDir == "":
+	case a.backedUp == 0:
+		fmt.Fprintln(a.out, "💾 No existing files to back up")
+	case a.opts.DryRun:
+		fmt.Fprintf(a.out, "💾 Would back up %d files to: %s\n", a.backedUp, a.opts.BackupDir)
+	default:
+		fmt.Fprintf(a.out, "💾 Backed up %d files to: %s\n", a.backedUp, a.opts.BackupDir)
+	}
+}
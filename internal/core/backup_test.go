@@ -0,0 +1,88 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestApplyBackup(t *testing.T) {
+	v1 := writeTemplate(t, map[string]string{"run.sh": "#!/bin/sh\n", "gone.txt": "gone", "sub/a.txt": "a"})
+	v2 := writeTemplate(t, map[string]string{"run.sh": "#!/bin/sh\necho v2\n", "sub/a.txt": "a", "new.txt": "new"})
+	outputDir := t.TempDir()
+	if err := Apply(Options{TemplatePath: v1, OutputDir: outputDir, Out: &bytes.Buffer{}}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	runPath := filepath.Join(outputDir, "run.sh")
+	if err := os.WriteFile(runPath, []byte("#!/bin/sh\n# mine\n"), 0644); err != nil {
+		t.Fatalf("Failed to edit: %v", err)
+	}
+	if err := os.Chmod(runPath, 0750); err != nil {
+		t.Fatalf("Failed to chmod: %v", err)
+	}
+	before := readFiles(t, outputDir)
+
+	backupDir := DefaultBackupDir(outputDir, time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC))
+	if want := filepath.Join(outputDir, ".mold", "backup", "20240501T123000Z"); backupDir != want {
+		t.Errorf("Expected the default backup directory %s, got %s", want, backupDir)
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		var out bytes.Buffer
+		opts := Options{TemplatePath: v2, OutputDir: outputDir, Prune: true, BackupDir: backupDir, DryRun: true, Out: &out}
+		if err := Apply(opts); err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		if !contains(out.String(), "💾 Would back up 4 files to: "+backupDir) {
+			t.Errorf("Expected the backup to be reported:\n%s", out.String())
+		}
+		if _, err := os.Stat(backupDir); !os.IsNotExist(err) {
+			t.Errorf("Expected a dry run to back up nothing, got: %v", err)
+		}
+	})
+
+	t.Run("backup", func(t *testing.T) {
+		var out bytes.Buffer
+		opts := Options{TemplatePath: v2, OutputDir: outputDir, Prune: true, BackupDir: backupDir, Out: &out}
+		if err := Apply(opts); err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		if !contains(out.String(), "💾 Backed up 4 files to: "+backupDir) {
+			t.Errorf("Expected the backup to be reported:\n%s", out.String())
+		}
+		want := map[string]string{
+			"run.sh":       before["run.sh"],
+			"gone.txt":     before["gone.txt"],
+			"sub/a.txt":    before["sub/a.txt"],
+			ProvenanceFile: before[ProvenanceFile],
+		}
+		if files := readFiles(t, backupDir); !reflect.DeepEqual(files, want) {
+			t.Errorf("Unexpected backup:\ngot:  %q\nwant: %q", files, want)
+		}
+		if info, err := os.Stat(filepath.Join(backupDir, "run.sh")); err != nil || info.Mode().Perm() != 0750 {
+			t.Errorf("Expected the backup to keep the mode, got %v: %v", info.Mode(), err)
+		}
+	})
+
+	t.Run("nothing to back up", func(t *testing.T) {
+		var out bytes.Buffer
+		err := Apply(Options{TemplatePath: v2, OutputDir: t.TempDir(), BackupDir: t.TempDir(), Out: &out})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		if !contains(out.String(), "💾 No existing files to back up") {
+			t.Errorf("Expected an empty backup to be reported:\n%s", out.String())
+		}
+	})
+
+	t.Run("archive", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "out.tar")
+		err := Apply(Options{TemplatePath: v2, OutputDir: output, BackupDir: t.TempDir(), Out: &bytes.Buffer{}})
+		if err == nil || !contains(err.Error(), "backing up needs an output directory") {
+			t.Errorf("Expected an error backing up an archive, got: %v", err)
+		}
+	})
+}
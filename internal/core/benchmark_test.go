@@ -0,0 +1,57 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+// benchmarkFiles is the number of files of the synthetic templates.
+const benchmarkFiles = 5000
+
+// benchmarkApply applies a template to a sink discarding the files.
+func benchmarkApply(b *testing.B, templateDir string, data map[string]any) {
+	b.Helper()
+	outputDir := b.TempDir()
+	b.ReportAllocs()
+	for b.Loop() {
+		err := Apply(Options{
+			TemplatePath: templateDir, OutputDir: outputDir, Sink: discardSink{}, Data: data, Out: io.Discard,
+			NoFormat: true, NoProvenance: true, NoWarnUnused: true,
+		})
+		if err != nil {
+			b.Fatalf("Apply failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkApplyManySmallFiles renders many small files including a
+// partial, where parsing outweighs executing.
+func BenchmarkApplyManySmallFiles(b *testing.B) {
+	files := map[string]string{
+		MetadataFile:                        "acronyms: [API, HTTP]\n",
+		PartialsDir + "/header.tmpl":        "// Code for {{.name}}. {{template \"license.tmpl\" .}}\n",
+		PartialsDir + "/license.tmpl":       "Licensed under {{.license}}.",
+		PartialsDir + "/unused/footer.tmpl": "{{define \"footer\"}}// {{kebab .name}}{{end}}",
+	}
+	for i := range benchmarkFiles {
+		files[fmt.Sprintf("pkg%02d/file%04d.go.tmpl", i%50, i)] =
+			"{{template \"header.tmpl\" .}}package {{snake .name}}\n\nconst Name = \"{{camel .name}}\"\n"
+	}
+	templateDir := writeTemplate(b, files)
+	benchmarkApply(b, templateDir, map[string]any{"name": "http api server", "license": "MIT"})
+}
+
+// BenchmarkDeepTemplatedPaths copies files deep under templated
+// directories, where rendering the paths outweighs the rest.
+func BenchmarkDeepTemplatedPaths(b *testing.B) {
+	files := make(map[string]string, benchmarkFiles)
+	for i := range benchmarkFiles {
+		files[fmt.Sprintf("{{.org}}/__kebab_project__/services/{{.service}}/d%d/{{.layer}}%d/file%04d.txt",
+			i%10, i/10%10, i)] = "content"
+	}
+	templateDir := writeTemplate(b, files)
+	benchmarkApply(b, templateDir, map[string]any{
+		"org": "acme", "project": "Billing Service", "service": "invoices", "layer": "domain",
+	})
+}
@@ -0,0 +1,199 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultAcronyms are the acronyms the case helpers keep as units, spelled as
+// camel and title case write them. Templates add theirs with the acronyms
+// list of template.yaml.
+//
+//nolint:gochecknoglobals // read-only list of acronyms
+var DefaultAcronyms = []string{
+	"API", "CPU", "CSS", "DNS", "gRPC", "HTML", "HTTP", "HTTPS", "ID", "IP", "JSON", "JWT", "SQL", "SSH",
+	"TCP", "TLS", "UDP", "UI", "URI", "URL", "UUID", "XML", "YAML",
+}
+
+// Casing converts strings between case styles. Every style splits the
+// string into the same words, so the helpers agree with each other: a known
+// acronym is one word, whatever the case it is written in, so "user_id",
+// "userID" and "UserId" all give user_id and UserID.
+type Casing struct {
+	// acronyms maps the lowercase acronyms to their spelling.
+	acronyms map[string]string
+}
+
+// NewCasing returns a casing knowing DefaultAcronyms and the given ones. A
+// given acronym also replaces the spelling of a default one, so "Grpc"
+// overrides gRPC.
+func NewCasing(acronyms []string) *Casing {
+	c := &Casing{acronyms: make(map[string]string, len(DefaultAcronyms)+len(acronyms))}
+	for _, acronym := range slices.Concat(DefaultAcronyms, acronyms) {
+		c.acronyms[strings.ToLower(acronym)] = acronym
+	}
+	return c
+}
+
+// checkAcronym fails unless the acronym is made of letters and digits only.
+func checkAcronym(acronym string) error {
+	if acronym == "" || strings.IndexFunc(acronym, func(r rune) bool { return !isAlnum(r) }) >= 0 {
+		return fmt.Errorf("invalid acronym '%s': expected letters and digits only", acronym)
+	}
+	return nil
+}
+
+// word is one word of a string split by a Casing.
+type word struct {
+	text    string
+	acronym bool
+}
+
+// words splits s into words. Anything but a letter or a digit separates
+// words, and so does a change of case: "HTTPServerURL" gives HTTP, Server
+// and URL. Digits stick to the letters before them. Words spelling a known
+// acronym are then joined, so "gRPC" and "OAuth2" stay single words when
+// they are known.
+func (c *Casing) words(s string) []word {
+	var words []word
+	for _, chunk := range strings.FieldsFunc(s, func(r rune) bool { return !isAlnum(r) }) {
+		parts := splitCase(chunk)
+		for i := 0; i < len(parts); {
+			n, spelling := c.matchAcronym(parts[i:])
+			if n == 0 {
+				words = append(words, word{text: parts[i]})
+				i++
+				continue
+			}
+			words = append(words, word{text: spelling, acronym: true})
+			i += n
+		}
+	}
+	return words
+}
+
+// matchAcronym returns how many of the leading parts spell the longest known
+// acronym, and its spelling, or 0 when they don't spell one.
+func (c *Casing) matchAcronym(parts []string) (int, string) {
+	for n := len(parts); n > 0; n-- {
+		if spelling, ok := c.acronyms[strings.ToLower(strings.Join(parts[:n], ""))]; ok {
+			return n, spelling
+		}
+	}
+	return 0, ""
+}
+
+// splitCase splits a string of letters and digits where its case changes:
+// before an upper case letter following a lower case letter or a digit, and
+// before the last letter of a run of upper case letters followed by a lower
+// case one.
+func splitCase(s string) []string {
+	runes := []rune(s)
+	var parts []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		if !unicode.IsUpper(cur) {
+			continue
+		}
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+			(unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			parts = append(parts, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(parts, string(runes[start:]))
+}
+
+// join converts the words of s with convert, which gets the index of the
+// word, and joins them with sep.
+func (c *Casing) join(s, sep string, convert func(i int, w word) string) string {
+	words := c.words(s)
+	converted := make([]string, len(words))
+	for i, w := range words {
+		converted[i] = convert(i, w)
+	}
+	return strings.Join(converted, sep)
+}
+
+// Snake converts s to snake_case: "HTTPServer" gives http_server.
+func (c *Casing) Snake(s string) string {
+	return c.join(s, "_", func(_ int, w word) string { return strings.ToLower(w.text) })
+}
+
+// UpperSnake converts s to UPPER_SNAKE_CASE: "HTTPServer" gives
+// HTTP_SERVER.
+func (c *Casing) UpperSnake(s string) string {
+	return c.join(s, "_", func(_ int, w word) string { return strings.ToUpper(w.text) })
+}
+
+// Kebab converts s to kebab-case: "HTTPServer" gives http-server.
+func (c *Casing) Kebab(s string) string {
+	return c.join(s, "-", func(_ int, w word) string { return strings.ToLower(w.text) })
+}
+
+// Camel converts s to UpperCamelCase, writing acronyms in capitals:
+// "user_id" gives UserID and "grpc_server" GRPCServer.
+func (c *Casing) Camel(s string) string {
+	return c.join(s, "", func(_ int, w word) string { return camelWord(w) })
+}
+
+// LowerCamel converts s to lowerCamelCase: "HTTPServer" gives httpServer
+// and "user_id" userID.
+func (c *Casing) LowerCamel(s string) string {
+	return c.join(s, "", func(i int, w word) string {
+		if i == 0 {
+			return strings.ToLower(w.text)
+		}
+		return camelWord(w)
+	})
+}
+
+// Title converts s to Title Case, writing acronyms with their spelling:
+// "grpc_server_url" gives gRPC Server URL.
+func (c *Casing) Title(s string) string {
+	return c.join(s, " ", func(_ int, w word) string {
+		if w.acronym {
+			return w.text
+		}
+		return upperFirst(strings.ToLower(w.text))
+	})
+}
+
+// funcs returns the case helpers of templates.
+func (c *Casing) funcs() template.FuncMap {
+	return template.FuncMap{
+		"snake":  c.Snake,
+		"usnake": c.UpperSnake,
+		"kebab":  c.Kebab,
+		"camel":  c.Camel,
+		"lcamel": c.LowerCamel,
+		"title":  c.Title,
+	}
+}
+
+// camelWord writes a word of an UpperCamelCase string.
+func camelWord(w word) string {
+	if w.acronym {
+		return upperFirst(w.text)
+	}
+	return upperFirst(strings.ToLower(w.text))
+}
+
+// upperFirst returns s with its first letter in upper case.
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// isAlnum reports whether r is a letter or a digit.
+func isAlnum(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
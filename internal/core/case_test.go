@@ -0,0 +1,82 @@
+package core
+
+import (
+	"io"
+	"testing"
+)
+
+func TestCasing(t *testing.T) {
+	casing := NewCasing([]string{"K8s", "OAuth2"})
+	helpers := casing.funcs()
+	names := []string{"snake", "usnake", "kebab", "camel", "lcamel", "title"}
+
+	tests := []struct {
+		input string
+		// want holds the results of the helpers, in the order of names.
+		want []string
+	}{
+		{"HTTPServerURL", []string{"http_server_url", "HTTP_SERVER_URL", "http-server-url", "HTTPServerURL",
+			"httpServerURL", "HTTP Server URL"}},
+		{"user_id", []string{"user_id", "USER_ID", "user-id", "UserID", "userID", "User ID"}},
+		{"grpc-gateway", []string{"grpc_gateway", "GRPC_GATEWAY", "grpc-gateway", "GRPCGateway", "grpcGateway",
+			"gRPC Gateway"}},
+		{"someVariableName", []string{"some_variable_name", "SOME_VARIABLE_NAME", "some-variable-name",
+			"SomeVariableName", "someVariableName", "Some Variable Name"}},
+		{"oauth2_token", []string{"oauth2_token", "OAUTH2_TOKEN", "oauth2-token", "OAuth2Token", "oauth2Token",
+			"OAuth2 Token"}},
+		{"K8sClusterID", []string{"k8s_cluster_id", "K8S_CLUSTER_ID", "k8s-cluster-id", "K8sClusterID",
+			"k8sClusterID", "K8s Cluster ID"}},
+		{"v2Api", []string{"v2_api", "V2_API", "v2-api", "V2API", "v2API", "V2 API"}},
+		{"Identity", []string{"identity", "IDENTITY", "identity", "Identity", "identity", "Identity"}},
+		{"my  project", []string{"my_project", "MY_PROJECT", "my-project", "MyProject", "myProject",
+			"My Project"}},
+		{"", []string{"", "", "", "", "", ""}},
+	}
+	for _, tt := range tests {
+		for i, name := range names {
+			convert := helpers[name].(func(string) string)
+			if got := convert(tt.input); got != tt.want[i] {
+				t.Errorf("%s %q: expected %q, got %q", name, tt.input, tt.want[i], got)
+			}
+		}
+	}
+
+	// Converting the result of any helper gives what converting the input
+	// does, so the helpers agree on the words.
+	for _, tt := range tests {
+		for _, outer := range names {
+			convert := helpers[outer].(func(string) string)
+			for _, inner := range names {
+				converted := helpers[inner].(func(string) string)(tt.input)
+				if got, want := convert(converted), convert(tt.input); got != want {
+					t.Errorf("%s(%s %q): expected %q, got %q", outer, inner, tt.input, want, got)
+				}
+			}
+		}
+	}
+
+	t.Run("default acronyms", func(t *testing.T) {
+		if got := defaultCasing.Camel("oauth2_token"); got != "Oauth2Token" {
+			t.Errorf("Expected %q, got %q", "Oauth2Token", got)
+		}
+		if got := NewCasing([]string{"Grpc"}).Title("grpc_server"); got != "Grpc Server" {
+			t.Errorf("Expected a template acronym to replace the default spelling, got %q", got)
+		}
+	})
+}
+
+func TestApplyAcronyms(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile:              "acronyms: [OAuth2]\n",
+		"{{camel .name}}.go.tmpl": "type {{camel .name}} struct{} // {{title .name}}",
+	})
+	outputDir := t.TempDir()
+	data := map[string]any{"name": "oauth2_client"}
+	if err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: io.Discard}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	files := readFiles(t, outputDir)
+	if got, want := files["OAuth2Client.go"], "type OAuth2Client struct{} // OAuth2 Client"; got != want {
+		t.Errorf("Expected %q, got %q in %v", want, got, files)
+	}
+}
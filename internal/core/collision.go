@@ -0,0 +1,122 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Collision is a group of generated paths only differing by case, which a
+// case-insensitive filesystem, the default on macOS and Windows, makes one
+// path, losing all but one of the files.
+type Collision struct {
+	// Paths are the slash-separated paths relative to the output root, and
+	// Sources the template paths generating them, in the same order. The
+	// source of a directory the template doesn't have is the first file
+	// generated in it.
+	Paths   []string `json:"paths"`
+	Sources []string `json:"sources"`
+}
+
+// String returns the paths of the collision with their sources.
+func (c Collision) String() string {
+	paths := make([]string, len(c.Paths))
+	for i, p := range c.Paths {
+		paths[i] = fmt.Sprintf("%s (%s)", p, c.Sources[i])
+	}
+	return strings.Join(paths, ", ")
+}
+
+// findCollisions returns the groups of planned paths only differing by
+// case, the directories holding them included, sorted. A group only holding
+// directories isn't one: their files end up in the same directory, and
+// collide there when their names do too.
+func findCollisions(entries []entry) []Collision {
+	// sources maps each path to its source, by folded path.
+	sources := make(map[string]map[string]string)
+	dirs := make(map[string]bool)
+	add := func(key, source string, dir bool) {
+		folded := strings.ToLower(key)
+		if sources[folded] == nil {
+			sources[folded] = make(map[string]string)
+		}
+		if _, ok := sources[folded][key]; !ok {
+			sources[folded][key] = source
+		}
+		if dir {
+			dirs[key] = true
+		}
+	}
+	for _, e := range entries {
+		key := filepath.ToSlash(e.rel)
+		if key == "." {
+			continue
+		}
+		add(key, filepath.ToSlash(e.source()), e.kind == entryDir)
+		for dir := path.Dir(key); dir != "."; dir = path.Dir(dir) {
+			add(dir, filepath.ToSlash(e.source()), true)
+		}
+	}
+
+	var collisions []Collision
+	for _, folded := range slices.Sorted(maps.Keys(sources)) {
+		group := sources[folded]
+		paths := slices.Sorted(maps.Keys(group))
+		if len(paths) < 2 || !slices.ContainsFunc(paths, func(p string) bool { return !dirs[p] }) {
+			continue
+		}
+		collision := Collision{Paths: paths}
+		for _, p := range paths {
+			collision.Sources = append(collision.Sources, group[p])
+		}
+		collisions = append(collisions, collision)
+	}
+	return collisions
+}
+
+// checkCollisions warns about the planned paths only differing by case, and
+// rejects them in strict mode or when the output directory is on a
+// case-insensitive filesystem, before anything is written.
+func (a *applier) checkCollisions(entries []entry) error {
+	a.collisions = findCollisions(entries)
+	if len(a.collisions) == 0 {
+		return nil
+	}
+	insensitive := false
+	if dirSink, onDisk := a.sink.(*DirSink); onDisk {
+		insensitive = caseInsensitive(dirSink.dir)
+	}
+	if a.opts.Strict || insensitive {
+		reason := "only differ by case"
+		if insensitive {
+			reason += ", and the output directory ignores case"
+		}
+		groups := make([]string, len(a.collisions))
+		for i, collision := range a.collisions {
+			groups[i] = collision.String()
+		}
+		return fmt.Errorf("generated paths %s: %s", reason, strings.Join(groups, "; "))
+	}
+	for _, collision := range a.collisions {
+		fmt.Fprintf(a.out, "⚠️  Paths only differing by case collide on case-insensitive filesystems: %s\n", collision)
+	}
+	return nil
+}
+
+// caseInsensitive reports whether the filesystem of dir ignores case, by
+// creating a probe file there and looking it up in upper case.
+func caseInsensitive(dir string) bool {
+	probe, err := os.CreateTemp(dir, ".mold-case-probe-*")
+	if err != nil {
+		return false
+	}
+	name := probe.Name()
+	_ = probe.Close()
+	defer os.Remove(name)
+	_, err = os.Stat(filepath.Join(dir, strings.ToUpper(filepath.Base(name))))
+	return err == nil
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestApplyCollisions(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []Collision
+	}{
+		{
+			name:  "file and file",
+			files: map[string]string{"README.md": "a", "Readme.md.tmpl": "{{.name}}"},
+			want:  []Collision{{Paths: []string{"README.md", "Readme.md"}, Sources: []string{"README.md", "Readme.md.tmpl"}}},
+		},
+		{
+			name:  "file and directory",
+			files: map[string]string{"docs": "a", "Docs/index.md": "b"},
+			want:  []Collision{{Paths: []string{"Docs", "docs"}, Sources: []string{"Docs", "docs"}}},
+		},
+		{
+			name:  "directory from a placeholder",
+			files: map[string]string{"cmd": "a", "{{.name}}.go": "b"},
+			want:  []Collision{{Paths: []string{"CMD", "cmd"}, Sources: []string{"{{.name}}.go", "cmd"}}},
+		},
+		{
+			name: "deep paths",
+			files: map[string]string{
+				"src/App/Main.go": "a", "src/app/main.go": "b", "src/app/util.go": "c", "src/App/other.go": "d",
+			},
+			want: []Collision{{
+				Paths:   []string{"src/App/Main.go", "src/app/main.go"},
+				Sources: []string{"src/App/Main.go", "src/app/main.go"},
+			}},
+		},
+		{
+			name:  "directories only",
+			files: map[string]string{"lib/a.txt": "a", "LIB/b.txt": "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templateDir := writeTemplate(t, tt.files)
+			data := map[string]any{"name": "CMD/main"}
+			var out bytes.Buffer
+			result := NewResult()
+			err := Apply(Options{
+				TemplatePath: templateDir, OutputDir: t.TempDir(), Data: data, Out: &out, Result: result,
+			})
+			if runtime.GOOS == "linux" && err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if !reflect.DeepEqual(result.Collisions, tt.want) {
+				t.Errorf("Expected the collisions %v, got %v", tt.want, result.Collisions)
+			}
+			for _, collision := range tt.want {
+				line := "⚠️  Paths only differing by case collide on case-insensitive filesystems: " +
+					collision.String() + "\n"
+				if runtime.GOOS == "linux" && !contains(out.String(), line) {
+					t.Errorf("Expected %q in the output:\n%s", line, out.String())
+				}
+			}
+
+			outputDir := t.TempDir()
+			err = Apply(Options{
+				TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: &bytes.Buffer{}, Strict: true,
+			})
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Expected no collision, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "generated paths only differ by case: "+tt.want[0].String()) {
+				t.Errorf("Expected strict mode to reject the collision, got %v", err)
+			}
+			if files := readFiles(t, outputDir); len(files) != 0 {
+				t.Errorf("Expected nothing written, got %v", files)
+			}
+		})
+	}
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	dir := t.TempDir()
+	insensitive := caseInsensitive(dir)
+	if runtime.GOOS == "linux" && insensitive {
+		t.Error("Expected the temporary directory to be case-sensitive")
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 0 {
+		t.Errorf("Expected the probe to be removed, got %v (%v)", entries, err)
+	}
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// ComputedValue is a value of the data computed by the computed section of
+// template.yaml.
+type ComputedValue struct {
+	Key   string
+	Value string
+}
+
+// Compute evaluates the expressions of computed, such as
+// "github.com/{{.org}}/{{snake .service}}", against data and adds their
+// results to it. An expression can use other computed values, which are
+// evaluated first; expressions using each other fail. A key the user gave
+// in explicit is kept and reported to warn instead. It returns the values
+// computed, in the order they were evaluated.
+func Compute(computed map[string]string, data, explicit map[string]any, funcs template.FuncMap,
+	warn io.Writer) ([]ComputedValue, error) {
+	templates := make(map[string]*template.Template, len(computed))
+	for _, key := range slices.Sorted(maps.Keys(computed)) {
+		if _, ok := explicit[key]; ok {
+			fmt.Fprintf(warn, "⚠️  Keeping '%s' from the data instead of computing it\n", key)
+			continue
+		}
+		tmpl, err := template.New(key).Funcs(funcs).Option("missingkey=error").Parse(computed[key])
+		if err != nil {
+			return nil, fmt.Errorf("invalid computed value '%s': %w", key, err)
+		}
+		templates[key] = tmpl
+	}
+
+	order, err := computeOrder(templates)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]ComputedValue, 0, len(order))
+	for _, key := range order {
+		var result strings.Builder
+		if err = templates[key].Execute(&result, data); err != nil {
+			return nil, fmt.Errorf("failed to compute '%s': %w", key, err)
+		}
+		data[key] = result.String()
+		values = append(values, ComputedValue{Key: key, Value: result.String()})
+	}
+	return values, nil
+}
+
+// computeOrder sorts the computed values so each comes after the computed
+// values it uses, and fails when they use each other.
+func computeOrder(templates map[string]*template.Template) ([]string, error) {
+	uses := make(map[string][]string, len(templates))
+	for key, tmpl := range templates {
+		c := &collector{keys: make(map[string]bool)}
+		for _, t := range tmpl.Templates() {
+			if t.Tree != nil {
+				c.collect(t.Tree.Root, true)
+			}
+		}
+		for used := range c.keys {
+			if _, ok := templates[used]; ok {
+				uses[key] = append(uses[key], used)
+			}
+		}
+		slices.Sort(uses[key])
+	}
+
+	var order []string
+	done := make(map[string]bool)
+	var visit func(chain []string) error
+	visit = func(chain []string) error {
+		key := chain[len(chain)-1]
+		if done[key] {
+			return nil
+		}
+		for _, used := range uses[key] {
+			if i := slices.Index(chain, used); i >= 0 {
+				cycle := append(slices.Clone(chain[i:]), used)
+				return fmt.Errorf("computed values use each other: %s", strings.Join(cycle, " -> "))
+			}
+			if err := visit(append(slices.Clone(chain), used)); err != nil {
+				return err
+			}
+		}
+		done[key] = true
+		order = append(order, key)
+		return nil
+	}
+	for _, key := range slices.Sorted(maps.Keys(templates)) {
+		if err := visit([]string{key}); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
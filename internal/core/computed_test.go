@@ -0,0 +1,135 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCompute(t *testing.T) {
+	computed := map[string]string{
+		"module_path":   "github.com/{{.org}}/{{.service_snake}}",
+		"service_snake": "{{snake .service}}",
+		"db_name":       "{{.service_snake}}_db",
+		"image":         "{{.module_path}}:latest",
+	}
+
+	t.Run("dependency order", func(t *testing.T) {
+		data := map[string]any{"org": "acme", "service": "OrderAPI"}
+		values, err := Compute(computed, data, map[string]any{}, helperFunc, io.Discard)
+		if err != nil {
+			t.Fatalf("Compute failed: %v", err)
+		}
+		want := []ComputedValue{
+			{"service_snake", "order_api"},
+			{"db_name", "order_api_db"},
+			{"module_path", "github.com/acme/order_api"},
+			{"image", "github.com/acme/order_api:latest"},
+		}
+		if len(values) != len(want) {
+			t.Fatalf("Expected %v, got %v", want, values)
+		}
+		for i := range want {
+			if values[i] != want[i] {
+				t.Errorf("Expected %v at %d, got %v", want[i], i, values[i])
+			}
+			if data[want[i].Key] != want[i].Value {
+				t.Errorf("Expected '%s' in the data, got %v", want[i].Key, data[want[i].Key])
+			}
+		}
+	})
+
+	t.Run("overridden by the data", func(t *testing.T) {
+		explicit := map[string]any{"service_snake": "orders"}
+		data := map[string]any{"org": "acme", "service": "OrderAPI", "service_snake": "orders"}
+		var warn bytes.Buffer
+		if _, err := Compute(computed, data, explicit, helperFunc, &warn); err != nil {
+			t.Fatalf("Compute failed: %v", err)
+		}
+		if data["db_name"] != "orders_db" {
+			t.Errorf("Expected the given value to be used, got %v", data["db_name"])
+		}
+		want := "⚠️  Keeping 'service_snake' from the data instead of computing it\n"
+		if warn.String() != want {
+			t.Errorf("Expected %q, got %q", want, warn.String())
+		}
+	})
+
+	errorTests := []struct {
+		name     string
+		computed map[string]string
+		want     string
+	}{
+		{
+			name:     "cycle",
+			computed: map[string]string{"a": "{{.b}}", "b": "{{.c}}-x", "c": "{{.a}}"},
+			want:     "computed values use each other: a -> b -> c -> a",
+		},
+		{
+			name:     "self reference",
+			computed: map[string]string{"a": "{{.a}}"},
+			want:     "computed values use each other: a -> a",
+		},
+		{
+			name:     "missing key",
+			computed: map[string]string{"a": "{{.nope}}"},
+			want:     "failed to compute 'a'",
+		},
+		{
+			name:     "invalid expression",
+			computed: map[string]string{"a": "{{.org"},
+			want:     "invalid computed value 'a'",
+		},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]any{"org": "acme"}
+			_, err := Compute(tt.computed, data, data, helperFunc, io.Discard)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestApplyComputed(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile: "minMoldVersion: 0.9.0\nprompts:\n  org: {}\n  service: {}\n" +
+			"computed:\n  module_path: \"github.com/{{.org}}/{{snake .service}}\"\n" +
+			"  db_name: \"{{snake .service}}_db\"\n",
+		"{{.db_name}}.sql.tmpl": "-- {{.module_path}}",
+	})
+	data := map[string]any{"org": "acme", "service": "Billing"}
+
+	var out bytes.Buffer
+	outputDir := t.TempDir()
+	err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: &out, DryRun: true})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	for _, line := range []string{
+		"🧮 Computed: db_name = billing_db\n",
+		"🧮 Computed: module_path = github.com/acme/billing\n",
+		"✨ Rendering: billing_db.sql.tmpl -> billing_db.sql\n",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Expected %q in the dry run, got:\n%s", line, out.String())
+		}
+	}
+
+	if err = Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: io.Discard}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if files := readFiles(t, outputDir); files["billing_db.sql"] != "-- github.com/acme/billing" {
+		t.Errorf("Expected the computed values to be rendered, got %v", files)
+	}
+
+	findings, err := Lint(templateDir)
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("Expected computed values to count as declared and to use their keys, got %v", findings)
+	}
+}
@@ -0,0 +1,198 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/0m3kk/mold/internal/utils"
+)
+
+const (
+	// ConfigEnv names the environment variable that overrides the path of
+	// the config file.
+	ConfigEnv = "MOLD_CONFIG"
+	// CacheDirEnv names the environment variable that overrides the cache
+	// directory.
+	CacheDirEnv = "MOLD_CACHE_DIR"
+)
+
+// registryNamePattern matches the names of registry sources.
+//
+//nolint:gochecknoglobals // compiled once
+var registryNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// Config holds the user settings of mold, read from the config file.
+type Config struct {
+	// Registries are the registry indexes templates are looked up in, in
+	// order. The first registry listing a name wins.
+	Registries []RegistrySource `yaml:"registries"`
+	// TemplatePaths are the directories the named templates are looked up
+	// in after the templates directory of the project, in order, such as
+	// '~/.local/share/mold/templates'.
+	TemplatePaths []string `yaml:"templatePaths,omitempty"`
+}
+
+// RegistrySource is a registry index registered in the config file.
+type RegistrySource struct {
+	// Name identifies the registry in listings and in the cache, such as
+	// "internal".
+	Name string `yaml:"name"`
+	// URL is the https URL or the local path of the registry.yaml index, or
+	// a git repository holding it at its root.
+	URL string `yaml:"url"`
+}
+
+// DefaultConfigPath returns the path of the config file: the value of
+// MOLD_CONFIG, or '.mold/config.yaml' in the home directory.
+func DefaultConfigPath() (string, error) {
+	if path := os.Getenv(ConfigEnv); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine the config file, set %s: %w", ConfigEnv, err)
+	}
+	return filepath.Join(home, ".mold", "config.yaml"), nil
+}
+
+// DefaultCacheDir returns the directory holding the fetched registry indexes
+// and templates: the value of MOLD_CACHE_DIR, or '.mold/cache' in the home
+// directory.
+func DefaultCacheDir() (string, error) {
+	if dir := os.Getenv(CacheDirEnv); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine the cache directory, set %s: %w", CacheDirEnv, err)
+	}
+	return filepath.Join(home, ".mold", "cache"), nil
+}
+
+// LoadConfig reads the config file at path. A missing file is an empty
+// config.
+func LoadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file '%s': %w", path, err)
+	}
+	config := &Config{}
+	if err = yaml.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%s': %w", path, err)
+	}
+	if err = config.check(); err != nil {
+		return nil, fmt.Errorf("invalid config file '%s': %w", path, err)
+	}
+	return config, nil
+}
+
+// SaveConfig writes the config file at path, creating its directory.
+func SaveConfig(path string, config *Config) error {
+	if err := config.check(); err != nil {
+		return err
+	}
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), utils.DirMode); err != nil {
+		return fmt.Errorf("failed to create config directory '%s': %w", filepath.Dir(path), err)
+	}
+	if err = os.WriteFile(path, content, 0600); err != nil {
+		return fmt.Errorf("failed to write config file '%s': %w", path, err)
+	}
+	return nil
+}
+
+// SearchPaths returns the TemplatePaths of the config file at path, with a
+// leading '~' standing for the home directory and relative paths taken from
+// the directory of the config file.
+func (c *Config) SearchPaths(path string) ([]string, error) {
+	paths := make([]string, 0, len(c.TemplatePaths))
+	for _, dir := range c.TemplatePaths {
+		if rest, ok := strings.CutPrefix(dir, "~"); ok && (rest == "" || os.IsPathSeparator(rest[0])) {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("cannot expand template path '%s': %w", dir, err)
+			}
+			dir = home + rest
+		} else if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(path), dir)
+		}
+		paths = append(paths, dir)
+	}
+	return paths, nil
+}
+
+// Registry returns the registry source with the given name.
+func (c *Config) Registry(name string) (RegistrySource, bool) {
+	for _, source := range c.Registries {
+		if source.Name == name {
+			return source, true
+		}
+	}
+	return RegistrySource{}, false
+}
+
+// AddRegistry appends a registry source, refusing an invalid one or a name
+// that is taken.
+func (c *Config) AddRegistry(source RegistrySource) error {
+	if _, ok := c.Registry(source.Name); ok {
+		return fmt.Errorf("registry '%s' already exists", source.Name)
+	}
+	if err := source.check(); err != nil {
+		return err
+	}
+	c.Registries = append(c.Registries, source)
+	return nil
+}
+
+// RemoveRegistry removes the registry source with the given name.
+func (c *Config) RemoveRegistry(name string) error {
+	i := slices.IndexFunc(c.Registries, func(s RegistrySource) bool { return s.Name == name })
+	if i < 0 {
+		return fmt.Errorf("registry '%s' not found", name)
+	}
+	c.Registries = slices.Delete(c.Registries, i, i+1)
+	return nil
+}
+
+// check reports invalid or duplicate registry sources.
+func (c *Config) check() error {
+	seen := make(map[string]bool)
+	for _, source := range c.Registries {
+		if err := source.check(); err != nil {
+			return err
+		}
+		if seen[source.Name] {
+			return fmt.Errorf("registry '%s' is listed twice", source.Name)
+		}
+		seen[source.Name] = true
+	}
+	return nil
+}
+
+// check reports an invalid name or url.
+func (s RegistrySource) check() error {
+	if !registryNamePattern.MatchString(s.Name) {
+		return fmt.Errorf("invalid registry name '%s', expected lowercase letters, digits, '.', '_' and '-'", s.Name)
+	}
+	if s.URL == "" {
+		return fmt.Errorf("registry '%s' has no url", s.Name)
+	}
+	if err := CheckRegistryURL(s.URL); err != nil {
+		return fmt.Errorf("registry '%s': %w", s.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	config, err := LoadConfig(filepath.Join(dir, "missing.yaml"))
+	if err != nil || len(config.Registries) != 0 {
+		t.Fatalf("Expected an empty config for a missing file, got %+v, %v", config, err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		wantErr string
+	}{
+		{
+			name: "registries",
+			content: "registries:\n  - name: acme\n    url: https://example.com/registry.yaml\n" +
+				"  - name: team-2\n    url: ./r.yaml\n",
+			want: 2,
+		},
+		{name: "not yaml", content: "registries: [", wantErr: "failed to parse config file"},
+		{
+			name:    "invalid name",
+			content: "registries:\n  - name: Acme\n    url: r.yaml",
+			wantErr: "invalid registry name 'Acme'",
+		},
+		{
+			name:    "plain http",
+			content: "registries:\n  - name: acme\n    url: http://example.com/registry.yaml",
+			wantErr: "registry 'acme': refusing to fetch",
+		},
+		{
+			name:    "duplicate name",
+			content: "registries:\n  - name: acme\n    url: a.yaml\n  - name: acme\n    url: b.yaml",
+			wantErr: "registry 'acme' is listed twice",
+		},
+		{name: "missing url", content: "registries:\n  - name: acme", wantErr: "registry 'acme' has no url"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, "config.yaml")
+		if err = os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		config, err = LoadConfig(path)
+		if tt.wantErr != "" {
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error containing %q, got: %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: LoadConfig failed: %v", tt.name, err)
+			continue
+		}
+		if len(config.Registries) != tt.want {
+			t.Errorf("%s: expected %d registries, got %+v", tt.name, tt.want, config.Registries)
+		}
+	}
+}
+
+func TestConfigRegistries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "config.yaml")
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = config.AddRegistry(RegistrySource{Name: "acme", URL: "https://example.com/registry.yaml"}); err != nil {
+		t.Fatalf("AddRegistry failed: %v", err)
+	}
+	if err = config.AddRegistry(RegistrySource{Name: "team", URL: "git@example.com:team/registry.git"}); err != nil {
+		t.Fatalf("AddRegistry failed: %v", err)
+	}
+	for _, tt := range []struct {
+		source  RegistrySource
+		wantErr string
+	}{
+		{source: RegistrySource{Name: "acme", URL: "https://example.com/other.yaml"}, wantErr: "already exists"},
+		{source: RegistrySource{Name: "Other", URL: "https://example.com/r.yaml"}, wantErr: "invalid registry name"},
+		{source: RegistrySource{Name: "other"}, wantErr: "has no url"},
+		{source: RegistrySource{Name: "other", URL: "ftp://example.com/r.yaml"}, wantErr: "unsupported url"},
+	} {
+		if err = config.AddRegistry(tt.source); err == nil || !contains(err.Error(), tt.wantErr) {
+			t.Errorf("AddRegistry(%+v): expected error containing %q, got: %v", tt.source, tt.wantErr, err)
+		}
+	}
+	if err = SaveConfig(path, config); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+
+	saved, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(saved.Registries) != 2 || saved.Registries[1] != config.Registries[1] {
+		t.Fatalf("Expected the registries to round-trip, got %+v", saved.Registries)
+	}
+	if err = saved.RemoveRegistry("acme"); err != nil {
+		t.Fatalf("RemoveRegistry failed: %v", err)
+	}
+	if err = saved.RemoveRegistry("acme"); err == nil || !contains(err.Error(), "registry 'acme' not found") {
+		t.Errorf("Expected removing a missing registry to fail, got: %v", err)
+	}
+	if _, ok := saved.Registry("team"); !ok || len(saved.Registries) != 1 {
+		t.Errorf("Expected only team to remain, got %+v", saved.Registries)
+	}
+}
+
+func TestDefaultConfigPath(t *testing.T) {
+	t.Setenv(ConfigEnv, "/etc/mold.yaml")
+	t.Setenv(CacheDirEnv, "/var/cache/mold")
+	if path, err := DefaultConfigPath(); err != nil || path != "/etc/mold.yaml" {
+		t.Errorf("Expected the config path from %s, got %q, %v", ConfigEnv, path, err)
+	}
+	if dir, err := DefaultCacheDir(); err != nil || dir != "/var/cache/mold" {
+		t.Errorf("Expected the cache directory from %s, got %q, %v", CacheDirEnv, dir, err)
+	}
+}
+
+func TestConfigSearchPaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	config := &Config{TemplatePaths: []string{"~/templates", "~", "/srv/templates", "team", "~other/templates"}}
+	paths, err := config.SearchPaths("/etc/mold/config.yaml")
+	want := []string{
+		filepath.Join(home, "templates"), home, "/srv/templates", "/etc/mold/team", "/etc/mold/~other/templates",
+	}
+	if err != nil || !slices.Equal(paths, want) {
+		t.Errorf("Expected %q, got %q (%v)", want, paths, err)
+	}
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MaxConfirmAttempts is how many answers AskConfirm reads before giving up
+// on a question answered with something other than yes or no.
+const MaxConfirmAttempts = 3
+
+// ParseConfirm parses a yes or no answer: y, yes, true or 1, and n, no,
+// false or 0, in any case. It returns whether the answer is yes, and whether
+// it is one of them at all.
+func ParseConfirm(answer string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes", "true", "1":
+		return true, true
+	case "n", "no", "false", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// AskConfirm asks a yes or no question on out, showing the default as
+// [Y/n] or [y/N], and reads the answer from in. An empty answer, or the end
+// of the input, picks the default. Anything ParseConfirm doesn't accept asks
+// again, up to MaxConfirmAttempts times.
+func AskConfirm(in io.Reader, out io.Writer, question string, def bool) (bool, error) {
+	hint := "[y/N]"
+	if def {
+		hint = "[Y/n]"
+	}
+	reader := bufio.NewReader(in)
+	for range MaxConfirmAttempts {
+		fmt.Fprintf(out, "%s %s ", question, hint)
+		answer, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return false, fmt.Errorf("failed to read the answer: %w", err)
+		}
+		if strings.TrimSpace(answer) == "" {
+			return def, nil
+		}
+		if yes, ok := ParseConfirm(answer); ok {
+			return yes, nil
+		}
+		fmt.Fprintf(out, "⚠️  Please answer yes or no, not '%s'\n", strings.TrimSpace(answer))
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	return false, fmt.Errorf("no yes or no answer to '%s'", question)
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseConfirm(t *testing.T) {
+	for answer, want := range map[string]bool{
+		"y": true, "YES": true, " True\n": true, "1": true,
+		"n": false, "No": false, "FALSE": false, "0": false,
+	} {
+		if yes, ok := ParseConfirm(answer); !ok || yes != want {
+			t.Errorf("%q: expected %v, got %v, %v", answer, want, yes, ok)
+		}
+	}
+	for _, answer := range []string{"", "maybe", "yep", "2"} {
+		if _, ok := ParseConfirm(answer); ok {
+			t.Errorf("%q: expected no answer", answer)
+		}
+	}
+}
+
+func TestAskConfirm(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		def    bool
+		want   bool
+		asked  int
+		errMsg string
+	}{
+		{name: "empty answer picks the default yes", input: "\n", def: true, want: true, asked: 1},
+		{name: "empty answer picks the default no", input: "\n", want: false, asked: 1},
+		{name: "end of input picks the default", input: "", def: true, want: true, asked: 1},
+		{name: "explicit no", input: "NO\n", def: true, want: false, asked: 1},
+		{name: "junk asks again", input: "maybe\nsure\ny\n", want: true, asked: 3},
+		{name: "answer without newline", input: "1", want: true, asked: 1},
+		{name: "junk until the limit", input: "a\nb\nc\ny\n", def: true, asked: 3, errMsg: "no yes or no answer"},
+		{name: "junk then end of input", input: "a", def: true, asked: 1, errMsg: "no yes or no answer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := AskConfirm(strings.NewReader(tt.input), &out, "Use Docker?", tt.def)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("Expected an error containing %q, got %v, %v", tt.errMsg, got, err)
+				}
+			} else if err != nil || got != tt.want {
+				t.Fatalf("Expected %v, got %v, %v", tt.want, got, err)
+			}
+			hint := "[y/N]"
+			if tt.def {
+				hint = "[Y/n]"
+			}
+			if asked := strings.Count(out.String(), "Use Docker? "+hint+" "); asked != tt.asked {
+				t.Errorf("Expected the question asked %d times, got:\n%s", tt.asked, out.String())
+			}
+		})
+	}
+}
+
+func TestApplyConfirm(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile:        "prompts:\n  use_docker: {type: confirm}\n  use_ci: {type: confirm}\n",
+		"features.txt.tmpl": "{{if .use_docker}}docker{{end}}{{if .use_ci}}ci{{end}}",
+	})
+	outputDir := t.TempDir()
+	data := map[string]any{"use_docker": "Yes", "use_ci": "n"}
+	if err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: io.Discard}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if files := readFiles(t, outputDir); files["features.txt"] != "docker" {
+		t.Errorf("Expected the answers as bools, got %q", files["features.txt"])
+	}
+
+	data = map[string]any{"use_docker": "sure"}
+	err := Apply(Options{TemplatePath: templateDir, OutputDir: t.TempDir(), Data: data, Out: io.Discard})
+	want := `invalid value for 'use_docker': expected yes or no, got string "sure"`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected the junk answer to be rejected, got %v", err)
+	}
+}
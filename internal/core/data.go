@@ -1,40 +1,457 @@
 package core
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"gopkg.in/yaml.v3"
 )
 
 // LoadDataFile reads a JSON or YAML file from the given path and unmarshals it
 // into a map that can be used for template rendering.
-func LoadDataFile(path string) (map[string]any, error) {
+func LoadDataFile(path string, opts DataOptions) (map[string]any, error) {
+	// Determine the file type by extension.
+	format, err := DataFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	return LoadDataFileAs(path, format, opts)
+}
+
+// LoadDataFileAs reads the file at path and parses it in the given format,
+// regardless of its extension. Files encrypted with sops are decrypted with
+// the sops binary first.
+func LoadDataFileAs(path, format string, opts DataOptions) (map[string]any, error) {
 	// Read the file content.
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read data file '%s': %w", path, err)
 	}
+	if IsSopsEncrypted(path, content, format) {
+		if content, err = decryptSops(path, format); err != nil {
+			return nil, err
+		}
+	}
+	return ParseData(content, format, path, opts)
+}
 
-	data := make(map[string]any)
-
-	// Determine the file type by extension and unmarshal accordingly.
+// DataFormat returns the data format implied by the extension of path.
+func DataFormat(path string) (string, error) {
 	ext := strings.ToLower(filepath.Ext(path))
 	switch ext {
 	case ".json":
-		if err = json.Unmarshal(content, &data); err != nil {
-			return nil, fmt.Errorf("failed to parse JSON file '%s': %w", path, err)
-		}
+		return "json", nil
+	case ".jsonc":
+		return "jsonc", nil
 	case ".yaml", ".yml":
-		if err = yaml.Unmarshal(content, &data); err != nil {
-			return nil, fmt.Errorf("failed to parse YAML file '%s': %w", path, err)
+		return "yaml", nil
+	default:
+		return "", fmt.Errorf("unsupported data file format: '%s'. Please use .json, .jsonc, .yaml, or .yml", ext)
+	}
+}
+
+// ParseData unmarshals JSON or YAML content into a map that can be used for
+// template rendering. The format is "json", "jsonc", "yaml" or "yml", and
+// source names the content in error messages. Comments and trailing commas
+// are accepted in JSON unless opts is strict; they are always accepted in
+// JSONC. Keys defined twice in the same mapping are handled as opts says.
+func ParseData(content []byte, format, source string, opts DataOptions) (map[string]any, error) {
+	data := make(map[string]any)
+
+	switch format = strings.ToLower(format); format {
+	case "json", "jsonc":
+		// Stripping keeps offsets, so errors point into the original content.
+		plain := content
+		if format == "jsonc" || !opts.Strict {
+			plain = stripJSONC(content)
+		}
+		if err := opts.checkDuplicates("JSON", source, jsonDuplicates(plain)); err != nil {
+			return nil, err
+		}
+		if err := decodeJSON(plain, &data); err != nil {
+			return nil, jsonParseError(source, content, err)
+		}
+	case "yaml", "yml":
+		var err error
+		if data, err = parseYAMLDocuments(content, source, opts); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported data format: '%s'. Please use json, jsonc or yaml", format)
+	}
+	// A null document, such as JSON null or YAML ~, holds no data.
+	if data == nil {
+		data = make(map[string]any)
+	}
+	return data, nil
+}
+
+// ParseSetValue splits a "key=value" override into its dotted key path and
+// value. The value is interpreted as a YAML scalar, so numbers and booleans
+// keep their types; anything else is kept as a string.
+func ParseSetValue(expr string) (string, any, error) {
+	key, raw, ok := strings.Cut(expr, "=")
+	if !ok || key == "" {
+		return "", nil, fmt.Errorf("invalid --set value '%s': expected key=value", expr)
+	}
+	if raw == "" {
+		return key, "", nil
+	}
+	return key, parseScalar(raw), nil
+}
+
+// parseScalar interprets raw as a YAML scalar, falling back to the raw string
+// for anything that is not a single scalar.
+func parseScalar(raw string) any {
+	var node yaml.Node
+	if yaml.Unmarshal([]byte(raw), &node) != nil || len(node.Content) != 1 ||
+		node.Content[0].Kind != yaml.ScalarNode {
+		return raw
+	}
+	var value any
+	if node.Content[0].Decode(&value) != nil {
+		return raw
+	}
+	return value
+}
+
+// pathSegment is one step of a key path: a map key, a list index or an
+// append to a list.
+type pathSegment struct {
+	name     string
+	index    int
+	isIndex  bool
+	isAppend bool
+}
+
+// String formats the segment as it appears in a key path.
+func (s pathSegment) String() string {
+	switch {
+	case s.isAppend:
+		return "[]"
+	case s.isIndex:
+		return "[" + strconv.Itoa(s.index) + "]"
+	default:
+		return "." + s.name
+	}
+}
+
+// parseKeyPath splits a key path such as "services[0].port" or "tags[]" into
+// its segments. Every dot-separated part starts with a map key.
+func parseKeyPath(key string) ([]pathSegment, error) {
+	var segments []pathSegment
+	for _, part := range strings.Split(key, ".") {
+		name, rest := part, ""
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			name, rest = part[:i], part[i:]
+		}
+		if name == "" {
+			return nil, fmt.Errorf("invalid key '%s': empty path segment", key)
+		}
+		segments = append(segments, pathSegment{name: name})
+
+		for rest != "" {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("invalid key '%s': malformed index in '%s'", key, part)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			if inner == "" {
+				segments = append(segments, pathSegment{isAppend: true})
+				continue
+			}
+			index, err := strconv.Atoi(inner)
+			if err != nil || strings.TrimLeft(inner, "0123456789") != "" {
+				return nil, fmt.Errorf("invalid key '%s': index '%s' is not a number", key, inner)
+			}
+			segments = append(segments, pathSegment{index: index, isIndex: true})
+		}
+	}
+	return segments, nil
+}
+
+// SetValue stores value in data at the key path, creating intermediate maps
+// and lists as needed. Dots separate map keys, "[n]" addresses the element
+// at index n of a list, which may be one past its end, and "[]" appends to
+// a list. data must not be nil, since the value couldn't be stored in it.
+func SetValue(data map[string]any, key string, value any) error {
+	segments, err := parseKeyPath(key)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return fmt.Errorf("cannot set '%s': there is no data to set it in", key)
+	}
+	_, err = setPath(data, segments, value, key, "")
+	return err
+}
+
+// valueKind names the kind of a data value for template authors, such as
+// "a list" or "a number", rather than by its Go type.
+func valueKind(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "a map"
+	case []any:
+		return "a list"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case int, int64, uint64, float64:
+		return "a number"
+	case time.Time:
+		return "a date"
+	default:
+		return "a value"
+	}
+}
+
+// setPath stores value below current, the value at prefix, and returns the
+// updated current value. Lists are returned anew since appending may move
+// them.
+func setPath(current any, segments []pathSegment, value any, key, prefix string) (any, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+	segment := segments[0]
+	path := strings.TrimPrefix(prefix+segment.String(), ".")
+
+	if !segment.isIndex && !segment.isAppend {
+		m, ok := current.(map[string]any)
+		if current == nil {
+			m, ok = make(map[string]any), true
 		}
+		if !ok {
+			return nil, fmt.Errorf("cannot set '%s': '%s' is %s, not a map", key, prefix[1:], valueKind(current))
+		}
+		child, err := setPath(m[segment.name], segments[1:], value, key, "."+path)
+		if err != nil {
+			return nil, err
+		}
+		m[segment.name] = child
+		return m, nil
+	}
+
+	list, ok := current.([]any)
+	if current == nil {
+		ok = true
+	}
+	if !ok {
+		return nil, fmt.Errorf("cannot set '%s': '%s' is %s, not a list", key, prefix[1:], valueKind(current))
+	}
+	switch {
+	case segment.isAppend || segment.index == len(list):
+		child, err := setPath(nil, segments[1:], value, key, "."+path)
+		if err != nil {
+			return nil, err
+		}
+		return append(list, child), nil
+	case segment.index < len(list):
+		child, err := setPath(list[segment.index], segments[1:], value, key, "."+path)
+		if err != nil {
+			return nil, err
+		}
+		list[segment.index] = child
+		return list, nil
 	default:
-		return nil, fmt.Errorf("unsupported data file format: '%s'. Please use .json, .yaml, or .yml", ext)
+		return nil, fmt.Errorf("cannot set '%s': index %d is out of range for '%s' of length %d",
+			key, segment.index, prefix[1:], len(list))
 	}
+}
 
+// decodeJSON unmarshals JSON content into data like json.Unmarshal, except
+// that numbers are kept exact: integers become int and other numbers
+// float64, so a large integer doesn't render in exponent notation.
+func decodeJSON(content []byte, data *map[string]any) error {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(data); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		// Let json.Unmarshal report what follows the value.
+		return json.Unmarshal(content, new(any))
+	}
+	for key, value := range *data {
+		(*data)[key] = convertJSONNumbers(value)
+	}
+	return nil
+}
+
+// convertJSONNumbers replaces the json.Number values under value with int
+// for integers and float64 for other numbers. Integers too large for int are
+// kept as their digits, since a float64 would lose some of them.
+func convertJSONNumbers(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			v[key] = convertJSONNumbers(child)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = convertJSONNumbers(child)
+		}
+	case json.Number:
+		if i, err := strconv.Atoi(v.String()); err == nil {
+			return i
+		}
+		if !strings.ContainsAny(v.String(), ".eE") {
+			return v.String()
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	}
+	return value
+}
+
+// parseYAMLDocuments unmarshals every document of YAML content and merges
+// them in order, so later documents win. Empty documents are skipped, and a
+// single document is unmarshaled as is.
+func parseYAMLDocuments(content []byte, source string, opts DataOptions) (map[string]any, error) {
+	var docs []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, yamlParseError(source, content, err)
+		}
+		docs = append(docs, &doc)
+	}
+	var duplicates []DuplicateKey
+	for _, doc := range docs {
+		duplicates = append(duplicates, pruneYAMLDuplicates(doc, "")...)
+	}
+	if err := opts.checkDuplicates("YAML", source, duplicates); err != nil {
+		return nil, err
+	}
+	opts.checkYAML11Bools(source, docs)
+
+	data := make(map[string]any)
+	if len(docs) == 1 {
+		if err := docs[0].Decode(&data); err != nil {
+			return nil, yamlParseError(source, content, err)
+		}
+		return data, nil
+	}
+	for i, doc := range docs {
+		root := doc.Content[0]
+		if root.Kind == yaml.ScalarNode && root.Tag == "!!null" {
+			continue
+		}
+		if root.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("failed to parse YAML file '%s': document %d at line %d is not a mapping\n%s",
+				source, i+1, root.Line, sourceContext(content, root.Line, root.Column))
+		}
+		docData := make(map[string]any)
+		if err := root.Decode(&docData); err != nil {
+			return nil, yamlParseError(source, content, err)
+		}
+		MergeData(data, docData)
+	}
 	return data, nil
 }
+
+// OverrideKind tells how the value of an Override is interpreted.
+type OverrideKind int
+
+const (
+	// OverrideValue is a --set value, interpreted as a YAML scalar.
+	OverrideValue OverrideKind = iota
+	// OverrideString is a --set-string value, always used as a string.
+	OverrideString
+	// OverrideFile is a --set-file value, the path of a file whose content
+	// is used as a string.
+	OverrideFile
+)
+
+// Override is a data value set from the command line with --set,
+// --set-string or --set-file.
+type Override struct {
+	// Expr is "key=value", or "key=path" for a file.
+	Expr string
+	Kind OverrideKind
+}
+
+// ApplyOverrides applies overrides to data in order, so a later override of
+// the same key wins whichever flag set it. File values are injected as
+// strings without their trailing newline. A warning is written to warn for
+// each file that is not valid text.
+func ApplyOverrides(data map[string]any, overrides []Override, warn io.Writer) error {
+	for _, override := range overrides {
+		var key string
+		var value any
+		var err error
+		switch override.Kind {
+		case OverrideString:
+			var ok bool
+			if key, value, ok = strings.Cut(override.Expr, "="); !ok || key == "" {
+				return fmt.Errorf("invalid --set-string value '%s': expected key=value", override.Expr)
+			}
+		case OverrideFile:
+			key, value, err = readSetFile(override.Expr, warn)
+		default:
+			key, value, err = ParseSetValue(override.Expr)
+		}
+		if err != nil {
+			return err
+		}
+		if err = SetValue(data, key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSetFile reads the file of a "key=path" --set-file value and returns the
+// key and the content without its trailing newline.
+func readSetFile(expr string, warn io.Writer) (string, string, error) {
+	key, path, ok := strings.Cut(expr, "=")
+	if !ok || key == "" || path == "" {
+		return "", "", fmt.Errorf("invalid --set-file value '%s': expected key=path", expr)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read --set-file '%s': %w", expr, err)
+	}
+	if !utf8.Valid(content) || bytes.IndexByte(content, 0) >= 0 {
+		fmt.Fprintf(warn, "⚠️  File '%s' for '%s' is binary, its content is used as a string\n", path, key)
+	}
+	return key, string(bytes.TrimSuffix(content, []byte("\n"))), nil
+}
+
+// MergeData merges src into dst. Nested maps are merged recursively; any
+// other value of src replaces the one in dst.
+func MergeData(dst, src map[string]any) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]any)
+		dstMap, dstIsMap := dst[key].(map[string]any)
+		if srcIsMap && dstIsMap {
+			MergeData(dstMap, srcMap)
+			continue
+		}
+		if srcIsMap {
+			// Copy the map so later merges don't modify src.
+			copied := make(map[string]any, len(srcMap))
+			MergeData(copied, srcMap)
+			value = copied
+		}
+		dst[key] = value
+	}
+}
@@ -1,11 +1,13 @@
 package core
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -33,7 +35,7 @@ func TestLoadDataFile(t *testing.T) {
 		}
 
 		// Load and verify
-		result, err := LoadDataFile(jsonPath)
+		result, err := LoadDataFile(jsonPath, DataOptions{})
 		if err != nil {
 			t.Fatalf("LoadDataFile failed: %v", err)
 		}
@@ -41,8 +43,9 @@ func TestLoadDataFile(t *testing.T) {
 		if result["name"] != "test" {
 			t.Errorf("Expected name 'test', got %v", result["name"])
 		}
-		if result["version"] != 1.0 {
-			t.Errorf("Expected version 1.0, got %v", result["version"])
+		// json.Marshal writes 1.0 as 1, which is an integer.
+		if result["version"] != 1 {
+			t.Errorf("Expected version 1, got %#v", result["version"])
 		}
 		if result["enabled"] != true {
 			t.Errorf("Expected enabled true, got %v", result["enabled"])
@@ -63,7 +66,7 @@ nested:
 			t.Fatalf("Failed to write YAML file: %v", err)
 		}
 
-		result, err := LoadDataFile(yamlPath)
+		result, err := LoadDataFile(yamlPath, DataOptions{})
 		if err != nil {
 			t.Fatalf("LoadDataFile failed: %v", err)
 		}
@@ -97,7 +100,7 @@ version: 3
 			t.Fatalf("Failed to write YML file: %v", err)
 		}
 
-		result, err := LoadDataFile(ymlPath)
+		result, err := LoadDataFile(ymlPath, DataOptions{})
 		if err != nil {
 			t.Fatalf("LoadDataFile failed: %v", err)
 		}
@@ -113,7 +116,7 @@ version: 3
 	t.Run("file does not exist", func(t *testing.T) {
 		nonExistentPath := filepath.Join(tempDir, "nonexistent.json")
 
-		_, err := LoadDataFile(nonExistentPath)
+		_, err := LoadDataFile(nonExistentPath, DataOptions{})
 		if err == nil {
 			t.Error("Expected error when file does not exist")
 		}
@@ -129,7 +132,7 @@ version: 3
 			t.Fatalf("Failed to write TXT file: %v", err)
 		}
 
-		_, err = LoadDataFile(txtPath)
+		_, err = LoadDataFile(txtPath, DataOptions{})
 		if err == nil {
 			t.Error("Expected error for unsupported file extension")
 		}
@@ -147,7 +150,7 @@ version: 3
 			t.Fatalf("Failed to write invalid JSON file: %v", err)
 		}
 
-		_, err = LoadDataFile(invalidJSONPath)
+		_, err = LoadDataFile(invalidJSONPath, DataOptions{})
 		if err == nil {
 			t.Error("Expected error for invalid JSON content")
 		}
@@ -165,7 +168,7 @@ version: 3
 			t.Fatalf("Failed to write invalid YAML file: %v", err)
 		}
 
-		_, err = LoadDataFile(invalidYamlPath)
+		_, err = LoadDataFile(invalidYamlPath, DataOptions{})
 		if err == nil {
 			t.Error("Expected error for invalid YAML content")
 		}
@@ -193,3 +196,562 @@ func containsAt(s, substr string, start int) bool {
 	}
 	return containsAt(s, substr, start+1)
 }
+
+func TestParseData(t *testing.T) {
+	t.Run("json and yaml formats", func(t *testing.T) {
+		for format, content := range map[string]string{
+			"json": `{"name": "test"}`,
+			"yaml": "name: test",
+			"YML":  "name: test",
+		} {
+			data, err := ParseData([]byte(content), format, "<stdin>", DataOptions{})
+			if err != nil {
+				t.Fatalf("ParseData(%s) failed: %v", format, err)
+			}
+			if data["name"] != "test" {
+				t.Errorf("ParseData(%s): expected name 'test', got %v", format, data["name"])
+			}
+		}
+	})
+
+	t.Run("errors name the source", func(t *testing.T) {
+		_, err := ParseData([]byte("{invalid"), "json", "<stdin>", DataOptions{})
+		if err == nil || !contains(err.Error(), "failed to parse JSON file '<stdin>'") {
+			t.Errorf("Expected JSON parse error naming <stdin>, got: %v", err)
+		}
+	})
+
+	t.Run("multi-document yaml", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			content string
+			want    map[string]any
+			wantErr string
+		}{
+			{
+				name:    "two documents",
+				content: "name: app\ndb: {host: localhost, port: 5432}\n---\ndb: {host: prod}\n",
+				want:    map[string]any{"name": "app", "db": map[string]any{"host": "prod", "port": 5432}},
+			},
+			{
+				name:    "three documents",
+				content: "env: dev\nreplicas: 1\n---\nenv: staging\n---\nreplicas: 3\ntags: [a]\n",
+				want:    map[string]any{"env": "staging", "replicas": 3, "tags": []any{"a"}},
+			},
+			{
+				name:    "empty trailing document",
+				content: "name: app\n---\n",
+				want:    map[string]any{"name": "app"},
+			},
+			{
+				name:    "empty document in the middle",
+				content: "name: app\n---\n# nothing here\n---\nname: other\n",
+				want:    map[string]any{"name": "other"},
+			},
+			{
+				name:    "single document",
+				content: "---\nname: app\n",
+				want:    map[string]any{"name": "app"},
+			},
+			{
+				name:    "scalar document in the middle",
+				content: "name: app\n---\njust a string\n---\nname: other\n",
+				wantErr: "document 2 at line 3 is not a mapping",
+			},
+			{
+				name:    "list document",
+				content: "name: app\n---\n- a\n",
+				wantErr: "document 2 at line 3 is not a mapping",
+			},
+			{
+				name:    "invalid later document",
+				content: "name: app\n---\nname: [\n",
+				wantErr: "failed to parse YAML file 'data.yaml'",
+			},
+		}
+		for _, tt := range tests {
+			data, err := ParseData([]byte(tt.content), "yaml", "data.yaml", DataOptions{})
+			if tt.wantErr != "" {
+				if err == nil || !contains(err.Error(), tt.wantErr) {
+					t.Errorf("%s: expected error containing %q, got: %v", tt.name, tt.wantErr, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s: ParseData failed: %v", tt.name, err)
+				continue
+			}
+			if !reflect.DeepEqual(data, tt.want) {
+				t.Errorf("%s: got %#v; want %#v", tt.name, data, tt.want)
+			}
+		}
+	})
+
+	t.Run("single scalar document fails as before", func(t *testing.T) {
+		_, err := ParseData([]byte("just a string"), "yaml", "data.yaml", DataOptions{})
+		if err == nil || !contains(err.Error(), "cannot unmarshal !!str") {
+			t.Errorf("Expected unmarshal error, got: %v", err)
+		}
+	})
+
+	t.Run("null document is empty data", func(t *testing.T) {
+		for format, content := range map[string]string{
+			"json": "null",
+			"yaml": "~",
+		} {
+			data, err := ParseData([]byte(content), format, "data."+format, DataOptions{})
+			if err != nil {
+				t.Fatalf("ParseData(%s) failed: %v", format, err)
+			}
+			if data == nil || len(data) != 0 {
+				t.Fatalf("ParseData(%s): expected an empty map, got %#v", format, data)
+			}
+			err = ApplyOverrides(data, []Override{
+				{Expr: "name=app"},
+				{Expr: "port=80", Kind: OverrideString},
+			}, &bytes.Buffer{})
+			if err != nil {
+				t.Fatalf("ApplyOverrides on null %s data failed: %v", format, err)
+			}
+			if data["name"] != "app" || data["port"] != "80" {
+				t.Errorf("Expected the overrides to be set on null %s data, got %#v", format, data)
+			}
+		}
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := ParseData([]byte("name = 'x'"), "toml", "<stdin>", DataOptions{})
+		if err == nil || !contains(err.Error(), "unsupported data format") {
+			t.Errorf("Expected unsupported format error, got: %v", err)
+		}
+	})
+}
+
+func TestDataFormat(t *testing.T) {
+	for path, want := range map[string]string{"a.json": "json", "a.YAML": "yaml", "dir/a.yml": "yaml"} {
+		got, err := DataFormat(path)
+		if err != nil || got != want {
+			t.Errorf("DataFormat(%q) = %q, %v; want %q", path, got, err, want)
+		}
+	}
+	if _, err := DataFormat("a.txt"); err == nil {
+		t.Error("Expected error for unsupported extension")
+	}
+}
+
+func TestParseDataNumbers(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		content string
+	}{
+		{
+			name:   "json",
+			format: "json",
+			content: `{"build": 1234567890123, "negative": -42, "ratio": 0.25, "big_float": 1.5e300,
+				"huge": 123456789012345678901234567890, "whole_float": 2.0,
+				"mixed": [1, 2.5, "3", 9007199254740993, {"n": 10}]}`,
+		},
+		{
+			name:   "yaml",
+			format: "yaml",
+			content: "build: 1234567890123\nnegative: -42\nratio: 0.25\nbig_float: 1.5e+300\n" +
+				"huge: \"123456789012345678901234567890\"\nwhole_float: 2.0\n" +
+				"mixed: [1, 2.5, \"3\", 9007199254740993, {n: 10}]\n",
+		},
+	}
+	want := map[string]any{
+		"build":       1234567890123,
+		"negative":    -42,
+		"ratio":       0.25,
+		"big_float":   1.5e300,
+		"huge":        "123456789012345678901234567890",
+		"whole_float": 2.0,
+		"mixed":       []any{1, 2.5, "3", 9007199254740993, map[string]any{"n": 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ParseData([]byte(tt.content), tt.format, "data", DataOptions{})
+			if err != nil {
+				t.Fatalf("ParseData failed: %v", err)
+			}
+			if !reflect.DeepEqual(data, want) {
+				t.Errorf("got %#v\nwant %#v", data, want)
+			}
+
+			var out bytes.Buffer
+			tmpl := "{{.build}} {{.negative}} {{.ratio}} {{.huge}} {{range .mixed}}{{.}};{{end}}"
+			renderer, err := NewRenderer("", true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = renderer.Render(&out, "numbers", []byte(tmpl), data); err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			wantOut := "1234567890123 -42 0.25 123456789012345678901234567890 1;2.5;3;9007199254740993;map[n:10];"
+			if out.String() != wantOut {
+				t.Errorf("Rendered %q; want %q", out.String(), wantOut)
+			}
+		})
+	}
+
+	t.Run("json trailing content is still an error", func(t *testing.T) {
+		_, err := ParseData([]byte(`{"a": 1} {"b": 2}`), "json", "data", DataOptions{})
+		if err == nil || !contains(err.Error(), "after top-level value") {
+			t.Errorf("Expected a trailing content error, got: %v", err)
+		}
+	})
+}
+
+func TestParseSetValue(t *testing.T) {
+	tests := []struct {
+		expr      string
+		wantKey   string
+		wantValue any
+		wantErr   bool
+	}{
+		{expr: "name=world", wantKey: "name", wantValue: "world"},
+		{expr: "port=8080", wantKey: "port", wantValue: 8080},
+		{expr: "ratio=0.5", wantKey: "ratio", wantValue: 0.5},
+		{expr: "enabled=true", wantKey: "enabled", wantValue: true},
+		{expr: "a.b.c=x=y", wantKey: "a.b.c", wantValue: "x=y"},
+		{expr: "empty=", wantKey: "empty", wantValue: ""},
+		{expr: "list=[1, 2]", wantKey: "list", wantValue: "[1, 2]"},
+		{expr: "map=a: b", wantKey: "map", wantValue: "a: b"},
+		{expr: "novalue", wantErr: true},
+		{expr: "=value", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		key, value, err := ParseSetValue(tt.expr)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseSetValue(%q): expected error", tt.expr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSetValue(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if key != tt.wantKey || value != tt.wantValue {
+			t.Errorf("ParseSetValue(%q) = %q, %#v; want %q, %#v", tt.expr, key, value, tt.wantKey, tt.wantValue)
+		}
+	}
+}
+
+func TestParseKeyPath(t *testing.T) {
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{key: "name", want: ".name"},
+		{key: "a.b.c", want: ".a.b.c"},
+		{key: "tags[]", want: ".tags[]"},
+		{key: "services[0].port", want: ".services[0].port"},
+		{key: "matrix[10][2]", want: ".matrix[10][2]"},
+		{key: "a[0][].b[]", want: ".a[0][].b[]"},
+		{key: "a[007]", want: ".a[7]"},
+		{key: "", wantErr: true},
+		{key: "[0]", wantErr: true},
+		{key: "a.[0]", wantErr: true},
+		{key: "a..b", wantErr: true},
+		{key: "a[", wantErr: true},
+		{key: "a]", want: ".a]"},
+		{key: "a[0", wantErr: true},
+		{key: "a[0]b", wantErr: true},
+		{key: "a[-1]", wantErr: true},
+		{key: "a[+1]", wantErr: true},
+		{key: "a[x]", wantErr: true},
+		{key: "a[ 1]", wantErr: true},
+		{key: "a[[0]]", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		segments, err := parseKeyPath(tt.key)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseKeyPath(%q): expected error, got %v", tt.key, segments)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseKeyPath(%q) failed: %v", tt.key, err)
+			continue
+		}
+		var got string
+		for _, segment := range segments {
+			got += segment.String()
+		}
+		if got != tt.want {
+			t.Errorf("parseKeyPath(%q) = %q; want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "cert.pem")
+	if err := os.WriteFile(certPath, []byte("-----BEGIN-----\nabc\n-----END-----\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	flagPath := filepath.Join(dir, "flag.txt")
+	if err := os.WriteFile(flagPath, []byte("true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	binPath := filepath.Join(dir, "blob.bin")
+	if err := os.WriteFile(binPath, []byte{0xff, 0x00, 0x01}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// set applies --set overrides to data.
+	set := func(data map[string]any, exprs ...string) error {
+		overrides := make([]Override, 0, len(exprs))
+		for _, expr := range exprs {
+			overrides = append(overrides, Override{Expr: expr})
+		}
+		return ApplyOverrides(data, overrides, &bytes.Buffer{})
+	}
+
+	t.Run("nested keys create maps and later values win", func(t *testing.T) {
+		data := map[string]any{
+			"name": "old",
+			"db":   map[string]any{"host": "localhost", "port": 5432},
+		}
+
+		err := set(data, "name=new", "db.port=6543", "app.meta.owner=team", "name=newer")
+		if err != nil {
+			t.Fatalf("ApplyOverrides failed: %v", err)
+		}
+
+		if data["name"] != "newer" {
+			t.Errorf("Expected name 'newer', got %v", data["name"])
+		}
+		db, _ := data["db"].(map[string]any)
+		if db["port"] != 6543 || db["host"] != "localhost" {
+			t.Errorf("Unexpected db map: %v", db)
+		}
+		app, _ := data["app"].(map[string]any)
+		meta, _ := app["meta"].(map[string]any)
+		if meta["owner"] != "team" {
+			t.Errorf("Expected app.meta.owner 'team', got %v", data["app"])
+		}
+	})
+
+	t.Run("setting below a scalar fails", func(t *testing.T) {
+		data := map[string]any{"name": "value"}
+
+		err := set(data, "name.first=x")
+		if err == nil || !contains(err.Error(), "'name' is a string, not a map") {
+			t.Errorf("Expected type conflict error, got: %v", err)
+		}
+	})
+
+	t.Run("empty path segment", func(t *testing.T) {
+		for _, expr := range []string{"a..b=x", "a.=x"} {
+			if err := set(map[string]any{}, expr); err == nil {
+				t.Errorf("Expected error for %q", expr)
+			}
+		}
+	})
+
+	t.Run("list indices and appends", func(t *testing.T) {
+		data := map[string]any{
+			"services": []any{map[string]any{"name": "api", "port": 8080}},
+			"tags":     []any{"alpha"},
+		}
+
+		err := set(data,
+			"services[0].port=9090",
+			"services[1].name=worker",
+			"tags[]=beta",
+			"tags[2]=gamma",
+			"matrix[0][]=1",
+			"hosts[].name=db",
+		)
+		if err != nil {
+			t.Fatalf("ApplyOverrides failed: %v", err)
+		}
+
+		want := map[string]any{
+			"services": []any{
+				map[string]any{"name": "api", "port": 9090},
+				map[string]any{"name": "worker"},
+			},
+			"tags":   []any{"alpha", "beta", "gamma"},
+			"matrix": []any{[]any{1}},
+			"hosts":  []any{map[string]any{"name": "db"}},
+		}
+		if !reflect.DeepEqual(data, want) {
+			t.Errorf("Unexpected data:\n got: %#v\nwant: %#v", data, want)
+		}
+	})
+
+	t.Run("index errors name the full key path", func(t *testing.T) {
+		tests := []struct {
+			expr    string
+			wantErr string
+		}{
+			{expr: "tags[5]=x", wantErr: "index 5 is out of range for 'tags' of length 1"},
+			{expr: "db[0]=x", wantErr: "'db' is a map, not a list"},
+			{expr: "tags.first=x", wantErr: "'tags' is a list, not a map"},
+			{expr: "db.port.value=x", wantErr: "'db.port' is a number, not a map"},
+			{expr: "db.tls[0]=x", wantErr: "'db.tls' is a boolean, not a list"},
+			{expr: "db.hosts[0].name[1]=x", wantErr: "'db.hosts[0].name' is a string, not a list"},
+		}
+		for _, tt := range tests {
+			data := map[string]any{
+				"tags": []any{"alpha"},
+				"db":   map[string]any{"hosts": []any{map[string]any{"name": "primary"}}, "port": 5432, "tls": true},
+			}
+			err := set(data, tt.expr)
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("ApplyOverrides(%q): expected error containing %q, got: %v", tt.expr, tt.wantErr, err)
+			}
+		}
+	})
+
+	t.Run("file values are strings and the last override wins", func(t *testing.T) {
+		data := map[string]any{"tls": map[string]any{"enabled": true}}
+		var warn bytes.Buffer
+
+		err := ApplyOverrides(data, []Override{
+			{Expr: "tls.cert=" + certPath, Kind: OverrideFile},
+			{Expr: "flag=" + flagPath, Kind: OverrideFile},
+			{Expr: "name=file"},
+			{Expr: "name=" + flagPath, Kind: OverrideFile},
+			{Expr: "port=80"},
+			{Expr: "port=" + flagPath, Kind: OverrideFile},
+			{Expr: "port=8080"},
+		}, &warn)
+		if err != nil {
+			t.Fatalf("ApplyOverrides failed: %v", err)
+		}
+
+		tls, _ := data["tls"].(map[string]any)
+		if tls["cert"] != "-----BEGIN-----\nabc\n-----END-----\n" || tls["enabled"] != true {
+			t.Errorf("Expected only one trailing newline to be stripped, got %v", tls)
+		}
+		if data["flag"] != "true" || data["name"] != "true" {
+			t.Errorf("Expected file values to stay strings, got %#v and %#v", data["flag"], data["name"])
+		}
+		if data["port"] != 8080 {
+			t.Errorf("Expected the last override to win, got %#v", data["port"])
+		}
+		if warn.Len() != 0 {
+			t.Errorf("Expected no warning for text files, got %q", warn.String())
+		}
+	})
+
+	t.Run("binary file is used with a warning", func(t *testing.T) {
+		data := map[string]any{}
+		var warn bytes.Buffer
+
+		if err := ApplyOverrides(data, []Override{{Expr: "blob=" + binPath, Kind: OverrideFile}}, &warn); err != nil {
+			t.Fatalf("ApplyOverrides failed: %v", err)
+		}
+		if data["blob"] != "\xff\x00\x01" {
+			t.Errorf("Expected the raw content, got %q", data["blob"])
+		}
+		if !contains(warn.String(), "is binary") {
+			t.Errorf("Expected a binary warning, got %q", warn.String())
+		}
+	})
+
+	t.Run("string values are never interpreted", func(t *testing.T) {
+		tests := []struct {
+			raw       string
+			wantValue any
+		}{
+			{raw: "1.10", wantValue: 1.1},
+			{raw: "42", wantValue: 42},
+			{raw: "0x10", wantValue: 16},
+			{raw: "true", wantValue: true},
+			{raw: "no", wantValue: "no"},
+			{raw: "null", wantValue: nil},
+			{raw: "~", wantValue: nil},
+			{raw: "", wantValue: ""},
+			{raw: "a=b", wantValue: "a=b"},
+		}
+		for _, tt := range tests {
+			data := map[string]any{}
+			err := ApplyOverrides(data, []Override{
+				{Expr: "value=" + tt.raw},
+				{Expr: "list[]=" + tt.raw, Kind: OverrideString},
+			}, &bytes.Buffer{})
+			if err != nil {
+				t.Fatalf("ApplyOverrides(%q) failed: %v", tt.raw, err)
+			}
+			if data["value"] != tt.wantValue {
+				t.Errorf("--set value=%s: got %#v; want %#v", tt.raw, data["value"], tt.wantValue)
+			}
+			if list, _ := data["list"].([]any); len(list) != 1 || list[0] != tt.raw {
+				t.Errorf("--set-string list[]=%s: got %#v; want [%q]", tt.raw, data["list"], tt.raw)
+			}
+		}
+
+		err := ApplyOverrides(map[string]any{}, []Override{{Expr: "novalue", Kind: OverrideString}}, &bytes.Buffer{})
+		if err == nil || !contains(err.Error(), "invalid --set-string value 'novalue'") {
+			t.Errorf("Expected invalid --set-string error, got: %v", err)
+		}
+	})
+
+	t.Run("nil data fails instead of panicking", func(t *testing.T) {
+		err := ApplyOverrides(nil, []Override{{Expr: "name=app"}}, &bytes.Buffer{})
+		if err == nil || !contains(err.Error(), "cannot set 'name'") {
+			t.Errorf("Expected an error setting a value in nil data, got: %v", err)
+		}
+	})
+
+	t.Run("invalid and missing files fail", func(t *testing.T) {
+		tests := []struct {
+			expr    string
+			wantErr string
+		}{
+			{expr: "nokey", wantErr: "expected key=path"},
+			{expr: "=" + certPath, wantErr: "expected key=path"},
+			{expr: "key=", wantErr: "expected key=path"},
+			{expr: "key=" + filepath.Join(dir, "missing"), wantErr: "failed to read --set-file"},
+		}
+		for _, tt := range tests {
+			err := ApplyOverrides(map[string]any{}, []Override{{Expr: tt.expr, Kind: OverrideFile}}, &bytes.Buffer{})
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("ApplyOverrides(%q): expected error containing %q, got: %v", tt.expr, tt.wantErr, err)
+			}
+		}
+	})
+}
+
+func TestMergeData(t *testing.T) {
+	src := map[string]any{
+		"db":   map[string]any{"engine": "postgres"},
+		"tags": []any{"b"},
+	}
+	dst := map[string]any{
+		"db":   map[string]any{"engine": "sqlite", "port": 5432},
+		"tags": []any{"a"},
+		"name": "app",
+	}
+
+	MergeData(dst, src)
+
+	db, _ := dst["db"].(map[string]any)
+	if db["engine"] != "postgres" || db["port"] != 5432 {
+		t.Errorf("Expected nested maps to be merged, got %v", db)
+	}
+	if tags, _ := dst["tags"].([]any); len(tags) != 1 || tags[0] != "b" {
+		t.Errorf("Expected lists to be replaced, got %v", dst["tags"])
+	}
+	if dst["name"] != "app" {
+		t.Errorf("Expected untouched key to be kept, got %v", dst["name"])
+	}
+
+	fresh := make(map[string]any)
+	MergeData(fresh, src)
+	fresh["db"].(map[string]any)["engine"] = "changed"
+	if src["db"].(map[string]any)["engine"] != "postgres" {
+		t.Error("Expected merged maps to be copied")
+	}
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// derivedCases are the case variants added next to each top-level string of
+// the data when a template sets deriveCases, by key suffix.
+//
+//nolint:gochecknoglobals // read-only table of the derived cases
+var derivedCases = []struct {
+	suffix  string
+	convert func(*Casing, string) string
+}{
+	{"_snake", (*Casing).Snake},
+	{"_usnake", (*Casing).UpperSnake},
+	{"_kebab", (*Casing).Kebab},
+	{"_camel", (*Casing).Camel},
+	{"_lcamel", (*Casing).LowerCamel},
+}
+
+// DeriveCases adds the case variants of every top-level string value of data
+// under keys with the suffixes _snake, _usnake, _kebab, _camel and _lcamel,
+// so "service_name" gets "service_name_camel". The values of secret prompts
+// get none, they would show in messages unmasked. A key already in data is
+// kept and reported to warn. It returns the keys it added, sorted.
+func DeriveCases(data map[string]any, casing *Casing, prompts Prompts, warn io.Writer) []string {
+	secret := make(map[string]bool)
+	for _, prompt := range prompts {
+		if prompt.Secret {
+			secret[prompt.Name] = true
+		}
+	}
+	var derived []string
+	// Only the keys given are derived from, not the keys added.
+	for _, key := range slices.Sorted(maps.Keys(data)) {
+		value, ok := data[key].(string)
+		if !ok || secret[key] {
+			continue
+		}
+		for _, c := range derivedCases {
+			name := key + c.suffix
+			if _, exists := data[name]; exists {
+				fmt.Fprintf(warn, "⚠️  Keeping '%s' from the data, it hides the %s case derived from '%s'\n",
+					name, strings.TrimPrefix(c.suffix, "_"), key)
+				continue
+			}
+			data[name] = c.convert(casing, value)
+			derived = append(derived, name)
+		}
+	}
+	slices.Sort(derived)
+	return derived
+}
+
+// IsDerivedCase reports whether key is a case variant DeriveCases adds for
+// one of the given keys, and returns that key.
+func IsDerivedCase(key string, keys map[string]bool) (string, bool) {
+	for _, c := range derivedCases {
+		if base, ok := strings.CutSuffix(key, c.suffix); ok && keys[base] {
+			return base, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDeriveCases(t *testing.T) {
+	data := map[string]any{
+		"service_name":       "HTTPGateway",
+		"service_name_kebab": "gw",
+		"port":               8080,
+		"token":              "s3cret",
+		"db":                 map[string]any{"name": "orders"},
+	}
+	prompts := Prompts{{Name: "token", Secret: true}}
+	var warn bytes.Buffer
+	derived := DeriveCases(data, NewCasing(nil), prompts, &warn)
+
+	want := map[string]string{
+		"service_name_snake":  "http_gateway",
+		"service_name_usnake": "HTTP_GATEWAY",
+		"service_name_camel":  "HTTPGateway",
+		"service_name_lcamel": "httpGateway",
+		// The key given wins.
+		"service_name_kebab": "gw",
+		// Derived from the key given, not from what was added.
+		"service_name_kebab_camel": "Gw",
+	}
+	for key, value := range want {
+		if data[key] != value {
+			t.Errorf("Expected '%s' to be %q, got %v", key, value, data[key])
+		}
+	}
+	if len(derived) != 9 || derived[0] != "service_name_camel" {
+		t.Errorf("Expected the 9 added keys sorted, got %v", derived)
+	}
+	for key := range data {
+		if strings.HasPrefix(key, "port_") || strings.HasPrefix(key, "token_") || strings.HasPrefix(key, "db_") {
+			t.Errorf("Expected no case derived from '%s'", key)
+		}
+	}
+	warning := "⚠️  Keeping 'service_name_kebab' from the data, it hides the kebab case derived from 'service_name'\n"
+	if warn.String() != warning {
+		t.Errorf("Expected %q, got %q", warning, warn.String())
+	}
+
+	if base, ok := IsDerivedCase("service_name_lcamel", map[string]bool{"service_name": true}); !ok ||
+		base != "service_name" {
+		t.Errorf("Expected service_name_lcamel to derive from service_name, got %q, %v", base, ok)
+	}
+	if _, ok := IsDerivedCase("service_name_title", map[string]bool{"service_name": true}); ok {
+		t.Errorf("Expected no title case to be derived")
+	}
+}
+
+func TestApplyDeriveCases(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile: "minMoldVersion: 0.8.0\nderiveCases: true\nacronyms: [K8s]\n" +
+			"prompts:\n  service_name: {}\n",
+		"{{.service_name_kebab}}/main.go.tmpl": "package {{.service_name_snake}} // {{.service_name_camel}}",
+	})
+	outputDir := t.TempDir()
+	data := map[string]any{"service_name": "k8s_watcher"}
+	if err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: io.Discard}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	files := readFiles(t, outputDir)
+	if got, want := files["k8s-watcher/main.go"], "package k8s_watcher // K8sWatcher"; got != want {
+		t.Errorf("Expected %q, got %q in %v", want, got, files)
+	}
+	provenance, err := LoadProvenance(outputDir)
+	if err != nil {
+		t.Fatalf("LoadProvenance failed: %v", err)
+	}
+	if len(provenance.Data) != 1 {
+		t.Errorf("Expected the derived keys left out of the provenance, got %v", provenance.Data)
+	}
+
+	findings, err := Lint(templateDir)
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("Expected the derived keys to count as declared and to use service_name, got %v", findings)
+	}
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// diffOp is one line of a line-based diff: kept (' '), removed ('-') or
+// added ('+'). aIdx and bIdx are the positions in both texts before the line.
+type diffOp struct {
+	kind byte
+	line string
+	aIdx int
+	bIdx int
+}
+
+// UnifiedDiff returns a unified diff turning a into b, with aName and bName
+// as file names in the header, or an empty string when both are equal.
+func UnifiedDiff(aName, bName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+	for start := 0; start < len(ops); {
+		// Find the next change.
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		// Extend the hunk while changes are close enough to share context.
+		last := first
+		for end := first; end < len(ops) && end-last <= 2*diffContext; end++ {
+			if ops[end].kind != ' ' {
+				last = end
+			}
+		}
+		lo := max(first-diffContext, start)
+		hi := min(last+diffContext+1, len(ops))
+		writeHunk(&out, ops[lo:hi])
+		start = hi
+	}
+	return out.String()
+}
+
+// writeHunk writes a hunk header followed by its lines.
+func writeHunk(out *strings.Builder, hunk []diffOp) {
+	aStart, bStart := hunk[0].aIdx, hunk[0].bIdx
+	var aCount, bCount int
+	for _, op := range hunk {
+		if op.kind != '+' {
+			aCount++
+		}
+		if op.kind != '-' {
+			bCount++
+		}
+	}
+	// Ranges start at line 1, empty ranges at the line before them.
+	if aCount > 0 {
+		aStart++
+	}
+	if bCount > 0 {
+		bStart++
+	}
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+	for _, op := range hunk {
+		out.WriteByte(op.kind)
+		out.WriteString(op.line)
+		if !strings.HasSuffix(op.line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// splitLines splits text into lines that keep their newline.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes the line operations turning a into b from their longest
+// common subsequence. Removals come before additions within a change.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, max(n, m))
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', line: a[i], aIdx: i, bIdx: j})
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{kind: '-', line: a[i], aIdx: i, bIdx: j})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: b[j], aIdx: i, bIdx: j})
+			j++
+		}
+	}
+	return ops
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// CheckStatus is the outcome of a doctor check.
+type CheckStatus string
+
+const (
+	// CheckOK means nothing needs attention.
+	CheckOK CheckStatus = "OK"
+	// CheckWarn means something may not work as expected.
+	CheckWarn CheckStatus = "WARN"
+	// CheckFail means something is broken.
+	CheckFail CheckStatus = "FAIL"
+)
+
+// Check is the result of one doctor check. Hint tells how to fix a warning
+// or failure.
+type Check struct {
+	Name    string      `json:"name"`
+	Status  CheckStatus `json:"status"`
+	Message string      `json:"message"`
+	Hint    string      `json:"hint,omitempty"`
+}
+
+// CheckTemplatesDir checks that the templates directory exists and can be
+// read.
+func CheckTemplatesDir(dir string) Check {
+	check := Check{Name: "templates directory"}
+	info, err := os.Stat(dir)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		check.Status = CheckWarn
+		check.Message = fmt.Sprintf("'%s' does not exist, templates can only be applied by path", dir)
+		check.Hint = "Run 'mold init' to create it"
+		return check
+	case err != nil:
+		check.Status = CheckFail
+		check.Message = fmt.Sprintf("cannot access '%s': %v", dir, err)
+		check.Hint = "Check the permissions of its parent directories"
+		return check
+	case !info.IsDir():
+		check.Status = CheckFail
+		check.Message = fmt.Sprintf("'%s' is not a directory", dir)
+		check.Hint = "Point --templates-dir or " + TemplatesDirEnv + " at a directory"
+		return check
+	}
+	if _, err = os.ReadDir(dir); err != nil {
+		check.Status = CheckFail
+		check.Message = fmt.Sprintf("cannot read '%s': %v", dir, err)
+		check.Hint = fmt.Sprintf("Check the permissions of '%s'", dir)
+		return check
+	}
+	check.Status = CheckOK
+	check.Message = fmt.Sprintf("'%s' is readable", dir)
+	return check
+}
+
+// ListTemplates returns the names of the templates in templatesDir, sorted.
+// A directory is a template when it holds a template.yaml file, or any file
+// at all. Directories only holding directories group nested templates, such
+// as 'go' for 'go/service', unless they are a template in the versions
+// layout. Hidden directories are skipped.
+func ListTemplates(templatesDir string) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(templatesDir, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if !d.IsDir() || path == templatesDir {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		if !IsVersioned(path) && !slices.ContainsFunc(entries, func(e fs.DirEntry) bool { return !e.IsDir() }) {
+			return nil
+		}
+		rel, err := filepath.Rel(templatesDir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the templates of '%s': %w", templatesDir, err)
+	}
+	return names, nil
+}
+
+// CheckTemplate checks that the template.yaml of a template, and of the
+// templates it extends, parses, that its templates and paths compile, and
+// that the commands of its formatters are installed.
+func CheckTemplate(name, templatePath string) Check {
+	check := Check{Name: "template " + name}
+	_, meta, err := ResolveChain(templatePath)
+	if err != nil {
+		check.Status = CheckFail
+		check.Message = err.Error()
+		check.Hint = "Fix " + MetadataFile + ", or the template it extends"
+		return check
+	}
+	findings, err := Lint(templatePath)
+	if err != nil {
+		check.Status = CheckFail
+		check.Message = err.Error()
+		check.Hint = fmt.Sprintf("Check the permissions of '%s'", templatePath)
+		return check
+	}
+	var broken []string
+	for _, finding := range findings {
+		if (finding.Rule == RuleParseError || finding.Rule == RuleUnknownFunc) &&
+			!slices.Contains(broken, finding.Path) {
+			broken = append(broken, finding.Path)
+		}
+	}
+	if len(broken) > 0 {
+		check.Status = CheckFail
+		check.Message = fmt.Sprintf("%d files or paths don't compile: %s", len(broken), strings.Join(broken, ", "))
+		check.Hint = fmt.Sprintf("Run 'mold lint %s' for the details", templatePath)
+		return check
+	}
+	if missing := missingFormatters(meta); len(missing) > 0 {
+		check.Status = CheckWarn
+		check.Message = "formatters not found on the PATH: " + strings.Join(missing, ", ")
+		check.Hint = "Install them, or apply with --no-format"
+		return check
+	}
+	check.Status = CheckOK
+	check.Message = fmt.Sprintf("%s parses and its templates compile", MetadataFile)
+	return check
+}
+
+// missingFormatters returns the formatter commands of the metadata that are
+// not installed, sorted. The builtin gofmt needs no binary.
+func missingFormatters(meta *Metadata) []string {
+	var missing []string
+	for _, command := range meta.Formatters {
+		if isBuiltinGofmt(command) || slices.Contains(missing, command[0]) {
+			continue
+		}
+		if _, err := exec.LookPath(command[0]); err != nil {
+			missing = append(missing, command[0])
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
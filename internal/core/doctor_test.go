@@ -0,0 +1,93 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckTemplatesDir(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		dir      string
+		want     CheckStatus
+		wantHint string
+	}{
+		{dir: dir, want: CheckOK},
+		{dir: filepath.Join(dir, "missing"), want: CheckWarn, wantHint: "mold init"},
+		{dir: file, want: CheckFail, wantHint: TemplatesDirEnv},
+	}
+	for _, tt := range tests {
+		check := CheckTemplatesDir(tt.dir)
+		if check.Status != tt.want || !contains(check.Hint, tt.wantHint) {
+			t.Errorf("%s: expected %s with a hint about %q, got %+v", tt.dir, tt.want, tt.wantHint, check)
+		}
+	}
+}
+
+func TestListTemplates(t *testing.T) {
+	dir := writeTemplate(t, map[string]string{
+		".gitkeep":                   "",
+		".cache/x/file":              "",
+		"go/service/" + MetadataFile: "name: go/service",
+		"go/service/sub/a.txt":       "",
+		"go/cli/main.go.tmpl":        "",
+		"plain/README.md":            "",
+	})
+	names, err := ListTemplates(dir)
+	if err != nil {
+		t.Fatalf("ListTemplates failed: %v", err)
+	}
+	if want := []string{"go/cli", "go/service", "plain"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected %v, got %v", want, names)
+	}
+}
+
+func TestCheckTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		want     CheckStatus
+		wantText string
+	}{
+		{
+			name:     "valid",
+			files:    map[string]string{MetadataFile: "formatters:\n  '*.go': [gofmt]\n", "main.go.tmpl": "{{.name}}"},
+			want:     CheckOK,
+			wantText: "parses",
+		},
+		{
+			name:     "invalid metadata",
+			files:    map[string]string{MetadataFile: "prompts: [oops"},
+			want:     CheckFail,
+			wantText: "failed to parse template metadata",
+		},
+		{
+			name:     "broken template",
+			files:    map[string]string{"main.go.tmpl": "{{.name", "{{if .name}}.txt": ""},
+			want:     CheckFail,
+			wantText: "2 files or paths don't compile",
+		},
+		{
+			name:     "missing formatter",
+			files:    map[string]string{MetadataFile: "formatters:\n  '*.txt': [mold-no-such-formatter]\n"},
+			want:     CheckWarn,
+			wantText: "mold-no-such-formatter",
+		},
+	}
+	for _, tt := range tests {
+		check := CheckTemplate(tt.name, writeTemplate(t, tt.files))
+		if check.Status != tt.want || !contains(check.Message, tt.wantText) {
+			t.Errorf("%s: expected %s with %q, got %+v", tt.name, tt.want, tt.wantText, check)
+		}
+		if check.Status != CheckOK && check.Hint == "" {
+			t.Errorf("%s: expected a hint, got %+v", tt.name, check)
+		}
+	}
+}
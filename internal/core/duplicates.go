@@ -0,0 +1,168 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DataOptions configures how data files are parsed.
+type DataOptions struct {
+	// Strict rejects keys defined twice in the same mapping, and comments
+	// and trailing commas in '.json' files. Otherwise the later value of a
+	// duplicate key wins and a warning is written to Warn.
+	Strict bool
+	// YAML11Bools turns the unquoted YAML 1.1 boolean words yes, no, on,
+	// off, y and n, in any case, into booleans. In strict mode they are
+	// reported as warnings instead.
+	YAML11Bools bool
+	// Warn receives warnings about the data. Nil discards them.
+	Warn io.Writer
+}
+
+// DuplicateKey is a key defined more than once in the same mapping of a data
+// file.
+type DuplicateKey struct {
+	// Path is the full key path, such as "services[1].port".
+	Path string
+	// Line is the line of the later definition, FirstLine the line of the
+	// first one.
+	Line      int
+	FirstLine int
+}
+
+// String describes the duplicate for messages.
+func (d DuplicateKey) String() string {
+	return fmt.Sprintf("duplicate key '%s' at line %d, first defined at line %d", d.Path, d.Line, d.FirstLine)
+}
+
+// checkDuplicates fails on duplicate keys in strict mode and warns about them
+// otherwise. kind names the format in messages.
+func (o DataOptions) checkDuplicates(kind, source string, duplicates []DuplicateKey) error {
+	if len(duplicates) == 0 {
+		return nil
+	}
+	if o.Strict {
+		msgs := make([]string, 0, len(duplicates))
+		for _, d := range duplicates {
+			msgs = append(msgs, d.String())
+		}
+		return fmt.Errorf("failed to parse %s file '%s': %s", kind, source, strings.Join(msgs, "; "))
+	}
+	if o.Warn != nil {
+		for _, d := range duplicates {
+			fmt.Fprintf(o.Warn, "⚠️  Data file '%s' has a %s, the later value wins\n", source, d)
+		}
+	}
+	return nil
+}
+
+// joinKeyPath appends a mapping key to a key path.
+func joinKeyPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// pruneYAMLDuplicates removes the earlier definitions of keys defined more
+// than once in the same mapping under node, so the later value wins, and
+// returns the duplicates found.
+func pruneYAMLDuplicates(node *yaml.Node, path string) []DuplicateKey {
+	var duplicates []DuplicateKey
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			duplicates = append(duplicates, pruneYAMLDuplicates(child, path)...)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			duplicates = append(duplicates, pruneYAMLDuplicates(child, path+"["+strconv.Itoa(i)+"]")...)
+		}
+	case yaml.MappingNode:
+		// Drop every definition of a key but the last one.
+		last := make(map[string]int)
+		first := make(map[string]int)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode || key.Tag == "!!merge" {
+				continue
+			}
+			if j, ok := last[key.Value]; ok {
+				duplicates = append(duplicates, DuplicateKey{
+					Path:      joinKeyPath(path, key.Value),
+					Line:      key.Line,
+					FirstLine: node.Content[first[key.Value]].Line,
+				})
+				node.Content[j+1] = nil
+			} else {
+				first[key.Value] = i
+			}
+			last[key.Value] = i
+		}
+		content := node.Content[:0]
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i+1] == nil {
+				continue
+			}
+			content = append(content, node.Content[i], node.Content[i+1])
+			childPath := joinKeyPath(path, node.Content[i].Value)
+			duplicates = append(duplicates, pruneYAMLDuplicates(node.Content[i+1], childPath)...)
+		}
+		node.Content = content
+	}
+	return duplicates
+}
+
+// jsonDuplicates returns the keys defined more than once in the same object
+// of JSON content. Invalid content is left for json.Unmarshal to report.
+func jsonDuplicates(content []byte) []DuplicateKey {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	var duplicates []DuplicateKey
+	if err := walkJSON(dec, content, "", &duplicates); err != nil {
+		return nil
+	}
+	return duplicates
+}
+
+// walkJSON reads the next JSON value from dec and records its duplicate keys.
+func walkJSON(dec *json.Decoder, content []byte, path string, duplicates *[]DuplicateKey) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch token {
+	case json.Delim('{'):
+		lines := make(map[string]int)
+		for dec.More() {
+			if token, err = dec.Token(); err != nil {
+				return err
+			}
+			key, _ := token.(string)
+			line := 1 + bytes.Count(content[:dec.InputOffset()], []byte("\n"))
+			if firstLine, ok := lines[key]; ok {
+				*duplicates = append(*duplicates,
+					DuplicateKey{Path: joinKeyPath(path, key), Line: line, FirstLine: firstLine})
+			} else {
+				lines[key] = line
+			}
+			if err = walkJSON(dec, content, joinKeyPath(path, key), duplicates); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+	case json.Delim('['):
+		for i := 0; dec.More(); i++ {
+			if err = walkJSON(dec, content, path+"["+strconv.Itoa(i)+"]", duplicates); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+	}
+	return err
+}
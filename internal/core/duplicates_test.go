@@ -0,0 +1,136 @@
+package core
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDuplicateKeys(t *testing.T) {
+	yamlContent := strings.Join([]string{
+		"name: first",                // 1
+		"db:",                        // 2
+		"  host: localhost",          // 3
+		"  options:",                 // 4
+		"    ssl: false",             // 5
+		"    ssl: true",              // 6
+		"  host: prod",               // 7
+		"services:",                  // 8
+		"  - name: api",              // 9
+		"    port: '80'",             // 10
+		"  - name: worker",           // 11
+		"    name: jobs",             // 12
+		"name: second",               // 13
+		"list: [{a: 1, b: 2, a: 3}]", // 14
+	}, "\n")
+	jsonContent := strings.Join([]string{
+		`{`,                                  // 1
+		`  "name": "first",`,                 // 2
+		`  "db": {`,                          // 3
+		`    "host": "localhost",`,           // 4
+		`    "options": {"ssl": false,`,      // 5
+		`                "ssl": true},`,      // 6
+		`    "host": "prod"`,                 // 7
+		`  },`,                               // 8
+		`  "services": [`,                    // 9
+		`    {"name": "api", "port": "80"},`, // 10
+		`    {"name": "worker",`,             // 11
+		`     "name": "jobs"}`,               // 12
+		`  ],`,                               // 13
+		`  "name": "second"`,                 // 14
+		`}`,                                  // 15
+	}, "\n")
+	wantData := map[string]any{
+		"name": "second",
+		"db":   map[string]any{"host": "prod", "options": map[string]any{"ssl": true}},
+		"services": []any{
+			map[string]any{"name": "api", "port": "80"},
+			map[string]any{"name": "jobs"},
+		},
+	}
+
+	tests := []struct {
+		format  string
+		content string
+		want    []string
+		data    map[string]any
+	}{
+		{
+			format:  "yaml",
+			content: yamlContent,
+			want: []string{
+				"duplicate key 'db.options.ssl' at line 6, first defined at line 5",
+				"duplicate key 'db.host' at line 7, first defined at line 3",
+				"duplicate key 'services[1].name' at line 12, first defined at line 11",
+				"duplicate key 'name' at line 13, first defined at line 1",
+				"duplicate key 'list[0].a' at line 14, first defined at line 14",
+			},
+		},
+		{
+			format:  "json",
+			content: jsonContent,
+			want: []string{
+				"duplicate key 'db.options.ssl' at line 6, first defined at line 5",
+				"duplicate key 'db.host' at line 7, first defined at line 4",
+				"duplicate key 'services[1].name' at line 12, first defined at line 11",
+				"duplicate key 'name' at line 14, first defined at line 2",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+" strict", func(t *testing.T) {
+			_, err := ParseData([]byte(tt.content), tt.format, "data."+tt.format, DataOptions{Strict: true})
+			if err == nil {
+				t.Fatal("Expected duplicate key error")
+			}
+			for _, want := range tt.want {
+				if !contains(err.Error(), want) {
+					t.Errorf("Expected error to contain %q, got: %v", want, err)
+				}
+			}
+		})
+
+		t.Run(tt.format+" warns and the later value wins", func(t *testing.T) {
+			var warn bytes.Buffer
+			data, err := ParseData([]byte(tt.content), tt.format, "data."+tt.format, DataOptions{Warn: &warn})
+			if err != nil {
+				t.Fatalf("ParseData failed: %v", err)
+			}
+			delete(data, "list")
+			if !reflect.DeepEqual(data, wantData) {
+				t.Errorf("got %#v; want %#v", data, wantData)
+			}
+			if lines := strings.Count(warn.String(), "\n"); lines != len(tt.want) {
+				t.Errorf("Expected %d warnings, got:\n%s", len(tt.want), warn.String())
+			}
+			if !contains(warn.String(), "Data file 'data."+tt.format+"' has a "+tt.want[0]+", the later value wins") {
+				t.Errorf("Unexpected warnings:\n%s", warn.String())
+			}
+		})
+	}
+
+	t.Run("multiple yaml documents are checked separately", func(t *testing.T) {
+		content := "name: base\n---\nname: override\nport: 1\nport: 2\n"
+		_, err := ParseData([]byte(content), "yaml", "data.yaml", DataOptions{Strict: true})
+		if err == nil || !contains(err.Error(), "duplicate key 'port' at line 5, first defined at line 4") ||
+			contains(err.Error(), "'name'") {
+			t.Errorf("Expected only the duplicate within the second document, got: %v", err)
+		}
+	})
+
+	t.Run("no duplicates", func(t *testing.T) {
+		for format, content := range map[string]string{
+			"yaml": "a: 1\nb: {a: 2}\nc: [{a: 1}, {a: 2}]\n",
+			"json": `{"a": 1, "b": {"a": 2}, "c": [{"a": 1}, {"a": 2}]}`,
+		} {
+			var warn bytes.Buffer
+			if _, err := ParseData([]byte(content), format, "data", DataOptions{Strict: true, Warn: &warn}); err != nil {
+				t.Errorf("ParseData(%s) failed: %v", format, err)
+			}
+			if warn.Len() != 0 {
+				t.Errorf("Expected no warnings for %s, got %q", format, warn.String())
+			}
+		}
+	})
+}
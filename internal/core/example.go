@@ -0,0 +1,68 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ExampleTemplateName is the name of the starter template created by
+// 'mold init --with-example'.
+const ExampleTemplateName = "example"
+
+// exampleTemplate holds the files of the starter template. tmpl.yaml doubles
+// as the example data file.
+//
+//nolint:gochecknoglobals // content of the starter template
+var exampleTemplate = map[string]string{
+	MetadataFile: `name: example
+description: A minimal Go program with a README, to show how templates work.
+prompts:
+  project_name:
+    description: Name of the project, also used as the directory of the program
+    type: string
+formatters:
+  "**/*.go": ["gofmt"]
+`,
+	"README.md.tmpl": `# {{.project_name}}
+
+Generated by mold from the example template.
+
+Run it with:
+
+` + "```sh" + `
+go run ./{{.project_name}}
+` + "```" + `
+`,
+	"{{.project_name}}/main.go.tmpl": `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("Hello from {{.project_name}}!")
+}
+`,
+	"tmpl.yaml": `# Example data for the template. Copy and edit it, then run:
+#   mold apply example -d tmpl.yaml -o my-project
+project_name: hello
+`,
+}
+
+// WriteExampleTemplate creates the starter template in the directory path,
+// which must not exist yet.
+func WriteExampleTemplate(path string) error {
+	if err := os.Mkdir(path, 0750); err != nil {
+		return fmt.Errorf("failed to create example template '%s': %w", path, err)
+	}
+	for name, content := range exampleTemplate {
+		file := filepath.Join(path, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0750); err != nil {
+			return fmt.Errorf("failed to create directory '%s': %w", filepath.Dir(file), err)
+		}
+		//nolint:gosec // template files are meant to be shared
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write '%s': %w", file, err)
+		}
+	}
+	return nil
+}
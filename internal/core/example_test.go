@@ -0,0 +1,45 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteExampleTemplate(t *testing.T) {
+	examplePath := filepath.Join(t.TempDir(), ExampleTemplateName)
+	if err := WriteExampleTemplate(examplePath); err != nil {
+		t.Fatalf("WriteExampleTemplate failed: %v", err)
+	}
+
+	meta, err := LoadMetadata(examplePath)
+	if err != nil {
+		t.Fatalf("LoadMetadata failed: %v", err)
+	}
+	if meta.Name != ExampleTemplateName || meta.Description == "" || len(meta.Prompts) != 1 {
+		t.Errorf("Unexpected example metadata: %+v", meta)
+	}
+	data, err := LoadDataFile(filepath.Join(examplePath, "tmpl.yaml"), DataOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("Failed to load the example data: %v", err)
+	}
+
+	outputDir := t.TempDir()
+	err = Apply(Options{TemplatePath: examplePath, OutputDir: outputDir, Data: data, Strict: true, Out: &bytes.Buffer{}})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	for _, name := range []string{"README.md", filepath.Join("hello", "main.go")} {
+		if _, err = os.Stat(filepath.Join(outputDir, name)); err != nil {
+			t.Errorf("Expected '%s' to be generated: %v", name, err)
+		}
+	}
+	if _, err = os.Stat(filepath.Join(outputDir, "tmpl.yaml")); !os.IsNotExist(err) {
+		t.Errorf("Expected the example data not to be generated, got: %v", err)
+	}
+
+	if err = WriteExampleTemplate(examplePath); err == nil {
+		t.Error("Expected writing over an existing example to fail")
+	}
+}
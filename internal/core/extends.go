@@ -0,0 +1,60 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ResolveChain follows the extends field of the template's metadata up to
+// the root template. It returns the template paths root first, ending with
+// templatePath, and the metadata of the whole chain merged with child values
+// winning.
+func ResolveChain(templatePath string) ([]string, *Metadata, error) {
+	var chain []string
+	var metas []*Metadata
+	for path := templatePath; ; {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve template path '%s': %w", path, err)
+		}
+		if slices.Contains(chain, abs) {
+			return nil, nil, fmt.Errorf("template inheritance cycle: %s", formatChain(append(chain, abs)))
+		}
+		if _, err = os.Stat(abs); err != nil {
+			return nil, nil, fmt.Errorf("parent template '%s' of %s not found: %w", path, formatChain(chain), err)
+		}
+		chain = append(chain, abs)
+
+		meta, err := LoadMetadata(abs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w (in %s)", err, formatChain(chain))
+		}
+		metas = append(metas, meta)
+		if meta.Extends == "" {
+			break
+		}
+		path = meta.Extends
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(abs), path)
+		}
+	}
+
+	// The chain was collected child first.
+	slices.Reverse(chain)
+	slices.Reverse(metas)
+	merged := &Metadata{}
+	for _, meta := range metas {
+		merged = merged.merge(meta)
+	}
+	chain[len(chain)-1] = templatePath
+	return chain, merged, nil
+}
+
+// formatChain describes an inheritance chain, collected child first, as
+// "child -> parent -> ...".
+func formatChain(chain []string) string {
+	return strings.Join(chain, " -> ")
+}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolveChain(t *testing.T) {
+	// writeSibling creates a template named name in dir from a map of files.
+	writeSibling := func(t *testing.T, dir, name string, files map[string]string) string {
+		t.Helper()
+		for file, content := range files {
+			path := filepath.Join(dir, name, file)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("Failed to create template directory: %v", err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write template file: %v", err)
+			}
+		}
+		return filepath.Join(dir, name)
+	}
+
+	t.Run("merged chain", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSibling(t, dir, "base", map[string]string{
+			MetadataFile: `
+defaults:
+  db: {engine: sqlite, port: 0}
+  license: MIT
+prompts:
+  service: {description: base service}
+  org: {description: org}
+ignore: ["docs/**"]
+raw: ["assets"]
+formatters:
+  "**/*.go": ["gofmt"]
+`,
+		})
+		writeSibling(t, dir, "service", map[string]string{MetadataFile: `
+extends: base
+defaults:
+  db: {engine: postgres}
+prompts:
+  service: {description: service name}
+  port: {description: port}
+ignore: ["*.bak"]
+raw: ["web/vendor"]
+`})
+		child := writeSibling(t, dir, "child", map[string]string{
+			MetadataFile: "extends: ../" + filepath.Base(dir) + "/service\n",
+		})
+
+		chain, meta, err := ResolveChain(child)
+		if err != nil {
+			t.Fatalf("ResolveChain failed: %v", err)
+		}
+		want := []string{filepath.Join(dir, "base"), filepath.Join(dir, "service"), child}
+		if len(chain) != len(want) {
+			t.Fatalf("Expected chain %v, got %v", want, chain)
+		}
+		for i := range want {
+			if chain[i] != want[i] {
+				t.Errorf("Expected chain %v, got %v", want, chain)
+			}
+		}
+
+		db, _ := meta.Defaults["db"].(map[string]any)
+		if db["engine"] != "postgres" || db["port"] != 0 || meta.Defaults["license"] != "MIT" {
+			t.Errorf("Unexpected merged defaults: %v", meta.Defaults)
+		}
+		var names []string
+		for _, prompt := range meta.Prompts {
+			names = append(names, prompt.Name+"="+prompt.Description)
+		}
+		if got := len(names); got != 3 || names[0] != "service=service name" || names[2] != "port=port" {
+			t.Errorf("Unexpected merged prompts: %v", names)
+		}
+		if !meta.Ignored("docs/a.md") || !meta.Ignored("x.bak") {
+			t.Errorf("Expected both ignore lists, got %v", meta.Ignore)
+		}
+		if !meta.IsRaw("assets") || !meta.IsRaw("web/vendor") || meta.IsRaw("web") {
+			t.Errorf("Expected both raw lists, got %v", meta.Raw)
+		}
+		if len(meta.FormattersFor("main.go")) != 1 {
+			t.Errorf("Expected inherited formatter, got %v", meta.Formatters)
+		}
+	})
+
+	t.Run("missing parent names the chain", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSibling(t, dir, "mid", map[string]string{MetadataFile: "extends: gone\n"})
+		child := writeSibling(t, dir, "child", map[string]string{MetadataFile: "extends: mid\n"})
+
+		_, _, err := ResolveChain(child)
+		wantChain := filepath.Join(dir, "child") + " -> " + filepath.Join(dir, "mid")
+		if err == nil || !contains(err.Error(), "parent template '"+filepath.Join(dir, "gone")+"' of "+wantChain) {
+			t.Errorf("Expected missing parent error naming the chain, got: %v", err)
+		}
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSibling(t, dir, "a", map[string]string{MetadataFile: "extends: b\n"})
+		writeSibling(t, dir, "b", map[string]string{MetadataFile: "extends: a\n"})
+
+		_, _, err := ResolveChain(filepath.Join(dir, "a"))
+		a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+		if err == nil || !contains(err.Error(), "template inheritance cycle: "+a+" -> "+b+" -> "+a) {
+			t.Errorf("Expected cycle error, got: %v", err)
+		}
+	})
+
+	t.Run("apply overlays the child", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSibling(t, dir, "base", map[string]string{
+			MetadataFile: "name: base\nversion: 1.0.0\n" +
+				"defaults:\n  name: base-app\n  port: 80\nignore: [\"secret.txt\"]\n",
+			"README.md.tmpl":   "{{.name}}:{{.port}}",
+			"main.go":          "package base",
+			"secret.txt":       "never",
+			"docs/guide.md":    "guide",
+			"docs/internal.md": "internal",
+		})
+		child := writeSibling(t, dir, "child", map[string]string{
+			MetadataFile: "name: child\nextends: base\ndefaults:\n  port: 8080\nignore: [\"docs/internal.md\"]\n",
+			"main.go":    "package child",
+		})
+		outDir := t.TempDir()
+		var out bytes.Buffer
+
+		err := Apply(Options{TemplatePath: child, OutputDir: outDir, Data: map[string]any{"name": "app"}, Out: &out})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+
+		for file, want := range map[string]string{
+			"README.md":     "app:8080",
+			"main.go":       "package child",
+			"docs/guide.md": "guide",
+		} {
+			content, readErr := os.ReadFile(filepath.Join(outDir, file))
+			if readErr != nil || string(content) != want {
+				t.Errorf("File '%s' = %q, want %q (%v)", file, content, want, readErr)
+			}
+		}
+		for _, file := range []string{"secret.txt", "docs/internal.md"} {
+			if _, statErr := os.Stat(filepath.Join(outDir, file)); !os.IsNotExist(statErr) {
+				t.Errorf("Expected ignored file '%s' to be skipped", file)
+			}
+		}
+		if !contains(out.String(), "🧬 Template chain: "+filepath.Join(dir, "base")+" -> "+child) {
+			t.Errorf("Expected template chain in output:\n%s", out.String())
+		}
+
+		// The provenance records the chain.
+		p, err := LoadProvenance(outDir)
+		if err != nil {
+			t.Fatalf("LoadProvenance failed: %v", err)
+		}
+		want := ProvenanceTemplate{
+			Name: "child", Path: child,
+			Extends: []ProvenanceTemplate{{Name: "base", Version: "1.0.0", Path: filepath.Join(dir, "base")}},
+		}
+		if !reflect.DeepEqual(p.Template, want) {
+			t.Errorf("Expected the template with its chain %+v, got %+v", want, p.Template)
+		}
+	})
+}
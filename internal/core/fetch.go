@@ -0,0 +1,267 @@
+package core
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/0m3kk/mold/internal/utils"
+)
+
+// TemplateOrigin locates a template fetched from a remote repository or
+// archive, or a version of a template. It is recorded in the provenance of
+// the projects generated from it.
+type TemplateOrigin struct {
+	URL string
+	Ref string
+	// Version is the version the template was resolved to, from
+	// name@version or the versions layout.
+	Version string
+}
+
+// FetchTemplate fetches the template of a registry entry into the templates
+// directory of cacheDir and returns its directory. A git repository is
+// checked out at the ref of the entry, an archive is downloaded and
+// extracted. The path of the entry is looked up in the archive, then in the
+// single directory it holds, such as the one of a release archive; without a
+// path, that directory is the template. When
+// fetching fails, the copy fetched last is used with a warning.
+func FetchTemplate(entry RegistryEntry, cacheDir string, opts FetchOptions, warn io.Writer) (string, error) {
+	sum := sha256.Sum256([]byte(entry.URL + "\x00" + entry.Ref))
+	key := hex.EncodeToString(sum[:8])
+	parent := filepath.Join(cacheDir, "templates")
+	dir := filepath.Join(parent, key)
+	if err := os.MkdirAll(parent, utils.DirMode); err != nil {
+		return "", fmt.Errorf("failed to create cache directory '%s': %w", parent, err)
+	}
+	tmp, err := os.MkdirTemp(parent, key+".tmp-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create cache directory '%s': %w", parent, err)
+	}
+	if IsArchiveURL(entry.URL) {
+		err = fetchArchive(entry.URL, tmp, opts)
+	} else {
+		err = fetchGit(entry.URL, entry.Ref, tmp)
+	}
+	if err != nil {
+		_ = os.RemoveAll(tmp)
+		if _, statErr := os.Stat(dir); statErr != nil {
+			return "", fmt.Errorf("failed to fetch template '%s' from '%s': %w", entry.Name, entry.URL, err)
+		}
+		fmt.Fprintf(warn, "⚠️  Using the copy of template '%s' fetched before: %v\n", entry.Name, err)
+	} else {
+		if err = os.RemoveAll(dir); err == nil {
+			err = os.Rename(tmp, dir)
+		}
+		if err != nil {
+			_ = os.RemoveAll(tmp)
+			return "", fmt.Errorf("failed to update the cached template '%s': %w", dir, err)
+		}
+	}
+
+	roots := []string{dir}
+	if IsArchiveURL(entry.URL) && entry.Path == "" {
+		roots = []string{unwrapArchive(dir)}
+	} else if IsArchiveURL(entry.URL) {
+		roots = append(roots, unwrapArchive(dir))
+	}
+	for _, root := range roots {
+		path := filepath.Join(root, filepath.FromSlash(entry.Path))
+		if info, statErr := os.Stat(path); statErr == nil && info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("template '%s' has no directory '%s' in '%s'", entry.Name, entry.Path, entry.URL)
+}
+
+// fetchGit checks out the ref of a git repository, or its default branch,
+// into dir, without its history.
+func fetchGit(repoURL, ref, dir string) error {
+	if err := checkRef(ref); err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return errors.New("fetching a git repository needs git on the PATH")
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet", dir},
+		// Nothing after --end-of-options is read as an option.
+		{"-C", dir, "fetch", "--quiet", "--depth", "1", "--end-of-options", repoURL, cmp.Or(ref, "HEAD")},
+		{"-C", dir, "checkout", "--quiet", "FETCH_HEAD"},
+	} {
+		cmd := exec.Command("git", args...)
+		// Fail instead of asking for credentials.
+		cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("'git %s' failed: %w: %s", strings.Join(args, " "), err,
+				strings.TrimSpace(string(output)))
+		}
+	}
+	return os.RemoveAll(filepath.Join(dir, ".git"))
+}
+
+// fetchArchive downloads an archive over https, or reads it from a file
+// URL, and extracts it into dir.
+func fetchArchive(rawURL, dir string, opts FetchOptions) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url '%s': %w", rawURL, err)
+	}
+	var content []byte
+	if strings.EqualFold(u.Scheme, "file") {
+		content, err = os.ReadFile(filepath.FromSlash(u.Path))
+	} else {
+		_, content, err = fetchURL(u, opts, "*/*")
+	}
+	if err != nil {
+		return err
+	}
+	limit := &fileLimit{maxFiles: opts.MaxFiles, maxBytes: opts.MaxBytes}
+	switch archiveURLFormat(rawURL) {
+	case "zip":
+		return extractZip(content, dir, limit)
+	case "tar.gz":
+		gz, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return fmt.Errorf("invalid archive '%s': %w", rawURL, err)
+		}
+		return extractTar(gz, dir, limit)
+	default:
+		return extractTar(bytes.NewReader(content), dir, limit)
+	}
+}
+
+// extractTar writes the directories and regular files of a tar archive into
+// dir, counting the files against limit. Links and special files are
+// skipped.
+func extractTar(r io.Reader, dir string, limit *fileLimit) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid archive: %w", err)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = extractDir(dir, header.Name)
+		case tar.TypeReg:
+			if err = countEntry(limit, header.Name, header.Size); err == nil {
+				err = extractFile(dir, header.Name, header.FileInfo().Mode(), tr)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// extractZip writes the directories and regular files of a zip archive into
+// dir, counting the files against limit. Links and special files are
+// skipped.
+func extractZip(content []byte, dir string, limit *fileLimit) error {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return fmt.Errorf("invalid archive: %w", err)
+	}
+	for _, file := range zr.File {
+		mode := file.Mode()
+		if mode.IsDir() {
+			if err = extractDir(dir, file.Name); err != nil {
+				return err
+			}
+			continue
+		}
+		if !mode.IsRegular() {
+			continue
+		}
+		// The zip reader fails on content longer than the size it declares.
+		if err = countEntry(limit, file.Name, int64(min(file.UncompressedSize64, 1<<63-1))); err != nil {
+			return err
+		}
+		r, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("invalid archive entry '%s': %w", file.Name, err)
+		}
+		err = extractFile(dir, file.Name, mode, r)
+		_ = r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countEntry counts a file of an archive against limit before it is
+// extracted.
+func countEntry(limit *fileLimit, name string, size int64) error {
+	if err := limit.add(size); err != nil {
+		return fmt.Errorf("extracting '%s' brings the archive to %w", name, err)
+	}
+	return nil
+}
+
+// archivePath returns where an archive entry goes under dir, refusing
+// entries escaping it.
+func archivePath(dir, name string) (string, error) {
+	rel := filepath.FromSlash(strings.TrimPrefix(name, "./"))
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("invalid archive entry '%s': it is outside the archive", name)
+	}
+	return filepath.Join(dir, rel), nil
+}
+
+// extractDir creates the directory of an archive entry.
+func extractDir(dir, name string) error {
+	path, err := archivePath(dir, name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(path, utils.DirMode)
+}
+
+// extractFile writes the regular file of an archive entry, keeping its
+// permission bits, always readable and writable by the owner.
+func extractFile(dir, name string, mode fs.FileMode, r io.Reader) error {
+	path, err := archivePath(dir, name)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), utils.DirMode); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to extract '%s': %w", name, err)
+	}
+	return f.Close()
+}
+
+// unwrapArchive returns the single directory an extracted archive holds,
+// such as the 'repo-v1.2.0' directory of a release archive, or dir.
+func unwrapArchive(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+		return dir
+	}
+	return filepath.Join(dir, entries[0].Name())
+}
@@ -0,0 +1,214 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTarGz writes an archive of the files, keyed by slash-separated path.
+func writeTarGz(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runGit runs git in dir as a test author, skipping the test without git.
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v: %s", args, err, output)
+	}
+}
+
+func TestFetchTemplateArchive(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "templates-v2.tar.gz")
+	writeTarGz(t, archive, map[string]string{
+		"templates-v2/web/README.md.tmpl": "# {{.name}}\n",
+		"templates-v2/LICENSE":            "MIT\n",
+	})
+	entry := RegistryEntry{Name: "web", URL: "file://" + filepath.ToSlash(archive), Path: "web"}
+	cacheDir := t.TempDir()
+
+	var warn bytes.Buffer
+	path, err := FetchTemplate(entry, cacheDir, FetchOptions{}, &warn)
+	if err != nil {
+		t.Fatalf("FetchTemplate failed: %v", err)
+	}
+	if got := readFiles(t, path); got["README.md.tmpl"] != "# {{.name}}\n" || len(got) != 1 {
+		t.Errorf("Expected the web directory of the unwrapped archive, got %v", got)
+	}
+
+	// The copy fetched before is used when the archive is gone.
+	if err = os.Remove(archive); err != nil {
+		t.Fatal(err)
+	}
+	again, err := FetchTemplate(entry, cacheDir, FetchOptions{}, &warn)
+	if err != nil || again != path {
+		t.Fatalf("Expected the cached copy %s, got %s, %v", path, again, err)
+	}
+	if !contains(warn.String(), "Using the copy of template 'web' fetched before") {
+		t.Errorf("Expected a warning about the cached copy, got: %s", warn.String())
+	}
+	if _, err = FetchTemplate(entry, t.TempDir(), FetchOptions{}, &warn); err == nil ||
+		!contains(err.Error(), "failed to fetch template 'web'") {
+		t.Errorf("Expected an error without a cached copy, got: %v", err)
+	}
+
+	t.Run("release archive without path", func(t *testing.T) {
+		archive := filepath.Join(t.TempDir(), "web.tar.gz")
+		writeTarGz(t, archive, map[string]string{"web-1.0/index.html": "hi"})
+		entry := RegistryEntry{Name: "web", URL: "file://" + filepath.ToSlash(archive)}
+		path, err := FetchTemplate(entry, t.TempDir(), FetchOptions{}, &warn)
+		if err != nil {
+			t.Fatalf("FetchTemplate failed: %v", err)
+		}
+		if filepath.Base(path) != "web-1.0" {
+			t.Errorf("Expected the directory of the archive, got %s", path)
+		}
+	})
+
+	t.Run("archive over the limits", func(t *testing.T) {
+		archive := filepath.Join(t.TempDir(), "big.tar.gz")
+		writeTarGz(t, archive, map[string]string{"big/a": "a", "big/b": "b", "big/c": "c"})
+		entry := RegistryEntry{Name: "big", URL: "file://" + filepath.ToSlash(archive)}
+		_, err := FetchTemplate(entry, t.TempDir(), FetchOptions{MaxFiles: 2}, &warn)
+		if err == nil || !contains(err.Error(), "brings the archive to 3 files, over the limit of 2 files") {
+			t.Errorf("Expected the archive to be refused, got: %v", err)
+		}
+		_, err = FetchTemplate(entry, t.TempDir(), FetchOptions{MaxBytes: 2}, &warn)
+		if err == nil || !contains(err.Error(), "brings the archive to 3 B, over the limit of 2 B") {
+			t.Errorf("Expected the archive to be refused, got: %v", err)
+		}
+	})
+
+	t.Run("entry escaping the archive", func(t *testing.T) {
+		archive := filepath.Join(t.TempDir(), "evil.tar.gz")
+		writeTarGz(t, archive, map[string]string{"../evil": "x"})
+		entry := RegistryEntry{Name: "evil", URL: "file://" + filepath.ToSlash(archive)}
+		_, err := FetchTemplate(entry, t.TempDir(), FetchOptions{}, &warn)
+		if err == nil || !contains(err.Error(), "outside the archive") {
+			t.Errorf("Expected the entry to be refused, got: %v", err)
+		}
+	})
+}
+
+func TestFetchTemplateGit(t *testing.T) {
+	repo := t.TempDir()
+	git := func(args ...string) { runGit(t, repo, args...) }
+	write := func(content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(repo, "go"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(repo, "go", "main.go.tmpl"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "--quiet")
+	write("package {{.name}} // v1\n")
+	git("add", "-A")
+	git("commit", "--quiet", "-m", "v1")
+	git("tag", "v1")
+	write("package {{.name}} // v2\n")
+	git("commit", "--quiet", "-am", "v2")
+
+	url := "file://" + filepath.ToSlash(repo)
+	for ref, want := range map[string]string{"": "// v2", "v1": "// v1"} {
+		entry := RegistryEntry{Name: "go", URL: url, Ref: ref, Path: "go"}
+		path, err := FetchTemplate(entry, t.TempDir(), FetchOptions{}, &bytes.Buffer{})
+		if err != nil {
+			t.Fatalf("FetchTemplate(%q) failed: %v", ref, err)
+		}
+		files := readFiles(t, path)
+		if !contains(files["main.go.tmpl"], want) {
+			t.Errorf("FetchTemplate(%q): expected %q, got %v", ref, want, files)
+		}
+		if _, err = os.Stat(filepath.Join(path, "..", ".git")); err == nil {
+			t.Errorf("FetchTemplate(%q): expected the history to be removed", ref)
+		}
+	}
+
+	// A ref git would read as an option never reaches it.
+	marker := filepath.Join(t.TempDir(), "pwned")
+	index := fmt.Sprintf("schema: 1\ntemplates:\n  go:\n    url: %s\n    ref: '--upload-pack=touch %s'\n", url, marker)
+	if _, err := ParseRegistryIndex([]byte(index), "registry.yaml"); err == nil || !contains(err.Error(), "invalid ref") {
+		t.Errorf("Expected the malicious index to be refused, got: %v", err)
+	}
+	entry := RegistryEntry{Name: "go", URL: url, Ref: "--upload-pack=touch " + marker, Path: "go"}
+	if _, err := FetchTemplate(entry, t.TempDir(), FetchOptions{}, &bytes.Buffer{}); err == nil ||
+		!contains(err.Error(), "invalid ref") {
+		t.Errorf("Expected the ref to be refused, got: %v", err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("Expected the command of the ref not to run")
+	}
+	head, err := exec.Command("git", "-C", repo, "rev-parse", "HEAD").Output()
+	if err != nil {
+		t.Fatalf("git rev-parse failed: %v", err)
+	}
+	entry = RegistryEntry{Name: "go", URL: url, Ref: strings.TrimSpace(string(head)), Path: "go"}
+	if _, err := FetchTemplate(entry, t.TempDir(), FetchOptions{}, &bytes.Buffer{}); err != nil {
+		t.Errorf("Expected a commit hash to be fetched, got: %v", err)
+	}
+
+	entry = RegistryEntry{Name: "go", URL: url, Ref: "v9"}
+	if _, err := FetchTemplate(entry, t.TempDir(), FetchOptions{}, &bytes.Buffer{}); err == nil ||
+		!contains(err.Error(), "git -C") {
+		t.Errorf("Expected an unknown ref to fail, got: %v", err)
+	}
+}
+
+func TestApplyOrigin(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{"README.md": "hello\n"})
+	outputDir := t.TempDir()
+	origin := TemplateOrigin{URL: "https://example.com/acme/templates.git", Ref: "v1"}
+	err := Apply(Options{
+		TemplatePath: templateDir,
+		OutputDir:    outputDir,
+		Data:         map[string]any{},
+		Out:          &bytes.Buffer{},
+		Origins:      map[string]TemplateOrigin{templateDir: origin},
+	})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	p, err := LoadProvenance(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Template.URL != origin.URL || p.Template.Ref != origin.Ref {
+		t.Errorf("Expected the origin in the provenance, got %+v", p.Template)
+	}
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"fmt"
+	"go/format"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FormattersFor returns the formatter commands whose glob matches the
+// slash-separated output path, ordered by glob so runs are reproducible.
+func (m *Metadata) FormattersFor(relPath string) [][]string {
+	patterns := make([]string, 0, len(m.Formatters))
+	for pattern := range m.Formatters {
+		if MatchGlob(pattern, relPath) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	slices.Sort(patterns)
+
+	commands := make([][]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		commands = append(commands, m.Formatters[pattern])
+	}
+	return commands
+}
+
+// FormatFile runs a formatter command on the file at path. The commands
+// "gofmt" and "gofmt -w" use the builtin go/format formatter so no external
+// binary is required; any other command is executed with the file path
+// appended as its last argument.
+func FormatFile(command []string, path string) error {
+	if isBuiltinGofmt(command) {
+		return formatGoFile(path)
+	}
+
+	//nolint:gosec // running the formatter declared by the template is the point
+	cmd := exec.Command(command[0], append(command[1:], path)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// FormatContent runs a formatter command on content that is not on disk
+// yet. External commands get a temporary copy of the content named like the
+// output file, since many formatters pick their rules from the extension.
+func FormatContent(command []string, name string, content []byte) ([]byte, error) {
+	if isBuiltinGofmt(command) {
+		return format.Source(content)
+	}
+
+	dir, err := os.MkdirTemp("", "mold-format-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, filepath.Base(name))
+	if err = os.WriteFile(path, content, 0600); err != nil {
+		return nil, err
+	}
+	if err = FormatFile(command, path); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// isBuiltinGofmt reports whether the command can be served by go/format.
+func isBuiltinGofmt(command []string) bool {
+	return slices.Equal(command, []string{"gofmt"}) || slices.Equal(command, []string{"gofmt", "-w"})
+}
+
+// formatGoFile rewrites a Go source file in its canonical gofmt style.
+func formatGoFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	formatted, err := format.Source(content)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, formatted, info.Mode())
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormattersFor(t *testing.T) {
+	meta := &Metadata{Formatters: map[string][]string{
+		"**/*.go":  {"gofmt"},
+		"cmd/**":   {"echo"},
+		"**/*.tf":  {"terraform", "fmt"},
+		"*.go":     {"goimports", "-w"},
+		"other/**": {"true"},
+	}}
+
+	got := meta.FormattersFor("cmd/main.go")
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 formatters, got %v", got)
+	}
+	// Sorted by glob: "**/*.go" < "cmd/**".
+	if got[0][0] != "gofmt" || got[1][0] != "echo" {
+		t.Errorf("Unexpected formatter order: %v", got)
+	}
+}
+
+func TestFormatFile(t *testing.T) {
+	tempDir := t.TempDir()
+
+	t.Run("builtin gofmt", func(t *testing.T) {
+		path := filepath.Join(tempDir, "main.go")
+		err := os.WriteFile(path, []byte("package main\nfunc main() {\n      println( 1 )\n}\n"), 0755)
+		if err != nil {
+			t.Fatalf("Failed to write Go file: %v", err)
+		}
+
+		if err = FormatFile([]string{"gofmt", "-w"}, path); err != nil {
+			t.Fatalf("FormatFile failed: %v", err)
+		}
+
+		output, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read formatted file: %v", err)
+		}
+		expected := "package main\n\nfunc main() {\n\tprintln(1)\n}\n"
+		if string(output) != expected {
+			t.Errorf("Output mismatch:\nGot:\n%s\nWant:\n%s", string(output), expected)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Failed to stat formatted file: %v", err)
+		}
+		if info.Mode().Perm() != 0755 {
+			t.Errorf("Permission mismatch: got %v, want %v", info.Mode().Perm(), os.FileMode(0755))
+		}
+	})
+
+	t.Run("builtin gofmt with invalid source", func(t *testing.T) {
+		path := filepath.Join(tempDir, "broken.go")
+		if err := os.WriteFile(path, []byte("package main\nfunc {"), 0644); err != nil {
+			t.Fatalf("Failed to write Go file: %v", err)
+		}
+
+		if err := FormatFile([]string{"gofmt"}, path); err == nil {
+			t.Error("Expected error for invalid Go source")
+		}
+	})
+
+	t.Run("external command", func(t *testing.T) {
+		path := filepath.Join(tempDir, "file.txt")
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+
+		if err := FormatFile([]string{"true"}, path); err != nil {
+			t.Errorf("FormatFile failed: %v", err)
+		}
+		if err := FormatFile([]string{"false"}, path); err == nil {
+			t.Error("Expected error from failing formatter command")
+		}
+	})
+
+	t.Run("missing external binary", func(t *testing.T) {
+		path := filepath.Join(tempDir, "file.txt")
+		if err := FormatFile([]string{"mold-no-such-formatter"}, path); err == nil {
+			t.Error("Expected error for missing formatter binary")
+		}
+	})
+}
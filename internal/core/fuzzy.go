@@ -0,0 +1,43 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// normalizeKey returns the spelling-independent form of a data key, its
+// lower-case words without separators, so "projectName", "ProjectName",
+// "project_name", "projectname" and "PROJECTNAME" compare equal.
+func normalizeKey(key string) string {
+	return strings.ReplaceAll(defaultCasing.Snake(key), "_", "")
+}
+
+// MatchFuzzyKeys copies data values whose key is spelled differently from a
+// referenced key, such as "projectName" for "project_name", under the
+// referenced spelling. It returns the matches made as "data key -> referenced
+// key" and fails when several data keys, the referenced spelling included,
+// match the same referenced key, since nothing tells which value is meant.
+func MatchFuzzyKeys(data map[string]any, referenced []string) ([]string, error) {
+	var matches []string
+	// The keys copied under other spellings aren't candidates themselves.
+	given := slices.Collect(maps.Keys(data))
+	for _, key := range referenced {
+		var candidates []string
+		for _, dataKey := range given {
+			if normalizeKey(dataKey) == normalizeKey(key) {
+				candidates = append(candidates, dataKey)
+			}
+		}
+		slices.Sort(candidates)
+		switch {
+		case len(candidates) > 1:
+			return nil, fmt.Errorf("ambiguous data keys for '%s': %s", key, strings.Join(candidates, ", "))
+		case len(candidates) == 1 && candidates[0] != key:
+			data[key] = data[candidates[0]]
+			matches = append(matches, candidates[0]+" -> "+key)
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMatchFuzzyKeys(t *testing.T) {
+	t.Run("matches other spellings", func(t *testing.T) {
+		data := map[string]any{"projectName": "demo", "OwnerEmail": "a@b.c", "version": "1"}
+
+		matches, err := MatchFuzzyKeys(data, []string{"project_name", "owner_email", "version", "missing"})
+		if err != nil {
+			t.Fatalf("MatchFuzzyKeys failed: %v", err)
+		}
+		want := []string{"projectName -> project_name", "OwnerEmail -> owner_email"}
+		if !slices.Equal(matches, want) {
+			t.Errorf("Expected matches %v, got %v", want, matches)
+		}
+		if data["project_name"] != "demo" || data["owner_email"] != "a@b.c" {
+			t.Errorf("Expected values under the referenced keys, got %v", data)
+		}
+	})
+
+	t.Run("lower-case spellings", func(t *testing.T) {
+		data := map[string]any{"projectname": "demo", "OWNERNAME": "ada"}
+
+		matches, err := MatchFuzzyKeys(data, []string{"project_name", "owner_name"})
+		if err != nil {
+			t.Fatalf("MatchFuzzyKeys failed: %v", err)
+		}
+		want := []string{"projectname -> project_name", "OWNERNAME -> owner_name"}
+		if !slices.Equal(matches, want) || data["project_name"] != "demo" || data["owner_name"] != "ada" {
+			t.Errorf("Expected matches %v, got %v with %v", want, matches, data)
+		}
+	})
+
+	t.Run("exact key alone is kept", func(t *testing.T) {
+		data := map[string]any{"project_name": "exact"}
+
+		matches, err := MatchFuzzyKeys(data, []string{"project_name"})
+		if err != nil || len(matches) != 0 || data["project_name"] != "exact" {
+			t.Errorf("Expected exact key to be kept, got %v, %v (%v)", data, matches, err)
+		}
+	})
+
+	t.Run("several referenced spellings", func(t *testing.T) {
+		data := map[string]any{"projectName": "demo"}
+
+		matches, err := MatchFuzzyKeys(data, []string{"project_name", "projectName", "PROJECT_NAME"})
+		if err != nil {
+			t.Fatalf("MatchFuzzyKeys failed: %v", err)
+		}
+		want := []string{"projectName -> project_name", "projectName -> PROJECT_NAME"}
+		if !slices.Equal(matches, want) {
+			t.Errorf("Expected matches %v, got %v", want, matches)
+		}
+	})
+
+	t.Run("exact key with another spelling is ambiguous", func(t *testing.T) {
+		data := map[string]any{"projectName": "fuzzy", "project_name": "exact"}
+
+		_, err := MatchFuzzyKeys(data, []string{"project_name"})
+		if err == nil || !contains(err.Error(), "ambiguous data keys for 'project_name': projectName, project_name") {
+			t.Errorf("Expected ambiguity error listing both keys, got: %v", err)
+		}
+	})
+
+	t.Run("ambiguous keys", func(t *testing.T) {
+		data := map[string]any{"projectName": "a", "ProjectName": "b"}
+
+		_, err := MatchFuzzyKeys(data, []string{"project_name"})
+		if err == nil || !contains(err.Error(), "ambiguous data keys for 'project_name': ProjectName, projectName") {
+			t.Errorf("Expected ambiguity error, got: %v", err)
+		}
+	})
+}
+
+func TestApplyFuzzyKeys(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		"{{.project_name}}/README.md.tmpl": "{{.project_name}} by {{template \"owner.tmpl\" .}}",
+		"_partials/owner.tmpl":             "{{.owner_name}}",
+	})
+	data := map[string]any{"ProjectName": "demo", "ownerName": "ada"}
+
+	outDir := t.TempDir()
+	var out bytes.Buffer
+	err := Apply(Options{TemplatePath: templateDir, OutputDir: outDir, Data: data, FuzzyKeys: true, Out: &out})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	content, _ := os.ReadFile(filepath.Join(outDir, "demo", "README.md"))
+	if string(content) != "demo by ada" {
+		t.Errorf("Unexpected output %q", content)
+	}
+	if !contains(out.String(), "🔤 Matched data key: ProjectName -> project_name") {
+		t.Errorf("Expected match in output:\n%s", out.String())
+	}
+	if _, ok := data["project_name"]; ok {
+		t.Error("Expected the caller's data to be left alone")
+	}
+
+	err = Apply(Options{
+		TemplatePath: templateDir,
+		OutputDir:    t.TempDir(),
+		Data:         data,
+		FuzzyKeys:    true,
+		Strict:       true,
+		Out:          &bytes.Buffer{},
+	})
+	if err == nil || !contains(err.Error(), "map has no entry for key") {
+		t.Errorf("Expected strict mode to disable fuzzy keys, got: %v", err)
+	}
+}
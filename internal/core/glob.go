@@ -0,0 +1,49 @@
+package core
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// MatchGlob reports whether the slash-separated name matches the pattern.
+// Besides the syntax understood by path.Match, a "**" segment matches zero or
+// more directories, so "**/*.go" matches Go files at any depth.
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// CheckGlob returns an error when the pattern is malformed, such as an
+// unclosed '[', which MatchGlob takes as matching nothing.
+func CheckGlob(pattern string) error {
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid glob pattern '%s': %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchSegments matches the pattern segments against the name segments.
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Try every possible number of directories for the wildcard.
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		ok, err := path.Match(pattern[0], name[0])
+		if err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,270 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
+)
+
+const (
+	// TestsDir is the directory at the root of a template holding its golden
+	// test cases. It is never copied to the output.
+	TestsDir = "tests"
+	// TestDataFile is the data file of a golden test case.
+	TestDataFile = "data.yaml"
+	// TestExpectedDir is the directory of a golden test case holding the
+	// expected output.
+	TestExpectedDir = "expected"
+)
+
+// testClock is the fixed time golden tests are applied at, so the output
+// doesn't depend on when the tests run.
+//
+//nolint:gochecknoglobals // fixed clock shared by all golden test runs
+var testClock = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// TestCase is a golden test case of a template.
+type TestCase struct {
+	// Name is the name of the case directory.
+	Name string
+	// Dir is the path of the case directory.
+	Dir string
+}
+
+// FileMismatch describes a generated file that doesn't match the expected
+// output.
+type FileMismatch struct {
+	// Path is the slash-separated path relative to the output root.
+	Path string
+	// Diff is a unified diff from the expected to the generated content, or
+	// a note when the file is missing on either side.
+	Diff string
+}
+
+// TestResult is the outcome of a golden test case.
+type TestResult struct {
+	Case TestCase
+	// Err is set when the template could not be applied.
+	Err        error
+	Mismatches []FileMismatch
+}
+
+// Passed reports whether the case applied and matched the expected output.
+func (r TestResult) Passed() bool {
+	return r.Err == nil && len(r.Mismatches) == 0
+}
+
+// FindTestCases returns the test cases under the template's tests directory
+// whose name matches the run regular expression, sorted by name. An empty run
+// selects every case.
+func FindTestCases(templatePath, run string) ([]TestCase, error) {
+	pattern, err := regexp.Compile(run)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --run pattern '%s': %w", run, err)
+	}
+
+	testsDir := filepath.Join(templatePath, TestsDir)
+	dirEntries, err := os.ReadDir(testsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test cases in '%s': %w", testsDir, err)
+	}
+	var cases []TestCase
+	for _, d := range dirEntries {
+		if d.IsDir() && pattern.MatchString(d.Name()) {
+			cases = append(cases, TestCase{Name: d.Name(), Dir: filepath.Join(testsDir, d.Name())})
+		}
+	}
+	return cases, nil
+}
+
+// RunTestCase applies the template with the case's data into a temporary
+// directory and compares the result with the case's expected output.
+func RunTestCase(templatePath string, tc TestCase) TestResult {
+	result := TestResult{Case: tc}
+	outputDir, err := os.MkdirTemp("", "mold-test-")
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer os.RemoveAll(outputDir)
+
+	if result.Err = applyTestCase(templatePath, tc, outputDir); result.Err != nil {
+		return result
+	}
+	result.Mismatches, result.Err = compareTrees(filepath.Join(tc.Dir, TestExpectedDir), outputDir)
+	return result
+}
+
+// applyTestCase applies the template with the case's data into outputDir.
+func applyTestCase(templatePath string, tc TestCase, outputDir string) error {
+	data, err := LoadDataFile(filepath.Join(tc.Dir, TestDataFile), DataOptions{Strict: true})
+	if err != nil {
+		return err
+	}
+	return Apply(Options{
+		TemplatePath: templatePath,
+		OutputDir:    outputDir,
+		Data:         data,
+		Out:          io.Discard,
+		Clock:        testClock,
+		// Expected output describes the template, not how it was applied.
+		NoProvenance: true,
+	})
+}
+
+// compareTrees compares the files under the expected and actual directories.
+func compareTrees(expectedDir, actualDir string) ([]FileMismatch, error) {
+	expected, err := readTree(expectedDir)
+	if err != nil {
+		return nil, err
+	}
+	actual, err := readTree(actualDir)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(expected)+len(actual))
+	for path := range expected {
+		paths = append(paths, path)
+	}
+	for path := range actual {
+		if _, ok := expected[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+
+	var mismatches []FileMismatch
+	for _, path := range paths {
+		want, wantOK := expected[path]
+		got, gotOK := actual[path]
+		switch {
+		case !gotOK:
+			mismatches = append(mismatches, FileMismatch{Path: path, Diff: "expected file was not generated"})
+		case !wantOK:
+			mismatches = append(mismatches, FileMismatch{Path: path, Diff: "generated file is not expected"})
+		case !bytes.Equal(want, got):
+			diff := UnifiedDiff("expected/"+path, "actual/"+path, string(want), string(got))
+			mismatches = append(mismatches, FileMismatch{Path: path, Diff: diff})
+		}
+	}
+	return mismatches, nil
+}
+
+// readTree reads the content of every file under dir, keyed by its
+// slash-separated relative path. A missing dir is an empty tree.
+func readTree(dir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil || d.IsDir() {
+			return walkErr
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(relPath)] = content
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read '%s': %w", dir, err)
+	}
+	return files, nil
+}
+
+// TestUpdate holds the freshly generated output of a golden test case,
+// ready to replace its expected output.
+type TestUpdate struct {
+	Case TestCase
+	// Added, Changed and Removed list the slash-separated paths that the
+	// update adds to, changes in and removes from the expected output.
+	Added   []string
+	Changed []string
+	Removed []string
+
+	staging string
+}
+
+// PrepareTestUpdate applies the template with the case's data into a staging
+// directory next to the expected output. Nothing is changed until Commit.
+func PrepareTestUpdate(templatePath string, tc TestCase) (*TestUpdate, error) {
+	staging, err := os.MkdirTemp(tc.Dir, "."+TestExpectedDir+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	u := &TestUpdate{Case: tc, staging: staging}
+	if err = applyTestCase(templatePath, tc, staging); err != nil {
+		_ = u.Discard()
+		return nil, err
+	}
+
+	expected, err := readTree(filepath.Join(tc.Dir, TestExpectedDir))
+	if err == nil {
+		var actual map[string][]byte
+		if actual, err = readTree(staging); err == nil {
+			u.classify(expected, actual)
+		}
+	}
+	if err != nil {
+		_ = u.Discard()
+		return nil, err
+	}
+	return u, nil
+}
+
+// classify sorts the paths of both trees into added, changed and removed.
+func (u *TestUpdate) classify(expected, actual map[string][]byte) {
+	for path, content := range actual {
+		want, ok := expected[path]
+		switch {
+		case !ok:
+			u.Added = append(u.Added, path)
+		case !bytes.Equal(want, content):
+			u.Changed = append(u.Changed, path)
+		}
+	}
+	for path := range expected {
+		if _, ok := actual[path]; !ok {
+			u.Removed = append(u.Removed, path)
+		}
+	}
+	slices.Sort(u.Added)
+	slices.Sort(u.Changed)
+	slices.Sort(u.Removed)
+}
+
+// Empty reports whether the expected output is already up to date.
+func (u *TestUpdate) Empty() bool {
+	return len(u.Added)+len(u.Changed)+len(u.Removed) == 0
+}
+
+// Commit replaces the expected output with the staged output.
+func (u *TestUpdate) Commit() error {
+	expectedDir := filepath.Join(u.Case.Dir, TestExpectedDir)
+	if err := os.RemoveAll(expectedDir); err != nil {
+		return fmt.Errorf("failed to remove '%s': %w", expectedDir, err)
+	}
+	// Temporary directories are private, expected output is not.
+	if err := os.Chmod(u.staging, 0750); err != nil {
+		return err
+	}
+	if err := os.Rename(u.staging, expectedDir); err != nil {
+		return fmt.Errorf("failed to update '%s': %w", expectedDir, err)
+	}
+	return nil
+}
+
+// Discard removes the staged output.
+func (u *TestUpdate) Discard() error {
+	return os.RemoveAll(u.staging)
+}
@@ -0,0 +1,141 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestGolden(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		"README.md.tmpl":                       "# {{.name}}\n",
+		"static.txt":                           "static\n",
+		"tests/basic/data.yaml":                "name: demo",
+		"tests/basic/expected/README.md":       "# demo\n",
+		"tests/basic/expected/static.txt":      "static\n",
+		"tests/broken/data.yaml":               "name: other",
+		"tests/broken/expected/README.md":      "# demo\n",
+		"tests/broken/expected/gone.txt":       "gone\n",
+		"tests/invalid-data/data.yaml":         "name: [",
+		"tests/invalid-data/expected/.gitkeep": "",
+	})
+
+	t.Run("find cases", func(t *testing.T) {
+		cases, err := FindTestCases(templateDir, "")
+		if err != nil {
+			t.Fatalf("FindTestCases failed: %v", err)
+		}
+		if len(cases) != 3 || cases[0].Name != "basic" || cases[2].Name != "invalid-data" {
+			t.Errorf("Unexpected cases: %+v", cases)
+		}
+
+		cases, err = FindTestCases(templateDir, "^b.*c$")
+		if err != nil || len(cases) != 1 || cases[0].Name != "basic" {
+			t.Errorf("Expected only 'basic', got %+v (%v)", cases, err)
+		}
+
+		if _, err = FindTestCases(templateDir, "("); err == nil || !contains(err.Error(), "invalid --run pattern") {
+			t.Errorf("Expected pattern error, got: %v", err)
+		}
+	})
+
+	t.Run("passing case", func(t *testing.T) {
+		result := RunTestCase(templateDir, TestCase{Name: "basic", Dir: templateDir + "/tests/basic"})
+		if !result.Passed() {
+			t.Errorf("Expected pass, got %+v", result)
+		}
+	})
+
+	t.Run("failing case", func(t *testing.T) {
+		result := RunTestCase(templateDir, TestCase{Name: "broken", Dir: templateDir + "/tests/broken"})
+		if result.Passed() || result.Err != nil {
+			t.Fatalf("Expected mismatches, got %+v", result)
+		}
+		want := map[string]string{
+			"README.md":  "--- expected/README.md\n+++ actual/README.md\n@@ -1,1 +1,1 @@\n-# demo\n+# other\n",
+			"gone.txt":   "expected file was not generated",
+			"static.txt": "generated file is not expected",
+		}
+		if len(result.Mismatches) != len(want) {
+			t.Fatalf("Expected %d mismatches, got %+v", len(want), result.Mismatches)
+		}
+		for _, mismatch := range result.Mismatches {
+			if mismatch.Diff != want[mismatch.Path] {
+				t.Errorf("Mismatch for '%s' = %q, want %q", mismatch.Path, mismatch.Diff, want[mismatch.Path])
+			}
+		}
+	})
+
+	t.Run("case that fails to apply", func(t *testing.T) {
+		result := RunTestCase(templateDir, TestCase{Name: "invalid-data", Dir: templateDir + "/tests/invalid-data"})
+		if result.Err == nil || !contains(result.Err.Error(), "failed to parse YAML file") {
+			t.Errorf("Expected data error, got: %v", result.Err)
+		}
+	})
+}
+
+func TestPrepareTestUpdate(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		"README.md.tmpl":                "# {{.name}}\n",
+		"new.txt":                       "new\n",
+		"tests/case/data.yaml":          "name: demo",
+		"tests/case/expected/README.md": "# old\n",
+		"tests/case/expected/stale.txt": "stale\n",
+		"tests/bad/data.yaml":           "name: [",
+		"tests/bad/expected/README.md":  "# keep\n",
+	})
+	tc := TestCase{Name: "case", Dir: filepath.Join(templateDir, "tests", "case")}
+
+	t.Run("discard keeps expected output", func(t *testing.T) {
+		update, err := PrepareTestUpdate(templateDir, tc)
+		if err != nil {
+			t.Fatalf("PrepareTestUpdate failed: %v", err)
+		}
+		if !slices.Equal(update.Added, []string{"new.txt"}) ||
+			!slices.Equal(update.Changed, []string{"README.md"}) ||
+			!slices.Equal(update.Removed, []string{"stale.txt"}) {
+			t.Errorf("Unexpected update: %+v", update)
+		}
+		if err = update.Discard(); err != nil {
+			t.Fatalf("Discard failed: %v", err)
+		}
+		entries, _ := os.ReadDir(tc.Dir)
+		if len(entries) != 2 {
+			t.Errorf("Expected staging directory to be removed, got %v", entries)
+		}
+	})
+
+	t.Run("commit replaces expected output", func(t *testing.T) {
+		update, err := PrepareTestUpdate(templateDir, tc)
+		if err != nil {
+			t.Fatalf("PrepareTestUpdate failed: %v", err)
+		}
+		if err = update.Commit(); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+		if result := RunTestCase(templateDir, tc); !result.Passed() {
+			t.Errorf("Expected case to pass after update, got %+v", result)
+		}
+		if _, err = os.Stat(filepath.Join(tc.Dir, "expected", "stale.txt")); !os.IsNotExist(err) {
+			t.Errorf("Expected stale file to be removed, got: %v", err)
+		}
+
+		update, err = PrepareTestUpdate(templateDir, tc)
+		if err != nil || !update.Empty() {
+			t.Errorf("Expected an empty update, got %+v (%v)", update, err)
+		}
+		_ = update.Discard()
+	})
+
+	t.Run("render error", func(t *testing.T) {
+		bad := TestCase{Name: "bad", Dir: filepath.Join(templateDir, "tests", "bad")}
+		if _, err := PrepareTestUpdate(templateDir, bad); err == nil {
+			t.Fatal("Expected render error")
+		}
+		entries, _ := os.ReadDir(bad.Dir)
+		if len(entries) != 2 {
+			t.Errorf("Expected staging directory to be removed, got %v", entries)
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package core
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Comment styles of file headers.
+const (
+	CommentSlashes = "//"
+	CommentHash    = "#"
+	CommentDashes  = "--"
+	CommentBlock   = "/* */"
+	CommentMarkup  = "<!-- -->"
+)
+
+// CommentStyles lists the comment styles a header can use.
+//
+//nolint:gochecknoglobals // list of the supported comment styles
+var CommentStyles = []string{CommentSlashes, CommentHash, CommentDashes, CommentBlock, CommentMarkup}
+
+// commentStyles maps file extensions, and the names of files without one,
+// to the comment style of their headers.
+//
+//nolint:gochecknoglobals // table of the comment styles of common languages
+var commentStyles = map[string]string{
+	".go": CommentSlashes, ".js": CommentSlashes, ".jsx": CommentSlashes, ".mjs": CommentSlashes,
+	".ts": CommentSlashes, ".tsx": CommentSlashes, ".java": CommentSlashes, ".kt": CommentSlashes,
+	".kts": CommentSlashes, ".scala": CommentSlashes, ".swift": CommentSlashes, ".rs": CommentSlashes,
+	".c": CommentSlashes, ".h": CommentSlashes, ".cc": CommentSlashes, ".cpp": CommentSlashes,
+	".hpp": CommentSlashes, ".cs": CommentSlashes, ".dart": CommentSlashes, ".proto": CommentSlashes,
+	".groovy": CommentSlashes, ".php": CommentSlashes,
+
+	".py": CommentHash, ".sh": CommentHash, ".bash": CommentHash, ".zsh": CommentHash, ".rb": CommentHash,
+	".pl": CommentHash, ".r": CommentHash, ".yaml": CommentHash, ".yml": CommentHash, ".toml": CommentHash,
+	".tf": CommentHash, ".hcl": CommentHash, ".ps1": CommentHash, ".mk": CommentHash, ".cmake": CommentHash,
+	".conf": CommentHash, ".ini": CommentHash, "Makefile": CommentHash, "Dockerfile": CommentHash,
+	"Containerfile": CommentHash, "Gemfile": CommentHash, "Rakefile": CommentHash,
+
+	".sql": CommentDashes, ".lua": CommentDashes, ".hs": CommentDashes,
+
+	".css": CommentBlock, ".scss": CommentBlock, ".less": CommentBlock,
+
+	".html": CommentMarkup, ".htm": CommentMarkup, ".xml": CommentMarkup, ".svg": CommentMarkup,
+	".vue": CommentMarkup, ".md": CommentMarkup,
+}
+
+// Header is a header, such as a license notice, prepended to the generated
+// files matching a glob of the header section of template.yaml.
+type Header struct {
+	// Template is the text of the header, rendered with the data.
+	Template string `yaml:"template"`
+	// Partial names a file of the _partials directory holding the text of
+	// the header instead, such as "license.tmpl".
+	Partial string `yaml:"partial"`
+	// CommentStyle is the comment style of the header, one of
+	// CommentStyles. Empty infers it from the extension of the file.
+	CommentStyle string `yaml:"commentStyle"`
+}
+
+// UnmarshalYAML decodes a header, given as its template or as a mapping.
+func (h *Header) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		h.Template = node.Value
+		return nil
+	}
+	type plain Header
+	return node.Decode((*plain)(h))
+}
+
+// check fails unless the header has a template or a partial, and a known
+// comment style.
+func (h Header) check() error {
+	switch {
+	case (h.Template == "") == (h.Partial == ""):
+		return errors.New("expected either a template or a partial")
+	case h.CommentStyle != "" && !slices.Contains(CommentStyles, h.CommentStyle):
+		return fmt.Errorf("unknown comment style '%s', expected one of: %s", h.CommentStyle,
+			strings.Join(CommentStyles, ", "))
+	}
+	return nil
+}
+
+// text returns the template of the header text.
+func (h Header) text() string {
+	if h.Partial != "" {
+		return fmt.Sprintf("{{template %q .}}", h.Partial)
+	}
+	return h.Template
+}
+
+// HeaderFor returns the glob and the header of the slash-separated output
+// path, the longest matching glob winning. Copied files only get one when
+// they also match a glob of HeaderCopied.
+func (m *Metadata) HeaderFor(relPath string, copied bool) (string, Header, bool) {
+	if copied && !slices.ContainsFunc(m.HeaderCopied, func(pattern string) bool {
+		return MatchGlob(pattern, relPath)
+	}) {
+		return "", Header{}, false
+	}
+	best := ""
+	for _, pattern := range slices.Sorted(maps.Keys(m.Headers)) {
+		if MatchGlob(pattern, relPath) && len(pattern) > len(best) {
+			best = pattern
+		}
+	}
+	if best == "" {
+		return "", Header{}, false
+	}
+	return best, m.Headers[best], true
+}
+
+// CommentStyleFor returns the comment style of the headers of a file,
+// inferred from its extension or its name. It returns "" for files in an
+// unknown language.
+func CommentStyleFor(name string) string {
+	base := path.Base(filepath.ToSlash(name))
+	if style, ok := commentStyles[strings.ToLower(path.Ext(base))]; ok {
+		return style
+	}
+	return commentStyles[base]
+}
+
+// CommentHeader turns the text of a header into a comment of the given
+// style, followed by a blank line.
+func CommentHeader(text, style string) string {
+	lines := strings.Split(strings.TrimRight(text, "\r\n"), "\n")
+	var b strings.Builder
+	switch style {
+	case CommentBlock:
+		b.WriteString("/*\n")
+		for _, line := range lines {
+			b.WriteString(strings.TrimRight(" * "+line, " ") + "\n")
+		}
+		b.WriteString(" */\n")
+	case CommentMarkup:
+		b.WriteString("<!--\n")
+		for _, line := range lines {
+			b.WriteString(strings.TrimRight("  "+line, " ") + "\n")
+		}
+		b.WriteString("-->\n")
+	default:
+		for _, line := range lines {
+			b.WriteString(strings.TrimRight(style+" "+line, " ") + "\n")
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// InsertHeader prepends a commented header to content, after a shebang or
+// an XML declaration, which have to stay first. Content already starting
+// with the header is returned unchanged, so headers are never doubled.
+func InsertHeader(content []byte, header string) []byte {
+	at := 0
+	if bytes.HasPrefix(content, []byte("#!")) || bytes.HasPrefix(content, []byte("<?xml")) {
+		at = len(content)
+		if i := bytes.IndexByte(content, '\n'); i >= 0 {
+			at = i + 1
+		}
+	}
+	rest, comment := content[at:], strings.TrimRight(header, "\n")
+	if string(rest) == comment || bytes.HasPrefix(rest, []byte(comment+"\n")) {
+		return content
+	}
+	result := make([]byte, 0, len(content)+len(header)+1)
+	result = append(result, content[:at]...)
+	if at > 0 && content[at-1] != '\n' {
+		result = append(result, '\n')
+	}
+	result = append(result, header...)
+	return append(result, content[at:]...)
+}
+
+// copiedHeader reports whether a copied file gets a header, in which case
+// it is copied through memory rather than streamed or linked.
+func (a *applier) copiedHeader(l *layer, relPath string) bool {
+	_, _, ok := l.meta.HeaderFor(filepath.ToSlash(filepath.Join(a.prefix, relPath)), true)
+	return ok
+}
+
+// addHeader prepends the header matching the output path of a file, if
+// any, to its content. The header is rendered with the helper functions
+// and partials of the layer.
+func (a *applier) addHeader(l *layer, relPath string, content []byte, copied bool) ([]byte, error) {
+	outPath := filepath.ToSlash(filepath.Join(a.prefix, relPath))
+	pattern, header, ok := l.meta.HeaderFor(outPath, copied)
+	if !ok {
+		return content, nil
+	}
+	style := cmp.Or(header.CommentStyle, CommentStyleFor(outPath))
+	if style == "" {
+		err := fmt.Errorf("no comment style is known for '%s', set the commentStyle of header '%s'", relPath,
+			pattern)
+		if a.opts.Strict {
+			return nil, err
+		}
+		fmt.Fprintf(a.out, "⚠️  Skipping the header of '%s': %v\n", relPath, err)
+		return content, nil
+	}
+	key := l.path + "\x00" + pattern
+	text, ok := a.headers[key]
+	if !ok {
+		var rendered strings.Builder
+		name := "header '" + pattern + "'"
+		if err := l.renderer.Render(&rendered, name, []byte(header.text()), a.opts.Data); err != nil {
+			return nil, err
+		}
+		text = rendered.String()
+		a.headers[key] = text
+	}
+	if strings.TrimSpace(text) == "" {
+		return content, nil
+	}
+	return InsertHeader(content, CommentHeader(text, style)), nil
+}
@@ -0,0 +1,187 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommentHeader(t *testing.T) {
+	tests := []struct {
+		style, want string
+	}{
+		{style: CommentSlashes, want: "// Copyright ACME\n//\n// MIT\n\n"},
+		{style: CommentHash, want: "# Copyright ACME\n#\n# MIT\n\n"},
+		{style: CommentDashes, want: "-- Copyright ACME\n--\n-- MIT\n\n"},
+		{style: CommentBlock, want: "/*\n * Copyright ACME\n *\n * MIT\n */\n\n"},
+		{style: CommentMarkup, want: "<!--\n  Copyright ACME\n\n  MIT\n-->\n\n"},
+	}
+	for _, tt := range tests {
+		if got := CommentHeader("Copyright ACME\n\nMIT\n", tt.style); got != tt.want {
+			t.Errorf("CommentHeader(%q) = %q, want %q", tt.style, got, tt.want)
+		}
+	}
+	for name, want := range map[string]string{
+		"main.go": CommentSlashes, "app.PY": CommentHash, "cmd/Makefile": CommentHash, "site.css": CommentBlock,
+		"schema.sql": CommentDashes, "index.html": CommentMarkup, "LICENSE": "", "data.bin": "",
+	} {
+		if got := CommentStyleFor(name); got != want {
+			t.Errorf("CommentStyleFor(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestInsertHeader(t *testing.T) {
+	header := "# Copyright ACME\n\n"
+	tests := []struct {
+		name, content, want string
+	}{
+		{name: "plain", content: "x = 1\n", want: header + "x = 1\n"},
+		{name: "empty", content: "", want: header},
+		{name: "shebang", content: "#!/bin/sh\necho hi\n", want: "#!/bin/sh\n" + header + "echo hi\n"},
+		{name: "shebang only", content: "#!/bin/sh", want: "#!/bin/sh\n" + header},
+		{name: "xml", content: "<?xml version=\"1.0\"?>\n<a/>\n", want: "<?xml version=\"1.0\"?>\n" + header + "<a/>\n"},
+		{name: "already there", content: header + "x = 1\n", want: header + "x = 1\n"},
+		{name: "already there without a blank line", content: "# Copyright ACME\nx = 1\n",
+			want: "# Copyright ACME\nx = 1\n"},
+		{name: "already after a shebang", content: "#!/bin/sh\n" + header + "echo hi\n",
+			want: "#!/bin/sh\n" + header + "echo hi\n"},
+		{name: "longer first comment", content: "# Copyright ACME Corp\n", want: header + "# Copyright ACME Corp\n"},
+	}
+	for _, tt := range tests {
+		if got := string(InsertHeader([]byte(tt.content), header)); got != tt.want {
+			t.Errorf("%s: InsertHeader() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyHeaders(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile: "header:\n" +
+			"  '**/*.go': 'Copyright {{.year}} {{.company}}'\n" +
+			"  '**/*.py': {partial: license.tmpl}\n" +
+			"  'api/*.proto': {template: 'Generated, do not edit.', commentStyle: '//'}\n" +
+			"  '**/*.sh': 'Copyright {{.company}}'\n" +
+			"  '**/*.txt': 'Copyright {{.company}}'\n" +
+			"  '**/*.css': '{{if .none}}unused{{end}}'\n" +
+			"  'lib/**': '{{.company}}'\n" +
+			"headerCopied: ['scripts/**']\n",
+		"_partials/license.tmpl": "Licensed to {{.company}}.",
+		"main.go.tmpl":           "package main\n",
+		"app/run.py.tmpl":        "print('{{.company}}')\n",
+		"api/service.proto.tmpl": "syntax = \"proto3\";\n",
+		"notes.txt.tmpl":         "notes\n",
+		"site.css.tmpl":          "body {}\n",
+		"lib/lib.go.tmpl":        "package lib\n",
+		"scripts/setup.sh":       "#!/bin/sh\necho setup\n",
+		"tools/build.sh":         "#!/bin/sh\necho build\n",
+		"scripts/done.sh":        "#!/bin/sh\n# Copyright ACME\n\necho done\n",
+	})
+	outputDir := t.TempDir()
+	var out bytes.Buffer
+	opts := Options{
+		TemplatePath: templateDir,
+		OutputDir:    outputDir,
+		Data:         map[string]any{"year": 2025, "company": "ACME"},
+		Out:          &out,
+	}
+	if err := Apply(opts); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	want := map[string]string{
+		"main.go":           "// Copyright 2025 ACME\n\npackage main\n",
+		"app/run.py":        "# Licensed to ACME.\n\nprint('ACME')\n",
+		"api/service.proto": "// Generated, do not edit.\n\nsyntax = \"proto3\";\n",
+		"notes.txt":         "notes\n",
+		"site.css":          "body {}\n",
+		"lib/lib.go":        "// Copyright 2025 ACME\n\npackage lib\n",
+		"scripts/setup.sh":  "#!/bin/sh\n# Copyright ACME\n\necho setup\n",
+		"tools/build.sh":    "#!/bin/sh\necho build\n",
+		"scripts/done.sh":   "#!/bin/sh\n# Copyright ACME\n\necho done\n",
+	}
+	check := func() {
+		t.Helper()
+		for name, content := range want {
+			got, err := os.ReadFile(filepath.Join(outputDir, name))
+			if err != nil || string(got) != content {
+				t.Errorf("Expected %s to be %q, got %q, %v", name, content, got, err)
+			}
+		}
+	}
+	check()
+	warning := "⚠️  Skipping the header of 'notes.txt': no comment style is known for 'notes.txt'"
+	if !contains(out.String(), warning) {
+		t.Errorf("Expected %q in the output:\n%s", warning, out.String())
+	}
+
+	// Applying again gives the same files.
+	if err := Apply(opts); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	check()
+
+	opts.OutputDir, opts.Strict = t.TempDir(), true
+	if err := Apply(opts); err == nil || !contains(err.Error(), "set the commentStyle of header '**/*.txt'") {
+		t.Errorf("Expected an unknown comment style to fail in strict mode, got: %v", err)
+	}
+}
+
+func TestLoadMetadataHeaders(t *testing.T) {
+	tests := []struct {
+		metadata, err string
+	}{
+		{metadata: "header:\n  '*.go': {}\n", err: "expected either a template or a partial"},
+		{metadata: "header:\n  '*.go': {template: x, partial: y.tmpl}\n", err: "expected either a template or a partial"},
+		{metadata: "header:\n  '*.go': {template: x, commentStyle: ';'}\n", err: "unknown comment style ';'"},
+	}
+	for _, tt := range tests {
+		templateDir := writeTemplate(t, map[string]string{MetadataFile: tt.metadata})
+		_, err := LoadMetadata(templateDir)
+		if err == nil || !contains(err.Error(), "invalid header '*.go'") || !contains(err.Error(), tt.err) {
+			t.Errorf("Expected %q to fail with %q, got: %v", tt.metadata, tt.err, err)
+		}
+	}
+
+	// The headers of a chain are merged, the child winning.
+	parent := writeTemplate(t, map[string]string{
+		MetadataFile: "header:\n  '*.go': parent\n  '*.py': parent\nheaderCopied: ['a/**']\n",
+	})
+	child := writeTemplate(t, map[string]string{
+		MetadataFile: "extends: " + parent + "\nheader:\n  '*.go': child\nheaderCopied: ['b/**']\n",
+	})
+	_, meta, err := ResolveChain(child)
+	if err != nil {
+		t.Fatalf("ResolveChain failed: %v", err)
+	}
+	if meta.Headers["*.go"].Template != "child" || meta.Headers["*.py"].Template != "parent" ||
+		len(meta.HeaderCopied) != 2 {
+		t.Errorf("Expected the merged headers, got %+v, %q", meta.Headers, meta.HeaderCopied)
+	}
+}
+
+func TestLintHeaders(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile: "prompts:\n  company: {}\nheader:\n  '*.go': '{{.company}} {{.year}}'\n  '*.py': '{{.company'\n",
+	})
+	findings, err := Lint(templateDir)
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+	var rules []string
+	for _, finding := range findings {
+		rules = append(rules, finding.Rule+": "+finding.Message)
+	}
+	want := []string{
+		RuleParseError + ": header '*.py' does not parse",
+		RuleUndeclaredKey + ": 'year' is not declared in prompts or defaults",
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("Expected %d findings, got %q", len(want), rules)
+	}
+	for i, w := range want {
+		if !contains(rules[i], w) {
+			t.Errorf("Finding %d = %q, want %q", i, rules[i], w)
+		}
+	}
+}
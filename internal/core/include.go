@@ -0,0 +1,105 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// DefaultMaxIncludeDepth is how deep {{template}} calls can nest when the
+// renderer doesn't set a limit.
+const DefaultMaxIncludeDepth = 20
+
+// checkIncludes follows the {{template}} calls of the named template through
+// the partials and the templates it defines, in every branch, including the
+// bodies of range loops. It fails when a template includes itself, directly
+// or through others, since executing it would only stop at the depth limit of
+// text/template, and when calls nest deeper than maxDepth.
+func checkIncludes(tmpl *template.Template, name string, maxDepth int) error {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxIncludeDepth
+	}
+	c := &includeChecker{tmpl: tmpl, maxDepth: maxDepth, heights: make(map[string]int)}
+	_, err := c.check([]string{name})
+	return err
+}
+
+// includeChecker walks the inclusion chains of a template set.
+type includeChecker struct {
+	tmpl     *template.Template
+	maxDepth int
+	// heights holds how deep the calls of the templates already checked
+	// nest, so a template included from many places is walked once.
+	heights map[string]int
+}
+
+// check walks the calls of the last template of chain and returns how deep
+// they nest.
+func (c *includeChecker) check(chain []string) (int, error) {
+	name := chain[len(chain)-1]
+	if height, ok := c.heights[name]; ok && len(chain)-1+height <= c.maxDepth {
+		return height, nil
+	}
+	t := c.tmpl.Lookup(name)
+	if t == nil || t.Tree == nil {
+		// Executing reports templates that don't exist.
+		return 0, nil
+	}
+	height, err := c.walk(t.Root, chain)
+	if err != nil {
+		return 0, err
+	}
+	c.heights[name] = height
+	return height, nil
+}
+
+// walk follows the {{template}} calls under node and returns how deep they
+// nest.
+func (c *includeChecker) walk(node parse.Node, chain []string) (int, error) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return 0, nil
+		}
+		height := 0
+		for _, child := range n.Nodes {
+			h, err := c.walk(child, chain)
+			if err != nil {
+				return 0, err
+			}
+			height = max(height, h)
+		}
+		return height, nil
+	case *parse.IfNode:
+		return c.walkBranch(&n.BranchNode, chain)
+	case *parse.RangeNode:
+		return c.walkBranch(&n.BranchNode, chain)
+	case *parse.WithNode:
+		return c.walkBranch(&n.BranchNode, chain)
+	case *parse.TemplateNode:
+		if i := slices.Index(chain, n.Name); i >= 0 {
+			cycle := append(slices.Clone(chain[i:]), n.Name)
+			return 0, fmt.Errorf("template includes itself: %s", strings.Join(cycle, " -> "))
+		}
+		next := append(slices.Clone(chain), n.Name)
+		// The first template of the chain is the one being rendered.
+		if len(next)-1 > c.maxDepth {
+			return 0, fmt.Errorf("templates nested deeper than %d: %s", c.maxDepth, strings.Join(next, " -> "))
+		}
+		height, err := c.check(next)
+		return height + 1, err
+	}
+	return 0, nil
+}
+
+// walkBranch follows the calls of both lists of an if, range or with block.
+func (c *includeChecker) walkBranch(n *parse.BranchNode, chain []string) (int, error) {
+	height, err := c.walk(n.List, chain)
+	if err != nil {
+		return 0, err
+	}
+	elseHeight, err := c.walk(n.ElseList, chain)
+	return max(height, elseHeight), err
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRendererIncludes(t *testing.T) {
+	// chain returns partials p00.tmpl to p<n-1>.tmpl, each including the
+	// next one for every item.
+	chain := func(n int) map[string]string {
+		partials := make(map[string]string)
+		for i := range n - 1 {
+			partials[fmt.Sprintf("%s/p%02d.tmpl", PartialsDir, i)] =
+				fmt.Sprintf(`{{range .items}}{{template "p%02d.tmpl" $}}{{end}}`, i+1)
+		}
+		partials[fmt.Sprintf("%s/p%02d.tmpl", PartialsDir, n-1)] = "{{.name}}"
+		return partials
+	}
+	render := func(partials map[string]string, content string, maxDepth int) (string, error) {
+		t.Helper()
+		renderer, err := NewRenderer(filepath.Join(writeTemplate(t, partials), PartialsDir), false)
+		if err != nil {
+			t.Fatalf("NewRenderer failed: %v", err)
+		}
+		renderer.MaxIncludeDepth = maxDepth
+		var out bytes.Buffer
+		err = renderer.Render(&out, "main.tmpl", []byte(content), map[string]any{"name": "x", "items": []any{1}})
+		return out.String(), err
+	}
+
+	errorTests := []struct {
+		name     string
+		partials map[string]string
+		content  string
+		maxDepth int
+		want     string
+	}{
+		{
+			name:     "direct self-include",
+			partials: map[string]string{PartialsDir + "/a.tmpl": `{{if .name}}{{template "a.tmpl" .}}{{end}}`},
+			content:  `{{template "a.tmpl" .}}`,
+			want:     "template includes itself: a.tmpl -> a.tmpl",
+		},
+		{
+			name: "two-node cycle from a range loop",
+			partials: map[string]string{
+				PartialsDir + "/a.tmpl": `{{range .items}}{{template "b.tmpl" $}}{{end}}`,
+				PartialsDir + "/b.tmpl": `{{with .name}}{{else}}{{template "a.tmpl" $}}{{end}}`,
+			},
+			content: `{{range .items}}{{template "a.tmpl" $}}{{end}}`,
+			want:    "template includes itself: a.tmpl -> b.tmpl -> a.tmpl",
+		},
+		{
+			name:     "cycle through a template defined in the file",
+			partials: map[string]string{PartialsDir + "/a.tmpl": `{{template "local" .}}`},
+			content:  `{{define "local"}}{{template "a.tmpl" .}}{{end}}{{template "local" .}}`,
+			want:     "template includes itself: local -> a.tmpl -> local",
+		},
+		{
+			name:     "deeper than the default limit",
+			partials: chain(21),
+			content:  `{{template "p00.tmpl" .}}`,
+			want:     "templates nested deeper than 20: main.tmpl -> p00.tmpl -> p01.tmpl",
+		},
+		{
+			name:     "deeper than a configured limit",
+			partials: chain(5),
+			content:  `{{template "p00.tmpl" .}}`,
+			maxDepth: 3,
+			want:     "templates nested deeper than 3: main.tmpl -> p00.tmpl -> p01.tmpl -> p02.tmpl -> p03.tmpl",
+		},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := render(tt.partials, tt.content, tt.maxDepth)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected an error containing %q, got %v", tt.want, err)
+			}
+			if !strings.HasPrefix(err.Error(), "could not parse template 'main.tmpl'") {
+				t.Errorf("Expected the error to name the rendered file, got %v", err)
+			}
+		})
+	}
+
+	t.Run("deep but legal chain", func(t *testing.T) {
+		got, err := render(chain(20), `{{template "p00.tmpl" .}}{{template "p10.tmpl" .}}`, 0)
+		if err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+		if got != "xx" {
+			t.Errorf("Expected %q, got %q", "xx", got)
+		}
+
+		got, err = render(chain(30), `{{template "p00.tmpl" .}}`, 30)
+		if err != nil || got != "x" {
+			t.Errorf("Expected a raised limit to render %q, got %q, %v", "x", got, err)
+		}
+	})
+
+	t.Run("shared partials", func(t *testing.T) {
+		// Every partial includes the next one twice, which is walked once.
+		partials := make(map[string]string)
+		for i := range 19 {
+			partials[fmt.Sprintf("%s/p%02d.tmpl", PartialsDir, i)] =
+				fmt.Sprintf(`{{if false}}{{template "p%02d.tmpl" .}}{{template "p%02d.tmpl" .}}{{end}}`, i+1, i+1)
+		}
+		partials[PartialsDir+"/p19.tmpl"] = "{{.name}}"
+		if _, err := render(partials, `{{template "p00.tmpl" .}}`, 0); err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package core
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Sources of the values of the keys a template references.
+const (
+	SourceData     = "data"
+	SourceDefault  = "default"
+	SourceComputed = "computed"
+	SourceDerived  = "derived"
+)
+
+// KeyUsage is a data key referenced by a template, with the files that
+// reference it.
+type KeyUsage struct {
+	// Key is the dotted path of the reference, such as "db.port".
+	Key string `json:"key"`
+	// Files are the slash-separated paths, relative to the template root,
+	// of the files and directories referencing the key. template.yaml
+	// stands for its computed values, when conditions, notices and headers.
+	Files []string `json:"files"`
+	// Source is where the value of a satisfied key comes from: data,
+	// default, computed or derived.
+	Source string `json:"source,omitempty"`
+}
+
+// InspectReport cross-references the keys a template references with the
+// data it is given.
+type InspectReport struct {
+	// Missing are the referenced keys neither the data nor the template
+	// provides.
+	Missing []KeyUsage `json:"missing"`
+	// Unused are the dotted paths of the data values no template references.
+	Unused []string `json:"unused"`
+	// Satisfied are the referenced keys that have a value.
+	Satisfied []KeyUsage `json:"satisfied"`
+}
+
+// Inspect reports the keys the template and its parents reference that data
+// is missing, the values of data they never reference, and the keys that
+// have a value, from data or from the defaults, computed values and derived
+// cases of the template. It finds the references the way Apply does before
+// asking for missing values. A reference to a nested key, such as
+// {{.db.port}}, is looked up in the nested data as deep as it goes.
+func Inspect(templatePath string, data map[string]any, maxSize int64) (*InspectReport, error) {
+	chain, meta, err := ResolveChain(templatePath)
+	if err != nil {
+		return nil, err
+	}
+	refs := make(keyTracker)
+	for _, path := range chain {
+		found, err := templateReferences(path, meta, maxSize)
+		if err != nil {
+			return nil, err
+		}
+		refs.add(found)
+	}
+	known := make(map[string]bool)
+	for _, key := range slices.Concat(slices.Collect(maps.Keys(data)), slices.Collect(maps.Keys(meta.Defaults))) {
+		known[key] = true
+	}
+
+	report := &InspectReport{Missing: []KeyUsage{}, Satisfied: []KeyUsage{}}
+	for _, ref := range slices.Sorted(maps.Keys(refs)) {
+		usage := KeyUsage{Key: ref, Files: slices.Sorted(slices.Values(refs[ref]))}
+		key, _, _ := strings.Cut(ref, ".")
+		_, computed := meta.Computed[key]
+		_, derived := IsDerivedCase(key, known)
+		switch {
+		case reaches(data, ref):
+			usage.Source = SourceData
+		case reaches(meta.Defaults, ref):
+			usage.Source = SourceDefault
+		case computed:
+			usage.Source = SourceComputed
+		case meta.DeriveCases && derived:
+			usage.Source = SourceDerived
+		default:
+			report.Missing = append(report.Missing, usage)
+			continue
+		}
+		report.Satisfied = append(report.Satisfied, usage)
+	}
+
+	report.Unused = refs.unused(data)
+	return report, nil
+}
+
+// reaches reports whether data has a value at the dotted path ref, or at a
+// shorter part of it holding something other than a mapping, which the data
+// doesn't describe any deeper.
+func reaches(data map[string]any, ref string) bool {
+	var value any = data
+	for part := range strings.SplitSeq(ref, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return value != nil
+		}
+		if value, ok = m[part]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// templateReferences returns the dotted paths of the data referenced by the
+// paths, templates and partials of a template directory, and by the
+// expressions of its metadata, with the slash-separated paths of the files
+// referencing them. Files that don't parse, or are larger than maxSize when
+// it is positive, are skipped; planning and rendering report them.
+func templateReferences(templatePath string, meta *Metadata, maxSize int64) (keyTracker, error) {
+	refs := make(keyTracker)
+	err := filepath.WalkDir(templatePath, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		relPath, err := filepath.Rel(templatePath, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if (relPath == TestsDir || meta.Ignored(relPath) || meta.IsRaw(relPath)) && d.IsDir() {
+			return filepath.SkipDir
+		}
+		if relPath == MetadataFile || IsHintFile(d.Name()) || meta.Ignored(relPath) {
+			return nil
+		}
+
+		partial := strings.HasPrefix(relPath, PartialsDir+"/") || relPath == PartialsDir
+		if !partial {
+			refs.record(relPath, pathTokens(relPath, helperFunc))
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			// The target of a preserved symlink is rendered like a path.
+			if link, linkErr := os.Readlink(path); linkErr == nil {
+				refs.record(relPath, pathTokens(link, helperFunc))
+			}
+		}
+		_, render, _ := templateName(d.Name())
+		if !d.IsDir() && (partial || render) {
+			// Oversized templates and the irregular files apply skips aren't
+			// read.
+			info, infoErr := os.Stat(path)
+			if infoErr == nil && (maxSize > 0 && info.Size() > maxSize || irregularType(info.Mode()) != "") {
+				return nil
+			}
+			content, readErr := os.ReadFile(path)
+			if readErr != nil {
+				return readErr
+			}
+			refs.record(relPath, string(content))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template '%s': %w", templatePath, err)
+	}
+
+	// The expressions of template.yaml use the data too.
+	for _, text := range meta.Computed {
+		refs.record(MetadataFile, text)
+	}
+	for _, prompt := range meta.Prompts {
+		refs.record(MetadataFile, prompt.When)
+	}
+	for _, text := range meta.Notices {
+		refs.record(MetadataFile, text)
+	}
+	for _, header := range meta.Headers {
+		refs.record(MetadataFile, header.Template)
+	}
+	return refs, nil
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	parent := writeTemplate(t, map[string]string{
+		MetadataFile:        "defaults:\n  license: MIT\n",
+		"LICENSE.tmpl":      "{{.license}} {{.owner}}",
+		"_partials/db.tmpl": "{{.db.host}}:{{.db.port}}",
+	})
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile: "extends: " + parent + "\n" +
+			"deriveCases: true\n" +
+			"computed:\n  module: 'example.com/{{.name}}'\n" +
+			"notices:\n  - '{{.next_step}}'\n",
+		"{{.name}}/main.go.tmpl": "{{.module}} {{.name_camel}} {{template \"db.tmpl\" .}} {{.owner}}",
+		"config.yaml.tmpl":       "{{.features.auth}} {{.tags.first}} {{.owner}}",
+	})
+	data := map[string]any{
+		"name":     "app",
+		"db":       map[string]any{"host": "localhost"},
+		"features": map[string]any{"auth": true, "billing": false},
+		"tags":     []any{"a"},
+		"extra":    1,
+	}
+	report, err := Inspect(templateDir, data, 0)
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	wantMissing := []KeyUsage{
+		{Key: "db.port", Files: []string{"_partials/db.tmpl"}},
+		{Key: "next_step", Files: []string{MetadataFile}},
+		{Key: "owner", Files: []string{"LICENSE.tmpl", "config.yaml.tmpl", "{{.name}}/main.go.tmpl"}},
+	}
+	if !reflect.DeepEqual(report.Missing, wantMissing) {
+		t.Errorf("Expected missing %+v, got %+v", wantMissing, report.Missing)
+	}
+	wantUnused := []string{"extra", "features.billing"}
+	if !reflect.DeepEqual(report.Unused, wantUnused) {
+		t.Errorf("Expected unused %q, got %q", wantUnused, report.Unused)
+	}
+	var satisfied []string
+	for _, usage := range report.Satisfied {
+		satisfied = append(satisfied, usage.Key+" "+usage.Source)
+	}
+	wantSatisfied := []string{
+		"db.host data", "features.auth data", "license default", "module computed", "name data",
+		"name_camel derived", "tags.first data",
+	}
+	if !reflect.DeepEqual(satisfied, wantSatisfied) {
+		t.Errorf("Expected satisfied %q, got %q", wantSatisfied, satisfied)
+	}
+}
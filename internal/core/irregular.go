@@ -0,0 +1,39 @@
+package core
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+)
+
+// irregularType names the type of a file that is neither a regular file, a
+// directory nor a symlink, such as a named pipe, or returns "" for the
+// others. Reading such a file blocks or never ends, so it isn't generated.
+func irregularType(mode fs.FileMode) string {
+	switch {
+	case mode&fs.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeCharDevice != 0:
+		return "character device"
+	case mode&fs.ModeDevice != 0:
+		return "device"
+	case mode&fs.ModeIrregular != 0:
+		return "irregular file"
+	default:
+		return ""
+	}
+}
+
+// skipIrregular leaves out a file of the templates of the given irregular
+// type, warning about it, or fails in strict mode. rel is the destination
+// of the file, recorded as skipped in the result.
+func (a *applier) skipIrregular(path, rel, kind string) error {
+	if a.opts.Strict {
+		return fmt.Errorf("'%s' is a %s, which can't be generated", path, kind)
+	}
+	fmt.Fprintf(a.out, "⚠️  Skipping '%s': it is a %s\n", path, kind)
+	a.status[filepath.ToSlash(rel)] = StatusSkippedIrregular
+	return nil
+}
@@ -0,0 +1,91 @@
+//go:build unix
+
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestApplySkipsNamedPipes(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile:          "raw: [assets]\n",
+		"README.md.tmpl":      "# {{.name}}\n",
+		"assets/logo.svg":     "<svg/>",
+		"partials/.gitkeep":   "",
+		"docs/{{.name}}.tmpl": "{{.name}}\n",
+	})
+	for _, name := range []string{"events.tmpl", "assets/feed", "partials/pipe"} {
+		if err := unix.Mkfifo(filepath.Join(templateDir, name), 0o600); err != nil {
+			t.Skipf("Can't create a named pipe: %v", err)
+		}
+	}
+	// apply fails the test rather than hanging when a pipe is read.
+	apply := func(opts Options) (string, error) {
+		t.Helper()
+		var out bytes.Buffer
+		opts.TemplatePath, opts.Data, opts.Out = templateDir, map[string]any{"name": "app"}, &out
+		done := make(chan error, 1)
+		go func() { done <- Apply(opts) }()
+		select {
+		case err := <-done:
+			return out.String(), err
+		case <-time.After(10 * time.Second):
+			t.Fatal("Apply hung on a named pipe")
+			return "", nil
+		}
+	}
+
+	outputDir := t.TempDir()
+	result := NewResult()
+	out, err := apply(Options{OutputDir: outputDir, Result: result})
+	if err != nil {
+		t.Fatalf("Apply failed: %v\n%s", err, out)
+	}
+	for _, line := range []string{
+		"⚠️  Skipping '" + filepath.Join(templateDir, "events.tmpl") + "': it is a named pipe\n",
+		"⚠️  Skipping '" + filepath.Join(templateDir, "assets", "feed") + "': it is a named pipe\n",
+	} {
+		if !contains(out, line) {
+			t.Errorf("Expected %q in the output:\n%s", line, out)
+		}
+	}
+	files := readFiles(t, outputDir)
+	if files["README.md"] != "# app\n" || files["assets/logo.svg"] != "<svg/>" {
+		t.Errorf("Expected the other files to be generated, got %v", files)
+	}
+	for _, path := range []string{"events.tmpl", "events", "assets/feed"} {
+		if _, err := os.Lstat(filepath.Join(outputDir, path)); !os.IsNotExist(err) {
+			t.Errorf("Expected no %s in the output, got %v", path, err)
+		}
+	}
+	statuses := make(map[string]FileStatus)
+	for _, file := range result.Files {
+		statuses[file.Path] = file.Status
+	}
+	for _, path := range []string{"events.tmpl", "assets/feed"} {
+		if statuses[path] != StatusSkippedIrregular {
+			t.Errorf("Expected %s to be %s, got %q", path, StatusSkippedIrregular, statuses[path])
+		}
+	}
+
+	// A dry run plans the same.
+	result = NewResult()
+	if out, err = apply(Options{OutputDir: t.TempDir(), DryRun: true, Result: result}); err != nil {
+		t.Fatalf("Dry run failed: %v\n%s", err, out)
+	}
+	if !contains(out, "events.tmpl': it is a named pipe") {
+		t.Errorf("Expected the dry run to skip the pipe:\n%s", out)
+	}
+
+	// Strict mode fails on them.
+	_, err = apply(Options{OutputDir: t.TempDir(), Strict: true})
+	if err == nil || !contains(err.Error(), "is a named pipe, which can't be generated") {
+		t.Errorf("Expected strict mode to fail on the pipe, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package core
+
+// stripJSONC blanks out the line and block comments and the trailing commas
+// of JSON with comments, leaving plain JSON. Everything removed is replaced
+// with spaces, keeping newlines, so offsets and line numbers still match the
+// original content. String literals are left untouched.
+func stripJSONC(content []byte) []byte {
+	out := make([]byte, len(content))
+	copy(out, content)
+
+	// Blank out comments.
+	for i := 0; i < len(out); i++ {
+		switch {
+		case out[i] == '"':
+			i = skipJSONString(out, i)
+		case out[i] == '/' && i+1 < len(out) && out[i+1] == '/':
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		case out[i] == '/' && i+1 < len(out) && out[i+1] == '*':
+			out[i], out[i+1] = ' ', ' '
+			for i += 2; i < len(out) && (out[i] != '*' || i+1 >= len(out) || out[i+1] != '/'); i++ {
+				if out[i] != '\n' {
+					out[i] = ' '
+				}
+			}
+			if i < len(out) {
+				out[i], out[i+1] = ' ', ' '
+				i++
+			}
+		}
+	}
+
+	// Blank out commas followed by a closing bracket.
+	for i := 0; i < len(out); i++ {
+		switch out[i] {
+		case '"':
+			i = skipJSONString(out, i)
+		case ',':
+			j := i + 1
+			for j < len(out) && isJSONSpace(out[j]) {
+				j++
+			}
+			if j < len(out) && (out[j] == '}' || out[j] == ']') {
+				out[i] = ' '
+			}
+		}
+	}
+	return out
+}
+
+// skipJSONString returns the index of the quote closing the string literal
+// starting at the quote at start, or the last index if it is unterminated.
+func skipJSONString(content []byte, start int) int {
+	for i := start + 1; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return len(content) - 1
+}
+
+// isJSONSpace reports whether c is JSON whitespace.
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStripJSONC(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "pure json", content: `{"a": [1, 2], "b": {"c": "d"}}`, want: `{"a": [1, 2], "b": {"c": "d"}}`},
+		{name: "line comment", content: "{\"a\": 1 // one\n}", want: "{\"a\": 1       \n}"},
+		{name: "block comment", content: "{/* a\nb */\"a\": 1}", want: "{    \n    \"a\": 1}"},
+		{
+			name:    "trailing commas",
+			content: "{\"a\": [1, 2,], \"b\": {\"c\": 1,\n},}",
+			want:    "{\"a\": [1, 2 ], \"b\": {\"c\": 1 \n} }",
+		},
+		{name: "comma before comment", content: "[1, // last\n]", want: "[1         \n]"},
+		{
+			name:    "comments in strings",
+			content: `{"url": "http://x/*y*/", "s": "a // b,]"}`,
+			want:    `{"url": "http://x/*y*/", "s": "a // b,]"}`,
+		},
+		{name: "escaped quotes", content: `{"q": "say \"//hi\"", // c` + "\n}", want: `{"q": "say \"//hi\""      ` + "\n}"},
+		{name: "unterminated block comment", content: `{"a": 1} /* open`, want: `{"a": 1}        `},
+		{name: "unterminated string", content: `{"a": "// open`, want: `{"a": "// open`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(stripJSONC([]byte(tt.content)))
+			if got != tt.want {
+				t.Errorf("stripJSONC(%q) = %q; want %q", tt.content, got, tt.want)
+			}
+			if len(got) != len(tt.content) {
+				t.Errorf("Expected the length to be kept, got %d for %d", len(got), len(tt.content))
+			}
+		})
+	}
+}
+
+func TestParseDataJSONC(t *testing.T) {
+	content := `{
+  // The service name.
+  "name": "api", /* inline */
+  "url": "https://example.com//path", // not a comment inside the string
+  "db": {
+    "hosts": [
+      {"name": "primary", "tags": ["a", "b",],},
+      {"name": "replica",},
+    ],
+  },
+}
+`
+	want := map[string]any{
+		"name": "api",
+		"url":  "https://example.com//path",
+		"db": map[string]any{
+			"hosts": []any{
+				map[string]any{"name": "primary", "tags": []any{"a", "b"}},
+				map[string]any{"name": "replica"},
+			},
+		},
+	}
+
+	for _, format := range []string{"json", "jsonc"} {
+		data, err := ParseData([]byte(content), format, "data."+format, DataOptions{})
+		if err != nil {
+			t.Fatalf("ParseData(%s) failed: %v", format, err)
+		}
+		if !reflect.DeepEqual(data, want) {
+			t.Errorf("ParseData(%s) = %#v; want %#v", format, data, want)
+		}
+	}
+
+	t.Run("strict json rejects comments", func(t *testing.T) {
+		_, err := ParseData([]byte(content), "json", "data.json", DataOptions{Strict: true})
+		if err == nil || !contains(err.Error(), "failed to parse JSON file 'data.json'") {
+			t.Errorf("Expected a parse error, got: %v", err)
+		}
+		if _, err = ParseData([]byte(content), "jsonc", "data.jsonc", DataOptions{Strict: true}); err != nil {
+			t.Errorf("Expected jsonc to allow comments in strict mode, got: %v", err)
+		}
+	})
+
+	t.Run("pure json is unchanged", func(t *testing.T) {
+		pure := []byte(`{"a": "//", "b": [1, 2.5, true, null], "c": {"d": "/* x */"}}`)
+		want := map[string]any{"a": "//", "b": []any{1, 2.5, true, nil}, "c": map[string]any{"d": "/* x */"}}
+		for _, opts := range []DataOptions{{}, {Strict: true}} {
+			data, err := ParseData(pure, "json", "data.json", opts)
+			if err != nil || !reflect.DeepEqual(data, want) {
+				t.Errorf("ParseData = %#v, %v; want %#v", data, err, want)
+			}
+		}
+	})
+
+	t.Run("jsonc extension", func(t *testing.T) {
+		if format, err := DataFormat("data.JSONC"); err != nil || format != "jsonc" {
+			t.Errorf("DataFormat(data.JSONC) = %q, %v; want jsonc", format, err)
+		}
+	})
+}
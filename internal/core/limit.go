@@ -0,0 +1,68 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// DefaultMaxFiles and DefaultMaxBytes are the limits of the files and bytes
+// a run of mold apply generates, and a template archive extracts, unless
+// told otherwise. They are far above what a project needs, and only stop a
+// runaway path placeholder or template.
+const (
+	DefaultMaxFiles = 100_000
+	DefaultMaxBytes = 10 << 30
+)
+
+// fileLimit counts files and their bytes against limits, zero or less
+// disabling one.
+type fileLimit struct {
+	maxFiles int
+	maxBytes int64
+	files    int
+	bytes    int64
+}
+
+// add counts a file of size bytes, failing with the totals once they are
+// over a limit.
+func (l *fileLimit) add(size int64) error {
+	l.files++
+	l.bytes += size
+	if l.maxFiles > 0 && l.files > l.maxFiles {
+		return fmt.Errorf("%d files, over the limit of %d files", l.files, l.maxFiles)
+	}
+	if l.maxBytes > 0 && l.bytes > l.maxBytes {
+		return fmt.Errorf("%s, over the limit of %s", formatSize(l.bytes), formatSize(l.maxBytes))
+	}
+	return nil
+}
+
+// checkLimits counts the planned files against MaxFiles and MaxBytes
+// before anything is written, rendered files by the size of their template.
+// The files of raw directories are only counted as they are copied.
+func (a *applier) checkLimits(entries []entry) error {
+	planned := fileLimit{maxFiles: a.opts.MaxFiles, maxBytes: a.opts.MaxBytes}
+	for _, e := range entries {
+		var size int64
+		switch e.kind {
+		case entryDir, entryRaw:
+			continue
+		case entryRender, entryCopy:
+			size = e.info.Size()
+		case entrySymlink:
+		}
+		if err := planned.add(size); err != nil {
+			return fmt.Errorf("the templates generate %w", err)
+		}
+	}
+	return nil
+}
+
+// count counts a generated file against MaxFiles and MaxBytes, stopping
+// the run once it goes over one.
+func (a *applier) count(relPath string, size int64) error {
+	if err := a.generated.add(size); err != nil {
+		return fmt.Errorf("generating '%s' brings the output to %w", filepath.ToSlash(relPath), err)
+	}
+	return nil
+}
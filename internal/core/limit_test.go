@@ -0,0 +1,79 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestApplyLimits(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile:         "raw: [assets]\n",
+		"README.md.tmpl":     "# {{.name}}\n",
+		"LICENSE":            "MIT\n",
+		"assets/a.txt":       "a",
+		"assets/b.txt":       "b",
+		"assets/fonts/c.txt": "c",
+	})
+	data := map[string]any{"name": "app"}
+
+	t.Run("planned files", func(t *testing.T) {
+		outputDir := t.TempDir()
+		var out bytes.Buffer
+		err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: &out, MaxFiles: 1})
+		if err == nil || err.Error() != "the templates generate 2 files, over the limit of 1 files" {
+			t.Fatalf("Expected the plan to be rejected, got %v", err)
+		}
+		if files := readFiles(t, outputDir); len(files) != 0 {
+			t.Errorf("Expected nothing written, got %v", files)
+		}
+	})
+
+	t.Run("planned bytes", func(t *testing.T) {
+		var out bytes.Buffer
+		err := Apply(Options{TemplatePath: templateDir, OutputDir: t.TempDir(), Data: data, Out: &out, MaxBytes: 10})
+		if err == nil || err.Error() != "the templates generate 16 B, over the limit of 10 B" {
+			t.Fatalf("Expected the plan to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("raw files while generating", func(t *testing.T) {
+		archive := filepath.Join(t.TempDir(), "app.tar")
+		sink, err := NewSink(archive, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		result := NewResult()
+		err = Apply(Options{TemplatePath: templateDir, Sink: sink, Data: data, Out: &out, MaxFiles: 4,
+			Result: result})
+		want := "error during template processing: failed to copy raw directory '" +
+			filepath.Join(templateDir, "assets") + "': generating 'assets/fonts/c.txt' brings the output to 5 files, " +
+			"over the limit of 4 files"
+		if err == nil || err.Error() != want {
+			t.Fatalf("Expected the run to stop, got %v", err)
+		}
+		if _, statErr := os.Stat(archive); !os.IsNotExist(statErr) {
+			t.Errorf("Expected the partial archive to be removed, got %v", statErr)
+		}
+		if result.Options.MaxFiles != 4 || result.GeneratedFiles != 5 || result.GeneratedBytes != 13 {
+			t.Errorf("Expected the limits and totals in the result, got %+v %d %d",
+				result.Options, result.GeneratedFiles, result.GeneratedBytes)
+		}
+	})
+
+	t.Run("within the limits", func(t *testing.T) {
+		var out bytes.Buffer
+		result := NewResult()
+		err := Apply(Options{TemplatePath: templateDir, OutputDir: t.TempDir(), Data: data, Out: &out, MaxFiles: 5,
+			MaxBytes: 16, NoProvenance: true, Result: result})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		if result.GeneratedFiles != 5 || result.GeneratedBytes != 13 {
+			t.Errorf("Expected 5 files of 13 bytes, got %d of %d", result.GeneratedFiles, result.GeneratedBytes)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/0m3kk/mold/internal/utils"
+)
+
+// LinkMode says how Apply puts the files it copies, rather than renders,
+// into an output directory.
+type LinkMode string
+
+const (
+	// LinkCopy copies them.
+	LinkCopy LinkMode = "copy"
+	// LinkHard hard links them to the template files, or copies them when
+	// the template is on another device.
+	LinkHard LinkMode = "hard"
+	// LinkSymlink makes them symlinks to the template files.
+	LinkSymlink LinkMode = "symlink"
+)
+
+// ParseLinkMode parses the name of a link mode.
+func ParseLinkMode(name string) (LinkMode, error) {
+	switch mode := LinkMode(name); mode {
+	case LinkCopy, LinkHard, LinkSymlink:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid link mode '%s': expected copy, hard or symlink", name)
+}
+
+// linking reports whether copied files are linked to the template.
+func (a *applier) linking() bool {
+	return a.opts.Link != "" && a.opts.Link != LinkCopy
+}
+
+// checkLink rejects linking where the output couldn't share the template
+// files, or would change them.
+func (a *applier) checkLink() error {
+	if !a.linking() {
+		return nil
+	}
+	switch {
+	case a.opts.Sink != nil || ArchiveFormat(a.opts.OutputDir) != "":
+		return errors.New("linking needs an output directory, not an archive or a stream")
+	case a.merging():
+		return errors.New("linking cannot be combined with merging, merging into a linked file would change " +
+			"the template")
+	}
+	return nil
+}
+
+// link puts a copied file into the output directory as a link to the
+// template file, and reports whether it did. A file matching a formatter is
+// left to be copied, since formatting it in place would change the template.
+func (a *applier) link(e entry) (bool, error) {
+	if !a.linking() || len(a.formatters(e.layer, e.rel)) > 0 || a.copiedHeader(e.layer, e.rel) {
+		return false, nil
+	}
+	if err := a.count(e.rel, e.info.Size()); err != nil {
+		return false, err
+	}
+	fmt.Fprintf(a.out, "🔗 Linking: %s (%s)\n", e.rel, a.opts.Link)
+	if err := a.backup(e.rel); err != nil {
+		return false, err
+	}
+	dirSink, onDisk := a.sink.(*DirSink)
+	if !onDisk {
+		// A dry run.
+		return true, nil
+	}
+	start := a.profiler.Now()
+	src, err := filepath.EvalSymlinks(e.src)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve '%s': %w", e.src, err)
+	}
+	dest := dirSink.Path(e.rel)
+	if err = os.MkdirAll(filepath.Dir(dest), utils.DirMode); err != nil {
+		return false, fmt.Errorf("failed to create directory for '%s': %w", dest, err)
+	}
+	info, err := os.Lstat(dest)
+	switch {
+	case err == nil && !info.IsDir():
+		err = os.Remove(dest)
+	case errors.Is(err, fs.ErrNotExist):
+		err = nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to replace '%s': %w", dest, err)
+	}
+
+	linked := true
+	switch a.opts.Link {
+	case LinkHard:
+		linked, err = utils.HardLink(src, dest)
+		if err == nil && !linked {
+			fmt.Fprintf(a.out, "📄 Copying: %s, the template is on another device\n", e.rel)
+		}
+	case LinkSymlink:
+		if src, err = filepath.Abs(src); err == nil {
+			err = os.Symlink(src, dest)
+		}
+	case LinkCopy:
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to link '%s': %w", e.rel, err)
+	}
+	sum, err := HashFile(src)
+	if err != nil {
+		return false, err
+	}
+	a.files.add(e.rel, e.info.Mode(), sum)
+	if linked {
+		a.files.link(e.rel, a.opts.Link)
+	}
+	a.profiler.Record(e.rel, PhaseCopy, start)
+	return true, nil
+}
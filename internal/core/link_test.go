@@ -0,0 +1,153 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyLink(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile:         "formatters:\n  \"**/*.go\": [\"true\"]\n",
+		"assets/model.bin":   "weights",
+		"README.md.tmpl":     "# {{.name}}",
+		"{{.name}}/lib.go":   "package lib",
+		"{{.name}}/data.txt": "data",
+	})
+	data := map[string]any{"name": "demo"}
+	template := func(rel string) string { return filepath.Join(templateDir, filepath.FromSlash(rel)) }
+
+	for _, mode := range []LinkMode{LinkHard, LinkSymlink} {
+		t.Run(string(mode), func(t *testing.T) {
+			outputDir := filepath.Join(t.TempDir(), "out")
+			var out bytes.Buffer
+			err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: &out, Link: mode})
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			for rel, src := range map[string]string{
+				"assets/model.bin": "assets/model.bin",
+				"demo/data.txt":    "{{.name}}/data.txt",
+			} {
+				info, err := os.Stat(filepath.Join(outputDir, rel))
+				if err != nil || !os.SameFile(mustStat(t, template(src)), info) {
+					t.Errorf("Expected '%s' linked to the template, got %v (%v)", rel, info, err)
+				}
+				linkInfo, _ := os.Lstat(filepath.Join(outputDir, rel))
+				if isSymlink := linkInfo.Mode()&os.ModeSymlink != 0; isSymlink != (mode == LinkSymlink) {
+					t.Errorf("Expected '%s' to be a symlink only with %s, got %v", rel, LinkSymlink, linkInfo.Mode())
+				}
+			}
+			// Rendered files and files to format are written.
+			for _, rel := range []string{"README.md", "demo/lib.go"} {
+				info, err := os.Lstat(filepath.Join(outputDir, rel))
+				if err != nil || !info.Mode().IsRegular() {
+					t.Errorf("Expected '%s' to be a regular file, got %v (%v)", rel, info, err)
+				}
+			}
+			lib := mustStat(t, filepath.Join(outputDir, "demo", "lib.go"))
+			if os.SameFile(lib, mustStat(t, template("{{.name}}/lib.go"))) {
+				t.Errorf("Expected a file to format not to be linked")
+			}
+			if !contains(out.String(), "🔗 Linking: assets/model.bin ("+string(mode)+")") {
+				t.Errorf("Expected the link to be logged, got:\n%s", out.String())
+			}
+
+			provenance, err := LoadProvenance(outputDir)
+			if err != nil {
+				t.Fatalf("LoadProvenance failed: %v", err)
+			}
+			links := make(map[string]LinkMode)
+			for _, file := range provenance.Files {
+				links[file.Path] = file.Link
+			}
+			want := map[string]LinkMode{"README.md": "", "assets/model.bin": mode, "demo/data.txt": mode, "demo/lib.go": ""}
+			for path, link := range want {
+				if links[path] != link {
+					t.Errorf("Expected '%s' recorded with link %q, got %q", path, link, links[path])
+				}
+			}
+			checks, err := CheckFiles(outputDir, provenance.Files)
+			if err != nil {
+				t.Fatalf("CheckFiles failed: %v", err)
+			}
+			for _, check := range checks {
+				if check.State != FileUnchanged || check.Link != want[check.Path] {
+					t.Errorf("Expected '%s' unchanged with link %q, got %+v", check.Path, want[check.Path], check)
+				}
+			}
+
+			// Copying over the links replaces them, leaving the template alone.
+			err = Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: io.Discard})
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			info, err := os.Lstat(filepath.Join(outputDir, "assets", "model.bin"))
+			if err != nil || !info.Mode().IsRegular() || os.SameFile(info, mustStat(t, template("assets/model.bin"))) {
+				t.Errorf("Expected the link replaced by a copy, got %v (%v)", info, err)
+			}
+			if content, err := os.ReadFile(template("assets/model.bin")); err != nil || string(content) != "weights" {
+				t.Errorf("Expected the template file unchanged, got %q (%v)", content, err)
+			}
+		})
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		outputDir := filepath.Join(t.TempDir(), "out")
+		var out bytes.Buffer
+		err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: &out,
+			Link: LinkHard, DryRun: true})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		if !contains(out.String(), "🔗 Linking: assets/model.bin (hard)") {
+			t.Errorf("Expected the link to be logged, got:\n%s", out.String())
+		}
+		if _, err = os.Stat(outputDir); !os.IsNotExist(err) {
+			t.Errorf("Expected nothing written, got %v", err)
+		}
+	})
+
+	errorTests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "archive",
+			opts: Options{OutputDir: filepath.Join(t.TempDir(), "out.zip"), Link: LinkHard},
+			want: "linking needs an output directory",
+		},
+		{
+			name: "merge",
+			opts: Options{OutputDir: t.TempDir(), Link: LinkSymlink, Merge: MergeAlways},
+			want: "linking cannot be combined with merging",
+		},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.TemplatePath = templateDir
+			tt.opts.Data = data
+			tt.opts.Out = io.Discard
+			if err := Apply(tt.opts); err == nil || !contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := ParseLinkMode("soft"); err == nil || !contains(err.Error(), "expected copy, hard or symlink") {
+		t.Errorf("Expected an invalid link mode to fail, got %v", err)
+	}
+}
+
+// mustStat returns the information of a file, failing the test without it.
+func mustStat(t *testing.T, path string) os.FileInfo {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat '%s': %v", path, err)
+	}
+	return info
+}
@@ -0,0 +1,256 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Lint rule IDs. They are stable so findings can be disabled by ID.
+const (
+	// RuleUndeclaredKey reports a key referenced by the template that no
+	// prompt or default declares.
+	RuleUndeclaredKey = "undeclared-key"
+	// RuleUnusedInput reports a declared prompt or default that no file or
+	// path references.
+	RuleUnusedInput = "unused-input"
+	// RuleParseError reports a template or path that doesn't parse.
+	RuleParseError = "parse-error"
+	// RuleUnknownFunc reports a call of a function that neither the helpers
+	// nor text/template define, which fails when rendering.
+	RuleUnknownFunc = "unknown-func"
+	// RuleCopiedDelimiters reports a copied file containing template
+	// delimiters, which usually means it is missing the '.tmpl' suffix.
+	RuleCopiedDelimiters = "copied-delimiters"
+	// RuleMinMoldVersion reports a setting older mold versions don't
+	// support, without a minMoldVersion requiring one that does.
+	RuleMinMoldVersion = "min-mold-version"
+	// RuleTemplateFile reports a call of a function reading the files of
+	// the template with a wrong constant argument: a data file the template
+	// doesn't hold or that doesn't parse, a malformed glob pattern or a path
+	// outside the template.
+	RuleTemplateFile = "template-file"
+)
+
+// LintRules lists every lint rule ID.
+//
+//nolint:gochecknoglobals // list of the stable lint rule IDs
+var LintRules = []string{
+	RuleUndeclaredKey, RuleUnusedInput, RuleParseError, RuleUnknownFunc, RuleCopiedDelimiters, RuleMinMoldVersion,
+	RuleTemplateFile,
+}
+
+// Finding levels.
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+)
+
+// Finding is a problem reported by Lint.
+type Finding struct {
+	Rule  string
+	Level string
+	// Path is the file the finding is about, relative to its template, or
+	// the metadata file for declarations.
+	Path    string
+	Message string
+}
+
+// Lint checks a template and the templates it extends. It reports keys that
+// files, paths, computed values and when expressions reference without a
+// declaration in prompts, defaults or computed values, declarations nothing
+// references, templates that don't parse, copied files that look like
+// templates, wrong arguments of the functions reading the files of the
+// template, and settings newer than the minMoldVersion declared.
+// Findings are sorted by path and rule.
+func Lint(templatePath string) ([]Finding, error) {
+	chain, meta, err := ResolveChain(templatePath)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &linter{meta: meta, referenced: make(map[string]string)}
+	for _, path := range chain {
+		l.files = newTemplateFiles(path)
+		if err = filepath.WalkDir(path, func(p string, d fs.DirEntry, walkErr error) error {
+			return l.visit(path, p, d, walkErr)
+		}); err != nil {
+			return nil, fmt.Errorf("failed to lint template '%s': %w", path, err)
+		}
+	}
+	// Computed values use the keys of their expressions.
+	for _, key := range slices.Sorted(maps.Keys(meta.Computed)) {
+		l.check(MetadataFile, fmt.Sprintf("computed value '%s'", key), meta.Computed[key])
+	}
+	// So do the when expressions of prompts.
+	for _, prompt := range meta.Prompts {
+		if prompt.When != "" {
+			l.check(MetadataFile, fmt.Sprintf("when of prompt '%s'", prompt.Name), prompt.When)
+		}
+	}
+	// And the notices.
+	for i, text := range meta.Notices {
+		l.check(MetadataFile, fmt.Sprintf("notice %d", i+1), text)
+	}
+	// And the headers.
+	for _, pattern := range slices.Sorted(maps.Keys(meta.Headers)) {
+		l.check(MetadataFile, fmt.Sprintf("header '%s'", pattern), meta.Headers[pattern].text())
+	}
+
+	declared := make(map[string]bool)
+	for _, prompt := range meta.Prompts {
+		declared[prompt.Name] = true
+	}
+	for key := range meta.Defaults {
+		declared[key] = true
+	}
+	for key := range meta.Computed {
+		declared[key] = true
+	}
+	if meta.DeriveCases {
+		// A derived case is declared with the key it derives from, and uses
+		// it.
+		for key, path := range maps.Clone(l.referenced) {
+			if base, ok := IsDerivedCase(key, declared); ok {
+				declared[key] = true
+				if _, used := l.referenced[base]; !used {
+					l.referenced[base] = path
+				}
+			}
+		}
+	}
+	for key, path := range l.referenced {
+		if !declared[key] {
+			l.add(RuleUndeclaredKey, LevelError, path,
+				fmt.Sprintf("'%s' is not declared in prompts or defaults", key))
+		}
+	}
+	for key := range declared {
+		if _, ok := l.referenced[key]; !ok {
+			l.add(RuleUnusedInput, LevelWarning, MetadataFile,
+				fmt.Sprintf("'%s' is declared but never used", key))
+		}
+	}
+	for _, feature := range moldFeatures {
+		if !feature.used(meta) {
+			continue
+		}
+		switch {
+		case meta.MinMoldVersion == "":
+			l.add(RuleMinMoldVersion, LevelWarning, MetadataFile, fmt.Sprintf(
+				"%s needs mold %s or later, declare minMoldVersion: \"%s\"", feature.name, feature.since, feature.since))
+		case compareVersions(meta.MinMoldVersion, feature.since) < 0:
+			l.add(RuleMinMoldVersion, LevelWarning, MetadataFile, fmt.Sprintf(
+				"%s needs mold %s or later, but minMoldVersion is %s", feature.name, feature.since, meta.MinMoldVersion))
+		}
+	}
+
+	slices.SortFunc(l.findings, func(a, b Finding) int {
+		return strings.Compare(a.Path+"\x00"+a.Rule+"\x00"+a.Message, b.Path+"\x00"+b.Rule+"\x00"+b.Message)
+	})
+	return l.findings, nil
+}
+
+// linter collects the findings and referenced keys of a Lint run.
+type linter struct {
+	meta     *Metadata
+	findings []Finding
+	// files reads the files of the template being walked.
+	files *templateFiles
+	// referenced maps each referenced key to the first path using it.
+	referenced map[string]string
+}
+
+// add records a finding.
+func (l *linter) add(rule, level, path, message string) {
+	l.findings = append(l.findings, Finding{Rule: rule, Level: level, Path: path, Message: message})
+}
+
+// visit lints one entry of a template directory.
+func (l *linter) visit(templatePath, path string, d fs.DirEntry, walkErr error) error {
+	if walkErr != nil {
+		return walkErr
+	}
+	relPath, err := filepath.Rel(templatePath, path)
+	if err != nil || relPath == "." {
+		return err
+	}
+	relPath = filepath.ToSlash(relPath)
+	if relPath == TestsDir && d.IsDir() {
+		return filepath.SkipDir
+	}
+	if relPath == MetadataFile || IsHintFile(d.Name()) {
+		return nil
+	}
+	if l.meta.Ignored(relPath) {
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+	partial := strings.HasPrefix(relPath, PartialsDir+"/")
+	if !partial && relPath != PartialsDir {
+		l.check(relPath, "path", pathTokens(relPath, helperFunc))
+	}
+	if d.IsDir() {
+		if l.meta.IsRaw(relPath) {
+			// Raw directories are copied verbatim.
+			return filepath.SkipDir
+		}
+		return nil
+	}
+	// Apply skips named pipes, sockets and devices, reading them would block.
+	if irregularType(d.Type()) != "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	_, render, err := templateName(d.Name())
+	if err != nil {
+		l.add(RuleParseError, LevelError, relPath, err.Error())
+		return nil
+	}
+	if partial || render {
+		l.check(relPath, "template", string(content))
+	} else if bytes.Contains(content, []byte("{{")) && bytes.Contains(content, []byte("}}")) {
+		l.add(RuleCopiedDelimiters, LevelWarning, relPath,
+			"copied file contains template delimiters, did you mean to name it '"+d.Name()+".tmpl'?")
+	}
+	return nil
+}
+
+// check parses a template or path, reports the unknown functions it calls
+// and records the keys it references.
+func (l *linter) check(relPath, kind, content string) {
+	ids, err := Identify(relPath, content)
+	if err != nil {
+		l.add(RuleParseError, LevelError, relPath, fmt.Sprintf("%s does not parse: %v", kind, err))
+		return
+	}
+	for _, call := range ids.Unknown(helperFunc) {
+		l.add(RuleUnknownFunc, LevelError, relPath, fmt.Sprintf("%s calls unknown function %s", kind, call))
+	}
+	// The files read with a constant path are checked, others only when
+	// applying.
+	for _, call := range ids.Funcs {
+		if call.Arg == "" {
+			continue
+		}
+		if err = l.files.check(call); err != nil {
+			l.add(RuleTemplateFile, LevelError, relPath, fmt.Sprintf("%s calls %s: %v", kind, call, err))
+		}
+	}
+	for _, key := range ids.Keys() {
+		if _, ok := l.referenced[key]; !ok {
+			l.referenced[key] = relPath
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	t.Run("clean template", func(t *testing.T) {
+		templateDir := writeTemplate(t, map[string]string{
+			MetadataFile:           "prompts:\n  name: {}\ndefaults:\n  port: 8080\n",
+			"{{.name}}/main.tmpl":  "{{.port}}",
+			"README.md":            "no delimiters",
+			"cmd.tmpl.go":          "package {{.name}}",
+			"tests/case/data.yaml": "{{.ignored}}",
+		})
+
+		findings, err := Lint(templateDir)
+		if err != nil {
+			t.Fatalf("Lint failed: %v", err)
+		}
+		if len(findings) != 0 {
+			t.Errorf("Expected no findings, got %+v", findings)
+		}
+	})
+
+	t.Run("all rules", func(t *testing.T) {
+		templateDir := writeTemplate(t, map[string]string{
+			MetadataFile:            "prompts:\n  name: {}\n  unused: {}\nignore: [\"skipped/**\"]\n",
+			"main.go.tmpl":          "{{.name}} {{.missing}}",
+			"_partials/header.tmpl": "{{.author}}",
+			"broken.tmpl":           "{{.name",
+			"{{.dir}}/notes.txt":    "Hello {{.name}}",
+			"skipped/copied.txt":    "{{.skipped}}",
+			"a.tmpl.b.tmpl.go":      "{{.name}}",
+			"funcs.tmpl":            "{{snake .name}}\n{{if gt (len .name) 3}}{{printf \"%s\" (lower .name)}}{{end}}",
+		})
+
+		findings, err := Lint(templateDir)
+		if err != nil {
+			t.Fatalf("Lint failed: %v", err)
+		}
+		undeclared := " is not declared in prompts or defaults"
+		want := []Finding{
+			{Rule: RuleUndeclaredKey, Level: LevelError, Path: "_partials/header.tmpl", Message: "'author'" + undeclared},
+			{Rule: RuleParseError, Level: LevelError, Path: "a.tmpl.b.tmpl.go"},
+			{Rule: RuleParseError, Level: LevelError, Path: "broken.tmpl"},
+			{
+				Rule: RuleUnknownFunc, Level: LevelError, Path: "funcs.tmpl",
+				Message: "template calls unknown function 'lower' at 2:39",
+			},
+			{Rule: RuleUndeclaredKey, Level: LevelError, Path: "main.go.tmpl", Message: "'missing'" + undeclared},
+			{Rule: RuleUnusedInput, Level: LevelWarning, Path: MetadataFile, Message: "'unused' is declared but never used"},
+			{Rule: RuleUndeclaredKey, Level: LevelError, Path: "{{.dir}}", Message: "'dir'" + undeclared},
+			{Rule: RuleCopiedDelimiters, Level: LevelWarning, Path: "{{.dir}}/notes.txt"},
+		}
+		if len(findings) != len(want) {
+			t.Fatalf("Expected %d findings, got %+v", len(want), findings)
+		}
+		for i, w := range want {
+			got := findings[i]
+			sameMessage := w.Message == "" || got.Message == w.Message
+			if got.Rule != w.Rule || got.Level != w.Level || got.Path != w.Path || !sameMessage {
+				t.Errorf("Finding %d = %+v, want %+v", i, got, w)
+			}
+		}
+	})
+
+	t.Run("declarations of parents count", func(t *testing.T) {
+		parent := writeTemplate(t, map[string]string{MetadataFile: "defaults:\n  org: acme\n", "LICENSE.tmpl": "{{.org}}"})
+		child := writeTemplate(t, map[string]string{MetadataFile: "extends: " + parent + "\n", "README.md.tmpl": "{{.org}}"})
+
+		findings, err := Lint(child)
+		if err != nil {
+			t.Fatalf("Lint failed: %v", err)
+		}
+		if len(findings) != 0 {
+			t.Errorf("Expected no findings, got %+v", findings)
+		}
+	})
+
+	t.Run("template files", func(t *testing.T) {
+		templateDir := writeTemplate(t, map[string]string{
+			MetadataFile:            "ignore: [\"data/**\"]\n",
+			"data/licenses.yaml":    "mit: MIT\n",
+			"data/broken.json":      "{",
+			"_partials/header.tmpl": `{{index (dataFile "data/licenses.yaml") "mit"}}`,
+			"ok.tmpl":               `{{dataFile "data/licenses.yaml"}} {{$f := "data/x.yaml"}}{{dataFile $f}}`,
+			"missing.tmpl":          `{{dataFile "data/missing.yaml"}}`,
+			"broken.tmpl":           "\n{{dataFile \"data/broken.json\"}}",
+			"outside.tmpl":          `{{dataFile "../licenses.yaml"}}`,
+			"glob.tmpl":             `{{range templateGlob "scripts/*.sh"}}{{end}}{{templateGlob "[a-"}}`,
+			"exists.tmpl":           `{{templateFileExists "Dockerfile"}} {{templateFileExists "/etc/passwd"}}`,
+		})
+
+		findings, err := Lint(templateDir)
+		if err != nil {
+			t.Fatalf("Lint failed: %v", err)
+		}
+		want := []string{
+			"broken.tmpl: template calls 'dataFile' at 2:3: ",
+			"exists.tmpl: template calls 'templateFileExists' at 1:39: invalid path '/etc/passwd'",
+			"glob.tmpl: template calls 'templateGlob' at 1:47: invalid glob pattern '[a-'",
+			"missing.tmpl: template calls 'dataFile' at 1:3: could not read template file 'data/missing.yaml'",
+			"outside.tmpl: template calls 'dataFile' at 1:3: invalid path '../licenses.yaml'",
+		}
+		if len(findings) != len(want) {
+			t.Fatalf("Expected %d findings, got %+v", len(want), findings)
+		}
+		for i, w := range want {
+			got := findings[i]
+			if got.Rule != RuleTemplateFile || got.Level != LevelError || !contains(got.Path+": "+got.Message, w) {
+				t.Errorf("Finding %d = %+v, want %q", i, got, w)
+			}
+		}
+	})
+}
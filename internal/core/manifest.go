@@ -0,0 +1,221 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// ManifestFile records a file Apply generated, as it was written.
+type ManifestFile struct {
+	// Path is the slash-separated path relative to the output root.
+	Path string `json:"path" yaml:"path"`
+	// SHA256 is the hex-encoded SHA-256 hash of the content.
+	SHA256 string `json:"sha256" yaml:"sha256"`
+	// Mode is the octal permission bits, such as "0644".
+	Mode string `json:"mode" yaml:"mode"`
+	// Link is LinkHard or LinkSymlink when the file is linked to the
+	// template file, so editing one edits the other.
+	Link LinkMode `json:"link,omitempty" yaml:"link,omitempty"`
+	// Template is the path of the template that generated the file, the
+	// Path of the provenance's Template or of one of its Layers. A file of
+	// a parent template belongs to the template extending it.
+	Template string `json:"template,omitempty" yaml:"template,omitempty"`
+}
+
+// FileState is the state of a tracked file compared to the manifest.
+type FileState string
+
+const (
+	// FileUnchanged is a file whose content matches the manifest.
+	FileUnchanged FileState = "unchanged"
+	// FileModified is a file whose content differs from the manifest.
+	FileModified FileState = "modified"
+	// FileMissing is a tracked file that no longer exists.
+	FileMissing FileState = "missing"
+)
+
+// FileCheck is the result of comparing a tracked file with the manifest.
+type FileCheck struct {
+	Path  string    `json:"path"`
+	State FileState `json:"state"`
+	// Mode is the permission bits of the file when they differ from the
+	// recorded ones, such as "0755".
+	Mode string `json:"mode,omitempty"`
+	// Link is the link of the file to its template file, if any.
+	Link LinkMode `json:"link,omitempty"`
+}
+
+// HashFile returns the hex-encoded SHA-256 hash of a file's content.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return hashReader(path, f)
+}
+
+// hashReader returns the hex-encoded SHA-256 hash of the content of the
+// file at path read from r. It is the hash of every check of a generated
+// file, so Apply and CheckFiles agree on what is unchanged.
+func hashReader(path string, r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("failed to hash '%s': %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashContent returns the hex-encoded SHA-256 hash of content, like
+// HashFile.
+func hashContent(content []byte) string {
+	sum, _ := hashReader("", bytes.NewReader(content))
+	return sum
+}
+
+// CheckFiles compares the content and the permissions of the tracked files
+// under dir with the manifest, in manifest order.
+func CheckFiles(dir string, files []ManifestFile) ([]FileCheck, error) {
+	checks := make([]FileCheck, 0, len(files))
+	for _, file := range files {
+		check, err := checkFile(dir, file)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// checkFile compares a tracked file under dir with its manifest entry.
+func checkFile(dir string, file ManifestFile) (FileCheck, error) {
+	check := FileCheck{Path: file.Path, State: FileUnchanged, Link: file.Link}
+	if !filepath.IsLocal(filepath.FromSlash(file.Path)) {
+		return check, fmt.Errorf("invalid manifest path '%s'", file.Path)
+	}
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(file.Path)))
+	if errors.Is(err, fs.ErrNotExist) {
+		check.State = FileMissing
+		return check, nil
+	}
+	if err != nil {
+		return check, fmt.Errorf("failed to check '%s': %w", file.Path, err)
+	}
+	defer f.Close()
+	sum, err := hashReader(file.Path, f)
+	if err != nil {
+		return check, fmt.Errorf("failed to check '%s': %w", file.Path, err)
+	}
+	if sum != file.SHA256 {
+		check.State = FileModified
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return check, fmt.Errorf("failed to check '%s': %w", file.Path, err)
+	}
+	// Windows only keeps a read-only flag, which doesn't round-trip the
+	// recorded bits.
+	if mode := info.Mode().Perm(); runtime.GOOS != "windows" && mode != file.modeBits() {
+		check.Mode = fmt.Sprintf("%04o", mode)
+	}
+	return check, nil
+}
+
+// manifest collects the files of an Apply run.
+type manifest map[string]ManifestFile
+
+// track wraps a writer of the sink so the file is recorded when it is closed.
+func (m manifest) track(w io.WriteCloser, relPath string, mode fs.FileMode) io.WriteCloser {
+	return &hashWriter{WriteCloser: w, hash: sha256.New(), done: func(sum string) {
+		m.add(relPath, mode, sum)
+	}}
+}
+
+// rehash records the content of a file changed in place, such as by a
+// formatter.
+func (m manifest) rehash(relPath, path string) error {
+	sum, err := HashFile(path)
+	if err != nil {
+		return err
+	}
+	m.add(relPath, m[filepath.ToSlash(relPath)].modeBits(), sum)
+	return nil
+}
+
+// add records a file.
+func (m manifest) add(relPath string, mode fs.FileMode, sum string) {
+	rel := filepath.ToSlash(relPath)
+	m[rel] = ManifestFile{Path: rel, SHA256: sum, Mode: fmt.Sprintf("%04o", mode.Perm())}
+}
+
+// link records that a file is linked to its template file.
+func (m manifest) link(relPath string, mode LinkMode) {
+	rel := filepath.ToSlash(relPath)
+	file := m[rel]
+	file.Link = mode
+	m[rel] = file
+}
+
+// files returns the recorded files sorted by path.
+func (m manifest) files() []ManifestFile {
+	files := slices.Collect(maps.Values(m))
+	slices.SortFunc(files, func(a, b ManifestFile) int { return strings.Compare(a.Path, b.Path) })
+	return files
+}
+
+// producedFiles returns the recorded files sorted by path, with the
+// template of the entry that generated each one, or of the raw directory
+// holding it.
+func (a *applier) producedFiles() []ManifestFile {
+	files := a.files.files()
+	for i, file := range files {
+		for key := file.Path; key != "."; key = path.Dir(key) {
+			if e, ok := a.entries[key]; ok {
+				files[i].Template = a.sources[e.layer.source].Path
+				break
+			}
+		}
+	}
+	return files
+}
+
+// modeBits parses the recorded permission bits.
+func (f ManifestFile) modeBits() fs.FileMode {
+	var mode fs.FileMode
+	_, _ = fmt.Sscanf(f.Mode, "%o", &mode)
+	return mode
+}
+
+// hashWriter hashes what is written through it.
+type hashWriter struct {
+	io.WriteCloser
+	hash hash.Hash
+	done func(sum string)
+}
+
+func (w *hashWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	w.hash.Write(p[:n])
+	return n, err
+}
+
+func (w *hashWriter) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		return err
+	}
+	w.done(hex.EncodeToString(w.hash.Sum(nil)))
+	return nil
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckFiles(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		"a.txt":            "a",
+		"b.txt.tmpl":       "{{.b}}",
+		"nested/c.txt":     "c",
+		"nested/d.sh.tmpl": "#!/bin/sh",
+	})
+	if err := os.Chmod(filepath.Join(templateDir, "nested", "d.sh.tmpl"), 0755); err != nil {
+		t.Fatalf("Failed to chmod: %v", err)
+	}
+	outputDir := t.TempDir()
+	err := Apply(Options{
+		TemplatePath: templateDir,
+		OutputDir:    outputDir,
+		Data:         map[string]any{"b": "b"},
+		Out:          &bytes.Buffer{},
+	})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	p, err := LoadProvenance(outputDir)
+	if err != nil {
+		t.Fatalf("LoadProvenance failed: %v", err)
+	}
+	if len(p.Files) != 4 || p.Files[3].Path != "nested/d.sh" || p.Files[3].Mode != "0755" {
+		t.Fatalf("Unexpected manifest: %+v", p.Files)
+	}
+
+	if err = os.WriteFile(filepath.Join(outputDir, "b.txt"), []byte("edited"), 0644); err != nil {
+		t.Fatalf("Failed to edit: %v", err)
+	}
+	if err = os.Remove(filepath.Join(outputDir, "nested", "c.txt")); err != nil {
+		t.Fatalf("Failed to remove: %v", err)
+	}
+	checks, err := CheckFiles(outputDir, p.Files)
+	if err != nil {
+		t.Fatalf("CheckFiles failed: %v", err)
+	}
+	want := []FileCheck{
+		{Path: "a.txt", State: FileUnchanged},
+		{Path: "b.txt", State: FileModified},
+		{Path: "nested/c.txt", State: FileMissing},
+		{Path: "nested/d.sh", State: FileUnchanged},
+	}
+	if !reflect.DeepEqual(checks, want) {
+		t.Errorf("Expected %v, got %v", want, checks)
+	}
+
+	_, err = CheckFiles(outputDir, []ManifestFile{{Path: "../outside"}})
+	if err == nil || !contains(err.Error(), "invalid manifest path '../outside'") {
+		t.Errorf("Expected an invalid path error, got: %v", err)
+	}
+}
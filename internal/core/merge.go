@@ -0,0 +1,197 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// StateDir is the directory at the output root where mold keeps the
+	// state of a generated project. Like ProvenanceFile, it is never
+	// generated from a template.
+	StateDir = ".mold"
+	// BaseDir holds the generated content of the tracked text files, the
+	// base of the three-way merges of the next run.
+	BaseDir = StateDir + "/base"
+)
+
+// MergeMode says what Apply does with generated files the user changed since
+// the previous run.
+type MergeMode string
+
+const (
+	// MergeOff overwrites them.
+	MergeOff MergeMode = "off"
+	// MergeCleanOnly merges the template changes into them when they don't
+	// conflict, and keeps the user's version otherwise.
+	MergeCleanOnly MergeMode = "clean-only"
+	// MergeAlways merges the template changes into them, leaving conflict
+	// markers where they conflict.
+	MergeAlways MergeMode = "always"
+)
+
+// ParseMergeMode parses the name of a merge mode.
+func ParseMergeMode(name string) (MergeMode, error) {
+	switch mode := MergeMode(name); mode {
+	case MergeOff, MergeCleanOnly, MergeAlways:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid merge mode '%s': expected off, clean-only or always", name)
+}
+
+// merging reports whether files changed by the user are merged.
+func (a *applier) merging() bool {
+	return a.opts.Merge != "" && a.opts.Merge != MergeOff
+}
+
+// userChange is a tracked file the user changed since the previous run.
+type userChange struct {
+	// ours is the user's content, unless the file was deleted.
+	ours    []byte
+	deleted bool
+}
+
+// userChange returns how the user changed a tracked file since the previous
+// run, or nil when the file is not merged: it is unchanged, untracked, or
+// merging is off.
+func (a *applier) userChange(relPath string) (*userChange, error) {
+	if !a.merging() || a.previous == nil {
+		return nil, nil
+	}
+	tracked, ok := a.tracked[filepath.ToSlash(relPath)]
+	if !ok {
+		return nil, nil
+	}
+	ours, err := os.ReadFile(filepath.Join(a.opts.OutputDir, relPath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return a.planMerge(relPath, &userChange{deleted: true}), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read '%s': %w", relPath, err)
+	}
+	if hashContent(ours) == tracked.SHA256 {
+		return nil, nil
+	}
+	return a.planMerge(relPath, &userChange{ours: ours}), nil
+}
+
+// planMerge returns the change to merge, or reports it and returns nil in a
+// dry run.
+func (a *applier) planMerge(relPath string, change *userChange) *userChange {
+	if !a.opts.DryRun {
+		return change
+	}
+	if change.deleted {
+		fmt.Fprintf(a.out, "🔀 Would merge the deletion of: %s\n", relPath)
+	} else {
+		fmt.Fprintf(a.out, "🔀 Would merge your changes to: %s\n", relPath)
+	}
+	return nil
+}
+
+// merge combines the file just generated with the user's change, using the
+// content generated by the previous run as the base.
+func (a *applier) merge(relPath string, change *userChange) error {
+	if change == nil {
+		return nil
+	}
+	path := filepath.Join(a.opts.OutputDir, relPath)
+	theirs, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read '%s': %w", relPath, err)
+	}
+	a.theirs[filepath.ToSlash(relPath)] = theirs
+	base, err := os.ReadFile(filepath.Join(a.opts.OutputDir, filepath.FromSlash(BaseDir), relPath))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read the base of '%s': %w", relPath, err)
+	}
+	hasBase := err == nil
+
+	if change.deleted {
+		switch {
+		case hasBase && bytes.Equal(base, theirs):
+			fmt.Fprintf(a.out, "🗑️  Keeping '%s' deleted, the template didn't change it\n", relPath)
+		case a.opts.Merge == MergeAlways:
+			fmt.Fprintf(a.out, "⚠️  Restoring '%s', you deleted it but the template changed it\n", relPath)
+			return nil
+		default:
+			fmt.Fprintf(a.out, "⚠️  Keeping '%s' deleted, but the template changed it\n", relPath)
+		}
+		return os.Remove(path)
+	}
+
+	switch {
+	case !isText(change.ours) || !isText(theirs):
+		fmt.Fprintf(a.out, "⚠️  Keeping your version of '%s', binary files can't be merged\n", relPath)
+		return os.WriteFile(path, change.ours, 0)
+	case !hasBase:
+		fmt.Fprintf(a.out, "⚠️  Keeping your version of '%s', no base was recorded to merge with\n", relPath)
+		return os.WriteFile(path, change.ours, 0)
+	}
+	merged, conflicts := Merge3(string(base), string(change.ours), string(theirs))
+	switch {
+	case conflicts == 0:
+		fmt.Fprintf(a.out, "🔀 Merged your changes to: %s\n", relPath)
+	case a.opts.Merge == MergeAlways:
+		fmt.Fprintf(a.out, "❌ Conflict in '%s': %d hunks conflict with your changes, resolve the markers\n",
+			relPath, conflicts)
+	default:
+		fmt.Fprintf(a.out, "⚠️  Keeping your version of '%s', %d hunks conflict with the template changes\n",
+			relPath, conflicts)
+		merged = string(change.ours)
+	}
+	// The file exists, so its mode is kept.
+	return os.WriteFile(path, []byte(merged), 0)
+}
+
+// storeBases replaces the base copies with the content this run generated.
+// Binary files and the files of raw directories are not stored, they are
+// never merged.
+func (a *applier) storeBases() error {
+	dir := filepath.Join(a.opts.OutputDir, filepath.FromSlash(BaseDir))
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to clear '%s': %w", dir, err)
+	}
+	for _, file := range a.files.files() {
+		if a.raw[file.Path] {
+			continue
+		}
+		content, ok := a.theirs[file.Path]
+		if !ok {
+			var err error
+			if content, err = os.ReadFile(filepath.Join(a.opts.OutputDir, filepath.FromSlash(file.Path))); err != nil {
+				return fmt.Errorf("failed to read '%s': %w", file.Path, err)
+			}
+		}
+		if !isText(content) {
+			continue
+		}
+		path := filepath.Join(dir, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			return fmt.Errorf("failed to create directory for '%s': %w", path, err)
+		}
+		//nolint:gosec // generated files are meant to be shared
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return fmt.Errorf("failed to write '%s': %w", path, err)
+		}
+	}
+	return nil
+}
+
+// isText reports whether content looks like text that can be merged line by
+// line.
+func isText(content []byte) bool {
+	return utf8.Valid(content) && bytes.IndexByte(content, 0) < 0
+}
+
+// isStatePath reports whether a slash-separated output path is the state
+// directory or inside it.
+func isStatePath(relPath string) bool {
+	return relPath == StateDir || strings.HasPrefix(relPath, StateDir+"/")
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"slices"
+	"strings"
+)
+
+// Conflict markers written by Merge3. The sections hold the user's lines,
+// the lines of the previous generation and the lines of the template.
+const (
+	conflictStart = "<<<<<<< yours\n"
+	conflictBase  = "||||||| generated before\n"
+	conflictSep   = "=======\n"
+	conflictEnd   = ">>>>>>> template\n"
+)
+
+// Merge3 merges the changes from base to ours and from base to theirs, line
+// by line. Hunks changed on one side only, or the same way on both, merge
+// cleanly. Other hunks are written with conflict markers holding both
+// versions and the base, and counted in the returned number of conflicts.
+func Merge3(base, ours, theirs string) (string, int) {
+	o, a, b := splitLines(base), splitLines(ours), splitLines(theirs)
+	ma, mb := matchLines(o, a), matchLines(o, b)
+
+	var out strings.Builder
+	conflicts := 0
+	// resolve writes the hunks between two stable points.
+	resolve := func(oc, ac, bc []string) {
+		switch {
+		case slices.Equal(ac, oc):
+			writeLines(&out, bc)
+		case slices.Equal(bc, oc), slices.Equal(ac, bc):
+			writeLines(&out, ac)
+		default:
+			conflicts++
+			out.WriteString(conflictStart)
+			writeSection(&out, ac)
+			out.WriteString(conflictBase)
+			writeSection(&out, oc)
+			out.WriteString(conflictSep)
+			writeSection(&out, bc)
+			out.WriteString(conflictEnd)
+		}
+	}
+
+	i, j, k := 0, 0, 0
+	for i < len(o) || j < len(a) || k < len(b) {
+		// Lines unchanged on both sides are copied as they are.
+		n := 0
+		for i+n < len(o) && ma[i+n] == j+n && mb[i+n] == k+n {
+			n++
+		}
+		if n > 0 {
+			writeLines(&out, o[i:i+n])
+			i, j, k = i+n, j+n, k+n
+			continue
+		}
+		// Otherwise the hunk runs up to the next base line both sides kept.
+		next := i
+		for next < len(o) && (ma[next] < 0 || mb[next] < 0) {
+			next++
+		}
+		if next == len(o) {
+			resolve(o[i:], a[j:], b[k:])
+			break
+		}
+		resolve(o[i:next], a[j:ma[next]], b[k:mb[next]])
+		i, j, k = next, ma[next], mb[next]
+	}
+	return out.String(), conflicts
+}
+
+// writeLines writes lines as they are.
+func writeLines(out *strings.Builder, lines []string) {
+	for _, line := range lines {
+		out.WriteString(line)
+	}
+}
+
+// writeSection writes the lines of a conflict section, ending the last one
+// with a newline so the next marker starts a line.
+func writeSection(out *strings.Builder, lines []string) {
+	writeLines(out, lines)
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		out.WriteString("\n")
+	}
+}
+
+// matchLines returns, for each line of x, the index of the line of y it is
+// kept as in the diff from x to y, or -1 when it is removed.
+func matchLines(x, y []string) []int {
+	matches := make([]int, len(x))
+	for i := range matches {
+		matches[i] = -1
+	}
+	for _, op := range diffLines(x, y) {
+		if op.kind == ' ' {
+			matches[op.aIdx] = op.bIdx
+		}
+	}
+	return matches
+}
@@ -0,0 +1,55 @@
+package core
+
+import "testing"
+
+func TestMerge3(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+	tests := []struct {
+		name          string
+		ours          string
+		theirs        string
+		want          string
+		wantConflicts int
+	}{
+		{name: "unchanged", ours: base, theirs: base, want: base},
+		{name: "template only", ours: base, theirs: "a\nB\nc\nd\ne\n", want: "a\nB\nc\nd\ne\n"},
+		{name: "user only", ours: "a\nb\nc\nd\ne\nf\n", theirs: base, want: "a\nb\nc\nd\ne\nf\n"},
+		{
+			name:   "separate hunks",
+			ours:   "a\nb\nc\nd\nE\n",
+			theirs: "header\na\nB\nc\nd\ne\n",
+			want:   "header\na\nB\nc\nd\nE\n",
+		},
+		{name: "same change", ours: "a\nX\nc\nd\ne\n", theirs: "a\nX\nc\nd\ne\n", want: "a\nX\nc\nd\ne\n"},
+		{name: "user removed lines", ours: "a\ne\n", theirs: "a\nb\nc\nd\ne\nf\n", want: "a\ne\nf\n"},
+		{
+			name:   "conflict",
+			ours:   "a\nmine\nc\nd\ne\n",
+			theirs: "a\ntheirs\nc\nd\nE\n",
+			want: "a\n" + conflictStart + "mine\n" + conflictBase + "b\n" + conflictSep + "theirs\n" + conflictEnd +
+				"c\nd\nE\n",
+			wantConflicts: 1,
+		},
+		{
+			name:   "conflict without trailing newline",
+			ours:   "a\nb\nc\nd\nmine",
+			theirs: "a\nb\nc\nd\ntheirs",
+			want: "a\nb\nc\nd\n" + conflictStart + "mine\n" + conflictBase + "e\n" + conflictSep + "theirs\n" +
+				conflictEnd,
+			wantConflicts: 1,
+		},
+		{
+			name:          "both appended",
+			ours:          base + "mine\n",
+			theirs:        base + "theirs\n",
+			want:          base + conflictStart + "mine\n" + conflictBase + conflictSep + "theirs\n" + conflictEnd,
+			wantConflicts: 1,
+		},
+	}
+	for _, tt := range tests {
+		got, conflicts := Merge3(base, tt.ours, tt.theirs)
+		if got != tt.want || conflicts != tt.wantConflicts {
+			t.Errorf("%s: expected %d conflicts and\n%q\ngot %d and\n%q", tt.name, tt.wantConflicts, tt.want, conflicts, got)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/0m3kk/mold/internal/utils"
+)
+
+func TestApplyMerge(t *testing.T) {
+	v1 := writeTemplate(t, map[string]string{
+		"clean.txt":           "a\nb\nc\nd\ne\n",
+		"conflict.txt":        "one\ntwo\nthree\n",
+		"deleted.txt":         "x\n",
+		"deleted-changed.txt": "y\n",
+		"binary.bin":          "\x00v1",
+	})
+	v2 := writeTemplate(t, map[string]string{
+		"clean.txt.tmpl":      "{{.header}}\na\nb\nc\nd\ne\n",
+		"conflict.txt":        "one\ntheirs\nthree\n",
+		"deleted.txt":         "x\n",
+		"deleted-changed.txt": "y2\n",
+		"binary.bin":          "\x00v2",
+	})
+	data := map[string]any{"header": "header"}
+	project := filepath.Join(t.TempDir(), "project")
+	if err := Apply(Options{TemplatePath: v1, OutputDir: project, Data: data, Out: &bytes.Buffer{}}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	edits := map[string]string{
+		"clean.txt":    "a\nb\nc\nd\ne\nf\n",
+		"conflict.txt": "one\nmine\nthree\n",
+		"binary.bin":   "\x00me",
+	}
+	for name, content := range edits {
+		if err := os.WriteFile(filepath.Join(project, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to edit: %v", err)
+		}
+	}
+	for _, name := range []string{"deleted.txt", "deleted-changed.txt"} {
+		if err := os.Remove(filepath.Join(project, name)); err != nil {
+			t.Fatalf("Failed to delete: %v", err)
+		}
+	}
+
+	merged := "header\na\nb\nc\nd\ne\nf\n"
+	tests := []struct {
+		mode MergeMode
+		want map[string]string
+		logs []string
+	}{
+		{
+			mode: MergeOff,
+			want: map[string]string{
+				"clean.txt":           "header\na\nb\nc\nd\ne\n",
+				"conflict.txt":        "one\ntheirs\nthree\n",
+				"deleted.txt":         "x\n",
+				"deleted-changed.txt": "y2\n",
+				"binary.bin":          "\x00v2",
+			},
+		},
+		{
+			mode: MergeCleanOnly,
+			want: map[string]string{"clean.txt": merged, "conflict.txt": "one\nmine\nthree\n", "binary.bin": "\x00me"},
+			logs: []string{
+				"🔀 Merged your changes to: clean.txt",
+				"Keeping your version of 'conflict.txt', 1 hunks conflict with the template changes",
+				"Keeping 'deleted.txt' deleted, the template didn't change it",
+				"Keeping 'deleted-changed.txt' deleted, but the template changed it",
+				"Keeping your version of 'binary.bin', binary files can't be merged",
+			},
+		},
+		{
+			mode: MergeAlways,
+			want: map[string]string{
+				"clean.txt": merged,
+				"conflict.txt": "one\n" + conflictStart + "mine\n" + conflictBase + "two\n" + conflictSep + "theirs\n" +
+					conflictEnd + "three\n",
+				"deleted-changed.txt": "y2\n",
+				"binary.bin":          "\x00me",
+			},
+			logs: []string{
+				"❌ Conflict in 'conflict.txt': 1 hunks conflict with your changes",
+				"Keeping 'deleted.txt' deleted, the template didn't change it",
+				"Restoring 'deleted-changed.txt', you deleted it but the template changed it",
+			},
+		},
+	}
+	for _, tt := range tests {
+		outputDir := filepath.Join(t.TempDir(), "project")
+		if _, err := utils.CopyDir(project, outputDir); err != nil {
+			t.Fatalf("Failed to copy the project: %v", err)
+		}
+		var out bytes.Buffer
+		err := Apply(Options{TemplatePath: v2, OutputDir: outputDir, Data: data, Merge: tt.mode, Out: &out})
+		if err != nil {
+			t.Fatalf("%s: Apply failed: %v", tt.mode, err)
+		}
+		files := readFiles(t, outputDir)
+		for name := range files {
+			if name == ProvenanceFile || isStatePath(name) {
+				delete(files, name)
+			}
+		}
+		if !reflect.DeepEqual(files, tt.want) {
+			t.Errorf("%s: unexpected files:\ngot:  %q\nwant: %q", tt.mode, files, tt.want)
+		}
+		for _, line := range tt.logs {
+			if !contains(out.String(), line) {
+				t.Errorf("%s: expected %q in output:\n%s", tt.mode, line, out.String())
+			}
+		}
+
+		// The manifest and the bases hold the generated content, so the next
+		// run merges from it.
+		p, err := LoadProvenance(outputDir)
+		if err != nil {
+			t.Fatalf("%s: LoadProvenance failed: %v", tt.mode, err)
+		}
+		checks, err := CheckFiles(outputDir, p.Files)
+		if err != nil || checks[1].Path != "clean.txt" || (checks[1].State == FileUnchanged) != (tt.mode == MergeOff) {
+			t.Errorf("%s: unexpected file states %v: %v", tt.mode, checks, err)
+		}
+		base, err := os.ReadFile(filepath.Join(outputDir, filepath.FromSlash(BaseDir), "clean.txt"))
+		if err != nil || string(base) != "header\na\nb\nc\nd\ne\n" {
+			t.Errorf("%s: unexpected base %q: %v", tt.mode, base, err)
+		}
+		if _, err = os.Stat(filepath.Join(outputDir, filepath.FromSlash(BaseDir), "binary.bin")); !os.IsNotExist(err) {
+			t.Errorf("%s: expected no base for a binary file, got: %v", tt.mode, err)
+		}
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		var out bytes.Buffer
+		err := Apply(Options{TemplatePath: v2, OutputDir: project, Data: data, Merge: MergeAlways, DryRun: true, Out: &out})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		for _, line := range []string{"Would merge your changes to: clean.txt", "Would merge the deletion of: deleted.txt"} {
+			if !contains(out.String(), line) {
+				t.Errorf("Expected %q in output:\n%s", line, out.String())
+			}
+		}
+		content, _ := os.ReadFile(filepath.Join(project, "clean.txt"))
+		if string(content) != edits["clean.txt"] {
+			t.Errorf("Expected a dry run to leave the file alone, got %q", content)
+		}
+	})
+
+	t.Run("template state is reserved", func(t *testing.T) {
+		withState := writeTemplate(t, map[string]string{"a.txt": "a", BaseDir + "/a.txt": "stale"})
+		outputDir := t.TempDir()
+		err := Apply(Options{TemplatePath: withState, OutputDir: outputDir, NoProvenance: true, Out: &bytes.Buffer{}})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		if files := readFiles(t, outputDir); len(files) != 1 {
+			t.Errorf("Expected the state directory of the template to be skipped, got %v", files)
+		}
+	})
+
+	if _, err := ParseMergeMode("sometimes"); err == nil || !contains(err.Error(), "invalid merge mode 'sometimes'") {
+		t.Errorf("Expected an invalid merge mode error, got: %v", err)
+	}
+}
@@ -0,0 +1,330 @@
+package core
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/0m3kk/mold/internal/version"
+)
+
+// MetadataFile is the name of the optional file at the root of a template
+// directory that describes how the template should be applied.
+const MetadataFile = "template.yaml"
+
+// Metadata holds the settings declared in a template's template.yaml file.
+type Metadata struct {
+	// Name identifies the template, such as "go/service". It is kept in sync
+	// when the template is copied or renamed in the templates directory.
+	Name string `yaml:"name"`
+	// Description tells users what the template generates.
+	Description string `yaml:"description"`
+	// Version is the version of the template, recorded in the provenance of
+	// generated projects.
+	Version string `yaml:"version"`
+	// Extends names the parent template, applied before this one. A relative
+	// path is resolved against the directory holding the template, so a bare
+	// name refers to a sibling template.
+	Extends string `yaml:"extends"`
+	// Formatters maps file globs, relative to the output root, to the command
+	// run on each matching generated file.
+	Formatters map[string][]string `yaml:"formatters"`
+	// Defaults holds data values used when the data doesn't provide them.
+	Defaults map[string]any `yaml:"defaults"`
+	// Prompts declares the inputs the template expects, in order.
+	Prompts Prompts `yaml:"prompts"`
+	// Ignore lists globs, relative to the template root, of template files
+	// and directories that are never generated.
+	Ignore []string `yaml:"ignore"`
+	// Raw lists globs, relative to the template root, of directories copied
+	// verbatim as a whole, such as static assets. Nothing inside them is
+	// rendered, ignored or formatted, and the paths inside them keep any
+	// placeholders.
+	Raw []string `yaml:"raw"`
+	// Acronyms lists the acronyms the case helpers keep as units, on top of
+	// DefaultAcronyms, spelled as camel and title case write them, such as
+	// "K8s" or "OAuth2".
+	Acronyms []string `yaml:"acronyms"`
+	// DeriveCases adds the snake, usnake, kebab, camel and lcamel variants of
+	// every top-level string of the data under suffixed keys, such as
+	// "service_name_camel", before rendering.
+	DeriveCases bool `yaml:"deriveCases"`
+	// Computed maps data keys to template expressions computing their value
+	// from the rest of the data, such as "{{snake .service}}_db". They can
+	// use each other, and a value given by the data wins.
+	Computed map[string]string `yaml:"computed"`
+	// MinMoldVersion is the oldest mold version the template works with,
+	// such as "0.9.0". Loading the template with an older mold fails.
+	MinMoldVersion string `yaml:"minMoldVersion"`
+	// Notices are template strings rendered with the data of a successful
+	// run and shown to the user after it, such as "run make bootstrap next".
+	Notices []string `yaml:"notices"`
+	// Deprecated, when set, marks the template as deprecated with a message
+	// shown when it is applied, such as "use go-service-v2 instead".
+	Deprecated string `yaml:"deprecated"`
+	// Headers maps file globs, relative to the output root, to the header
+	// prepended to the matching rendered files, such as a license notice.
+	Headers map[string]Header `yaml:"header"`
+	// HeaderCopied lists globs, relative to the output root, of the copied
+	// files that get the header matching them too.
+	HeaderCopied []string `yaml:"headerCopied"`
+	// Requires names the template a component needs the project to be
+	// generated from, checked against its provenance when the component is
+	// added with Add.
+	Requires Requirement `yaml:"requires"`
+}
+
+// Requirement is the template a component template is added on top of.
+type Requirement struct {
+	// Template is the name of the template, as in its template.yaml.
+	Template string `yaml:"template"`
+	// Version is a constraint on its version, such as "^1.2". Empty accepts
+	// any version.
+	Version string `yaml:"version"`
+}
+
+// Prompt declares one input of a template.
+type Prompt struct {
+	// Name is the data key the input is stored under.
+	Name string `yaml:"-"`
+	// Description tells the user what the input is for.
+	Description string `yaml:"description"`
+	// Type is the type the value must have: string, int, float, bool,
+	// confirm, list, map, enum, select or multiselect. Values are checked
+	// and coerced before rendering. An empty type accepts any value.
+	Type string `yaml:"type"`
+	// Choices lists the allowed values of an enum, select or multiselect
+	// prompt. A choice is a value, or a mapping with a value and a label.
+	Choices []any `yaml:"choices"`
+	// Secret masks the value in every message Mold prints.
+	Secret bool `yaml:"secret"`
+	// Pattern is a Go regular expression string values must match.
+	Pattern string `yaml:"pattern"`
+	// MinLength and MaxLength bound the number of characters of string
+	// values.
+	MinLength *int `yaml:"minLength"`
+	MaxLength *int `yaml:"maxLength"`
+	// Min and Max bound numeric values.
+	Min *float64 `yaml:"min"`
+	Max *float64 `yaml:"max"`
+	// Message is shown instead of the rule when a value breaks one, such as
+	// "use lowercase letters and dashes".
+	Message string `yaml:"message"`
+	// When is a template expression, such as '{{eq .database "postgres"}}',
+	// evaluated against the answers of the prompts before it and the data.
+	// An empty, false or missing result skips the prompt, leaving its key
+	// to its default or absent.
+	When string `yaml:"when"`
+}
+
+// Prompts is the ordered list of prompts declared in template.yaml as a
+// mapping from name to prompt.
+type Prompts []Prompt
+
+// UnmarshalYAML decodes the prompts mapping, keeping the declaration order.
+func (p *Prompts) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: prompts must be a mapping from name to prompt", node.Line)
+	}
+	prompts := make(Prompts, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		prompt := Prompt{Name: node.Content[i].Value}
+		if err := node.Content[i+1].Decode(&prompt); err != nil {
+			return err
+		}
+		prompts = append(prompts, prompt)
+	}
+	*p = prompts
+	return nil
+}
+
+// Ignored reports whether the slash-separated path, relative to the template
+// root, matches one of the ignore globs.
+func (m *Metadata) Ignored(relPath string) bool {
+	for _, pattern := range m.Ignore {
+		if MatchGlob(pattern, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRaw reports whether the slash-separated path, relative to the template
+// root, matches one of the raw globs.
+func (m *Metadata) IsRaw(relPath string) bool {
+	for _, pattern := range m.Raw {
+		if MatchGlob(pattern, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// merge returns the metadata of a template extending m with child. Values
+// of the child win: its formatters and prompts replace those with the same
+// glob or name, its defaults are merged over the parent's, its computed
+// values replace those with the same key, and the ignore,
+// raw and acronyms lists are combined. Cases are derived when any of them
+// asks, and the highest minimum mold version applies. The notices of the
+// parent come first, and only the child's deprecation applies. Headers are
+// merged like formatters. The requirement of the child replaces the parent's.
+func (m *Metadata) merge(child *Metadata) *Metadata {
+	merged := &Metadata{
+		Name:           child.Name,
+		Description:    child.Description,
+		Version:        child.Version,
+		Extends:        child.Extends,
+		Formatters:     make(map[string][]string),
+		Defaults:       make(map[string]any),
+		Computed:       make(map[string]string),
+		Ignore:         slices.Concat(m.Ignore, child.Ignore),
+		Raw:            slices.Concat(m.Raw, child.Raw),
+		Acronyms:       slices.Concat(m.Acronyms, child.Acronyms),
+		DeriveCases:    m.DeriveCases || child.DeriveCases,
+		MinMoldVersion: maxVersion(m.MinMoldVersion, child.MinMoldVersion),
+		Notices:        slices.Concat(m.Notices, child.Notices),
+		Deprecated:     child.Deprecated,
+		Headers:        make(map[string]Header),
+		HeaderCopied:   slices.Concat(m.HeaderCopied, child.HeaderCopied),
+		Requires:       cmp.Or(child.Requires, m.Requires),
+	}
+	maps.Copy(merged.Headers, m.Headers)
+	maps.Copy(merged.Headers, child.Headers)
+	maps.Copy(merged.Formatters, m.Formatters)
+	maps.Copy(merged.Formatters, child.Formatters)
+	maps.Copy(merged.Computed, m.Computed)
+	maps.Copy(merged.Computed, child.Computed)
+	MergeData(merged.Defaults, m.Defaults)
+	MergeData(merged.Defaults, child.Defaults)
+
+	merged.Prompts = slices.Clone(m.Prompts)
+	for _, prompt := range child.Prompts {
+		i := slices.IndexFunc(merged.Prompts, func(p Prompt) bool { return p.Name == prompt.Name })
+		if i >= 0 {
+			merged.Prompts[i] = prompt
+		} else {
+			merged.Prompts = append(merged.Prompts, prompt)
+		}
+	}
+	return merged
+}
+
+// LoadMetadata reads the template.yaml file at the root of the template
+// directory. A template without the file gets empty metadata. A template
+// requiring a newer mold than the running one fails to load.
+func LoadMetadata(templatePath string) (*Metadata, error) {
+	path := filepath.Join(templatePath, MetadataFile)
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Metadata{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template metadata '%s': %w", path, err)
+	}
+
+	meta := &Metadata{}
+	if err = yaml.Unmarshal(content, meta); err != nil {
+		return nil, fmt.Errorf("failed to parse template metadata '%s': %w", path, err)
+	}
+	if meta.MinMoldVersion != "" {
+		if _, err = ParseSemver(meta.MinMoldVersion); err != nil {
+			return nil, fmt.Errorf("invalid minMoldVersion in '%s': %w", path, err)
+		}
+		if _, err = checkMoldVersion(meta.MinMoldVersion, version.String()); err != nil {
+			return nil, fmt.Errorf("template '%s' %w", templatePath, err)
+		}
+	}
+	for pattern, command := range meta.Formatters {
+		if len(command) == 0 {
+			return nil, fmt.Errorf("formatter for '%s' in '%s' has an empty command", pattern, path)
+		}
+	}
+	for pattern, header := range meta.Headers {
+		if err = header.check(); err != nil {
+			return nil, fmt.Errorf("invalid header '%s' in '%s': %w", pattern, path, err)
+		}
+	}
+	for i, prompt := range meta.Prompts {
+		if err = prompt.check(); err == nil {
+			err = prompt.checkWhen(meta.Prompts[i:])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt '%s' in '%s': %w", prompt.Name, path, err)
+		}
+		// A default must be one of the choices.
+		if value, ok := LookupValue(meta.Defaults, prompt.Name); ok && prompt.hasChoices() {
+			if _, err = prompt.coerce(value); err != nil {
+				return nil, fmt.Errorf("invalid default of prompt '%s' in '%s': %w", prompt.Name, path, err)
+			}
+		}
+	}
+	for _, acronym := range meta.Acronyms {
+		if err = checkAcronym(acronym); err != nil {
+			return nil, fmt.Errorf("%w in '%s'", err, path)
+		}
+	}
+	if err = meta.Requires.check(); err != nil {
+		return nil, fmt.Errorf("invalid requires in '%s': %w", path, err)
+	}
+	return meta, nil
+}
+
+// setMetadataName replaces the value of the top-level name field in the
+// template.yaml file of the template, keeping the rest of the file as
+// written. A template without the file or the field is left unchanged.
+func setMetadataName(templatePath, name string) error {
+	path := filepath.Join(templatePath, MetadataFile)
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read template metadata '%s': %w", path, err)
+	}
+	var doc yaml.Node
+	if err = yaml.Unmarshal(content, &doc); err != nil {
+		return fmt.Errorf("failed to parse template metadata '%s': %w", path, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "name" {
+			continue
+		}
+		value := root.Content[i+1]
+		if value.Kind != yaml.ScalarNode || value.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			return fmt.Errorf("cannot rename the template in '%s': line %d: name must be a single-line string",
+				path, value.Line)
+		}
+		// Quote the name when YAML needs it.
+		quoted, err := yaml.Marshal(name)
+		if err != nil {
+			return fmt.Errorf("failed to encode template name '%s': %w", name, err)
+		}
+		lines := strings.SplitAfter(string(content), "\n")
+		line := lines[value.Line-1]
+		rest := strings.TrimRight(line, "\r\n")
+		newLine := rest[:value.Column-1] + strings.TrimSpace(string(quoted))
+		if value.LineComment != "" {
+			newLine += " " + value.LineComment
+		}
+		lines[value.Line-1] = newLine + line[len(rest):]
+		// The file exists, so its permissions are kept.
+		//nolint:gosec // template files are meant to be shared
+		if err = os.WriteFile(path, []byte(strings.Join(lines, "")), 0644); err != nil {
+			return fmt.Errorf("failed to write template metadata '%s': %w", path, err)
+		}
+		return nil
+	}
+	return nil
+}
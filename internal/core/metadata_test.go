@@ -0,0 +1,182 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadMetadata(t *testing.T) {
+	t.Run("missing metadata file", func(t *testing.T) {
+		meta, err := LoadMetadata(t.TempDir())
+		if err != nil {
+			t.Fatalf("LoadMetadata failed: %v", err)
+		}
+		if len(meta.Formatters) != 0 {
+			t.Errorf("Expected no formatters, got %v", meta.Formatters)
+		}
+	})
+
+	t.Run("formatters section", func(t *testing.T) {
+		templateDir := t.TempDir()
+		content := `
+formatters:
+  "**/*.go": ["gofmt", "-w"]
+  "**/*.tf": ["terraform", "fmt"]
+`
+		err := os.WriteFile(filepath.Join(templateDir, MetadataFile), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("Failed to write metadata file: %v", err)
+		}
+
+		meta, err := LoadMetadata(templateDir)
+		if err != nil {
+			t.Fatalf("LoadMetadata failed: %v", err)
+		}
+		if got := meta.Formatters["**/*.tf"]; len(got) != 2 || got[0] != "terraform" {
+			t.Errorf("Unexpected terraform formatter: %v", got)
+		}
+	})
+
+	t.Run("empty formatter command", func(t *testing.T) {
+		templateDir := t.TempDir()
+		err := os.WriteFile(filepath.Join(templateDir, MetadataFile), []byte(`formatters: {"*.go": []}`), 0644)
+		if err != nil {
+			t.Fatalf("Failed to write metadata file: %v", err)
+		}
+
+		_, err = LoadMetadata(templateDir)
+		if err == nil || !contains(err.Error(), "empty command") {
+			t.Errorf("Expected empty command error, got: %v", err)
+		}
+	})
+
+	t.Run("prompts keep declaration order", func(t *testing.T) {
+		templateDir := t.TempDir()
+		content := "prompts:\n  zeta: {description: last letter}\n  alpha: {}\n"
+		err := os.WriteFile(filepath.Join(templateDir, MetadataFile), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("Failed to write metadata file: %v", err)
+		}
+
+		meta, err := LoadMetadata(templateDir)
+		if err != nil {
+			t.Fatalf("LoadMetadata failed: %v", err)
+		}
+		if len(meta.Prompts) != 2 || meta.Prompts[0].Name != "zeta" || meta.Prompts[1].Name != "alpha" {
+			t.Errorf("Unexpected prompts: %+v", meta.Prompts)
+		}
+		if meta.Prompts[0].Description != "last letter" {
+			t.Errorf("Unexpected description: %q", meta.Prompts[0].Description)
+		}
+	})
+
+	t.Run("prompts must be a mapping", func(t *testing.T) {
+		templateDir := t.TempDir()
+		err := os.WriteFile(filepath.Join(templateDir, MetadataFile), []byte("prompts: [name]"), 0644)
+		if err != nil {
+			t.Fatalf("Failed to write metadata file: %v", err)
+		}
+
+		_, err = LoadMetadata(templateDir)
+		if err == nil || !contains(err.Error(), "prompts must be a mapping") {
+			t.Errorf("Expected prompts error, got: %v", err)
+		}
+	})
+
+	t.Run("invalid prompt", func(t *testing.T) {
+		for _, tt := range []struct{ content, want string }{
+			{"prompts:\n  port: {type: integer}\n", "invalid prompt 'port'"},
+			{"prompts:\n  license: {type: enum}\n", "enum prompts need choices"},
+			{"prompts:\n  name: {pattern: \"[a-\"}\n", "invalid prompt 'name'"},
+			{"prompts:\n  port: {type: int, pattern: x}\n", "pattern, minLength and maxLength need a string prompt"},
+			{"prompts:\n  name: {type: string, max: 3}\n", "min and max need an int or float prompt"},
+			{"prompts:\n  name: {minLength: 5, maxLength: 2}\n", "minLength 5 is above maxLength 2"},
+			{"prompts:\n  port: {type: int, min: 10, max: 1}\n", "min 10 is above max 1"},
+			{"prompts:\n  db: {type: select}\n", "select prompts need choices"},
+			{"prompts:\n  db: {type: select, choices: [{label: Postgres}]}\n", "choice 1 has no value"},
+			{
+				"prompts:\n  db: {type: select, choices: [pg, mysql]}\ndefaults: {db: sqlite}\n",
+				"invalid default of prompt 'db'",
+			},
+			{"prompts:\n  dbs: {type: multiselect, choices: [pg]}\ndefaults: {dbs: [pg, mysql]}\n", `"mysql" is not`},
+			{"prompts:\n  a: {when: \"{{.b}}\"}\n  b: {}\n", "when uses 'b', which is asked later"},
+			{"prompts:\n  a: {when: \"{{not .a}}\"}\n", "when uses 'a', the prompt itself"},
+			{"prompts:\n  a: {when: \"{{.b\"}\n", "invalid when"},
+		} {
+			templateDir := t.TempDir()
+			err := os.WriteFile(filepath.Join(templateDir, MetadataFile), []byte(tt.content), 0644)
+			if err != nil {
+				t.Fatalf("Failed to write metadata file: %v", err)
+			}
+
+			_, err = LoadMetadata(templateDir)
+			if err == nil || !contains(err.Error(), tt.want) {
+				t.Errorf("Expected error %q, got: %v", tt.want, err)
+			}
+		}
+	})
+
+	t.Run("invalid acronym", func(t *testing.T) {
+		templateDir := t.TempDir()
+		err := os.WriteFile(filepath.Join(templateDir, MetadataFile), []byte(`acronyms: [K8s, "O-Auth"]`), 0644)
+		if err != nil {
+			t.Fatalf("Failed to write metadata file: %v", err)
+		}
+
+		_, err = LoadMetadata(templateDir)
+		if err == nil || !contains(err.Error(), "invalid acronym 'O-Auth'") {
+			t.Errorf("Expected acronym error, got: %v", err)
+		}
+	})
+
+	t.Run("invalid YAML", func(t *testing.T) {
+		templateDir := t.TempDir()
+		err := os.WriteFile(filepath.Join(templateDir, MetadataFile), []byte("formatters: [unclosed"), 0644)
+		if err != nil {
+			t.Fatalf("Failed to write metadata file: %v", err)
+		}
+
+		_, err = LoadMetadata(templateDir)
+		if err == nil || !contains(err.Error(), "failed to parse template metadata") {
+			t.Errorf("Expected parse error, got: %v", err)
+		}
+	})
+}
+
+func TestSetMetadataName(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{name: "plain", content: "name: old\nextends: base\n", want: "name: go/new\nextends: base\n"},
+		{name: "quoted with comment", content: "name: \"old\"  # name\r\n", want: "name: go/new # name\r\n"},
+		{name: "nested key untouched", content: "defaults:\n  name: app\n", want: "defaults:\n  name: app\n"},
+		{name: "no name", content: "extends: base", want: "extends: base"},
+		{name: "block scalar", content: "name: |\n  old\n", wantErr: "single-line string"},
+	}
+	for _, tt := range tests {
+		templateDir := writeTemplate(t, map[string]string{MetadataFile: tt.content})
+		err := setMetadataName(templateDir, "go/new")
+		if tt.wantErr != "" {
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error containing %q, got: %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: setMetadataName failed: %v", tt.name, err)
+			continue
+		}
+		content, _ := os.ReadFile(filepath.Join(templateDir, MetadataFile))
+		if string(content) != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, content)
+		}
+	}
+
+	if err := setMetadataName(t.TempDir(), "x"); err != nil {
+		t.Errorf("Expected a template without metadata to be left alone, got: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// moldFeature is a template.yaml setting older mold versions ignore or
+// reject, with the version that introduced it.
+type moldFeature struct {
+	name  string
+	since string
+	used  func(meta *Metadata) bool
+}
+
+// moldFeatures lists the settings Lint expects minMoldVersion to cover.
+//
+//nolint:gochecknoglobals // table of the versions settings were introduced in
+var moldFeatures = []moldFeature{
+	{name: "raw", since: "0.8.0", used: func(m *Metadata) bool { return len(m.Raw) > 0 }},
+	{name: "acronyms", since: "0.8.0", used: func(m *Metadata) bool { return len(m.Acronyms) > 0 }},
+	{name: "deriveCases", since: "0.8.0", used: func(m *Metadata) bool { return m.DeriveCases }},
+	{name: "computed", since: "0.9.0", used: func(m *Metadata) bool { return len(m.Computed) > 0 }},
+	{name: "prompts with when", since: "0.9.0", used: func(m *Metadata) bool {
+		for _, prompt := range m.Prompts {
+			if prompt.When != "" {
+				return true
+			}
+		}
+		return false
+	}},
+}
+
+// checkMoldVersion checks that the running mold version is at least the
+// required one. A pre-release or pseudo-version counts as its release. It
+// returns false when the running version can't be compared, such as for a
+// development build.
+func checkMoldVersion(required, running string) (bool, error) {
+	minimum, err := ParseSemver(required)
+	if err != nil {
+		return false, err
+	}
+	current, err := ParseSemver(running)
+	if err != nil || current.Major == 0 && current.Minor == 0 && current.Patch == 0 {
+		// v0.0.0-... is the pseudo-version of a build without a release.
+		return false, nil
+	}
+	current.Pre = nil
+	if current.Compare(minimum) < 0 {
+		return true, fmt.Errorf("requires mold %s or later, but this is mold %s: upgrade mold to use it",
+			strings.TrimPrefix(required, "v"), running)
+	}
+	return true, nil
+}
+
+// maxVersion returns the highest of two minimum versions, either possibly
+// empty.
+func maxVersion(a, b string) string {
+	if a == "" || b != "" && compareVersions(a, b) < 0 {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/0m3kk/mold/internal/version"
+)
+
+func TestCheckMoldVersion(t *testing.T) {
+	tests := []struct {
+		running string
+		checked bool
+		err     bool
+	}{
+		{running: "v0.4.2", checked: true, err: true},
+		{running: "v0.5.0", checked: true},
+		{running: "0.5.0", checked: true},
+		{running: "v0.6.1", checked: true},
+		{running: "v1.0.0", checked: true},
+		{running: "v0.5.0-rc.1", checked: true},
+		{running: "v0.4.9-0.20240501120000-abcdef123456", checked: true, err: true},
+		{running: "v0.5.0+dirty", checked: true},
+		{running: "dev"},
+		{running: "v0.0.0-20240501120000-abcdef123456"},
+	}
+	for _, tt := range tests {
+		checked, err := checkMoldVersion("0.5.0", tt.running)
+		if checked != tt.checked || (err != nil) != tt.err {
+			t.Errorf("checkMoldVersion(0.5.0, %q) = %v, %v; want %v, error %v", tt.running, checked, err,
+				tt.checked, tt.err)
+		}
+	}
+}
+
+func TestLoadMetadataMinMoldVersion(t *testing.T) {
+	original := version.Version
+	t.Cleanup(func() { version.Version = original })
+	templateDir := writeTemplate(t, map[string]string{MetadataFile: "minMoldVersion: \"0.5.0\"\n"})
+
+	version.Version = "v0.4.2"
+	_, err := LoadMetadata(templateDir)
+	if err == nil || !contains(err.Error(), "requires mold 0.5.0 or later, but this is mold v0.4.2: upgrade mold") {
+		t.Errorf("Expected an older mold to fail with both versions, got: %v", err)
+	}
+	for _, running := range []string{"v0.5.0", "v0.7.0", "dev"} {
+		version.Version = running
+		meta, err := LoadMetadata(templateDir)
+		if err != nil || meta.MinMoldVersion != "0.5.0" {
+			t.Errorf("Expected mold %s to load the template, got %+v, %v", running, meta, err)
+		}
+	}
+
+	var out bytes.Buffer
+	err = Apply(Options{TemplatePath: templateDir, OutputDir: t.TempDir(), Out: &out})
+	if err != nil || !contains(out.String(), "⚠️  Template '"+templateDir+"' needs mold 0.5.0 or later") {
+		t.Errorf("Expected a dev build to warn and proceed, got %v:\n%s", err, out.String())
+	}
+
+	invalid := writeTemplate(t, map[string]string{MetadataFile: "minMoldVersion: latest\n"})
+	if _, err = LoadMetadata(invalid); err == nil || !contains(err.Error(), "invalid minMoldVersion") {
+		t.Errorf("Expected an invalid minMoldVersion to fail, got: %v", err)
+	}
+
+	// The highest minimum of a chain applies.
+	version.Version = "v0.5.5"
+	parent := writeTemplate(t, map[string]string{MetadataFile: "minMoldVersion: 0.5.0\n"})
+	child := writeTemplate(t, map[string]string{MetadataFile: "extends: " + parent + "\nminMoldVersion: 0.4.0\n"})
+	if _, meta, err := ResolveChain(child); err != nil || meta.MinMoldVersion != "0.5.0" {
+		t.Errorf("Expected the minimum of the parent, got %+v, %v", meta, err)
+	}
+}
+
+func TestLintMinMoldVersion(t *testing.T) {
+	tests := []struct {
+		name, metadata, want string
+	}{
+		{name: "plain template", metadata: "prompts:\n  name: {}\n"},
+		{
+			name:     "undeclared",
+			metadata: "computed:\n  name: '{{\"x\"}}'\n",
+			want:     "computed needs mold 0.9.0 or later, declare minMoldVersion: \"0.9.0\"",
+		},
+		{
+			name:     "too low",
+			metadata: "minMoldVersion: 0.8.0\ncomputed:\n  name: '{{\"x\"}}'\n",
+			want:     "computed needs mold 0.9.0 or later, but minMoldVersion is 0.8.0",
+		},
+		{name: "covered", metadata: "minMoldVersion: 0.9.1\ncomputed:\n  name: '{{\"x\"}}'\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templateDir := writeTemplate(t, map[string]string{MetadataFile: tt.metadata, "main.tmpl": "{{.name}}"})
+			findings, err := Lint(templateDir)
+			if err != nil {
+				t.Fatalf("Lint failed: %v", err)
+			}
+			var got []string
+			for _, finding := range findings {
+				if finding.Rule == RuleMinMoldVersion {
+					got = append(got, finding.Message)
+				}
+			}
+			if tt.want == "" && len(got) != 0 || tt.want != "" && (len(got) != 1 || got[0] != tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
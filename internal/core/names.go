@@ -0,0 +1,137 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NameRule says which file and directory names Apply generates, once the
+// placeholders of their paths are replaced.
+type NameRule string
+
+const (
+	// NameWindowsSafe accepts the names valid on Windows as well as on
+	// POSIX systems: no control characters, none of <>:"\|?*, no leading
+	// '-', no trailing space or dot, and none of the names Windows reserves,
+	// such as CON or nul.txt. Names are normalized to the NFC form of
+	// Unicode, so a name typed in another form is the same name.
+	NameWindowsSafe NameRule = "windows-safe"
+	// NamePOSIX only accepts the portable filename characters of POSIX:
+	// ASCII letters, digits, '.', '_' and '-', without a leading '-'.
+	NamePOSIX NameRule = "posix"
+	// NamePermissive accepts any name without control characters, as is.
+	NamePermissive NameRule = "permissive"
+)
+
+// windowsReservedNames are the names Windows reserves for devices, whatever
+// their case and extension.
+//
+//nolint:gochecknoglobals // list of the reserved names
+var windowsReservedNames = []string{
+	"CON", "PRN", "AUX", "NUL",
+	"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+	"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9",
+}
+
+// ParseNameRule parses the name of a name rule.
+func ParseNameRule(name string) (NameRule, error) {
+	switch rule := NameRule(name); rule {
+	case NameWindowsSafe, NamePOSIX, NamePermissive:
+		return rule, nil
+	}
+	return "", fmt.Errorf("invalid name rule '%s': expected posix, windows-safe or permissive", name)
+}
+
+// checkNames checks the names of the destination path rel, rendered from
+// the template path source, against the name rule, and returns the path to
+// generate. With SanitizeNames, what the rule rejects in a name is replaced
+// by '_' instead of failing the run. A path leaving the output directory
+// always fails, since no name can be fixed into one that stays there.
+func (a *applier) checkNames(source, rel string) (string, error) {
+	if rel == "." {
+		return rel, nil
+	}
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("invalid path '%s' rendered from '%s': it is outside the output directory",
+			filepath.ToSlash(rel), source)
+	}
+	rule := cmp.Or(a.opts.NameRule, NameWindowsSafe)
+	names := strings.Split(filepath.ToSlash(filepath.Clean(rel)), "/")
+	for i, name := range names {
+		sanitized, problem := rule.check(name)
+		if problem == "" {
+			names[i] = sanitized
+			continue
+		}
+		if !a.opts.SanitizeNames {
+			return "", fmt.Errorf("invalid name '%s' rendered from '%s': %s with the %s name rule",
+				name, source, problem, rule)
+		}
+		fmt.Fprintf(a.out, "🧼 Sanitizing name '%s' rendered from '%s' to '%s'\n", name, source, sanitized)
+		names[i] = sanitized
+	}
+	return filepath.FromSlash(strings.Join(names, "/")), nil
+}
+
+// check returns the name normalized under the rule, with what the rule
+// rejects replaced by '_', and why it rejects the name, empty when it
+// doesn't.
+func (rule NameRule) check(name string) (string, string) {
+	if rule != NamePermissive {
+		name = norm.NFC.String(name)
+	}
+	var b strings.Builder
+	var problem string
+	for i, r := range name {
+		if reason := rule.reject(r, i == 0); reason != "" {
+			problem = cmp.Or(problem, reason)
+			b.WriteByte('_')
+			continue
+		}
+		b.WriteRune(r)
+	}
+	sanitized := b.String()
+	if rule != NameWindowsSafe {
+		return sanitized, problem
+	}
+	if trimmed := strings.TrimRight(sanitized, " ."); trimmed != sanitized {
+		problem = cmp.Or(problem, "it ends with a space or a dot")
+		sanitized = trimmed + strings.Repeat("_", len(sanitized)-len(trimmed))
+	}
+	stem, _, _ := strings.Cut(sanitized, ".")
+	if slices.Contains(windowsReservedNames, strings.ToUpper(strings.TrimRight(stem, " "))) {
+		problem = cmp.Or(problem, fmt.Sprintf("'%s' is a name Windows reserves", stem))
+		sanitized = stem + "_" + sanitized[len(stem):]
+	}
+	return sanitized, problem
+}
+
+// reject returns why the rule rejects the character r of a name, empty when
+// it doesn't. first tells whether r starts the name.
+func (rule NameRule) reject(r rune, first bool) string {
+	switch {
+	case unicode.IsControl(r):
+		return fmt.Sprintf("control character %U is not allowed", r)
+	case rule == NamePermissive:
+		return ""
+	case first && r == '-':
+		return "it starts with '-'"
+	case rule == NamePOSIX && !isPortableChar(r):
+		return fmt.Sprintf("character %q (%U) is not allowed", r, r)
+	case rule == NameWindowsSafe && strings.ContainsRune(`<>:"\|?*`, r):
+		return fmt.Sprintf("character %q (%U) is not allowed", r, r)
+	}
+	return ""
+}
+
+// isPortableChar reports whether r is in the portable filename character
+// set of POSIX.
+func isPortableChar(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("._-", r))
+}
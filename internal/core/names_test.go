@@ -0,0 +1,138 @@
+package core
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestNameRuleCheck(t *testing.T) {
+	tests := []struct {
+		rule      NameRule
+		name      string
+		sanitized string
+		problem   string
+	}{
+		{NameWindowsSafe, "main.go", "main.go", ""},
+		{NameWindowsSafe, "my app ünïcode.md", "my app ünïcode.md", ""},
+		{NameWindowsSafe, "a:b?.txt", "a_b_.txt", "character ':' (U+003A) is not allowed"},
+		{NameWindowsSafe, `a\b`, "a_b", `character '\\' (U+005C) is not allowed`},
+		{NameWindowsSafe, "tab\there", "tab_here", "control character U+0009 is not allowed"},
+		{NameWindowsSafe, "-rf", "_rf", "it starts with '-'"},
+		{NameWindowsSafe, "notes. ", "notes__", "it ends with a space or a dot"},
+		{NameWindowsSafe, "CON", "CON_", "'CON' is a name Windows reserves"},
+		{NameWindowsSafe, "nul.txt", "nul_.txt", "'nul' is a name Windows reserves"},
+		{NameWindowsSafe, "Lpt1.tar.gz", "Lpt1_.tar.gz", "'Lpt1' is a name Windows reserves"},
+		{NameWindowsSafe, "console.log", "console.log", ""},
+		{NameWindowsSafe, "café", "café", ""},
+		{NamePOSIX, "main_v2-final.go", "main_v2-final.go", ""},
+		{NamePOSIX, "my app.md", "my_app.md", "character ' ' (U+0020) is not allowed"},
+		{NamePOSIX, "café", "caf_", "character 'é' (U+00E9) is not allowed"},
+		{NamePOSIX, "CON", "CON", ""},
+		{NamePermissive, "-a:b ", "-a:b ", ""},
+		{NamePermissive, "café", "café", ""},
+		{NamePermissive, "bell\a", "bell_", "control character U+0007 is not allowed"},
+	}
+	for _, tt := range tests {
+		sanitized, problem := tt.rule.check(tt.name)
+		if sanitized != tt.sanitized || problem != tt.problem {
+			t.Errorf("%s: expected %q to give %q (%q), got %q (%q)",
+				tt.rule, tt.name, tt.sanitized, tt.problem, sanitized, problem)
+		}
+	}
+	if _, err := ParseNameRule("strict"); err == nil {
+		t.Error("Expected an unknown name rule to fail")
+	}
+}
+
+func TestApplyNameRule(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		"{{.name}}/main.go.tmpl": "package {{.name}}\n",
+		"<literal>.txt":          "The template's own names aren't checked.",
+	})
+	apply := func(name string, opts Options) (string, map[string]string, error) {
+		var out bytes.Buffer
+		opts.TemplatePath, opts.OutputDir, opts.Out = templateDir, t.TempDir(), &out
+		opts.Data = map[string]any{"name": name}
+		err := Apply(opts)
+		if err != nil {
+			return out.String(), nil, err
+		}
+		return out.String(), readFiles(t, opts.OutputDir), nil
+	}
+
+	t.Run("slashes from data", func(t *testing.T) {
+		// A slash is a directory separator, as long as the path stays inside
+		// the output directory.
+		_, files, err := apply("cmd/app", Options{})
+		if err != nil || files["cmd/app/main.go"] != "package cmd/app\n" {
+			t.Errorf("Expected the nested directories, got %v (%v)", files, err)
+		}
+		for _, name := range []string{"../escape", "a/../../escape", "/etc"} {
+			_, _, err = apply(name, Options{SanitizeNames: true})
+			if err == nil || !strings.Contains(err.Error(), "is outside the output directory") {
+				t.Errorf("Expected %q to be rejected as outside the output, got %v", name, err)
+			}
+		}
+		_, _, err = apply(`..\..\escape`, Options{})
+		want := `invalid name '..\..\escape' rendered from '{{.name}}': ` +
+			`character '\\' (U+005C) is not allowed with the windows-safe name rule`
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q, got %v", want, err)
+		}
+	})
+
+	t.Run("reserved names", func(t *testing.T) {
+		_, _, err := apply("aux", Options{})
+		if err == nil || !strings.Contains(err.Error(), "'aux' is a name Windows reserves") {
+			t.Errorf("Expected a reserved name to fail, got %v", err)
+		}
+		_, files, err := apply("aux", Options{NameRule: NamePermissive})
+		if err != nil || files["aux/main.go"] == "" {
+			t.Errorf("Expected the permissive rule to accept it, got %v (%v)", files, err)
+		}
+	})
+
+	t.Run("sanitize", func(t *testing.T) {
+		out, files, err := apply("Con: v2?", Options{SanitizeNames: true})
+		if err != nil || files["Con_ v2_/main.go"] == "" {
+			t.Errorf("Expected the sanitized name, got %v (%v)", files, err)
+		}
+		line := "🧼 Sanitizing name 'Con: v2?' rendered from '{{.name}}/main.go.tmpl' to 'Con_ v2_'\n"
+		if !contains(out, line) {
+			t.Errorf("Expected %q in the output:\n%s", line, out)
+		}
+		_, files, err = apply("my app", Options{NameRule: NamePOSIX, SanitizeNames: true})
+		if err != nil || files["my_app/main.go"] == "" {
+			t.Errorf("Expected the posix rule to replace the space, got %v (%v)", files, err)
+		}
+	})
+
+	t.Run("unicode normalization", func(t *testing.T) {
+		composed, decomposed := "caf\u00e9", "cafe\u0301"
+		templateDir := writeTemplate(t, map[string]string{
+			composed + ".txt": "template",
+			"{{.name}}.txt":   "data",
+		})
+		data := map[string]any{"name": decomposed}
+		// A name given decomposed is the composed name the template has.
+		err := Apply(Options{TemplatePath: templateDir, OutputDir: t.TempDir(), Data: data, Out: &bytes.Buffer{}})
+		if err == nil || !strings.Contains(err.Error(), "both generate '"+composed+".txt'") {
+			t.Errorf("Expected the names to collide, got %v", err)
+		}
+		if runtime.GOOS == "darwin" {
+			// Its filesystems don't tell the two forms apart.
+			return
+		}
+		outputDir := t.TempDir()
+		err = Apply(Options{
+			TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: &bytes.Buffer{},
+			NameRule: NamePermissive,
+		})
+		files := readFiles(t, outputDir)
+		if err != nil || files[composed+".txt"] != "template" || files[decomposed+".txt"] != "data" {
+			t.Errorf("Expected the permissive rule to keep both forms, got %q (%v)", files, err)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Deprecation is the deprecated message of an applied template.
+type Deprecation struct {
+	// Template is the path of the template as given to Apply.
+	Template string `json:"template"`
+	Message  string `json:"message"`
+}
+
+// notice is a notice of an applied template, rendered with the helper
+// functions and partials of the layer declaring it.
+type notice struct {
+	template string
+	// name tells the notices of a template apart in errors, such as
+	// "notice 2".
+	name  string
+	text  string
+	layer *layer
+}
+
+// renderNotices renders the notices of the templates with the data of the
+// run, with secret values masked. A notice that fails to render is warned
+// about and left out: the project was generated all the same.
+func (a *applier) renderNotices(secrets secrets) []string {
+	var rendered []string
+	for _, n := range a.notices {
+		var text strings.Builder
+		if err := n.layer.renderer.Render(&text, n.name, []byte(n.text), a.opts.Data); err != nil {
+			fmt.Fprintf(a.out, "⚠️  Skipping a notice of template '%s': %v\n", n.template, err)
+			continue
+		}
+		if notice := strings.TrimSpace(text.String()); notice != "" {
+			rendered = append(rendered, secrets.mask(notice))
+		}
+	}
+	return rendered
+}
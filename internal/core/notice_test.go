@@ -0,0 +1,91 @@
+package core
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestApplyNotices(t *testing.T) {
+	parent := writeTemplate(t, map[string]string{
+		MetadataFile:     "deprecated: parents don't count\nnotices:\n  - 'Parent: {{.name}}'\n",
+		"README.md.tmpl": "# {{.name}}",
+	})
+	child := writeTemplate(t, map[string]string{
+		MetadataFile: "extends: " + parent + "\n" +
+			"deprecated: use go-service-v2 instead\n" +
+			"computed:\n  target: '{{snake .name}}_bootstrap'\n" +
+			"prompts:\n  token: {secret: true}\n" +
+			"notices:\n" +
+			"  - 'Run {{template \"next.tmpl\" .}} in {{kebab .name}}.'\n" +
+			"  - '{{if .ci}}Set up CI.{{end}}'\n" +
+			"  - '{{.name | nosuchfunc}}'\n" +
+			"  - '{{index .name 99}}'\n" +
+			"  - 'Token: {{.token}}'\n",
+		"_partials/next.tmpl": "make {{.target}}",
+	})
+
+	var out bytes.Buffer
+	result := NewResult()
+	err := Apply(Options{
+		TemplatePath: child,
+		OutputDir:    t.TempDir(),
+		Data:         map[string]any{"name": "MyApp", "token": "s3cr3t"},
+		Out:          &out,
+		Result:       result,
+	})
+	// A notice that doesn't parse or execute only warns.
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	want := []string{"Parent: MyApp", "Run make my_app_bootstrap in my-app.", "Token: ******"}
+	if !reflect.DeepEqual(result.Notices, want) {
+		t.Errorf("Expected notices %q, got %q", want, result.Notices)
+	}
+	for _, warning := range []string{
+		"⚠️  Template '" + child + "' is deprecated: use go-service-v2 instead\n",
+		"⚠️  Skipping a notice of template '" + child + "': could not parse template 'notice 4'",
+		"⚠️  Skipping a notice of template '" + child + "': failed to render template 'notice 5'",
+	} {
+		if !contains(out.String(), warning) {
+			t.Errorf("Expected %q in the output:\n%s", warning, out.String())
+		}
+	}
+	wantDeprecations := []Deprecation{{Template: child, Message: "use go-service-v2 instead"}}
+	if !reflect.DeepEqual(result.Deprecations, wantDeprecations) {
+		t.Errorf("Expected deprecations %+v, got %+v", wantDeprecations, result.Deprecations)
+	}
+
+	// A failed run has no notices.
+	result = NewResult()
+	err = Apply(Options{TemplatePath: child, OutputDir: t.TempDir(), Strict: true, Out: &out, Result: result})
+	if err == nil || result.Notices != nil {
+		t.Errorf("Expected the run to fail without notices, got %q, %v", result.Notices, err)
+	}
+}
+
+func TestLintNotices(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile: "prompts:\n  name: {}\nnotices:\n  - '{{.name}} {{.missing}}'\n  - '{{.name'\n",
+	})
+	findings, err := Lint(templateDir)
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+	var rules []string
+	for _, finding := range findings {
+		rules = append(rules, finding.Rule+": "+finding.Message)
+	}
+	want := []string{
+		RuleParseError + ": notice 2 does not parse",
+		RuleUndeclaredKey + ": 'missing' is not declared in prompts or defaults",
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("Expected %d findings, got %q", len(want), rules)
+	}
+	for i, w := range want {
+		if !contains(rules[i], w) {
+			t.Errorf("Finding %d = %q, want %q", i, rules[i], w)
+		}
+	}
+}
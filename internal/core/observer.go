@@ -0,0 +1,65 @@
+package core
+
+import (
+	"cmp"
+	"path/filepath"
+)
+
+// Observer receives the events of an Apply run as they happen, for tools
+// embedding mold that show its progress. Apply calls it from the goroutine
+// it runs in, one event at a time, so an observer needs no locking of its
+// own.
+type Observer interface {
+	// WalkStart is called before the templates are walked to plan the
+	// files, with their directories, inherited templates and layers
+	// included, in the order they are applied.
+	WalkStart(templates []string)
+	// FileStart is called before a file is generated or pruned, with its
+	// slash-separated path relative to the output root, and FileFinish
+	// after it, with what was done with it and the error it failed with.
+	// A raw directory is a single file, copied. Directories aren't
+	// reported.
+	FileStart(path string)
+	FileFinish(path string, status FileStatus, err error)
+	// Warning is called with each warning printed, without its "⚠️".
+	Warning(message string)
+	// Done is called once the run is over, even when it fails, with its
+	// result.
+	Done(result *Result)
+}
+
+// NopObserver ignores every event. An observer embedding it only implements
+// the events it handles.
+type NopObserver struct{}
+
+func (NopObserver) WalkStart([]string) {}
+
+func (NopObserver) FileStart(string) {}
+
+func (NopObserver) FileFinish(string, FileStatus, error) {}
+
+func (NopObserver) Warning(string) {}
+
+func (NopObserver) Done(*Result) {}
+
+// observe generates the entry between the FileStart and FileFinish events of
+// its file.
+func (a *applier) observe(e entry) error {
+	key := filepath.ToSlash(e.rel)
+	if e.kind != entryDir {
+		a.observer.FileStart(key)
+	}
+	err := a.generate(e)
+	if err != nil {
+		a.status[key] = StatusFailed
+	}
+	if e.kind != entryDir {
+		// Only the files of a raw directory are recorded, as copied.
+		status := cmp.Or(a.status[key], StatusSkipped)
+		if e.kind == entryRaw {
+			status = cmp.Or(a.status[key], StatusCopied)
+		}
+		a.observer.FileFinish(key, status, err)
+	}
+	return err
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// recordingObserver records the events of a run as lines.
+type recordingObserver struct {
+	events []string
+	result *Result
+}
+
+func (o *recordingObserver) WalkStart(templates []string) {
+	o.events = append(o.events, fmt.Sprintf("walk %d", len(templates)))
+}
+
+func (o *recordingObserver) FileStart(path string) {
+	o.events = append(o.events, "start "+path)
+}
+
+func (o *recordingObserver) FileFinish(path string, status FileStatus, err error) {
+	o.events = append(o.events, fmt.Sprintf("finish %s %s %v", path, status, err != nil))
+}
+
+func (o *recordingObserver) Warning(message string) {
+	o.events = append(o.events, "warning "+message)
+}
+
+func (o *recordingObserver) Done(result *Result) {
+	o.events = append(o.events, fmt.Sprintf("done %v", result.Success))
+	o.result = result
+}
+
+func TestApplyObserver(t *testing.T) {
+	v1 := writeTemplate(t, map[string]string{
+		MetadataFile:     "deprecated: use v2\nraw: [vendor]\n",
+		"README.md.tmpl": "# {{.name}}\n",
+		"gone.txt":       "gone",
+		"vendor/lib.txt": "lib",
+	})
+	data := map[string]any{"name": "demo"}
+	outputDir := t.TempDir()
+	observer := &recordingObserver{}
+	if err := Apply(Options{
+		TemplatePath: v1, OutputDir: outputDir, Data: data, Out: &bytes.Buffer{}, Observer: observer,
+	}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	want := []string{
+		"warning Template '" + v1 + "' is deprecated: use v2",
+		"walk 1",
+		"start README.md",
+		"finish README.md rendered false",
+		"start gone.txt",
+		"finish gone.txt copied false",
+		"start vendor",
+		"finish vendor copied false",
+		"done true",
+	}
+	if !reflect.DeepEqual(observer.events, want) {
+		t.Errorf("Expected the events\n%q\ngot\n%q", want, observer.events)
+	}
+	if observer.result == nil || len(observer.result.Files) != 3 {
+		t.Errorf("Expected the result of the run without one set, got %+v", observer.result)
+	}
+
+	t.Run("prune", func(t *testing.T) {
+		v2 := writeTemplate(t, map[string]string{"README.md.tmpl": "# {{.name}}\n"})
+		observer := &recordingObserver{}
+		result := NewResult()
+		err := Apply(Options{
+			TemplatePath: v2, OutputDir: outputDir, Data: data, Prune: true, Out: &bytes.Buffer{},
+			Result: result, Observer: observer,
+		})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		want := []string{
+			"walk 1",
+			"start README.md",
+			"finish README.md rendered false",
+			"start gone.txt",
+			"finish gone.txt pruned false",
+			"start vendor/lib.txt",
+			"finish vendor/lib.txt pruned false",
+			"done true",
+		}
+		if !reflect.DeepEqual(observer.events, want) {
+			t.Errorf("Expected the events\n%q\ngot\n%q", want, observer.events)
+		}
+		if observer.result != result {
+			t.Errorf("Expected the result set to be observed")
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		templateDir := writeTemplate(t, map[string]string{"a.txt": "a", "b.txt.tmpl": "{{.missing}}"})
+		observer := &recordingObserver{}
+		err := Apply(Options{
+			TemplatePath: templateDir, OutputDir: t.TempDir(), Data: data, Strict: true, Out: &bytes.Buffer{},
+			Observer: observer,
+		})
+		if err == nil {
+			t.Fatal("Expected Apply to fail on the missing key")
+		}
+		want := []string{
+			"walk 1",
+			"start a.txt",
+			"finish a.txt copied false",
+			"start b.txt",
+			"finish b.txt failed true",
+			"done false",
+		}
+		if !reflect.DeepEqual(observer.events, want) {
+			t.Errorf("Expected the events\n%q\ngot\n%q", want, observer.events)
+		}
+		if observer.result.Error == "" {
+			t.Errorf("Expected the error in the result, got %+v", observer.result)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// yamlErrorLine finds the line number in a yaml.v3 error message.
+//
+//nolint:gochecknoglobals // compiled once
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// jsonParseError describes an error parsing JSON content, with the line and
+// column it occurred at and the lines around it when the error tells.
+func jsonParseError(source string, content []byte, err error) error {
+	var offset int64 = -1
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	}
+	if offset < 0 {
+		return fmt.Errorf("failed to parse JSON file '%s': %w", source, err)
+	}
+
+	// The offset is just past the byte the error is about.
+	index := max(0, min(int(offset)-1, len(content)-1))
+	line := 1 + bytes.Count(content[:index], []byte("\n"))
+	column := 1 + utf8.RuneCount(content[bytes.LastIndexByte(content[:index], '\n')+1:index])
+	return fmt.Errorf("failed to parse JSON file '%s': line %d, column %d: %w\n%s",
+		source, line, column, err, sourceContext(content, line, column))
+}
+
+// yamlParseError describes an error parsing YAML content, with the lines
+// around the line it names.
+func yamlParseError(source string, content []byte, err error) error {
+	match := yamlErrorLine.FindStringSubmatch(err.Error())
+	if match == nil {
+		return fmt.Errorf("failed to parse YAML file '%s': %w", source, err)
+	}
+	line, _ := strconv.Atoi(match[1])
+	return fmt.Errorf("failed to parse YAML file '%s': %w\n%s", source, err, sourceContext(content, line, 0))
+}
+
+// sourceContext formats the given line of content with one line above and
+// below it, each prefixed by its number. A caret under the line points at
+// the column, counted in characters from 1. Column 0 marks the whole line
+// instead.
+func sourceContext(content []byte, line, column int) string {
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	first, last := max(1, line-1), min(len(lines), line+1)
+	width := len(strconv.Itoa(last))
+
+	var out strings.Builder
+	for n := first; n <= last; n++ {
+		text := strings.TrimSuffix(lines[n-1], "\r")
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&out, "%s %*d | %s\n", marker, width, n, text)
+		if n != line || column < 1 {
+			continue
+		}
+		// Keep tabs so the caret lines up with the text above it.
+		var pad strings.Builder
+		for i, r := range []rune(text) {
+			if i >= column-1 {
+				break
+			}
+			if r == '\t' {
+				pad.WriteRune('\t')
+			} else {
+				pad.WriteByte(' ')
+			}
+		}
+		fmt.Fprintf(&out, "  %s | %s^\n", strings.Repeat(" ", width), pad.String())
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
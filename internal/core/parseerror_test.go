@@ -0,0 +1,134 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSourceContext(t *testing.T) {
+	content := []byte("first\n\tsecond\nthird\nfourth\n")
+	tests := []struct {
+		name   string
+		line   int
+		column int
+		want   []string
+	}{
+		{
+			name: "caret under the column, tabs kept",
+			line: 2, column: 3,
+			want: []string{"  1 | first", "> 2 | \tsecond", "    | \t ^", "  3 | third"},
+		},
+		{
+			name: "first line has no line above",
+			line: 1, column: 1,
+			want: []string{"> 1 | first", "    | ^", "  2 | \tsecond"},
+		},
+		{
+			name: "last line has no line below",
+			line: 4,
+			want: []string{"  3 | third", "> 4 | fourth"},
+		},
+		{name: "out of range", line: 9, want: []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sourceContext(content, tt.line, tt.column); got != strings.Join(tt.want, "\n") {
+				t.Errorf("sourceContext() =\n%s\nwant:\n%s", got, strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+
+	t.Run("line numbers are aligned", func(t *testing.T) {
+		long := []byte(strings.Repeat("x\n", 9) + "ten\neleven\n")
+		want := "   9 | x\n> 10 | ten\n     |  ^\n  11 | eleven"
+		if got := sourceContext(long, 10, 2); got != want {
+			t.Errorf("sourceContext() =\n%s\nwant:\n%s", got, want)
+		}
+	})
+}
+
+func TestParseDataErrorContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		content string
+		want    []string
+	}{
+		{
+			name:    "json syntax error",
+			format:  "json",
+			content: "{\n  \"name\": \"api\",\n  \"port\": x\n}\n",
+			want: []string{
+				"failed to parse JSON file 'data': line 3, column 11: invalid character 'x'",
+				"> 3 |   \"port\": x\n    |           ^\n  4 | }",
+			},
+		},
+		{
+			name:    "json with comments points into the original content",
+			format:  "json",
+			content: "{\n  // the name\n  \"name\": \"api\" \"port\": 1\n}",
+			want: []string{
+				"line 3, column 17: invalid character '\"' after object key:value pair",
+				"  2 |   // the name\n> 3 |   \"name\": \"api\" \"port\": 1\n    |                 ^",
+			},
+		},
+		{
+			name:    "json with multibyte characters",
+			format:  "json",
+			content: "{\"naïve\": tru}",
+			want:    []string{"line 1, column 14:", "> 1 | {\"naïve\": tru}\n    |              ^"},
+		},
+		{
+			name:    "json top-level type",
+			format:  "json",
+			content: "[1, 2]",
+			want:    []string{"line 1, column 1: json: cannot unmarshal array", "> 1 | [1, 2]\n    | ^"},
+		},
+		{
+			name:    "yaml syntax error",
+			format:  "yaml",
+			content: "name: api\n  bad: indent\nport: 1\n",
+			want: []string{
+				"failed to parse YAML file 'data': yaml: line 2: mapping values are not allowed",
+				"  1 | name: api\n> 2 |   bad: indent\n  3 | port: 1",
+			},
+		},
+		{
+			name:    "yaml type error",
+			format:  "yaml",
+			content: "- a\n- b\n",
+			want:    []string{"cannot unmarshal !!seq", "> 1 | - a\n  2 | - b"},
+		},
+		{
+			name:    "yaml document that is not a mapping",
+			format:  "yaml",
+			content: "name: api\n---\n  - a\n",
+			want:    []string{"document 2 at line 3 is not a mapping", "  2 | ---\n> 3 |   - a\n    |   ^"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseData([]byte(tt.content), tt.format, "data", DataOptions{})
+			if err == nil {
+				t.Fatal("Expected a parse error")
+			}
+			for _, want := range tt.want {
+				if !contains(err.Error(), want) {
+					t.Errorf("Expected error to contain:\n%s\ngot:\n%v", want, err)
+				}
+			}
+		})
+	}
+
+	t.Run("errors without a position are kept as is", func(t *testing.T) {
+		err := jsonParseError("data", []byte("{}"), errors.New("boom"))
+		if err.Error() != "failed to parse JSON file 'data': boom" {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		err = yamlParseError("data", []byte("{}"), errors.New("boom"))
+		if err.Error() != "failed to parse YAML file 'data': boom" {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// pathTokenKey matches the key of a __name__ token: dotted names of letters
+// and digits joined by single underscores.
+//
+//nolint:gochecknoglobals // compiled once
+var pathTokenKey = regexp.MustCompile(
+	`^[A-Za-z][A-Za-z0-9]*(?:_[A-Za-z0-9]+)*(?:\.[A-Za-z][A-Za-z0-9]*(?:_[A-Za-z0-9]+)*)*$`)
+
+// pathTokenHelper matches the name of a helper a token pipes its key to.
+//
+//nolint:gochecknoglobals // compiled once
+var pathTokenHelper = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+
+// escapedPathToken is how a template path writes a literal "__".
+const escapedPathToken = "____"
+
+// pathTokens converts the __name__ tokens of a template path to the
+// placeholders they stand for, so names can hold placeholders without the
+// braces some editors, archivers and filesystems reject. __name__ stands for
+// {{.name}}, and __name|snake__ or __snake_name__ for {{.name | snake}}, the
+// prefix form applying to the case helpers of funcs only. Four underscores
+// are a literal "__", so ____init____.py generates __init__.py. Text that
+// isn't a token, and the actions of the braces syntax, are left alone.
+func pathTokens(path string, funcs template.FuncMap) string {
+	if !strings.Contains(path, "__") {
+		return path
+	}
+	var b strings.Builder
+	for i := 0; i < len(path); {
+		rest := path[i:]
+		switch {
+		case strings.HasPrefix(rest, "{{"):
+			end := strings.Index(rest, "}}")
+			if end < 0 {
+				end = len(rest) - 2
+			}
+			b.WriteString(rest[:end+2])
+			i += end + 2
+		case strings.HasPrefix(rest, escapedPathToken):
+			b.WriteString("__")
+			i += len(escapedPathToken)
+		case strings.HasPrefix(rest, "__"):
+			if end := strings.Index(rest[2:], "__"); end >= 0 {
+				if placeholder, ok := pathToken(rest[2:2+end], funcs); ok {
+					b.WriteString(placeholder)
+					i += end + 4
+					continue
+				}
+			}
+			b.WriteString("__")
+			i += 2
+		default:
+			b.WriteByte(path[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// pathToken returns the placeholder of the body of a __name__ token, or
+// false when it isn't one.
+func pathToken(body string, funcs template.FuncMap) (string, bool) {
+	key, pipes, piped := strings.Cut(body, "|")
+	if !pathTokenKey.MatchString(key) {
+		return "", false
+	}
+	var helpers []string
+	if piped {
+		// An unknown helper fails to parse, naming it.
+		for helper := range strings.SplitSeq(pipes, "|") {
+			if !pathTokenHelper.MatchString(helper) {
+				return "", false
+			}
+			helpers = append(helpers, helper)
+		}
+	} else if helper, name, ok := strings.Cut(key, "_"); ok && !strings.Contains(helper, ".") {
+		if _, isHelper := funcs[helper]; isHelper {
+			key, helpers = name, []string{helper}
+		}
+	}
+	placeholder := "{{." + key
+	for _, helper := range helpers {
+		placeholder += " | " + helper
+	}
+	return placeholder + "}}", true
+}
+
+// escapePathTokens writes the "__" of a name as escapedPathToken when the
+// name would otherwise be read as holding a token.
+func escapePathTokens(name string) string {
+	if pathTokens(name, helperFunc) == name {
+		return name
+	}
+	return strings.ReplaceAll(name, "__", escapedPathToken)
+}
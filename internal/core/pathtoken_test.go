@@ -0,0 +1,80 @@
+package core
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestPathTokens(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "plain/name.go", want: "plain/name.go"},
+		{path: "__project_name__/main.go", want: "{{.project_name}}/main.go"},
+		{path: "cmd/__name__-__version__.txt", want: "cmd/{{.name}}-{{.version}}.txt"},
+		{path: "__db.host__.conf", want: "{{.db.host}}.conf"},
+		{path: "__project_name|snake__.go", want: "{{.project_name | snake}}.go"},
+		{path: "__name|snake|upper__", want: "{{.name | snake | upper}}"},
+		{path: "__snake_project_name__.go", want: "{{.project_name | snake}}.go"},
+		{path: "__kebab_name__/{{.other}}", want: "{{.name | kebab}}/{{.other}}"},
+		// Only case helpers are a prefix.
+		{path: "__upper_limit__", want: "{{.upper_limit}}"},
+		{path: `{{"__init__"}}.py`, want: `{{"__init__"}}.py`},
+		{path: "____init____.py", want: "__init__.py"},
+		{path: "a__b.txt", want: "a__b.txt"},
+		{path: "__not a key__", want: "__not a key__"},
+		{path: "___x___", want: "___x___"},
+	}
+	for _, tt := range tests {
+		if got := pathTokens(tt.path, helperFunc); got != tt.want {
+			t.Errorf("pathTokens(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestApplyPathTokens(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		"__project_name__/{{.module}}/__snake_service__.go.tmpl": "package {{.module}}\n",
+		"__project_name__/__service|kebab__.md":                  "docs",
+		"pkg/____init____.py":                                    "",
+	})
+	outputDir := t.TempDir()
+	err := Apply(Options{
+		TemplatePath: templateDir,
+		OutputDir:    outputDir,
+		Data:         map[string]any{"project_name": "shop", "module": "api", "service": "OrderService"},
+		Strict:       true,
+		NoProvenance: true,
+		Out:          &bytes.Buffer{},
+	})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	want := map[string]string{
+		"shop/api/order_service.go": "package api\n",
+		"shop/order-service.md":     "docs",
+		"pkg/__init__.py":           "",
+	}
+	if files := readFiles(t, outputDir); !reflect.DeepEqual(files, want) {
+		t.Errorf("Expected %q, got %q", want, files)
+	}
+
+	// Tokens are references like placeholders.
+	report, err := Inspect(templateDir, map[string]any{"module": "api"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Missing) != 2 || report.Missing[0].Key != "project_name" || report.Missing[1].Key != "service" {
+		t.Errorf("Expected the token keys to be missing, got %+v", report.Missing)
+	}
+
+	// An unknown helper fails, naming it.
+	templateDir = writeTemplate(t, map[string]string{"__name|snek__.txt": ""})
+	err = Apply(Options{TemplatePath: templateDir, OutputDir: t.TempDir(), Data: map[string]any{"name": "x"},
+		Out: &bytes.Buffer{}})
+	if err == nil || !contains(err.Error(), `function "snek" not defined`) {
+		t.Errorf("Expected the unknown helper to fail, got: %v", err)
+	}
+}
@@ -0,0 +1,283 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// VersionsDir is the directory of a local template holding one directory per
+// version, such as 'versions/v1.4.2'.
+const VersionsDir = "versions"
+
+// SplitVersion splits a template argument of the form name@version. An
+// argument without a name or a version before or after its last '@' isn't
+// one.
+func SplitVersion(arg string) (name, version string, ok bool) {
+	i := strings.LastIndex(arg, "@")
+	if i <= 0 || i == len(arg)-1 {
+		return arg, "", false
+	}
+	return arg[:i], arg[i+1:], true
+}
+
+// IsVersioned reports whether a template directory uses the versions layout:
+// it holds nothing but a versions directory, hidden files aside.
+func IsVersioned(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	versioned := false
+	for _, entry := range entries {
+		switch {
+		case strings.HasPrefix(entry.Name(), "."):
+		case entry.Name() == VersionsDir && entry.IsDir():
+			versioned = true
+		default:
+			return false
+		}
+	}
+	return versioned
+}
+
+// TemplateVersions lists the versions of a template in the versions layout,
+// oldest first.
+func TemplateVersions(dir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, VersionsDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the versions of template '%s': %w", dir, err)
+	}
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			versions = append(versions, entry.Name())
+		}
+	}
+	SortVersions(versions)
+	return versions, nil
+}
+
+// ResolveVersion returns the directory of a version of a local template and
+// the version it resolved to. The version is one of its versions, or a
+// constraint such as ^1.4 resolving to the highest matching one. A template
+// in the versions layout resolves to its latest version when version is
+// empty. Any other template has no versions and is returned as it is.
+func ResolveVersion(dir, version string) (string, string, error) {
+	if !IsVersioned(dir) {
+		if version == "" {
+			return dir, "", nil
+		}
+		return "", "", fmt.Errorf("template '%s' has no version '%s': it has no '%s' directory", dir, version,
+			VersionsDir)
+	}
+	versions, err := TemplateVersions(dir)
+	if err != nil {
+		return "", "", err
+	}
+	if len(versions) == 0 {
+		return "", "", fmt.Errorf("template '%s' has an empty '%s' directory", dir, VersionsDir)
+	}
+	if version == "" {
+		version = versions[len(versions)-1]
+	}
+	if version, err = selectVersion(dir, version, versions); err != nil {
+		return "", "", err
+	}
+	return filepath.Join(dir, VersionsDir, version), version, nil
+}
+
+// unknownVersion reports a version a template doesn't have, listing the ones
+// it has.
+func unknownVersion(template, version string, available []string) error {
+	if len(available) == 0 {
+		return fmt.Errorf("template '%s' has no version '%s', nor any other", template, version)
+	}
+	return fmt.Errorf("template '%s' has no version '%s', available versions: %s", template, version,
+		strings.Join(available, ", "))
+}
+
+// SortVersions sorts versions oldest first, by semver precedence when both
+// are semantic versions. Otherwise the dot-separated numbers of versions
+// such as 'v1.10.0' are compared as numbers, so it sorts after 'v1.9.0'.
+func SortVersions(versions []string) {
+	slices.SortFunc(versions, compareVersions)
+}
+
+// compareVersions compares two versions by semver precedence, or part by
+// part, numerically when both parts are numbers.
+func compareVersions(a, b string) int {
+	if av, err := ParseSemver(a); err == nil {
+		if bv, err := ParseSemver(b); err == nil {
+			if c := av.Compare(bv); c != 0 {
+				return c
+			}
+			return strings.Compare(a, b)
+		}
+	}
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := range min(len(as), len(bs)) {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		var c int
+		if aErr == nil && bErr == nil {
+			c = an - bn
+		} else {
+			c = strings.Compare(as[i], bs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	if c := len(as) - len(bs); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// ListGitTags lists the tags of a git repository without cloning it.
+func ListGitTags(repoURL string) ([]string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, errors.New("listing the tags of a git repository needs git on the PATH")
+	}
+	cmd := exec.Command("git", "ls-remote", "--tags", "--refs", "--end-of-options", repoURL)
+	// Fail instead of asking for credentials.
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("failed to list the tags of '%s': %w: %s", repoURL, err,
+				strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("failed to list the tags of '%s': %w", repoURL, err)
+	}
+	var tags []string
+	for line := range strings.Lines(string(output)) {
+		if _, ref, ok := strings.Cut(strings.TrimSpace(line), "\t"); ok {
+			tags = append(tags, strings.TrimPrefix(ref, "refs/tags/"))
+		}
+	}
+	SortVersions(tags)
+	return tags, nil
+}
+
+// PinOptions configures how Pin resolves version constraints.
+type PinOptions struct {
+	// CacheDir keeps the versions constraints resolved to, so they resolve
+	// the same way without listing the tags again. Empty disables it.
+	CacheDir string
+	// Refresh resolves constraints again, replacing the cached versions.
+	Refresh bool
+}
+
+// Pin returns the entry of a version of a registry template: one listed in
+// its versions or a tag of its git repository, or the highest of them
+// matching a constraint such as ^1.4.
+func (e RegistryEntry) Pin(version string, opts PinOptions) (RegistryEntry, error) {
+	if _, ok := e.Versions[version]; ok {
+		return e.pinned(version), nil
+	}
+	cachePath := ""
+	if opts.CacheDir != "" {
+		cachePath = resolvedCachePath(opts.CacheDir, e.URL, version)
+	}
+	if cachePath != "" && !opts.Refresh {
+		if resolved, ok := readResolved(cachePath, e.URL, version); ok {
+			return e.pinned(resolved), nil
+		}
+	}
+	available := slices.Collect(maps.Keys(e.Versions))
+	if !IsArchiveURL(e.URL) {
+		tags, err := ListGitTags(e.URL)
+		if err != nil {
+			return RegistryEntry{}, err
+		}
+		for _, tag := range tags {
+			if !slices.Contains(available, tag) {
+				available = append(available, tag)
+			}
+		}
+	}
+	SortVersions(available)
+	resolved, err := selectVersion(e.Name, version, available)
+	if err != nil {
+		return RegistryEntry{}, err
+	}
+	if cachePath != "" && resolved != version {
+		// The version is still usable when it can't be cached.
+		_ = writeResolved(cachePath, e.URL, version, resolved)
+	}
+	return e.pinned(resolved), nil
+}
+
+// pinned returns the entry of one of its versions or git tags.
+func (e RegistryEntry) pinned(version string) RegistryEntry {
+	pinned := e
+	pinned.Version = version
+	v, ok := e.Versions[version]
+	if !ok {
+		pinned.Ref = version
+		return pinned
+	}
+	if v.URL != "" {
+		pinned.URL = v.URL
+	}
+	if v.Path != "" {
+		pinned.Path = v.Path
+	}
+	pinned.Ref = v.Ref
+	if pinned.Ref == "" && !IsArchiveURL(pinned.URL) {
+		pinned.Ref = version
+	}
+	return pinned
+}
+
+// resolvedVersion is a version constraint resolved for a template source,
+// as cached by Pin.
+type resolvedVersion struct {
+	URL        string `yaml:"url"`
+	Constraint string `yaml:"constraint"`
+	Version    string `yaml:"version"`
+}
+
+// resolvedCachePath returns the cache file of a constraint resolved for a
+// template source.
+func resolvedCachePath(cacheDir, sourceURL, constraint string) string {
+	sum := sha256.Sum256([]byte(sourceURL + "\x00" + constraint))
+	return filepath.Join(cacheDir, "resolved", hex.EncodeToString(sum[:8])+".yaml")
+}
+
+// readResolved reads the version a constraint was resolved to, if cached.
+func readResolved(path, sourceURL, constraint string) (string, bool) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	var cached resolvedVersion
+	if err = yaml.Unmarshal(content, &cached); err != nil || cached.URL != sourceURL ||
+		cached.Constraint != constraint || cached.Version == "" {
+		return "", false
+	}
+	return cached.Version, true
+}
+
+// writeResolved caches the version a constraint was resolved to.
+func writeResolved(path, sourceURL, constraint, version string) error {
+	content, err := yaml.Marshal(resolvedVersion{URL: sourceURL, Constraint: constraint, Version: version})
+	if err != nil {
+		return err
+	}
+	return writeCache(path, content)
+}
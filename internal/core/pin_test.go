@@ -0,0 +1,148 @@
+package core
+
+import (
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestSplitVersion(t *testing.T) {
+	tests := []struct {
+		arg, name, version string
+		ok                 bool
+	}{
+		{arg: "go-service@v1.4.2", name: "go-service", version: "v1.4.2", ok: true},
+		{arg: "go/service@1.0", name: "go/service", version: "1.0", ok: true},
+		{arg: "scope@org/tmpl@v2", name: "scope@org/tmpl", version: "v2", ok: true},
+		{arg: "go-service", name: "go-service"},
+		{arg: "@v1", name: "@v1"},
+		{arg: "go-service@", name: "go-service@"},
+	}
+	for _, tt := range tests {
+		name, version, ok := SplitVersion(tt.arg)
+		if name != tt.name || version != tt.version || ok != tt.ok {
+			t.Errorf("SplitVersion(%q) = %q, %q, %v; want %q, %q, %v",
+				tt.arg, name, version, ok, tt.name, tt.version, tt.ok)
+		}
+	}
+}
+
+func TestSortVersions(t *testing.T) {
+	versions := []string{"v1.10.0", "v1.2.0", "v1.9.3", "v0.1", "v1.9", "2.0.0", "beta"}
+	SortVersions(versions)
+	want := []string{"v0.1", "v1.2.0", "v1.9", "v1.9.3", "v1.10.0", "2.0.0", "beta"}
+	if !reflect.DeepEqual(versions, want) {
+		t.Errorf("Expected %v, got %v", want, versions)
+	}
+}
+
+func TestResolveVersion(t *testing.T) {
+	dir := writeTemplate(t, map[string]string{
+		"versions/v1.4.2/main.go.tmpl":  "v1.4.2",
+		"versions/v1.10.0/main.go.tmpl": "v1.10.0",
+		".keep":                         "",
+	})
+	if !IsVersioned(dir) {
+		t.Fatal("Expected the template to use the versions layout")
+	}
+
+	path, version, err := ResolveVersion(dir, "")
+	if err != nil || version != "v1.10.0" || path != filepath.Join(dir, VersionsDir, "v1.10.0") {
+		t.Errorf("Expected the latest version, got %s, %s, %v", path, version, err)
+	}
+	path, version, err = ResolveVersion(dir, "v1.4.2")
+	if err != nil || version != "v1.4.2" || path != filepath.Join(dir, VersionsDir, "v1.4.2") {
+		t.Errorf("Expected version v1.4.2, got %s, %s, %v", path, version, err)
+	}
+	path, version, err = ResolveVersion(dir, "~1.4")
+	if err != nil || version != "v1.4.2" || path != filepath.Join(dir, VersionsDir, "v1.4.2") {
+		t.Errorf("Expected ~1.4 to resolve to v1.4.2, got %s, %s, %v", path, version, err)
+	}
+	_, _, err = ResolveVersion(dir, "v9.0.0")
+	if err == nil || !contains(err.Error(), "has no version 'v9.0.0', available versions: v1.4.2, v1.10.0") {
+		t.Errorf("Expected the available versions in the error, got: %v", err)
+	}
+
+	plain := writeTemplate(t, map[string]string{"main.go.tmpl": "", "versions/v1/notes.md": ""})
+	if IsVersioned(plain) {
+		t.Error("Expected a template with files next to its versions directory not to be versioned")
+	}
+	if path, version, err = ResolveVersion(plain, ""); err != nil || path != plain || version != "" {
+		t.Errorf("Expected the template itself, got %s, %s, %v", path, version, err)
+	}
+	if _, _, err = ResolveVersion(plain, "v1"); err == nil || !contains(err.Error(), "it has no 'versions' directory") {
+		t.Errorf("Expected a version of a plain template to fail, got: %v", err)
+	}
+
+	templatesDir := filepath.Dir(dir)
+	names, err := ListTemplates(templatesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Base(dir); !slices.Contains(names, want) {
+		t.Errorf("Expected %s to be listed as one template, got %v", want, names)
+	}
+}
+
+func TestRegistryEntryPin(t *testing.T) {
+	archive := RegistryEntry{
+		Name: "web",
+		URL:  "https://example.com/web/v2.tar.gz",
+		Versions: map[string]RegistryVersion{
+			"v1": {URL: "https://example.com/web/v1.tar.gz"},
+			"v2": {},
+		},
+	}
+	pinned, err := archive.Pin("v1", PinOptions{})
+	if err != nil || pinned.URL != "https://example.com/web/v1.tar.gz" || pinned.Ref != "" || pinned.Version != "v1" {
+		t.Errorf("Expected the archive of v1, got %+v, %v", pinned, err)
+	}
+	if _, err = archive.Pin("v3", PinOptions{}); err == nil || !contains(err.Error(), "available versions: v1, v2") {
+		t.Errorf("Expected the versions of the entry in the error, got: %v", err)
+	}
+
+	repo := t.TempDir()
+	runGit(t, repo, "init", "--quiet")
+	runGit(t, repo, "commit", "--quiet", "--allow-empty", "-m", "init")
+	runGit(t, repo, "tag", "v1.4.2")
+	runGit(t, repo, "tag", "v1.10.0")
+	git := RegistryEntry{
+		Name:     "go",
+		URL:      "file://" + filepath.ToSlash(repo),
+		Ref:      "main",
+		Versions: map[string]RegistryVersion{"stable": {Ref: "v1.4.2"}},
+	}
+	for version, ref := range map[string]string{"v1.10.0": "v1.10.0", "stable": "v1.4.2"} {
+		pinned, err = git.Pin(version, PinOptions{})
+		if err != nil || pinned.Ref != ref || pinned.Version != version {
+			t.Errorf("Pin(%q): expected ref %s, got %+v, %v", version, ref, pinned, err)
+		}
+	}
+	if _, err = git.Pin("v2", PinOptions{}); err == nil ||
+		!contains(err.Error(), "available versions: v1.4.2, v1.10.0, stable") {
+		t.Errorf("Expected the tags and versions in the error, got: %v", err)
+	}
+
+	// A constraint resolves to the highest matching tag, then to the cached
+	// version until it's refreshed.
+	opts := PinOptions{CacheDir: t.TempDir()}
+	if pinned, err = git.Pin("^1.4", opts); err != nil || pinned.Ref != "v1.10.0" || pinned.Version != "v1.10.0" {
+		t.Errorf("Expected ^1.4 to resolve to v1.10.0, got %+v, %v", pinned, err)
+	}
+	runGit(t, repo, "tag", "v1.11.0")
+	if pinned, err = git.Pin("^1.4", opts); err != nil || pinned.Version != "v1.10.0" {
+		t.Errorf("Expected the cached v1.10.0, got %+v, %v", pinned, err)
+	}
+	opts.Refresh = true
+	if pinned, err = git.Pin("^1.4", opts); err != nil || pinned.Version != "v1.11.0" {
+		t.Errorf("Expected the refreshed v1.11.0, got %+v, %v", pinned, err)
+	}
+	opts.Refresh = false
+	if pinned, err = git.Pin("^1.4", opts); err != nil || pinned.Version != "v1.11.0" {
+		t.Errorf("Expected the refresh to be cached, got %+v, %v", pinned, err)
+	}
+	if pinned, err = archive.Pin(">=1", opts); err != nil || pinned.Version != "v2" {
+		t.Errorf("Expected >=1 to resolve to the archive of v2, got %+v, %v", pinned, err)
+	}
+}
@@ -0,0 +1,206 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// BuiltinFuncs are the functions text/template defines for every template.
+//
+//nolint:gochecknoglobals // list of the text/template builtins
+var BuiltinFuncs = []string{
+	"and", "call", "eq", "ge", "gt", "html", "index", "js", "le", "len", "lt", "ne", "not", "or", "print",
+	"printf", "println", "slice", "urlquery",
+}
+
+// Identifiers are the data and the functions a template references.
+type Identifiers struct {
+	// References are the sorted dotted paths of the data referenced, such
+	// as "db.port" for {{.db.port}}.
+	References []string
+	// Funcs are the function calls, in the order of the template.
+	Funcs []FuncCall
+}
+
+// FuncCall is a call of a function by a template, such as kebab in
+// {{kebab .name}}.
+type FuncCall struct {
+	Name string
+	// Line and Column locate the call in the template, from 1.
+	Line   int
+	Column int
+	// Arg is the first argument of the call when it is a string constant,
+	// such as "licenses.yaml" in {{dataFile "licenses.yaml"}}.
+	Arg string
+}
+
+// String returns the call as 'name' at line:column.
+func (c FuncCall) String() string {
+	return fmt.Sprintf("'%s' at %d:%d", c.Name, c.Line, c.Column)
+}
+
+// Keys returns the sorted top-level data keys of the references.
+func (ids *Identifiers) Keys() []string {
+	keys := make([]string, 0, len(ids.References))
+	for _, ref := range ids.References {
+		key, _, _ := strings.Cut(ref, ".")
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// Unknown returns the calls of functions neither funcs nor BuiltinFuncs
+// define.
+func (ids *Identifiers) Unknown(funcs template.FuncMap) []FuncCall {
+	var unknown []FuncCall
+	for _, call := range ids.Funcs {
+		if _, ok := funcs[call.Name]; !ok && !slices.Contains(BuiltinFuncs, call.Name) {
+			unknown = append(unknown, call)
+		}
+	}
+	return unknown
+}
+
+// Identify parses template content and returns the data and the functions
+// it references. Only references to the root data count: fields used where
+// the dot is the data, and fields of the $ variable anywhere. Fields inside
+// range and with blocks refer to other values. Functions aren't checked
+// while parsing, so a call of an unknown one is returned rather than
+// failing; Unknown finds them.
+func Identify(name, content string) (*Identifiers, error) {
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+	trees := make(map[string]*parse.Tree)
+	if _, err := tree.Parse(content, "", "", trees); err != nil {
+		return nil, fmt.Errorf("could not parse template '%s': %w", name, err)
+	}
+
+	c := &collector{content: content, keys: make(map[string]bool)}
+	for _, t := range trees {
+		c.collect(t.Root, true)
+	}
+	slices.SortFunc(c.funcs, func(a, b FuncCall) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+	return &Identifiers{References: slices.Sorted(maps.Keys(c.keys)), Funcs: c.funcs}, nil
+}
+
+// IdentifyPlaceholders parses template content and returns the sorted
+// top-level data keys it references, like Identify. A call of a function
+// the helpers don't define fails, as it would when rendering.
+func IdentifyPlaceholders(name, content string) ([]string, error) {
+	ids, err := identifyKnown(name, content)
+	if err != nil {
+		return nil, err
+	}
+	return ids.Keys(), nil
+}
+
+// IdentifyReferences is IdentifyPlaceholders returning the whole dotted
+// path of each reference, such as "db.port" for {{.db.port}}.
+func IdentifyReferences(name, content string) ([]string, error) {
+	ids, err := identifyKnown(name, content)
+	if err != nil {
+		return nil, err
+	}
+	return ids.References, nil
+}
+
+// identifyKnown is Identify failing on the first call of a function the
+// helpers don't define.
+func identifyKnown(name, content string) (*Identifiers, error) {
+	ids, err := Identify(name, content)
+	if err != nil {
+		return nil, err
+	}
+	if unknown := ids.Unknown(helperFunc); len(unknown) > 0 {
+		return nil, fmt.Errorf("could not parse template '%s': function %s not defined", name, unknown[0])
+	}
+	return ids, nil
+}
+
+// collector gathers the data and the functions referenced by the nodes of
+// a template.
+type collector struct {
+	content string
+	keys    map[string]bool
+	funcs   []FuncCall
+}
+
+// collect adds the dotted paths of the data and the functions referenced
+// under node. atRoot tells whether the dot is the root data at node.
+func (c *collector) collect(node parse.Node, atRoot bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			c.collect(child, atRoot)
+		}
+	case *parse.ActionNode:
+		c.collect(n.Pipe, atRoot)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			c.collect(cmd, atRoot)
+		}
+	case *parse.CommandNode:
+		call := len(c.funcs)
+		for _, arg := range n.Args {
+			c.collect(arg, atRoot)
+		}
+		if _, ok := n.Args[0].(*parse.IdentifierNode); ok && len(n.Args) > 1 {
+			if s, ok := n.Args[1].(*parse.StringNode); ok {
+				c.funcs[call].Arg = s.Text
+			}
+		}
+	case *parse.IdentifierNode:
+		c.funcs = append(c.funcs, c.call(n.Ident, int(n.Pos)))
+	case *parse.FieldNode:
+		if atRoot {
+			c.keys[strings.Join(n.Ident, ".")] = true
+		}
+	case *parse.ChainNode:
+		c.collect(n.Node, atRoot)
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			c.keys[strings.Join(n.Ident[1:], ".")] = true
+		}
+	case *parse.IfNode:
+		c.branch(&n.BranchNode, atRoot, atRoot)
+	case *parse.RangeNode:
+		c.branch(&n.BranchNode, atRoot, false)
+	case *parse.WithNode:
+		c.branch(&n.BranchNode, atRoot, false)
+	case *parse.TemplateNode:
+		c.collect(n.Pipe, atRoot)
+	}
+}
+
+// branch collects the references of an if, range or with block, whose body
+// may run with a different dot than its pipeline and else branch.
+func (c *collector) branch(n *parse.BranchNode, atRoot, bodyAtRoot bool) {
+	c.collect(n.Pipe, atRoot)
+	c.collect(n.List, bodyAtRoot)
+	c.collect(n.ElseList, atRoot)
+}
+
+// call locates the call of a function at byte offset pos of the content.
+func (c *collector) call(name string, pos int) FuncCall {
+	pos = min(pos, len(c.content))
+	before := c.content[:pos]
+	return FuncCall{
+		Name:   name,
+		Line:   strings.Count(before, "\n") + 1,
+		Column: pos - strings.LastIndexByte(before, '\n'),
+	}
+}
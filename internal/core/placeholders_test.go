@@ -0,0 +1,108 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIdentifyPlaceholders(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{name: "no placeholders", content: "plain text", want: []string{}},
+		{
+			name:    "fields and functions",
+			content: "{{.name}} {{snake .service}} {{.db.port}}",
+			want:    []string{"db", "name", "service"},
+		},
+		{name: "if and else", content: "{{if .a}}{{.b}}{{else}}{{.c}}{{end}}", want: []string{"a", "b", "c"}},
+		{
+			name:    "range and with bodies use another dot",
+			content: "{{range .items}}{{.name}} {{$.owner}}{{else}}{{.empty}}{{end}}{{with .db}}{{.port}}{{end}}",
+			want:    []string{"db", "empty", "items", "owner"},
+		},
+		{
+			name:    "template calls and defines",
+			content: `{{define "x"}}{{.inner}}{{end}}{{template "x" .outer}}`,
+			want:    []string{"inner", "outer"},
+		},
+		{name: "variables", content: "{{$v := .value}}{{$v}}", want: []string{"value"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IdentifyPlaceholders("test", tt.content)
+			if err != nil {
+				t.Fatalf("IdentifyPlaceholders failed: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("IdentifyPlaceholders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("parse error", func(t *testing.T) {
+		_, err := IdentifyPlaceholders("bad", "{{.name")
+		if err == nil || !contains(err.Error(), "could not parse template 'bad'") {
+			t.Errorf("Expected parse error, got: %v", err)
+		}
+	})
+}
+
+func TestIdentifyReferences(t *testing.T) {
+	got, err := IdentifyReferences("test", "{{.db.port}} {{.name}} {{with .a}}{{.b}}{{end}} {{$.x.y}} {{(.c).d}}")
+	if err != nil {
+		t.Fatalf("IdentifyReferences failed: %v", err)
+	}
+	want := []string{"a", "c", "db.port", "name", "x.y"}
+	if !slices.Equal(got, want) {
+		t.Errorf("IdentifyReferences() = %v, want %v", got, want)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	content := "{{.name | snake | kebabcase}}\n" +
+		"{{printf \"%s-%d\" (camel (index .parts 0)) (len .items)}}\n" +
+		"{{if and (gt (len .tags) 0) (isSet .owner)}}{{range where .items}}{{upper .}}{{end}}{{end}}\n" +
+		`{{define "x"}}{{title .inner}}{{end}}`
+	ids, err := Identify("test", content)
+	if err != nil {
+		t.Fatalf("Identify failed: %v", err)
+	}
+	wantRefs := []string{"inner", "items", "name", "owner", "parts", "tags"}
+	if !slices.Equal(ids.References, wantRefs) {
+		t.Errorf("References = %v, want %v", ids.References, wantRefs)
+	}
+	var names []string
+	for _, call := range ids.Funcs {
+		names = append(names, call.Name)
+	}
+	wantNames := []string{
+		"snake", "kebabcase", "printf", "camel", "index", "len", "and", "gt", "len", "isSet", "where", "upper", "title",
+	}
+	if !slices.Equal(names, wantNames) {
+		t.Errorf("Funcs = %v, want %v", names, wantNames)
+	}
+	// A string constant given first is recorded with the call.
+	if ids.Funcs[2].Arg != "%s-%d" || ids.Funcs[3].Arg != "" {
+		t.Errorf("Expected the constant argument of printf only, got %+v", ids.Funcs[2:4])
+	}
+	unknown := ids.Unknown(helperFunc)
+	want := []FuncCall{
+		{Name: "kebabcase", Line: 1, Column: 19},
+		{Name: "isSet", Line: 3, Column: 30},
+		{Name: "where", Line: 3, Column: 53},
+		{Name: "upper", Line: 3, Column: 69},
+	}
+	if !slices.Equal(unknown, want) {
+		t.Errorf("Unknown = %v, want %v", unknown, want)
+	}
+
+	// The former signatures fail on unknown functions, as rendering does.
+	_, err = IdentifyPlaceholders("test", "{{kebabcase .name}}")
+	if err == nil || !contains(err.Error(), "function 'kebabcase' at 1:3 not defined") {
+		t.Errorf("Expected the unknown function to fail, got: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Phase is a step of generating a file that a Profiler times.
+type Phase string
+
+const (
+	// PhaseRead reads a template file.
+	PhaseRead Phase = "read"
+	// PhaseParse parses a template file with the partials.
+	PhaseParse Phase = "parse"
+	// PhaseExecute executes a parsed template with the data.
+	PhaseExecute Phase = "execute"
+	// PhaseFormat runs the formatters of a generated file.
+	PhaseFormat Phase = "format"
+	// PhaseWrite writes a rendered file to the output.
+	PhaseWrite Phase = "write"
+	// PhaseCopy copies a regular file to the output.
+	PhaseCopy Phase = "copy"
+	// PhaseOther is the time of a run spent outside the other phases, such
+	// as planning, logging and recording the provenance.
+	PhaseOther Phase = "other"
+)
+
+// Phases lists the phases in the order they are reported.
+//
+//nolint:gochecknoglobals // fixed list of the phases
+var Phases = []Phase{PhaseRead, PhaseParse, PhaseExecute, PhaseFormat, PhaseWrite, PhaseCopy, PhaseOther}
+
+// Profiler times the phases of generating each file. Apply calls Now before
+// a phase and Record after it, so a profiler that doesn't record anything
+// doesn't even read the clock.
+type Profiler interface {
+	// Now returns the start of a phase.
+	Now() time.Time
+	// Record adds the time since start to a phase of the file.
+	Record(relPath string, phase Phase, start time.Time)
+}
+
+// nopProfiler is the Profiler of runs that are not profiled.
+type nopProfiler struct{}
+
+func (nopProfiler) Now() time.Time {
+	return time.Time{}
+}
+
+func (nopProfiler) Record(string, Phase, time.Time) {}
+
+// Profile is a Profiler keeping the durations of every file of a run.
+type Profile struct {
+	started time.Time
+	elapsed time.Duration
+	files   map[string]map[Phase]time.Duration
+}
+
+// NewProfile starts a profile.
+func NewProfile() *Profile {
+	return &Profile{started: time.Now(), files: make(map[string]map[Phase]time.Duration)}
+}
+
+// Now returns the current time.
+func (p *Profile) Now() time.Time {
+	return time.Now()
+}
+
+// Record adds the time since start to a phase of the file.
+func (p *Profile) Record(relPath string, phase Phase, start time.Time) {
+	phases, ok := p.files[relPath]
+	if !ok {
+		phases = make(map[Phase]time.Duration)
+		p.files[relPath] = phases
+	}
+	phases[phase] += time.Since(start)
+}
+
+// Stop ends the profile. The time since NewProfile is the elapsed time of
+// the report.
+func (p *Profile) Stop() {
+	p.elapsed = time.Since(p.started)
+}
+
+// ProfileReport holds the durations recorded by a Profile. Durations are in
+// nanoseconds in JSON.
+type ProfileReport struct {
+	Elapsed time.Duration `json:"elapsed_ns"`
+	// Totals sums the phases of every file. With PhaseOther, they add up to
+	// Elapsed.
+	Totals map[Phase]time.Duration `json:"totals_ns"`
+	// Files are sorted from the slowest to the fastest, then by path.
+	Files []FileProfile `json:"files"`
+}
+
+// FileProfile holds the durations of the phases of one file.
+type FileProfile struct {
+	Path   string                  `json:"path"`
+	Total  time.Duration           `json:"total_ns"`
+	Phases map[Phase]time.Duration `json:"phases_ns"`
+}
+
+// Report returns the durations recorded so far.
+func (p *Profile) Report() ProfileReport {
+	report := ProfileReport{Elapsed: p.elapsed, Totals: make(map[Phase]time.Duration)}
+	for _, phase := range Phases {
+		report.Totals[phase] = 0
+	}
+	var recorded time.Duration
+	for _, path := range slices.Sorted(maps.Keys(p.files)) {
+		file := FileProfile{Path: path, Phases: maps.Clone(p.files[path])}
+		for phase, d := range file.Phases {
+			file.Total += d
+			report.Totals[phase] += d
+		}
+		recorded += file.Total
+		report.Files = append(report.Files, file)
+	}
+	report.Totals[PhaseOther] = max(p.elapsed-recorded, 0)
+	slices.SortStableFunc(report.Files, func(a, b FileProfile) int {
+		return cmp.Compare(b.Total, a.Total)
+	})
+	return report
+}
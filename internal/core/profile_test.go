@@ -0,0 +1,132 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestProfile(t *testing.T) {
+	files := map[string]string{
+		MetadataFile:     "formatters:\n  \"*.go\": [\"gofmt\"]\n",
+		"main.go.tmpl":   "package {{.name}}\nfunc  main() {}\n",
+		"logo.png":       "\x89PNG",
+		"docs/a.md":      "# docs",
+		"docs/b.md.tmpl": "{{range .items}}- {{.}}\n{{end}}",
+	}
+	for i := range 20 {
+		files[fmt.Sprintf("gen/%02d.txt.tmpl", i)] = "{{.name}} {{len .items}}"
+	}
+	templateDir := writeTemplate(t, files)
+
+	profile := NewProfile()
+	err := Apply(Options{
+		TemplatePath: templateDir,
+		OutputDir:    t.TempDir(),
+		Data:         map[string]any{"name": "main", "items": []any{"x", "y"}},
+		Out:          io.Discard,
+		Profiler:     profile,
+	})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	profile.Stop()
+	report := profile.Report()
+
+	t.Run("json schema", func(t *testing.T) {
+		content, err := json.Marshal(report)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		var decoded struct {
+			Elapsed *int64            `json:"elapsed_ns"`
+			Totals  map[string]*int64 `json:"totals_ns"`
+			Files   []struct {
+				Path   *string          `json:"path"`
+				Total  *int64           `json:"total_ns"`
+				Phases map[string]int64 `json:"phases_ns"`
+			} `json:"files"`
+		}
+		if err = json.Unmarshal(content, &decoded); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if decoded.Elapsed == nil || *decoded.Elapsed <= 0 {
+			t.Errorf("Expected a positive elapsed_ns, got %s", content)
+		}
+		for _, phase := range Phases {
+			if decoded.Totals[string(phase)] == nil {
+				t.Errorf("Expected totals_ns.%s, got %s", phase, content)
+			}
+		}
+		if len(decoded.Files) != 24 {
+			t.Fatalf("Expected 24 files, got %d", len(decoded.Files))
+		}
+		for _, file := range decoded.Files {
+			if file.Path == nil || file.Total == nil || len(file.Phases) == 0 {
+				t.Errorf("Expected path, total_ns and phases_ns for every file, got %s", content)
+			}
+		}
+	})
+
+	t.Run("phases", func(t *testing.T) {
+		phases := make(map[string][]Phase)
+		for _, file := range report.Files {
+			for phase := range file.Phases {
+				phases[file.Path] = append(phases[file.Path], phase)
+			}
+			slices.Sort(phases[file.Path])
+		}
+		want := map[string][]Phase{
+			"main.go":   {PhaseExecute, PhaseFormat, PhaseParse, PhaseRead, PhaseWrite},
+			"logo.png":  {PhaseCopy},
+			"docs/b.md": {PhaseExecute, PhaseParse, PhaseRead, PhaseWrite},
+		}
+		for path, expected := range want {
+			if !slices.Equal(phases[path], expected) {
+				t.Errorf("Expected phases %v for '%s', got %v", expected, path, phases[path])
+			}
+		}
+	})
+
+	t.Run("totals sum to the elapsed time", func(t *testing.T) {
+		var sum, recorded time.Duration
+		for phase, d := range report.Totals {
+			if d < 0 {
+				t.Errorf("Expected a non-negative %s total, got %s", phase, d)
+			}
+			sum += d
+		}
+		if sum != report.Elapsed {
+			t.Errorf("Expected the totals to sum to %s, got %s", report.Elapsed, sum)
+		}
+		for i, file := range report.Files {
+			var total time.Duration
+			for _, d := range file.Phases {
+				total += d
+			}
+			if total != file.Total {
+				t.Errorf("Expected the phases of '%s' to sum to %s, got %s", file.Path, file.Total, total)
+			}
+			if i > 0 && report.Files[i-1].Total < file.Total {
+				t.Errorf("Expected the files sorted from the slowest, got '%s' before '%s'",
+					report.Files[i-1].Path, file.Path)
+			}
+			recorded += total
+		}
+		if recorded <= 0 || recorded > report.Elapsed {
+			t.Errorf("Expected the recorded phases within the elapsed %s, got %s", report.Elapsed, recorded)
+		}
+	})
+
+	t.Run("not profiled", func(t *testing.T) {
+		var p nopProfiler
+		start := p.Now()
+		p.Record("main.go", PhaseRead, start)
+		if !start.IsZero() {
+			t.Errorf("Expected the no-op profiler not to read the clock, got %s", start)
+		}
+	})
+}
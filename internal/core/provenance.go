@@ -0,0 +1,137 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ProvenanceFile is the file Apply writes at the output root to record
+	// how the project was generated.
+	ProvenanceFile = ".mold.yaml"
+	// ProvenanceSchema is the version of the provenance format written by
+	// this release. Older versions are migrated when they are read.
+	ProvenanceSchema = 1
+)
+
+// Provenance records which templates generated a project, when, and with
+// which data.
+type Provenance struct {
+	// Schema is the version of the format.
+	Schema int `json:"schema" yaml:"schema"`
+	// Template is the applied template, Layers the templates applied on top
+	// of it.
+	Template ProvenanceTemplate   `json:"template" yaml:"template"`
+	Layers   []ProvenanceTemplate `json:"layers,omitempty" yaml:"layers,omitempty"`
+	// MoldVersion is the version of mold that generated the project.
+	MoldVersion string `json:"mold_version" yaml:"mold_version"`
+	// GeneratedAt is when the project was generated.
+	GeneratedAt time.Time `json:"generated_at" yaml:"generated_at"`
+	// Data is the effective data, defaults included, with the values of
+	// secret prompts masked.
+	Data map[string]any `json:"data" yaml:"data"`
+	// Files is the manifest of the generated files, sorted by path.
+	Files []ManifestFile `json:"files,omitempty" yaml:"files,omitempty"`
+	// Dirs are the directories the run generated, sorted.
+	Dirs []string `json:"dirs,omitempty" yaml:"dirs,omitempty"`
+}
+
+// ProvenanceTemplate identifies an applied template.
+type ProvenanceTemplate struct {
+	// Name and Version are taken from the template's template.yaml.
+	Name    string `json:"name,omitempty" yaml:"name,omitempty"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+	// Path is the absolute path of the template directory.
+	Path string `json:"path" yaml:"path"`
+	// URL and Ref locate a template fetched from a remote repository.
+	URL string `json:"url,omitempty" yaml:"url,omitempty"`
+	Ref string `json:"ref,omitempty" yaml:"ref,omitempty"`
+	// Resolved is the version the template was resolved to, from a git tag,
+	// a registry entry or the versions directory of a local template.
+	Resolved string `json:"resolved,omitempty" yaml:"resolved,omitempty"`
+	// Extends are the templates the template extends, the root of its
+	// inheritance chain first.
+	Extends []ProvenanceTemplate `json:"extends,omitempty" yaml:"extends,omitempty"`
+}
+
+// provenanceHeader starts every provenance file.
+const provenanceHeader = "# Written by mold when the project was generated. Do not edit.\n"
+
+// Marshal encodes the provenance as the content of a provenance file.
+func (p *Provenance) Marshal() ([]byte, error) {
+	content, err := yaml.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode provenance: %w", err)
+	}
+	return append([]byte(provenanceHeader), content...), nil
+}
+
+// ParseProvenance decodes the content of a provenance file, migrating older
+// schema versions to the current one.
+func ParseProvenance(content []byte, source string) (*Provenance, error) {
+	p := &Provenance{}
+	if err := yaml.Unmarshal(content, p); err != nil {
+		return nil, fmt.Errorf("failed to parse provenance file '%s': %w", source, err)
+	}
+	switch {
+	case p.Schema <= 0:
+		return nil, fmt.Errorf("invalid provenance file '%s': missing schema version", source)
+	case p.Schema > ProvenanceSchema:
+		return nil, fmt.Errorf("provenance file '%s' has schema version %d, this version of mold reads up to %d",
+			source, p.Schema, ProvenanceSchema)
+	}
+	// Migrations from older schema versions go here, oldest first.
+	return p, nil
+}
+
+// LoadProvenance reads the provenance file at the root of a generated
+// project. The error wraps fs.ErrNotExist when the project has none.
+func LoadProvenance(dir string) (*Provenance, error) {
+	path := filepath.Join(dir, ProvenanceFile)
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no provenance file '%s': %w", path, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provenance file '%s': %w", path, err)
+	}
+	return ParseProvenance(content, path)
+}
+
+// provenanceTemplate describes an applied template for the provenance, with
+// the parents of its chain, as returned by ResolveChain.
+func provenanceTemplate(templatePath string, chain []string, meta *Metadata) (ProvenanceTemplate, error) {
+	abs, err := filepath.Abs(templatePath)
+	if err != nil {
+		return ProvenanceTemplate{}, fmt.Errorf("failed to resolve template path '%s': %w", templatePath, err)
+	}
+	source := ProvenanceTemplate{Name: meta.Name, Version: meta.Version, Path: abs}
+	for _, parent := range chain[:len(chain)-1] {
+		var parentMeta *Metadata
+		if parentMeta, err = LoadMetadata(parent); err != nil {
+			return ProvenanceTemplate{}, err
+		}
+		source.Extends = append(source.Extends,
+			ProvenanceTemplate{Name: parentMeta.Name, Version: parentMeta.Version, Path: parent})
+	}
+	return source, nil
+}
+
+// maskedData returns a copy of data with the values of secret prompts
+// replaced by SecretMask.
+func maskedData(prompts Prompts, data map[string]any) map[string]any {
+	masked := make(map[string]any, len(data))
+	MergeData(masked, data)
+	for _, prompt := range prompts {
+		if _, ok := LookupValue(masked, prompt.Name); ok && prompt.Secret {
+			_ = SetValue(masked, prompt.Name, SecretMask)
+		}
+	}
+	return masked
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProvenanceRoundTrip(t *testing.T) {
+	want := &Provenance{
+		Schema: ProvenanceSchema,
+		Template: ProvenanceTemplate{
+			Name: "go/service", Version: "1.2.0", Path: "/templates/go/service",
+			Extends: []ProvenanceTemplate{{Name: "go/base", Version: "1.0.0", Path: "/templates/go/base"}},
+		},
+		Layers:      []ProvenanceTemplate{{Path: "/templates/postgres", URL: "https://example.com/t.git", Ref: "v1"}},
+		MoldVersion: "v0.9.0",
+		GeneratedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Data:        map[string]any{"name": "demo", "port": 8080, "db": map[string]any{"password": SecretMask}},
+		Files:       []ManifestFile{{Path: "cmd/main.go", SHA256: sha256Hex("package main\n"), Mode: "0755"}},
+	}
+	content, err := want.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !bytes.HasPrefix(content, []byte(provenanceHeader)) {
+		t.Errorf("Expected the header comment, got:\n%s", content)
+	}
+	got, err := ParseProvenance(content, ProvenanceFile)
+	if err != nil {
+		t.Fatalf("ParseProvenance failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Round trip mismatch:\ngot:  %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestParseProvenanceErrors(t *testing.T) {
+	tests := map[string]string{
+		"template:\n  path: /t\n":       "missing schema version",
+		"schema: 99\ntemplate: {}\n":    "has schema version 99",
+		"schema: [1]\n":                 "failed to parse provenance file",
+		"schema: 1\ntemplate: [x, y]\n": "failed to parse provenance file",
+	}
+	for content, wantErr := range tests {
+		_, err := ParseProvenance([]byte(content), ProvenanceFile)
+		if err == nil || !contains(err.Error(), wantErr) {
+			t.Errorf("%q: expected error containing %q, got: %v", content, wantErr, err)
+		}
+	}
+
+	_, err := LoadProvenance(t.TempDir())
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected a not exist error for a project without provenance, got: %v", err)
+	}
+}
+
+func TestApplyProvenance(t *testing.T) {
+	base := writeTemplate(t, map[string]string{
+		MetadataFile: "name: go/service\nversion: 1.2.0\nformatters:\n  \"*.go\": [gofmt]\n" +
+			"defaults:\n  port: 8080\nprompts:\n  db.password: {type: string, secret: true}\n",
+		"README.md.tmpl": "{{.name}}",
+		"main.go":        "package main\nfunc main() {}",
+		ProvenanceFile:   "stale: true\n",
+	})
+	layer := writeTemplate(t, map[string]string{"db.sql": "create table t();"})
+	clock := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	outputDir := t.TempDir()
+	data := map[string]any{"name": "demo", "db": map[string]any{"password": "hunter2"}}
+
+	err := Apply(Options{
+		TemplatePath: base,
+		Layers:       []string{layer},
+		OutputDir:    outputDir,
+		Data:         data,
+		Clock:        clock,
+		Out:          &bytes.Buffer{},
+	})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	got, err := LoadProvenance(outputDir)
+	if err != nil {
+		t.Fatalf("LoadProvenance failed: %v", err)
+	}
+	want := &Provenance{
+		Schema:      ProvenanceSchema,
+		Template:    ProvenanceTemplate{Name: "go/service", Version: "1.2.0", Path: base},
+		Layers:      []ProvenanceTemplate{{Path: layer}},
+		MoldVersion: got.MoldVersion,
+		GeneratedAt: clock,
+		Data:        map[string]any{"name": "demo", "port": 8080, "db": map[string]any{"password": SecretMask}},
+		// The manifest holds the content after formatting.
+		Files: []ManifestFile{
+			{Path: "README.md", SHA256: sha256Hex("demo"), Mode: "0644", Template: base},
+			{Path: "db.sql", SHA256: sha256Hex("create table t();"), Mode: "0644", Template: layer},
+			{Path: "main.go", SHA256: sha256Hex("package main\n\nfunc main() {}\n"), Mode: "0644", Template: base},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected provenance:\ngot:  %+v\nwant: %+v", got, want)
+	}
+	if got.MoldVersion == "" {
+		t.Error("Expected the mold version to be recorded")
+	}
+	if data["db"].(map[string]any)["password"] != "hunter2" {
+		t.Error("Expected the data of the caller to be left unmasked")
+	}
+
+	outputDir = t.TempDir()
+	err = Apply(Options{TemplatePath: base, OutputDir: outputDir, Data: data, NoProvenance: true, Out: &bytes.Buffer{}})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if _, err = LoadProvenance(outputDir); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected no provenance with NoProvenance, got: %v", err)
+	}
+	if files := readFiles(t, outputDir); len(files) != 2 {
+		t.Errorf("Expected only the template files, got %v", files)
+	}
+}
+
+// sha256Hex returns the hex-encoded SHA-256 hash of s.
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
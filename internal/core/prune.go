@@ -0,0 +1,141 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+)
+
+// loadPrevious validates the options of a pruning or merging run and loads
+// the manifest of the previous run, before the provenance file is replaced.
+func (a *applier) loadPrevious() error {
+	if !a.opts.Prune && !a.merging() {
+		return nil
+	}
+	action := "pruning"
+	if !a.opts.Prune {
+		action = "merging"
+	}
+	switch {
+	case a.opts.Sink != nil || ArchiveFormat(a.opts.OutputDir) != "":
+		return fmt.Errorf("%s needs an output directory, not an archive or a stream", action)
+	case a.opts.NoProvenance:
+		return fmt.Errorf("%s needs the provenance, it would leave the manifest out of date", action)
+	case a.opts.Subdir != "":
+		return fmt.Errorf("%s cannot be combined with a subdirectory, the manifest would only cover part of "+
+			"the project", action)
+	}
+	previous, err := LoadProvenance(a.opts.OutputDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintf(a.out, "⚠️  No %s manifest in '%s', nothing to prune or merge\n", ProvenanceFile, a.opts.OutputDir)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	a.previous = previous
+	a.tracked = make(map[string]ManifestFile, len(previous.Files))
+	for _, file := range previous.Files {
+		a.tracked[file.Path] = file
+	}
+	return nil
+}
+
+// prune deletes the files of the previous run that this run didn't generate,
+// unless they were modified since, followed by the directories of the
+// previous run left empty. Files the manifest doesn't track are never
+// touched. In a dry run, nothing is deleted.
+func (a *applier) prune() error {
+	if a.previous == nil {
+		return nil
+	}
+	verb := "🧹 Pruning"
+	if a.opts.DryRun {
+		verb = "🧹 Would prune"
+	}
+	// removed holds the slash-separated paths deleted, or that would be.
+	removed := make(map[string]bool)
+	for _, file := range a.previous.Files {
+		if _, generated := a.entries[file.Path]; generated || file.Path == ProvenanceFile {
+			continue
+		}
+		checks, err := CheckFiles(a.opts.OutputDir, []ManifestFile{file})
+		if err != nil {
+			return err
+		}
+		switch checks[0].State {
+		case FileMissing:
+			continue
+		case FileModified:
+			fmt.Fprintf(a.out, "⚠️  Keeping '%s', it was modified since it was generated\n", file.Path)
+			continue
+		case FileUnchanged:
+		}
+		a.observer.FileStart(file.Path)
+		fmt.Fprintf(a.out, "%s: %s\n", verb, file.Path)
+		if err = a.pruneFile(file.Path); err != nil {
+			a.observer.FileFinish(file.Path, StatusFailed, err)
+			return err
+		}
+		removed[file.Path] = true
+		a.status[file.Path] = StatusPruned
+		a.observer.FileFinish(file.Path, StatusPruned, nil)
+	}
+
+	// Deepest directories first, so a parent is checked once its children
+	// are gone.
+	dirs := slices.Clone(a.previous.Dirs)
+	slices.Sort(dirs)
+	slices.Reverse(dirs)
+	for _, dir := range dirs {
+		if _, generated := a.entries[dir]; generated || !filepath.IsLocal(filepath.FromSlash(dir)) {
+			continue
+		}
+		empty, err := emptyAfter(filepath.Join(a.opts.OutputDir, filepath.FromSlash(dir)), dir, removed)
+		if err != nil || !empty {
+			continue
+		}
+		fmt.Fprintf(a.out, "%s: %s/\n", verb, dir)
+		if !a.opts.DryRun {
+			if err = os.Remove(filepath.Join(a.opts.OutputDir, filepath.FromSlash(dir))); err != nil {
+				return fmt.Errorf("failed to prune '%s': %w", dir, err)
+			}
+		}
+		removed[dir] = true
+	}
+	return nil
+}
+
+// pruneFile backs up and deletes the file at the slash-separated path of
+// the output directory.
+func (a *applier) pruneFile(path string) error {
+	if err := a.backup(filepath.FromSlash(path)); err != nil {
+		return err
+	}
+	if a.opts.DryRun {
+		return nil
+	}
+	if err := os.Remove(filepath.Join(a.opts.OutputDir, filepath.FromSlash(path))); err != nil {
+		return fmt.Errorf("failed to prune '%s': %w", path, err)
+	}
+	return nil
+}
+
+// emptyAfter reports whether the directory only holds entries that were
+// removed. rel is its slash-separated path relative to the output root.
+func emptyAfter(dir, rel string, removed map[string]bool) (bool, error) {
+	children, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+	for _, child := range children {
+		if !removed[path.Join(rel, child.Name())] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestApplyPrune(t *testing.T) {
+	v1 := writeTemplate(t, map[string]string{
+		"keep.txt":            "keep",
+		"gone.txt":            "gone",
+		"edited.txt":          "edited",
+		"old/nested/old.txt":  "old",
+		"mixed/gone.txt.tmpl": "{{.name}}",
+	})
+	v2 := writeTemplate(t, map[string]string{"keep.txt": "keep v2"})
+	data := map[string]any{"name": "demo"}
+	outputDir := t.TempDir()
+	if err := Apply(Options{TemplatePath: v1, OutputDir: outputDir, Data: data, Out: &bytes.Buffer{}}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	userFiles := map[string]string{"edited.txt": "edited by hand", "mixed/user.txt": "mine", "untracked.txt": "mine"}
+	for name, content := range userFiles {
+		if err := os.WriteFile(filepath.Join(outputDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write project file: %v", err)
+		}
+	}
+	before := readFiles(t, outputDir)
+
+	pruned := []string{
+		"🧹 Pruning: gone.txt",
+		"🧹 Pruning: mixed/gone.txt",
+		"🧹 Pruning: old/nested/old.txt",
+		"🧹 Pruning: old/nested/",
+		"🧹 Pruning: old/",
+	}
+	t.Run("dry run", func(t *testing.T) {
+		var out bytes.Buffer
+		err := Apply(Options{TemplatePath: v2, OutputDir: outputDir, Data: data, Prune: true, DryRun: true, Out: &out})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		for _, line := range pruned {
+			line = "🧹 Would prune" + line[len("🧹 Pruning"):]
+			if !contains(out.String(), line+"\n") {
+				t.Errorf("Expected %q in output:\n%s", line, out.String())
+			}
+		}
+		if contains(out.String(), "mixed/\n") || contains(out.String(), "untracked") {
+			t.Errorf("Expected untracked files and their directories to be left alone:\n%s", out.String())
+		}
+		if after := readFiles(t, outputDir); !reflect.DeepEqual(after, before) {
+			t.Errorf("Expected a dry run to write nothing, got %v", after)
+		}
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		var out bytes.Buffer
+		err := Apply(Options{TemplatePath: v2, OutputDir: outputDir, Data: data, Prune: true, Out: &out})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		for _, line := range pruned {
+			if !contains(out.String(), line+"\n") {
+				t.Errorf("Expected %q in output:\n%s", line, out.String())
+			}
+		}
+		if !contains(out.String(), "Keeping 'edited.txt', it was modified since it was generated") {
+			t.Errorf("Expected the modified file to be reported:\n%s", out.String())
+		}
+		files := readFiles(t, outputDir)
+		delete(files, ProvenanceFile)
+		delete(files, BaseDir+"/keep.txt")
+		want := map[string]string{
+			"keep.txt":       "keep v2",
+			"edited.txt":     "edited by hand",
+			"mixed/user.txt": "mine",
+			"untracked.txt":  "mine",
+		}
+		if !reflect.DeepEqual(files, want) {
+			t.Errorf("Expected %v, got %v", want, files)
+		}
+		if _, err = os.Stat(filepath.Join(outputDir, "old")); !os.IsNotExist(err) {
+			t.Errorf("Expected the emptied directory to be removed, got: %v", err)
+		}
+		p, err := LoadProvenance(outputDir)
+		if err != nil || len(p.Files) != 1 || p.Files[0].Path != "keep.txt" {
+			t.Errorf("Expected the manifest to only track keep.txt, got %+v: %v", p, err)
+		}
+	})
+
+	t.Run("no manifest", func(t *testing.T) {
+		var out bytes.Buffer
+		err := Apply(Options{TemplatePath: v2, OutputDir: t.TempDir(), Data: data, Prune: true, Out: &out})
+		if err != nil || !contains(out.String(), "nothing to prune") {
+			t.Errorf("Expected a warning without a manifest, got %v:\n%s", err, out.String())
+		}
+	})
+
+	errTests := map[string]Options{
+		"not an archive":         {OutputDir: filepath.Join(t.TempDir(), "out.tar")},
+		"needs the provenance":   {OutputDir: outputDir, NoProvenance: true},
+		"combined with a subdir": {OutputDir: outputDir, Subdir: "old"},
+	}
+	for wantErr, opts := range errTests {
+		opts.TemplatePath, opts.Data, opts.Prune, opts.Out = v1, data, true, &bytes.Buffer{}
+		if err := Apply(opts); err == nil || !contains(err.Error(), wantErr) {
+			t.Errorf("Expected error containing %q, got: %v", wantErr, err)
+		}
+	}
+}
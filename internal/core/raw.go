@@ -0,0 +1,118 @@
+package core
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/0m3kk/mold/internal/utils"
+)
+
+// copyRaw copies a raw directory of a template as a whole. A directory sink
+// gets it through utils.CopyDir, other sinks file by file. Symlinks are
+// followed, like for the other files of a template. Raw files replace the
+// existing ones without being merged, and no base is kept for them.
+func (a *applier) copyRaw(e entry) error {
+	if e.rel != "." {
+		a.dirs = append(a.dirs, filepath.ToSlash(e.rel))
+	}
+	start := a.profiler.Now()
+	var stats utils.CopyStats
+	var err error
+	if dirSink, onDisk := a.sink.(*DirSink); onDisk {
+		stats, err = utils.CopyDir(e.src, dirSink.Path(e.rel), utils.WithOverwrite(),
+			utils.WithSymlinks(utils.SymlinkFollow),
+			utils.WithVisit(func(relPath string, info fs.FileInfo) error {
+				return a.trackRaw(e, relPath, info)
+			}))
+	} else {
+		stats, err = a.streamRaw(e)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy raw directory '%s': %w", e.src, err)
+	}
+	a.profiler.Record(e.rel, PhaseCopy, start)
+	e.layer.copied += stats.Files
+	fmt.Fprintf(a.out, "🗂️  Copying raw directory: %s (%d files, %d directories, %s)\n",
+		e.rel, stats.Files, stats.Dirs, formatSize(stats.Bytes))
+	return nil
+}
+
+// trackRaw records a directory or file of a raw directory before CopyDir
+// copies it, backing up the file it replaces. The manifest gets the hash of
+// the source, which the copy has once written. Named pipes, sockets and
+// devices are skipped.
+func (a *applier) trackRaw(e entry, relPath string, info fs.FileInfo) error {
+	rel := filepath.Join(e.rel, relPath)
+	if info.IsDir() {
+		a.dirs = append(a.dirs, filepath.ToSlash(rel))
+		return nil
+	}
+	if kind := irregularType(info.Mode()); kind != "" {
+		if err := a.skipIrregular(filepath.Join(e.src, relPath), rel, kind); err != nil {
+			return err
+		}
+		return utils.ErrSkipEntry
+	}
+	if err := a.count(rel, info.Size()); err != nil {
+		return err
+	}
+	if err := a.backup(rel); err != nil {
+		return err
+	}
+	sum, err := HashFile(filepath.Join(e.src, relPath))
+	if err != nil {
+		return err
+	}
+	a.files.add(rel, info.Mode(), sum)
+	a.raw[filepath.ToSlash(rel)] = true
+	return nil
+}
+
+// streamRaw copies a raw directory file by file to a sink that is not a
+// directory.
+func (a *applier) streamRaw(e entry) (utils.CopyStats, error) {
+	var stats utils.CopyStats
+	err := filepath.WalkDir(e.src, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		relPath, err := filepath.Rel(e.src, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path for '%s': %w", path, err)
+		}
+		rel := filepath.Join(e.rel, relPath)
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat '%s': %w", path, err)
+		}
+		if info.IsDir() {
+			if !d.IsDir() {
+				return fmt.Errorf("symlink '%s' points to a directory, which can't be followed", path)
+			}
+			if relPath != "." {
+				stats.Dirs++
+				a.dirs = append(a.dirs, filepath.ToSlash(rel))
+			}
+			return a.sink.Mkdir(filepath.ToSlash(rel), info.Mode())
+		}
+		if kind := irregularType(info.Mode()); kind != "" {
+			return a.skipIrregular(path, rel, kind)
+		}
+
+		stats.Files++
+		stats.Bytes += info.Size()
+		a.raw[filepath.ToSlash(rel)] = true
+		w, err := a.create(rel, info.Mode(), info.Size())
+		if err != nil {
+			return err
+		}
+		if err = utils.CopyFileTo(w, path); err != nil {
+			_ = w.Close()
+			return err
+		}
+		return w.Close()
+	})
+	return stats, err
+}
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyRaw(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile:                    "raw: [\"{{.name}}-assets\"]\nignore: [\"**/*.bak\"]\n",
+		"{{.name}}-assets/app.js.tmpl":  "{{not rendered}}",
+		"{{.name}}-assets/{{.x}}/a.txt": "a",
+		"{{.name}}-assets/fonts/x.bak":  "kept",
+		"README.md.tmpl":                "# {{.name}}",
+	})
+	assets := filepath.Join(templateDir, "{{.name}}-assets")
+	if err := os.WriteFile(filepath.Join(assets, "run.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Symlink("run.sh", filepath.Join(assets, "link.sh")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	data := map[string]any{"name": "demo"}
+	want := map[string]string{
+		"README.md":                "# demo",
+		"demo-assets/app.js.tmpl":  "{{not rendered}}",
+		"demo-assets/{{.x}}/a.txt": "a",
+		"demo-assets/fonts/x.bak":  "kept",
+		"demo-assets/run.sh":       "#!/bin/sh\n",
+		"demo-assets/link.sh":      "#!/bin/sh\n",
+	}
+
+	t.Run("directory", func(t *testing.T) {
+		outputDir := t.TempDir()
+		var out bytes.Buffer
+		if err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: &out}); err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		files := readFiles(t, outputDir)
+		for path, content := range want {
+			if files[path] != content {
+				t.Errorf("Expected '%s' to hold %q, got %q", path, content, files[path])
+			}
+		}
+		if _, ok := files[BaseDir+"/README.md"]; !ok {
+			t.Errorf("Expected a base for README.md")
+		}
+		if _, ok := files[BaseDir+"/demo-assets/run.sh"]; ok {
+			t.Errorf("Expected no base for raw files")
+		}
+		info, err := os.Lstat(filepath.Join(outputDir, "demo-assets", "run.sh"))
+		if err != nil || info.Mode().Perm() != 0755 {
+			t.Errorf("Expected run.sh to keep mode 0755, got %v (%v)", info, err)
+		}
+		log := out.String()
+		summary := "🗂️  Copying raw directory: demo-assets (5 files, 2 directories, 41 B)\n"
+		if !strings.Contains(log, summary) {
+			t.Errorf("Expected %q in the log, got:\n%s", summary, log)
+		}
+		if strings.Contains(log, "app.js") {
+			t.Errorf("Expected raw files not to be logged one by one, got:\n%s", log)
+		}
+
+		provenance, err := LoadProvenance(outputDir)
+		if err != nil {
+			t.Fatalf("LoadProvenance failed: %v", err)
+		}
+		if len(provenance.Files) != 6 {
+			t.Errorf("Expected the raw files in the manifest, got %v", provenance.Files)
+		}
+		checks, err := CheckFiles(outputDir, provenance.Files)
+		if err != nil {
+			t.Fatalf("CheckFiles failed: %v", err)
+		}
+		for _, check := range checks {
+			if check.State != FileUnchanged {
+				t.Errorf("Expected '%s' unchanged, got %s", check.Path, check.State)
+			}
+		}
+		for _, file := range provenance.Files {
+			if file.Template != templateDir {
+				t.Errorf("Expected '%s' to be generated by the template, got '%s'", file.Path, file.Template)
+			}
+		}
+
+		// Applying again replaces the raw files, backing them up.
+		if err = os.WriteFile(filepath.Join(outputDir, "demo-assets", "run.sh"), []byte("mine"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		backupDir := filepath.Join(t.TempDir(), "backup")
+		err = Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: io.Discard,
+			BackupDir: backupDir})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		files = readFiles(t, outputDir)
+		if files["demo-assets/run.sh"] != "#!/bin/sh\n" {
+			t.Errorf("Expected run.sh to be replaced, got %q", files["demo-assets/run.sh"])
+		}
+		if backups := readFiles(t, backupDir); backups["demo-assets/run.sh"] != "mine" {
+			t.Errorf("Expected run.sh to be backed up, got %v", backups)
+		}
+	})
+
+	t.Run("archive", func(t *testing.T) {
+		archive := filepath.Join(t.TempDir(), "out.tar")
+		if err := Apply(Options{TemplatePath: templateDir, OutputDir: archive, Data: data, Out: io.Discard}); err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		f, err := os.Open(archive)
+		if err != nil {
+			t.Fatalf("Failed to open archive: %v", err)
+		}
+		defer f.Close()
+		entries := make(map[string]string)
+		tr := tar.NewReader(f)
+		for {
+			header, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Failed to read archive: %v", err)
+			}
+			content, _ := io.ReadAll(tr)
+			entries[strings.TrimSuffix(header.Name, "/")] = string(content)
+		}
+		for path, content := range want {
+			if got, ok := entries[path]; !ok || got != content {
+				t.Errorf("Expected '%s' to hold %q in the archive, got %q", path, content, got)
+			}
+		}
+		if _, ok := entries["demo-assets/fonts"]; !ok {
+			t.Errorf("Expected the raw directories in the archive, got %v", entries)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		outputDir := filepath.Join(t.TempDir(), "out")
+		var out bytes.Buffer
+		err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: &out, DryRun: true})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		if !strings.Contains(out.String(), "(5 files, 2 directories, 41 B)") {
+			t.Errorf("Expected the raw summary, got:\n%s", out.String())
+		}
+		if _, err = os.Stat(outputDir); !os.IsNotExist(err) {
+			t.Errorf("Expected nothing written, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// HelperFunc describes a function templates can call.
+type HelperFunc struct {
+	Name string
+	// Signature is the Go signature of the function under its template name,
+	// such as "snake(string) string".
+	Signature   string
+	Description string
+}
+
+// helperDocs describes the functions of helperFunc for the reference.
+//
+//nolint:gochecknoglobals // documentation of helperFunc
+var helperDocs = map[string]string{
+	"snake":  `Converts a string to snake_case: {{snake "HTTPServer"}} gives http_server.`,
+	"usnake": `Converts a string to UPPER_SNAKE_CASE: {{usnake "MyProject"}} gives MY_PROJECT.`,
+	"kebab":  `Converts a string to kebab-case: {{kebab "MyProject"}} gives my-project.`,
+	"camel": `Converts a string to UpperCamelCase, with acronyms in capitals: {{camel "user_id"}} gives ` +
+		`UserID.`,
+	"lcamel": `Converts a string to lowerCamelCase: {{lcamel "my_project"}} gives myProject.`,
+	"title":  `Converts a string to Title Case: {{title "grpc_server_url"}} gives gRPC Server URL.`,
+	DataFileFunc: `Parses a JSON or YAML file of the template, by its path from the template root, and ` +
+		`returns its mapping: {{index (dataFile "licenses.yaml") .license}}. Only files the template holds ` +
+		`can be read, and each is parsed once per apply.`,
+	TemplateGlobFunc: `Lists the files of the template matching a pattern with the syntax of ignore, "**" ` +
+		`included, as sorted slash-separated paths from the template root: {{range templateGlob "scripts/*.sh"}}.`,
+	TemplateFileExistsFunc: `Tells whether the template holds a file or directory, by its path from the ` +
+		`template root: {{if templateFileExists "Dockerfile.tmpl"}}.`,
+}
+
+// HelperFuncs returns the functions available to templates, sorted by name.
+// It lists what the renderer actually registers, so a new helper shows up
+// even before it is described.
+func HelperFuncs() []HelperFunc {
+	funcs := make([]HelperFunc, 0, len(helperFunc))
+	for _, name := range slices.Sorted(maps.Keys(helperFunc)) {
+		signature := reflect.TypeOf(helperFunc[name]).String()
+		funcs = append(funcs, HelperFunc{
+			Name:        name,
+			Signature:   name + strings.TrimPrefix(signature, "func"),
+			Description: helperDocs[name],
+		})
+	}
+	return funcs
+}
+
+// MetadataField describes a key of the template.yaml file.
+type MetadataField struct {
+	// Key is the dotted path of the key. "<name>" stands for a key chosen by
+	// the template author.
+	Key         string
+	Type        string
+	Description string
+}
+
+// MetadataSchema returns the reference of the keys of the template.yaml
+// file, in the order they are usually written.
+func MetadataSchema() []MetadataField {
+	return []MetadataField{
+		{"name", "string", "Name of the template, such as go/service. Kept in sync by mold copy and " +
+			"mold rename, and recorded in the provenance of generated projects."},
+		{"description", "string", "What the template generates."},
+		{"version", "string", "Version of the template, recorded in the provenance of generated projects."},
+		{"extends", "string", "Parent template applied before this one. A relative path is resolved against " +
+			"the directory holding the template."},
+		{"prompts", "mapping", "Inputs the template expects, in order, from name to prompt."},
+		{"prompts.<name>.description", "string", "What the input is for."},
+		{"prompts.<name>.type", "string", "Type the value must have: string, int, float, bool, confirm, a yes " +
+			"or no answer, list, map, enum, select or multiselect, a list of choices. Empty accepts any value."},
+		{"prompts.<name>.choices", "list", "Allowed values of an enum, select or multiselect prompt, each a " +
+			"value or a mapping with a value and a label."},
+		{"prompts.<name>.secret", "bool", "Masks the value in messages and in the provenance."},
+		{"prompts.<name>.pattern", "string", "Go regular expression string values must match."},
+		{"prompts.<name>.minLength", "int", "Minimum number of characters of string values."},
+		{"prompts.<name>.maxLength", "int", "Maximum number of characters of string values."},
+		{"prompts.<name>.min", "number", "Minimum of numeric values."},
+		{"prompts.<name>.max", "number", "Maximum of numeric values."},
+		{"prompts.<name>.message", "string", "Shown instead of the broken rule when a value is rejected."},
+		{"prompts.<name>.when", "string", "Template expression, such as '{{eq .database \"postgres\"}}', " +
+			"deciding whether the prompt is asked. It can use the prompts declared before it and the data. " +
+			"When it renders empty or false, the key keeps its default or stays absent."},
+		{"defaults", "mapping", "Data values used when the data doesn't provide them."},
+		{"computed", "mapping", "Data values computed from the rest of the data, from key to template " +
+			"expression, such as \"{{snake .service}}_db\". They can use each other, and the data overrides them."},
+		{"formatters", "mapping", "Commands run on the generated files, from a glob relative to the output " +
+			"root to the command and its arguments."},
+		{"ignore", "list", "Globs, relative to the template root, of files and directories never generated."},
+		{"raw", "list", "Globs, relative to the template root, of directories copied verbatim as a whole. Their " +
+			"files are neither rendered nor checked, and the paths inside them keep any placeholders."},
+		{"acronyms", "list", "Acronyms the case helpers keep as units, on top of API, HTTP, ID, URL, gRPC " +
+			"and other common ones, spelled as camel and title case write them, such as K8s or OAuth2."},
+		{"deriveCases", "bool", "Adds the snake, usnake, kebab, camel and lcamel variants of every top-level " +
+			"string of the data under keys with those suffixes, such as service_name_camel. Keys already in " +
+			"the data win."},
+		{"minMoldVersion", "string", "Oldest mold version the template works with, such as 0.9.0. An older " +
+			"mold fails to load the template, asking to upgrade."},
+		{"notices", "list", "Templates rendered with the data once the project is generated and shown after " +
+			"the summary, such as \"Run make bootstrap in {{.name}} next.\". One rendering empty is left out, " +
+			"and one failing is warned about."},
+		{"header", "mapping", "Headers, such as a license notice, prepended to the generated files, from a " +
+			"glob relative to the output root to a template rendered with the data. The longest matching glob " +
+			"wins, and a file already starting with its header is left alone."},
+		{"header.<glob>.template", "string", "Text of the header. The header can be given as this string alone."},
+		{"header.<glob>.partial", "string", "File of the _partials directory holding the text of the header " +
+			"instead, such as license.tmpl."},
+		{"header.<glob>.commentStyle", "string", "Comment style of the header: //, #, --, /* */ or <!-- -->. " +
+			"Empty infers it from the file extension."},
+		{"headerCopied", "list", "Globs, relative to the output root, of the copied files that get their " +
+			"header too. Other copied files are left untouched."},
+		{"deprecated", "string", "Marks the template as deprecated, with the message shown when it is applied, " +
+			"such as \"Use go-service-v2 instead.\""},
+		{"requires", "mapping", "Template a component needs the project to be generated from, checked " +
+			"against the project's provenance by mold add."},
+		{"requires.template", "string", "Name of the template, as in its template.yaml."},
+		{"requires.version", "string", "Constraint on the version of the template, such as ^1.2. Empty " +
+			"accepts any version."},
+	}
+}
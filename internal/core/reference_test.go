@@ -0,0 +1,49 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHelperFuncs(t *testing.T) {
+	funcs := HelperFuncs()
+	if len(funcs) != len(helperFunc) {
+		t.Fatalf("Expected every helper to be listed, got %v", funcs)
+	}
+	for i, f := range funcs {
+		if i > 0 && funcs[i-1].Name >= f.Name {
+			t.Errorf("Expected the helpers sorted by name, got %s before %s", funcs[i-1].Name, f.Name)
+		}
+		if f.Description == "" {
+			t.Errorf("Expected helper '%s' to be described in helperDocs", f.Name)
+		}
+	}
+	want := HelperFunc{Name: "snake", Signature: "snake(string) string", Description: helperDocs["snake"]}
+	if funcs[4] != want {
+		t.Errorf("Expected %+v, got %+v", want, funcs[4])
+	}
+}
+
+func TestMetadataSchema(t *testing.T) {
+	documented := make(map[string]bool)
+	for _, field := range MetadataSchema() {
+		documented[field.Key] = true
+	}
+	// Every key the template.yaml decoder reads is documented.
+	var keys []string
+	metadata := reflect.TypeFor[Metadata]()
+	for i := range metadata.NumField() {
+		keys = append(keys, metadata.Field(i).Tag.Get("yaml"))
+	}
+	prompt := reflect.TypeFor[Prompt]()
+	for i := range prompt.NumField() {
+		if tag := prompt.Field(i).Tag.Get("yaml"); tag != "-" {
+			keys = append(keys, "prompts.<name>."+tag)
+		}
+	}
+	for _, key := range keys {
+		if !documented[key] {
+			t.Errorf("Expected '%s' to be documented in MetadataSchema", key)
+		}
+	}
+}
@@ -0,0 +1,434 @@
+package core
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/0m3kk/mold/internal/utils"
+)
+
+// RegistrySchema is the version of the registry index format read by this
+// release.
+const RegistrySchema = 1
+
+// RegistryFile is the index at the root of a git repository registered as a
+// registry.
+const RegistryFile = "registry.yaml"
+
+// scpLikeURL matches the scp-like syntax of git URLs, such as
+// git@github.com:acme/templates.git.
+//
+//nolint:gochecknoglobals // compiled once
+var scpLikeURL = regexp.MustCompile(`^[\w.-]+@[\w.-]+:[^/]`)
+
+// gitCommit matches an abbreviated or full commit hash.
+//
+//nolint:gochecknoglobals // compiled once
+var gitCommit = regexp.MustCompile(`^[0-9a-f]{7,64}$`)
+
+// RegistryIndex is a registry.yaml file, an index of published templates.
+type RegistryIndex struct {
+	// Schema is the version of the format.
+	Schema int `yaml:"schema"`
+	// Templates maps template names, such as "go/service", to where they
+	// are published.
+	Templates map[string]RegistryEntry `yaml:"templates"`
+}
+
+// RegistryEntry is a template published in a registry index.
+type RegistryEntry struct {
+	// Name is the name of the template, its key in the index.
+	Name string `yaml:"-"`
+	// Registry is the name of the registry listing the template.
+	Registry string `yaml:"-"`
+	// URL is the git repository, or the .tar.gz, .tgz, .tar or .zip
+	// archive, holding the template.
+	URL string `yaml:"url"`
+	// Ref is the branch, tag or commit of a git repository. Empty means its
+	// default branch.
+	Ref string `yaml:"ref"`
+	// Path is the slash-separated directory of the template inside the
+	// repository or archive. Empty means its root.
+	Path string `yaml:"path"`
+	// Description tells users what the template generates.
+	Description string `yaml:"description"`
+	// Versions maps the versions of the template applied with name@version
+	// to where they are published, when that isn't the git tag of the same
+	// name. Empty fields default to those of the entry.
+	Versions map[string]RegistryVersion `yaml:"versions"`
+	// Version is the version the entry was pinned to by Pin.
+	Version string `yaml:"-"`
+}
+
+// RegistryVersion is where a version of a registry template is published.
+type RegistryVersion struct {
+	URL  string `yaml:"url"`
+	Ref  string `yaml:"ref"`
+	Path string `yaml:"path"`
+}
+
+// ParseRegistryIndex decodes and checks the content of a registry index read
+// from source.
+func ParseRegistryIndex(content []byte, source string) (*RegistryIndex, error) {
+	index := &RegistryIndex{}
+	if err := yaml.Unmarshal(content, index); err != nil {
+		return nil, fmt.Errorf("failed to parse registry index '%s': %w", source, err)
+	}
+	if index.Schema == 0 {
+		return nil, fmt.Errorf("invalid registry index '%s': missing schema version", source)
+	}
+	if index.Schema > RegistrySchema {
+		return nil, fmt.Errorf("registry index '%s' has schema version %d, this version of mold reads up to %d",
+			source, index.Schema, RegistrySchema)
+	}
+	for _, name := range slices.Sorted(maps.Keys(index.Templates)) {
+		entry := index.Templates[name]
+		entry.Name = name
+		if err := entry.check(); err != nil {
+			return nil, fmt.Errorf("invalid template '%s' in registry index '%s': %w", name, source, err)
+		}
+		index.Templates[name] = entry
+	}
+	return index, nil
+}
+
+// check reports mistakes in a registry entry.
+func (e RegistryEntry) check() error {
+	if _, err := TemplatePath("", e.Name); err != nil {
+		return err
+	}
+	if e.URL == "" {
+		return fmt.Errorf("missing url")
+	}
+	if err := CheckTemplateURL(e.URL); err != nil {
+		return err
+	}
+	if err := checkLocation(e.URL, e.Ref, e.Path); err != nil {
+		return err
+	}
+	for _, version := range slices.Sorted(maps.Keys(e.Versions)) {
+		v := e.Versions[version]
+		url := cmp.Or(v.URL, e.URL)
+		if v.URL != "" {
+			if err := CheckTemplateURL(v.URL); err != nil {
+				return fmt.Errorf("version '%s': %w", version, err)
+			}
+		} else if IsArchiveURL(url) {
+			return fmt.Errorf("version '%s': missing url, an archive holds a single version", version)
+		}
+		if err := checkLocation(url, v.Ref, v.Path); err != nil {
+			return fmt.Errorf("version '%s': %w", version, err)
+		}
+	}
+	return nil
+}
+
+// checkLocation reports a ref or path that doesn't apply to a URL.
+func checkLocation(url, ref, path string) error {
+	if ref != "" && IsArchiveURL(url) {
+		return fmt.Errorf("ref '%s' only applies to git repositories, not to the archive '%s'", ref, url)
+	}
+	if err := checkRef(ref); err != nil {
+		return err
+	}
+	if path != "" && !filepath.IsLocal(filepath.FromSlash(path)) {
+		return fmt.Errorf("invalid path '%s': expected a directory inside the repository", path)
+	}
+	return nil
+}
+
+// checkRef rejects a ref that is neither a commit hash nor a branch or tag
+// name git accepts, as checked by git check-ref-format. A ref starting with
+// '-' would be read by git as an option, such as --upload-pack running a
+// command.
+func checkRef(ref string) error {
+	if ref == "" || gitCommit.MatchString(ref) {
+		return nil
+	}
+	special := func(r rune) bool { return r < ' ' || r == 0x7f || strings.ContainsRune(" ~^:?*[\\", r) }
+	invalid := ref == "@" || strings.HasPrefix(ref, "-") || strings.HasSuffix(ref, ".") ||
+		strings.Contains(ref, "..") || strings.Contains(ref, "@{") || strings.ContainsFunc(ref, special)
+	for component := range strings.SplitSeq(ref, "/") {
+		invalid = invalid || component == "" || strings.HasPrefix(component, ".") || strings.HasSuffix(component, ".lock")
+	}
+	if invalid {
+		return fmt.Errorf("invalid ref '%s': expected a branch, a tag or a commit hash", ref)
+	}
+	return nil
+}
+
+// Entries returns the templates of the index sorted by name.
+func (i *RegistryIndex) Entries() []RegistryEntry {
+	entries := make([]RegistryEntry, 0, len(i.Templates))
+	for _, name := range slices.Sorted(maps.Keys(i.Templates)) {
+		entries = append(entries, i.Templates[name])
+	}
+	return entries
+}
+
+// CheckTemplateURL accepts the URLs templates are fetched from: git
+// repositories over https, ssh, git or file URLs, or in the scp-like
+// syntax, and archives over https or file URLs.
+func CheckTemplateURL(rawURL string) error {
+	if scpLikeURL.MatchString(rawURL) && !IsArchiveURL(rawURL) {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url '%s': %w", rawURL, err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "https", "file":
+		return nil
+	case "ssh", "git":
+		if IsArchiveURL(rawURL) {
+			return fmt.Errorf("unsupported url scheme '%s' for the archive '%s'", u.Scheme, rawURL)
+		}
+		return nil
+	case "http":
+		return fmt.Errorf("refusing to fetch '%s' over plain http, use https", rawURL)
+	default:
+		return fmt.Errorf("unsupported url '%s', expected a git repository or an archive URL", rawURL)
+	}
+}
+
+// CheckRegistryURL accepts the locations registry indexes are read from: an
+// https URL, a git repository holding a registry.yaml file at its root, or a
+// local path.
+func CheckRegistryURL(location string) error {
+	if isGitURL(location) {
+		return nil
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return fmt.Errorf("invalid url '%s': %w", location, err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "https", "":
+		return nil
+	case "http":
+		return fmt.Errorf("refusing to fetch '%s' over plain http, use https", location)
+	default:
+		return fmt.Errorf("unsupported url '%s', expected an https URL, a git repository or a local path", location)
+	}
+}
+
+// isGitURL reports whether a registry location is a git repository: an ssh,
+// git or scp-like URL, or an https or file URL ending in '.git'.
+func isGitURL(location string) bool {
+	if scpLikeURL.MatchString(location) {
+		return true
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "ssh", "git":
+		return true
+	case "https", "file":
+		return strings.HasSuffix(u.Path, ".git")
+	default:
+		return false
+	}
+}
+
+// IsArchiveURL reports whether a template URL points to an archive rather
+// than a git repository.
+func IsArchiveURL(rawURL string) bool {
+	return archiveURLFormat(rawURL) != ""
+}
+
+// archiveURLFormat returns the archive format implied by the extension of
+// the path of a URL, ignoring its query.
+func archiveURLFormat(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Path != "" {
+		return ArchiveFormat(u.Path)
+	}
+	return ArchiveFormat(rawURL)
+}
+
+// Registry is the index of a registry source, as loaded by LoadRegistries.
+type Registry struct {
+	Source RegistrySource
+	Index  *RegistryIndex
+}
+
+// LoadRegistries fetches the index of every registry source, in order. The
+// index fetched last is kept in cacheDir and used, with a warning, when the
+// source can't be fetched. A registry with neither is skipped with a
+// warning, so listing and applying local templates works offline.
+func LoadRegistries(sources []RegistrySource, cacheDir string, opts FetchOptions, warn io.Writer) []Registry {
+	registries := make([]Registry, 0, len(sources))
+	for _, source := range sources {
+		index, err := loadRegistry(source, cacheDir, opts, warn)
+		if err != nil {
+			fmt.Fprintf(warn, "⚠️  Skipping registry '%s': %v\n", source.Name, err)
+			continue
+		}
+		registries = append(registries, Registry{Source: source, Index: index})
+	}
+	return registries
+}
+
+// FindRegistryTemplate returns the entry of the first registry publishing
+// a template with the given name.
+func FindRegistryTemplate(registries []Registry, name string) (RegistryEntry, bool) {
+	for _, registry := range registries {
+		if entry, ok := registry.Index.Templates[name]; ok {
+			return entry, true
+		}
+	}
+	return RegistryEntry{}, false
+}
+
+// RegistryCachePath returns the path of the cached index of a registry.
+func RegistryCachePath(cacheDir, name string) string {
+	return filepath.Join(cacheDir, "registries", name+".yaml")
+}
+
+// FetchRegistryIndex fetches and checks the index of a registry source and
+// replaces its cached index.
+func FetchRegistryIndex(source RegistrySource, cacheDir string, opts FetchOptions) (*RegistryIndex, error) {
+	content, index, err := fetchIndex(source, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = writeCache(RegistryCachePath(cacheDir, source.Name), content); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// CachedRegistryIndex reads the cached index of a registry and returns it
+// with the time it was fetched. The error wraps fs.ErrNotExist when the
+// registry was never fetched.
+func CachedRegistryIndex(cacheDir, name string) (*RegistryIndex, time.Time, error) {
+	path := RegistryCachePath(cacheDir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("no cached index for registry '%s': %w", name, err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to read the cached index '%s': %w", path, err)
+	}
+	index, err := ParseRegistryIndex(content, path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	index.setRegistry(name)
+	return index, info.ModTime(), nil
+}
+
+// RemoveRegistryCache deletes the cached index of a registry, if any.
+func RemoveRegistryCache(cacheDir, name string) error {
+	path := RegistryCachePath(cacheDir, name)
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove the cached index '%s': %w", path, err)
+	}
+	return nil
+}
+
+// loadRegistry fetches the index of a registry source and caches it, or
+// falls back to the cached index.
+func loadRegistry(source RegistrySource, cacheDir string, opts FetchOptions, warn io.Writer) (*RegistryIndex, error) {
+	content, index, err := fetchIndex(source, opts)
+	if err != nil {
+		cached, fetched, cacheErr := CachedRegistryIndex(cacheDir, source.Name)
+		if errors.Is(cacheErr, fs.ErrNotExist) {
+			return nil, err
+		}
+		if cacheErr != nil {
+			return nil, cacheErr
+		}
+		fmt.Fprintf(warn, "⚠️  Using the index of registry '%s' cached on %s: %v\n",
+			source.Name, fetched.Format("2006-01-02 15:04"), err)
+		return cached, nil
+	}
+	if err = writeCache(RegistryCachePath(cacheDir, source.Name), content); err != nil {
+		fmt.Fprintf(warn, "⚠️  Failed to cache the index of registry '%s': %v\n", source.Name, err)
+	}
+	return index, nil
+}
+
+// fetchIndex fetches and parses the index of a registry source.
+func fetchIndex(source RegistrySource, opts FetchOptions) ([]byte, *RegistryIndex, error) {
+	content, err := fetchRegistry(source.URL, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	index, err := ParseRegistryIndex(content, source.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+	index.setRegistry(source.Name)
+	return content, index, nil
+}
+
+// setRegistry records the registry listing the entries of the index.
+func (i *RegistryIndex) setRegistry(name string) {
+	for key, entry := range i.Templates {
+		entry.Registry = name
+		i.Templates[key] = entry
+	}
+}
+
+// fetchRegistry reads a registry index from an https URL, the root of a git
+// repository or a local path.
+func fetchRegistry(location string, opts FetchOptions) ([]byte, error) {
+	if isGitURL(location) {
+		dir, err := os.MkdirTemp("", "mold-registry-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create a temporary directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+		if err = fetchGit(location, "", dir); err != nil {
+			return nil, fmt.Errorf("failed to fetch registry '%s': %w", location, err)
+		}
+		location = filepath.Join(dir, RegistryFile)
+	}
+	if !IsDataURL(location) {
+		content, err := os.ReadFile(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read registry index '%s': %w", location, err)
+		}
+		return content, nil
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry url '%s': %w", location, err)
+	}
+	if !strings.EqualFold(u.Scheme, "https") {
+		return nil, fmt.Errorf("refusing to fetch registry index '%s' over plain http, use https", location)
+	}
+	_, content, err := fetchURL(u, opts, "application/yaml, */*;q=0.1")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch registry index '%s': %w", location, err)
+	}
+	return content, nil
+}
+
+// writeCache replaces a file of the cache directory.
+func writeCache(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), utils.DirMode); err != nil {
+		return fmt.Errorf("failed to create cache directory '%s': %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		return fmt.Errorf("failed to write cache file '%s': %w", path, err)
+	}
+	return nil
+}
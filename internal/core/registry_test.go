@@ -0,0 +1,281 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testRegistryIndex = `schema: 1
+templates:
+  go/service:
+    url: https://example.com/acme/templates.git
+    ref: v1.2.0
+    path: go/service
+    description: A Go service
+  web:
+    url: https://example.com/acme/web/archive/v2.tar.gz
+`
+
+func TestParseRegistryIndex(t *testing.T) {
+	index, err := ParseRegistryIndex([]byte(testRegistryIndex), "registry.yaml")
+	if err != nil {
+		t.Fatalf("ParseRegistryIndex failed: %v", err)
+	}
+	entries := index.Entries()
+	if len(entries) != 2 || entries[0].Name != "go/service" || entries[1].Name != "web" {
+		t.Fatalf("Expected the entries go/service and web, got %+v", entries)
+	}
+	want := RegistryEntry{
+		Name:        "go/service",
+		URL:         "https://example.com/acme/templates.git",
+		Ref:         "v1.2.0",
+		Path:        "go/service",
+		Description: "A Go service",
+	}
+	if !reflect.DeepEqual(entries[0], want) {
+		t.Errorf("Expected %+v, got %+v", want, entries[0])
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "not yaml", content: "schema: [", wantErr: "failed to parse registry index"},
+		{name: "missing schema", content: "templates: {}", wantErr: "missing schema version"},
+		{name: "newer schema", content: "schema: 2", wantErr: "has schema version 2, this version of mold reads up to 1"},
+		{
+			name:    "invalid name",
+			content: "schema: 1\ntemplates:\n  ../escape:\n    url: https://example.com/t.git",
+			wantErr: "invalid template name '../escape'",
+		},
+		{name: "missing url", content: "schema: 1\ntemplates:\n  web: {}", wantErr: "missing url"},
+		{
+			name:    "plain http",
+			content: "schema: 1\ntemplates:\n  web:\n    url: http://example.com/t.git",
+			wantErr: "over plain http",
+		},
+		{
+			name:    "unsupported scheme",
+			content: "schema: 1\ntemplates:\n  web:\n    url: ftp://example.com/t.tar.gz",
+			wantErr: "unsupported url",
+		},
+		{
+			name:    "ref of an archive",
+			content: "schema: 1\ntemplates:\n  web:\n    url: https://example.com/t.zip\n    ref: main",
+			wantErr: "only applies to git repositories",
+		},
+		{
+			name:    "version of an archive without url",
+			content: "schema: 1\ntemplates:\n  web:\n    url: https://example.com/t.zip\n    versions:\n      v1: {}",
+			wantErr: "version 'v1': missing url",
+		},
+		{
+			name: "invalid version url",
+			content: "schema: 1\ntemplates:\n  web:\n    url: https://example.com/t.git\n" +
+				"    versions:\n      v1: {url: ftp://x/t.zip}",
+			wantErr: "version 'v1': unsupported url",
+		},
+		{
+			name: "ref read as an option",
+			content: "schema: 1\ntemplates:\n  web:\n    url: file:///tmp/t.git\n" +
+				"    ref: '--upload-pack=touch /tmp/pwned'",
+			wantErr: "invalid ref '--upload-pack=touch /tmp/pwned'",
+		},
+		{
+			name: "invalid version ref",
+			content: "schema: 1\ntemplates:\n  web:\n    url: https://example.com/t.git\n" +
+				"    versions:\n      v1: {ref: 'main..dev'}",
+			wantErr: "version 'v1': invalid ref 'main..dev'",
+		},
+		{
+			name:    "path outside the repository",
+			content: "schema: 1\ntemplates:\n  web:\n    url: https://example.com/t.git\n    path: ../other",
+			wantErr: "invalid path '../other'",
+		},
+	}
+	for _, tt := range tests {
+		_, err := ParseRegistryIndex([]byte(tt.content), "registry.yaml")
+		if err == nil || !contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error containing %q, got: %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestCheckTemplateURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://example.com/acme/templates.git":  true,
+		"https://example.com/acme/t.tar.gz?x=1":   true,
+		"file:///srv/templates.git":               true,
+		"ssh://git@example.com/acme/t.git":        true,
+		"git://example.com/acme/t.git":            true,
+		"git@example.com:acme/templates.git":      true,
+		"ssh://git@example.com/acme/t.tar.gz":     false,
+		"http://example.com/acme/templates.git":   false,
+		"ftp://example.com/acme/templates.tar.gz": false,
+		"templates.git":                           false,
+	}
+	for rawURL, want := range tests {
+		if err := CheckTemplateURL(rawURL); (err == nil) != want {
+			t.Errorf("CheckTemplateURL(%q) = %v; want accepted %v", rawURL, err, want)
+		}
+	}
+}
+
+func TestCheckRegistryURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://example.com/registry.yaml":     true,
+		"https://example.com/acme/registry.git": true,
+		"git@example.com:acme/registry.git":     true,
+		"ssh://git@example.com/acme/registry":   true,
+		"file:///srv/registry.git":              true,
+		"./registry.yaml":                       true,
+		"/etc/mold/registry.yaml":               true,
+		"http://example.com/registry.yaml":      false,
+		"ftp://example.com/registry.yaml":       false,
+		"file:///srv/registry.yaml":             false,
+	}
+	for location, want := range tests {
+		if err := CheckRegistryURL(location); (err == nil) != want {
+			t.Errorf("CheckRegistryURL(%q) = %v; want accepted %v", location, err, want)
+		}
+	}
+}
+
+func TestRegistryCache(t *testing.T) {
+	dir := t.TempDir()
+	local := filepath.Join(dir, "registry.yaml")
+	if err := os.WriteFile(local, []byte(testRegistryIndex), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cacheDir := filepath.Join(dir, "cache")
+	source := RegistrySource{Name: "acme", URL: local}
+
+	if _, _, err := CachedRegistryIndex(cacheDir, "acme"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected no cached index before fetching, got: %v", err)
+	}
+	if _, err := FetchRegistryIndex(source, cacheDir, FetchOptions{}); err != nil {
+		t.Fatalf("FetchRegistryIndex failed: %v", err)
+	}
+	index, fetched, err := CachedRegistryIndex(cacheDir, "acme")
+	if err != nil {
+		t.Fatalf("CachedRegistryIndex failed: %v", err)
+	}
+	if len(index.Templates) != 2 || index.Templates["web"].Registry != "acme" || fetched.IsZero() {
+		t.Errorf("Expected the cached index of acme, got %+v fetched %v", index, fetched)
+	}
+	if err = RemoveRegistryCache(cacheDir, "acme"); err != nil {
+		t.Fatalf("RemoveRegistryCache failed: %v", err)
+	}
+	if _, _, err = CachedRegistryIndex(cacheDir, "acme"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected the cached index to be removed, got: %v", err)
+	}
+	if err = RemoveRegistryCache(cacheDir, "acme"); err != nil {
+		t.Errorf("Expected removing a missing cache to succeed, got: %v", err)
+	}
+
+	if err = os.WriteFile(local, []byte("schema: 7"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = FetchRegistryIndex(source, cacheDir, FetchOptions{}); err == nil ||
+		!contains(err.Error(), "has schema version 7") {
+		t.Errorf("Expected an invalid index to be refused, got: %v", err)
+	}
+}
+
+func TestFetchRegistryIndexGit(t *testing.T) {
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, RegistryFile), []byte(testRegistryIndex), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, "init", "--quiet")
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "--quiet", "-m", "index")
+	source := RegistrySource{Name: "acme", URL: "file://" + filepath.ToSlash(repo) + "/.git"}
+	index, err := FetchRegistryIndex(source, t.TempDir(), FetchOptions{})
+	if err != nil {
+		t.Fatalf("FetchRegistryIndex failed: %v", err)
+	}
+	if _, ok := index.Templates["go/service"]; !ok {
+		t.Errorf("Expected go/service in the index of the repository, got %+v", index.Templates)
+	}
+}
+
+func TestLoadRegistries(t *testing.T) {
+	online := true
+	mux := http.NewServeMux()
+	mux.HandleFunc("/registry.yaml", func(w http.ResponseWriter, _ *http.Request) {
+		if !online {
+			http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(testRegistryIndex))
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+	opts := FetchOptions{transport: server.Client().Transport}
+
+	local := filepath.Join(t.TempDir(), "registry.yaml")
+	content := "schema: 1\ntemplates:\n  web:\n    url: file:///srv/web.git\n"
+	if err := os.WriteFile(local, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cacheDir := t.TempDir()
+	sources := []RegistrySource{
+		{Name: "acme", URL: server.URL + "/registry.yaml"},
+		{Name: "local", URL: local},
+		{Name: "missing", URL: filepath.Join(t.TempDir(), "missing.yaml")},
+	}
+
+	var warn bytes.Buffer
+	registries := LoadRegistries(sources, cacheDir, opts, &warn)
+	if len(registries) != 2 {
+		t.Fatalf("Expected the registries acme and local, got %d", len(registries))
+	}
+	if !contains(warn.String(), "Skipping registry 'missing'") {
+		t.Errorf("Expected a warning about the missing registry, got: %s", warn.String())
+	}
+	entry, ok := FindRegistryTemplate(registries, "web")
+	if !ok || entry.Registry != "acme" {
+		t.Errorf("Expected web from the first registry, acme, got %+v", entry)
+	}
+	if _, err := os.Stat(RegistryCachePath(cacheDir, "acme")); err != nil {
+		t.Errorf("Expected the index of acme to be cached: %v", err)
+	}
+
+	t.Run("cached index when offline", func(t *testing.T) {
+		online = false
+		defer func() { online = true }()
+		var warn bytes.Buffer
+		registries := LoadRegistries(sources[:1], cacheDir, opts, &warn)
+		if len(registries) != 1 || len(registries[0].Index.Templates) != 2 {
+			t.Fatalf("Expected the cached index of acme, got %+v", registries)
+		}
+		if !contains(warn.String(), "Using the index of registry 'acme' cached on") {
+			t.Errorf("Expected a warning about the cached index, got: %s", warn.String())
+		}
+		if _, ok := FindRegistryTemplate(registries, "go/service"); !ok {
+			t.Error("Expected go/service in the cached index")
+		}
+	})
+
+	t.Run("offline without cache", func(t *testing.T) {
+		online = false
+		defer func() { online = true }()
+		var warn bytes.Buffer
+		registries := LoadRegistries(sources[:1], t.TempDir(), opts, &warn)
+		if len(registries) != 0 {
+			t.Errorf("Expected acme to be skipped, got %+v", registries)
+		}
+		if !contains(warn.String(), "Skipping registry 'acme'") || !contains(warn.String(), "503") {
+			t.Errorf("Expected a warning with the failure, got: %s", warn.String())
+		}
+	})
+}
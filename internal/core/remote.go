@@ -0,0 +1,150 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultDataTimeout is how long fetching a data URL may take by default.
+	DefaultDataTimeout = 10 * time.Second
+	// bodySnippetSize is how much of an error response is shown.
+	bodySnippetSize = 200
+)
+
+// FetchOptions configures how data is fetched from a URL.
+type FetchOptions struct {
+	// Timeout bounds the whole request, including redirects and reading the
+	// body. Zero means DefaultDataTimeout.
+	Timeout time.Duration
+	// Insecure allows plain http URLs and redirects to them.
+	Insecure bool
+	// MaxFiles and MaxBytes limit the files and bytes extracted from a
+	// template archive. Zero disables a limit.
+	MaxFiles int
+	MaxBytes int64
+
+	// transport replaces the default transport in tests.
+	transport http.RoundTripper
+}
+
+// IsDataURL reports whether path is an http or https URL rather than a file.
+func IsDataURL(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")
+}
+
+// LoadDataURL fetches the data at an http or https URL and parses it like a
+// data file. The format is taken from format when it is set, then from the
+// Content-Type of the response, then from the extension of the URL path.
+func LoadDataURL(rawURL, format string, opts FetchOptions, dataOpts DataOptions) (map[string]any, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data URL '%s': %w", rawURL, err)
+	}
+	if err = checkScheme(u, opts.Insecure); err != nil {
+		return nil, err
+	}
+
+	resp, content, err := fetchURL(u, opts, "application/json, application/yaml;q=0.9, */*;q=0.1")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch data from '%s': %w", rawURL, err)
+	}
+
+	if format == "" {
+		if format, err = urlDataFormat(resp); err != nil {
+			return nil, fmt.Errorf("cannot determine the data format of '%s': %w", rawURL, err)
+		}
+	}
+	return ParseData(content, format, rawURL, dataOpts)
+}
+
+// fetchURL gets the content at u, following redirects as long as checkScheme
+// accepts them, and fails on a response other than 2xx.
+func fetchURL(u *url.URL, opts FetchOptions, accept string) (*http.Response, []byte, error) {
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = DefaultDataTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", accept)
+
+	client := &http.Client{
+		Transport: opts.transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			// Don't let a redirect downgrade to plain http.
+			return checkScheme(req.URL, opts.Insecure)
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, nil, fmt.Errorf("%s: %s", resp.Status, snippet(content))
+	}
+	return resp, content, nil
+}
+
+// checkScheme accepts https URLs, and http URLs when insecure is set.
+func checkScheme(u *url.URL, insecure bool) error {
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+		return nil
+	case "http":
+		if insecure {
+			return nil
+		}
+		return fmt.Errorf("refusing to fetch data over plain http from '%s', use --insecure-data to allow it", u)
+	default:
+		return fmt.Errorf("unsupported data URL scheme '%s' in '%s'", u.Scheme, u)
+	}
+}
+
+// urlDataFormat returns the data format of a response from its Content-Type,
+// falling back to the extension of the final URL path.
+func urlDataFormat(resp *http.Response) (string, error) {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return "json", nil
+	case strings.HasSuffix(mediaType, "/yaml") || strings.HasSuffix(mediaType, "/x-yaml") ||
+		strings.HasSuffix(mediaType, "+yaml"):
+		return "yaml", nil
+	}
+	return DataFormat(path.Base(resp.Request.URL.Path))
+}
+
+// snippet returns the start of a response body for error messages.
+func snippet(content []byte) string {
+	text := strings.Join(strings.Fields(string(content)), " ")
+	if len(text) > bodySnippetSize {
+		text = text[:bodySnippetSize] + "..."
+	}
+	if text == "" {
+		return "empty response body"
+	}
+	return text
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsDataURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://example.com/data.json": true,
+		"HTTP://example.com/data.yaml":  true,
+		"data.yaml":                     false,
+		"./https/data.yaml":             false,
+		"-":                             false,
+		"ftp://example.com/data.json":   false,
+	}
+	for path, want := range tests {
+		if got := IsDataURL(path); got != want {
+			t.Errorf("IsDataURL(%q) = %v; want %v", path, got, want)
+		}
+	}
+}
+
+func TestLoadDataURL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/service.json", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(`{"name": "by-extension"}`))
+	})
+	mux.HandleFunc("/catalog/foo", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+		_, _ = w.Write([]byte("name: by-content-type"))
+	})
+	mux.HandleFunc("/vendor", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.catalog+json")
+		_, _ = w.Write([]byte(`{"name": "vendor"}`))
+	})
+	mux.HandleFunc("/unknown", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("name: unknown"))
+	})
+	mux.HandleFunc("/missing.json", func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "service 'foo' does not exist\n"+strings.Repeat("x", 500), http.StatusNotFound)
+	})
+	mux.HandleFunc("/broken.json", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("{not json"))
+	})
+	mux.HandleFunc("/slow.json", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	})
+	mux.Handle("/moved", http.RedirectHandler("/service.json", http.StatusFound))
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+	plain := httptest.NewServer(mux)
+	defer plain.Close()
+
+	// Trust the test server's certificate.
+	transport := server.Client().Transport
+
+	t.Run("format from extension, content type or flag", func(t *testing.T) {
+		tests := []struct {
+			path     string
+			format   string
+			wantName string
+		}{
+			{path: "/service.json", wantName: "by-extension"},
+			{path: "/catalog/foo", wantName: "by-content-type"},
+			{path: "/vendor", wantName: "vendor"},
+			{path: "/unknown", format: "yaml", wantName: "unknown"},
+			{path: "/moved", wantName: "by-extension"},
+		}
+		for _, tt := range tests {
+			data, err := LoadDataURL(server.URL+tt.path, tt.format, FetchOptions{transport: transport}, DataOptions{})
+			if err != nil {
+				t.Errorf("LoadDataURL(%s) failed: %v", tt.path, err)
+				continue
+			}
+			if data["name"] != tt.wantName {
+				t.Errorf("LoadDataURL(%s): expected name %q, got %v", tt.path, tt.wantName, data["name"])
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			url     string
+			opts    FetchOptions
+			wantErr string
+		}{
+			{name: "unknown format", url: server.URL + "/unknown", wantErr: "cannot determine the data format"},
+			{
+				name:    "non-2xx status",
+				url:     server.URL + "/missing.json",
+				wantErr: "404 Not Found: service 'foo' does not exist",
+			},
+			{name: "parse error", url: server.URL + "/broken.json", wantErr: "failed to parse JSON file '" + server.URL},
+			{name: "plain http", url: plain.URL + "/service.json", wantErr: "use --insecure-data"},
+			{
+				name:    "timeout",
+				url:     server.URL + "/slow.json",
+				opts:    FetchOptions{Timeout: 50 * time.Millisecond},
+				wantErr: "failed to fetch data from",
+			},
+		}
+		for _, tt := range tests {
+			tt.opts.transport = transport
+			_, err := LoadDataURL(tt.url, "", tt.opts, DataOptions{})
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error containing %q, got: %v", tt.name, tt.wantErr, err)
+			}
+		}
+
+		_, err := LoadDataURL(server.URL+"/missing.json", "", FetchOptions{transport: transport}, DataOptions{})
+		if err != nil && len(err.Error()) > 400 {
+			t.Errorf("Expected the response body to be shortened, got %d bytes", len(err.Error()))
+		}
+	})
+
+	t.Run("plain http with insecure", func(t *testing.T) {
+		opts := FetchOptions{Insecure: true, transport: transport}
+		data, err := LoadDataURL(plain.URL+"/service.json", "", opts, DataOptions{})
+		if err != nil {
+			t.Fatalf("LoadDataURL failed: %v", err)
+		}
+		if data["name"] != "by-extension" {
+			t.Errorf("Expected name 'by-extension', got %v", data["name"])
+		}
+	})
+}
@@ -2,38 +2,189 @@ package core
 
 import (
 	"fmt"
+	"io"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
 	"strings"
 	"text/template"
-
-	"github.com/stoewer/go-strcase"
 )
 
+// PartialsDir is the directory at the root of a template whose files are
+// available to every template file via {{template "name" .}}, where name is
+// the slash-separated path relative to the directory. It is never copied to
+// the output.
+const PartialsDir = "_partials"
+
+// defaultCasing knows DefaultAcronyms only.
+//
+//nolint:gochecknoglobals // read-only casing shared by the helpers
+var defaultCasing = NewCasing(nil)
+
+// helperFunc holds the helper functions of templates, with the case helpers
+// knowing DefaultAcronyms. The functions reading the files of a template
+// fail, a Renderer of a template replaces them.
+//
 //nolint:gochecknoglobals // helper function use when render templates
-var helperFunc = template.FuncMap{
-	"snake":  strcase.SnakeCase,
-	"usnake": strcase.UpperSnakeCase,
-	"camel":  strcase.UpperCamelCase,
-	"lcamel": strcase.LowerCamelCase,
+var helperFunc = helperFuncs(defaultCasing, nil)
+
+// helperFuncs returns the case helpers of casing with the functions reading
+// the files of a template.
+func helperFuncs(casing *Casing, files *templateFiles) template.FuncMap {
+	funcs := casing.funcs()
+	maps.Copy(funcs, files.funcs())
+	return funcs
 }
 
-// RenderTemplateFile reads a template file, executes it with the provided data,
-// and writes the output to the destination path.
-func RenderTemplateFile(templatePath, destPath string, data map[string]any) error {
+// Renderer parses and executes template content with the helper functions
+// and the partials of a template. The partials are parsed once, and set up
+// with the options of the renderer by the first render, so its fields must
+// be set before. Each template is then parsed on a copy of them, so the
+// templates one defines aren't seen by the next.
+type Renderer struct {
+	// Strict makes a reference to a missing key an error instead of
+	// rendering "<no value>".
+	Strict bool
+	// MaxIncludeDepth limits how deep {{template}} calls nest. Zero means
+	// DefaultMaxIncludeDepth.
+	MaxIncludeDepth int
+	// ParentDirMode, when set, is the mode RenderFile creates the missing
+	// parent directories of the destination with, such as utils.DirMode.
+	// When zero, the parent directory must exist.
+	ParentDirMode fs.FileMode
+	// Acronyms are kept as units by the case helpers, on top of
+	// DefaultAcronyms.
+	Acronyms []string
+	// Funcs replace or add to the helper functions, such as the functions
+	// reading the files of the template being applied.
+	Funcs template.FuncMap
+
+	partials *template.Template
+	// base is partials with the options and functions of the renderer,
+	// copied for each template parsed.
+	base *template.Template
+}
+
+// NewRenderer creates a renderer whose templates can include the partials
+// found under partialsDir. An empty or missing partialsDir means no partials.
+func NewRenderer(partialsDir string, strict bool) (*Renderer, error) {
+	partials := template.New("").Funcs(helperFunc)
+	if partialsDir == "" {
+		return &Renderer{Strict: strict, partials: partials}, nil
+	}
+
+	err := filepath.WalkDir(partialsDir, func(path string, d fs.DirEntry, walkErr error) error {
+		// Named pipes, sockets and devices can't be read as partials.
+		if walkErr != nil || d.IsDir() || irregularType(d.Type()) != "" {
+			return walkErr
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("could not read partial '%s': %w", path, err)
+		}
+		name, err := filepath.Rel(partialsDir, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path for '%s': %w", path, err)
+		}
+		if _, err = partials.New(filepath.ToSlash(name)).Parse(string(content)); err != nil {
+			return fmt.Errorf("could not parse partial '%s': %w", path, err)
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return &Renderer{Strict: strict, partials: partials}, nil
+}
+
+// Render parses the template content under the given name and writes the
+// result of executing it with data to w.
+func (r *Renderer) Render(w io.Writer, name string, content []byte, data map[string]any) error {
+	tmpl, err := r.parse(name, content)
+	if err != nil {
+		return err
+	}
+	if err = tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render template '%s': %w", name, err)
+	}
+	return nil
+}
+
+// parse parses the template content on top of a copy of the partials.
+func (r *Renderer) parse(name string, content []byte) (*template.Template, error) {
+	if r.base == nil {
+		base, err := r.prepare()
+		if err != nil {
+			return nil, fmt.Errorf("could not prepare template '%s': %w", name, err)
+		}
+		r.base = base
+	}
+	tmpl, err := r.base.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("could not prepare template '%s': %w", name, err)
+	}
+	if tmpl, err = tmpl.New(name).Parse(string(content)); err != nil {
+		return nil, fmt.Errorf("could not parse template '%s': %w", name, err)
+	}
+	if err = checkIncludes(tmpl, name, r.MaxIncludeDepth); err != nil {
+		return nil, fmt.Errorf("could not parse template '%s': %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// prepare returns a copy of the partials with the options and functions of
+// the renderer.
+func (r *Renderer) prepare() (*template.Template, error) {
+	if r.partials == nil {
+		r.partials = template.New("").Funcs(helperFunc)
+	}
+	base, err := r.partials.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if r.Strict {
+		base = base.Option("missingkey=error")
+	}
+	if len(r.Acronyms) > 0 {
+		base = base.Funcs(NewCasing(r.Acronyms).funcs())
+	}
+	if len(r.Funcs) > 0 {
+		base = base.Funcs(r.Funcs)
+	}
+	return base, nil
+}
+
+// RenderFile reads a template file, executes it with the provided data,
+// and writes the output to the destination path. Template files larger than
+// DefaultMaxTemplateSize are rejected.
+func (r *Renderer) RenderFile(templatePath, destPath string, data map[string]any) error {
+	sourceInfo, err := os.Stat(templatePath)
+	if err != nil {
+		return fmt.Errorf("could not read template file '%s': %w", templatePath, err)
+	}
+	if err = checkTemplateSize(templatePath, sourceInfo.Size(), DefaultMaxTemplateSize); err != nil {
+		return err
+	}
+
 	// Read the template content.
 	content, err := os.ReadFile(templatePath)
 	if err != nil {
 		return fmt.Errorf("could not read template file '%s': %w", templatePath, err)
 	}
 
-	// Create a new template, parse the content, and execute it.
-	tmpl, err := template.New(filepath.Base(templatePath)).Funcs(helperFunc).Parse(string(content))
+	// Parse the content together with the partials.
+	tmpl, err := r.parse(filepath.Base(templatePath), content)
 	if err != nil {
-		return fmt.Errorf("could not parse template '%s': %w", templatePath, err)
+		return err
 	}
 
 	// Create the destination file.
+	if r.ParentDirMode != 0 {
+		if err = os.MkdirAll(filepath.Dir(destPath), r.ParentDirMode); err != nil {
+			return fmt.Errorf("failed to create directory for '%s': %w", destPath, err)
+		}
+	}
 	destFile, err := os.Create(destPath)
 	if err != nil {
 		return fmt.Errorf("failed to create destination file '%s': %w", destPath, err)
@@ -46,16 +197,60 @@ func RenderTemplateFile(templatePath, destPath string, data map[string]any) erro
 	}
 
 	// Preserve file permissions from the original template
-	sourceInfo, err := os.Stat(templatePath)
+	return os.Chmod(destPath, sourceInfo.Mode())
+}
+
+// RenderTemplateFile reads a template file, executes it with the provided data,
+// and writes the output to the destination path.
+func RenderTemplateFile(templatePath, destPath string, data map[string]any) error {
+	return (&Renderer{}).RenderFile(templatePath, destPath, data)
+}
+
+// ReplacePlaceholdersInOutput renders the placeholders of an output path,
+// such as ./{{.project_name}}, like ReplacePlaceholdersInPath. A key missing
+// from the data is an error rather than "<no value>", and so is a path that
+// renders empty, which would otherwise generate into the current directory.
+func ReplacePlaceholdersInOutput(output string, data map[string]any) (string, error) {
+	if !strings.Contains(output, "{{") {
+		return output, nil
+	}
+	tmpl, err := template.New("output").Funcs(helperFunc).Option("missingkey=error").Parse(output)
 	if err != nil {
-		return fmt.Errorf("failed to stat source file '%s': %w", templatePath, err)
+		return "", fmt.Errorf("invalid output path '%s': %w", output, err)
 	}
-	return os.Chmod(destPath, sourceInfo.Mode())
+	var result strings.Builder
+	if err = tmpl.Execute(&result, data); err != nil {
+		return "", fmt.Errorf("failed to render output path '%s': %w", output, err)
+	}
+	if strings.TrimSpace(result.String()) == "" {
+		return "", fmt.Errorf("output path '%s' renders to an empty path", output)
+	}
+	return result.String(), nil
 }
 
-// ReplacePlaceholdersInPath replace placeholders in directory names.
+// ReplacePlaceholdersInPath replace placeholders in directory names. Both
+// {{.name}} placeholders and __name__ tokens are replaced. A key missing from
+// the data fails, naming the path, rather than generating a "<no value>"
+// name.
 func ReplacePlaceholdersInPath(path string, data map[string]any) (string, error) {
-	tmpl, err := template.New("path").Funcs(helperFunc).Parse(path)
+	result, err := replacePlaceholders(path, data, helperFunc, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to replace placeholders in path '%s': %w", path, err)
+	}
+	return result, nil
+}
+
+// replacePlaceholders replaces the placeholders and __name__ tokens of a path
+// with the given helper functions, such as those knowing the acronyms of a
+// template. With strict, a key missing from the data fails like in file
+// contents. Without it, the "<no value>" it renders as fails all the same,
+// since it is never the name wanted, unless the path holds the text itself.
+func replacePlaceholders(path string, data map[string]any, funcs template.FuncMap, strict bool) (string, error) {
+	tmpl := template.New("path").Funcs(funcs)
+	if strict {
+		tmpl = tmpl.Option("missingkey=error")
+	}
+	tmpl, err := tmpl.Parse(pathTokens(path, funcs))
 	if err != nil {
 		return "", err
 	}
@@ -63,5 +258,8 @@ func ReplacePlaceholdersInPath(path string, data map[string]any) (string, error)
 	if err = tmpl.Execute(&result, data); err != nil {
 		return "", err
 	}
+	if strings.Contains(result.String(), noValue) && !strings.Contains(path, noValue) {
+		return "", fmt.Errorf("it renders to '%s', a key it uses is missing from the data", result.String())
+	}
 	return result.String(), nil
 }
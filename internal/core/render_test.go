@@ -1,7 +1,9 @@
 package core
 
 import (
+	"bytes"
 	"errors"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -133,6 +135,27 @@ Lower camel: someVariableName`
 		}
 	})
 
+	t.Run("creates parent directories when asked", func(t *testing.T) {
+		templatePath := filepath.Join(tempDir, "template5.txt")
+		if err := os.WriteFile(templatePath, []byte(`Hello {{.name}}!`), 0644); err != nil {
+			t.Fatalf("Failed to create template file: %v", err)
+		}
+
+		destPath := filepath.Join(tempDir, "a", "b", "c", "output.txt")
+		renderer := &Renderer{ParentDirMode: 0750}
+		if err := renderer.RenderFile(templatePath, destPath, map[string]any{"name": "John"}); err != nil {
+			t.Fatalf("RenderFile failed: %v", err)
+		}
+		content, err := os.ReadFile(destPath)
+		if err != nil || string(content) != "Hello John!" {
+			t.Errorf("Expected %q, got %q (%v)", "Hello John!", content, err)
+		}
+		info, err := os.Stat(filepath.Join(tempDir, "a", "b"))
+		if err != nil || info.Mode().Perm() != 0750 {
+			t.Errorf("Expected the parents created with mode 0750, got %v (%v)", info, err)
+		}
+	})
+
 	t.Run("template execution error", func(t *testing.T) {
 		// Template that references non-existent field
 		templateContent := `Hello {{.name.NonExistent}}!`
@@ -157,6 +180,34 @@ Lower camel: someVariableName`
 	})
 }
 
+func TestReplacePlaceholdersInOutput(t *testing.T) {
+	data := map[string]any{"project_name": "Demo App", "blank": " "}
+	tests := []struct {
+		output  string
+		want    string
+		wantErr string
+	}{
+		{output: "./{{ .project_name }}", want: "./Demo App"},
+		{output: "out/{{snake .project_name}}.tar.gz", want: "out/demo_app.tar.gz"},
+		{output: "-", want: "-"},
+		{output: "{{.blank}}", wantErr: "renders to an empty path"},
+		{output: "out/{{.missing}}", wantErr: `map has no entry for key "missing"`},
+		{output: "out/{{.project_name", wantErr: "invalid output path 'out/{{.project_name'"},
+	}
+	for _, tt := range tests {
+		got, err := ReplacePlaceholdersInOutput(tt.output, data)
+		if tt.wantErr != "" {
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("%q: expected an error containing %q, got %q, %v", tt.output, tt.wantErr, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: expected %q, got %q, %v", tt.output, tt.want, got, err)
+		}
+	}
+}
+
 func TestReplacePlaceholdersInPath(t *testing.T) {
 	t.Run("successful path replacement", func(t *testing.T) {
 		path := "/app/{{.service}}/{{snake .serviceName}}/config"
@@ -246,4 +297,204 @@ func TestReplacePlaceholdersInPath(t *testing.T) {
 			t.Errorf("Expected unchanged path %q, got %q", path, result)
 		}
 	})
+
+	t.Run("missing key in path", func(t *testing.T) {
+		_, err := ReplacePlaceholdersInPath("src/{{.nme}}/main.go", map[string]any{"name": "app"})
+		want := "failed to replace placeholders in path 'src/{{.nme}}/main.go': it renders to " +
+			"'src/<no value>/main.go', a key it uses is missing from the data"
+		if err == nil || err.Error() != want {
+			t.Errorf("Expected %q, got: %v", want, err)
+		}
+	})
+}
+
+func TestApplierRenderPath(t *testing.T) {
+	a := &applier{opts: Options{Data: map[string]any{"org": "acme", "pkg": "a.b", "x": "X"}}}
+	l := &layer{funcs: helperFunc, paths: make(map[string]string)}
+	tests := map[string]string{
+		"plain.txt":                                "plain.txt",
+		"{{.org}}":                                 "acme",
+		"{{.org}}/src/main.go":                     "acme/src/main.go",
+		"{{.org}}/src/{{.x}}.go":                   "acme/src/X.go",
+		"{{.org}}/__x__/____init____.py":           "acme/X/__init__.py",
+		`{{printf "%s/%s" .org .x}}.go`:            "acme/X.go",
+		`{{.pkg | printf "%s/x"}}/y.go`:            "a.b/x/y.go",
+		"{{if .x}}yes/{{.org}}{{end}}/file":        "yes/acme/file",
+		"{{with .org}}{{.}}/inner{{end}}/file.txt": "acme/inner/file.txt",
+	}
+	for path, want := range tests {
+		for range 2 {
+			got, err := a.renderPath(l, path)
+			if err != nil || got != want {
+				t.Errorf("Expected %q to render %q, got %q (%v)", path, want, got, err)
+			}
+		}
+	}
+	if _, ok := l.paths["{{.org}}/src"]; !ok && filepath.Separator == '/' {
+		t.Errorf("Expected the directory of a plain file name to be rendered once, got %v", l.paths)
+	}
+	if _, err := a.renderPath(l, "{{.missing}}/file"); err == nil {
+		t.Error("Expected a missing key in the directory to fail")
+	}
+}
+
+func TestApplyPathMissingKeys(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		"{{.project_name}}/{{.moduel}}/main.go": "package main\n",
+	})
+	data := map[string]any{"project_name": "app", "module": "api"}
+	for _, strict := range []bool{false, true} {
+		outputDir := t.TempDir()
+		err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Strict: strict,
+			Out: io.Discard})
+		want := "failed to replace placeholders in path '{{.project_name}}/{{.moduel}}': " +
+			"it renders to 'app/<no value>', a key it uses is missing from the data"
+		if strict {
+			want = "failed to replace placeholders in path '{{.project_name}}/{{.moduel}}': " +
+				`template: path:1:20: executing "path" at <.moduel>: map has no entry for key "moduel"`
+		}
+		if err == nil || !contains(err.Error(), want) {
+			t.Errorf("Expected %q with strict %v, got: %v", want, strict, err)
+		}
+		if _, statErr := os.Stat(filepath.Join(outputDir, "app")); !os.IsNotExist(statErr) {
+			t.Errorf("Expected nothing to be generated, got %v", statErr)
+		}
+	}
+
+	// A name holding the text itself is generated as it is.
+	outputDir := t.TempDir()
+	err := Apply(Options{TemplatePath: writeTemplate(t, map[string]string{"<no value>.txt": "kept"}),
+		OutputDir: outputDir, Out: io.Discard})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(outputDir, "<no value>.txt")); err != nil {
+		t.Errorf("Expected the literal name to be kept: %v", err)
+	}
+}
+
+func TestRenderer(t *testing.T) {
+	templateRoot := t.TempDir()
+	partialsDir := filepath.Join(templateRoot, PartialsDir)
+	if err := os.MkdirAll(filepath.Join(partialsDir, "go"), 0755); err != nil {
+		t.Fatalf("Failed to create partials directory: %v", err)
+	}
+	err := os.WriteFile(filepath.Join(partialsDir, "header.tmpl"), []byte("// {{.name}} header"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write partial: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(partialsDir, "go", "pkg.tmpl"), []byte("package {{snake .name}}"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write partial: %v", err)
+	}
+
+	t.Run("partials can be included", func(t *testing.T) {
+		renderer, err := NewRenderer(partialsDir, false)
+		if err != nil {
+			t.Fatalf("NewRenderer failed: %v", err)
+		}
+
+		var out bytes.Buffer
+		content := []byte(`{{template "header.tmpl" .}}` + "\n" + `{{template "go/pkg.tmpl" .}}`)
+		if err = renderer.Render(&out, "main.go.tmpl", content, map[string]any{"name": "MyApp"}); err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+
+		expected := "// MyApp header\npackage my_app"
+		if out.String() != expected {
+			t.Errorf("Output mismatch:\nGot:\n%s\nWant:\n%s", out.String(), expected)
+		}
+	})
+
+	t.Run("missing partials directory", func(t *testing.T) {
+		renderer, err := NewRenderer(filepath.Join(t.TempDir(), PartialsDir), false)
+		if err != nil {
+			t.Fatalf("NewRenderer failed: %v", err)
+		}
+
+		var out bytes.Buffer
+		if err = renderer.Render(&out, "x", []byte("{{.a}}"), map[string]any{"a": 1}); err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+		if out.String() != "1" {
+			t.Errorf("Expected '1', got %q", out.String())
+		}
+	})
+
+	t.Run("invalid partial", func(t *testing.T) {
+		brokenDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(brokenDir, "bad.tmpl"), []byte("{{.a"), 0644); err != nil {
+			t.Fatalf("Failed to write partial: %v", err)
+		}
+
+		_, err := NewRenderer(brokenDir, false)
+		if err == nil || !contains(err.Error(), "could not parse partial") {
+			t.Errorf("Expected partial parse error, got: %v", err)
+		}
+	})
+
+	t.Run("strict mode fails on missing keys", func(t *testing.T) {
+		lenient, err := NewRenderer("", false)
+		if err != nil {
+			t.Fatalf("NewRenderer failed: %v", err)
+		}
+		var out bytes.Buffer
+		if err = lenient.Render(&out, "x", []byte("{{.missing}}"), map[string]any{}); err != nil {
+			t.Fatalf("Lenient render failed: %v", err)
+		}
+		if out.String() != "<no value>" {
+			t.Errorf("Expected '<no value>', got %q", out.String())
+		}
+
+		strictRenderer, err := NewRenderer("", true)
+		if err != nil {
+			t.Fatalf("NewRenderer failed: %v", err)
+		}
+		err = strictRenderer.Render(&bytes.Buffer{}, "x", []byte("{{.missing}}"), map[string]any{})
+		if err == nil || !contains(err.Error(), "map has no entry for key") {
+			t.Errorf("Expected missing key error, got: %v", err)
+		}
+	})
+
+	t.Run("renders do not share definitions", func(t *testing.T) {
+		renderer, err := NewRenderer(partialsDir, false)
+		if err != nil {
+			t.Fatalf("NewRenderer failed: %v", err)
+		}
+		content := []byte(`{{define "local"}}first{{end}}{{template "local"}}`)
+		if err = renderer.Render(&bytes.Buffer{}, "a", content, nil); err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+
+		err = renderer.Render(&bytes.Buffer{}, "b", []byte(`{{template "local"}}`), nil)
+		if err == nil {
+			t.Error("Expected definitions from another file to be unavailable")
+		}
+	})
+	t.Run("redefined partials stay in their file", func(t *testing.T) {
+		renderer, err := NewRenderer(partialsDir, false)
+		if err != nil {
+			t.Fatalf("NewRenderer failed: %v", err)
+		}
+		renderer.Acronyms = []string{"API"}
+		data := map[string]any{"name": "api server"}
+		files := []struct{ name, content, want string }{
+			{"a", `{{define "header.tmpl"}}// a{{end}}{{template "header.tmpl" .}} {{camel .name}}`, "// a APIServer"},
+			{"b", `{{template "header.tmpl" .}}`, "// api server header"},
+			{"c", `{{define "local"}}c{{end}}{{define "header.tmpl"}}// c{{end}}{{template "header.tmpl" .}}`, "// c"},
+			{"d", `{{template "header.tmpl" .}} {{camel .name}}`, "// api server header APIServer"},
+		}
+		for _, file := range files {
+			var out bytes.Buffer
+			if err = renderer.Render(&out, file.name, []byte(file.content), data); err != nil {
+				t.Fatalf("Render of %s failed: %v", file.name, err)
+			}
+			if out.String() != file.want {
+				t.Errorf("Expected %s to render %q, got %q", file.name, file.want, out.String())
+			}
+		}
+		if err = renderer.Render(&bytes.Buffer{}, "e", []byte(`{{template "local"}}`), nil); err == nil {
+			t.Error("Expected definitions from another file to be unavailable")
+		}
+	})
 }
@@ -0,0 +1,248 @@
+package core
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/0m3kk/mold/internal/version"
+)
+
+// ResultSchema is the version of the Result format written by this release.
+const ResultSchema = 1
+
+// FileStatus says what an Apply run did with a file of the templates.
+type FileStatus string
+
+const (
+	// StatusRendered is a '.tmpl' file rendered with the data.
+	StatusRendered FileStatus = "rendered"
+	// StatusCopied is a file copied or linked as-is, raw files included.
+	StatusCopied FileStatus = "copied"
+	// StatusSymlinked is a symlink recreated with its target rendered.
+	StatusSymlinked FileStatus = "symlinked"
+	// StatusPruned is a file of the previous run that was deleted.
+	StatusPruned FileStatus = "pruned"
+	// StatusFailed is the file the run failed on.
+	StatusFailed FileStatus = "failed"
+	// StatusSkipped is a planned file the run didn't get to.
+	StatusSkipped FileStatus = "skipped"
+	// StatusSkippedIrregular is a named pipe, socket or device of the
+	// templates, which is never generated.
+	StatusSkippedIrregular FileStatus = "skipped-irregular"
+)
+
+// Result records what an Apply run did, for tools wrapping mold. It is
+// filled in even when the run fails, with the error.
+type Result struct {
+	// Schema is the version of the format.
+	Schema int `json:"schema"`
+	// Template is the applied template, Layers the templates applied on top
+	// of it, as recorded in the provenance. Their url and ref locate a
+	// template fetched from a remote repository.
+	Template ProvenanceTemplate   `json:"template"`
+	Layers   []ProvenanceTemplate `json:"layers,omitempty"`
+	// MoldVersion is the version of mold that ran.
+	MoldVersion string `json:"mold_version"`
+	// Options are the effective options of the run, defaults included.
+	Options ResultOptions `json:"options"`
+	// Files are the files of the run, sorted by path. Their hash and mode
+	// are those of the content written, or that would be on a dry run.
+	Files []ResultFile `json:"files"`
+	// Warnings are the warnings printed during the run, in order.
+	Warnings []string `json:"warnings"`
+	// Deprecations are the messages of the deprecated templates applied.
+	Deprecations []Deprecation `json:"deprecations,omitempty"`
+	// Notices are the notices of the templates rendered with the data, for
+	// the user, once the run succeeded.
+	Notices []string `json:"notices,omitempty"`
+	// UnusedKeys are the dotted paths of the data values no template or path
+	// uses, and MissingValues the references to missing keys rendered as
+	// "<no value>", unless the options turn their warnings off.
+	UnusedKeys    []string       `json:"unused_keys,omitempty"`
+	MissingValues []MissingValue `json:"missing_values,omitempty"`
+	// Collisions are the groups of generated paths only differing by case,
+	// with their sources, whether they were warned about or failed the run.
+	Collisions []Collision `json:"collisions,omitempty"`
+	// GeneratedFiles and GeneratedBytes are the totals of the files written,
+	// or that would be on a dry run, counted against the limits of the
+	// options. A run stopped by a limit counts the file that went over it.
+	GeneratedFiles int   `json:"generated_files"`
+	GeneratedBytes int64 `json:"generated_bytes"`
+	// StartedAt is when the run started, Elapsed how long it took.
+	StartedAt time.Time     `json:"started_at"`
+	Elapsed   time.Duration `json:"elapsed_ns"`
+	// Success tells whether the run succeeded, Error why it failed.
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ResultOptions are the options of an Apply run recorded in its Result.
+type ResultOptions struct {
+	OutputDir        string    `json:"output_dir"`
+	Strict           bool      `json:"strict"`
+	NoFormat         bool      `json:"no_format"`
+	NoProvenance     bool      `json:"no_provenance"`
+	FuzzyKeys        bool      `json:"fuzzy_keys"`
+	Subdir           string    `json:"subdir,omitempty"`
+	KeepPrefix       bool      `json:"keep_prefix"`
+	Prune            bool      `json:"prune"`
+	Merge            MergeMode `json:"merge"`
+	DryRun           bool      `json:"dry_run"`
+	BackupDir        string    `json:"backup_dir,omitempty"`
+	MaxTemplateSize  int64     `json:"max_template_size"`
+	MaxIncludeDepth  int       `json:"max_include_depth"`
+	MaxFiles         int       `json:"max_files"`
+	MaxBytes         int64     `json:"max_bytes"`
+	Link             LinkMode  `json:"link"`
+	PreserveSymlinks bool      `json:"preserve_symlinks"`
+	NameRule         NameRule  `json:"name_rule"`
+	SanitizeNames    bool      `json:"sanitize_names"`
+	// Clock is the timestamp of the archive entries and of the provenance,
+	// empty for the current time.
+	Clock string `json:"clock,omitempty"`
+}
+
+// ResultFile is one file of an Apply run.
+type ResultFile struct {
+	// Path is the slash-separated path relative to the output root.
+	Path   string     `json:"path"`
+	Status FileStatus `json:"status"`
+	// SHA256 and Mode are those of the file written, as in the manifest.
+	SHA256 string   `json:"sha256,omitempty"`
+	Mode   string   `json:"mode,omitempty"`
+	Link   LinkMode `json:"link,omitempty"`
+}
+
+// NewResult starts the result of a run, to pass to Apply in
+// Options.Result.
+func NewResult() *Result {
+	return &Result{
+		Schema:      ResultSchema,
+		MoldVersion: version.String(),
+		Files:       []ResultFile{},
+		Warnings:    []string{},
+		StartedAt:   time.Now(),
+	}
+}
+
+// Finish records the end of the run and the error it failed with, if any.
+func (r *Result) Finish(err error) {
+	r.Elapsed = time.Since(r.StartedAt)
+	r.Success = err == nil
+	r.Error = ""
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// Marshal encodes the result as indented JSON.
+func (r *Result) Marshal() ([]byte, error) {
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+	return append(content, '\n'), nil
+}
+
+// record fills in the result of the run from what it did so far.
+func (a *applier) record(r *Result, warnings []string, err error) {
+	opts := a.opts
+	if len(a.sources) > 0 {
+		r.Template, r.Layers = a.sources[0], a.sources[1:]
+	} else if abs, absErr := filepath.Abs(opts.TemplatePath); absErr == nil {
+		// The run failed before loading the template.
+		r.Template = ProvenanceTemplate{Path: abs}
+	}
+	r.Options = ResultOptions{
+		OutputDir:        opts.OutputDir,
+		Strict:           opts.Strict,
+		NoFormat:         opts.NoFormat,
+		NoProvenance:     opts.NoProvenance,
+		FuzzyKeys:        opts.FuzzyKeys && !opts.Strict,
+		Subdir:           opts.Subdir,
+		KeepPrefix:       opts.KeepPrefix,
+		Prune:            opts.Prune,
+		Merge:            cmp.Or(opts.Merge, MergeOff),
+		DryRun:           opts.DryRun,
+		BackupDir:        opts.BackupDir,
+		MaxTemplateSize:  opts.MaxTemplateSize,
+		MaxIncludeDepth:  cmp.Or(opts.MaxIncludeDepth, DefaultMaxIncludeDepth),
+		MaxFiles:         opts.MaxFiles,
+		MaxBytes:         opts.MaxBytes,
+		Link:             cmp.Or(opts.Link, LinkCopy),
+		PreserveSymlinks: opts.PreserveSymlinks,
+		NameRule:         cmp.Or(opts.NameRule, NameWindowsSafe),
+		SanitizeNames:    opts.SanitizeNames,
+	}
+	if !opts.Clock.IsZero() {
+		r.Options.Clock = opts.Clock.UTC().Format(time.RFC3339)
+	}
+
+	files := make(map[string]ResultFile)
+	for key, e := range a.entries {
+		if e.kind == entryDir || e.kind == entryRaw {
+			continue
+		}
+		files[key] = ResultFile{Path: key, Status: cmp.Or(a.status[key], StatusSkipped)}
+	}
+	for key := range a.raw {
+		files[key] = ResultFile{Path: key, Status: StatusCopied}
+	}
+	for key, status := range a.status {
+		if status == StatusPruned || status == StatusSkippedIrregular {
+			files[key] = ResultFile{Path: key, Status: status}
+		}
+	}
+	r.Files = make([]ResultFile, 0, len(files))
+	for _, key := range slices.Sorted(maps.Keys(files)) {
+		file := files[key]
+		if written, ok := a.files[key]; ok && file.Status != StatusPruned {
+			file.SHA256, file.Mode, file.Link = written.SHA256, written.Mode, written.Link
+		}
+		r.Files = append(r.Files, file)
+	}
+	r.Warnings = append([]string{}, warnings...)
+	r.Deprecations = a.deprecations
+	r.Notices = a.rendered
+	r.UnusedKeys = a.unused
+	r.Collisions = a.collisions
+	r.MissingValues = a.missing
+	r.GeneratedFiles, r.GeneratedBytes = a.generated.files, a.generated.bytes
+	r.Finish(err)
+}
+
+// warningRecorder passes the progress messages through to w and keeps the
+// warnings, the lines starting with "⚠️", passing them on to observer.
+type warningRecorder struct {
+	w        io.Writer
+	observer Observer
+	line     []byte
+	warnings []string
+}
+
+func (r *warningRecorder) Write(p []byte) (int, error) {
+	r.line = append(r.line, p...)
+	for {
+		i := bytes.IndexByte(r.line, '\n')
+		if i < 0 {
+			break
+		}
+		if line := string(r.line[:i]); strings.HasPrefix(line, "⚠️") {
+			warning := strings.TrimSpace(strings.TrimPrefix(line, "⚠️"))
+			r.warnings = append(r.warnings, warning)
+			if r.observer != nil {
+				r.observer.Warning(warning)
+			}
+		}
+		r.line = r.line[i+1:]
+	}
+	return r.w.Write(p)
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//nolint:gochecknoglobals // test flag
+var updateGolden = flag.Bool("update", false, "Rewrite the golden files of the tests")
+
+func TestApplyResultGolden(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile: "name: go/service\nversion: 1.2.0\n" +
+			"prompts:\n  name: {}\n  database: {type: select, choices: [postgres, sqlite]}\n" +
+			"  postgres_version: {type: int, when: '{{eq .database \"postgres\"}}'}\n",
+		"main.go.tmpl": "package {{.name}}\n",
+		"README.md":    "# Service\n",
+	})
+	result := NewResult()
+	err := Apply(Options{
+		TemplatePath: templateDir,
+		OutputDir:    t.TempDir(),
+		Data:         map[string]any{"name": "orders", "database": "sqlite", "postgres_version": 16},
+		Out:          io.Discard,
+		Clock:        time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Result:       result,
+	})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if !result.Success || result.Elapsed <= 0 || result.StartedAt.IsZero() {
+		t.Errorf("Expected a timed success, got %+v", result)
+	}
+
+	// Pin everything that doesn't depend on the machine.
+	result.Template.Path = "/templates/go/service"
+	result.Options.OutputDir = "/projects/orders"
+	result.MoldVersion = "v1.0.0"
+	result.StartedAt = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	result.Elapsed = time.Second
+	content, err := result.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	golden := filepath.Join("testdata", "result.golden.json")
+	if *updateGolden {
+		if err = os.WriteFile(golden, content, 0644); err != nil {
+			t.Fatalf("Failed to update %s: %v", golden, err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", golden, err)
+	}
+	if string(content) != string(want) {
+		t.Errorf("The result changed, bump ResultSchema if it breaks readers and run go test -update:\n%s", content)
+	}
+}
+
+func TestApplyResultFailure(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile:   "prompts:\n  port: {type: int}\n",
+		"a.txt.tmpl":   "{{.name}}",
+		"b.txt.tmpl":   "{{.missing}}",
+		"c.txt":        "c",
+		"d/e.txt.tmpl": "e",
+	})
+
+	t.Run("while generating", func(t *testing.T) {
+		result := NewResult()
+		err := Apply(Options{TemplatePath: templateDir, OutputDir: t.TempDir(), Data: map[string]any{"name": "x"},
+			Out: io.Discard, Strict: true, Result: result})
+		if err == nil {
+			t.Fatal("Expected Apply to fail")
+		}
+		if result.Success || result.Error != err.Error() {
+			t.Errorf("Expected the error recorded, got %v, %q", result.Success, result.Error)
+		}
+		want := map[string]FileStatus{
+			"a.txt": StatusRendered, "b.txt": StatusFailed, "c.txt": StatusSkipped, "d/e.txt": StatusSkipped,
+		}
+		if len(result.Files) != len(want) {
+			t.Fatalf("Expected %d files, got %+v", len(want), result.Files)
+		}
+		for _, file := range result.Files {
+			if file.Status != want[file.Path] {
+				t.Errorf("Expected '%s' to be %s, got %s", file.Path, want[file.Path], file.Status)
+			}
+		}
+		if result.Files[0].SHA256 != sha256Hex("x") || result.Files[1].SHA256 != "" {
+			t.Errorf("Expected a hash for the written file only, got %+v", result.Files)
+		}
+	})
+
+	t.Run("before generating", func(t *testing.T) {
+		result := NewResult()
+		err := Apply(Options{TemplatePath: templateDir, OutputDir: t.TempDir(), Data: map[string]any{"port": "x"},
+			Out: io.Discard, Result: result})
+		if err == nil || !strings.Contains(result.Error, "invalid value for 'port'") {
+			t.Fatalf("Expected the validation error recorded, got %v, %q", err, result.Error)
+		}
+		if result.Template.Path == "" || len(result.Files) != 0 || result.Schema != ResultSchema {
+			t.Errorf("Expected the template and no files, got %+v", result)
+		}
+	})
+}
+
+func TestWarningRecorder(t *testing.T) {
+	var out strings.Builder
+	r := &warningRecorder{w: &out}
+	for _, p := range []string{"📄 Copying: a\n⚠️  Keeping 'b', it", " was modified\n", "⚠️  Partial"} {
+		if _, err := io.WriteString(r, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(r.warnings) != 1 || r.warnings[0] != "Keeping 'b', it was modified" {
+		t.Errorf("Expected the complete warning line, got %q", r.warnings)
+	}
+	if !strings.HasSuffix(out.String(), "⚠️  Partial") {
+		t.Errorf("Expected everything passed through, got %q", out.String())
+	}
+}
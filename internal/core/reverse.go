@@ -0,0 +1,341 @@
+package core
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// shortValueLength is the length below which a reversed value is likely to
+// match unrelated text.
+const shortValueLength = 4
+
+//nolint:gochecknoglobals // constant lookup tables
+var (
+	// vcsDirs are the version control directories Reverse skips.
+	vcsDirs = []string{".git", ".hg", ".svn", ".bzr"}
+	// varNamePattern matches the data keys Reverse accepts, which are used as
+	// {{.name}} placeholders.
+	varNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// ReverseVar is a data key whose literal value in a project is turned into a
+// placeholder.
+type ReverseVar struct {
+	Name  string
+	Value string
+}
+
+// ReverseOptions configures a Reverse run.
+type ReverseOptions struct {
+	// ProjectDir is the existing project to turn into a template.
+	ProjectDir string
+	// OutputDir is the template directory to create. It must not exist.
+	OutputDir string
+	// Vars are the values to replace, in the order they are declared as
+	// prompts.
+	Vars []ReverseVar
+	// WordBoundary only replaces values that are not part of a longer word.
+	WordBoundary bool
+	// Out receives progress messages and warnings. Defaults to os.Stdout.
+	Out io.Writer
+}
+
+// reverser carries the state of one Reverse run.
+type reverser struct {
+	opts ReverseOptions
+	out  io.Writer
+	// vars are tried longest value first, so a value containing another one
+	// wins.
+	vars   []ReverseVar
+	counts map[string]int
+}
+
+// Reverse creates a template from an existing project. The files are copied
+// and each value of opts.Vars is replaced with its placeholder in the file
+// contents and paths. Files with replacements get the '.tmpl' suffix, with
+// template delimiters they already held escaped, so the template generates
+// the project again from the same values. Version control directories are
+// skipped. A template.yaml declaring the variables as prompts and a tmpl.yaml
+// holding the original values are written. Reverse returns the number of
+// replacements per variable name.
+func Reverse(opts ReverseOptions) (map[string]int, error) {
+	r := &reverser{opts: opts, out: opts.Out, counts: make(map[string]int)}
+	if r.out == nil {
+		r.out = os.Stdout
+	}
+	if err := r.check(); err != nil {
+		return nil, err
+	}
+
+	err := filepath.WalkDir(opts.ProjectDir, r.visit)
+	if err == nil {
+		err = r.writeMetadata()
+	}
+	if err != nil {
+		_ = os.RemoveAll(opts.OutputDir)
+		return nil, fmt.Errorf("failed to reverse project '%s': %w", opts.ProjectDir, err)
+	}
+	for _, v := range opts.Vars {
+		if r.counts[v.Name] == 0 {
+			fmt.Fprintf(r.out, "⚠️  Value '%s' of '%s' was not found in the project\n", v.Value, v.Name)
+		}
+	}
+	return r.counts, nil
+}
+
+// check validates the options and warns about values likely to over-match.
+func (r *reverser) check() error {
+	if len(r.opts.Vars) == 0 {
+		return errors.New("no variables to reverse, expected at least one name=value")
+	}
+	seen := make(map[string]bool)
+	for _, v := range r.opts.Vars {
+		if !varNamePattern.MatchString(v.Name) {
+			return fmt.Errorf("invalid variable name '%s': expected letters, digits and underscores", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("variable '%s' is given more than once", v.Name)
+		}
+		seen[v.Name] = true
+		if v.Value == "" {
+			return fmt.Errorf("variable '%s' has an empty value", v.Name)
+		}
+		if i := slices.IndexFunc(r.vars, func(o ReverseVar) bool { return o.Value == v.Value }); i >= 0 {
+			return fmt.Errorf("variables '%s' and '%s' have the same value '%s'", r.vars[i].Name, v.Name, v.Value)
+		}
+		r.vars = append(r.vars, v)
+	}
+	slices.SortStableFunc(r.vars, func(a, b ReverseVar) int { return cmp.Compare(len(b.Value), len(a.Value)) })
+
+	info, err := os.Stat(r.opts.ProjectDir)
+	if err != nil {
+		return fmt.Errorf("project directory '%s' not found: %w", r.opts.ProjectDir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("project path '%s' is not a directory", r.opts.ProjectDir)
+	}
+	if _, err = os.Lstat(r.opts.OutputDir); err == nil {
+		return fmt.Errorf("output directory '%s' already exists", r.opts.OutputDir)
+	}
+	project, err := filepath.Abs(r.opts.ProjectDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve project directory '%s': %w", r.opts.ProjectDir, err)
+	}
+	output, err := filepath.Abs(r.opts.OutputDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve output directory '%s': %w", r.opts.OutputDir, err)
+	}
+	if rel, err := filepath.Rel(project, output); err == nil && filepath.IsLocal(rel) {
+		return fmt.Errorf("output directory '%s' is inside the project '%s'", r.opts.OutputDir, r.opts.ProjectDir)
+	}
+
+	if !r.opts.WordBoundary {
+		for _, v := range r.opts.Vars {
+			if utf8.RuneCountInString(v.Value) < shortValueLength {
+				fmt.Fprintf(r.out, "⚠️  Value '%s' of '%s' is short and may replace unrelated text, "+
+					"check the result or use --word-boundary\n", v.Value, v.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// visit copies one entry of the project into the template.
+func (r *reverser) visit(path string, d fs.DirEntry, walkErr error) error {
+	if walkErr != nil {
+		return walkErr
+	}
+	rel, err := filepath.Rel(r.opts.ProjectDir, path)
+	if err != nil {
+		return fmt.Errorf("failed to get relative path for '%s': %w", path, err)
+	}
+	if rel == "." {
+		return os.MkdirAll(r.opts.OutputDir, 0750)
+	}
+	if d.IsDir() && slices.Contains(vcsDirs, d.Name()) {
+		return filepath.SkipDir
+	}
+	targetRel := r.templatePath(rel)
+	target := filepath.Join(r.opts.OutputDir, targetRel)
+
+	info, err := d.Info()
+	if err != nil {
+		return fmt.Errorf("failed to stat '%s': %w", path, err)
+	}
+	switch {
+	case d.IsDir():
+		return os.Mkdir(target, 0750)
+	case info.Mode()&fs.ModeSymlink != 0:
+		link, err := os.Readlink(path)
+		if err != nil {
+			return fmt.Errorf("failed to read symlink '%s': %w", path, err)
+		}
+		fmt.Fprintf(r.out, "📄 Copying: %s\n", targetRel)
+		return os.Symlink(link, target)
+	case irregularType(info.Mode()) != "":
+		fmt.Fprintf(r.out, "⚠️  Skipping '%s': it is a %s\n", rel, irregularType(info.Mode()))
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read '%s': %w", path, err)
+	}
+	// Binary files are copied as they are. Files whose name already holds
+	// the '.tmpl' marker have to be rendered to keep their name.
+	action := "📄 Copying"
+	if utf8.Valid(content) && bytes.IndexByte(content, 0) < 0 {
+		_, counts := r.substitute(string(content), false)
+		if _, marked, nameErr := templateName(d.Name()); len(counts) > 0 || marked || nameErr != nil {
+			text, counts := r.substitute(string(content), true)
+			r.add(counts)
+			content = []byte(text)
+			targetRel += ".tmpl"
+			target += ".tmpl"
+			action = "✨ Templating"
+		}
+	}
+	fmt.Fprintf(r.out, "%s: %s\n", action, targetRel)
+	if err = os.WriteFile(target, content, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write '%s': %w", target, err)
+	}
+	return nil
+}
+
+// templatePath returns the template path of a project path relative to its
+// root, with the values replaced in every name. Only the replacements in the
+// last name are counted, the others were counted with their directory. Names
+// Apply treats specially, such as template.yaml at the root or hint files,
+// are quoted in a placeholder so they are generated like any other file, and
+// names Apply would read as holding __name__ tokens, such as __init__.py,
+// are escaped.
+func (r *reverser) templatePath(rel string) string {
+	names := strings.Split(rel, string(filepath.Separator))
+	for i, name := range names {
+		if IsHintFile(name) || (i == 0 && (name == MetadataFile || name == PartialsDir || name == TestsDir)) {
+			names[i] = `{{"` + name + `"}}`
+			continue
+		}
+		var counts map[string]int
+		if names[i], counts = r.substitute(escapePathTokens(name), true); i == len(names)-1 {
+			r.add(counts)
+		}
+	}
+	return filepath.Join(names...)
+}
+
+// substitute replaces the values in text with their placeholders and
+// returns the number of replacements per variable name. With escape, the
+// template delimiters already in text are escaped so the result renders back
+// to the original text.
+func (r *reverser) substitute(text string, escape bool) (string, map[string]int) {
+	var b strings.Builder
+	counts := make(map[string]int)
+	for i := 0; i < len(text); {
+		if delim := text[i:min(i+2, len(text))]; escape && (delim == "{{" || delim == "}}") {
+			b.WriteString(`{{"` + delim + `"}}`)
+			i += 2
+			continue
+		}
+		matched := false
+		for _, v := range r.vars {
+			end := i + len(v.Value)
+			if strings.HasPrefix(text[i:], v.Value) && r.atBoundary(text, i, end) {
+				b.WriteString("{{." + v.Name + "}}")
+				counts[v.Name]++
+				i = end
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteByte(text[i])
+			i++
+		}
+	}
+	return b.String(), counts
+}
+
+// atBoundary reports whether text[start:end] may be replaced: always,
+// unless word boundaries are required and it continues a word on either
+// side.
+func (r *reverser) atBoundary(text string, start, end int) bool {
+	if !r.opts.WordBoundary {
+		return true
+	}
+	first, _ := utf8.DecodeRuneInString(text[start:end])
+	last, _ := utf8.DecodeLastRuneInString(text[start:end])
+	if start > 0 {
+		prev, _ := utf8.DecodeLastRuneInString(text[:start])
+		if isWordRune(prev) && isWordRune(first) {
+			return false
+		}
+	}
+	if end < len(text) {
+		next, _ := utf8.DecodeRuneInString(text[end:])
+		if isWordRune(next) && isWordRune(last) {
+			return false
+		}
+	}
+	return true
+}
+
+// isWordRune reports whether the rune is part of a word.
+func isWordRune(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// add adds replacement counts to the totals.
+func (r *reverser) add(counts map[string]int) {
+	for name, n := range counts {
+		r.counts[name] += n
+	}
+}
+
+// writeMetadata writes the template.yaml declaring the variables as prompts
+// and the tmpl.yaml example data holding their original values.
+func (r *reverser) writeMetadata() error {
+	var meta, example strings.Builder
+	fmt.Fprintf(&meta, "name: %s\n", yamlString(filepath.Base(r.opts.OutputDir)))
+	fmt.Fprintf(&meta, "description: %s\n",
+		yamlString("Created by mold reverse from "+filepath.Base(filepath.Clean(r.opts.ProjectDir))))
+	meta.WriteString("prompts:\n")
+	for _, v := range r.opts.Vars {
+		fmt.Fprintf(&meta, "  %s:\n    description: %s\n    type: string\n",
+			v.Name, yamlString("Replaces '"+v.Value+"' in the original project"))
+		fmt.Fprintf(&example, "%s: %s\n", v.Name, yamlString(v.Value))
+	}
+
+	files := map[string]string{MetadataFile: meta.String(), "tmpl.yaml": example.String()}
+	for _, name := range []string{MetadataFile, "tmpl.yaml"} {
+		path := filepath.Join(r.opts.OutputDir, name)
+		//nolint:gosec // template files are meant to be shared
+		if err := os.WriteFile(path, []byte(files[name]), 0644); err != nil {
+			return fmt.Errorf("failed to write '%s': %w", path, err)
+		}
+	}
+	return nil
+}
+
+// yamlString returns s as a single-line YAML scalar, quoted when needed.
+func yamlString(s string) string {
+	out, err := yaml.Marshal(s)
+	if err != nil || strings.Contains(s, "\n") {
+		return strconv.Quote(s)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
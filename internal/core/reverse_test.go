@@ -0,0 +1,186 @@
+package core
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readFiles reads the files under dir as strings, keyed by their
+// slash-separated relative path.
+func readFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	tree, err := readTree(dir)
+	if err != nil {
+		t.Fatalf("readTree failed: %v", err)
+	}
+	files := make(map[string]string, len(tree))
+	for path, content := range tree {
+		files[path] = string(content)
+	}
+	return files
+}
+
+func TestReverse(t *testing.T) {
+	project := writeTemplate(t, map[string]string{
+		"go.mod":               "module github.com/acme/svc\n\ngo 1.24\n",
+		"cmd/acme/main.go":     "package main // the acme service\n\nimport _ \"github.com/acme/svc/internal\"\n",
+		"README.md":            "# acme\n\nKeeps {{ mustache }} braces.\n",
+		"LICENSE":              "MIT\n",
+		"logo.bin":             "\x00acme",
+		"template.yaml":        "owner: acme\n",
+		"config/tmpl.yaml":     "hint: acme\n",
+		"_partials/footer.txt": "footer\n",
+		"page.html.tmpl":       "{{.title}}\n",
+		"lib/__init__.py":      "",
+		".git/HEAD":            "ref: refs/heads/acme\n",
+	})
+	templateDir := filepath.Join(t.TempDir(), "acme-template")
+	var out bytes.Buffer
+
+	counts, err := Reverse(ReverseOptions{
+		ProjectDir: project,
+		OutputDir:  templateDir,
+		Vars: []ReverseVar{
+			{Name: "project_name", Value: "acme"},
+			{Name: "module", Value: "github.com/acme/svc"},
+		},
+		Out: &out,
+	})
+	if err != nil {
+		t.Fatalf("Reverse failed: %v", err)
+	}
+	wantCounts := map[string]int{"project_name": 5, "module": 2}
+	if !reflect.DeepEqual(counts, wantCounts) {
+		t.Errorf("Expected counts %v, got %v", wantCounts, counts)
+	}
+
+	files := readFiles(t, templateDir)
+	want := map[string]string{
+		"go.mod.tmpl": "module {{.module}}\n\ngo 1.24\n",
+		"cmd/{{.project_name}}/main.go.tmpl": "package main // the {{.project_name}} service\n\n" +
+			"import _ \"{{.module}}/internal\"\n",
+		"README.md.tmpl":              "# {{.project_name}}\n\nKeeps {{\"{{\"}} mustache {{\"}}\"}} braces.\n",
+		"LICENSE":                     "MIT\n",
+		"logo.bin":                    "\x00acme",
+		`{{"template.yaml"}}.tmpl`:    "owner: {{.project_name}}\n",
+		`config/{{"tmpl.yaml"}}.tmpl`: "hint: {{.project_name}}\n",
+		`{{"_partials"}}/footer.txt`:  "footer\n",
+		"page.html.tmpl.tmpl":         "{{\"{{\"}}.title{{\"}}\"}}\n",
+		"lib/____init____.py":         "",
+		MetadataFile:                  files[MetadataFile],
+		"tmpl.yaml":                   "project_name: acme\nmodule: github.com/acme/svc\n",
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("Unexpected template files:\ngot:  %q\nwant: %q", files, want)
+	}
+	meta, err := LoadMetadata(templateDir)
+	if err != nil {
+		t.Fatalf("LoadMetadata failed: %v", err)
+	}
+	if meta.Name != "acme-template" || len(meta.Prompts) != 2 || meta.Prompts[0].Name != "project_name" {
+		t.Errorf("Unexpected metadata: %+v", meta)
+	}
+	if contains(out.String(), "is short") {
+		t.Errorf("Expected no short value warning, got:\n%s", out.String())
+	}
+
+	// Applying the template with the original values gives the project back.
+	data, err := LoadDataFile(filepath.Join(templateDir, "tmpl.yaml"), DataOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("Failed to load the example data: %v", err)
+	}
+	outputDir := t.TempDir()
+	err = Apply(Options{
+		TemplatePath: templateDir, OutputDir: outputDir, Data: data, Strict: true, NoProvenance: true, Out: &out,
+	})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	original := readFiles(t, project)
+	delete(original, ".git/HEAD")
+	if generated := readFiles(t, outputDir); !reflect.DeepEqual(generated, original) {
+		t.Errorf("Round trip mismatch:\ngot:  %q\nwant: %q", generated, original)
+	}
+}
+
+func TestReverseWordBoundary(t *testing.T) {
+	project := writeTemplate(t, map[string]string{"notes.txt": "go gopher ago go_lang go-lang"})
+	tests := []struct {
+		wordBoundary bool
+		wantCount    int
+		wantWarning  bool
+	}{
+		{wordBoundary: false, wantCount: 5, wantWarning: true},
+		{wordBoundary: true, wantCount: 2, wantWarning: false},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		counts, err := Reverse(ReverseOptions{
+			ProjectDir:   project,
+			OutputDir:    filepath.Join(t.TempDir(), "template"),
+			Vars:         []ReverseVar{{Name: "lang", Value: "go"}},
+			WordBoundary: tt.wordBoundary,
+			Out:          &out,
+		})
+		if err != nil {
+			t.Fatalf("Reverse failed: %v", err)
+		}
+		if counts["lang"] != tt.wantCount {
+			t.Errorf("word boundary %v: expected %d replacements, got %d", tt.wordBoundary, tt.wantCount, counts["lang"])
+		}
+		if got := contains(out.String(), "Value 'go' of 'lang' is short"); got != tt.wantWarning {
+			t.Errorf("word boundary %v: expected warning %v, got:\n%s", tt.wordBoundary, tt.wantWarning, out.String())
+		}
+	}
+}
+
+func TestReverseErrors(t *testing.T) {
+	project := writeTemplate(t, map[string]string{"a.txt": "acme"})
+	existing := t.TempDir()
+	acme := []ReverseVar{{Name: "name", Value: "acme"}}
+
+	tests := []struct {
+		name    string
+		project string
+		output  string
+		vars    []ReverseVar
+		wantErr string
+	}{
+		{name: "no vars", project: project, wantErr: "no variables to reverse"},
+		{name: "bad name", project: project, vars: []ReverseVar{{Name: "a-b", Value: "x"}}, wantErr: "invalid variable"},
+		{name: "empty value", project: project, vars: []ReverseVar{{Name: "a", Value: ""}}, wantErr: "empty value"},
+		{
+			name:    "twice",
+			project: project,
+			vars:    []ReverseVar{{Name: "a", Value: "x"}, {Name: "a", Value: "y"}},
+			wantErr: "given more than once",
+		},
+		{
+			name:    "same value",
+			project: project,
+			vars:    []ReverseVar{{Name: "a", Value: "x"}, {Name: "b", Value: "x"}},
+			wantErr: "have the same value 'x'",
+		},
+		{name: "missing project", project: filepath.Join(project, "nope"), vars: acme, wantErr: "not found"},
+		{name: "existing output", project: project, output: existing, vars: acme, wantErr: "already exists"},
+		{
+			name:    "output inside project",
+			project: project,
+			output:  filepath.Join(project, "template"),
+			vars:    acme,
+			wantErr: "is inside the project",
+		},
+	}
+	for _, tt := range tests {
+		output := tt.output
+		if output == "" {
+			output = filepath.Join(t.TempDir(), "template")
+		}
+		_, err := Reverse(ReverseOptions{ProjectDir: tt.project, OutputDir: output, Vars: tt.vars, Out: &bytes.Buffer{}})
+		if err == nil || !contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error containing %q, got: %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// SecretMask replaces the values of secret prompts in messages.
+const SecretMask = "******"
+
+// secrets are the values of secret prompts, masked in every message.
+type secrets []string
+
+// collect adds the values of the secret prompts found in data, keeping the
+// longest values first so a value containing another one is masked whole.
+func (s secrets) collect(prompts Prompts, data map[string]any) secrets {
+	for _, prompt := range prompts {
+		if !prompt.Secret {
+			continue
+		}
+		if value, ok := LookupValue(data, prompt.Name); ok && value != nil {
+			if text := fmt.Sprint(value); text != "" && !slices.Contains(s, text) {
+				s = append(s, text)
+			}
+		}
+	}
+	slices.SortStableFunc(s, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+	return s
+}
+
+// mask replaces every secret value in text.
+func (s secrets) mask(text string) string {
+	for _, value := range s {
+		text = strings.ReplaceAll(text, value, SecretMask)
+	}
+	return text
+}
+
+// maskError returns err with every secret value masked in its message.
+func (s secrets) maskError(err error) error {
+	if err == nil || len(s) == 0 {
+		return err
+	}
+	if msg := s.mask(err.Error()); msg != err.Error() {
+		return &maskedError{msg: msg, err: err}
+	}
+	return err
+}
+
+// maskedError is an error whose message has its secret values masked. It
+// still unwraps to the original error for errors.Is and errors.As.
+type maskedError struct {
+	msg string
+	err error
+}
+
+// Error returns the masked message.
+func (e *maskedError) Error() string { return e.msg }
+
+// Unwrap returns the original error.
+func (e *maskedError) Unwrap() error { return e.err }
+
+// maskWriter masks secret values in the messages written through it. Every
+// message is expected in a single write.
+type maskWriter struct {
+	w       io.Writer
+	secrets secrets
+}
+
+// Write writes p with its secret values masked.
+func (m *maskWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(m.w, m.secrets.mask(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+func TestSecrets(t *testing.T) {
+	prompts := Prompts{
+		{Name: "api_key", Secret: true},
+		{Name: "db.password", Secret: true},
+		{Name: "pin", Secret: true},
+		{Name: "missing", Secret: true},
+		{Name: "name"},
+	}
+	data := map[string]any{
+		"api_key": "key",
+		"db":      map[string]any{"password": "key-and-more"},
+		"pin":     1234,
+		"name":    "visible",
+	}
+
+	var s secrets
+	s = s.collect(prompts, data)
+	s = s.collect(prompts, data)
+	if len(s) != 3 || s[0] != "key-and-more" {
+		t.Fatalf("Expected three secrets, longest first, got %q", s)
+	}
+
+	if got := s.mask("key-and-more, key, 1234 and visible"); got != "******, ******, ****** and visible" {
+		t.Errorf("Unexpected masked text: %q", got)
+	}
+
+	err := s.maskError(fmt.Errorf("wrapped: %w", &fs.PathError{Op: "open", Path: "key.txt", Err: fs.ErrNotExist}))
+	if contains(err.Error(), "key.txt") || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected a masked error that still unwraps, got %v", err)
+	}
+	if plain := errors.New("nothing secret"); s.maskError(plain) != plain {
+		t.Error("Expected errors without secrets to be returned as is")
+	}
+
+	var out bytes.Buffer
+	w := &maskWriter{w: &out, secrets: s}
+	if n, err := w.Write([]byte("pin 1234\n")); n != 9 || err != nil {
+		t.Errorf("Write() = %d, %v; want 9, nil", n, err)
+	}
+	if out.String() != "pin ******\n" {
+		t.Errorf("Unexpected masked output: %q", out.String())
+	}
+}
@@ -0,0 +1,427 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Semver is a semantic version, such as the v1.4.2 of a git tag.
+type Semver struct {
+	Major, Minor, Patch int
+	// Pre are the dot-separated identifiers of a pre-release, such as
+	// ["rc", "1"] for 1.0.0-rc.1.
+	Pre []string
+}
+
+// ParseSemver parses a version of the form [v]major[.minor[.patch]] with an
+// optional -pre-release and +build metadata, which is ignored. Missing minor
+// and patch numbers are 0, so the tag v1.4 is 1.4.0.
+func ParseSemver(s string) (Semver, error) {
+	p, err := parsePartial(s)
+	if err != nil {
+		return Semver{}, err
+	}
+	if p.wild < 3 && p.given > p.wild {
+		return Semver{}, fmt.Errorf("invalid version '%s': wildcards only apply to constraints", s)
+	}
+	return p.Semver, nil
+}
+
+// String formats the version without a 'v' prefix.
+func (v Semver) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Pre) > 0 {
+		s += "-" + strings.Join(v.Pre, ".")
+	}
+	return s
+}
+
+// Compare orders versions by semver precedence: a pre-release comes before
+// its release, and numeric identifiers compare as numbers.
+func (v Semver) Compare(o Semver) int {
+	if c := cmp.Compare(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+	switch {
+	case len(v.Pre) == 0 && len(o.Pre) == 0:
+		return 0
+	case len(v.Pre) == 0:
+		return 1
+	case len(o.Pre) == 0:
+		return -1
+	}
+	for i := range min(len(v.Pre), len(o.Pre)) {
+		if c := comparePre(v.Pre[i], o.Pre[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(v.Pre), len(o.Pre))
+}
+
+// comparePre compares pre-release identifiers: numbers numerically and
+// before words, words in ASCII order.
+func comparePre(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return cmp.Compare(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// partial is a version of a constraint, where trailing numbers may be
+// missing or wildcards.
+type partial struct {
+	Semver
+	// given is how many of major, minor and patch are written, wild the
+	// index of the first wildcard or missing number, 3 for none.
+	given, wild int
+}
+
+// parsePartial parses a version with optional trailing wildcards, 'x', 'X'
+// or '*', or missing numbers.
+func parsePartial(s string) (partial, error) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(s, "v"), "V")
+	rest, _, _ = strings.Cut(rest, "+")
+	core, pre, hasPre := strings.Cut(rest, "-")
+	p := partial{wild: 3}
+	if hasPre {
+		if pre == "" {
+			return partial{}, fmt.Errorf("invalid version '%s': empty pre-release", s)
+		}
+		p.Pre = strings.Split(pre, ".")
+		for _, id := range p.Pre {
+			if id == "" {
+				return partial{}, fmt.Errorf("invalid version '%s': empty pre-release identifier", s)
+			}
+		}
+	}
+	parts := strings.Split(core, ".")
+	if core == "" || len(parts) > 3 {
+		return partial{}, fmt.Errorf("invalid version '%s': expected major[.minor[.patch]]", s)
+	}
+	p.given = len(parts)
+	numbers := []*int{&p.Major, &p.Minor, &p.Patch}
+	for i, part := range parts {
+		if part == "x" || part == "X" || part == "*" {
+			p.wild = min(p.wild, i)
+			continue
+		}
+		if p.wild < 3 {
+			return partial{}, fmt.Errorf("invalid version '%s': a number follows a wildcard", s)
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || part[0] == '+' {
+			return partial{}, fmt.Errorf("invalid version '%s': '%s' is not a number", s, part)
+		}
+		*numbers[i] = n
+	}
+	p.wild = min(p.wild, p.given)
+	if p.wild < 3 && hasPre {
+		return partial{}, fmt.Errorf("invalid version '%s': a pre-release needs a full version", s)
+	}
+	return p, nil
+}
+
+// bump returns the first version after every version starting with the
+// first n numbers of p, such as 1.3.0-0 for the first 2 of 1.2. It's the
+// lowest pre-release of the next version, which a range up to it excludes.
+func (p partial) bump(n int) Semver {
+	lowest := []string{"0"}
+	switch n {
+	case 0:
+		// Nothing is after every version: a bound no version reaches.
+		return Semver{Major: int(^uint(0) >> 1)}
+	case 1:
+		return Semver{Major: p.Major + 1, Pre: lowest}
+	case 2:
+		return Semver{Major: p.Major, Minor: p.Minor + 1, Pre: lowest}
+	default:
+		return Semver{Major: p.Major, Minor: p.Minor, Patch: p.Patch + 1, Pre: lowest}
+	}
+}
+
+// floor returns p with its wildcards and missing numbers set to 0.
+func (p partial) floor() Semver {
+	v := Semver{Major: p.Major, Minor: p.Minor, Patch: p.Patch, Pre: p.Pre}
+	if p.wild < 2 {
+		v.Minor = 0
+	}
+	if p.wild < 3 {
+		v.Patch = 0
+	}
+	if p.wild < 1 {
+		v.Major = 0
+	}
+	return v
+}
+
+// comparator is a single comparison of a constraint, such as >=1.2.0.
+type comparator struct {
+	op string
+	v  Semver
+}
+
+func (c comparator) matches(v Semver) bool {
+	n := v.Compare(c.v)
+	switch c.op {
+	case ">":
+		return n > 0
+	case ">=":
+		return n >= 0
+	case "<":
+		return n < 0
+	case "<=":
+		return n <= 0
+	case "!=":
+		return n != 0
+	default:
+		return n == 0
+	}
+}
+
+// Constraint is a set of acceptable versions, such as ^1.4 or >=1.2 <2.
+type Constraint struct {
+	raw string
+	// alternatives are the ranges separated by '||', each a list of
+	// comparators that must all match.
+	alternatives [][]comparator
+	// pre tells whether the constraint names a pre-release, the only case
+	// pre-release versions match.
+	pre bool
+}
+
+// ParseConstraint parses a version constraint. It holds ranges separated by
+// '||', matching when any does. A range is a list of comparisons separated
+// by spaces or commas, matching when all do:
+//
+//   - 1.2.3 or =1.2.3 is that version, 1.2 or 1.2.x any 1.2 version, and
+//     *, x or an empty range any version.
+//   - >, >=, < and <= compare with a version, missing numbers being 0,
+//     except for > and <=, where >1.2 means >=1.3.0 and <=1.2 means <1.3.0.
+//     != excludes a version.
+//   - ^1.2.3 allows changes that keep the first non-zero number: >=1.2.3
+//     <2.0.0, and ^0.2.3 means >=0.2.3 <0.3.0.
+//   - ~1.2.3 allows patch changes: >=1.2.3 <1.3.0. ~1 means >=1.0.0 <2.0.0.
+//   - 1.2 - 1.4 is the inclusive range >=1.2.0 <1.5.0.
+//
+// Pre-release versions only match a constraint naming a pre-release.
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{raw: s}
+	for _, alternative := range strings.Split(s, "||") {
+		comparators, pre, err := parseRange(alternative)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint '%s': %w", s, err)
+		}
+		c.pre = c.pre || pre
+		c.alternatives = append(c.alternatives, comparators)
+	}
+	return c, nil
+}
+
+// String returns the constraint as written.
+func (c *Constraint) String() string {
+	return c.raw
+}
+
+// Check reports whether a version satisfies the constraint.
+func (c *Constraint) Check(v Semver) bool {
+	if len(v.Pre) > 0 && !c.pre {
+		return false
+	}
+	for _, alternative := range c.alternatives {
+		matches := true
+		for _, comparator := range alternative {
+			if !comparator.matches(v) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRange parses the comparisons of a range of a constraint, and tells
+// whether it names a pre-release.
+func parseRange(s string) ([]comparator, bool, error) {
+	var tokens []string
+	for _, field := range strings.Fields(strings.ReplaceAll(s, ",", " ")) {
+		// Join an operator written apart from its version, as in '>= 1.2'.
+		if n := len(tokens); n > 0 && strings.Trim(tokens[n-1], "<>=!~^") == "" {
+			tokens[n-1] += field
+			continue
+		}
+		tokens = append(tokens, field)
+	}
+	var comparators []comparator
+	pre := false
+	for i := 0; i < len(tokens); i++ {
+		if i+2 < len(tokens) && tokens[i+1] == "-" {
+			hyphen, err := parseHyphen(tokens[i], tokens[i+2])
+			if err != nil {
+				return nil, false, err
+			}
+			comparators = append(comparators, hyphen...)
+			pre = pre || namesPre(tokens[i]) || namesPre(tokens[i+2])
+			i += 2
+			continue
+		}
+		parsed, err := parseComparator(tokens[i])
+		if err != nil {
+			return nil, false, err
+		}
+		comparators = append(comparators, parsed...)
+		pre = pre || namesPre(tokens[i])
+	}
+	if len(comparators) == 0 {
+		// An empty range, as in '1.2 || ', is any version.
+		comparators = []comparator{{op: ">=", v: Semver{}}}
+	}
+	return comparators, pre, nil
+}
+
+// namesPre tells whether a version of a constraint, parsed already, names a
+// pre-release rather than build metadata only.
+func namesPre(token string) bool {
+	version, _, _ := strings.Cut(token, "+")
+	return strings.Contains(version, "-")
+}
+
+// parseHyphen parses the inclusive range 'from - to'.
+func parseHyphen(from, to string) ([]comparator, error) {
+	lo, err := parsePartial(from)
+	if err != nil {
+		return nil, err
+	}
+	hi, err := parsePartial(to)
+	if err != nil {
+		return nil, err
+	}
+	comparators := []comparator{{op: ">=", v: lo.floor()}}
+	switch {
+	case hi.wild == 0:
+	case hi.wild < 3:
+		comparators = append(comparators, comparator{op: "<", v: hi.bump(hi.wild)})
+	default:
+		comparators = append(comparators, comparator{op: "<=", v: hi.Semver})
+	}
+	return comparators, nil
+}
+
+// parseComparator parses a comparison, such as ^1.2 or >=1.0.0, into the
+// comparators it stands for.
+func parseComparator(token string) ([]comparator, error) {
+	op := token[:len(token)-len(strings.TrimLeft(token, "<>=!~^"))]
+	p, err := parsePartial(token[len(op):])
+	if err != nil {
+		return nil, err
+	}
+	lo := p.floor()
+	switch op {
+	case "", "=":
+		if p.wild == 3 {
+			return []comparator{{op: "=", v: p.Semver}}, nil
+		}
+		return span(lo, p.bump(p.wild), p.wild), nil
+	case "!=":
+		if p.wild < 3 {
+			return nil, fmt.Errorf("'%s' needs a full version", token)
+		}
+		return []comparator{{op: "!=", v: p.Semver}}, nil
+	case ">":
+		if p.wild < 3 {
+			return []comparator{{op: ">=", v: p.bump(p.wild)}}, nil
+		}
+		return []comparator{{op: ">", v: p.Semver}}, nil
+	case ">=":
+		return []comparator{{op: ">=", v: lo}}, nil
+	case "<":
+		return []comparator{{op: "<", v: lo}}, nil
+	case "<=":
+		if p.wild < 3 {
+			return []comparator{{op: "<", v: p.bump(p.wild)}}, nil
+		}
+		return []comparator{{op: "<=", v: p.Semver}}, nil
+	case "~", "~>":
+		// ~1 allows minor changes, ~1.2 and ~1.2.3 patch changes.
+		return span(lo, p.bump(max(min(p.wild, 2), 1)), p.wild), nil
+	case "^":
+		// Keep the first non-zero number, or the numbers given when all
+		// are zero, as in ^0.0.3 and ^0.0.
+		keep := 1
+		switch {
+		case p.Major != 0 || p.wild <= 1:
+		case p.Minor != 0 || p.wild == 2:
+			keep = 2
+		default:
+			keep = 3
+		}
+		return span(lo, p.bump(keep), p.wild), nil
+	default:
+		return nil, fmt.Errorf("unknown operator '%s' in '%s'", op, token)
+	}
+}
+
+// span returns the comparators of the versions from lo up to hi, excluded.
+// A version written as a wildcard from its major number, such as '*', is any
+// version.
+func span(lo, hi Semver, wild int) []comparator {
+	if wild == 0 {
+		return []comparator{{op: ">=", v: Semver{}}}
+	}
+	return []comparator{{op: ">=", v: lo}, {op: "<", v: hi}}
+}
+
+// selectVersion returns the version of a template matching a requested
+// version: one of the available versions, as written, or the highest one
+// satisfying it as a constraint.
+func selectVersion(template, requested string, available []string) (string, error) {
+	for _, version := range available {
+		if version == requested {
+			return version, nil
+		}
+	}
+	constraint, err := ParseConstraint(requested)
+	if err != nil {
+		if strings.ContainsAny(requested, "<>=!~^*| ") {
+			return "", err
+		}
+		return "", unknownVersion(template, requested, available)
+	}
+	best, found := "", Semver{}
+	for _, version := range available {
+		v, err := ParseSemver(version)
+		if err != nil || !constraint.Check(v) {
+			continue
+		}
+		if best == "" || v.Compare(found) > 0 {
+			best, found = version, v
+		}
+	}
+	if best != "" {
+		return best, nil
+	}
+	if p, err := parsePartial(requested); err == nil && p.wild == 3 || len(available) == 0 {
+		// A full version is a pin rather than a range.
+		return "", unknownVersion(template, requested, available)
+	}
+	return "", fmt.Errorf("template '%s' has no version matching '%s', available versions: %s", template,
+		requested, strings.Join(available, ", "))
+}
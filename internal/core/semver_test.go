@@ -0,0 +1,194 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Semver
+		err  bool
+	}{
+		{in: "1.2.3", want: Semver{Major: 1, Minor: 2, Patch: 3}},
+		{in: "v1.2.3", want: Semver{Major: 1, Minor: 2, Patch: 3}},
+		{in: "v1.4", want: Semver{Major: 1, Minor: 4}},
+		{in: "v2", want: Semver{Major: 2}},
+		{in: "1.0.0-rc.1", want: Semver{Major: 1, Pre: []string{"rc", "1"}}},
+		{in: "1.0.0-beta+build.5", want: Semver{Major: 1, Pre: []string{"beta"}}},
+		{in: "1.0.0+build.5", want: Semver{Major: 1}},
+		{in: "1.x", err: true},
+		{in: "*", err: true},
+		{in: "1.2.3.4", err: true},
+		{in: "1.2.3-", err: true},
+		{in: "1.2.3-rc..1", err: true},
+		{in: "1.a.3", err: true},
+		{in: "1.-2.3", err: true},
+		{in: "stable", err: true},
+		{in: "", err: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSemver(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseSemver(%q): expected an error, got %v", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSemver(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	// Each version is lower than the next, following the semver precedence.
+	ordered := []string{
+		"0.9.9", "1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2",
+		"1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.2.0", "1.10.0", "2.0.0",
+	}
+	for i := range len(ordered) - 1 {
+		a, err := ParseSemver(ordered[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ParseSemver(ordered[i+1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.Compare(b) >= 0 || b.Compare(a) <= 0 || a.Compare(a) != 0 {
+			t.Errorf("Expected %s < %s", ordered[i], ordered[i+1])
+		}
+	}
+	if v, _ := ParseSemver("v1.0.0+build"); v.String() != "1.0.0" {
+		t.Errorf("Expected the build metadata to be ignored, got %s", v)
+	}
+}
+
+func TestConstraintCheck(t *testing.T) {
+	tests := []struct {
+		constraint string
+		match      []string
+		noMatch    []string
+	}{
+		{constraint: "1.2.3", match: []string{"1.2.3", "v1.2.3"}, noMatch: []string{"1.2.4", "1.2.3-rc.1"}},
+		{constraint: "=v1.2.3", match: []string{"1.2.3"}, noMatch: []string{"1.2.2"}},
+		{constraint: "1.2", match: []string{"1.2.0", "1.2.9"}, noMatch: []string{"1.3.0", "1.1.9"}},
+		{constraint: "1.2.x", match: []string{"1.2.0", "1.2.9"}, noMatch: []string{"1.3.0"}},
+		{constraint: "1.X", match: []string{"1.0.0", "1.9.0"}, noMatch: []string{"2.0.0", "0.9.0"}},
+		{constraint: "*", match: []string{"0.0.1", "9.9.9"}, noMatch: []string{"1.0.0-rc.1"}},
+		{constraint: "1.0.0+build-5", match: []string{"1.0.0"}, noMatch: []string{"1.0.0-rc.1"}},
+		{constraint: "x", match: []string{"1.0.0"}},
+		{constraint: "^1.2.3", match: []string{"1.2.3", "1.9.0"}, noMatch: []string{"1.2.2", "2.0.0", "1.3.0-rc.1"}},
+		{constraint: "^1.4", match: []string{"1.4.0", "1.10.0"}, noMatch: []string{"1.3.9", "2.0.0"}},
+		{constraint: "^0.2.3", match: []string{"0.2.3", "0.2.9"}, noMatch: []string{"0.3.0", "0.2.2"}},
+		{constraint: "^0.0.3", match: []string{"0.0.3"}, noMatch: []string{"0.0.4", "0.0.2"}},
+		{constraint: "^0.0", match: []string{"0.0.0", "0.0.9"}, noMatch: []string{"0.1.0"}},
+		{constraint: "^0", match: []string{"0.0.0", "0.9.0"}, noMatch: []string{"1.0.0"}},
+		{constraint: "^1.x", match: []string{"1.0.0", "1.9.0"}, noMatch: []string{"2.0.0"}},
+		{constraint: "~1.2.3", match: []string{"1.2.3", "1.2.9"}, noMatch: []string{"1.3.0", "1.2.2"}},
+		{constraint: "~1.2", match: []string{"1.2.0", "1.2.9"}, noMatch: []string{"1.3.0"}},
+		{constraint: "~1", match: []string{"1.0.0", "1.9.9"}, noMatch: []string{"2.0.0"}},
+		{constraint: "~>1.2", match: []string{"1.2.5"}, noMatch: []string{"1.3.0"}},
+		{constraint: ">1.2.3", match: []string{"1.2.4", "2.0.0"}, noMatch: []string{"1.2.3"}},
+		{constraint: ">1.2", match: []string{"1.3.0"}, noMatch: []string{"1.2.9"}},
+		{constraint: ">=1.2", match: []string{"1.2.0", "3.0.0"}, noMatch: []string{"1.1.9"}},
+		{constraint: "<1.2", match: []string{"1.1.9"}, noMatch: []string{"1.2.0"}},
+		{constraint: "<=1.2", match: []string{"1.2.9"}, noMatch: []string{"1.3.0"}},
+		{constraint: "<=1.2.3", match: []string{"1.2.3"}, noMatch: []string{"1.2.4"}},
+		{constraint: ">=1.2 <2", match: []string{"1.2.0", "1.9.9"}, noMatch: []string{"2.0.0", "1.1.0"}},
+		{constraint: ">=1.2, <2", match: []string{"1.5.0"}, noMatch: []string{"2.1.0"}},
+		{constraint: ">= 1.2 < 2", match: []string{"1.5.0"}, noMatch: []string{"2.1.0"}},
+		{constraint: "^1.2 != 1.4.0", match: []string{"1.3.0", "1.4.1"}, noMatch: []string{"1.4.0"}},
+		{constraint: "1.2 - 1.4", match: []string{"1.2.0", "1.4.9"}, noMatch: []string{"1.5.0", "1.1.9"}},
+		{constraint: "1.2.3 - 1.4.5", match: []string{"1.2.3", "1.4.5"}, noMatch: []string{"1.4.6", "1.2.2"}},
+		{constraint: "^1 || ^3", match: []string{"1.5.0", "3.1.0"}, noMatch: []string{"2.0.0"}},
+		{
+			constraint: ">=1.0.0-rc.1",
+			match:      []string{"1.0.0-rc.1", "1.0.0-rc.2", "1.0.0"},
+			noMatch:    []string{"1.0.0-beta.1"},
+		},
+		{constraint: "^2.0.0-0", match: []string{"2.0.0-alpha", "2.1.0"}, noMatch: []string{"3.0.0-alpha"}},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Errorf("ParseConstraint(%q): %v", tt.constraint, err)
+			continue
+		}
+		if c.String() != tt.constraint {
+			t.Errorf("Expected the constraint %q as written, got %q", tt.constraint, c)
+		}
+		for _, version := range tt.match {
+			if v, err := ParseSemver(version); err != nil || !c.Check(v) {
+				t.Errorf("Expected %s to satisfy %q, got %v", version, tt.constraint, err)
+			}
+		}
+		for _, version := range tt.noMatch {
+			if v, err := ParseSemver(version); err != nil || c.Check(v) {
+				t.Errorf("Expected %s not to satisfy %q, got %v", version, tt.constraint, err)
+			}
+		}
+	}
+}
+
+func TestParseConstraintInvalid(t *testing.T) {
+	tests := []struct {
+		constraint, err string
+	}{
+		{constraint: "=>1.2", err: "unknown operator '=>'"},
+		{constraint: "^", err: "expected major[.minor[.patch]]"},
+		{constraint: "^1.x.3", err: "a number follows a wildcard"},
+		{constraint: "~1.x-rc.1", err: "a pre-release needs a full version"},
+		{constraint: "!=1.2", err: "'!=1.2' needs a full version"},
+		{constraint: ">=1.2 || <a", err: "'a' is not a number"},
+		{constraint: "1.2.3.4", err: "expected major[.minor[.patch]]"},
+	}
+	for _, tt := range tests {
+		_, err := ParseConstraint(tt.constraint)
+		if err == nil || !contains(err.Error(), "invalid version constraint '"+tt.constraint+"'") ||
+			!contains(err.Error(), tt.err) {
+			t.Errorf("ParseConstraint(%q): expected an error containing %q, got: %v", tt.constraint, tt.err, err)
+		}
+	}
+}
+
+func TestSelectVersion(t *testing.T) {
+	available := []string{"v1.2.0", "v1.4.2", "v1.10.0", "v2.0.0-rc.1", "stable", "v0.9"}
+	tests := []struct {
+		requested, want, err string
+	}{
+		{requested: "stable", want: "stable"},
+		{requested: "v1.4.2", want: "v1.4.2"},
+		{requested: "1.4.2", want: "v1.4.2"},
+		{requested: "^1.4", want: "v1.10.0"},
+		{requested: "~1.4", want: "v1.4.2"},
+		{requested: "1.x", want: "v1.10.0"},
+		{requested: "v1", want: "v1.10.0"},
+		{requested: "*", want: "v1.10.0"},
+		{requested: "<1", want: "v0.9"},
+		{requested: ">=1.2 <1.10", want: "v1.4.2"},
+		{requested: "^2.0.0-rc.0", want: "v2.0.0-rc.1"},
+		{requested: "^2", err: "has no version matching '^2', available versions: v1.2.0, v1.4.2"},
+		{requested: "v1.4.3", err: "has no version 'v1.4.3', available versions: v1.2.0"},
+		{requested: "latest", err: "has no version 'latest', available versions: v1.2.0"},
+		{requested: "^1.x.2", err: "invalid version constraint '^1.x.2'"},
+	}
+	for _, tt := range tests {
+		got, err := selectVersion("go", tt.requested, available)
+		if tt.err != "" {
+			if err == nil || !contains(err.Error(), tt.err) {
+				t.Errorf("selectVersion(%q): expected an error containing %q, got %q, %v", tt.requested, tt.err,
+					got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("selectVersion(%q) = %q, %v; want %q", tt.requested, got, err, tt.want)
+		}
+	}
+	if _, err := selectVersion("go", "^1", nil); err == nil || !contains(err.Error(), "nor any other") {
+		t.Errorf("Expected a template without versions to fail, got: %v", err)
+	}
+}
@@ -0,0 +1,326 @@
+package core
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0m3kk/mold/internal/utils"
+)
+
+// Sink receives the directories and files generated by Apply. Paths are
+// slash-separated and relative to the output root.
+type Sink interface {
+	// Mkdir records a directory.
+	Mkdir(relPath string, mode fs.FileMode) error
+	// Create opens a file of the given size for writing.
+	Create(relPath string, mode fs.FileMode, size int64) (io.WriteCloser, error)
+	// Commit finishes the output after a successful run.
+	Commit() error
+	// Abort discards what can be discarded after a failed run.
+	Abort() error
+}
+
+// archiveFormats maps the supported archive extensions to their format.
+//
+//nolint:gochecknoglobals // lookup table of supported archive extensions
+var archiveFormats = []struct {
+	ext    string
+	format string
+}{
+	{".tar.gz", "tar.gz"},
+	{".tgz", "tar.gz"},
+	{".tar", "tar"},
+	{".zip", "zip"},
+}
+
+// ArchiveFormat returns the archive format implied by the extension of path,
+// or an empty string when path is not an archive.
+func ArchiveFormat(path string) string {
+	lower := strings.ToLower(path)
+	for _, f := range archiveFormats {
+		if strings.HasSuffix(lower, f.ext) {
+			return f.format
+		}
+	}
+	return ""
+}
+
+// NewSink returns an archive sink when the output path has an archive
+// extension and a directory sink otherwise. Archive entries are timestamped
+// with modTime, or the current time when it is zero.
+func NewSink(output string, modTime time.Time) (Sink, error) {
+	if format := ArchiveFormat(output); format != "" {
+		return NewArchiveSink(output, format, modTime)
+	}
+	return NewDirSink(output)
+}
+
+// DirSink writes the output into a directory on disk.
+type DirSink struct {
+	dir string
+	// ParentMode, when set, is the mode Create creates the missing parent
+	// directories of a file with. When zero, Create fails unless the parent
+	// directory was created first.
+	ParentMode fs.FileMode
+}
+
+// NewDirSink creates the output directory if it doesn't exist.
+func NewDirSink(dir string) (*DirSink, error) {
+	if err := os.MkdirAll(dir, utils.DirMode); err != nil {
+		return nil, fmt.Errorf("failed to create output directory '%s': %w", dir, err)
+	}
+	return &DirSink{dir: dir}, nil
+}
+
+// Path returns the location on disk of the output path.
+func (s *DirSink) Path(relPath string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(relPath))
+}
+
+// Mkdir creates the directory on disk.
+func (s *DirSink) Mkdir(relPath string, _ fs.FileMode) error {
+	return os.MkdirAll(s.Path(relPath), utils.DirMode)
+}
+
+// Create creates the file on disk. Its mode is applied when it is closed.
+func (s *DirSink) Create(relPath string, mode fs.FileMode, _ int64) (io.WriteCloser, error) {
+	path := s.Path(relPath)
+	if s.ParentMode != 0 {
+		if err := os.MkdirAll(filepath.Dir(path), s.ParentMode); err != nil {
+			return nil, fmt.Errorf("failed to create directory for '%s': %w", path, err)
+		}
+	}
+	// Writing through a link would change the file it is linked to, such as
+	// the template file of a run with Options.Link, so it is replaced.
+	if info, err := os.Lstat(path); err == nil && utils.IsLinked(info) {
+		if err = os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to replace '%s': %w", path, err)
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create destination file '%s': %w", path, err)
+	}
+	return &dirFile{File: file, mode: mode}, nil
+}
+
+// Commit has nothing to do, files are written in place.
+func (s *DirSink) Commit() error {
+	return nil
+}
+
+// Abort keeps the files written so far.
+func (s *DirSink) Abort() error {
+	return nil
+}
+
+// dirFile applies the file mode once its content is written.
+type dirFile struct {
+	*os.File
+
+	mode fs.FileMode
+}
+
+// Close closes the file and preserves the mode of the source.
+func (f *dirFile) Close() error {
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(f.Name(), f.mode)
+}
+
+// ArchiveSink streams the output into a tar, gzipped tar or zip archive. An
+// archive file is written to a temporary file and only moved into place on
+// Commit.
+type ArchiveSink struct {
+	path    string
+	file    *os.File
+	gzip    *gzip.Writer
+	tar     *tar.Writer
+	zip     *zip.Writer
+	modTime time.Time
+}
+
+// NewArchiveSink creates an archive sink writing the given format to path.
+func NewArchiveSink(path, format string, modTime time.Time) (*ArchiveSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create output directory '%s': %w", filepath.Dir(path), err)
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive '%s': %w", path, err)
+	}
+
+	s := &ArchiveSink{path: path, file: file, modTime: entryTime(modTime)}
+	switch format {
+	case "tar.gz":
+		s.gzip = gzip.NewWriter(file)
+		s.tar = tar.NewWriter(s.gzip)
+	case "tar":
+		s.tar = tar.NewWriter(file)
+	case "zip":
+		s.zip = zip.NewWriter(file)
+	default:
+		_ = s.Abort()
+		return nil, fmt.Errorf("unsupported archive format '%s'", format)
+	}
+	return s, nil
+}
+
+// NewTarStreamSink creates an archive sink writing an uncompressed tar stream
+// to w. A stream cannot be taken back, so Abort only stops writing.
+func NewTarStreamSink(w io.Writer, modTime time.Time) *ArchiveSink {
+	return &ArchiveSink{tar: tar.NewWriter(w), modTime: entryTime(modTime)}
+}
+
+// entryTime returns the timestamp for archive entries.
+func entryTime(modTime time.Time) time.Time {
+	if modTime.IsZero() {
+		return time.Now()
+	}
+	return modTime
+}
+
+// Mkdir adds a directory entry to the archive.
+func (s *ArchiveSink) Mkdir(relPath string, mode fs.FileMode) error {
+	if relPath == "." {
+		return nil
+	}
+	name := relPath + "/"
+	if s.zip != nil {
+		header := &zip.FileHeader{Name: name, Modified: s.modTime}
+		header.SetMode(mode | fs.ModeDir)
+		_, err := s.zip.CreateHeader(header)
+		return err
+	}
+	return s.tar.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     name,
+		Mode:     int64(mode.Perm()),
+		ModTime:  s.modTime,
+	})
+}
+
+// Create adds a file entry to the archive and returns a writer for its content.
+func (s *ArchiveSink) Create(relPath string, mode fs.FileMode, size int64) (io.WriteCloser, error) {
+	if s.zip != nil {
+		header := &zip.FileHeader{Name: relPath, Method: zip.Deflate, Modified: s.modTime}
+		header.SetMode(mode)
+		w, err := s.zip.CreateHeader(header)
+		if err != nil {
+			return nil, err
+		}
+		return nopCloser{w}, nil
+	}
+	err := s.tar.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     relPath,
+		Mode:     int64(mode.Perm()),
+		Size:     size,
+		ModTime:  s.modTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nopCloser{s.tar}, nil
+}
+
+// Commit finishes the archive and moves it into place.
+func (s *ArchiveSink) Commit() error {
+	if s.file == nil {
+		return s.closeWriters()
+	}
+	err := s.closeWriters()
+	if err == nil {
+		err = s.file.Chmod(0644)
+	}
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(s.file.Name(), s.path)
+	}
+	if err != nil {
+		_ = os.Remove(s.file.Name())
+		return fmt.Errorf("failed to write archive '%s': %w", s.path, err)
+	}
+	return nil
+}
+
+// Abort removes the partially written archive.
+func (s *ArchiveSink) Abort() error {
+	if s.file == nil {
+		return nil
+	}
+	_ = s.file.Close()
+	if err := os.Remove(s.file.Name()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// closeWriters flushes the archive and compression writers.
+func (s *ArchiveSink) closeWriters() error {
+	if s.zip != nil {
+		return s.zip.Close()
+	}
+	if err := s.tar.Close(); err != nil {
+		return err
+	}
+	if s.gzip != nil {
+		return s.gzip.Close()
+	}
+	return nil
+}
+
+// nopCloser lets an archive entry writer be closed without closing the archive.
+type nopCloser struct {
+	io.Writer
+}
+
+// Close does nothing.
+func (nopCloser) Close() error {
+	return nil
+}
+
+// discardSink drops the output of a dry run.
+type discardSink struct{}
+
+// Mkdir does nothing.
+func (discardSink) Mkdir(string, fs.FileMode) error {
+	return nil
+}
+
+// Create returns a writer discarding the content.
+func (discardSink) Create(string, fs.FileMode, int64) (io.WriteCloser, error) {
+	return nopWriteCloser{io.Discard}, nil
+}
+
+// Commit does nothing.
+func (discardSink) Commit() error {
+	return nil
+}
+
+// Abort does nothing.
+func (discardSink) Abort() error {
+	return nil
+}
+
+// nopWriteCloser adds a Close method that does nothing to a writer.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close does nothing.
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,217 @@
+package core
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// archiveEntry is a file or directory read back from an archive.
+type archiveEntry struct {
+	mode    fs.FileMode
+	content string
+}
+
+// readTarEntries reads the entries of a tar archive, gunzipping it if needed.
+func readTarEntries(t *testing.T, path string, gzipped bool) map[string]archiveEntry {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if gzipped {
+		gz, gzErr := gzip.NewReader(file)
+		if gzErr != nil {
+			t.Fatalf("Failed to open gzip stream: %v", gzErr)
+		}
+		r = gz
+	}
+
+	entries := make(map[string]archiveEntry)
+	tr := tar.NewReader(r)
+	for {
+		header, nextErr := tr.Next()
+		if nextErr == io.EOF {
+			break
+		}
+		if nextErr != nil {
+			t.Fatalf("Failed to read tar entry: %v", nextErr)
+		}
+		content, _ := io.ReadAll(tr)
+		entries[header.Name] = archiveEntry{mode: header.FileInfo().Mode(), content: string(content)}
+	}
+	return entries
+}
+
+// readZipEntries reads the entries of a zip archive.
+func readZipEntries(t *testing.T, path string) map[string]archiveEntry {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("Failed to open zip archive: %v", err)
+	}
+	defer zr.Close()
+
+	entries := make(map[string]archiveEntry)
+	for _, f := range zr.File {
+		rc, openErr := f.Open()
+		if openErr != nil {
+			t.Fatalf("Failed to open zip entry: %v", openErr)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		entries[f.Name] = archiveEntry{mode: f.Mode(), content: string(content)}
+	}
+	return entries
+}
+
+func TestArchiveFormat(t *testing.T) {
+	for path, want := range map[string]string{
+		"out.tar.gz": "tar.gz",
+		"out.TGZ":    "tar.gz",
+		"out.tar":    "tar",
+		"a/b.zip":    "zip",
+		"out":        "",
+		"out.gz":     "",
+	} {
+		if got := ArchiveFormat(path); got != want {
+			t.Errorf("ArchiveFormat(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestArchiveSink(t *testing.T) {
+	writeEntries := func(t *testing.T, sink Sink) {
+		t.Helper()
+		if err := sink.Mkdir(".", 0755); err != nil {
+			t.Fatalf("Mkdir failed: %v", err)
+		}
+		if err := sink.Mkdir("bin", 0755); err != nil {
+			t.Fatalf("Mkdir failed: %v", err)
+		}
+		w, err := sink.Create("bin/run.sh", 0755, 9)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if _, err = w.Write([]byte("#!/bin/sh")); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		if err = w.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}
+
+	for _, format := range []string{"tar", "tar.gz"} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out."+format)
+			sink, err := NewArchiveSink(path, format, time.Time{})
+			if err != nil {
+				t.Fatalf("NewArchiveSink failed: %v", err)
+			}
+			writeEntries(t, sink)
+			if err = sink.Commit(); err != nil {
+				t.Fatalf("Commit failed: %v", err)
+			}
+
+			entries := readTarEntries(t, path, format == "tar.gz")
+			if entry := entries["bin/run.sh"]; entry.content != "#!/bin/sh" || entry.mode.Perm() != 0755 {
+				t.Errorf("Unexpected file entry: %+v", entry)
+			}
+			if entry, ok := entries["bin/"]; !ok || !entry.mode.IsDir() {
+				t.Errorf("Expected directory entry, got %+v", entries)
+			}
+			if len(entries) != 2 {
+				t.Errorf("Expected 2 entries, got %v", entries)
+			}
+		})
+	}
+
+	t.Run("zip", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "nested", "out.zip")
+		sink, err := NewArchiveSink(path, "zip", time.Time{})
+		if err != nil {
+			t.Fatalf("NewArchiveSink failed: %v", err)
+		}
+		writeEntries(t, sink)
+		if err = sink.Commit(); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+
+		entries := readZipEntries(t, path)
+		if entry := entries["bin/run.sh"]; entry.content != "#!/bin/sh" || entry.mode.Perm() != 0755 {
+			t.Errorf("Unexpected file entry: %+v", entry)
+		}
+		if entry, ok := entries["bin/"]; !ok || !entry.mode.IsDir() {
+			t.Errorf("Expected directory entry, got %+v", entries)
+		}
+	})
+
+	t.Run("abort leaves nothing behind", func(t *testing.T) {
+		dir := t.TempDir()
+		sink, err := NewArchiveSink(filepath.Join(dir, "out.tar.gz"), "tar.gz", time.Time{})
+		if err != nil {
+			t.Fatalf("NewArchiveSink failed: %v", err)
+		}
+		writeEntries(t, sink)
+		if err = sink.Abort(); err != nil {
+			t.Fatalf("Abort failed: %v", err)
+		}
+
+		leftovers, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("Failed to read directory: %v", err)
+		}
+		if len(leftovers) != 0 {
+			t.Errorf("Expected no files after abort, got %v", leftovers)
+		}
+	})
+}
+
+func TestDirSink(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	sink, err := NewDirSink(dir)
+	if err != nil {
+		t.Fatalf("NewDirSink failed: %v", err)
+	}
+	if err = sink.Mkdir("a/b", 0755); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	w, err := sink.Create("a/b/file.sh", 0750, 2)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err = w.Write([]byte("ok")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "a", "b", "file.sh"))
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	if info.Mode().Perm() != 0750 {
+		t.Errorf("Permission mismatch: got %v, want %v", info.Mode().Perm(), os.FileMode(0750))
+	}
+	if sink.Path("a/b") != filepath.Join(dir, "a", "b") {
+		t.Errorf("Unexpected path: %s", sink.Path("a/b"))
+	}
+
+	if _, err = sink.Create("missing/file", 0644, 0); err == nil {
+		t.Error("Expected error when parent directory does not exist")
+	}
+
+	if _, err = NewDirSink(filepath.Join(dir, "a", "b", "file.sh")); err == nil {
+		t.Error("Expected error when output path is a file")
+	}
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxTemplateSize is the size limit of template files mold apply uses
+// unless told otherwise.
+const DefaultMaxTemplateSize = 10 << 20
+
+// sizeUnits are the units ParseSize accepts, from the largest.
+//
+//nolint:gochecknoglobals // fixed list of the units
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses a size in bytes, such as "1048576", or with a B, KB, MB
+// or GB unit, such as "10MB". Units are powers of 1024 and case-insensitive.
+func ParseSize(value string) (int64, error) {
+	number, unit := strings.TrimSpace(value), int64(1)
+	for _, u := range sizeUnits {
+		if trimmed, ok := strings.CutSuffix(strings.ToUpper(number), u.suffix); ok {
+			number, unit = strings.TrimSpace(trimmed), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 0 || n > (1<<63-1)/unit {
+		return 0, fmt.Errorf("invalid size '%s': expected a number of bytes, optionally followed by KB, MB or GB",
+			value)
+	}
+	return n * unit, nil
+}
+
+// formatSize returns a size in the largest unit it holds at least one of,
+// such as "2.5 MB".
+func formatSize(size int64) string {
+	for _, u := range sizeUnits[:len(sizeUnits)-1] {
+		if size >= u.bytes {
+			return fmt.Sprintf("%.1f %s", float64(size)/float64(u.bytes), u.suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", size)
+}
+
+// checkTemplateSize rejects a template file larger than limit, since
+// rendering reads the whole file into memory. A limit of zero or less
+// disables the check.
+func checkTemplateSize(path string, size, limit int64) error {
+	if limit > 0 && size > limit {
+		return fmt.Errorf("template file '%s' is %s, over the limit of %s", path, formatSize(size), formatSize(limit))
+	}
+	return nil
+}
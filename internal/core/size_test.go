@@ -0,0 +1,112 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"0", 0},
+		{"1048576", 1 << 20},
+		{"512B", 512},
+		{"512KB", 512 << 10},
+		{"10MB", 10 << 20},
+		{"10 mb", 10 << 20},
+		{"2GB", 2 << 30},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v, expected %d", tt.value, got, err, tt.want)
+		}
+	}
+	for _, value := range []string{"", "MB", "-1", "1.5MB", "10TB", "9999999999GB"} {
+		if _, err := ParseSize(value); err == nil {
+			t.Errorf("Expected ParseSize(%q) to fail", value)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for size, want := range map[int64]string{512: "512 B", 1536: "1.5 KB", 10 << 20: "10.0 MB", 2 << 30: "2.0 GB"} {
+		if got := formatSize(size); got != want {
+			t.Errorf("formatSize(%d) = %q, expected %q", size, got, want)
+		}
+	}
+}
+
+func TestApplyMaxTemplateSize(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		"small.txt.tmpl": "{{.name}}",
+		"copied.bin":     "",
+	})
+	// Sparse files are large without taking space.
+	big := filepath.Join(templateDir, "big.txt.tmpl")
+	if err := os.WriteFile(big, nil, 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+	if err := os.Truncate(filepath.Join(templateDir, "copied.bin"), 3<<20); err != nil {
+		t.Fatalf("Failed to grow copied file: %v", err)
+	}
+	apply := func(size int64, strict bool) (string, string, error) {
+		t.Helper()
+		if err := os.Truncate(big, size); err != nil {
+			t.Fatalf("Failed to grow template file: %v", err)
+		}
+		var out bytes.Buffer
+		outputDir := filepath.Join(t.TempDir(), "out")
+		err := Apply(Options{
+			TemplatePath:    templateDir,
+			OutputDir:       outputDir,
+			Data:            map[string]any{"name": "demo"},
+			Strict:          strict,
+			FuzzyKeys:       true,
+			Out:             &out,
+			MaxTemplateSize: 2 << 20,
+		})
+		return outputDir, out.String(), err
+	}
+
+	t.Run("under half the limit", func(t *testing.T) {
+		_, log, err := apply(1<<20, false)
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		if strings.Contains(log, "⚠️") {
+			t.Errorf("Expected no warning, got:\n%s", log)
+		}
+	})
+
+	t.Run("over half the limit", func(t *testing.T) {
+		_, log, err := apply(3<<19, false)
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		want := "⚠️  Template file '" + big + "' is 1.5 MB, close to the limit of 2.0 MB"
+		if !strings.Contains(log, want) {
+			t.Errorf("Expected %q, got:\n%s", want, log)
+		}
+
+		if _, _, err = apply(3<<19, true); err == nil || !strings.Contains(err.Error(), "close to the limit") {
+			t.Errorf("Expected the warning to fail a strict run, got %v", err)
+		}
+	})
+
+	t.Run("over the limit", func(t *testing.T) {
+		outputDir, _, err := apply(3<<20, false)
+		want := "template file '" + big + "' is 3.0 MB, over the limit of 2.0 MB"
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("Expected %q, got %v", want, err)
+		}
+		if _, err = os.Stat(outputDir); !os.IsNotExist(err) {
+			t.Errorf("Expected nothing written, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sopsBinary is the name of the sops executable looked up in PATH.
+const sopsBinary = "sops"
+
+// IsSopsEncrypted reports whether a data file is encrypted with sops, either
+// by its '.enc.yaml', '.enc.yml' or '.enc.json' extension or by the 'sops'
+// metadata block in its content.
+func IsSopsEncrypted(path string, content []byte, format string) bool {
+	base := strings.ToLower(filepath.Base(path))
+	ext := filepath.Ext(base)
+	if strings.HasSuffix(strings.TrimSuffix(base, ext), ".enc") {
+		return true
+	}
+
+	var doc struct {
+		Sops map[string]any `json:"sops" yaml:"sops"`
+	}
+	switch strings.ToLower(format) {
+	case "json", "jsonc":
+		_ = json.Unmarshal(stripJSONC(content), &doc)
+	case "yaml", "yml":
+		_ = yaml.Unmarshal(content, &doc)
+	}
+	_, hasMAC := doc.Sops["mac"]
+	return hasMAC
+}
+
+// decryptSops decrypts a sops-encrypted data file with the sops binary and
+// returns the plaintext. The plaintext is only kept in memory.
+func decryptSops(path, format string) ([]byte, error) {
+	binary, err := exec.LookPath(sopsBinary)
+	if err != nil {
+		return nil, fmt.Errorf("data file '%s' is encrypted with sops, but the '%s' binary was not found in PATH: %w",
+			path, sopsBinary, err)
+	}
+
+	switch format = strings.ToLower(format); format {
+	case "yml":
+		format = "yaml"
+	case "jsonc":
+		format = "json"
+	}
+	var stdout, stderr bytes.Buffer
+	//nolint:gosec // the sops binary and the data file are chosen by the user
+	cmd := exec.Command(binary, "--decrypt", "--input-type", format, "--output-type", format, path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("failed to decrypt data file '%s' with sops: %w", path, err)
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("sops returned no content for data file '%s'", path)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakeSops puts a sops script in PATH that prints plaintext for any file and
+// records its arguments in the returned file.
+func fakeSops(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake sops binary is a shell script")
+	}
+	binDir := t.TempDir()
+	argsFile := filepath.Join(binDir, "args")
+	content := "#!/bin/sh\necho \"$@\" > '" + argsFile + "'\n" + script
+	if err := os.WriteFile(filepath.Join(binDir, sopsBinary), []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir)
+	return argsFile
+}
+
+func TestIsSopsEncrypted(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		format  string
+		want    bool
+	}{
+		{name: "enc yaml extension", path: "secrets.enc.yaml", content: "key: value", format: "yaml", want: true},
+		{name: "enc json extension", path: "dir/Secrets.ENC.json", content: "{}", format: "json", want: true},
+		{
+			name:    "yaml metadata",
+			path:    "secrets.yaml",
+			content: "api_key: ENC[AES256_GCM,data:abc]\nsops:\n  mac: ENC[AES256_GCM,data:def]\n  version: 3.8.1\n",
+			format:  "yaml",
+			want:    true,
+		},
+		{
+			name:    "json metadata",
+			path:    "secrets.json",
+			content: `{"api_key": "ENC[...]", "sops": {"mac": "ENC[...]", "version": "3.8.1"}}`,
+			format:  "json",
+			want:    true,
+		},
+		{name: "plain yaml", path: "data.yaml", content: "name: app", format: "yaml"},
+		{name: "sops key without metadata", path: "data.yaml", content: "sops: enabled", format: "yaml"},
+		{name: "enc directory", path: "secrets.enc/data.yaml", content: "name: app", format: "yaml"},
+		{name: "invalid content", path: "data.json", content: "{", format: "json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsSopsEncrypted(tt.path, []byte(tt.content), tt.format); got != tt.want {
+				t.Errorf("IsSopsEncrypted() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadDataFileSops(t *testing.T) {
+	dir := t.TempDir()
+	encrypted := filepath.Join(dir, "secrets.enc.yml")
+	if err := os.WriteFile(encrypted, []byte("api_key: ENC[AES256_GCM,data:abc]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("decrypts with the sops binary", func(t *testing.T) {
+		argsFile := fakeSops(t, "echo 'api_key: s3cr3t'\n")
+
+		data, err := LoadDataFile(encrypted, DataOptions{})
+		if err != nil {
+			t.Fatalf("LoadDataFile failed: %v", err)
+		}
+		if data["api_key"] != "s3cr3t" {
+			t.Errorf("Expected the decrypted value, got %v", data["api_key"])
+		}
+		args, _ := os.ReadFile(argsFile)
+		if want := "--decrypt --input-type yaml --output-type yaml " + encrypted + "\n"; string(args) != want {
+			t.Errorf("Unexpected sops arguments %q; want %q", args, want)
+		}
+		entries, _ := os.ReadDir(dir)
+		if len(entries) != 1 {
+			t.Errorf("Expected no plaintext to be written next to the data file, got %v", entries)
+		}
+	})
+
+	t.Run("sops failure", func(t *testing.T) {
+		fakeSops(t, "echo 'no matching age key' >&2\nexit 128\n")
+
+		_, err := LoadDataFile(encrypted, DataOptions{})
+		if err == nil || !contains(err.Error(), "failed to decrypt data file") ||
+			!contains(err.Error(), "no matching age key") {
+			t.Errorf("Expected a decryption error with the sops message, got: %v", err)
+		}
+	})
+
+	t.Run("missing sops binary", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+
+		_, err := LoadDataFile(encrypted, DataOptions{})
+		if err == nil || !contains(err.Error(), "encrypted with sops, but the 'sops' binary was not found") {
+			t.Errorf("Expected a missing binary error, got: %v", err)
+		}
+	})
+
+	t.Run("plain files don't need sops", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+		plain := filepath.Join(dir, "data.yaml")
+		if err := os.WriteFile(plain, []byte("name: app"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadDataFile(plain, DataOptions{}); err != nil {
+			t.Errorf("LoadDataFile failed: %v", err)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/0m3kk/mold/internal/utils"
+)
+
+// checkSymlinks rejects preserving symlinks where the output can't hold
+// them.
+func (a *applier) checkSymlinks() error {
+	if a.opts.PreserveSymlinks && (a.opts.Sink != nil || ArchiveFormat(a.opts.OutputDir) != "") {
+		return errors.New("preserving symlinks needs an output directory, not an archive or a stream")
+	}
+	return nil
+}
+
+// symlinkTarget reads the target of the template symlink of e and replaces
+// its placeholders like in paths, so `current -> releases/{{.version}}`
+// points to the release of the data. A target rendering to an absolute path
+// outside the output directory is warned about, or rejected in strict mode.
+func (a *applier) symlinkTarget(e entry) (string, error) {
+	link, err := os.Readlink(e.src)
+	if err != nil {
+		return "", fmt.Errorf("failed to read symlink '%s': %w", e.src, err)
+	}
+	target, err := replacePlaceholders(link, a.opts.Data, e.layer.funcs, a.opts.Strict)
+	if err != nil {
+		return "", fmt.Errorf("failed to replace placeholders in the target '%s' of symlink '%s': %w",
+			link, e.src, err)
+	}
+	if strings.TrimSpace(target) == "" {
+		return "", fmt.Errorf("the target '%s' of symlink '%s' renders to an empty path", link, e.src)
+	}
+	if !filepath.IsAbs(target) || a.insideOutput(target) {
+		return target, nil
+	}
+	if a.opts.Strict {
+		return "", fmt.Errorf("symlink '%s' points outside the output directory: %s", e.rel, target)
+	}
+	fmt.Fprintf(a.out, "⚠️  Symlink '%s' points outside the output directory: %s\n", e.rel, target)
+	return target, nil
+}
+
+// insideOutput reports whether an absolute path is the output directory or
+// below it.
+func (a *applier) insideOutput(path string) bool {
+	root, err := filepath.Abs(a.opts.OutputDir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// symlink recreates a symlink of the template in the output directory, with
+// the placeholders of its target replaced. Symlinks are not recorded in the
+// manifest, which only tracks content.
+func (a *applier) symlink(e entry) error {
+	target, err := a.symlinkTarget(e)
+	if err != nil {
+		return err
+	}
+	if err = a.count(e.rel, 0); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "🔗 Symlinking: %s -> %s\n", e.rel, target)
+	a.status[filepath.ToSlash(e.rel)] = StatusSymlinked
+	if err = a.backup(e.rel); err != nil {
+		return err
+	}
+	dirSink, onDisk := a.sink.(*DirSink)
+	if !onDisk {
+		// A dry run.
+		return nil
+	}
+	dest := dirSink.Path(e.rel)
+	if err = os.MkdirAll(filepath.Dir(dest), utils.DirMode); err != nil {
+		return fmt.Errorf("failed to create directory for '%s': %w", dest, err)
+	}
+	info, err := os.Lstat(dest)
+	switch {
+	case err == nil && !info.IsDir():
+		err = os.Remove(dest)
+	case errors.Is(err, fs.ErrNotExist):
+		err = nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to replace '%s': %w", dest, err)
+	}
+	if err = os.Symlink(target, dest); err != nil {
+		return fmt.Errorf("failed to create symlink '%s': %w", dest, err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyPreserveSymlinks(t *testing.T) {
+	// symlinkTemplate returns a template holding a symlink named link with
+	// the given target.
+	symlinkTemplate := func(t *testing.T, target string) string {
+		t.Helper()
+		templateDir := writeTemplate(t, map[string]string{
+			"releases/{{.version}}/app.txt.tmpl": "{{.version}}",
+		})
+		if err := os.Symlink(target, filepath.Join(templateDir, "link")); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+		return templateDir
+	}
+	data := map[string]any{"version": "1.2.0", "empty": ""}
+
+	t.Run("relative target", func(t *testing.T) {
+		templateDir := symlinkTemplate(t, "releases/{{.version}}")
+		outputDir := t.TempDir()
+		var out bytes.Buffer
+		err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: &out,
+			PreserveSymlinks: true})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		target, err := os.Readlink(filepath.Join(outputDir, "link"))
+		if err != nil || target != "releases/1.2.0" {
+			t.Fatalf("Expected link to point to %q, got %q (%v)", "releases/1.2.0", target, err)
+		}
+		content, err := os.ReadFile(filepath.Join(outputDir, "link", "app.txt"))
+		if err != nil || string(content) != "1.2.0" {
+			t.Errorf("Expected the link to reach the release, got %q (%v)", content, err)
+		}
+		if !strings.Contains(out.String(), "🔗 Symlinking: link -> releases/1.2.0\n") {
+			t.Errorf("Expected the symlink in the log, got:\n%s", out.String())
+		}
+
+		// Applying again replaces the symlink.
+		if err = Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: io.Discard,
+			PreserveSymlinks: true}); err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+	})
+
+	t.Run("followed without preservation", func(t *testing.T) {
+		templateDir := writeTemplate(t, map[string]string{"a.txt": "a"})
+		if err := os.Symlink("a.txt", filepath.Join(templateDir, "link")); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+		outputDir := t.TempDir()
+		err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: io.Discard})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		info, err := os.Lstat(filepath.Join(outputDir, "link"))
+		if err != nil || !info.Mode().IsRegular() {
+			t.Errorf("Expected link to be copied as a file, got %v (%v)", info, err)
+		}
+	})
+
+	t.Run("absolute target", func(t *testing.T) {
+		outside := filepath.Join(t.TempDir(), "{{.version}}")
+		templateDir := symlinkTemplate(t, outside)
+		outputDir := t.TempDir()
+		var out bytes.Buffer
+		err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: &out,
+			PreserveSymlinks: true})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		want := strings.ReplaceAll(outside, "{{.version}}", "1.2.0")
+		if target, _ := os.Readlink(filepath.Join(outputDir, "link")); target != want {
+			t.Errorf("Expected link to point to %q, got %q", want, target)
+		}
+		warning := "⚠️  Symlink 'link' points outside the output directory: " + want
+		if !strings.Contains(out.String(), warning) {
+			t.Errorf("Expected %q in the log, got:\n%s", warning, out.String())
+		}
+
+		err = Apply(Options{TemplatePath: templateDir, OutputDir: t.TempDir(), Data: data, Out: io.Discard,
+			PreserveSymlinks: true, Strict: true})
+		if err == nil || !strings.Contains(err.Error(), "symlink 'link' points outside the output directory") {
+			t.Errorf("Expected strict mode to reject the target, got %v", err)
+		}
+
+		// An absolute target inside the output directory is fine.
+		outputDir = t.TempDir()
+		templateDir = symlinkTemplate(t, filepath.Join(outputDir, "releases", "{{.version}}"))
+		out.Reset()
+		err = Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: &out,
+			PreserveSymlinks: true, Strict: true, NoWarnUnused: true})
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		if strings.Contains(out.String(), "⚠️") {
+			t.Errorf("Expected no warning, got:\n%s", out.String())
+		}
+	})
+
+	errorTests := []struct {
+		name   string
+		target string
+		output string
+		want   string
+	}{
+		{
+			name:   "target rendering empty",
+			target: "{{.empty}}",
+			want:   "the target '{{.empty}}' of symlink",
+		},
+		{
+			name:   "invalid target",
+			target: "releases/{{.version",
+			want:   "failed to replace placeholders in the target 'releases/{{.version' of symlink",
+		},
+		{
+			name:   "archive",
+			target: "releases",
+			output: "out.tar",
+			want:   "preserving symlinks needs an output directory",
+		},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			templateDir := symlinkTemplate(t, tt.target)
+			outputDir := filepath.Join(t.TempDir(), tt.output)
+			err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: io.Discard,
+				PreserveSymlinks: true})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"text/template"
+)
+
+// Names of the functions reading the files of the template.
+const (
+	// DataFileFunc reads a data file of the template, such as
+	// {{range $id, $text := dataFile "licenses.yaml"}}.
+	DataFileFunc = "dataFile"
+	// TemplateGlobFunc lists the files of the template matching a pattern,
+	// such as {{range templateGlob "scripts/*.sh"}}.
+	TemplateGlobFunc = "templateGlob"
+	// TemplateFileExistsFunc tells whether the template holds a file, such
+	// as {{if templateFileExists "Dockerfile.tmpl"}}.
+	TemplateFileExistsFunc = "templateFileExists"
+)
+
+// errNoTemplate is returned by the functions reading the files of the
+// template where there is none.
+var errNoTemplate = errors.New("the files of a template can only be read by the files of a template")
+
+// templateFiles gives templates read access to the files of their template
+// directory, as authored: the ignore patterns and conditions of the template
+// don't hide them. Paths are slash-separated and relative to the root of the
+// template, and can't leave it, even through a symlink.
+type templateFiles struct {
+	root string
+	// data caches the data files parsed, by path, for the apply run.
+	data map[string]map[string]any
+	// files caches the sorted slash-separated paths of the files of the
+	// template, listed by the first glob.
+	files []string
+}
+
+// newTemplateFiles gives access to the files of the template at root.
+func newTemplateFiles(root string) *templateFiles {
+	return &templateFiles{root: root, data: make(map[string]map[string]any)}
+}
+
+// funcs returns the functions reading the files of the template. Without a
+// template, such as when rendering a single file, they fail.
+func (f *templateFiles) funcs() template.FuncMap {
+	return template.FuncMap{
+		DataFileFunc:           f.dataFile,
+		TemplateGlobFunc:       f.glob,
+		TemplateFileExistsFunc: f.exists,
+	}
+}
+
+// dataFile parses the JSON or YAML file at path in the template, chosen by
+// its extension like data files, and returns its mapping. Each file is only
+// parsed once.
+func (f *templateFiles) dataFile(path string) (map[string]any, error) {
+	if f == nil {
+		return nil, errNoTemplate
+	}
+	if data, ok := f.data[path]; ok {
+		return data, nil
+	}
+	format, err := DataFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := f.read(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ParseData(content, format, path, DataOptions{})
+	if err != nil {
+		return nil, err
+	}
+	f.data[path] = data
+	return data, nil
+}
+
+// glob returns the sorted slash-separated paths of the files of the
+// template matching pattern, with the syntax of the ignore patterns.
+// Directories aren't listed, their files are.
+func (f *templateFiles) glob(pattern string) ([]string, error) {
+	if f == nil {
+		return nil, errNoTemplate
+	}
+	if err := checkTemplatePattern(pattern); err != nil {
+		return nil, err
+	}
+	if f.files == nil {
+		files := []string{}
+		err := filepath.WalkDir(f.root, func(path string, d fs.DirEntry, walkErr error) error {
+			if walkErr != nil || d.IsDir() {
+				return walkErr
+			}
+			rel, err := filepath.Rel(f.root, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not list the files of template '%s': %w", f.root, err)
+		}
+		slices.Sort(files)
+		f.files = files
+	}
+	matches := []string{}
+	for _, file := range f.files {
+		if MatchGlob(pattern, file) {
+			matches = append(matches, file)
+		}
+	}
+	return matches, nil
+}
+
+// exists reports whether the template holds a file or a directory at path,
+// a symlink counting whatever it points to.
+func (f *templateFiles) exists(path string) (bool, error) {
+	if f == nil {
+		return false, errNoTemplate
+	}
+	name, err := f.name(path)
+	if err != nil {
+		return false, err
+	}
+	root, err := os.OpenRoot(f.root)
+	if err != nil {
+		return false, fmt.Errorf("could not open template '%s': %w", f.root, err)
+	}
+	defer root.Close()
+	// A path going through a file doesn't exist either.
+	if _, err = root.Lstat(name); errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("could not check template file '%s': %w", path, err)
+	}
+	return true, nil
+}
+
+// check reports a call of a function reading the files of the template
+// whose constant argument is wrong: a data file that is missing or doesn't
+// parse, a malformed pattern or a path outside the template. Other calls
+// are accepted.
+func (f *templateFiles) check(call FuncCall) error {
+	var err error
+	switch call.Name {
+	case DataFileFunc:
+		_, err = f.dataFile(call.Arg)
+	case TemplateGlobFunc:
+		err = checkTemplatePattern(call.Arg)
+	case TemplateFileExistsFunc:
+		_, err = f.name(call.Arg)
+	}
+	return err
+}
+
+// checkTemplatePattern checks a glob pattern of the files of the template.
+func checkTemplatePattern(pattern string) error {
+	if pattern == "" || !filepath.IsLocal(filepath.FromSlash(pattern)) {
+		return fmt.Errorf("invalid pattern '%s': expected a relative pattern inside the template", pattern)
+	}
+	return CheckGlob(pattern)
+}
+
+// read returns the content of the file at path in the template.
+func (f *templateFiles) read(path string) ([]byte, error) {
+	name, err := f.name(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenInRoot(f.root, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not read template file '%s': %w", path, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("could not read template file '%s': %w", path, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("could not read template file '%s': it is a directory", path)
+	}
+	if err = checkTemplateSize(path, info.Size(), DefaultMaxTemplateSize); err != nil {
+		return nil, err
+	}
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read template file '%s': %w", path, err)
+	}
+	return content, nil
+}
+
+// name checks that a slash-separated path stays inside the template and
+// returns it in the form of the platform.
+func (f *templateFiles) name(path string) (string, error) {
+	name := filepath.FromSlash(path)
+	if path == "" || !filepath.IsLocal(name) {
+		return "", fmt.Errorf("invalid path '%s': expected a relative path inside the template", path)
+	}
+	return name, nil
+}
@@ -0,0 +1,188 @@
+package core
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTemplateFilesDataFile(t *testing.T) {
+	outside := writeTemplate(t, map[string]string{"secret.yaml": "token: x"})
+	root := writeTemplate(t, map[string]string{
+		"data/licenses.yaml": "mit:\n  name: MIT License\napache:\n  name: Apache License 2.0\n",
+		"data/ports.json":    `{"http": 80, "https": 443}`,
+		"data/broken.yaml":   "a: [",
+		"data/notes.txt":     "text",
+	})
+	if err := os.Symlink(filepath.Join(outside, "secret.yaml"), filepath.Join(root, "data", "link.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	files := newTemplateFiles(root)
+
+	licenses, err := files.dataFile("data/licenses.yaml")
+	want := map[string]any{"mit": map[string]any{"name": "MIT License"},
+		"apache": map[string]any{"name": "Apache License 2.0"}}
+	if err != nil || !reflect.DeepEqual(licenses, want) {
+		t.Errorf("Expected %v, got %v (%v)", want, licenses, err)
+	}
+	ports, err := files.dataFile("data/ports.json")
+	if err != nil || ports["https"] != 443 {
+		t.Errorf("Expected the ports, got %#v (%v)", ports, err)
+	}
+
+	// A file is parsed once per run.
+	if err = os.WriteFile(filepath.Join(root, "data", "licenses.yaml"), []byte("changed: true"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if licenses, err = files.dataFile("data/licenses.yaml"); err != nil || !reflect.DeepEqual(licenses, want) {
+		t.Errorf("Expected the cached parse, got %v (%v)", licenses, err)
+	}
+
+	for path, wantErr := range map[string]string{
+		"../" + filepath.Base(outside) + "/secret.yaml": "expected a relative path inside the template",
+		filepath.Join(outside, "secret.yaml"):           "expected a relative path inside the template",
+		"data/link.yaml":                                "could not read template file 'data/link.yaml'",
+		"data/missing.yaml":                             "could not read template file 'data/missing.yaml'",
+		"data/broken.yaml":                              "data/broken.yaml",
+		"data/notes.txt":                                "unsupported data file format",
+		"data.yaml":                                     "could not read template file 'data.yaml'",
+		"":                                              "unsupported data file format",
+	} {
+		if _, err = files.dataFile(path); err == nil || !contains(err.Error(), wantErr) {
+			t.Errorf("%q: expected an error containing %q, got %v", path, wantErr, err)
+		}
+	}
+	if _, err = (*templateFiles)(nil).dataFile("data/ports.json"); err == nil {
+		t.Error("Expected data files to fail outside a template")
+	}
+}
+
+func TestApplyDataFile(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile:               "ignore: [\"data/**\"]\n",
+		"data/licenses.yaml":       "mit:\n  header: Licensed under the MIT License.\n",
+		"_partials/header.tmpl":    `// {{(index (dataFile "data/licenses.yaml") .license).header}}`,
+		"main.go.tmpl":             "{{template \"header.tmpl\" .}}\npackage main\n",
+		"LICENSES.md.tmpl":         "{{range $id, $l := dataFile \"data/licenses.yaml\"}}- {{$id}}\n{{end}}",
+		"{{.license}}/NOTICE.tmpl": "{{(index (dataFile \"data/licenses.yaml\") .license).header}}\n",
+		"unsafe/{{.license}}.tmpl": "{{dataFile \"../outside.yaml\"}}",
+	})
+	outputDir := t.TempDir()
+	err := Apply(Options{
+		TemplatePath: templateDir,
+		OutputDir:    outputDir,
+		Data:         map[string]any{"license": "mit"},
+		Out:          io.Discard,
+	})
+	if err == nil || !contains(err.Error(), "invalid path '../outside.yaml'") {
+		t.Fatalf("Expected reading outside the template to fail, got %v", err)
+	}
+
+	if err = os.RemoveAll(filepath.Join(templateDir, "unsafe")); err != nil {
+		t.Fatal(err)
+	}
+	if err = Apply(Options{
+		TemplatePath: templateDir,
+		OutputDir:    outputDir,
+		Data:         map[string]any{"license": "mit"},
+		Out:          io.Discard,
+	}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	files := readFiles(t, outputDir)
+	wantFiles := map[string]string{
+		"main.go":     "// Licensed under the MIT License.\npackage main\n",
+		"LICENSES.md": "- mit\n",
+		"mit/NOTICE":  "Licensed under the MIT License.\n",
+	}
+	for path, want := range wantFiles {
+		if files[path] != want {
+			t.Errorf("Expected %q in %s, got %q", want, path, files[path])
+		}
+	}
+	if _, ok := files["data/licenses.yaml"]; ok {
+		t.Error("Expected the ignored data file to be left out")
+	}
+}
+
+func TestTemplateFilesGlob(t *testing.T) {
+	root := writeTemplate(t, map[string]string{
+		MetadataFile:            "ignore: [\"scripts/**\"]\n",
+		"scripts/build.sh":      "",
+		"scripts/test.sh":       "",
+		"scripts/ci/lint.sh":    "",
+		"scripts/README.md":     "",
+		"_partials/header.tmpl": "",
+		"Dockerfile.tmpl":       "",
+		"a/b.tmpl":              "",
+	})
+	files := newTemplateFiles(root)
+
+	tests := []struct {
+		pattern string
+		want    []string
+		wantErr string
+	}{
+		{pattern: "scripts/*.sh", want: []string{"scripts/build.sh", "scripts/test.sh"}},
+		{pattern: "scripts/**/*.sh", want: []string{"scripts/build.sh", "scripts/ci/lint.sh", "scripts/test.sh"}},
+		// The template is seen as authored, partials included.
+		{pattern: "**/*.tmpl", want: []string{"Dockerfile.tmpl", "_partials/header.tmpl", "a/b.tmpl"}},
+		{pattern: "scripts", want: []string{}},
+		{pattern: "*.go", want: []string{}},
+		{pattern: "scripts/[", wantErr: "invalid glob pattern 'scripts/['"},
+		{pattern: "../*", wantErr: "invalid pattern '../*'"},
+		{pattern: "/etc/*", wantErr: "invalid pattern '/etc/*'"},
+		{pattern: "", wantErr: "invalid pattern ''"},
+	}
+	for _, tt := range tests {
+		got, err := files.glob(tt.pattern)
+		if tt.wantErr != "" {
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("%q: expected an error containing %q, got %v", tt.pattern, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: expected %q, got %q (%v)", tt.pattern, tt.want, got, err)
+		}
+	}
+
+	for path, want := range map[string]bool{
+		"Dockerfile.tmpl": true, "_partials/header.tmpl": true, "scripts": true, "scripts/ci/lint.sh": true,
+		"Dockerfile": false, "scripts/build.sh/x": false, "missing/file": false,
+	} {
+		if got, err := files.exists(path); err != nil || got != want {
+			t.Errorf("%q: expected %v, got %v (%v)", path, want, got, err)
+		}
+	}
+	if _, err := files.exists("../" + filepath.Base(root)); err == nil {
+		t.Error("Expected a path outside the template to fail")
+	}
+}
+
+func TestApplyTemplateGlob(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile:            "ignore: [\"scripts/**\"]\n",
+		"scripts/build.sh":      "",
+		"scripts/test.sh":       "",
+		"_partials/header.tmpl": "# Generated",
+		"Makefile.tmpl": "{{if templateFileExists \"_partials/header.tmpl\"}}{{template \"header.tmpl\"}}\n{{end}}" +
+			"{{range templateGlob \"scripts/*.sh\"}}run: {{.}}\n{{end}}" +
+			"{{if templateFileExists \"Dockerfile.tmpl\"}}docker:\n{{end}}",
+	})
+	outputDir := t.TempDir()
+	if err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Out: io.Discard}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	files := readFiles(t, outputDir)
+	if want := "# Generated\nrun: scripts/build.sh\nrun: scripts/test.sh\n"; files["Makefile"] != want {
+		t.Errorf("Expected %q, got %q", want, files["Makefile"])
+	}
+	for path := range files {
+		if contains(path, "scripts") || contains(path, PartialsDir) {
+			t.Errorf("Expected the ignored scripts and the partials left out, got %s", path)
+		}
+	}
+}
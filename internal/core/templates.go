@@ -0,0 +1,271 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/0m3kk/mold/internal/utils"
+)
+
+// TemplatesDirEnv names the environment variable that overrides the default
+// templates directory.
+const TemplatesDirEnv = "MOLD_TEMPLATES_DIR"
+
+// DefaultTemplatesDir returns the directory holding the named templates: the
+// value of MOLD_TEMPLATES_DIR, or '.mold/templates' in the home directory.
+func DefaultTemplatesDir() (string, error) {
+	if dir := os.Getenv(TemplatesDirEnv); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine the templates directory, set %s or --templates-dir: %w",
+			TemplatesDirEnv, err)
+	}
+	return filepath.Join(home, ".mold", "templates"), nil
+}
+
+// TemplatePathsEnv names the environment variable listing the template
+// search paths, separated like PATH.
+const TemplatePathsEnv = "MOLD_TEMPLATE_PATHS"
+
+// TemplatesDirName is the name of the project templates directory
+// FindTemplatePaths looks for.
+const TemplatesDirName = "templates"
+
+// FindTemplatePaths returns the directories holding the named templates,
+// seen from the directory start, in the order names are looked up in: the
+// paths of MOLD_TEMPLATE_PATHS, or the directory of MOLD_TEMPLATES_DIR, or
+// else the nearest 'templates' directory in start or one of its parents,
+// followed by the configured paths, or the default templates directory
+// without any. The project directory is found the way git finds '.git',
+// the search stopping at the root of the git repository holding start, the
+// directory holding '.git', or at the root of the filesystem. A directory
+// listed twice is only kept the first time.
+func FindTemplatePaths(start string, configured []string) ([]string, error) {
+	if paths := uniquePaths(filepath.SplitList(os.Getenv(TemplatePathsEnv))); len(paths) > 0 {
+		return paths, nil
+	}
+	if dir := os.Getenv(TemplatesDirEnv); dir != "" {
+		return []string{dir}, nil
+	}
+	var paths []string
+	if dir := ProjectTemplatesDir(start); dir != "" {
+		paths = append(paths, dir)
+	}
+	if len(configured) == 0 {
+		dir, err := DefaultTemplatesDir()
+		if err != nil {
+			return nil, err
+		}
+		configured = []string{dir}
+	}
+	return uniquePaths(append(paths, configured...)), nil
+}
+
+// uniquePaths drops the empty paths and the paths listed before.
+func uniquePaths(paths []string) []string {
+	var unique []string
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		if clean := filepath.Clean(path); path != "" && !seen[clean] {
+			seen[clean] = true
+			unique = append(unique, path)
+		}
+	}
+	return unique
+}
+
+// FindTemplate returns the directory of the template name in the first of
+// paths holding it, like ResolveTemplate, along with the directories of the
+// templates of that name in the later paths, which it shadows.
+func FindTemplate(paths []string, name string) (string, []string, error) {
+	var found string
+	var shadowed []string
+	for _, dir := range paths {
+		path, err := TemplatePath(dir, name)
+		if err != nil {
+			return "", nil, err
+		}
+		if _, err = os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if found != "" {
+			shadowed = append(shadowed, path)
+			continue
+		}
+		if found, err = ResolveTemplate(dir, name); err != nil {
+			return "", nil, err
+		}
+	}
+	if found == "" {
+		return "", nil, fmt.Errorf("template '%s' not found in '%s'", name, strings.Join(paths, "', '"))
+	}
+	return found, shadowed, nil
+}
+
+// ProjectTemplatesDir returns the project templates directory of
+// FindTemplatePaths, the nearest 'templates' directory in start or its
+// parents up to the git repository root, or "" when there is none.
+func ProjectTemplatesDir(start string) string {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, TemplatesDirName)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			return path
+		}
+		if _, err = os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// TemplatePath returns the path of the template with the given name in
+// templatesDir without checking that it exists. Nested names, such as
+// "go/service", are separated by slashes.
+func TemplatePath(templatesDir, name string) (string, error) {
+	rel := filepath.FromSlash(strings.Trim(name, "/"))
+	if rel == "" || rel == "." || filepath.Clean(rel) != rel || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("invalid template name '%s': expected a relative name such as 'go/service'", name)
+	}
+	return filepath.Join(templatesDir, rel), nil
+}
+
+// ResolveTemplate returns the directory of an existing template in
+// templatesDir. A template that is, or is reached through, a symlink pointing
+// outside templatesDir is refused.
+func ResolveTemplate(templatesDir, name string) (string, error) {
+	path, err := TemplatePath(templatesDir, name)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("template '%s' not found in '%s'", name, templatesDir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to stat template '%s': %w", path, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("template '%s' in '%s' is not a directory", name, templatesDir)
+	}
+	if err = checkInside(templatesDir, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// DeleteTemplate removes the template with the given name from templatesDir.
+func DeleteTemplate(templatesDir, name string) error {
+	path, err := ResolveTemplate(templatesDir, name)
+	if err != nil {
+		return err
+	}
+	if err = os.RemoveAll(path); err != nil {
+		return fmt.Errorf("failed to delete template '%s': %w", path, err)
+	}
+	return nil
+}
+
+// CopyTemplate copies the template src to the new template dst in
+// templatesDir, creating the grouping directories of a nested name, and sets
+// the name in the copied template.yaml. The options are passed to
+// utils.CopyDir. It returns the path of the copy.
+func CopyTemplate(templatesDir, src, dst string, opts ...utils.CopyDirOption) (string, error) {
+	srcPath, dstPath, err := prepareTransfer(templatesDir, src, dst)
+	if err != nil {
+		return "", err
+	}
+	if _, err = utils.CopyDir(srcPath, dstPath, opts...); err != nil {
+		_ = os.RemoveAll(dstPath)
+		return "", fmt.Errorf("failed to copy template '%s' to '%s': %w", src, dst, err)
+	}
+	if err = setMetadataName(dstPath, dst); err != nil {
+		return "", err
+	}
+	return dstPath, nil
+}
+
+// RenameTemplate moves the template src to dst in templatesDir, creating the
+// grouping directories of a nested name, and sets the name in its
+// template.yaml. It returns the new path of the template.
+func RenameTemplate(templatesDir, src, dst string) (string, error) {
+	srcPath, dstPath, err := prepareTransfer(templatesDir, src, dst)
+	if err != nil {
+		return "", err
+	}
+	if err = os.Rename(srcPath, dstPath); err != nil {
+		return "", fmt.Errorf("failed to rename template '%s' to '%s': %w", src, dst, err)
+	}
+	if err = setMetadataName(dstPath, dst); err != nil {
+		return "", err
+	}
+	return dstPath, nil
+}
+
+// prepareTransfer resolves the source and destination of a copy or rename.
+// The destination must not exist and must not be inside the source. Its
+// parent directories are created.
+func prepareTransfer(templatesDir, src, dst string) (string, string, error) {
+	srcPath, err := ResolveTemplate(templatesDir, src)
+	if err != nil {
+		return "", "", err
+	}
+	dstPath, err := TemplatePath(templatesDir, dst)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err = os.Lstat(dstPath); err == nil {
+		return "", "", fmt.Errorf("template '%s' already exists in '%s'", dst, templatesDir)
+	}
+	if rel, err := filepath.Rel(srcPath, dstPath); err == nil && filepath.IsLocal(rel) {
+		return "", "", fmt.Errorf("cannot put template '%s' inside itself as '%s'", src, dst)
+	}
+	// Check the deepest existing parent before creating the missing ones, so
+	// nothing is created through a symlink pointing elsewhere.
+	parent := filepath.Dir(dstPath)
+	existing := parent
+	for existing != filepath.Clean(templatesDir) {
+		if _, err = os.Lstat(existing); err == nil {
+			if err = checkInside(templatesDir, existing); err != nil {
+				return "", "", err
+			}
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+	if err = os.MkdirAll(parent, 0750); err != nil {
+		return "", "", fmt.Errorf("failed to create directory '%s': %w", parent, err)
+	}
+	return srcPath, dstPath, nil
+}
+
+// checkInside fails unless path, with every symlink followed, is below
+// templatesDir.
+func checkInside(templatesDir, path string) error {
+	root, err := filepath.EvalSymlinks(templatesDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve templates directory '%s': %w", templatesDir, err)
+	}
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve template '%s': %w", path, err)
+	}
+	rel, err := filepath.Rel(root, target)
+	if err != nil || rel == "." || !filepath.IsLocal(rel) {
+		return fmt.Errorf("refusing to use '%s': it resolves to '%s', outside the templates directory '%s'",
+			path, target, templatesDir)
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDefaultTemplatesDir(t *testing.T) {
+	t.Setenv(TemplatesDirEnv, "/srv/templates")
+	if dir, err := DefaultTemplatesDir(); err != nil || dir != "/srv/templates" {
+		t.Errorf("Expected the environment variable to win, got %q (%v)", dir, err)
+	}
+
+	t.Setenv(TemplatesDirEnv, "")
+	t.Setenv("HOME", "/home/someone")
+	want := filepath.Join("/home/someone", ".mold", "templates")
+	if dir, err := DefaultTemplatesDir(); err != nil || dir != want {
+		t.Errorf("Expected %q, got %q (%v)", want, dir, err)
+	}
+}
+
+func TestFindTemplatePaths(t *testing.T) {
+	// The tree is a git repository holding a service with its own templates,
+	// inside a directory with templates outside the repository.
+	root := writeTemplate(t, map[string]string{
+		"templates/outer/README.md":                       "outer",
+		"repo/.git/HEAD":                                  "ref: refs/heads/main\n",
+		"repo/templates/go/main.go.tmpl":                  "package main",
+		"repo/services/api/templates/api/README.md":       "api",
+		"repo/services/api/cmd/server/templates/.gitkeep": "",
+		"repo/services/api/cmd/server/internal/sub/x.go":  "package sub",
+		"repo/services/web/src/app.js":                    "app",
+		"repo/services/web/templates":                     "a file, not a directory",
+		"standalone/deep/nested/file.txt":                 "no repository",
+	})
+	t.Setenv(TemplatePathsEnv, "")
+	t.Setenv(TemplatesDirEnv, "")
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	userDir := filepath.Join(home, ".mold", "templates")
+
+	tests := []struct {
+		start string
+		want  string
+	}{
+		{start: "repo", want: "repo/templates"},
+		{start: "repo/templates/go", want: "repo/templates"},
+		{start: "repo/services/web/src", want: "repo/templates"},
+		{start: "repo/services/api", want: "repo/services/api/templates"},
+		{start: "repo/services/api/cmd", want: "repo/services/api/templates"},
+		{start: "repo/services/api/cmd/server/internal/sub", want: "repo/services/api/cmd/server/templates"},
+		{start: "standalone/deep/nested", want: "templates"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.start, func(t *testing.T) {
+			paths, err := FindTemplatePaths(filepath.Join(root, filepath.FromSlash(tt.start)), nil)
+			want := []string{filepath.Join(root, filepath.FromSlash(tt.want)), userDir}
+			if err != nil || !slices.Equal(paths, want) {
+				t.Errorf("Expected %q, got %q (%v)", want, paths, err)
+			}
+		})
+	}
+
+	// The configured paths replace the default one, after the project's.
+	paths, err := FindTemplatePaths(filepath.Join(root, "repo"), []string{"/team", "/personal", "/team"})
+	want := []string{filepath.Join(root, "repo", "templates"), "/team", "/personal"}
+	if err != nil || !slices.Equal(paths, want) {
+		t.Errorf("Expected %q, got %q (%v)", want, paths, err)
+	}
+
+	// The search stops at the repository root.
+	if err = os.RemoveAll(filepath.Join(root, "repo", "templates")); err != nil {
+		t.Fatal(err)
+	}
+	paths, err = FindTemplatePaths(filepath.Join(root, "repo", "services", "web"), nil)
+	if err != nil || !slices.Equal(paths, []string{userDir}) {
+		t.Errorf("Expected the default %q past the repository root, got %q (%v)", userDir, paths, err)
+	}
+
+	if dir := ProjectTemplatesDir(filepath.Join(root, "repo", "services", "web")); dir != "" {
+		t.Errorf("Expected no project templates directory, got %q", dir)
+	}
+
+	// The environment variables win over the search, a list of empty paths
+	// being unset.
+	t.Setenv(TemplatePathsEnv, string(os.PathListSeparator))
+	t.Setenv(TemplatesDirEnv, "/srv/templates")
+	paths, err = FindTemplatePaths(filepath.Join(root, "repo"), []string{"/team"})
+	if err != nil || !slices.Equal(paths, []string{"/srv/templates"}) {
+		t.Errorf("Expected the templates directory variable to win, got %q (%v)", paths, err)
+	}
+	t.Setenv(TemplatePathsEnv, strings.Join([]string{"/a", "", "/b"}, string(os.PathListSeparator)))
+	paths, err = FindTemplatePaths(filepath.Join(root, "repo"), []string{"/team"})
+	if err != nil || !slices.Equal(paths, []string{"/a", "/b"}) {
+		t.Errorf("Expected the template paths variable to win, got %q (%v)", paths, err)
+	}
+}
+
+func TestFindTemplate(t *testing.T) {
+	project := writeTemplate(t, map[string]string{
+		"svc/template.yaml":     "description: project",
+		"web/index.html":        "project",
+		"go/service/main.go":    "package main",
+		"not-a-dir/placeholder": "",
+	})
+	user := writeTemplate(t, map[string]string{
+		"svc/template.yaml": "description: user",
+		"cli/main.go":       "package main",
+	})
+	team := writeTemplate(t, map[string]string{"svc/README.md": "team"})
+	paths := []string{project, filepath.Join(t.TempDir(), "missing"), user, team}
+
+	tests := []struct {
+		name         string
+		want         string
+		wantShadowed []string
+		wantErr      string
+	}{
+		{name: "svc", want: filepath.Join(project, "svc"),
+			wantShadowed: []string{filepath.Join(user, "svc"), filepath.Join(team, "svc")}},
+		{name: "cli", want: filepath.Join(user, "cli")},
+		{name: "go/service", want: filepath.Join(project, "go", "service")},
+		{name: "nope", wantErr: "template 'nope' not found in '" + project + "', '"},
+		{name: "../svc", wantErr: "invalid template name"},
+	}
+	for _, tt := range tests {
+		path, shadowed, err := FindTemplate(paths, tt.name)
+		if tt.wantErr != "" {
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || path != tt.want || !slices.Equal(shadowed, tt.wantShadowed) {
+			t.Errorf("%s: expected %q shadowing %q, got %q %q (%v)", tt.name, tt.want, tt.wantShadowed, path,
+				shadowed, err)
+		}
+	}
+}
+
+func TestResolveTemplate(t *testing.T) {
+	templatesDir := writeTemplate(t, map[string]string{
+		"go/service/main.go.tmpl": "package main",
+		"plain/README.md":         "readme",
+		"file.txt":                "not a template",
+	})
+	outside := writeTemplate(t, map[string]string{"keep.txt": "keep"})
+	if err := os.Symlink(outside, filepath.Join(templatesDir, "escape")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(templatesDir, "plain"), filepath.Join(templatesDir, "alias")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{name: "go/service", want: filepath.Join(templatesDir, "go", "service")},
+		{name: "/plain/", want: filepath.Join(templatesDir, "plain")},
+		{name: "alias", want: filepath.Join(templatesDir, "alias")},
+		{name: "missing", wantErr: "template 'missing' not found in"},
+		{name: "file.txt", wantErr: "is not a directory"},
+		{name: "escape", wantErr: "outside the templates directory"},
+		{name: "escape/", wantErr: "outside the templates directory"},
+		{name: "../x", wantErr: "invalid template name '../x'"},
+		{name: "go/../plain", wantErr: "invalid template name"},
+		{name: "", wantErr: "invalid template name"},
+		{name: ".", wantErr: "invalid template name"},
+	}
+	for _, tt := range tests {
+		got, err := ResolveTemplate(templatesDir, tt.name)
+		if tt.wantErr != "" {
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("ResolveTemplate(%q): expected error containing %q, got %q, %v", tt.name, tt.wantErr, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ResolveTemplate(%q) = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestDeleteTemplate(t *testing.T) {
+	templatesDir := writeTemplate(t, map[string]string{
+		"go/service/main.go.tmpl": "package main",
+		"go/cli/main.go.tmpl":     "package main",
+	})
+	outside := writeTemplate(t, map[string]string{"keep.txt": "keep"})
+	if err := os.Symlink(outside, filepath.Join(templatesDir, "go", "escape")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	if err := DeleteTemplate(templatesDir, "go/service"); err != nil {
+		t.Fatalf("DeleteTemplate failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(templatesDir, "go", "service")); !os.IsNotExist(err) {
+		t.Errorf("Expected the template to be deleted, got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(templatesDir, "go", "cli")); err != nil {
+		t.Errorf("Expected the sibling template to be kept: %v", err)
+	}
+
+	if err := DeleteTemplate(templatesDir, "go/escape"); err == nil {
+		t.Error("Expected deleting a template outside the templates directory to fail")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "keep.txt")); err != nil {
+		t.Errorf("Expected the symlink target to be kept: %v", err)
+	}
+	if err := DeleteTemplate(templatesDir, "go/service"); err == nil || !contains(err.Error(), "not found") {
+		t.Errorf("Expected deleting a missing template to fail, got: %v", err)
+	}
+}
+
+func TestCopyAndRenameTemplate(t *testing.T) {
+	metadata := "# The service template.\nname: go/service # kept in sync\nextends: ../base\n"
+	templatesDir := writeTemplate(t, map[string]string{
+		"go/service/" + MetadataFile: metadata,
+		"go/service/main.go.tmpl":    "package main",
+		"go/other/README.md":         "other",
+		"plain/README.md":            "no metadata",
+	})
+
+	path, err := CopyTemplate(templatesDir, "go/service", "team/go/api")
+	if err != nil {
+		t.Fatalf("CopyTemplate failed: %v", err)
+	}
+	if path != filepath.Join(templatesDir, "team", "go", "api") {
+		t.Errorf("Unexpected path of the copy: %s", path)
+	}
+	content, err := os.ReadFile(filepath.Join(path, MetadataFile))
+	if err != nil {
+		t.Fatalf("Failed to read the copied metadata: %v", err)
+	}
+	want := "# The service template.\nname: team/go/api # kept in sync\nextends: ../base\n"
+	if string(content) != want {
+		t.Errorf("Copied metadata = %q, want %q", content, want)
+	}
+	original, _ := os.ReadFile(filepath.Join(templatesDir, "go", "service", MetadataFile))
+	if string(original) != metadata {
+		t.Errorf("Expected the source metadata to be unchanged, got %q", original)
+	}
+
+	if _, err = RenameTemplate(templatesDir, "plain", "docs/plain"); err != nil {
+		t.Fatalf("RenameTemplate failed: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(templatesDir, "docs", "plain", "README.md")); err != nil {
+		t.Errorf("Expected the renamed template: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(templatesDir, "plain")); !os.IsNotExist(err) {
+		t.Errorf("Expected the old template to be gone, got: %v", err)
+	}
+
+	path, err = RenameTemplate(templatesDir, "team/go/api", "api")
+	if err != nil {
+		t.Fatalf("RenameTemplate failed: %v", err)
+	}
+	if meta, err := LoadMetadata(path); err != nil || meta.Name != "api" {
+		t.Errorf("Expected the renamed template to be named 'api', got %+v (%v)", meta, err)
+	}
+
+	errTests := []struct {
+		src, dst, wantErr string
+	}{
+		{src: "go/service", dst: "go/other", wantErr: "template 'go/other' already exists"},
+		{src: "missing", dst: "new", wantErr: "template 'missing' not found"},
+		{src: "go", dst: "go/service/nested", wantErr: "inside itself"},
+		{src: "go/service", dst: "../outside", wantErr: "invalid template name"},
+	}
+	for _, tt := range errTests {
+		if _, err := CopyTemplate(templatesDir, tt.src, tt.dst); err == nil || !contains(err.Error(), tt.wantErr) {
+			t.Errorf("CopyTemplate(%q, %q): expected error containing %q, got: %v", tt.src, tt.dst, tt.wantErr, err)
+		}
+		if _, err := RenameTemplate(templatesDir, tt.src, tt.dst); err == nil || !contains(err.Error(), tt.wantErr) {
+			t.Errorf("RenameTemplate(%q, %q): expected error containing %q, got: %v", tt.src, tt.dst, tt.wantErr, err)
+		}
+	}
+
+	outside := t.TempDir()
+	if err = os.Symlink(outside, filepath.Join(templatesDir, "linked")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if _, err = CopyTemplate(templatesDir, "go/service", "linked/group/x"); err == nil ||
+		!contains(err.Error(), "outside the templates directory") {
+		t.Errorf("Expected copying through a symlink to fail, got: %v", err)
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+		t.Errorf("Expected nothing to be created outside the templates directory, got %v", entries)
+	}
+}
@@ -0,0 +1,168 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// noValue is what a reference to a missing key renders as outside strict
+// mode.
+const noValue = "<no value>"
+
+// keyTracker records the data the parts of a template use, from the dotted
+// path of each reference to the files, or other consumers, using it.
+type keyTracker map[string][]string
+
+// record adds the references of the template text source, used by file.
+// Text that doesn't parse records nothing; rendering reports it.
+func (k keyTracker) record(file, source string) {
+	found, _ := IdentifyReferences(file, source)
+	for _, ref := range found {
+		k.use(ref, file)
+	}
+}
+
+// use records that by uses the data at the dotted path ref.
+func (k keyTracker) use(ref, by string) {
+	if !slices.Contains(k[ref], by) {
+		k[ref] = append(k[ref], by)
+	}
+}
+
+// add records the references of another tracker.
+func (k keyTracker) add(other keyTracker) {
+	for ref, users := range other {
+		for _, by := range users {
+			k.use(ref, by)
+		}
+	}
+}
+
+// keys returns the sorted top-level keys of the references.
+func (k keyTracker) keys() []string {
+	keys := make([]string, 0, len(k))
+	for ref := range k {
+		key, _, _ := strings.Cut(ref, ".")
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// unused returns the sorted dotted paths of the values of data no reference
+// uses. A reference uses the values under it, and the value above it.
+func (k keyTracker) unused(data map[string]any) []string {
+	values := make(map[string]bool)
+	leafPaths("", data, values)
+	refs := slices.Collect(maps.Keys(k))
+	unused := []string{}
+	for _, path := range slices.Sorted(maps.Keys(values)) {
+		if !slices.ContainsFunc(refs, func(ref string) bool {
+			return ref == path || strings.HasPrefix(path, ref+".") || strings.HasPrefix(ref, path+".")
+		}) {
+			unused = append(unused, path)
+		}
+	}
+	return unused
+}
+
+// leafPaths adds the dotted paths of the values of data that aren't
+// mappings, or are empty ones, to paths.
+func leafPaths(prefix string, data map[string]any, paths map[string]bool) {
+	for key, value := range data {
+		path := prefix + key
+		if m, ok := value.(map[string]any); ok && len(m) > 0 {
+			leafPaths(path+".", m, paths)
+			continue
+		}
+		paths[path] = true
+	}
+}
+
+// MissingValue is a reference to a key missing from the data, rendered as
+// "<no value>" outside strict mode.
+type MissingValue struct {
+	// Path is the slash-separated output path of the file, and Line the
+	// line of the reference.
+	Path string `json:"path"`
+	Line int    `json:"line"`
+}
+
+// String returns the location as path:line.
+func (m MissingValue) String() string {
+	return fmt.Sprintf("%s:%d", m.Path, m.Line)
+}
+
+// references returns the data the layers use, found once for the run.
+// Matching fuzzy keys, asking for missing values and reporting unused keys
+// all rely on it.
+func (a *applier) references() (keyTracker, error) {
+	if a.refs != nil {
+		return a.refs, nil
+	}
+	refs := make(keyTracker)
+	for _, l := range a.layers {
+		found, err := templateReferences(l.path, l.meta, a.opts.MaxTemplateSize)
+		if err != nil {
+			return nil, err
+		}
+		refs.add(found)
+	}
+	for _, key := range a.opts.Referenced {
+		refs.use(key, "options")
+	}
+	a.refs = refs
+	return refs, nil
+}
+
+// trackMissing records the references to missing keys that rendered as
+// "<no value>" in the content of a generated file. Templates holding the
+// text themselves are left alone.
+func (a *applier) trackMissing(relPath string, source, content []byte) {
+	if a.opts.Strict || a.opts.NoWarnMissing || bytes.Contains(source, []byte(noValue)) {
+		return
+	}
+	line := 1
+	for text := range bytes.Lines(content) {
+		for range bytes.Count(text, []byte(noValue)) {
+			a.missing = append(a.missing, MissingValue{Path: relPath, Line: line})
+		}
+		line++
+	}
+}
+
+// warnUsage warns about the keys of the data the templates never use, which
+// are often misspelled, and about the references to missing keys rendered
+// as "<no value>".
+func (a *applier) warnUsage(data map[string]any) error {
+	if !a.opts.NoWarnUnused {
+		refs, err := a.references()
+		if err != nil {
+			return err
+		}
+		// The prompts consume their values, or warn about ignoring them.
+		for _, prompt := range a.prompts {
+			refs.use(prompt.Name, MetadataFile)
+		}
+		a.unused = refs.unused(data)
+		if len(a.unused) > 0 {
+			fmt.Fprintf(a.out, "⚠️  Data keys no template or path uses, check their spelling: %s\n",
+				strings.Join(a.unused, ", "))
+		}
+	}
+	if len(a.missing) > 0 {
+		locations := make([]string, 0, len(a.missing))
+		for _, missing := range a.missing {
+			locations = append(locations, missing.String())
+		}
+		references := fmt.Sprintf("%d references to missing keys", len(a.missing))
+		if len(a.missing) == 1 {
+			references = "1 reference to a missing key"
+		}
+		fmt.Fprintf(a.out, "⚠️  %s rendered as %s: %s\n", references, noValue, strings.Join(locations, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestKeyTrackerUnused(t *testing.T) {
+	refs := make(keyTracker)
+	refs.record("a.tmpl", "{{.name}} {{.db.host}} {{range .items}}{{.}}{{end}}")
+	refs.use("owner", "options")
+	data := map[string]any{
+		"name":         "app",
+		"owner":        "ann",
+		"db":           map[string]any{"host": "localhost", "port": 5432},
+		"items":        []any{"a"},
+		"porject_name": "typo",
+		"empty":        map[string]any{},
+	}
+	want := []string{"db.port", "empty", "porject_name"}
+	if unused := refs.unused(data); !reflect.DeepEqual(unused, want) {
+		t.Errorf("Expected %q to be unused, got %q", want, unused)
+	}
+	if keys := refs.keys(); !reflect.DeepEqual(keys, []string{"db", "items", "name", "owner"}) {
+		t.Errorf("Expected the top-level keys, got %q", keys)
+	}
+}
+
+func TestApplyUsageWarnings(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile: "prompts:\n  database: {}\n  db_user: {when: '{{eq .database \"postgres\"}}'}\n" +
+			"computed:\n  module: 'example.com/{{.org}}'\n",
+		"{{.dir}}/main.go.tmpl": "package {{.package}}\n\n// {{.project_name}} {{.module}} {{.db_user}}\n",
+		"literal.txt.tmpl":      "{{/* The template says <no value> itself. */}}<no value> {{.nothing}}\n",
+	})
+	// projectName is only read as project_name, org by a computed value,
+	// database by a when condition and slug by the caller.
+	data := map[string]any{
+		"dir": "cmd", "package": "main", "projectName": "app", "org": "acme", "database": "sqlite",
+		"porject_name": "typo", "slug": "app",
+	}
+	var out bytes.Buffer
+	result := NewResult()
+	err := Apply(Options{
+		TemplatePath: templateDir,
+		OutputDir:    t.TempDir(),
+		Data:         data,
+		FuzzyKeys:    true,
+		Out:          &out,
+		Result:       result,
+		Referenced:   []string{"slug"},
+	})
+	if err != nil {
+		t.Fatalf("Apply failed: %v\n%s", err, out.String())
+	}
+	if !reflect.DeepEqual(result.UnusedKeys, []string{"porject_name"}) {
+		t.Errorf("Expected the unused keys, got %q", result.UnusedKeys)
+	}
+	wantMissing := []MissingValue{{Path: "cmd/main.go", Line: 3}}
+	if !reflect.DeepEqual(result.MissingValues, wantMissing) {
+		t.Errorf("Expected %v, got %v", wantMissing, result.MissingValues)
+	}
+	for _, line := range []string{
+		"⚠️  Data keys no template or path uses, check their spelling: porject_name\n",
+		"⚠️  1 reference to a missing key rendered as <no value>: cmd/main.go:3\n",
+	} {
+		if !contains(out.String(), line) {
+			t.Errorf("Expected %q in the output:\n%s", line, out.String())
+		}
+	}
+
+	// The flags turn the warnings off.
+	out.Reset()
+	result = NewResult()
+	err = Apply(Options{
+		TemplatePath:  templateDir,
+		OutputDir:     t.TempDir(),
+		Data:          data,
+		Out:           &out,
+		Result:        result,
+		NoWarnUnused:  true,
+		NoWarnMissing: true,
+	})
+	if err != nil {
+		t.Fatalf("Apply failed: %v\n%s", err, out.String())
+	}
+	if contains(out.String(), "⚠️") || result.UnusedKeys != nil || result.MissingValues != nil {
+		t.Errorf("Expected no usage warning, got %v %v:\n%s", result.UnusedKeys, result.MissingValues, out.String())
+	}
+}
@@ -0,0 +1,335 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Prompt types.
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeFloat  = "float"
+	TypeBool   = "bool"
+	TypeList   = "list"
+	TypeMap    = "map"
+	TypeEnum   = "enum"
+	// TypeSelect is an enum whose choices can have labels.
+	TypeSelect = "select"
+	// TypeMultiselect is a list of choices.
+	TypeMultiselect = "multiselect"
+	// TypeConfirm is a yes or no answer, stored as a bool.
+	TypeConfirm = "confirm"
+)
+
+// promptTypes lists the supported prompt types.
+//
+//nolint:gochecknoglobals // list of the supported prompt types
+var promptTypes = []string{
+	"", TypeString, TypeInt, TypeFloat, TypeBool, TypeList, TypeMap, TypeEnum, TypeSelect, TypeMultiselect,
+	TypeConfirm,
+}
+
+// check reports mistakes in the declaration of the prompt.
+func (p Prompt) check() error {
+	if !slices.Contains(promptTypes, p.Type) {
+		return fmt.Errorf("unknown type '%s', expected one of %s", p.Type, strings.Join(promptTypes[1:], ", "))
+	}
+	if p.hasChoices() && len(p.Choices) == 0 {
+		return fmt.Errorf("%s prompts need choices", p.Type)
+	}
+	for i, choice := range p.Choices {
+		if _, ok := choiceValue(choice); !ok {
+			return fmt.Errorf("choice %d has no value", i+1)
+		}
+	}
+	text := p.Type == "" || p.Type == TypeString
+	numeric := p.Type == "" || p.Type == TypeInt || p.Type == TypeFloat
+	switch {
+	case !text && (p.Pattern != "" || p.MinLength != nil || p.MaxLength != nil):
+		return fmt.Errorf("pattern, minLength and maxLength need a string prompt, not %s", p.Type)
+	case !numeric && (p.Min != nil || p.Max != nil):
+		return fmt.Errorf("min and max need an int or float prompt, not %s", p.Type)
+	case p.MinLength != nil && *p.MinLength < 0:
+		return fmt.Errorf("minLength is %d, expected a positive number", *p.MinLength)
+	case p.MinLength != nil && p.MaxLength != nil && *p.MinLength > *p.MaxLength:
+		return fmt.Errorf("minLength %d is above maxLength %d", *p.MinLength, *p.MaxLength)
+	case p.Min != nil && p.Max != nil && *p.Min > *p.Max:
+		return fmt.Errorf("min %v is above max %v", *p.Min, *p.Max)
+	}
+	if _, err := regexp.Compile(p.Pattern); err != nil {
+		return fmt.Errorf("invalid pattern '%s': %w", p.Pattern, err)
+	}
+	return nil
+}
+
+// Validate checks the data value of every typed prompt and coerces obvious
+// cases in place, such as numeric strings for int prompts or "true" for bool
+// prompts, then checks the values against the rules of their prompts.
+// Missing values are left alone. All failures are reported together.
+func (p Prompts) Validate(data map[string]any) error {
+	var errs []error
+	for _, prompt := range p {
+		value, ok := LookupValue(data, prompt.Name)
+		if !ok {
+			continue
+		}
+		if prompt.Type == "" {
+			errs = append(errs, prompt.checkRules(value)...)
+			continue
+		}
+		coerced, err := prompt.coerce(value)
+		if err != nil && prompt.Secret {
+			errs = append(errs, fmt.Errorf("invalid value for '%s': expected %s, got %s",
+				prompt.Name, prompt.expected(), SecretMask))
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid value for '%s': %w", prompt.Name, err))
+			continue
+		}
+		if err = SetValue(data, prompt.Name, coerced); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, prompt.checkRules(coerced)...)
+	}
+	return errors.Join(errs...)
+}
+
+// checkRules returns an error for every rule of the prompt the value breaks,
+// naming the rule and showing the value, unless it is secret. The message
+// of the prompt replaces the description of the rule.
+func (p Prompt) checkRules(value any) []error {
+	shown := fmt.Sprintf("%#v", value)
+	if p.Secret {
+		shown = SecretMask
+	}
+	var errs []error
+	broken := func(rule, description string) {
+		if p.Message != "" {
+			description = p.Message
+		}
+		errs = append(errs, fmt.Errorf("invalid value for '%s': %s (%s), got %s", p.Name, description, rule, shown))
+	}
+	switch v := value.(type) {
+	case string:
+		// The pattern compiled when the metadata was loaded.
+		if p.Pattern != "" && !regexp.MustCompile(p.Pattern).MatchString(v) {
+			broken("pattern", fmt.Sprintf("must match '%s'", p.Pattern))
+		}
+		length := utf8.RuneCountInString(v)
+		if p.MinLength != nil && length < *p.MinLength {
+			broken("minLength", fmt.Sprintf("must be at least %d characters long", *p.MinLength))
+		}
+		if p.MaxLength != nil && length > *p.MaxLength {
+			broken("maxLength", fmt.Sprintf("must be at most %d characters long", *p.MaxLength))
+		}
+	case int, float64:
+		number := toFloat(v)
+		if p.Min != nil && number < *p.Min {
+			broken("min", fmt.Sprintf("must be at least %v", *p.Min))
+		}
+		if p.Max != nil && number > *p.Max {
+			broken("max", fmt.Sprintf("must be at most %v", *p.Max))
+		}
+	}
+	return errs
+}
+
+// toFloat returns an int or a float64 as a float64.
+func toFloat(value any) float64 {
+	if i, ok := value.(int); ok {
+		return float64(i)
+	}
+	f, _ := value.(float64)
+	return f
+}
+
+// expected describes the values the prompt accepts.
+func (p Prompt) expected() string {
+	switch p.Type {
+	case TypeEnum, TypeSelect:
+		return fmt.Sprintf("one of %v", p.choiceValues())
+	case TypeMultiselect:
+		return fmt.Sprintf("a list of %v", p.choiceValues())
+	case TypeConfirm:
+		return "yes or no"
+	}
+	return p.Type
+}
+
+// hasChoices reports whether the values of the prompt are picked from its
+// choices.
+func (p Prompt) hasChoices() bool {
+	return p.Type == TypeEnum || p.Type == TypeSelect || p.Type == TypeMultiselect
+}
+
+// choiceValues returns the values of the choices of the prompt.
+func (p Prompt) choiceValues() []any {
+	values := make([]any, 0, len(p.Choices))
+	for _, choice := range p.Choices {
+		if value, ok := choiceValue(choice); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// choiceValue returns the value of a choice, written either as the value
+// itself or as a mapping with a value and an optional label, which only
+// describes it.
+func choiceValue(choice any) (any, bool) {
+	m, ok := choice.(map[string]any)
+	if !ok {
+		return choice, true
+	}
+	value, ok := m["value"]
+	return value, ok
+}
+
+// pick returns the choice value matching value, compared as text so "2"
+// picks the choice 2.
+func (p Prompt) pick(value any) (any, error) {
+	for _, choice := range p.choiceValues() {
+		if fmt.Sprint(choice) == fmt.Sprint(value) {
+			return choice, nil
+		}
+	}
+	return nil, fmt.Errorf("%#v is not one of %v", value, p.choiceValues())
+}
+
+// pickAll returns the choice values matching a list of values, or a string
+// of comma-separated values as given with --set.
+func (p Prompt) pickAll(value any) (any, error) {
+	var values []any
+	switch v := value.(type) {
+	case []any:
+		values = v
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("expected %s, got %T %#v", p.expected(), value, value)
+	}
+	picked := make([]any, 0, len(values))
+	for _, item := range values {
+		choice, err := p.pick(item)
+		if err != nil {
+			return nil, err
+		}
+		picked = append(picked, choice)
+	}
+	return picked, nil
+}
+
+// coerce converts the value to the type of the prompt.
+func (p Prompt) coerce(value any) (any, error) {
+	mismatch := fmt.Errorf("expected %s, got %T %#v", p.expected(), value, value)
+	switch p.Type {
+	case TypeString:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case int, float64, bool:
+			return fmt.Sprint(v), nil
+		}
+	case TypeInt:
+		return coerceInt(value, mismatch)
+	case TypeFloat:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f, nil
+			}
+		}
+	case TypeBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(strings.ToLower(strings.TrimSpace(v))); err == nil {
+				return b, nil
+			}
+		}
+	case TypeList:
+		if v, ok := value.([]any); ok {
+			return v, nil
+		}
+	case TypeMap:
+		if v, ok := value.(map[string]any); ok {
+			return v, nil
+		}
+	case TypeEnum, TypeSelect:
+		return p.pick(value)
+	case TypeMultiselect:
+		return p.pickAll(value)
+	case TypeConfirm:
+		return coerceConfirm(value, mismatch)
+	}
+	return nil, mismatch
+}
+
+// coerceConfirm converts yes or no answers, as ParseConfirm reads them, and
+// the numbers 1 and 0 to bool.
+func coerceConfirm(value any, mismatch error) (any, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case int:
+		if v == 0 || v == 1 {
+			return v == 1, nil
+		}
+	case string:
+		if yes, ok := ParseConfirm(v); ok {
+			return yes, nil
+		}
+	}
+	return nil, mismatch
+}
+
+// coerceInt converts whole numbers and numeric strings to int.
+func coerceInt(value any, mismatch error) (any, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case float64:
+		// Whole floats, such as 8080.0, are accepted.
+		if v == math.Trunc(v) && v >= math.MinInt && v <= math.MaxInt {
+			return int(v), nil
+		}
+	case string:
+		if i, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return i, nil
+		}
+	}
+	return nil, mismatch
+}
+
+// LookupValue returns the value at the dotted key path in data.
+func LookupValue(data map[string]any, key string) (any, bool) {
+	var value any = data
+	for _, part := range strings.Split(key, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,209 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPromptsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		prompt  Prompt
+		value   any
+		want    any
+		wantErr string
+	}{
+		{name: "untyped", prompt: Prompt{}, value: "x", want: "x"},
+		{name: "string", prompt: Prompt{Type: TypeString}, value: "x", want: "x"},
+		{name: "number as string", prompt: Prompt{Type: TypeString}, value: 8080, want: "8080"},
+		{
+			name:    "list as string",
+			prompt:  Prompt{Type: TypeString},
+			value:   []any{},
+			wantErr: "expected string, got []interface {}",
+		},
+		{name: "int", prompt: Prompt{Type: TypeInt}, value: 8080, want: 8080},
+		{name: "numeric string as int", prompt: Prompt{Type: TypeInt}, value: " 8080", want: 8080},
+		{name: "JSON number as int", prompt: Prompt{Type: TypeInt}, value: float64(8080), want: 8080},
+		{name: "fraction as int", prompt: Prompt{Type: TypeInt}, value: 1.5, wantErr: "expected int, got float64 1.5"},
+		{name: "word as int", prompt: Prompt{Type: TypeInt}, value: "abc", wantErr: `expected int, got string "abc"`},
+		{name: "float", prompt: Prompt{Type: TypeFloat}, value: "0.5", want: 0.5},
+		{name: "int as float", prompt: Prompt{Type: TypeFloat}, value: 2, want: 2.0},
+		{name: "bool", prompt: Prompt{Type: TypeBool}, value: true, want: true},
+		{name: "bool string", prompt: Prompt{Type: TypeBool}, value: "False", want: false},
+		{name: "word as bool", prompt: Prompt{Type: TypeBool}, value: "maybe", wantErr: "expected bool"},
+		{name: "list", prompt: Prompt{Type: TypeList}, value: []any{"a"}, want: []any{"a"}},
+		{name: "map", prompt: Prompt{Type: TypeMap}, value: "a", wantErr: "expected map"},
+		{
+			name:   "enum member",
+			prompt: Prompt{Type: TypeEnum, Choices: []any{"mit", 2}},
+			value:  "2",
+			want:   2,
+		},
+		{
+			name:    "enum non-member",
+			prompt:  Prompt{Type: TypeEnum, Choices: []any{"mit", "apache"}},
+			value:   "gpl",
+			wantErr: `"gpl" is not one of [mit apache]`,
+		},
+		{
+			name:   "select with labels",
+			prompt: Prompt{Type: TypeSelect, Choices: []any{map[string]any{"value": "mit", "label": "MIT"}, "gpl"}},
+			value:  "mit",
+			want:   "mit",
+		},
+		{
+			name:    "select non-member",
+			prompt:  Prompt{Type: TypeSelect, Choices: []any{map[string]any{"value": "mit", "label": "MIT"}}},
+			value:   "MIT",
+			wantErr: `"MIT" is not one of [mit]`,
+		},
+		{
+			name:   "multiselect list",
+			prompt: Prompt{Type: TypeMultiselect, Choices: []any{"postgres", "redis", 3}},
+			value:  []any{"redis", "3"},
+			want:   []any{"redis", 3},
+		},
+		{
+			name:   "multiselect from --set",
+			prompt: Prompt{Type: TypeMultiselect, Choices: []any{"postgres", "redis"}},
+			value:  "postgres, redis",
+			want:   []any{"postgres", "redis"},
+		},
+		{
+			name:   "empty multiselect",
+			prompt: Prompt{Type: TypeMultiselect, Choices: []any{"postgres"}},
+			value:  []any{},
+			want:   []any{},
+		},
+		{
+			name:    "multiselect non-member",
+			prompt:  Prompt{Type: TypeMultiselect, Choices: []any{"postgres", "redis"}},
+			value:   "postgres,mysql",
+			wantErr: `"mysql" is not one of [postgres redis]`,
+		},
+		{
+			name:    "multiselect of a number",
+			prompt:  Prompt{Type: TypeMultiselect, Choices: []any{"postgres"}},
+			value:   5,
+			wantErr: "expected a list of [postgres], got int 5",
+		},
+		{name: "confirm", prompt: Prompt{Type: TypeConfirm}, value: "Y", want: true},
+		{name: "confirm bool", prompt: Prompt{Type: TypeConfirm}, value: false, want: false},
+		{name: "confirm number", prompt: Prompt{Type: TypeConfirm}, value: 1, want: true},
+		{name: "confirm junk", prompt: Prompt{Type: TypeConfirm}, value: 2, wantErr: "expected yes or no, got int 2"},
+		{name: "pattern", prompt: Prompt{Pattern: "^[a-z-]+$"}, value: "my-app", want: "my-app"},
+		{
+			name:    "pattern mismatch",
+			prompt:  Prompt{Type: TypeString, Pattern: "^[a-z-]+$"},
+			value:   "My App",
+			wantErr: `must match '^[a-z-]+$' (pattern), got "My App"`,
+		},
+		{
+			name:    "custom message",
+			prompt:  Prompt{Pattern: "^[a-z-]+$", Message: "use lowercase letters and dashes"},
+			value:   "My App",
+			wantErr: `use lowercase letters and dashes (pattern), got "My App"`,
+		},
+		{
+			name:    "too short",
+			prompt:  Prompt{Type: TypeString, MinLength: ptr(3)},
+			value:   "éé",
+			wantErr: `must be at least 3 characters long (minLength), got "éé"`,
+		},
+		{
+			name:    "too long",
+			prompt:  Prompt{MaxLength: ptr(2)},
+			value:   "abc",
+			wantErr: `must be at most 2 characters long (maxLength), got "abc"`,
+		},
+		{name: "within bounds", prompt: Prompt{Type: TypeInt, Min: ptr(1.0), Max: ptr(65535.0)}, value: "443", want: 443},
+		{
+			name:    "below min",
+			prompt:  Prompt{Type: TypeFloat, Min: ptr(0.5)},
+			value:   0.25,
+			wantErr: "must be at least 0.5 (min), got 0.25",
+		},
+		{
+			name:    "above max after coercion",
+			prompt:  Prompt{Type: TypeInt, Max: ptr(65535.0)},
+			value:   "70000",
+			wantErr: "must be at most 65535 (max), got 70000",
+		},
+		{
+			name:    "secret",
+			prompt:  Prompt{Secret: true, MinLength: ptr(12)},
+			value:   "hunter2",
+			wantErr: "must be at least 12 characters long (minLength), got " + SecretMask,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.prompt.Name = "key"
+			data := map[string]any{"key": tt.value}
+			err := Prompts{tt.prompt}.Validate(data)
+			if tt.wantErr != "" {
+				if err == nil || !contains(err.Error(), "invalid value for 'key': "+tt.wantErr) {
+					t.Errorf("Expected error %q, got: %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate failed: %v", err)
+			}
+			if !reflect.DeepEqual(data["key"], tt.want) {
+				t.Errorf("Got %#v, want %#v", data["key"], tt.want)
+			}
+		})
+	}
+
+	t.Run("nested keys, missing values and joined errors", func(t *testing.T) {
+		data := map[string]any{"db": map[string]any{"port": "5432"}, "a": "x", "b": "y"}
+		prompts := Prompts{
+			{Name: "db.port", Type: TypeInt},
+			{Name: "missing", Type: TypeInt},
+			{Name: "a", Type: TypeInt},
+			{Name: "b", Type: TypeBool},
+		}
+		err := prompts.Validate(data)
+		if err == nil || !contains(err.Error(), "'a'") || !contains(err.Error(), "'b'") {
+			t.Errorf("Expected errors for a and b, got: %v", err)
+		}
+		if port, _ := LookupValue(data, "db.port"); port != 5432 {
+			t.Errorf("Expected coerced nested value, got %#v", port)
+		}
+	})
+
+	t.Run("every broken rule", func(t *testing.T) {
+		data := map[string]any{"name": "A", "port": 0}
+		prompts := Prompts{
+			{Name: "name", Pattern: "^[a-z]+$", MinLength: ptr(2)},
+			{Name: "port", Type: TypeInt, Min: ptr(1.0)},
+		}
+		err := prompts.Validate(data)
+		for _, rule := range []string{"(pattern)", "(minLength)", "(min)"} {
+			if err == nil || !contains(err.Error(), rule) {
+				t.Errorf("Expected an error for %s, got: %v", rule, err)
+			}
+		}
+	})
+}
+
+// ptr returns a pointer to v.
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestLookupValue(t *testing.T) {
+	data := map[string]any{"a": map[string]any{"b": 1}, "s": "x"}
+	if v, ok := LookupValue(data, "a.b"); !ok || v != 1 {
+		t.Errorf("Expected 1, got %v (%v)", v, ok)
+	}
+	if _, ok := LookupValue(data, "s.x"); ok {
+		t.Error("Expected no value below a scalar")
+	}
+	if _, ok := LookupValue(data, "a.c"); ok {
+		t.Error("Expected no value for a missing key")
+	}
+}
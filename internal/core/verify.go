@@ -0,0 +1,85 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+)
+
+// Verification is the result of checking a generated project against the
+// manifest of its provenance.
+type Verification struct {
+	// Files holds the check of every tracked file, in manifest order.
+	Files []FileCheck `json:"files"`
+	// Untracked lists the slash-separated paths of the files under the
+	// generated directories that the manifest doesn't track, when asked for.
+	Untracked []string `json:"untracked,omitempty"`
+}
+
+// Clean reports whether every tracked file is unchanged, with its mode, and
+// no untracked file was found.
+func (v *Verification) Clean() bool {
+	for _, check := range v.Files {
+		if check.State != FileUnchanged || check.Mode != "" {
+			return false
+		}
+	}
+	return len(v.Untracked) == 0
+}
+
+// Verify checks the tracked files of the project at dir with CheckFiles,
+// the same check Apply uses to find the files changed since it generated
+// them. With untracked, it also lists the files the manifest doesn't track
+// in the directories it records, and in the directories created in them
+// since. The output root, holding files mold didn't create, is left out.
+func Verify(dir string, p *Provenance, untracked bool) (*Verification, error) {
+	checks, err := CheckFiles(dir, p.Files)
+	if err != nil {
+		return nil, err
+	}
+	v := &Verification{Files: checks}
+	if !untracked {
+		return v, nil
+	}
+
+	tracked := make(map[string]bool, len(p.Files))
+	for _, file := range p.Files {
+		tracked[file.Path] = true
+	}
+	generated := make(map[string]bool, len(p.Dirs))
+	for _, rel := range p.Dirs {
+		generated[rel] = true
+	}
+	for _, rel := range p.Dirs {
+		if !filepath.IsLocal(filepath.FromSlash(rel)) {
+			return nil, fmt.Errorf("invalid manifest directory '%s'", rel)
+		}
+		root := filepath.Join(dir, filepath.FromSlash(rel))
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, walkErr error) error {
+			if walkErr != nil {
+				return walkErr
+			}
+			relPath, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			relPath = filepath.ToSlash(relPath)
+			switch {
+			case d.IsDir() && path != root && generated[relPath]:
+				// Walked on its own.
+				return filepath.SkipDir
+			case d.IsDir() || tracked[relPath] || isStatePath(relPath):
+				return nil
+			}
+			v.Untracked = append(v.Untracked, relPath)
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to list the files of '%s': %w", rel, err)
+		}
+	}
+	slices.Sort(v.Untracked)
+	return v, nil
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		"README.md.tmpl":         "# {{.name}}",
+		"cmd/main.go":            "package main\n",
+		"cmd/tool/tool.go":       "package tool\n",
+		"docs/guide.md":          "guide",
+		"{{.name}}/config.yaml":  "port: 80\n",
+		"{{.name}}/empty/.keep":  "",
+		"scripts/run.sh":         "#!/bin/sh\n",
+		"scripts/lib/common.sh":  "# common\n",
+		"scripts/lib/extra/x.sh": "# x\n",
+	})
+	outputDir := t.TempDir()
+	err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: map[string]any{"name": "demo"},
+		Out: io.Discard})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	verify := func(untracked bool) *Verification {
+		t.Helper()
+		p, err := LoadProvenance(outputDir)
+		if err != nil {
+			t.Fatalf("LoadProvenance failed: %v", err)
+		}
+		v, err := Verify(outputDir, p, untracked)
+		if err != nil {
+			t.Fatalf("Verify failed: %v", err)
+		}
+		return v
+	}
+
+	if v := verify(true); !v.Clean() || len(v.Files) != 9 {
+		t.Fatalf("Expected a clean project with 9 files, got %+v", v)
+	}
+
+	write := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(outputDir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	write("cmd/main.go", "package edited\n")
+	write("cmd/new.go", "package main\n")
+	write("cmd/tool/added/deep.go", "package added\n")
+	write("notes.txt", "at the root")
+	write(".mold/local", "state")
+	if err = os.Remove(filepath.Join(outputDir, "docs", "guide.md")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	if err = os.Chmod(filepath.Join(outputDir, "scripts", "run.sh"), 0755); err != nil {
+		t.Fatalf("Failed to chmod file: %v", err)
+	}
+
+	v := verify(false)
+	if v.Clean() || v.Untracked != nil {
+		t.Errorf("Expected a dirty project without untracked files, got %+v", v)
+	}
+	var changed []FileCheck
+	for _, check := range v.Files {
+		if check.State != FileUnchanged || check.Mode != "" {
+			changed = append(changed, check)
+		}
+	}
+	want := []FileCheck{
+		{Path: "cmd/main.go", State: FileModified},
+		{Path: "docs/guide.md", State: FileMissing},
+		{Path: "scripts/run.sh", State: FileUnchanged, Mode: "0755"},
+	}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("Expected %+v, got %+v", want, changed)
+	}
+
+	wantUntracked := []string{"cmd/new.go", "cmd/tool/added/deep.go"}
+	if v = verify(true); !slices.Equal(v.Untracked, wantUntracked) {
+		t.Errorf("Expected untracked %v, got %v", wantUntracked, v.Untracked)
+	}
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// checkWhen reports a when expression of the prompt that doesn't parse or
+// uses the prompt itself or one declared after it, given as later. The
+// prompts are asked in order, so their answers don't exist yet.
+func (p Prompt) checkWhen(later Prompts) error {
+	if p.When == "" {
+		return nil
+	}
+	keys, err := IdentifyPlaceholders(p.Name, p.When)
+	if err != nil {
+		return fmt.Errorf("invalid when '%s': %w", p.When, err)
+	}
+	for _, key := range keys {
+		for i, prompt := range later {
+			if name, _, _ := strings.Cut(prompt.Name, "."); name != key {
+				continue
+			}
+			if i == 0 {
+				return fmt.Errorf("when uses '%s', the prompt itself", key)
+			}
+			return fmt.Errorf("when uses '%s', which is asked later", key)
+		}
+	}
+	return nil
+}
+
+// enabled evaluates the when expression of the prompt against data. An empty
+// result, a missing key or a no, such as "false" or "0", turns the prompt
+// off; a prompt without when is always on.
+func (p Prompt) enabled(data map[string]any, funcs template.FuncMap) (bool, error) {
+	if p.When == "" {
+		return true, nil
+	}
+	tmpl, err := template.New(p.Name).Funcs(funcs).Parse(p.When)
+	if err != nil {
+		return false, fmt.Errorf("invalid when of prompt '%s': %w", p.Name, err)
+	}
+	var result strings.Builder
+	if err = tmpl.Execute(&result, data); err != nil {
+		return false, fmt.Errorf("failed to evaluate the when of prompt '%s': %w", p.Name, err)
+	}
+	answer := strings.TrimSpace(result.String())
+	if answer == "" || answer == "<no value>" {
+		return false, nil
+	}
+	if yes, ok := ParseConfirm(answer); ok {
+		return yes, nil
+	}
+	return true, nil
+}
+
+// Gate evaluates the when expressions of the prompts in order against data,
+// the way they are asked, and returns the names of the prompts turned off.
+// The key of a prompt turned off goes back to its value in defaults, or is
+// removed, so later prompts and the rendering see it unanswered. A value the
+// user gave in explicit for it is dropped and reported to warn.
+func (p Prompts) Gate(data, defaults, explicit map[string]any, funcs template.FuncMap,
+	warn io.Writer) ([]string, error) {
+	var skipped []string
+	for _, prompt := range p {
+		on, err := prompt.enabled(data, funcs)
+		if err != nil {
+			return nil, err
+		}
+		if on {
+			continue
+		}
+		skipped = append(skipped, prompt.Name)
+		if _, ok := LookupValue(explicit, prompt.Name); ok {
+			fmt.Fprintf(warn, "⚠️  Ignoring '%s' from the data, its prompt is skipped by when: %s\n",
+				prompt.Name, prompt.When)
+		}
+		if value, ok := LookupValue(defaults, prompt.Name); ok {
+			if err = SetValue(data, prompt.Name, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		deleteValue(data, prompt.Name)
+	}
+	return skipped, nil
+}
+
+// deleteValue removes the value at the dotted key path from data, if any.
+func deleteValue(data map[string]any, key string) {
+	parent, name, nested := strings.Cut(key, ".")
+	if !nested {
+		delete(data, key)
+		return
+	}
+	if m, ok := data[parent].(map[string]any); ok {
+		deleteValue(m, name)
+	}
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPromptsGate(t *testing.T) {
+	prompts := Prompts{
+		{Name: "use_db", Type: TypeConfirm},
+		{Name: "database", When: "{{.use_db}}"},
+		{Name: "postgres_version", When: `{{eq .database "postgres"}}`},
+		{Name: "pg_extensions", When: "{{.postgres_version}}"},
+	}
+	defaults := map[string]any{"database": "sqlite"}
+
+	tests := []struct {
+		name     string
+		data     map[string]any
+		explicit map[string]any
+		skipped  []string
+		want     map[string]any
+		warning  string
+	}{
+		{
+			name:    "whole chain on",
+			data:    map[string]any{"use_db": true, "database": "postgres", "postgres_version": 16},
+			skipped: nil,
+			want:    map[string]any{"use_db": true, "database": "postgres", "postgres_version": 16},
+		},
+		{
+			name:    "middle of the chain off",
+			data:    map[string]any{"use_db": true, "database": "sqlite", "pg_extensions": "postgis"},
+			skipped: []string{"postgres_version", "pg_extensions"},
+			want:    map[string]any{"use_db": true, "database": "sqlite"},
+		},
+		{
+			name:     "first gate off takes the default and turns the rest off",
+			data:     map[string]any{"use_db": "false", "database": "postgres", "postgres_version": 16},
+			explicit: map[string]any{"postgres_version": 16},
+			skipped:  []string{"database", "postgres_version", "pg_extensions"},
+			want:     map[string]any{"use_db": "false", "database": "sqlite"},
+			warning: "⚠️  Ignoring 'postgres_version' from the data, its prompt is skipped by when: " +
+				"{{eq .database \"postgres\"}}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warn bytes.Buffer
+			skipped, err := prompts.Gate(tt.data, defaults, tt.explicit, helperFunc, &warn)
+			if err != nil {
+				t.Fatalf("Gate failed: %v", err)
+			}
+			if strings.Join(skipped, ",") != strings.Join(tt.skipped, ",") {
+				t.Errorf("Expected %v skipped, got %v", tt.skipped, skipped)
+			}
+			if len(tt.data) != len(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, tt.data)
+			}
+			for key, value := range tt.want {
+				if tt.data[key] != value {
+					t.Errorf("Expected '%s' to be %v, got %v", key, value, tt.data[key])
+				}
+			}
+			if warn.String() != tt.warning {
+				t.Errorf("Expected %q, got %q", tt.warning, warn.String())
+			}
+		})
+	}
+
+	t.Run("nested key", func(t *testing.T) {
+		data := map[string]any{"db": map[string]any{"kind": "none", "host": "localhost"}}
+		gated := Prompts{{Name: "db.host", When: `{{ne .db.kind "none"}}`}}
+		if _, err := gated.Gate(data, map[string]any{}, data, helperFunc, io.Discard); err != nil {
+			t.Fatalf("Gate failed: %v", err)
+		}
+		if _, ok := LookupValue(data, "db.host"); ok {
+			t.Errorf("Expected db.host to be removed, got %v", data)
+		}
+	})
+}
+
+func TestApplyWhen(t *testing.T) {
+	templateDir := writeTemplate(t, map[string]string{
+		MetadataFile: "minMoldVersion: 0.9.0\nprompts:\n  database: {type: select, choices: [postgres, sqlite]}\n" +
+			"  postgres_version: {type: int, min: 12, when: '{{eq .database \"postgres\"}}'}\n" +
+			"  pool_size: {type: int, when: '{{.postgres_version}}'}\n",
+		"db.txt.tmpl": "{{.database}}{{with .postgres_version}} {{.}}{{end}}{{with .pool_size}} x{{.}}{{end}}",
+	})
+
+	// The values of prompts turned off are dropped instead of validated.
+	outputDir := t.TempDir()
+	data := map[string]any{"database": "sqlite", "postgres_version": "junk"}
+	err := Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: io.Discard})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if files := readFiles(t, outputDir); files["db.txt"] != "sqlite" {
+		t.Errorf("Expected the gated keys left out, got %q", files["db.txt"])
+	}
+
+	outputDir = t.TempDir()
+	data = map[string]any{"database": "postgres", "postgres_version": "16", "pool_size": 5}
+	if err = Apply(Options{TemplatePath: templateDir, OutputDir: outputDir, Data: data, Out: io.Discard}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if files := readFiles(t, outputDir); files["db.txt"] != "postgres 16 x5" {
+		t.Errorf("Expected the gated keys rendered, got %q", files["db.txt"])
+	}
+
+	data = map[string]any{"database": "postgres", "postgres_version": 9}
+	err = Apply(Options{TemplatePath: templateDir, OutputDir: t.TempDir(), Data: data, Out: io.Discard})
+	if err == nil || !strings.Contains(err.Error(), "invalid value for 'postgres_version'") {
+		t.Errorf("Expected the prompt turned on to be validated, got %v", err)
+	}
+
+	findings, err := Lint(templateDir)
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("Expected the keys of when to count as used, got %v", findings)
+	}
+}
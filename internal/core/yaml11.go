@@ -0,0 +1,75 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yaml11Bools maps the YAML 1.1 boolean words, in lower case, to their value.
+//
+//nolint:gochecknoglobals // lookup table of the YAML 1.1 boolean words
+var yaml11Bools = map[string]bool{
+	"yes": true, "y": true, "on": true,
+	"no": false, "n": false, "off": false,
+}
+
+// YAML11Bool is an unquoted YAML 1.1 boolean word found in a data file.
+type YAML11Bool struct {
+	// Path is the full key path of the value, such as "features[0].enabled".
+	Path  string
+	Value string
+	Line  int
+}
+
+// findYAML11Bools returns the unquoted scalars under node that YAML 1.1 reads
+// as booleans, such as yes or off, while YAML 1.2 reads them as strings.
+// When convert is set they are turned into booleans in place.
+func findYAML11Bools(node *yaml.Node, path string, convert bool) []YAML11Bool {
+	var found []YAML11Bool
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			found = append(found, findYAML11Bools(child, path, convert)...)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			found = append(found, findYAML11Bools(child, path+"["+strconv.Itoa(i)+"]", convert)...)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childPath := joinKeyPath(path, node.Content[i].Value)
+			found = append(found, findYAML11Bools(node.Content[i+1], childPath, convert)...)
+		}
+	case yaml.ScalarNode:
+		value, ok := yaml11Bools[strings.ToLower(node.Value)]
+		if !ok || node.Style != 0 || node.Tag != "!!str" {
+			return nil
+		}
+		found = append(found, YAML11Bool{Path: path, Value: node.Value, Line: node.Line})
+		if convert {
+			node.Tag = "!!bool"
+			node.Value = strconv.FormatBool(value)
+		}
+	}
+	return found
+}
+
+// checkYAML11Bools converts the YAML 1.1 booleans of the documents when opts
+// asks for it. In strict mode they are reported as warnings instead, so
+// authors can fix their files.
+func (o DataOptions) checkYAML11Bools(source string, docs []*yaml.Node) {
+	if !o.YAML11Bools {
+		return
+	}
+	for _, doc := range docs {
+		for _, b := range findYAML11Bools(doc, "", !o.Strict) {
+			if o.Strict && o.Warn != nil {
+				fmt.Fprintf(o.Warn, "⚠️  Data file '%s' has the YAML 1.1 boolean '%s' for '%s' at line %d, "+
+					"use true or false, or quote it to keep a string\n", source, b.Value, b.Path, b.Line)
+			}
+		}
+	}
+}
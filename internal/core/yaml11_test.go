@@ -0,0 +1,101 @@
+package core
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestYAML11Bools(t *testing.T) {
+	content := strings.Join([]string{
+		"enabled: yes",           // 1
+		"debug: Off",             // 2
+		"quoted: \"yes\"",        // 3
+		"single: 'no'",           // 4
+		"tagged: !!str on",       // 5
+		"name: yesterday",        // 6
+		"flags: [Y, n, true]",    // 7
+		"nested:",                // 8
+		"  feature: ON",          // 9
+		"on: key stays a string", // 10
+		"block: |",               // 11
+		"  no",                   // 12
+	}, "\n")
+
+	tests := []struct {
+		name     string
+		opts     DataOptions
+		want     map[string]any
+		warnings []string
+	}{
+		{
+			name: "disabled",
+			want: map[string]any{
+				"enabled": "yes", "debug": "Off", "quoted": "yes", "single": "no", "tagged": "on",
+				"name": "yesterday", "flags": []any{"Y", "n", true}, "nested": map[string]any{"feature": "ON"},
+				"on": "key stays a string", "block": "no",
+			},
+		},
+		{
+			name: "normalized",
+			opts: DataOptions{YAML11Bools: true},
+			want: map[string]any{
+				"enabled": true, "debug": false, "quoted": "yes", "single": "no", "tagged": "on",
+				"name": "yesterday", "flags": []any{true, false, true}, "nested": map[string]any{"feature": true},
+				"on": "key stays a string", "block": "no",
+			},
+		},
+		{
+			name: "strict warns",
+			opts: DataOptions{YAML11Bools: true, Strict: true},
+			want: map[string]any{
+				"enabled": "yes", "debug": "Off", "quoted": "yes", "single": "no", "tagged": "on",
+				"name": "yesterday", "flags": []any{"Y", "n", true}, "nested": map[string]any{"feature": "ON"},
+				"on": "key stays a string", "block": "no",
+			},
+			warnings: []string{
+				"YAML 1.1 boolean 'yes' for 'enabled' at line 1",
+				"YAML 1.1 boolean 'Off' for 'debug' at line 2",
+				"YAML 1.1 boolean 'Y' for 'flags[0]' at line 7",
+				"YAML 1.1 boolean 'n' for 'flags[1]' at line 7",
+				"YAML 1.1 boolean 'ON' for 'nested.feature' at line 9",
+			},
+		},
+	}
+	for _, tt := range tests {
+		var warn bytes.Buffer
+		tt.opts.Warn = &warn
+		data, err := ParseData([]byte(content), "yaml", "data.yaml", tt.opts)
+		if err != nil {
+			t.Errorf("%s: ParseData failed: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(data, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, data)
+		}
+		lines := strings.Split(strings.TrimSpace(warn.String()), "\n")
+		if len(tt.warnings) == 0 && warn.Len() > 0 {
+			t.Errorf("%s: expected no warnings, got %q", tt.name, warn.String())
+		} else if len(tt.warnings) > 0 && len(lines) != len(tt.warnings) {
+			t.Errorf("%s: expected %d warnings, got %q", tt.name, len(tt.warnings), warn.String())
+		}
+		for _, want := range tt.warnings {
+			if !contains(warn.String(), want) {
+				t.Errorf("%s: expected a warning containing %q, got %q", tt.name, want, warn.String())
+			}
+		}
+	}
+}
+
+func TestYAML11BoolsMultiDocument(t *testing.T) {
+	content := "a: yes\n---\nb: off\n"
+	data, err := ParseData([]byte(content), "yaml", "data.yaml", DataOptions{YAML11Bools: true})
+	if err != nil {
+		t.Fatalf("ParseData failed: %v", err)
+	}
+	want := map[string]any{"a": true, "b": false}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("Expected %v, got %v", want, data)
+	}
+}
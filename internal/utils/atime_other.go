@@ -0,0 +1,14 @@
+//go:build !linux
+
+package utils
+
+import (
+	"io/fs"
+	"time"
+)
+
+// accessTime returns the modification time of a file, since the access time
+// isn't read on this platform.
+func accessTime(info fs.FileInfo) time.Time {
+	return info.ModTime()
+}
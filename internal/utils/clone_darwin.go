@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// cloneFile makes dst a copy-on-write clone of src with clonefile, which
+// APFS supports. It reports false when the filesystem can't clone src, or
+// when dst already exists, since clonefile only creates new files.
+func cloneFile(src *os.File, dst string) (bool, error) {
+	err := unix.Clonefile(src.Name(), dst, 0)
+	if err != nil {
+		if cloneUnsupported(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to clone '%s' to '%s': %w", src.Name(), dst, err)
+	}
+	// The clone has the times of src, which a copy only keeps when asked.
+	now := time.Now()
+	if err = os.Chtimes(dst, now, now); err != nil {
+		return false, fmt.Errorf("failed to set the times of '%s': %w", dst, err)
+	}
+	return true, nil
+}
+
+// cloneUnsupported reports whether a clonefile error means the file can't be
+// cloned, because it is on another filesystem, on one without clones or dst
+// exists, rather than that something went wrong.
+func cloneUnsupported(err error) bool {
+	return errors.Is(err, unix.EXDEV) || errors.Is(err, unix.ENOTSUP) ||
+		errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.EEXIST)
+}
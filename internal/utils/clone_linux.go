@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// cloneFile makes dst a copy-on-write clone of src with the FICLONE ioctl,
+// which Btrfs, XFS and other filesystems with reflinks support. It reports
+// false when the filesystem can't clone src into dst, leaving an empty dst
+// for the caller to copy into.
+func cloneFile(src *os.File, dst string) (bool, error) {
+	destFile, err := os.Create(dst)
+	if err != nil {
+		return false, fmt.Errorf("failed to create destination file '%s': %w", dst, err)
+	}
+	defer destFile.Close()
+
+	//nolint:gosec // file descriptors fit in an int
+	err = unix.IoctlFileClone(int(destFile.Fd()), int(src.Fd()))
+	if err != nil {
+		if cloneUnsupported(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to clone '%s' to '%s': %w", src.Name(), dst, err)
+	}
+	if err = destFile.Close(); err != nil {
+		return false, fmt.Errorf("failed to close '%s': %w", dst, err)
+	}
+	return true, nil
+}
+
+// cloneUnsupported reports whether a FICLONE error means the files can't be
+// cloned, because they are on different filesystems or on one without
+// reflinks, rather than that something went wrong.
+func cloneUnsupported(err error) bool {
+	return errors.Is(err, unix.EXDEV) || errors.Is(err, unix.EOPNOTSUPP) ||
+		errors.Is(err, unix.EINVAL) || errors.Is(err, unix.ENOTTY)
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestCloneFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.bin")
+	if err := os.WriteFile(src, []byte("model weights"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	sourceFile, err := os.Open(src)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	defer sourceFile.Close()
+
+	dst := filepath.Join(dir, "dst.bin")
+	cloned, err := cloneFile(sourceFile, dst)
+	if err != nil {
+		t.Fatalf("cloneFile failed: %v", err)
+	}
+	content, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("Failed to read clone: %v", err)
+	}
+	// ext4 and tmpfs can't clone, leaving an empty file to copy into.
+	if (cloned && string(content) != "model weights") || (!cloned && len(content) != 0) {
+		t.Errorf("Expected a clone or an empty file, got cloned=%v with %q", cloned, content)
+	}
+	t.Logf("cloned: %v", cloned)
+
+	errorTests := []struct {
+		err  error
+		want bool
+	}{
+		{unix.EXDEV, true},
+		{unix.EOPNOTSUPP, true},
+		{unix.EINVAL, true},
+		{unix.ENOTTY, true},
+		{fmt.Errorf("wrapped: %w", unix.EXDEV), true},
+		{unix.EACCES, false},
+		{unix.ENOSPC, false},
+	}
+	for _, tt := range errorTests {
+		if got := cloneUnsupported(tt.err); got != tt.want {
+			t.Errorf("cloneUnsupported(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
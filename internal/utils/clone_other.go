@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package utils
+
+import "os"
+
+// cloneFile reports that files can't be cloned on this platform.
+func cloneFile(*os.File, string) (bool, error) {
+	return false, nil
+}
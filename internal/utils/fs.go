@@ -1,35 +1,418 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"syscall"
 )
 
+// DirMode is the mode of the directories mold creates.
+const DirMode fs.FileMode = 0750
+
+// CopyFileOption configures CopyFile.
+type CopyFileOption func(*copyFileConfig)
+
+// copyFileConfig holds the options of a CopyFile call.
+type copyFileConfig struct {
+	parents    bool
+	parentMode fs.FileMode
+	preserve   PreserveOptions
+}
+
+// PreserveOptions says what CopyFile and CopyDir keep of the source besides
+// its permissions, which are always kept.
+type PreserveOptions struct {
+	// Times keeps the modification and access times.
+	Times bool
+	// Owner keeps the user and group on Unix. Changing them usually takes
+	// privileges, so a failure is passed to Warn instead of failing the copy.
+	Owner bool
+	// Warn receives the path and the error of each ownership that couldn't
+	// be kept. When nil, these failures are ignored.
+	Warn func(path string, err error)
+}
+
+// errOwnerUnsupported is passed to PreserveOptions.Warn on platforms without
+// file ownership.
+var errOwnerUnsupported = errors.New("file ownership is not supported on this platform")
+
+// WithParentDirs makes CopyFile create the missing parent directories of the
+// destination with the given mode, such as DirMode.
+func WithParentDirs(mode fs.FileMode) CopyFileOption {
+	return func(c *copyFileConfig) {
+		c.parents = true
+		c.parentMode = mode
+	}
+}
+
+// WithPreserve makes CopyFile keep the times and owner of the source as
+// preserve says.
+func WithPreserve(preserve PreserveOptions) CopyFileOption {
+	return func(c *copyFileConfig) { c.preserve = preserve }
+}
+
 // CopyFile copies a single file from a source path to a destination path.
-// It creates the destination file and copies the content.
-func CopyFile(src, dst string) error {
+// It creates the destination file and copies the content. The parent
+// directory of the destination must exist unless WithParentDirs is given.
+func CopyFile(src, dst string, opts ...CopyFileOption) error {
+	var config copyFileConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
 	sourceFile, err := os.Open(src)
 	if err != nil {
 		return fmt.Errorf("failed to open source file '%s': %w", src, err)
 	}
 	defer sourceFile.Close()
+	// The times are read before the content, which can update the access
+	// time.
+	sourceInfo, err := sourceFile.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat source file '%s': %w", src, err)
+	}
+
+	if config.parents {
+		if err = os.MkdirAll(filepath.Dir(dst), config.parentMode); err != nil {
+			return fmt.Errorf("failed to create directory for '%s': %w", dst, err)
+		}
+	}
+
+	// A clone shares the blocks of the source until either is changed, so
+	// large files copy instantly without taking more space.
+	cloned, err := cloneFile(sourceFile, dst)
+	if err != nil {
+		return err
+	}
+	if !cloned {
+		if err = copyContent(sourceFile, dst); err != nil {
+			return err
+		}
+	}
+
+	// Preserve file permissions. Changing the owner can clear the setuid and
+	// setgid bits, so it comes first.
+	config.preserve.owner(dst, sourceInfo, os.Chown)
+	if err = os.Chmod(dst, sourceInfo.Mode()); err != nil {
+		return err
+	}
+	return config.preserve.times(dst, sourceInfo)
+}
 
+// copyContent writes the content of src to the file dst, creating or
+// truncating it.
+func copyContent(src *os.File, dst string) error {
 	destFile, err := os.Create(dst)
 	if err != nil {
 		return fmt.Errorf("failed to create destination file '%s': %w", dst, err)
 	}
 	defer destFile.Close()
 
-	_, err = io.Copy(destFile, sourceFile)
+	if _, err = io.Copy(destFile, src); err != nil {
+		return fmt.Errorf("failed to copy content from '%s' to '%s': %w", src.Name(), dst, err)
+	}
+	return destFile.Close()
+}
+
+// owner gives path the user and group of info when Owner is set, passing a
+// failure to Warn.
+func (p PreserveOptions) owner(path string, info fs.FileInfo, chown func(string, int, int) error) {
+	if !p.Owner {
+		return
+	}
+	uid, gid, ok := fileOwner(info)
+	err := errOwnerUnsupported
+	if ok {
+		err = chown(path, uid, gid)
+	}
+	if err != nil && p.Warn != nil {
+		p.Warn(path, err)
+	}
+}
+
+// times gives path the access and modification times of info when Times is
+// set.
+func (p PreserveOptions) times(path string, info fs.FileInfo) error {
+	if !p.Times {
+		return nil
+	}
+	if err := os.Chtimes(path, accessTime(info), info.ModTime()); err != nil {
+		return fmt.Errorf("failed to set the times of '%s': %w", path, err)
+	}
+	return nil
+}
+
+// CopyFileAtomic copies a single file like CopyFile, but through a temporary
+// file in the destination directory that is only renamed to dst once its
+// content and permissions are on disk. A failed or interrupted copy never
+// leaves a partial dst behind.
+func CopyFileAtomic(src, dst string) error {
+	sourceFile, err := os.Open(src)
 	if err != nil {
+		return fmt.Errorf("failed to open source file '%s': %w", src, err)
+	}
+	defer sourceFile.Close()
+	sourceInfo, err := sourceFile.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat source file '%s': %w", src, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create destination file '%s': %w", dst, err)
+	}
+	// Removing the temporary file fails once it is renamed, which is fine.
+	defer os.Remove(tmp.Name())
+
+	if _, err = io.Copy(tmp, sourceFile); err != nil {
+		_ = tmp.Close()
 		return fmt.Errorf("failed to copy content from '%s' to '%s': %w", src, dst, err)
 	}
+	if err = tmp.Chmod(sourceInfo.Mode().Perm()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to set the permissions of '%s': %w", dst, err)
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to sync '%s': %w", dst, err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close '%s': %w", dst, err)
+	}
+	if err = os.Rename(tmp.Name(), dst); err != nil {
+		return fmt.Errorf("failed to move '%s' into place: %w", dst, err)
+	}
+	return nil
+}
 
-	// Preserve file permissions
-	sourceInfo, err := os.Stat(src)
+// CopyFileTo copies the content of a single file to the writer. It is the
+// io.Writer-based variant of CopyFile for destinations that are not files on
+// disk, such as archive entries.
+func CopyFileTo(w io.Writer, src string) error {
+	sourceFile, err := os.Open(src)
 	if err != nil {
-		return fmt.Errorf("failed to stat source file '%s': %w", src, err)
+		return fmt.Errorf("failed to open source file '%s': %w", src, err)
+	}
+	defer sourceFile.Close()
+
+	if _, err = io.Copy(w, sourceFile); err != nil {
+		return fmt.Errorf("failed to copy content from '%s': %w", src, err)
+	}
+	return nil
+}
+
+// CountFiles returns the number of files under dir, not counting
+// directories.
+func CountFiles(dir string) (int, error) {
+	count := 0
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			count++
+		}
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count the files of '%s': %w", dir, err)
+	}
+	return count, nil
+}
+
+// SymlinkPolicy says what CopyDir does with the symlinks it finds.
+type SymlinkPolicy int
+
+const (
+	// SymlinkCopy copies a symlink as a symlink with the same target.
+	SymlinkCopy SymlinkPolicy = iota
+	// SymlinkFollow copies the content of the file a symlink points to. A
+	// symlink to a directory is an error.
+	SymlinkFollow
+	// SymlinkSkip leaves symlinks out of the copy.
+	SymlinkSkip
+)
+
+// CopyStats summarizes what CopyDir copied, not counting the root directory.
+type CopyStats struct {
+	Dirs     int
+	Files    int
+	Symlinks int
+	// Bytes is the size of the copied files.
+	Bytes int64
+}
+
+// CopyDirOption configures CopyDir.
+type CopyDirOption func(*copyDirConfig)
+
+// copyDirConfig holds the options of a CopyDir call.
+type copyDirConfig struct {
+	symlinks  SymlinkPolicy
+	overwrite bool
+	visit     func(relPath string, info fs.FileInfo) error
+	preserve  PreserveOptions
+}
+
+// WithSymlinks sets what CopyDir does with symlinks. The default is
+// SymlinkCopy.
+func WithSymlinks(policy SymlinkPolicy) CopyDirOption {
+	return func(c *copyDirConfig) { c.symlinks = policy }
+}
+
+// WithOverwrite lets CopyDir copy into an existing destination. Files and
+// symlinks in the way are replaced, other files are left alone.
+func WithOverwrite() CopyDirOption {
+	return func(c *copyDirConfig) { c.overwrite = true }
+}
+
+// ErrSkipEntry is returned by the visit function of WithVisit to leave a
+// file or symlink out of the copy.
+var ErrSkipEntry = errors.New("skip this entry")
+
+// WithVisit calls visit with the path relative to src and the information
+// of each directory, file and symlink below src before it is copied. An
+// error stops the copy, except ErrSkipEntry for a file or symlink, which is
+// left out.
+func WithVisit(visit func(relPath string, info fs.FileInfo) error) CopyDirOption {
+	return func(c *copyDirConfig) { c.visit = visit }
+}
+
+// WithPreserveDir makes CopyDir keep the times and owner of the directories,
+// files and symlinks it copies as preserve says. The times of symlinks are
+// not kept.
+func WithPreserveDir(preserve PreserveOptions) CopyDirOption {
+	return func(c *copyDirConfig) { c.preserve = preserve }
+}
+
+// CopyDir recursively copies the directory src to dst, which must not exist
+// unless WithOverwrite is given. File and directory permissions are
+// preserved, directories getting theirs once their content is copied, and
+// symlinks are copied as symlinks unless WithSymlinks says otherwise.
+func CopyDir(src, dst string, opts ...CopyDirOption) (CopyStats, error) {
+	var config copyDirConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if _, err := os.Lstat(dst); err == nil && !config.overwrite {
+		return CopyStats{}, fmt.Errorf("destination '%s' already exists", dst)
+	}
+	var stats CopyStats
+	// Directories stay writable until their content is copied, and get their
+	// times once it no longer changes them.
+	dirs := make(map[string]fs.FileInfo)
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path for '%s': %w", path, err)
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("failed to stat '%s': %w", path, err)
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			switch config.symlinks {
+			case SymlinkSkip:
+				return nil
+			case SymlinkFollow:
+				if info, err = os.Stat(path); err != nil {
+					return fmt.Errorf("failed to follow symlink '%s': %w", path, err)
+				}
+				if info.IsDir() {
+					return fmt.Errorf("symlink '%s' points to a directory, which can't be followed", path)
+				}
+			case SymlinkCopy:
+			}
+		}
+		if config.visit != nil && rel != "." {
+			if err = config.visit(rel, info); errors.Is(err, ErrSkipEntry) && !info.IsDir() {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+		if config.overwrite && !info.IsDir() {
+			if err = removeExisting(target); err != nil {
+				return err
+			}
+		}
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("failed to read symlink '%s': %w", path, err)
+			}
+			stats.Symlinks++
+			if err = os.Symlink(link, target); err != nil {
+				return err
+			}
+			config.preserve.owner(target, info, os.Lchown)
+			return nil
+		case info.IsDir():
+			dirs[target] = info
+			if rel != "." {
+				stats.Dirs++
+			}
+			if err = os.MkdirAll(target, DirMode); err != nil {
+				return fmt.Errorf("failed to create directory '%s': %w", target, err)
+			}
+			config.preserve.owner(target, info, os.Chown)
+			return nil
+		default:
+			stats.Files++
+			stats.Bytes += info.Size()
+			return CopyFile(path, target, WithPreserve(config.preserve))
+		}
+	})
+	if err != nil {
+		return stats, err
+	}
+	for dir, info := range dirs {
+		if err = os.Chmod(dir, info.Mode().Perm()); err != nil {
+			return stats, fmt.Errorf("failed to set the permissions of '%s': %w", dir, err)
+		}
+		if err = config.preserve.times(dir, info); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// HardLink makes dst a hard link to src, so both share their content. When
+// they are on different devices, where hard links are impossible, src is
+// copied to dst instead. It reports whether dst was linked.
+func HardLink(src, dst string) (bool, error) {
+	err := os.Link(src, dst)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return false, fmt.Errorf("failed to link '%s' to '%s': %w", dst, src, err)
+	}
+	return false, CopyFile(src, dst)
+}
+
+// IsLinked reports whether writing to the file would change another one: it
+// is a symlink, or one of several hard links to the same content.
+func IsLinked(info fs.FileInfo) bool {
+	return info.Mode()&fs.ModeSymlink != 0 || (info.Mode().IsRegular() && linkCount(info) > 1)
+}
+
+// removeExisting removes the file or symlink at path, if any, so it can be
+// replaced without writing through a symlink or into a read-only file.
+func removeExisting(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return nil
+	}
+	if err == nil {
+		err = os.Remove(path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to replace '%s': %w", path, err)
 	}
-	return os.Chmod(dst, sourceInfo.Mode())
+	return nil
 }
@@ -1,11 +1,16 @@
 package utils
 
 import (
+	"bytes"
 	"errors"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestCopyFile(t *testing.T) {
@@ -110,3 +115,477 @@ func TestCopyFile(t *testing.T) {
 		}
 	})
 }
+
+func TestCopyFileParentDirs(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src.txt")
+	if err := os.WriteFile(src, []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	dst := filepath.Join(t.TempDir(), "a", "b", "c", "dst.txt")
+
+	if err := CopyFile(src, dst); err == nil {
+		t.Fatal("Expected a missing parent directory to fail without WithParentDirs")
+	}
+	if err := CopyFile(src, dst, WithParentDirs(DirMode)); err != nil {
+		t.Fatalf("CopyFile failed: %v", err)
+	}
+	if content, err := os.ReadFile(dst); err != nil || string(content) != "content" {
+		t.Errorf("Expected the file copied, got %q (%v)", content, err)
+	}
+	info, err := os.Stat(filepath.Dir(dst))
+	if err != nil || info.Mode().Perm() != DirMode {
+		t.Errorf("Expected the parents created with mode %v, got %v (%v)", DirMode, info, err)
+	}
+}
+
+func TestCopyFilePreserve(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src.txt")
+	if err := os.WriteFile(src, []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	atime := time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC)
+	mtime := time.Date(2021, 6, 7, 8, 9, 10, 11000, time.UTC)
+	if err := os.Chtimes(src, atime, mtime); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+
+	t.Run("times", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "dst.txt")
+		if err := CopyFile(src, dst, WithPreserve(PreserveOptions{Times: true})); err != nil {
+			t.Fatalf("CopyFile failed: %v", err)
+		}
+		info, err := os.Stat(dst)
+		if err != nil {
+			t.Fatalf("Failed to stat copy: %v", err)
+		}
+		if !info.ModTime().Equal(mtime) {
+			t.Errorf("Expected modification time %v, got %v", mtime, info.ModTime())
+		}
+		if runtime.GOOS == "linux" && !accessTime(info).Equal(atime) {
+			t.Errorf("Expected access time %v, got %v", atime, accessTime(info))
+		}
+
+		dst = filepath.Join(t.TempDir(), "dst.txt")
+		if err = CopyFile(src, dst); err != nil {
+			t.Fatalf("CopyFile failed: %v", err)
+		}
+		if info, err = os.Stat(dst); err != nil || info.ModTime().Equal(mtime) {
+			t.Errorf("Expected the times not to be kept by default, got %v (%v)", info, err)
+		}
+	})
+
+	t.Run("owner", func(t *testing.T) {
+		var warnings []string
+		preserve := PreserveOptions{Owner: true, Warn: func(path string, err error) {
+			warnings = append(warnings, filepath.Base(path)+": "+err.Error())
+		}}
+		// Giving a file its own owner needs no privileges.
+		dst := filepath.Join(t.TempDir(), "dst.txt")
+		if err := CopyFile(src, dst, WithPreserve(preserve)); err != nil {
+			t.Fatalf("CopyFile failed: %v", err)
+		}
+		if runtime.GOOS != "windows" && len(warnings) != 0 {
+			t.Errorf("Expected no warnings, got %v", warnings)
+		}
+
+		warnings = nil
+		info, err := os.Stat(src)
+		if err != nil {
+			t.Fatalf("Failed to stat file: %v", err)
+		}
+		preserve.owner(dst, info, func(string, int, int) error { return fs.ErrPermission })
+		if runtime.GOOS != "windows" && !slices.Equal(warnings, []string{"dst.txt: permission denied"}) {
+			t.Errorf("Expected a failed chown to be a warning, got %v", warnings)
+		}
+		(PreserveOptions{Owner: true}).owner(dst, info, func(string, int, int) error { return fs.ErrPermission })
+	})
+}
+
+func TestCopyFileLarge(t *testing.T) {
+	// Large enough to take the clone path where the filesystem has one, and
+	// several io.Copy buffers where it falls back.
+	content := bytes.Repeat([]byte("0123456789abcdef"), 1<<16)
+	src := filepath.Join(t.TempDir(), "model.bin")
+	if err := os.WriteFile(src, content, 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	mtime := time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)
+	if err := os.Chtimes(src, mtime, mtime); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "model.bin")
+	if err := os.WriteFile(dst, []byte("a longer existing file to truncate"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	for _, preserve := range []bool{false, true} {
+		if err := CopyFile(src, dst, WithPreserve(PreserveOptions{Times: preserve})); err != nil {
+			t.Fatalf("CopyFile failed: %v", err)
+		}
+		got, err := os.ReadFile(dst)
+		if err != nil || !bytes.Equal(got, content) {
+			t.Fatalf("Expected the content copied, got %d bytes (%v)", len(got), err)
+		}
+		info, err := os.Stat(dst)
+		if err != nil {
+			t.Fatalf("Failed to stat copy: %v", err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+		}
+		if info.ModTime().Equal(mtime) != preserve {
+			t.Errorf("Expected the times kept only when asked (%v), got %v", preserve, info.ModTime())
+		}
+	}
+}
+
+func TestCopyFileAtomic(t *testing.T) {
+	tempDir := t.TempDir()
+	srcPath := filepath.Join(tempDir, "source.sh")
+	if err := os.WriteFile(srcPath, []byte("#!/bin/sh"), 0600); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+	if err := os.Chmod(srcPath, 0750); err != nil {
+		t.Fatalf("Failed to chmod source file: %v", err)
+	}
+
+	t.Run("successful copy", func(t *testing.T) {
+		dstPath := filepath.Join(tempDir, "dest.sh")
+		if err := os.WriteFile(dstPath, []byte("old content"), 0644); err != nil {
+			t.Fatalf("Failed to create destination file: %v", err)
+		}
+		if err := CopyFileAtomic(srcPath, dstPath); err != nil {
+			t.Fatalf("CopyFileAtomic failed: %v", err)
+		}
+		content, err := os.ReadFile(dstPath)
+		if err != nil || string(content) != "#!/bin/sh" {
+			t.Errorf("Content mismatch: got %q, %v", content, err)
+		}
+		if info, err := os.Stat(dstPath); err != nil || info.Mode().Perm() != 0750 {
+			t.Errorf("Permission mismatch: got %v, %v", info.Mode(), err)
+		}
+	})
+
+	t.Run("failed copy leaves nothing behind", func(t *testing.T) {
+		dir := filepath.Join(tempDir, "failed")
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		err := CopyFileAtomic(filepath.Join(tempDir, "nonexistent"), filepath.Join(dir, "dest"))
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Expected file not found error, got: %v", err)
+		}
+		// A directory in the way makes the final rename fail.
+		if err = os.Mkdir(filepath.Join(dir, "taken"), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err = os.WriteFile(filepath.Join(dir, "taken", "x"), nil, 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if err = CopyFileAtomic(srcPath, filepath.Join(dir, "taken")); err == nil {
+			t.Error("Expected an error when the destination is a directory")
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+			t.Errorf("Expected no temporary file to be left, got %v", entries)
+		}
+	})
+}
+
+func TestCopyFileTo(t *testing.T) {
+	tempDir := t.TempDir()
+
+	t.Run("successful copy", func(t *testing.T) {
+		srcPath := filepath.Join(tempDir, "source.txt")
+		err := os.WriteFile(srcPath, []byte("Hello, World!"), 0644)
+		if err != nil {
+			t.Fatalf("Failed to create source file: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if err = CopyFileTo(&buf, srcPath); err != nil {
+			t.Fatalf("CopyFileTo failed: %v", err)
+		}
+		if buf.String() != "Hello, World!" {
+			t.Errorf("Content mismatch: got %q", buf.String())
+		}
+	})
+
+	t.Run("source file does not exist", func(t *testing.T) {
+		err := CopyFileTo(&bytes.Buffer{}, filepath.Join(tempDir, "nonexistent.txt"))
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Expected file not found error, got: %v", err)
+		}
+	})
+}
+
+func TestHardLink(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.bin")
+	if err := os.WriteFile(src, []byte("payload"), 0640); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	dst := filepath.Join(dir, "dst.bin")
+	linked, err := HardLink(src, dst)
+	if err != nil || !linked {
+		t.Fatalf("Expected a hard link, got %v (%v)", linked, err)
+	}
+	srcInfo, _ := os.Stat(src)
+	dstInfo, err := os.Lstat(dst)
+	if err != nil || !os.SameFile(srcInfo, dstInfo) || !IsLinked(dstInfo) {
+		t.Errorf("Expected dst to share the content of src, got %v (%v)", dstInfo, err)
+	}
+
+	if _, err = HardLink(src, dst); err == nil {
+		t.Errorf("Expected an existing destination to fail")
+	}
+
+	// A tmpfs is another device than the test directory on most systems.
+	other, err := os.MkdirTemp("/dev/shm", "mold-link-")
+	if err != nil {
+		t.Skipf("No tmpfs to link across devices: %v", err)
+	}
+	defer os.RemoveAll(other)
+	dst = filepath.Join(other, "dst.bin")
+	if linked, err = HardLink(src, dst); err != nil {
+		t.Fatalf("HardLink failed: %v", err)
+	}
+	if info, _ := os.Stat(dst); os.SameFile(srcInfo, info) || linked {
+		t.Errorf("Expected a copy across devices, got linked=%v", linked)
+	}
+	if content, err := os.ReadFile(dst); err != nil || string(content) != "payload" {
+		t.Errorf("Expected the content copied, got %q (%v)", content, err)
+	}
+}
+
+func TestCountFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"a.txt", "sub/b.txt", "sub/deeper/c.txt"} {
+		path := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(tempDir, "empty"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	count, err := CountFiles(tempDir)
+	if err != nil {
+		t.Fatalf("CountFiles failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 files, got %d", count)
+	}
+
+	if _, err = CountFiles(filepath.Join(tempDir, "missing")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected file not found error, got: %v", err)
+	}
+}
+
+func TestCopyDir(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "bin"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "bin", "run.sh"), []byte("#!/bin/sh"), 0755); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "README.md"), []byte("readme"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Symlink("README.md", filepath.Join(src, "link.md")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(src, "locked"), 0700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "locked", "file"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Chmod(filepath.Join(src, "locked"), 0555); err != nil {
+		t.Fatalf("Failed to chmod directory: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(filepath.Join(src, "locked"), 0755) })
+
+	dst := filepath.Join(t.TempDir(), "copy")
+	if _, err := CopyDir(src, dst); err != nil {
+		t.Fatalf("CopyDir failed: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(filepath.Join(dst, "locked"), 0755) })
+
+	modes := map[string]fs.FileMode{
+		"bin/run.sh":  0755,
+		"README.md":   0600,
+		"locked":      0555 | fs.ModeDir,
+		"locked/file": 0644,
+	}
+	for name, want := range modes {
+		info, err := os.Stat(filepath.Join(dst, name))
+		if err != nil {
+			t.Errorf("Expected '%s' to be copied: %v", name, err)
+			continue
+		}
+		if info.Mode() != want {
+			t.Errorf("Mode of '%s' = %v, want %v", name, info.Mode(), want)
+		}
+	}
+	if link, err := os.Readlink(filepath.Join(dst, "link.md")); err != nil || link != "README.md" {
+		t.Errorf("Expected the symlink to be copied, got %q (%v)", link, err)
+	}
+
+	if _, err := CopyDir(src, dst); err == nil || !bytes.Contains([]byte(err.Error()), []byte("already exists")) {
+		t.Errorf("Expected an existing destination to fail, got: %v", err)
+	}
+}
+
+func TestCopyDirOptions(t *testing.T) {
+	src := t.TempDir()
+	files := map[string]string{
+		"a/b/c/deep.txt": "deep",
+		"a/top.txt":      "top",
+		"root.bin":       "\x00\x01",
+	}
+	for name, content := range files {
+		path := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0640); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	if err := os.Chmod(filepath.Join(src, "a", "b"), 0700); err != nil {
+		t.Fatalf("Failed to chmod directory: %v", err)
+	}
+	if err := os.Symlink("top.txt", filepath.Join(src, "a", "link.txt")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	t.Run("stats and nested directories", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "copy")
+		var visited []string
+		stats, err := CopyDir(src, dst, WithVisit(func(relPath string, _ fs.FileInfo) error {
+			visited = append(visited, filepath.ToSlash(relPath))
+			return nil
+		}))
+		if err != nil {
+			t.Fatalf("CopyDir failed: %v", err)
+		}
+		want := CopyStats{Dirs: 3, Files: 3, Symlinks: 1, Bytes: 9}
+		if stats != want {
+			t.Errorf("Expected %+v, got %+v", want, stats)
+		}
+		wantVisited := []string{"a", "a/b", "a/b/c", "a/b/c/deep.txt", "a/link.txt", "a/top.txt", "root.bin"}
+		if !slices.Equal(visited, wantVisited) {
+			t.Errorf("Expected %v visited, got %v", wantVisited, visited)
+		}
+		info, err := os.Stat(filepath.Join(dst, "a", "b"))
+		if err != nil || info.Mode().Perm() != 0700 {
+			t.Errorf("Expected a/b to keep mode 0700, got %v (%v)", info, err)
+		}
+		info, err = os.Stat(filepath.Join(dst, "a", "b", "c", "deep.txt"))
+		if err != nil || info.Mode().Perm() != 0640 {
+			t.Errorf("Expected deep.txt to keep mode 0640, got %v (%v)", info, err)
+		}
+	})
+
+	t.Run("symlink policies", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "follow")
+		stats, err := CopyDir(src, dst, WithSymlinks(SymlinkFollow))
+		if err != nil {
+			t.Fatalf("CopyDir failed: %v", err)
+		}
+		info, err := os.Lstat(filepath.Join(dst, "a", "link.txt"))
+		if err != nil || !info.Mode().IsRegular() || stats.Files != 4 || stats.Symlinks != 0 {
+			t.Errorf("Expected the symlink copied as a file, got %v (%v), %+v", info, err, stats)
+		}
+
+		dst = filepath.Join(t.TempDir(), "skip")
+		if stats, err = CopyDir(src, dst, WithSymlinks(SymlinkSkip)); err != nil {
+			t.Fatalf("CopyDir failed: %v", err)
+		}
+		if _, err = os.Lstat(filepath.Join(dst, "a", "link.txt")); !os.IsNotExist(err) || stats.Files != 3 {
+			t.Errorf("Expected the symlink to be skipped, got %v, %+v", err, stats)
+		}
+
+		linked := t.TempDir()
+		if err = os.Symlink(filepath.Join(src, "a"), filepath.Join(linked, "dir")); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+		_, err = CopyDir(linked, filepath.Join(t.TempDir(), "dir"), WithSymlinks(SymlinkFollow))
+		if err == nil || !strings.Contains(err.Error(), "points to a directory") {
+			t.Errorf("Expected a followed directory symlink to fail, got %v", err)
+		}
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		dst := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(dst, "a"), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dst, "a", "top.txt"), []byte("old"), 0444); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		outside := filepath.Join(t.TempDir(), "outside.txt")
+		if err := os.WriteFile(outside, []byte("outside"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if err := os.Symlink(outside, filepath.Join(dst, "root.bin")); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dst, "extra.txt"), []byte("extra"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+
+		if _, err := CopyDir(src, dst, WithOverwrite()); err != nil {
+			t.Fatalf("CopyDir failed: %v", err)
+		}
+		for name, want := range map[string]string{"a/top.txt": "top", "root.bin": "\x00\x01", "extra.txt": "extra"} {
+			if content, err := os.ReadFile(filepath.Join(dst, name)); err != nil || string(content) != want {
+				t.Errorf("Expected '%s' to hold %q, got %q (%v)", name, want, content, err)
+			}
+		}
+		if content, err := os.ReadFile(outside); err != nil || string(content) != "outside" {
+			t.Errorf("Expected the symlink to be replaced, not written through, got %q (%v)", content, err)
+		}
+	})
+
+	t.Run("preserve", func(t *testing.T) {
+		mtime := time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)
+		for _, name := range []string{"a/b/c/deep.txt", "a/b/c", "a"} {
+			if err := os.Chtimes(filepath.Join(src, name), mtime, mtime); err != nil {
+				t.Fatalf("Failed to set times: %v", err)
+			}
+		}
+		dst := filepath.Join(t.TempDir(), "copy")
+		if _, err := CopyDir(src, dst, WithPreserveDir(PreserveOptions{Times: true, Owner: true})); err != nil {
+			t.Fatalf("CopyDir failed: %v", err)
+		}
+		for _, name := range []string{"a/b/c/deep.txt", "a/b/c", "a"} {
+			info, err := os.Stat(filepath.Join(dst, name))
+			if err != nil || !info.ModTime().Equal(mtime) {
+				t.Errorf("Expected '%s' to keep its modification time, got %v (%v)", name, info, err)
+			}
+		}
+	})
+
+	t.Run("visit error stops the copy", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "copy")
+		_, err := CopyDir(src, dst, WithVisit(func(relPath string, _ fs.FileInfo) error {
+			if filepath.Base(relPath) == "top.txt" {
+				return errors.New("stop")
+			}
+			return nil
+		}))
+		if err == nil || err.Error() != "stop" {
+			t.Fatalf("Expected the visit error, got %v", err)
+		}
+		if _, err = os.Stat(filepath.Join(dst, "a", "top.txt")); !os.IsNotExist(err) {
+			t.Errorf("Expected top.txt not to be copied, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package utils
+
+import "io/fs"
+
+// fileOwner reports that files have no owner IDs on this platform.
+func fileOwner(fs.FileInfo) (int, int, bool) {
+	return 0, 0, false
+}
+
+// linkCount reports a single link, as hard links aren't counted on this
+// platform.
+func linkCount(fs.FileInfo) uint64 {
+	return 1
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package utils
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileOwner returns the user and group IDs of a file.
+func fileOwner(info fs.FileInfo) (int, int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	//nolint:gosec // user and group IDs fit in an int
+	return int(stat.Uid), int(stat.Gid), true
+}
+
+// linkCount returns the number of hard links to a file.
+func linkCount(info fs.FileInfo) uint64 {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 1
+	}
+	return uint64(stat.Nlink)
+}
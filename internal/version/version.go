@@ -0,0 +1,22 @@
+// Package version reports the version of the mold binary.
+package version
+
+import "runtime/debug"
+
+// Version is the release version, set at build time with
+// -ldflags "-X github.com/0m3kk/mold/internal/version.Version=v1.2.3".
+//
+//nolint:gochecknoglobals // set by the linker
+var Version = ""
+
+// String returns the version of the running binary: Version when it was set
+// at build time, the module version when built with 'go install', or "dev".
+func String() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
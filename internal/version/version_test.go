@@ -0,0 +1,18 @@
+package version
+
+import "testing"
+
+func TestString(t *testing.T) {
+	original := Version
+	defer func() { Version = original }()
+
+	Version = "v1.2.3"
+	if got := String(); got != "v1.2.3" {
+		t.Errorf("Expected the linker version, got %q", got)
+	}
+
+	Version = ""
+	if got := String(); got == "" {
+		t.Error("Expected a fallback version")
+	}
+}
@@ -0,0 +1,84 @@
+// Package mold applies mold templates from Go programs embedding it, as the
+// mold apply command does. The types are those of the mold command itself,
+// so a program gets the same runs, results and reports.
+//
+// A program following a run as it happens sets Options.Observer:
+//
+//	type progress struct{ mold.NopObserver }
+//
+//	func (progress) FileFinish(path string, status mold.FileStatus, err error) {
+//		fmt.Println(status, path)
+//	}
+//
+//	err := mold.Apply(mold.Options{
+//		TemplatePath: "templates/go-service",
+//		OutputDir:    "billing",
+//		Data:         map[string]any{"name": "billing"},
+//		Observer:     progress{},
+//	})
+package mold
+
+import "github.com/0m3kk/mold/internal/core"
+
+type (
+	// Options configures a single Apply run.
+	Options = core.Options
+	// Observer receives the events of an Apply run as they happen.
+	Observer = core.Observer
+	// NopObserver ignores every event. An observer embedding it only
+	// implements the events it handles.
+	NopObserver = core.NopObserver
+	// Result records what an Apply run did. It is filled in even when the
+	// run fails, with the error.
+	Result = core.Result
+	// ResultFile is one file of an Apply run.
+	ResultFile = core.ResultFile
+	// FileStatus says what an Apply run did with a file of the templates.
+	FileStatus = core.FileStatus
+	// Sink receives the generated files instead of the output directory.
+	Sink = core.Sink
+	// LinkMode says how the copied files are put into the output directory.
+	LinkMode = core.LinkMode
+	// MergeMode says what Apply does with generated files the user changed
+	// since the previous run.
+	MergeMode = core.MergeMode
+	// NameRule says which file and directory names Apply generates.
+	NameRule = core.NameRule
+)
+
+// The statuses of the files of a run.
+const (
+	StatusRendered         = core.StatusRendered
+	StatusCopied           = core.StatusCopied
+	StatusSymlinked        = core.StatusSymlinked
+	StatusPruned           = core.StatusPruned
+	StatusFailed           = core.StatusFailed
+	StatusSkipped          = core.StatusSkipped
+	StatusSkippedIrregular = core.StatusSkippedIrregular
+)
+
+// The modes of the copied files, of the merges and of the names.
+const (
+	LinkCopy        = core.LinkCopy
+	LinkHard        = core.LinkHard
+	LinkSymlink     = core.LinkSymlink
+	MergeOff        = core.MergeOff
+	MergeCleanOnly  = core.MergeCleanOnly
+	MergeAlways     = core.MergeAlways
+	NameWindowsSafe = core.NameWindowsSafe
+	NamePOSIX       = core.NamePOSIX
+	NamePermissive  = core.NamePermissive
+)
+
+// Apply generates a project from the template directory, followed by its
+// layers, into the output directory or archive. Every template is loaded
+// and planned before anything is written.
+func Apply(opts Options) error {
+	return core.Apply(opts)
+}
+
+// NewResult starts the result of a run, to pass to Apply in
+// Options.Result.
+func NewResult() *Result {
+	return core.NewResult()
+}
@@ -0,0 +1,59 @@
+package mold_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/0m3kk/mold"
+)
+
+// finished records the files of a run as they finish, and its result.
+type finished struct {
+	mold.NopObserver
+	files  []string
+	result *mold.Result
+}
+
+func (f *finished) FileFinish(path string, status mold.FileStatus, _ error) {
+	f.files = append(f.files, string(status)+" "+path)
+}
+
+func (f *finished) Done(result *mold.Result) {
+	f.result = result
+}
+
+func TestApply(t *testing.T) {
+	templateDir := t.TempDir()
+	for name, content := range map[string]string{"README.md.tmpl": "# {{.name}}\n", "LICENSE": "MIT\n"} {
+		if err := os.WriteFile(filepath.Join(templateDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write template file: %v", err)
+		}
+	}
+	outputDir := t.TempDir()
+	observer := &finished{}
+
+	err := mold.Apply(mold.Options{
+		TemplatePath: templateDir,
+		OutputDir:    outputDir,
+		Data:         map[string]any{"name": "demo"},
+		Link:         mold.LinkCopy,
+		Out:          &bytes.Buffer{},
+		Observer:     observer,
+	})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if want := []string{"copied LICENSE", "rendered README.md"}; !slices.Equal(observer.files, want) {
+		t.Errorf("Expected the files %v to be observed, got %v", want, observer.files)
+	}
+	if observer.result == nil || !observer.result.Success || len(observer.result.Files) != 2 {
+		t.Errorf("Expected the result of a successful run, got %+v", observer.result)
+	}
+	content, err := os.ReadFile(filepath.Join(outputDir, "README.md"))
+	if err != nil || string(content) != "# demo\n" {
+		t.Errorf("Expected the rendered file, got %q: %v", content, err)
+	}
+}